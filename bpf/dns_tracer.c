@@ -1,19 +1,29 @@
 // go:build ignore
 
 // KubePulse DNS Tracer - eBPF Program
-// Hooks udp_sendmsg to capture DNS queries (port 53).
-// Parses DNS wire format query name with BPF-verifier-safe bounds checking.
+// Hooks udp_sendmsg and tcp_sendmsg to capture DNS queries (port 53).
+// Parses DNS wire format query name and type with BPF-verifier-safe bounds
+// checking.
 
 #include "headers/vmlinux.h"
+#include "headers/arch.h"
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_endian.h>
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_tracing.h>
 
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
 // Maximum DNS query name length
 #define MAX_DNS_NAME_LEN 128
 // DNS header size
 #define DNS_HEADER_SIZE 12
+// DNS over TCP prefixes each message with its 2-byte length (RFC 1035 4.2.2)
+#define DNS_TCP_LENGTH_SIZE 2
+// Labels parsed per name: enough for ip6.arpa reverse names (32 nibbles
+// plus "ip6" and "arpa").
+#define MAX_DNS_LABELS 34
 // Ring buffer size: 2MB
 #define RINGBUF_SIZE (2 * 1024 * 1024)
 
@@ -25,14 +35,28 @@ struct dns_event {
   __u32 daddr;
   __u16 sport;
   __u16 dport;
+  __u32 _pad0;
   __u64 latency_ns;
   __u64 timestamp;
   char qname[MAX_DNS_NAME_LEN];
   __u16 qname_len;
   char comm[16];
   __u8 _pad[6];
+  // Appended so events from older objects decode with qtype 0 (unknown)
+  // and transport 0 (UDP).
+  __u16 qtype; // question type, e.g. 1 (A); 0 if the name did not fit
+  __u8 transport; // DNS_TRANSPORT_UDP or DNS_TRANSPORT_TCP
+  __u8 _pad2[5];
 };
 
+// Values of dns_event.transport.
+#define DNS_TRANSPORT_UDP 0
+#define DNS_TRANSPORT_TCP 1
+
+// Must match rawEvent in internal/probes/dns.
+_Static_assert(sizeof(struct dns_event) == 200, "dns_event layout changed");
+EVENT_TYPE(dns_event);
+
 // Ring buffer for emitting DNS events to userspace
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
@@ -42,21 +66,25 @@ struct {
 // parse_dns_name parses a DNS wire format name from a stack buffer into
 // dot-separated human-readable form. All accesses are from the stack buffer,
 // so no bpf_probe_read is needed. Uses bounded loops (kernel 5.3+).
+// *name_end is set to the offset just past the name's terminating zero
+// label, or left untouched if the name was not parsed to its end.
 static __always_inline int parse_dns_name(const unsigned char *payload,
                                           int payload_len, char *dst,
-                                          int dst_len) {
+                                          int dst_len, int *name_end) {
   int src_pos = 0;
   int dst_pos = 0;
 
-  // Outer loop: iterate over labels. Max 32 labels is generous for any domain.
+  // Outer loop: iterate over labels.
   // No #pragma unroll — kernel 5.3+ supports bounded loops natively.
-  for (int i = 0; i < 32; i++) {
+  for (int i = 0; i < MAX_DNS_LABELS; i++) {
     if (src_pos >= payload_len || src_pos >= dst_len)
       break;
 
     unsigned char llen = payload[src_pos];
-    if (llen == 0)
+    if (llen == 0) {
+      *name_end = src_pos + 1;
       break;
+    }
 
     // Compression pointer or invalid
     if (llen >= 0xC0)
@@ -104,13 +132,11 @@ static __always_inline int parse_dns_name(const unsigned char *payload,
   return dst_pos;
 }
 
-// kprobe/udp_sendmsg - Fires when a UDP message is sent.
-// Filters for port 53 (DNS) and parses the query name.
-SEC("kprobe/udp_sendmsg")
-int kprobe_udp_sendmsg(struct pt_regs *ctx) {
-  struct sock *sk = (struct sock *)PT_REGS_PARM1(ctx);
-  struct msghdr *msg = (struct msghdr *)PT_REGS_PARM2(ctx);
-
+// trace_dns emits an event for a message sent to port 53, parsing the
+// query from the first iovec. Over TCP the message starts after its length
+// prefix, which resolvers may also write as an iovec of its own.
+static __always_inline int trace_dns(struct sock *sk, struct msghdr *msg,
+                                     __u8 transport) {
   if (!sk || !msg)
     return 0;
 
@@ -123,6 +149,8 @@ int kprobe_udp_sendmsg(struct pt_regs *ctx) {
 
   __u64 pid_tgid = bpf_get_current_pid_tgid();
   __u32 pid = pid_tgid >> 32;
+  if (excluded_pid(pid))
+    return 0;
   __u64 uid_gid = bpf_get_current_uid_gid();
 
   // Get the iov_iter from msghdr to read DNS payload
@@ -144,27 +172,45 @@ int kprobe_udp_sendmsg(struct pt_regs *ctx) {
   if (bpf_probe_read_kernel(&iov_len, sizeof(iov_len), &iov->iov_len) != 0)
     return 0;
 
+  int offset = DNS_HEADER_SIZE;
+  if (transport == DNS_TRANSPORT_TCP) {
+    if (iov_len == DNS_TCP_LENGTH_SIZE && iter.nr_segs > 1) {
+      // The length prefix was written separately; the message is next.
+      if (bpf_probe_read_kernel(&base, sizeof(base), &iov[1].iov_base) != 0)
+        return 0;
+      if (bpf_probe_read_kernel(&iov_len, sizeof(iov_len), &iov[1].iov_len) !=
+          0)
+        return 0;
+    } else {
+      offset += DNS_TCP_LENGTH_SIZE;
+    }
+  } else if (iov_len > 512) {
+    return 0;
+  }
+
   // Minimum DNS message: header (12) + 1 byte name + 4 bytes qtype/qclass
-  if (!base || iov_len < DNS_HEADER_SIZE + 5 || iov_len > 512)
+  if (!base || iov_len < offset + 5)
     return 0;
 
   // Read the raw DNS payload after the header into a stack buffer.
   unsigned char dns_payload[MAX_DNS_NAME_LEN];
-  int payload_len = iov_len - DNS_HEADER_SIZE;
+  int payload_len = iov_len - offset;
   if (payload_len <= 0)
     return 0;
   if (payload_len > MAX_DNS_NAME_LEN)
     payload_len = MAX_DNS_NAME_LEN;
 
   if (bpf_probe_read_user(dns_payload, payload_len & 0x7F,
-                          (void *)base + DNS_HEADER_SIZE) != 0)
+                          (void *)base + offset) != 0)
     return 0;
 
   // Reserve ring buffer space
   struct dns_event *event =
       bpf_ringbuf_reserve(&dns_events, sizeof(struct dns_event), 0);
-  if (!event)
+  if (!event) {
+    count_ringbuf_lost();
     return 0;
+  }
 
   // Fill basic event fields
   event->pid = pid;
@@ -180,12 +226,39 @@ int kprobe_udp_sendmsg(struct pt_regs *ctx) {
   bpf_get_current_comm(&event->comm, sizeof(event->comm));
 
   // Parse DNS wire format from stack buffer
+  int name_end = 0;
   int name_len = parse_dns_name(dns_payload, payload_len & 0x7F, event->qname,
-                                MAX_DNS_NAME_LEN);
+                                MAX_DNS_NAME_LEN, &name_end);
   event->qname_len = (__u16)name_len;
 
+  // QTYPE follows the name, big-endian.
+  event->qtype = 0;
+  event->transport = transport;
+  __builtin_memset(event->_pad2, 0, sizeof(event->_pad2));
+  if (name_end > 0 && name_end + 1 < payload_len) {
+    int off = name_end & 0x7F;
+    if (off + 1 < MAX_DNS_NAME_LEN)
+      event->qtype = ((__u16)dns_payload[off] << 8) | dns_payload[off + 1];
+  }
+
   bpf_ringbuf_submit(event, 0);
   return 0;
 }
 
+// kprobe/udp_sendmsg - Fires when a UDP message is sent.
+// Filters for port 53 (DNS) and parses the query name.
+SEC("kprobe/udp_sendmsg")
+int kprobe_udp_sendmsg(struct pt_regs *ctx) {
+  return trace_dns((struct sock *)PT_REGS_PARM1(ctx),
+                   (struct msghdr *)PT_REGS_PARM2(ctx), DNS_TRANSPORT_UDP);
+}
+
+// kprobe/tcp_sendmsg - DNS over TCP, used for large queries and by some
+// resolvers.
+SEC("kprobe/tcp_sendmsg")
+int kprobe_tcp_sendmsg(struct pt_regs *ctx) {
+  return trace_dns((struct sock *)PT_REGS_PARM1(ctx),
+                   (struct msghdr *)PT_REGS_PARM2(ctx), DNS_TRANSPORT_TCP);
+}
+
 char LICENSE[] SEC("license") = "GPL";
@@ -2,13 +2,32 @@
 
 // KubePulse Packet Drop Tracer
 // Hooks tracepoint/skb/kfree_skb to detect dropped packets with drop reasons.
+// Kernels before 5.17 have no reason field and every drop is reported as
+// NOT_SPECIFIED. Where the tracepoint is unavailable userspace attaches
+// kprobe/kfree_skb instead. For IPv4 and IPv6 packets the event carries the
+// addresses, and for TCP and UDP the ports, read from the skb's headers.
 
 #include "headers/vmlinux.h"
+#include "headers/arch.h"
 #include <bpf/bpf_core_read.h>
+#include <bpf/bpf_endian.h>
 #include <bpf/bpf_helpers.h>
+#include <bpf/bpf_tracing.h>
+
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
 
 #define RINGBUF_SIZE (1 * 1024 * 1024)
 
+// SKB_DROP_REASON_NOT_SPECIFIED
+#define DROP_REASON_NOT_SPECIFIED 2
+
+// Macros vmlinux.h does not carry.
+#define ETH_P_IP 0x0800
+#define ETH_P_IPV6 0x86DD
+#define AF_INET 2
+#define AF_INET6 10
+
 struct drop_event {
   __u32 pid;
   __u32 drop_reason;
@@ -18,33 +37,129 @@ struct drop_event {
   __u64 location; // Kernel function address where drop occurred
   __u64 timestamp;
   char comm[16];
+  // Addresses and ports, appended so events from older objects decode
+  // with family 0. family is 0 when the packet is not IPv4 or IPv6 or its
+  // headers could not be read; sport and dport are 0 unless l4_proto is
+  // TCP or UDP. IPv4 addresses use the first 4 bytes.
+  __u8 family; // AF_INET, AF_INET6 or 0
+  __u8 l4_proto;
+  __u16 sport;
+  __u16 dport;
+  __u16 _pad3;
+  __u8 saddr[16];
+  __u8 daddr[16];
 };
 
+// Must match rawEvent in internal/probes/drop.
+_Static_assert(sizeof(struct drop_event) == 88, "drop_event layout changed");
+EVENT_TYPE(drop_event);
+
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
   __uint(max_entries, RINGBUF_SIZE);
 } drop_events SEC(".maps");
 
-// Uses vmlinux.h struct: trace_event_raw_kfree_skb
-SEC("tracepoint/skb/kfree_skb")
-int tracepoint_kfree_skb(struct trace_event_raw_kfree_skb *ctx) {
-  // Only trace actual drops (reason >= 2), not normal consumption
-  if (ctx->reason < 2)
-    return 0;
+// read_addrs fills in the event's addresses and ports from skb's network
+// and transport headers, leaving family 0 if they cannot be read.
+static __always_inline void read_addrs(struct drop_event *event,
+                                       struct sk_buff *skb, __u16 protocol) {
+  unsigned char *head = BPF_CORE_READ(skb, head);
+  __u16 nh = BPF_CORE_READ(skb, network_header);
+  if (!head || nh == (__u16)~0U)
+    return;
+
+  __u8 l4_proto;
+  __u32 l4_off;
+  if (protocol == ETH_P_IP) {
+    struct iphdr ip;
+    if (bpf_probe_read_kernel(&ip, sizeof(ip), head + nh) || ip.version != 4)
+      return;
+    __builtin_memcpy(event->saddr, &ip.saddr, 4);
+    __builtin_memcpy(event->daddr, &ip.daddr, 4);
+    event->family = AF_INET;
+    l4_proto = ip.protocol;
+    l4_off = nh + ip.ihl * 4;
+  } else if (protocol == ETH_P_IPV6) {
+    struct ipv6hdr ip6;
+    if (bpf_probe_read_kernel(&ip6, sizeof(ip6), head + nh))
+      return;
+    __builtin_memcpy(event->saddr, &ip6.saddr, 16);
+    __builtin_memcpy(event->daddr, &ip6.daddr, 16);
+    event->family = AF_INET6;
+    l4_proto = ip6.nexthdr; // extension headers are not followed
+    l4_off = nh + sizeof(ip6);
+  } else {
+    return;
+  }
+
+  event->l4_proto = l4_proto;
+  if (l4_proto == IPPROTO_TCP || l4_proto == IPPROTO_UDP) {
+    __be16 ports[2];
+    if (!bpf_probe_read_kernel(ports, sizeof(ports), head + l4_off)) {
+      event->sport = bpf_ntohs(ports[0]);
+      event->dport = bpf_ntohs(ports[1]);
+    }
+  }
+}
+
+static __always_inline void emit_drop(struct sk_buff *skb, __u32 reason,
+                                      __u16 protocol, __u64 location) {
+  if (excluded_current())
+    return;
 
   struct drop_event *event =
       bpf_ringbuf_reserve(&drop_events, sizeof(*event), 0);
-  if (!event)
-    return 0;
+  if (!event) {
+    count_ringbuf_lost();
+    return;
+  }
 
   event->pid = bpf_get_current_pid_tgid() >> 32;
-  event->drop_reason = ctx->reason;
-  event->protocol = ctx->protocol;
-  event->location = (__u64)ctx->location;
+  event->drop_reason = reason;
+  event->protocol = protocol;
+  event->location = location;
   event->timestamp = bpf_ktime_get_ns();
   bpf_get_current_comm(&event->comm, sizeof(event->comm));
+  event->family = 0;
+  event->l4_proto = 0;
+  event->sport = 0;
+  event->dport = 0;
+  event->_pad3 = 0;
+  __builtin_memset(event->saddr, 0, sizeof(event->saddr));
+  __builtin_memset(event->daddr, 0, sizeof(event->daddr));
+  if (skb)
+    read_addrs(event, skb, protocol);
 
   bpf_ringbuf_submit(event, 0);
+}
+
+// Uses vmlinux.h struct: trace_event_raw_kfree_skb
+SEC("tracepoint/skb/kfree_skb")
+int tracepoint_kfree_skb(struct trace_event_raw_kfree_skb *ctx) {
+  __u32 reason = DROP_REASON_NOT_SPECIFIED;
+
+  // The guard keeps the program loadable on kernels without the field.
+  if (bpf_core_field_exists(ctx->reason)) {
+    // Only trace actual drops (reason >= 2), not normal consumption
+    if (ctx->reason < 2)
+      return 0;
+    reason = ctx->reason;
+  }
+
+  emit_drop((struct sk_buff *)ctx->skbaddr, reason, ctx->protocol,
+            (__u64)ctx->location);
+  return 0;
+}
+
+// Fallback for kernels without the kfree_skb tracepoint.
+SEC("kprobe/kfree_skb")
+int kprobe_kfree_skb(struct pt_regs *ctx) {
+  struct sk_buff *skb = (struct sk_buff *)PT_REGS_PARM1(ctx);
+  if (!skb)
+    return 0;
+
+  emit_drop(skb, DROP_REASON_NOT_SPECIFIED,
+            bpf_ntohs(BPF_CORE_READ(skb, protocol)), 0);
   return 0;
 }
 
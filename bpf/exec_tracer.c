@@ -1,12 +1,16 @@
 // go:build ignore
 
 // KubePulse Process Exec Tracer
-// Hooks tracepoint/sched/sched_process_exec to monitor process executions.
+// Hooks tracepoint/sched/sched_process_exec to monitor process executions,
+// reporting the parent that spawned each one.
 
 #include "headers/vmlinux.h"
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_helpers.h>
 
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
 #define RINGBUF_SIZE (1 * 1024 * 1024)
 #define MAX_FILENAME_LEN 128
 
@@ -18,8 +22,16 @@ struct exec_event {
   __u64 timestamp;
   char comm[16];
   char filename[MAX_FILENAME_LEN];
+  // Appended so events from older objects decode with no parent.
+  __u32 ppid;
+  char parent_comm[16];
+  __u32 _pad2;
 };
 
+// Must match rawEvent in internal/probes/exec.
+_Static_assert(sizeof(struct exec_event) == 192, "exec_event layout changed");
+EVENT_TYPE(exec_event);
+
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
   __uint(max_entries, RINGBUF_SIZE);
@@ -31,9 +43,18 @@ int tracepoint_sched_process_exec(
     struct trace_event_raw_sched_process_exec *ctx) {
   struct exec_event *event;
 
+  // Programs the agent runs are its own activity too.
+  struct task_struct *task = (struct task_struct *)bpf_get_current_task();
+  struct task_struct *parent = BPF_CORE_READ(task, real_parent);
+  __u32 ppid = BPF_CORE_READ(parent, tgid);
+  if (excluded_pid(ctx->pid) || excluded_pid(ppid))
+    return 0;
+
   event = bpf_ringbuf_reserve(&exec_events, sizeof(*event), 0);
-  if (!event)
+  if (!event) {
+    count_ringbuf_lost();
     return 0;
+  }
 
   event->pid = ctx->pid;
   event->old_pid = ctx->old_pid;
@@ -41,6 +62,10 @@ int tracepoint_sched_process_exec(
   event->timestamp = bpf_ktime_get_ns();
   bpf_get_current_comm(&event->comm, sizeof(event->comm));
 
+  event->ppid = ppid;
+  BPF_CORE_READ_STR_INTO(&event->parent_comm, parent, comm);
+  event->_pad2 = 0;
+
   // Read filename from __data_loc encoded field
   // __data_loc: lower 16 bits = offset, upper 16 bits = length
   unsigned short fname_off = ctx->__data_loc_filename & 0xFFFF;
@@ -1,16 +1,25 @@
 // go:build ignore
 
 // KubePulse File I/O Latency Tracer
-// Hooks kprobe/vfs_read, kretprobe/vfs_read, kprobe/vfs_write,
-// kretprobe/vfs_write to measure file I/O latency.
+// Hooks entry and return of vfs_read and vfs_write to measure file I/O
+// latency: fentry/fexit where the kernel supports them, else
+// kprobe/kretprobe. Both program sets emit identical events, naming the
+// file by its last PATH_DEPTH path components and its file system type.
 
 #include "headers/vmlinux.h"
+#include "headers/arch.h"
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_tracing.h>
 
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
 #define RINGBUF_SIZE (2 * 1024 * 1024)
 #define MAX_ENTRIES 8192
+#define PATH_DEPTH 3
+#define NAME_SIZE 64
+#define FSTYPE_SIZE 16
 
 // Track in-flight I/O operations
 struct io_key {
@@ -20,6 +29,7 @@ struct io_key {
 
 struct io_val {
   __u64 start_ns;
+  __u64 file; // struct file *; bpf2go has no Go type for pointers
   __u8 op; // 0=read, 1=write
   __u8 _pad[7];
 };
@@ -40,28 +50,67 @@ struct fileio_event {
   __u8 op; // 0=read, 1=write
   __u8 _pad[7];
   char comm[16];
+  // Dentry names from the file up, e.g. {"passwd", "etc", "/"}; all empty
+  // for anonymous files such as pipes and sockets.
+  char path[PATH_DEPTH][NAME_SIZE];
+  char fs[FSTYPE_SIZE]; // superblock file system type, e.g. "ext4"
 };
 
+// Must match rawEvent in internal/probes/fileio.
+_Static_assert(sizeof(struct fileio_event) == 264, "fileio_event layout changed");
+EVENT_TYPE(fileio_event);
+
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
   __uint(max_entries, RINGBUF_SIZE);
 } fileio_events SEC(".maps");
 
-static __always_inline int io_entry(__u8 op) {
+// I/O faster than this is counted in fileio_suppressed instead of being
+// sent to userspace. Set at load time from modules.fileio.min_latency.
+volatile const __u64 min_latency_ns = 1000000;
+
+struct {
+  __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+  __uint(max_entries, 1);
+  __type(key, __u32);
+  __type(value, __u64);
+} fileio_suppressed SEC(".maps");
+
+// read_file fills in the event's path components and file system type.
+static __always_inline void read_file(struct fileio_event *event,
+                                      struct file *file) {
+  struct dentry *d = BPF_CORE_READ(file, f_path.dentry);
+  for (int i = 0; i < PATH_DEPTH && d; i++) {
+    bpf_probe_read_kernel_str(event->path[i], NAME_SIZE,
+                              BPF_CORE_READ(d, d_name.name));
+    struct dentry *parent = BPF_CORE_READ(d, d_parent);
+    if (parent == d) // the file system root is its own parent
+      break;
+    d = parent;
+  }
+  bpf_probe_read_kernel_str(event->fs, FSTYPE_SIZE,
+                            BPF_CORE_READ(file, f_inode, i_sb, s_type, name));
+}
+
+static __always_inline int io_entry(struct file *file, __u8 op) {
   __u64 pid_tgid = bpf_get_current_pid_tgid();
+  // Untimed I/O is never reported, nor counted as suppressed.
+  if (excluded_pid(pid_tgid >> 32))
+    return 0;
   struct io_key key = {
       .pid = pid_tgid >> 32,
       .tid = (__u32)pid_tgid,
   };
   struct io_val val = {
       .start_ns = bpf_ktime_get_ns(),
+      .file = (__u64)file,
       .op = op,
   };
   bpf_map_update_elem(&io_start, &key, &val, BPF_ANY);
   return 0;
 }
 
-static __always_inline int io_exit(struct pt_regs *ctx) {
+static __always_inline int io_exit(__u64 ret) {
   __u64 pid_tgid = bpf_get_current_pid_tgid();
   struct io_key key = {
       .pid = pid_tgid >> 32,
@@ -73,40 +122,75 @@ static __always_inline int io_exit(struct pt_regs *ctx) {
     return 0;
 
   __u64 latency = bpf_ktime_get_ns() - val->start_ns;
+  struct file *file = (struct file *)val->file;
   __u8 op = val->op;
   bpf_map_delete_elem(&io_start, &key);
 
-  // Filter out very fast I/O (< 1ms) to reduce noise
-  if (latency < 1000000)
+  if (latency < min_latency_ns) {
+    __u32 zero = 0;
+    __u64 *suppressed = bpf_map_lookup_elem(&fileio_suppressed, &zero);
+    if (suppressed)
+      (*suppressed)++;
     return 0;
+  }
 
   struct fileio_event *event =
       bpf_ringbuf_reserve(&fileio_events, sizeof(*event), 0);
-  if (!event)
+  if (!event) {
+    count_ringbuf_lost();
     return 0;
+  }
 
   event->pid = key.pid;
   event->uid = bpf_get_current_uid_gid() & 0xFFFFFFFF;
   event->latency_ns = latency;
-  event->bytes = PT_REGS_RC(ctx);
+  event->bytes = ret;
   event->timestamp = bpf_ktime_get_ns();
   event->op = op;
   bpf_get_current_comm(&event->comm, sizeof(event->comm));
+  __builtin_memset(event->path, 0, sizeof(event->path));
+  __builtin_memset(event->fs, 0, sizeof(event->fs));
+  if (file)
+    read_file(event, file);
 
   bpf_ringbuf_submit(event, 0);
   return 0;
 }
 
 SEC("kprobe/vfs_read")
-int kprobe_vfs_read(struct pt_regs *ctx) { return io_entry(0); }
+int kprobe_vfs_read(struct pt_regs *ctx) {
+  return io_entry((struct file *)PT_REGS_PARM1(ctx), 0);
+}
 
 SEC("kretprobe/vfs_read")
-int kretprobe_vfs_read(struct pt_regs *ctx) { return io_exit(ctx); }
+int kretprobe_vfs_read(struct pt_regs *ctx) { return io_exit(PT_REGS_RC(ctx)); }
 
 SEC("kprobe/vfs_write")
-int kprobe_vfs_write(struct pt_regs *ctx) { return io_entry(1); }
+int kprobe_vfs_write(struct pt_regs *ctx) {
+  return io_entry((struct file *)PT_REGS_PARM1(ctx), 1);
+}
 
 SEC("kretprobe/vfs_write")
-int kretprobe_vfs_write(struct pt_regs *ctx) { return io_exit(ctx); }
+int kretprobe_vfs_write(struct pt_regs *ctx) { return io_exit(PT_REGS_RC(ctx)); }
+
+// fentry/fexit variants: Linux 5.5+ with BTF. Cheaper than kprobes, which
+// trap, and fexit sees the return value without a kretprobe trampoline.
+SEC("fentry/vfs_read")
+int BPF_PROG(fentry_vfs_read, struct file *file) { return io_entry(file, 0); }
+
+SEC("fexit/vfs_read")
+int BPF_PROG(fexit_vfs_read, struct file *file, char *buf, size_t count,
+             loff_t *pos, ssize_t ret) {
+  return io_exit(ret);
+}
+
+SEC("fentry/vfs_write")
+int BPF_PROG(fentry_vfs_write, struct file *file) { return io_entry(file, 1); }
+
+SEC("fexit/vfs_write")
+int BPF_PROG(fexit_vfs_write, struct file *file, const char *buf,
+             size_t count, loff_t *pos, ssize_t ret) {
+  return io_exit(ret);
+}
 
 char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,23 @@
+// KubePulse - per-architecture definitions missing from vmlinux.h.
+//
+// vmlinux.h is generated from an x86_64 kernel; CO-RE relocates kernel
+// struct accesses on any architecture, but the kprobe context type the
+// PT_REGS_* macros in bpf_tracing.h cast to is per-architecture and only
+// exists in that architecture's vmlinux.h. bpf2go defines
+// __TARGET_ARCH_<arch> for each -target it builds.
+
+#ifndef __KUBEPULSE_ARCH_H
+#define __KUBEPULSE_ARCH_H
+
+#if defined(__TARGET_ARCH_arm64)
+// arch/arm64/include/uapi/asm/ptrace.h; the kprobe ctx is a struct pt_regs
+// whose leading members have this layout.
+struct user_pt_regs {
+  __u64 regs[31];
+  __u64 sp;
+  __u64 pc;
+  __u64 pstate;
+};
+#endif
+
+#endif /* __KUBEPULSE_ARCH_H */
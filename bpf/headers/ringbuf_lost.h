@@ -0,0 +1,31 @@
+// KubePulse - ring buffer loss accounting shared by all programs.
+//
+// Each program counts the events it drops because bpf_ringbuf_reserve
+// failed (ring buffer full). Userspace sums the per-CPU slots and exports
+// kubepulse_ringbuf_lost_events_total{module}.
+
+#ifndef __KUBEPULSE_RINGBUF_LOST_H
+#define __KUBEPULSE_RINGBUF_LOST_H
+
+struct {
+  __uint(type, BPF_MAP_TYPE_PERCPU_ARRAY);
+  __uint(max_entries, 1);
+  __type(key, __u32);
+  __type(value, __u64);
+} ringbuf_lost SEC(".maps");
+
+// count_ringbuf_lost records one event dropped on reserve failure.
+static __always_inline void count_ringbuf_lost(void) {
+  __u32 key = 0;
+  __u64 *lost = bpf_map_lookup_elem(&ringbuf_lost, &key);
+  if (lost)
+    (*lost)++;
+}
+
+// EVENT_TYPE keeps struct name in the object's BTF, which the programs
+// alone leave it out of, so bpf2go -type generates its Go mirror. The
+// modules' tests compare that with their decode structs, failing when the
+// object was not regenerated after a layout change.
+#define EVENT_TYPE(name) const struct name *unused_##name __attribute__((unused))
+
+#endif /* __KUBEPULSE_RINGBUF_LOST_H */
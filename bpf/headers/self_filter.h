@@ -0,0 +1,32 @@
+// KubePulse - exclusion of the agent's own activity, shared by all programs.
+//
+// Unless agent.trace_self is set, userspace writes the agent's PID and
+// those of its children into excluded_pids after loading each object, and
+// programs skip events from those processes: the agent's NATS publishes,
+// /proc reads and DNS lookups would otherwise be traced and exported in a
+// feedback loop. oomkill does not include it: an OOM kill of the agent is
+// still worth reporting.
+
+#ifndef __KUBEPULSE_SELF_FILTER_H
+#define __KUBEPULSE_SELF_FILTER_H
+
+#define MAX_EXCLUDED_PIDS 64
+
+struct {
+  __uint(type, BPF_MAP_TYPE_HASH);
+  __uint(max_entries, MAX_EXCLUDED_PIDS);
+  __type(key, __u32);  // tgid
+  __type(value, __u8); // unused
+} excluded_pids SEC(".maps");
+
+// excluded_pid reports whether tgid is the agent or one of its children.
+static __always_inline bool excluded_pid(__u32 tgid) {
+  return bpf_map_lookup_elem(&excluded_pids, &tgid) != NULL;
+}
+
+// excluded_current reports whether the current task belongs to the agent.
+static __always_inline bool excluded_current(void) {
+  return excluded_pid(bpf_get_current_pid_tgid() >> 32);
+}
+
+#endif /* __KUBEPULSE_SELF_FILTER_H */
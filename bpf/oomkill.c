@@ -7,16 +7,18 @@
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_helpers.h>
 
+#include "headers/ringbuf_lost.h"
+
 #define RINGBUF_SIZE (512 * 1024)
 
 struct oom_event {
   __u32 pid; // Victim PID
   __u32 uid;
-  __u64 total_vm;      // Total VM pages
-  __u64 anon_rss;      // Anonymous RSS pages
-  __u64 file_rss;      // File-backed RSS pages
-  __u64 shmem_rss;     // Shared memory RSS pages
-  __u64 pgtables;      // Page table pages
+  __u64 total_vm;      // Total VM, kB
+  __u64 anon_rss;      // Anonymous RSS, kB
+  __u64 file_rss;      // File-backed RSS, kB
+  __u64 shmem_rss;     // Shared memory RSS, kB
+  __u64 pgtables;      // Page tables, kB
   __s16 oom_score_adj; // OOM score adjustment
   __u16 _pad;
   __u32 _pad2;
@@ -24,6 +26,10 @@ struct oom_event {
   char comm[16]; // Victim process name
 };
 
+// Must match rawEvent in internal/probes/oom.
+_Static_assert(sizeof(struct oom_event) == 80, "oom_event layout changed");
+EVENT_TYPE(oom_event);
+
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
   __uint(max_entries, RINGBUF_SIZE);
@@ -35,8 +41,10 @@ int tracepoint_oom_mark_victim(struct trace_event_raw_mark_victim *ctx) {
   struct oom_event *event;
 
   event = bpf_ringbuf_reserve(&oom_events, sizeof(*event), 0);
-  if (!event)
+  if (!event) {
+    count_ringbuf_lost();
     return 0;
+  }
 
   event->pid = ctx->pid;
   event->uid = ctx->uid;
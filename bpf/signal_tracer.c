@@ -0,0 +1,70 @@
+// go:build ignore
+
+// KubePulse Signal Tracer
+// Hooks tracepoint/signal/signal_generate to report fatal signals with the
+// process that sent them and the process they were sent to.
+
+#include "headers/vmlinux.h"
+#include <bpf/bpf_core_read.h>
+#include <bpf/bpf_helpers.h>
+
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
+#define RINGBUF_SIZE (256 * 1024)
+
+struct signal_event {
+  __u32 sender_pid;
+  __u32 sender_uid;
+  __u32 target_pid;
+  __u32 sig;
+  __u64 timestamp;
+  char sender_comm[16];
+  char target_comm[16];
+};
+
+// Must match rawEvent in internal/probes/signals.
+_Static_assert(sizeof(struct signal_event) == 56, "signal_event layout changed");
+EVENT_TYPE(signal_event);
+
+// Signals to report: bit n reports signal n. Set at load time from
+// constants.TracedSignals; the default is SIGABRT, SIGKILL, SIGSEGV and
+// SIGTERM.
+volatile const __u64 signal_mask = (1ULL << 6) | (1ULL << 9) | (1ULL << 11) | (1ULL << 15);
+
+struct {
+  __uint(type, BPF_MAP_TYPE_RINGBUF);
+  __uint(max_entries, RINGBUF_SIZE);
+} signal_events SEC(".maps");
+
+// Uses vmlinux.h struct: trace_event_raw_signal_generate. The tracepoint
+// fires in the sender's context; pid and comm name the target.
+SEC("tracepoint/signal/signal_generate")
+int tracepoint_signal_generate(struct trace_event_raw_signal_generate *ctx) {
+  int sig = ctx->sig;
+  if (sig <= 0 || sig >= 64 || !(signal_mask & (1ULL << sig)))
+    return 0;
+  if (excluded_current())
+    return 0;
+
+  struct signal_event *event =
+      bpf_ringbuf_reserve(&signal_events, sizeof(*event), 0);
+  if (!event) {
+    count_ringbuf_lost();
+    return 0;
+  }
+
+  event->sender_pid = bpf_get_current_pid_tgid() >> 32;
+  event->sender_uid = bpf_get_current_uid_gid() & 0xFFFFFFFF;
+  event->target_pid = ctx->pid;
+  event->sig = sig;
+  event->timestamp = bpf_ktime_get_ns();
+  bpf_get_current_comm(&event->sender_comm, sizeof(event->sender_comm));
+  bpf_probe_read_kernel(event->target_comm, sizeof(event->target_comm),
+                        ctx->comm);
+
+  bpf_ringbuf_submit(event, 0);
+  return 0;
+}
+
+char LICENSE[] SEC("license") = "GPL";
@@ -2,13 +2,25 @@
 
 // KubePulse TCP Retransmit Tracer
 // Hooks tracepoint/tcp/tcp_retransmit_skb to detect packet retransmissions.
+//
+// Retransmits are counted per flow, so one lossy path cannot flood the ring
+// buffer: a flow is reported on its 1st, 10th, 100th, ... retransmit, each
+// event carrying the flow's count so far.
 
 #include "headers/vmlinux.h"
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_endian.h>
 #include <bpf/bpf_helpers.h>
 
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
 #define RINGBUF_SIZE (1 * 1024 * 1024)
+#define MAX_FLOWS 16384
+
+// Ratio between successive reporting thresholds.
+// Must match constants.RetransmitEmitFactor.
+#define EMIT_FACTOR 10
 
 struct retransmit_event {
   __u32 pid;
@@ -18,10 +30,37 @@ struct retransmit_event {
   __u16 dport;
   __u16 family;
   __u16 _pad;
+  __u32 _pad2;
   __u64 timestamp;
   char comm[16];
+  __u64 count; // retransmits of this flow so far
+};
+
+// Must match rawEvent in internal/probes/retransmit.
+_Static_assert(sizeof(struct retransmit_event) == 56, "retransmit_event layout changed");
+EVENT_TYPE(retransmit_event);
+
+struct flow_key {
+  __u32 saddr;
+  __u32 daddr;
+  __u16 sport;
+  __u16 dport;
 };
 
+struct flow_state {
+  __u64 count;
+  __u64 next; // count at which the flow is reported again
+};
+
+// Least recently retransmitting flows are evicted first; an evicted flow
+// starts again from 1.
+struct {
+  __uint(type, BPF_MAP_TYPE_LRU_HASH);
+  __uint(max_entries, MAX_FLOWS);
+  __type(key, struct flow_key);
+  __type(value, struct flow_state);
+} retransmit_flows SEC(".maps");
+
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
   __uint(max_entries, RINGBUF_SIZE);
@@ -30,18 +69,48 @@ struct {
 SEC("tracepoint/tcp/tcp_retransmit_skb")
 int tracepoint_tcp_retransmit(struct trace_event_raw_tcp_event_sk_skb *ctx) {
   struct retransmit_event *event;
+  struct flow_key key = {};
+  struct flow_state *state;
+  __u64 count = 1;
+
+  if (excluded_current())
+    return 0;
+
+  bpf_probe_read_kernel(&key.saddr, 4, ctx->saddr);
+  bpf_probe_read_kernel(&key.daddr, 4, ctx->daddr);
+  key.sport = ctx->sport;
+  key.dport = ctx->dport;
+
+  state = bpf_map_lookup_elem(&retransmit_flows, &key);
+  if (!state) {
+    struct flow_state init = {.count = 1, .next = EMIT_FACTOR};
+    // Another CPU may have added the flow since the lookup.
+    if (bpf_map_update_elem(&retransmit_flows, &key, &init, BPF_NOEXIST) != 0)
+      state = bpf_map_lookup_elem(&retransmit_flows, &key);
+  }
+  if (state) {
+    // Each count is returned to one caller only, so exactly one reports
+    // a threshold however many CPUs retransmit on the flow at once.
+    count = __sync_fetch_and_add(&state->count, 1) + 1;
+    if (count != state->next)
+      return 0;
+    state->next = count * EMIT_FACTOR;
+  }
 
   event = bpf_ringbuf_reserve(&retransmit_events, sizeof(*event), 0);
-  if (!event)
+  if (!event) {
+    count_ringbuf_lost();
     return 0;
+  }
 
   event->pid = bpf_get_current_pid_tgid() >> 32;
   event->timestamp = bpf_ktime_get_ns();
   event->sport = ctx->sport;
   event->dport = ctx->dport;
   event->family = ctx->family;
-  bpf_probe_read_kernel(&event->saddr, 4, ctx->saddr);
-  bpf_probe_read_kernel(&event->daddr, 4, ctx->daddr);
+  event->saddr = key.saddr;
+  event->daddr = key.daddr;
+  event->count = count;
   bpf_get_current_comm(&event->comm, sizeof(event->comm));
 
   bpf_ringbuf_submit(event, 0);
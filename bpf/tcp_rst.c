@@ -1,15 +1,23 @@
 // go:build ignore
 
 // KubePulse TCP RST Tracer
-// Hooks tracepoint/tcp/tcp_send_reset to detect connection resets.
+// Hooks tracepoint/tcp/tcp_send_reset and tcp_receive_reset to detect
+// connection resets sent and received by local sockets.
 
 #include "headers/vmlinux.h"
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_endian.h>
 #include <bpf/bpf_helpers.h>
 
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
 #define RINGBUF_SIZE (1 * 1024 * 1024)
 
+// Values of rst_event.direction.
+#define RST_SENT 0
+#define RST_RECEIVED 1
+
 struct rst_event {
   __u32 pid;
   __u32 saddr;
@@ -20,34 +28,68 @@ struct rst_event {
   __u16 _pad;
   __u32 state;
   __u32 _pad2;
+  __u32 _pad3;
   __u64 timestamp;
   char comm[16];
+  // Appended so events from older objects, which only traced sent
+  // resets, decode as RST_SENT.
+  __u8 direction; // RST_SENT or RST_RECEIVED
+  __u8 _pad4[7];
 };
 
+// Must match rawEvent in internal/probes/rst.
+_Static_assert(sizeof(struct rst_event) == 64, "rst_event layout changed");
+EVENT_TYPE(rst_event);
+
 struct {
   __uint(type, BPF_MAP_TYPE_RINGBUF);
   __uint(max_entries, RINGBUF_SIZE);
 } rst_events SEC(".maps");
 
-SEC("tracepoint/tcp/tcp_send_reset")
-int tracepoint_tcp_send_reset(struct trace_event_raw_tcp_event_sk_skb *ctx) {
+static __always_inline void emit_rst(__u16 sport, __u16 dport, __u16 family,
+                                     __u32 state, const __u8 *saddr,
+                                     const __u8 *daddr, __u8 direction) {
   struct rst_event *event;
 
+  if (excluded_current())
+    return;
+
   event = bpf_ringbuf_reserve(&rst_events, sizeof(*event), 0);
-  if (!event)
-    return 0;
+  if (!event) {
+    count_ringbuf_lost();
+    return;
+  }
 
   event->pid = bpf_get_current_pid_tgid() >> 32;
   event->timestamp = bpf_ktime_get_ns();
-  event->sport = ctx->sport;
-  event->dport = ctx->dport;
-  event->family = ctx->family;
-  event->state = ctx->state;
-  bpf_probe_read_kernel(&event->saddr, 4, ctx->saddr);
-  bpf_probe_read_kernel(&event->daddr, 4, ctx->daddr);
+  event->sport = sport;
+  event->dport = dport;
+  event->family = family;
+  event->state = state;
+  bpf_probe_read_kernel(&event->saddr, 4, saddr);
+  bpf_probe_read_kernel(&event->daddr, 4, daddr);
   bpf_get_current_comm(&event->comm, sizeof(event->comm));
+  event->direction = direction;
+  __builtin_memset(event->_pad4, 0, sizeof(event->_pad4));
 
   bpf_ringbuf_submit(event, 0);
+}
+
+SEC("tracepoint/tcp/tcp_send_reset")
+int tracepoint_tcp_send_reset(struct trace_event_raw_tcp_event_sk_skb *ctx) {
+  emit_rst(ctx->sport, ctx->dport, ctx->family, ctx->state, ctx->saddr,
+           ctx->daddr, RST_SENT);
+  return 0;
+}
+
+// tcp_receive_reset has no state field, so it is read from the socket.
+SEC("tracepoint/tcp/tcp_receive_reset")
+int tracepoint_tcp_receive_reset(struct trace_event_raw_tcp_event_sk *ctx) {
+  const struct sock *sk = ctx->skaddr;
+  __u32 state = BPF_CORE_READ(sk, __sk_common.skc_state);
+
+  emit_rst(ctx->sport, ctx->dport, ctx->family, state, ctx->saddr,
+           ctx->daddr, RST_RECEIVED);
   return 0;
 }
 
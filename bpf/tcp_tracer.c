@@ -1,20 +1,47 @@
 //go:build ignore
 
 // KubePulse TCP Tracer - eBPF Program
-// Attaches kprobes to tcp_connect and tcp_close to measure per-connection latency.
+// Hooks tcp_connect and tcp_close to measure per-connection latency, with
+// fentry where the kernel supports it and kprobes otherwise. Both program
+// sets share the same handlers and emit identical events.
+//
+// With track_state set, tracepoint/sock/inet_sock_set_state follows each
+// connection through its handshake: connections that leave SYN_SENT for
+// CLOSE are reported as failures with the socket's error instead of a
+// latency, and tcp_close only reports established connections. It also
+// timestamps server-side connections as they complete the handshake and
+// enter the accept queue; kretprobe/inet_csk_accept reports how long they
+// waited there, attributed to the accepting process.
 
 #include "headers/vmlinux.h"
+#include "headers/arch.h"
 #include <bpf/bpf_helpers.h>
 #include <bpf/bpf_core_read.h>
 #include <bpf/bpf_tracing.h>
 #include <bpf/bpf_endian.h>
 
+#include "headers/ringbuf_lost.h"
+#include "headers/self_filter.h"
+
 // Maximum tracked connections in LRU map
 #define MAX_CONNECTIONS 65536
 
 // Ring buffer size: 4MB
 #define RINGBUF_SIZE (4 * 1024 * 1024)
 
+// include/net/tcp_states.h; vmlinux.h has them only as an anonymous enum.
+#define TCP_ESTABLISHED_STATE 1
+#define TCP_SYN_SENT_STATE 2
+#define TCP_SYN_RECV_STATE 3
+#define TCP_CLOSE_STATE 7
+
+// Values of tcp_event.role.
+#define ROLE_CLIENT 0
+#define ROLE_SERVER 1
+
+// Set at load time when inet_sock_set_state is attached.
+volatile const __u8 track_state = 0;
+
 // TCP event emitted to userspace
 struct tcp_event {
     __u32 pid;
@@ -23,18 +50,23 @@ struct tcp_event {
     __u32 daddr;     // Destination IPv4 address
     __u16 sport;     // Source port
     __u16 dport;     // Destination port
+    __u32 _pad;
     __u64 latency_ns;
     __u64 timestamp;
     char comm[16];   // Process name
+    // Appended so events from older objects decode as successes.
+    __u8 failed;     // the handshake failed; latency_ns is not meaningful
+    __u8 role;       // ROLE_SERVER: latency_ns is time in the accept queue
+    __u8 _pad2[2];
+    __u32 sk_err;    // the socket's errno when failed, 0 if closed by the app
 };
 
-// Key for the connection tracking map
-struct conn_key {
-    __u32 pid;
-    __u64 sock_ptr;  // struct sock pointer as u64
-};
+// Must match rawEvent in internal/probes/tcp.
+_Static_assert(sizeof(struct tcp_event) == 64, "tcp_event layout changed");
+EVENT_TYPE(tcp_event);
 
-// Value stored in the connection tracking map
+// Value stored in the connection tracking map. Failures are seen in
+// softirq context, so the connecting process is recorded here.
 struct conn_val {
     __u64 start_ns;
     __u32 saddr;
@@ -42,45 +74,58 @@ struct conn_val {
     __u16 sport;
     __u16 dport;
     __u32 uid;
+    __u32 pid;
+    __u8 established;
+    char comm[16];
 };
 
-// LRU hash map: tracks start time of connections
-// LRU ensures we never exceed MAX_CONNECTIONS and stale entries are evicted.
+// LRU hash map: tracks start time of connections, keyed by struct sock
+// pointer. LRU ensures we never exceed MAX_CONNECTIONS and stale entries
+// are evicted.
 struct {
     __uint(type, BPF_MAP_TYPE_LRU_HASH);
     __uint(max_entries, MAX_CONNECTIONS);
-    __type(key, struct conn_key);
+    __type(key, __u64);
     __type(value, struct conn_val);
 } conn_start SEC(".maps");
 
+// Accept queue entry times of server-side connections, keyed by the
+// child struct sock pointer. Connections never accepted age out.
+struct {
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __uint(max_entries, MAX_CONNECTIONS);
+    __type(key, __u64);
+    __type(value, __u64);
+} accept_start SEC(".maps");
+
 // Ring buffer for emitting TCP events to userspace
 struct {
     __uint(type, BPF_MAP_TYPE_RINGBUF);
     __uint(max_entries, RINGBUF_SIZE);
 } tcp_events SEC(".maps");
 
-// kprobe/tcp_connect - Fires when a TCP connection is initiated.
+// on_tcp_connect - Fires when a TCP connection is initiated.
 // Records the start timestamp, source/dest addresses and ports.
-SEC("kprobe/tcp_connect")
-int kprobe_tcp_connect(struct pt_regs *ctx) {
-    struct sock *sk = (struct sock *)PT_REGS_PARM1(ctx);
+static __always_inline int on_tcp_connect(struct sock *sk) {
     if (!sk)
         return 0;
 
     __u64 pid_tgid = bpf_get_current_pid_tgid();
     __u32 pid = pid_tgid >> 32;
+    // Untracked connections are never reported, on close or failure.
+    if (excluded_pid(pid))
+        return 0;
     __u64 uid_gid = bpf_get_current_uid_gid();
     __u32 uid = uid_gid & 0xFFFFFFFF;
 
-    struct conn_key key = {
-        .pid = pid,
-        .sock_ptr = (__u64)sk,
-    };
+    __u64 key = (__u64)sk;
 
     struct conn_val val = {
         .start_ns = bpf_ktime_get_ns(),
         .uid = uid,
+        .pid = pid,
     };
+    bpf_get_current_comm(&val.comm, sizeof(val.comm));
 
     // Read socket addresses using CO-RE
     BPF_CORE_READ_INTO(&val.saddr, sk, __sk_common.skc_rcv_saddr);
@@ -95,21 +140,44 @@ int kprobe_tcp_connect(struct pt_regs *ctx) {
     return 0;
 }
 
-// kprobe/tcp_close - Fires when a TCP connection is closed.
+// emit_failure reports a connection that never completed its handshake,
+// attributed to the process that started it.
+static __always_inline void emit_failure(struct sock *sk, struct conn_val *val) {
+    struct tcp_event *event = bpf_ringbuf_reserve(&tcp_events, sizeof(*event), 0);
+    if (!event) {
+        count_ringbuf_lost();
+        return;
+    }
+
+    __u64 now = bpf_ktime_get_ns();
+    event->pid = val->pid;
+    event->uid = val->uid;
+    event->saddr = val->saddr;
+    event->daddr = val->daddr;
+    event->sport = val->sport;
+    event->dport = val->dport;
+    event->_pad = 0;
+    event->latency_ns = now - val->start_ns;
+    event->timestamp = now;
+    __builtin_memcpy(event->comm, val->comm, sizeof(event->comm));
+    event->failed = 1;
+    event->role = ROLE_CLIENT;
+    __builtin_memset(event->_pad2, 0, sizeof(event->_pad2));
+    event->sk_err = BPF_CORE_READ(sk, sk_err);
+
+    bpf_ringbuf_submit(event, 0);
+}
+
+// on_tcp_close - Fires when a TCP connection is closed.
 // Looks up the start time, computes latency, and emits an event.
-SEC("kprobe/tcp_close")
-int kprobe_tcp_close(struct pt_regs *ctx) {
-    struct sock *sk = (struct sock *)PT_REGS_PARM1(ctx);
+static __always_inline int on_tcp_close(struct sock *sk) {
     if (!sk)
         return 0;
 
     __u64 pid_tgid = bpf_get_current_pid_tgid();
     __u32 pid = pid_tgid >> 32;
 
-    struct conn_key key = {
-        .pid = pid,
-        .sock_ptr = (__u64)sk,
-    };
+    __u64 key = (__u64)sk;
 
     // Look up connection start data
     struct conn_val *val = bpf_map_lookup_elem(&conn_start, &key);
@@ -118,14 +186,19 @@ int kprobe_tcp_close(struct pt_regs *ctx) {
         return 0;
     }
 
+    // Still connecting: closing moves it from SYN_SENT to CLOSE, which
+    // inet_sock_set_state reports as a failure.
+    if (track_state && !val->established)
+        return 0;
+
     __u64 now = bpf_ktime_get_ns();
     __u64 latency_ns = now - val->start_ns;
 
     // Reserve space in ring buffer
     struct tcp_event *event = bpf_ringbuf_reserve(&tcp_events, sizeof(*event), 0);
     if (!event) {
-        // Ring buffer full - event is dropped.
-        // Userspace tracks this via the ring buffer overflow callback.
+        // Ring buffer full - event is dropped and counted.
+        count_ringbuf_lost();
         bpf_map_delete_elem(&conn_start, &key);
         return 0;
     }
@@ -140,6 +213,10 @@ int kprobe_tcp_close(struct pt_regs *ctx) {
     event->latency_ns = latency_ns;
     event->timestamp = now;
     bpf_get_current_comm(&event->comm, sizeof(event->comm));
+    event->failed = 0;
+    event->role = ROLE_CLIENT;
+    __builtin_memset(event->_pad2, 0, sizeof(event->_pad2));
+    event->sk_err = 0;
 
     bpf_ringbuf_submit(event, 0);
 
@@ -148,4 +225,99 @@ int kprobe_tcp_close(struct pt_regs *ctx) {
     return 0;
 }
 
+// inet_sock_set_state - Follows connections out of SYN_SENT: to
+// ESTABLISHED on success, or to CLOSE on failure (RST, timeout, ICMP
+// unreachable, or closed by the app while connecting). A server-side
+// child going from SYN_RECV to ESTABLISHED has entered the accept queue.
+SEC("tracepoint/sock/inet_sock_set_state")
+int tracepoint_inet_sock_set_state(struct trace_event_raw_inet_sock_set_state *ctx) {
+    if (ctx->protocol != IPPROTO_TCP)
+        return 0;
+
+    __u64 key = (__u64)ctx->skaddr;
+    if (ctx->oldstate == TCP_SYN_RECV_STATE && ctx->newstate == TCP_ESTABLISHED_STATE) {
+        __u64 now = bpf_ktime_get_ns();
+        bpf_map_update_elem(&accept_start, &key, &now, BPF_ANY);
+        return 0;
+    }
+    if (ctx->oldstate != TCP_SYN_SENT_STATE)
+        return 0;
+
+    struct conn_val *val = bpf_map_lookup_elem(&conn_start, &key);
+    if (!val)
+        return 0;
+
+    if (ctx->newstate == TCP_ESTABLISHED_STATE) {
+        val->established = 1;
+    } else if (ctx->newstate == TCP_CLOSE_STATE) {
+        emit_failure((struct sock *)ctx->skaddr, val);
+        bpf_map_delete_elem(&conn_start, &key);
+    }
+    return 0;
+}
+
+// kretprobe/inet_csk_accept - accept() took a connection off the queue.
+SEC("kretprobe/inet_csk_accept")
+int kretprobe_inet_csk_accept(struct pt_regs *ctx) {
+    struct sock *sk = (struct sock *)PT_REGS_RC(ctx);
+    if (!sk)
+        return 0;
+
+    __u64 key = (__u64)sk;
+    __u64 *start = bpf_map_lookup_elem(&accept_start, &key);
+    if (!start)
+        return 0;
+    __u64 now = bpf_ktime_get_ns();
+    __u64 queued_ns = now - *start;
+    bpf_map_delete_elem(&accept_start, &key);
+    if (excluded_current())
+        return 0;
+
+    struct tcp_event *event = bpf_ringbuf_reserve(&tcp_events, sizeof(*event), 0);
+    if (!event) {
+        count_ringbuf_lost();
+        return 0;
+    }
+
+    event->pid = bpf_get_current_pid_tgid() >> 32;
+    event->uid = bpf_get_current_uid_gid() & 0xFFFFFFFF;
+    BPF_CORE_READ_INTO(&event->saddr, sk, __sk_common.skc_rcv_saddr);
+    BPF_CORE_READ_INTO(&event->daddr, sk, __sk_common.skc_daddr);
+    BPF_CORE_READ_INTO(&event->sport, sk, __sk_common.skc_num);
+    BPF_CORE_READ_INTO(&event->dport, sk, __sk_common.skc_dport);
+    event->dport = bpf_ntohs(event->dport);
+    event->_pad = 0;
+    event->latency_ns = queued_ns;
+    event->timestamp = now;
+    bpf_get_current_comm(&event->comm, sizeof(event->comm));
+    event->failed = 0;
+    event->role = ROLE_SERVER;
+    __builtin_memset(event->_pad2, 0, sizeof(event->_pad2));
+    event->sk_err = 0;
+
+    bpf_ringbuf_submit(event, 0);
+    return 0;
+}
+
+SEC("kprobe/tcp_connect")
+int kprobe_tcp_connect(struct pt_regs *ctx) {
+    return on_tcp_connect((struct sock *)PT_REGS_PARM1(ctx));
+}
+
+SEC("kprobe/tcp_close")
+int kprobe_tcp_close(struct pt_regs *ctx) {
+    return on_tcp_close((struct sock *)PT_REGS_PARM1(ctx));
+}
+
+// fentry variants: Linux 5.5+ with BTF. Cheaper than kprobes, which trap.
+SEC("fentry/tcp_connect")
+int BPF_PROG(fentry_tcp_connect, struct sock *sk) {
+    return on_tcp_connect(sk);
+}
+
+SEC("fentry/tcp_close")
+int BPF_PROG(fentry_tcp_close, struct sock *sk, long timeout) {
+    return on_tcp_close(sk);
+}
+
 char LICENSE[] SEC("license") = "GPL";
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/api"
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config/backend"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// options are the API server's settings: defaults, then the YAML file,
+// then environment variables, then flags.
+type options struct {
+	cli.Common
+	api      api.Config
+	backend  backend.Config
+	logLevel string
+}
+
+// parseOptions resolves the API settings from the config file, env and
+// args. Bearer tokens come only from API_TOKENS or a token file — never
+// a flag, so they don't show up in ps output.
+func parseOptions(args []string, env cli.LookupEnv, output io.Writer) (options, error) {
+	o := options{
+		api:      api.DefaultConfig(),
+		backend:  backend.Default(),
+		logLevel: constants.DefaultLogLevel,
+	}
+	s := cli.NewSettings("api", env, output)
+	s.String(&o.api.Addr, "addr", "API_ADDR", "HTTP listen address")
+	s.String(&o.backend.Cache.Redis.Addr, "redis-addr", "REDIS_ADDR", "Redis address")
+	s.String(&o.logLevel, "log-level", "LOG_LEVEL", "log level: debug, info, warn or error")
+	s.String(&o.api.TLS.CertFile, "tls-cert-file", "API_TLS_CERT_FILE", "PEM certificate; serves HTTPS when set")
+	s.String(&o.api.TLS.KeyFile, "tls-key-file", "API_TLS_KEY_FILE", "PEM private key for --tls-cert-file")
+	s.String(&o.api.TLS.ClientCA, "tls-client-ca", "API_TLS_CLIENT_CA", "PEM CA bundle; requires client certificates it signed")
+	var tokenFile string
+	s.String(&tokenFile, "token-file", "API_TOKEN_FILE", `file of "name:token" lines, merged with API_TOKENS`)
+	cli.ClickHouseVars(s, &o.backend.Storage.ClickHouse)
+	o.Register(s.FlagSet())
+
+	err := s.Parse(args, func(path string) (err error) {
+		if o.backend, err = backend.Load(path); err != nil {
+			return err
+		}
+		return loadAPIConfig(path, &o.api)
+	})
+	if err != nil {
+		return o, err
+	}
+
+	if v, ok := env("API_TOKENS"); ok && v != "" {
+		tokens, err := api.ParseTokens(v)
+		if err != nil {
+			return o, fmt.Errorf("invalid API_TOKENS: %w", err)
+		}
+		o.api.Tokens = tokens
+	}
+	if tokenFile != "" {
+		fileTokens, err := api.LoadTokenFile(tokenFile)
+		if err != nil {
+			return o, fmt.Errorf("invalid token file: %w", err)
+		}
+		if o.api.Tokens == nil {
+			o.api.Tokens = fileTokens
+		} else {
+			maps.Copy(o.api.Tokens, fileTokens)
+		}
+	}
+	return o, nil
+}
+
+// loadAPIConfig reads the config file's api section over cfg. A missing
+// file leaves cfg as is, as backend.Load does. Tokens are never read from
+// the file.
+func loadAPIConfig(path string, cfg *api.Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("reading config %s: %w", path, err)
+	}
+	file := struct {
+		API *api.Config `yaml:"api"`
+	}{API: cfg}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return nil
+}
+
+// validate checks settings that are only used after startup.
+func (o options) validate() error {
+	var errs []error
+	if o.api.Addr == "" {
+		errs = append(errs, errors.New("addr is required"))
+	}
+	if err := o.api.Validate(); err != nil {
+		errs = append(errs, fmt.Errorf("api: %w", err))
+	}
+	if err := o.api.ValidateTokens(); err != nil {
+		errs = append(errs, fmt.Errorf("api: %w", err))
+	}
+	if _, err := config.ParseLogLevel(o.logLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.backend.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// effectiveConfig is what --validate-config prints. Secrets are left
+// out: the DSN password is masked and only token names are listed.
+func (o options) effectiveConfig() any {
+	type apiView struct {
+		Addr                 string              `yaml:"addr"`
+		TokenNames           []string            `yaml:"token_names"`
+		TokenNamespaces      map[string][]string `yaml:"token_namespaces"`
+		CORSOrigins          []string            `yaml:"cors_origins"`
+		CORSAllowCredentials bool                `yaml:"cors_allow_credentials"`
+		TLS                  api.TLSConfig       `yaml:"tls"`
+		Ingest               api.IngestConfig    `yaml:"ingest"`
+	}
+	b := o.backend
+	b.Storage.ClickHouse.DSN = b.Storage.ClickHouse.RedactedDSN()
+	return struct {
+		API      apiView               `yaml:"api"`
+		Storage  backend.StorageConfig `yaml:"storage"`
+		Cache    backend.CacheConfig   `yaml:"cache"`
+		LogLevel string                `yaml:"log_level"`
+	}{
+		API: apiView{
+			Addr:                 o.api.Addr,
+			TokenNames:           slices.Sorted(maps.Keys(o.api.Tokens)),
+			TokenNamespaces:      o.api.TokenNamespaces,
+			CORSOrigins:          o.api.CORSOrigins,
+			CORSAllowCredentials: o.api.CORSAllowCredentials,
+			TLS:                  o.api.TLS,
+			Ingest:               o.api.Ingest,
+		},
+		Storage:  b.Storage,
+		Cache:    b.Cache,
+		LogLevel: o.logLevel,
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestParseOptions_Precedence(t *testing.T) {
+	env := map[string]string{
+		"API_ADDR":             ":8100",
+		"REDIS_ADDR":           "redis-env:6379",
+		"CLICKHOUSE_DSN":       "clickhouse://env:9000/kubepulse",
+		"CLICKHOUSE_RETENTION": "24h",
+	}
+
+	o, err := parseOptions(nil, lookup(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.api.Addr != constants.APIDefaultAddr || o.logLevel != constants.DefaultLogLevel {
+		t.Errorf("defaults: addr = %q, log level = %q", o.api.Addr, o.logLevel)
+	}
+
+	o, err = parseOptions(nil, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.api.Addr != ":8100" || o.backend.Cache.Redis.Addr != "redis-env:6379" || o.backend.Storage.ClickHouse.Retention != 24*time.Hour {
+		t.Errorf("env: addr = %q, redis = %q, retention = %s", o.api.Addr, o.backend.Cache.Redis.Addr, o.backend.Storage.ClickHouse.Retention)
+	}
+
+	o, err = parseOptions([]string{"--addr=:8200", "--clickhouse-retention=1h"}, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.api.Addr != ":8200" || o.backend.Storage.ClickHouse.Retention != time.Hour {
+		t.Errorf("flags: addr = %q, retention = %s", o.api.Addr, o.backend.Storage.ClickHouse.Retention)
+	}
+	if o.backend.Cache.Redis.Addr != "redis-env:6379" || o.backend.Storage.ClickHouse.DSN != env["CLICKHOUSE_DSN"] {
+		t.Errorf("unset flags should keep env values: redis = %q, dsn = %q", o.backend.Cache.Redis.Addr, o.backend.Storage.ClickHouse.DSN)
+	}
+}
+
+func TestParseOptions_Tokens(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "tokens")
+	if err := os.WriteFile(file, []byte("ci:from-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	o, err := parseOptions([]string{"--token-file", file}, lookup(map[string]string{"API_TOKENS": "alice:a1"}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.api.Tokens["alice"] != "a1" || o.api.Tokens["ci"] != "from-file" {
+		t.Errorf("tokens = %v", o.api.Tokens)
+	}
+}
+
+func TestParseOptions_InvalidEnv(t *testing.T) {
+	if _, err := parseOptions(nil, lookup(map[string]string{"CLICKHOUSE_RETENTION": "soon"}), io.Discard); err == nil {
+		t.Error("invalid CLICKHOUSE_RETENTION accepted")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	o, err := parseOptions([]string{"--log-level=loud", "--clickhouse-retention=-1h"}, lookup(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.validate()
+	if err == nil || !strings.Contains(err.Error(), "loud") || !strings.Contains(err.Error(), "retention") {
+		t.Errorf("validate() = %v, want log level and retention errors", err)
+	}
+}
+
+func TestEffectiveConfig_HidesSecrets(t *testing.T) {
+	env := map[string]string{
+		"API_TOKENS":     "alice:s3cret-token",
+		"CLICKHOUSE_DSN": "clickhouse://user:hunter2@ch:9000/kubepulse",
+	}
+	o, err := parseOptions(nil, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if code := cli.ValidateConfig("api", o.effectiveConfig(), nil, &b, io.Discard); code != 0 {
+		t.Fatalf("exit code = %d", code)
+	}
+	out := b.String()
+	if strings.Contains(out, "hunter2") || strings.Contains(out, "s3cret-token") {
+		t.Errorf("effective config leaks a secret:\n%s", out)
+	}
+	if !strings.Contains(out, "alice") {
+		t.Errorf("effective config should list token names:\n%s", out)
+	}
+}
+
+func TestParseOptions_APISection(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubepulse.yaml")
+	yaml := `
+api:
+  addr: ":8443"
+  tokens: { mallory: from-file }
+  cors_origins: ["https://dash.example.com"]
+  cors_allow_credentials: true
+  tls: { cert_file: /tls/tls.crt, key_file: /tls/tls.key }
+`
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{"API_TLS_CLIENT_CA": "/tls/ca.crt"}
+	o, err := parseOptions([]string{"--config", path}, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.api.Addr != ":8443" || len(o.api.CORSOrigins) != 1 || o.api.CORSOrigins[0] != "https://dash.example.com" ||
+		!o.api.CORSAllowCredentials {
+		t.Errorf("api = %+v", o.api)
+	}
+	if o.api.TLS.CertFile != "/tls/tls.crt" || o.api.TLS.KeyFile != "/tls/tls.key" || o.api.TLS.ClientCA != "/tls/ca.crt" {
+		t.Errorf("tls = %+v", o.api.TLS)
+	}
+	if len(o.api.Tokens) != 0 {
+		t.Errorf("tokens read from the config file: %v", o.api.Tokens)
+	}
+	if err := o.validate(); err != nil {
+		t.Errorf("validate() = %v", err)
+	}
+
+	o.api.CORSOrigins = []string{"*"}
+	if err := o.validate(); err == nil || !strings.Contains(err.Error(), "cors_allow_credentials") {
+		t.Errorf("validate() = %v, want wildcard origin with credentials rejected", err)
+	}
+}
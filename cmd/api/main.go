@@ -3,53 +3,84 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/api"
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
 	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
-	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
 	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
+	// Flags (override env)
+	opts, err := parseOptions(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if opts.Version {
+		fmt.Println(buildinfo.String("kubepulse-api"))
+		return
+	}
+	if err == nil {
+		err = opts.validate()
+	}
+	if opts.ValidateConfig {
+		os.Exit(cli.ValidateConfig("kubepulse-api", opts.effectiveConfig(), err, os.Stdout, os.Stderr))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kubepulse-api:", err)
+		os.Exit(2)
+	}
+
+	logger, err := cli.NewLogger(opts.logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kubepulse-api:", err)
+		os.Exit(2)
+	}
 	defer logger.Sync()
 
-	logger.Info("KubePulse API starting")
+	logger.Info("KubePulse API starting", zap.String("version", buildinfo.Version))
 
 	// ClickHouse
-	chCfg := storage.DefaultClickHouseConfig()
-	if dsn := os.Getenv("CLICKHOUSE_DSN"); dsn != "" {
-		chCfg.DSN = dsn
-	}
-	ch, err := storage.NewClickHouse(chCfg, logger)
+	ch, err := storage.NewClickHouse(opts.backend.Storage.ClickHouse, logger)
 	if err != nil {
 		logger.Fatal("ClickHouse connection failed", zap.Error(err))
 	}
 	defer ch.Close()
+	prometheus.MustRegister(ch.PoolCollector())
 
-	// Redis
-	rCfg := cache.DefaultRedisConfig()
-	if addr := os.Getenv("REDIS_ADDR"); addr != "" {
-		rCfg.Addr = addr
-	}
-	redis, err := cache.NewRedis(rCfg, logger)
-	if err != nil {
-		logger.Fatal("Redis connection failed", zap.Error(err))
+	if opts.backend.Storage.ClickHouse.AutoMigrate {
+		if err := ch.Migrate(context.Background()); err != nil {
+			logger.Fatal("ClickHouse migration failed", zap.Error(err))
+		}
 	}
-	defer redis.Close()
 
-	// API Server
-	addr := constants.APIDefaultAddr
-	if a := os.Getenv("API_ADDR"); a != "" {
-		addr = a
+	// Redis is optional: without it the API serves everything from
+	// ClickHouse, minus saved views and live events.
+	var rc cache.Cache = cache.Disabled{}
+	if opts.backend.Cache.Enabled {
+		redis, err := cache.NewRedis(opts.backend.Cache.Redis, logger)
+		if err != nil {
+			logger.Warn("Redis connection failed — running without cache, saved views and live events", zap.Error(err))
+		} else {
+			rc = redis
+		}
+	} else {
+		logger.Info("Redis disabled — running without cache, saved views and live events")
 	}
+	defer rc.Close()
 
-	srv := api.NewServer(addr, ch, redis, logger)
+	// API Server
+	srv := api.NewServer(opts.api, ch, rc, logger)
 
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM)
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"io"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config/backend"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/consumer"
+)
+
+// options are the consumer's settings: defaults, then the YAML file,
+// then environment variables, then flags.
+type options struct {
+	cli.Common
+	backend     backend.Config
+	metricsAddr string
+	logLevel    string
+}
+
+// parseOptions resolves the consumer settings from the config file, env
+// and args.
+func parseOptions(args []string, env cli.LookupEnv, output io.Writer) (options, error) {
+	o := options{
+		backend:     backend.Default(),
+		metricsAddr: constants.ConsumerMetricsAddr,
+		logLevel:    constants.DefaultLogLevel,
+	}
+	s := cli.NewSettings("consumer", env, output)
+	s.String(&o.backend.Consumer.NATSURL, "nats-url", "NATS_URL", "NATS server URL")
+	s.StringAllowEmpty(&o.backend.Consumer.SpillDir, "spill-dir", "SPILL_DIR",
+		"directory for batches spilled while ClickHouse is down; empty (the default) disables spilling")
+	s.String(&o.metricsAddr, "metrics-addr", "METRICS_ADDR", "Prometheus metrics listen address")
+	s.String(&o.logLevel, "log-level", "LOG_LEVEL", "log level: debug, info, warn or error")
+	cli.ClickHouseVars(s, &o.backend.Storage.ClickHouse)
+	o.Register(s.FlagSet())
+
+	err := s.Parse(args, func(path string) (err error) {
+		o.backend, err = backend.Load(path)
+		return err
+	})
+	return o, err
+}
+
+// validate checks settings that are only used after startup.
+func (o options) validate() error {
+	var errs []error
+	if o.metricsAddr == "" {
+		errs = append(errs, errors.New("metrics-addr is required"))
+	}
+	if _, err := config.ParseLogLevel(o.logLevel); err != nil {
+		errs = append(errs, err)
+	}
+	if err := o.backend.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// effectiveConfig is what --validate-config prints, with the DSN
+// password masked.
+func (o options) effectiveConfig() any {
+	b := o.backend
+	b.Storage.ClickHouse.DSN = b.Storage.ClickHouse.RedactedDSN()
+	return struct {
+		Storage     backend.StorageConfig `yaml:"storage"`
+		Consumer    consumer.Config       `yaml:"consumer"`
+		MetricsAddr string                `yaml:"metrics_addr"`
+		LogLevel    string                `yaml:"log_level"`
+	}{b.Storage, b.Consumer, o.metricsAddr, o.logLevel}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestParseOptions_Precedence(t *testing.T) {
+	env := map[string]string{"NATS_URL": "nats://env:4222", "METRICS_ADDR": ":9300"}
+
+	o, err := parseOptions(nil, lookup(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.backend.Consumer.NATSURL != constants.NATSDefaultURL || o.metricsAddr != constants.ConsumerMetricsAddr {
+		t.Errorf("defaults: nats = %q, metrics = %q", o.backend.Consumer.NATSURL, o.metricsAddr)
+	}
+
+	o, err = parseOptions([]string{"--nats-url=nats://flag:4222"}, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.backend.Consumer.NATSURL != "nats://flag:4222" {
+		t.Errorf("flag should beat env: nats = %q", o.backend.Consumer.NATSURL)
+	}
+	if o.metricsAddr != ":9300" {
+		t.Errorf("env should beat default: metrics = %q", o.metricsAddr)
+	}
+}
+
+func TestParseOptions_EmptySpillDirDisables(t *testing.T) {
+	o, err := parseOptions(nil, lookup(map[string]string{"SPILL_DIR": ""}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.backend.Consumer.SpillDir != "" {
+		t.Errorf("SPILL_DIR=\"\" should disable spilling, got %q", o.backend.Consumer.SpillDir)
+	}
+
+	o, err = parseOptions([]string{"--spill-dir="}, lookup(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.backend.Consumer.SpillDir != "" {
+		t.Errorf("--spill-dir= should disable spilling, got %q", o.backend.Consumer.SpillDir)
+	}
+}
+
+func TestValidate_RejectsBadLevel(t *testing.T) {
+	o, err := parseOptions(nil, lookup(map[string]string{"LOG_LEVEL": "loud"}), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.validate() == nil {
+		t.Error("validate accepted LOG_LEVEL=loud")
+	}
+}
+
+func TestParseOptions_FileEnvFlagLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kubepulse.yaml")
+	yaml := "consumer:\n  nats_url: nats://file:4222\n  batch_size: 777\n  spill_dir: /from/file\n" +
+		"storage:\n  clickhouse:\n    retention: 48h\n"
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	env := map[string]string{constants.EnvConfigPath: path, "NATS_URL": "nats://env:4222"}
+
+	o, err := parseOptions([]string{"--clickhouse-retention=1h"}, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := o.backend.Consumer
+	if c.BatchSize != 777 || c.SpillDir != "/from/file" {
+		t.Errorf("file values lost: batch_size = %d, spill_dir = %q", c.BatchSize, c.SpillDir)
+	}
+	if c.NATSURL != "nats://env:4222" {
+		t.Errorf("env should beat file: nats_url = %q", c.NATSURL)
+	}
+	if got := o.backend.Storage.ClickHouse.Retention; got != time.Hour {
+		t.Errorf("flag should beat file: retention = %s", got)
+	}
+
+	o, err = parseOptions([]string{"--config", path, "--nats-url", "nats://flag:4222"}, lookup(env), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.backend.Consumer.NATSURL != "nats://flag:4222" {
+		t.Errorf("flag should beat env: nats_url = %q", o.backend.Consumer.NATSURL)
+	}
+}
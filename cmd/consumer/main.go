@@ -3,44 +3,96 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/consumer"
 	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
 )
 
 func main() {
-	logger, _ := zap.NewProduction()
+	// Flags (override env)
+	opts, err := parseOptions(os.Args[1:], os.LookupEnv, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if opts.Version {
+		fmt.Println(buildinfo.String("kubepulse-consumer"))
+		return
+	}
+	if err == nil {
+		err = opts.validate()
+	}
+	if opts.ValidateConfig {
+		os.Exit(cli.ValidateConfig("kubepulse-consumer", opts.effectiveConfig(), err, os.Stdout, os.Stderr))
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kubepulse-consumer:", err)
+		os.Exit(2)
+	}
+
+	logger, err := cli.NewLogger(opts.logLevel)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "kubepulse-consumer:", err)
+		os.Exit(2)
+	}
 	defer logger.Sync()
 
-	logger.Info("KubePulse consumer starting")
+	logger.Info("KubePulse consumer starting", zap.String("version", buildinfo.Version))
 
 	// ClickHouse
-	chCfg := storage.DefaultClickHouseConfig()
-	if dsn := os.Getenv("CLICKHOUSE_DSN"); dsn != "" {
-		chCfg.DSN = dsn
-	}
-	ch, err := storage.NewClickHouse(chCfg, logger)
+	ch, err := storage.NewClickHouse(opts.backend.Storage.ClickHouse, logger)
 	if err != nil {
 		logger.Fatal("Failed to connect to ClickHouse", zap.Error(err))
 	}
 	defer ch.Close()
 
-	// Consumer
-	cfg := consumer.DefaultConfig()
-	if url := os.Getenv("NATS_URL"); url != "" {
-		cfg.NATSURL = url
+	if opts.backend.Storage.ClickHouse.AutoMigrate {
+		if err := ch.Migrate(context.Background()); err != nil {
+			logger.Fatal("ClickHouse migration failed", zap.Error(err))
+		}
 	}
 
+	c := consumer.New(opts.backend.Consumer, ch, logger)
+
+	// Metrics (pipeline, lag, build info), /healthz (lag) and /version
+	mux := http.NewServeMux()
+	mux.Handle(constants.PathMetrics, promhttp.Handler())
+	mux.Handle(constants.PathHealthz, c.HealthHandler())
+	mux.Handle(constants.PathVersion, buildinfo.Handler())
+	go func() {
+		if err := http.ListenAndServe(opts.metricsAddr, mux); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics server failed", zap.Error(err))
+		}
+	}()
+
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
-	c := consumer.New(cfg, ch, logger)
+	// Redis — only needed to feed the API's live WebSocket stream.
+	if opts.backend.Consumer.Live.Enabled {
+		rc, err := cache.NewRedis(opts.backend.Cache.Redis, logger)
+		if err != nil {
+			logger.Warn("Redis unavailable, live events disabled", zap.Error(err))
+		} else {
+			defer rc.Close()
+			c.EnableLive(rc)
+		}
+	}
+
 	if err := c.Run(ctx); err != nil && ctx.Err() == nil {
 		logger.Fatal("Consumer error", zap.Error(err))
 	}
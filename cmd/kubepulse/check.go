@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+	execprobe "github.com/sureshkrishnan-v/kubePulse/internal/probes/exec"
+	"github.com/sureshkrishnan-v/kubePulse/internal/selfcheck"
+)
+
+// runCheck implements `kubepulse check`: it verifies the node can run the
+// agent, prints a pass/warn/fail table and returns the exit code, 1 if
+// any check failed.
+func runCheck(ctx context.Context, args []string, sys selfcheck.System, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("kubepulse check", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: kubepulse check\n\nVerifies this node can run the agent and exits non-zero on hard failures.")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(stderr, "kubepulse check: unexpected argument %q\n", fs.Arg(0))
+		return 2
+	}
+
+	results := sys.Run(ctx)
+	if err := selfcheck.Print(stdout, results); err != nil {
+		fmt.Fprintf(stderr, "kubepulse check: %v\n", err)
+		return 1
+	}
+	if selfcheck.Failed(results) {
+		return 1
+	}
+	return 0
+}
+
+// checkSystem is the node `kubepulse check` inspects; the verifier check
+// loads the exec probe, the smallest.
+func checkSystem() selfcheck.System {
+	return selfcheck.DefaultSystem(execprobe.LoadSpec, metadata.Ping)
+}
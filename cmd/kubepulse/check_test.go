@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/caps"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+	"github.com/sureshkrishnan-v/kubePulse/internal/selfcheck"
+)
+
+// failingSystem fails every check that can fail without touching the kernel.
+func failingSystem() selfcheck.System {
+	return selfcheck.System{
+		Capabilities:  func() (caps.Set, error) { return 0, nil },
+		RemoveMemlock: func() error { return nil },
+		Kernel:        func() *kernelfeat.Features { return kernelfeat.Source{}.Detect() },
+		Ringbuf:       func() error { return ebpf.ErrNotSupported },
+		Kallsyms:      func() (map[string]bool, error) { return nil, errors.New("unreadable") },
+		Spec:          func() (*ebpf.CollectionSpec, error) { return nil, errors.New("no objects") },
+		Ping:          func(context.Context) (string, error) { return "", errors.New("no cluster") },
+	}
+}
+
+func TestRunCheck_ExitCode(t *testing.T) {
+	var stdout bytes.Buffer
+	if code := runCheck(context.Background(), nil, failingSystem(), &stdout, io.Discard); code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "FAIL") {
+		t.Errorf("table has no failures:\n%s", stdout.String())
+	}
+}
+
+func TestRunCheck_Args(t *testing.T) {
+	if code := runCheck(context.Background(), []string{"-h"}, failingSystem(), io.Discard, io.Discard); code != 0 {
+		t.Errorf("-h exit code = %d, want 0", code)
+	}
+	if code := runCheck(context.Background(), []string{"extra"}, failingSystem(), io.Discard, io.Discard); code != 2 {
+		t.Errorf("extra argument exit code = %d, want 2", code)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"io"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// options are the agent's command-line settings.
+type options struct {
+	cli.Common
+	source config.Source
+}
+
+// parseFlags parses the agent's arguments. Setting flags become
+// config.Overrides, so they win over both the file and the environment.
+func parseFlags(args []string, env cli.LookupEnv, output io.Writer) (options, error) {
+	var o options
+	fs := flag.NewFlagSet("kubepulse", flag.ContinueOnError)
+	fs.SetOutput(output)
+
+	o.source.Path = constants.DefaultConfigPath
+	cli.StringVar(fs, env, &o.source.Path, "config", constants.EnvConfigPath, "path to the YAML config file")
+	fs.StringVar(&o.source.Overrides.LogLevel, "log-level", "",
+		"log level: debug, info, warn or error (overrides agent.log_level and "+constants.EnvLogLevel+")")
+	fs.StringVar(&o.source.Overrides.MetricsAddr, "metrics-addr", "",
+		"metrics/health listen address (overrides agent.metrics_addr and "+constants.EnvMetricsAddr+")")
+	fs.StringVar(&o.source.Overrides.NodeName, "node-name", "",
+		"node name attached to events (overrides agent.node_name and "+constants.EnvNodeName+")")
+	o.Register(fs)
+
+	err := fs.Parse(args)
+	return o, err
+}
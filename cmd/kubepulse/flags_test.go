@@ -0,0 +1,62 @@
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func lookup(env map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := env[k]
+		return v, ok
+	}
+}
+
+func TestParseFlags_ConfigPath(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		env  map[string]string
+		want string
+	}{
+		{"default", nil, nil, constants.DefaultConfigPath},
+		{"env", nil, map[string]string{constants.EnvConfigPath: "/etc/env.yaml"}, "/etc/env.yaml"},
+		{"flag over env", []string{"--config", "/etc/flag.yaml"},
+			map[string]string{constants.EnvConfigPath: "/etc/env.yaml"}, "/etc/flag.yaml"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o, err := parseFlags(tt.args, lookup(tt.env), io.Discard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if o.source.Path != tt.want {
+				t.Errorf("config path = %q, want %q", o.source.Path, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFlags_Overrides(t *testing.T) {
+	o, err := parseFlags([]string{
+		"--log-level=debug", "--metrics-addr=:9999", "--node-name=n1", "--validate-config",
+	}, lookup(nil), io.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ov := o.source.Overrides
+	if ov.LogLevel != "debug" || ov.MetricsAddr != ":9999" || ov.NodeName != "n1" {
+		t.Errorf("overrides = %+v", ov)
+	}
+	if !o.ValidateConfig || o.Version {
+		t.Errorf("validate-config = %v, version = %v", o.ValidateConfig, o.Version)
+	}
+}
+
+func TestParseFlags_UnknownFlag(t *testing.T) {
+	if _, err := parseFlags([]string{"--nope"}, lookup(nil), io.Discard); err == nil {
+		t.Error("unknown flag accepted")
+	}
+}
@@ -14,13 +14,22 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 	"os/signal"
 	"syscall"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/agent"
+	"github.com/sureshkrishnan-v/kubePulse/internal/alert"
+	"github.com/sureshkrishnan-v/kubePulse/internal/api"
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
+	"github.com/sureshkrishnan-v/kubePulse/internal/cli"
 	"github.com/sureshkrishnan-v/kubePulse/internal/config"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/export"
@@ -31,27 +40,89 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/probes/oom"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probes/retransmit"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probes/rst"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probes/signals"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probes/tcp"
+	"github.com/sureshkrishnan-v/kubePulse/internal/redact"
+	"github.com/sureshkrishnan-v/kubePulse/internal/standalone"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "check" {
+		os.Exit(runCheck(context.Background(), os.Args[2:], checkSystem(), os.Stdout, os.Stderr))
+	}
+
+	// Flags (override env, which overrides the config file)
+	opts, err := parseFlags(os.Args[1:], os.LookupEnv, os.Stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		os.Exit(2)
+	}
+	if opts.Version {
+		fmt.Println(buildinfo.String("kubepulse"))
+		return
+	}
+	if opts.ValidateConfig {
+		cfg, err := opts.source.Load()
+		if err == nil {
+			// The webhook URL may embed a token, the salt keeps hashed
+			// labels irreversible, a bcrypt hash can be brute-forced
+			// offline, and remote write and HTTP push credentials are
+			// plain; mask them.
+			cfg = cfg.Clone()
+			cfg.Exporters.Alerts.WebhookURL = cfg.Exporters.Alerts.RedactedWebhookURL()
+			if cfg.Redaction.Salt != "" {
+				cfg.Redaction.Salt = "xxxxx"
+			}
+			if cfg.Exporters.Prometheus.BasicAuth.PasswordHash != "" {
+				cfg.Exporters.Prometheus.BasicAuth.PasswordHash = "xxxxx"
+			}
+			rw := &cfg.Exporters.RemoteWrite
+			if rw.BearerToken != "" {
+				rw.BearerToken = "xxxxx"
+			}
+			if rw.BasicAuth.Password != "" {
+				rw.BasicAuth.Password = "xxxxx"
+			}
+			rw.URL = rw.RedactedURL()
+			if cfg.Exporters.HTTPPush.BearerToken != "" {
+				cfg.Exporters.HTTPPush.BearerToken = "xxxxx"
+			}
+		}
+		os.Exit(cli.ValidateConfig("kubepulse", cfg, err, os.Stdout, os.Stderr))
+	}
+
 	// Logger
+	// The level is an AtomicLevel so a config reload can change it live.
 	logCfg := zap.NewProductionConfig()
 	logCfg.EncoderConfig.TimeKey = "ts"
 	logCfg.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	logger, _ := logCfg.Build()
 	defer logger.Sync()
 
-	logger.Info("KubePulse starting", zap.String("version", constants.Version))
+	logger.Info("KubePulse starting", zap.String("version", buildinfo.Version))
 
-	// Config (YAML + env overrides)
-	cfg, err := config.Load(constants.DefaultConfigPath)
+	// Config (YAML + env + flag overrides)
+	cfg, err := opts.source.Load()
 	if err != nil {
 		logger.Fatal("Failed to load config", zap.Error(err))
 	}
+	if lvl, err := config.ParseLogLevel(cfg.Agent.LogLevel); err == nil {
+		logCfg.Level.SetLevel(lvl)
+	}
 
 	// Runtime (Facade pattern)
-	rt := agent.NewRuntime(cfg, logger)
+	rt := agent.NewRuntime(cfg, logger, logCfg.Level)
+
+	// Redaction runs on every event before the bus hands it to exporters.
+	if cfg.Redaction.Enabled() {
+		redactor, err := redact.New(cfg.Redaction)
+		if err != nil {
+			logger.Fatal("Invalid redaction config", zap.Error(err))
+		}
+		rt.EventBus().Intercept(redactor.Apply)
+	}
 
 	// ─── Register modules (Factory + Registry pattern) ─────────
 	// Each module uses New() constructor — no raw struct literals.
@@ -67,19 +138,121 @@ func main() {
 	rt.RegisterModule(execprobe.New())
 	rt.RegisterModule(fileio.New())
 	rt.RegisterModule(drop.New())
+	rt.RegisterModule(signals.New())
 
 	// ─── Register exporters (Observer pattern) ─────────────────
 	// Prometheus exporter subscribes to EventBus automatically.
-	// Future: add OTLP, Kafka, etc.
-	rt.RegisterExporter(export.NewPrometheus(
-		cfg.Exporters.Prometheus.Addr, rt.EventBus(), logger,
-	))
+	// NATS JetStream feeds the consumer when exporters.nats.enabled is set.
+	// Remote write pushes the same metrics when exporters.remote_write.enabled is set.
+	// HTTP push sends events to the API's ingest endpoint, without NATS,
+	// when exporters.http_push.enabled is set.
+	// The file exporter writes rotated NDJSON when exporters.file.enabled is set.
+	// Alert rules post to a webhook and/or Alertmanager when
+	// exporters.alerts.enabled is set. mode: standalone adds the embedded
+	// store and API server.
+	prom := export.NewPrometheus(cfg.Exporters.Prometheus.Addr,
+		cfg.Exporters.Prometheus.PrometheusOptions, rt.EventBus(), prometheus.DefaultRegisterer, logger)
+	if err := prom.Secure(cfg.Exporters.Prometheus.MetricsSecurity); err != nil {
+		logger.Fatal("Invalid metrics server TLS or auth config", zap.Error(err))
+	}
+	if cfg.Redaction.Action(constants.KeyQName) != redact.ActionKeep {
+		prom.QNamesRedacted()
+	}
+	prom.WatchPods(rt.MetaCache())
+	prom.WatchBPF(rt.BPFResources)
+	prom.WatchModules(rt.Readiness())
+	prom.Handle(constants.PathVersion, buildinfo.Handler())
+	// The log level and module toggles change what the agent does, and can
+	// stop its probes: only behind basic auth.
+	if cfg.Exporters.Prometheus.MetricsSecurity.BasicAuth.Username != "" {
+		prom.Handle(constants.PathAdminLogLevel, agent.LogLevelHandler(logCfg.Level, logger))
+		for pattern, h := range rt.ModuleHandlers() {
+			prom.Handle(pattern, h)
+		}
+	} else {
+		logger.Info("Admin endpoints disabled — they need exporters.prometheus basic_auth")
+	}
+	for pattern, h := range rt.DebugHandlers() {
+		prom.Handle(pattern, h)
+	}
+	rt.RegisterExporter(prom)
+	if cfg.Exporters.NATS.Enabled {
+		rt.RegisterExporter(export.NewNATSExporter(
+			cfg.Exporters.NATS.NATSConfig, rt.EventBus(), logger,
+		))
+	}
+	if cfg.Exporters.RemoteWrite.Enabled {
+		rw := export.NewRemoteWrite(cfg.Exporters.RemoteWrite.RemoteWriteConfig,
+			cfg.Exporters.Prometheus.PrometheusOptions, rt.EventBus(), logger)
+		if cfg.Redaction.Action(constants.KeyQName) != redact.ActionKeep {
+			rw.QNamesRedacted()
+		}
+		rw.WatchPods(rt.MetaCache())
+		rt.RegisterExporter(rw)
+	}
+	if cfg.Exporters.HTTPPush.Enabled {
+		rt.RegisterExporter(export.NewHTTPPush(
+			cfg.Exporters.HTTPPush.HTTPPushConfig, rt.EventBus(), logger,
+		))
+	}
+	if cfg.Exporters.File.Enabled {
+		rt.RegisterExporter(export.NewFileExporter(
+			cfg.Exporters.File.FileConfig, rt.EventBus(), logger,
+		))
+	}
+	if cfg.Exporters.Alerts.Enabled {
+		alerts, err := alert.New(cfg.Exporters.Alerts.Config, rt.EventBus(), logger)
+		if err != nil {
+			logger.Fatal("Invalid alert rules", zap.Error(err))
+		}
+		if cfg.Exporters.Alertmanager.Enabled {
+			alerts.SendToAlertmanager(cfg.Exporters.Alertmanager.AlertmanagerConfig)
+		}
+		rt.RegisterExporter(alerts)
+	}
+
+	// Standalone mode stores events in an embedded SQLite database and
+	// serves the API from this process. API tokens come from API_TOKENS,
+	// as for cmd/api.
+	if cfg.Mode == config.ModeStandalone {
+		sa := cfg.Standalone
+		if v, ok := os.LookupEnv("API_TOKENS"); ok && v != "" {
+			tokens, err := api.ParseTokens(v)
+			if err != nil {
+				logger.Fatal("Invalid API_TOKENS", zap.Error(err))
+			}
+			sa.API.Tokens = tokens
+		}
+		if err := sa.API.ValidateTokens(); err != nil {
+			logger.Fatal("Invalid standalone API config", zap.Error(err))
+		}
+		backend, err := standalone.New(sa, rt.EventBus(), logger)
+		if err != nil {
+			logger.Fatal("Failed to open the standalone store", zap.Error(err))
+		}
+		rt.RegisterExporter(backend)
+	}
 
 	// ─── Run (Facade pattern) ──────────────────────────────────
 	ctx, cancel := signal.NotifyContext(context.Background(),
 		syscall.SIGINT, syscall.SIGTERM)
 	defer cancel()
 
+	// SIGHUP re-reads the config and applies what can change live.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				logger.Info("SIGHUP received — reloading config")
+				rt.Reload(opts.source)
+			}
+		}
+	}()
+
 	if err := rt.Run(ctx); err != nil && ctx.Err() == nil {
 		logger.Fatal("Runtime error", zap.Error(err))
 	}
@@ -0,0 +1,102 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+// adaptiveModules are the high-volume modules adaptive sampling may
+// throttle. OOM, drop and signal events are rare and each one matters, so
+// they are never sampled (see config.Validate and Sampler.Paused).
+var adaptiveModules = []string{constants.ModuleTCP, constants.ModuleFileIO, constants.ModuleExec}
+
+var effectiveRate = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: constants.MetricSamplingRate,
+	Help: "Current sampling probability per module, after adaptive backoff.",
+}, constants.LabelsModule)
+
+// adaptiveSampler is a feedback loop on the event bus: when any subscriber
+// drops more than constants.AdaptiveDropThreshold of published events in an
+// interval, it halves the sampling scale of the high-volume modules; once
+// drops subside it steps the scale back up to 1. Decrease is multiplicative
+// and recovery additive, so the loop settles instead of oscillating.
+type adaptiveSampler struct {
+	bus      *event.Bus
+	samplers map[string]*probe.Sampler // all modules, for the gauge
+	logger   *zap.Logger
+
+	scale     float64
+	published uint64
+	dropped   map[string]uint64
+}
+
+func newAdaptiveSampler(bus *event.Bus, samplers map[string]*probe.Sampler, logger *zap.Logger) *adaptiveSampler {
+	a := &adaptiveSampler{bus: bus, samplers: samplers, logger: logger, scale: 1}
+	a.baseline(bus.Stats())
+	return a
+}
+
+// Run adjusts sampling every constants.AdaptiveSamplingInterval until ctx
+// is cancelled.
+func (a *adaptiveSampler) Run(ctx context.Context) {
+	ticker := time.NewTicker(constants.AdaptiveSamplingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			a.tick()
+		}
+	}
+}
+
+// tick reads the drops since the last tick and moves the scale.
+func (a *adaptiveSampler) tick() {
+	stats := a.bus.Stats()
+	published := stats.Published - a.published
+	var worst float64
+	if published > 0 {
+		for name, n := range stats.DroppedBySubscriber {
+			worst = max(worst, float64(n-a.dropped[name])/float64(published))
+		}
+	}
+	a.baseline(stats)
+
+	prev := a.scale
+	if worst > constants.AdaptiveDropThreshold {
+		a.scale = max(a.scale*constants.AdaptiveBackoff, constants.AdaptiveMinScale)
+	} else {
+		a.scale = min(a.scale+constants.AdaptiveRecoveryStep, 1)
+	}
+	if a.scale != prev {
+		a.logger.Debug("Adaptive sampling adjusted",
+			zap.Float64("drop_ratio", worst), zap.Float64("scale", a.scale))
+	}
+	for _, name := range adaptiveModules {
+		if s, ok := a.samplers[name]; ok {
+			s.SetScale(a.scale)
+		}
+	}
+	a.report()
+}
+
+func (a *adaptiveSampler) baseline(stats event.Stats) {
+	a.published = stats.Published
+	a.dropped = stats.DroppedBySubscriber
+}
+
+// report publishes every module's effective rate.
+func (a *adaptiveSampler) report() {
+	for name, s := range a.samplers {
+		effectiveRate.WithLabelValues(name).Set(s.Effective())
+	}
+}
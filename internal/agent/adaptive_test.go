@@ -0,0 +1,88 @@
+package agent
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+// flood offers n records to s and publishes the ones it keeps, as a
+// probe's read loop would.
+func flood(bus *event.Bus, s *probe.Sampler, n int) {
+	for range n {
+		if s.Keep() {
+			bus.Publish(event.Acquire())
+		}
+	}
+}
+
+func drain(ch <-chan *event.Event) {
+	for {
+		select {
+		case <-ch:
+		default:
+			return
+		}
+	}
+}
+
+func TestAdaptiveSampler_BacksOffAndRecovers(t *testing.T) {
+	bus := event.NewBus(constants.MinEventBusBuffer, zap.NewNop())
+	sub := bus.Subscribe("slow")
+	samplers := map[string]*probe.Sampler{
+		constants.ModuleTCP: probe.NewSampler(1),
+		constants.ModuleOOM: probe.NewSampler(1),
+	}
+	a := newAdaptiveSampler(bus, samplers, zap.NewNop())
+	tcp, oom := samplers[constants.ModuleTCP], samplers[constants.ModuleOOM]
+
+	// Storm: the subscriber never reads, so nearly every event drops.
+	for range 3 {
+		flood(bus, tcp, 10_000)
+		a.tick()
+	}
+	if got := tcp.Effective(); got != 0.125 {
+		t.Errorf("tcp effective rate after 3 saturated ticks = %v, want 0.125", got)
+	}
+	if got := oom.Effective(); got != 1 {
+		t.Errorf("oom effective rate = %v, want 1 (never sampled)", got)
+	}
+
+	// Floor: sustained pressure never silences the module.
+	for range 20 {
+		flood(bus, tcp, 10_000)
+		a.tick()
+	}
+	if got := tcp.Effective(); got != constants.AdaptiveMinScale {
+		t.Errorf("tcp effective rate under sustained flood = %v, want %v", got, constants.AdaptiveMinScale)
+	}
+
+	// Calm: the subscriber keeps up, so the scale steps back to 1.
+	for range 20 {
+		drain(sub)
+		flood(bus, tcp, 10)
+		a.tick()
+	}
+	if got := tcp.Effective(); got != 1 {
+		t.Errorf("tcp effective rate after recovery = %v, want 1", got)
+	}
+}
+
+func TestAdaptiveSampler_KeepsConfiguredRate(t *testing.T) {
+	bus := event.NewBus(constants.MinEventBusBuffer, zap.NewNop())
+	bus.Subscribe("slow")
+	tcp := probe.NewSampler(0.5)
+	a := newAdaptiveSampler(bus, map[string]*probe.Sampler{constants.ModuleTCP: tcp}, zap.NewNop())
+
+	for range constants.MinEventBusBuffer * 2 {
+		bus.Publish(event.Acquire())
+	}
+	a.tick() // half the events dropped: back off once
+	if tcp.Rate() != 0.5 || tcp.Effective() != 0.25 {
+		t.Errorf("rate = %v, effective = %v; want 0.5 and 0.25", tcp.Rate(), tcp.Effective())
+	}
+}
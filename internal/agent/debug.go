@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+// DebugHandlers returns the diagnostics endpoints to mount on the metrics
+// server, keyed by pattern: the net/http/pprof handlers under
+// /debug/pprof/ and a JSON snapshot of runtime state at /debug/vars.
+// Returns nil unless agent.debug_endpoints is set.
+func (rt *Runtime) DebugHandlers() map[string]http.Handler {
+	if !rt.config().Agent.DebugEndpoints {
+		return nil
+	}
+	return map[string]http.Handler{
+		constants.PathDebugPprof:             http.HandlerFunc(pprof.Index),
+		constants.PathDebugPprof + "cmdline": http.HandlerFunc(pprof.Cmdline),
+		constants.PathDebugPprof + "profile": http.HandlerFunc(pprof.Profile),
+		constants.PathDebugPprof + "symbol":  http.HandlerFunc(pprof.Symbol),
+		constants.PathDebugPprof + "trace":   http.HandlerFunc(pprof.Trace),
+		constants.PathDebugVars:              http.HandlerFunc(rt.serveDebugVars),
+	}
+}
+
+// debugVars is the /debug/vars document.
+type debugVars struct {
+	Version    string                `json:"version"`
+	Cmdline    []string              `json:"cmdline"`
+	Uptime     string                `json:"uptime"`
+	Goroutines int                   `json:"goroutines"`
+	MemStats   runtime.MemStats      `json:"memstats"`
+	Bus        busVars               `json:"bus"`
+	Metadata   metadataVars          `json:"metadata_cache"`
+	Modules    map[string]moduleVars `json:"modules"`
+}
+
+type busVars struct {
+	Published           uint64            `json:"published"`
+	DroppedBySubscriber map[string]uint64 `json:"dropped_by_subscriber"`
+	QueueDepth          map[string]int    `json:"queue_depth"`
+}
+
+type metadataVars struct {
+	PIDEntries       int `json:"pid_entries"`
+	ContainerEntries int `json:"container_entries"`
+}
+
+type moduleVars struct {
+	Started      bool    `json:"started"`
+	Published    uint64  `json:"published"`
+	SamplingRate float64 `json:"sampling_rate"`
+	AttachMode   string  `json:"attach_mode,omitempty"`
+}
+
+var processStart = time.Now()
+
+func (rt *Runtime) serveDebugVars(w http.ResponseWriter, _ *http.Request) {
+	stats := rt.bus.Stats()
+	vars := debugVars{
+		Version:    buildinfo.Version,
+		Cmdline:    os.Args,
+		Uptime:     time.Since(processStart).Round(time.Second).String(),
+		Goroutines: runtime.NumGoroutine(),
+		Bus: busVars{
+			Published:           stats.Published,
+			DroppedBySubscriber: stats.DroppedBySubscriber,
+			QueueDepth:          stats.QueueDepth,
+		},
+		Modules: make(map[string]moduleVars, len(rt.modules)),
+	}
+	runtime.ReadMemStats(&vars.MemStats)
+	vars.Metadata.PIDEntries, vars.Metadata.ContainerEntries = rt.metaCache.Stats()
+
+	rt.mu.Lock()
+	for _, m := range rt.modules {
+		name := m.Name()
+		mv := moduleVars{
+			Started:      rt.started[name],
+			Published:    stats.PublishedByType[name],
+			SamplingRate: rt.samplers[name].Effective(),
+		}
+		// The mode is set during Init, which is done once started is.
+		if r, ok := m.(probe.AttachReporter); ok && mv.Started {
+			mv.AttachMode = r.AttachMode()
+		}
+		vars.Modules[name] = mv
+	}
+	rt.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, vars)
+}
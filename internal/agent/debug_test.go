@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// debugServer mounts rt's debug handlers next to a stand-in /metrics, as
+// the Prometheus exporter does.
+func debugServer(t *testing.T, rt *Runtime) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(constants.PathMetrics, http.NotFoundHandler())
+	for pattern, h := range rt.DebugHandlers() {
+		mux.Handle(pattern, h)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, url string) *http.Response {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestDebugHandlers_Gated(t *testing.T) {
+	paths := []string{constants.PathDebugPprof, constants.PathDebugPprof + "heap", constants.PathDebugVars}
+	for _, enabled := range []bool{false, true} {
+		rt, _ := newTestRuntime(t)
+		rt.cfg.Agent.DebugEndpoints = enabled
+		srv := debugServer(t, rt)
+
+		want := http.StatusNotFound
+		if enabled {
+			want = http.StatusOK
+		}
+		for _, path := range paths {
+			if got := get(t, srv.URL+path).StatusCode; got != want {
+				t.Errorf("debug_endpoints=%v: GET %s = %d, want %d", enabled, path, got, want)
+			}
+		}
+	}
+}
+
+func TestDebugVars(t *testing.T) {
+	rt, _ := newTestRuntime(t)
+	rt.cfg.Agent.DebugEndpoints = true
+	e := event.Acquire()
+	e.Type = event.TypeTCP
+	rt.bus.Publish(e)
+
+	var vars debugVars
+	if err := json.NewDecoder(get(t, debugServer(t, rt).URL+constants.PathDebugVars).Body).Decode(&vars); err != nil {
+		t.Fatal(err)
+	}
+	if vars.Bus.Published != 1 {
+		t.Errorf("bus.published = %d, want 1", vars.Bus.Published)
+	}
+	tcp, ok := vars.Modules[constants.ModuleTCP]
+	if !ok || !tcp.Started || tcp.Published != 1 || tcp.SamplingRate != 1 {
+		t.Errorf("modules.tcp = %+v, want started with 1 event at rate 1", tcp)
+	}
+	if vars.MemStats.HeapAlloc == 0 || vars.Goroutines == 0 {
+		t.Error("runtime stats missing")
+	}
+}
@@ -0,0 +1,45 @@
+package agent
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+var kernelFeature = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: constants.MetricKernelFeature,
+	Help: "Kernel capabilities detected at startup: 1 if available, 0 if not.",
+}, constants.LabelsFeature)
+
+var attachMode = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: constants.MetricAttachMode,
+	Help: "Attach strategy chosen by each module at init; 1 for the active mode.",
+}, constants.LabelsModuleMode)
+
+// reportAttachMode exports m's attach mode, if it chooses one.
+func reportAttachMode(m probe.Module) {
+	if r, ok := m.(probe.AttachReporter); ok && r.AttachMode() != "" {
+		attachMode.WithLabelValues(m.Name(), r.AttachMode()).Set(1)
+	}
+}
+
+// reportKernel logs the capabilities detected in k and exports them as
+// kubepulse_kernel_feature{feature}.
+func reportKernel(k *kernelfeat.Features, logger *zap.Logger) {
+	features := k.Map()
+	for name, ok := range features {
+		v := 0.0
+		if ok {
+			v = 1
+		}
+		kernelFeature.WithLabelValues(name).Set(v)
+	}
+	logger.Info("Kernel features detected",
+		zap.String("release", k.Release),
+		zap.Stringer("version", k.Version),
+		zap.Any("features", features))
+}
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+func TestReportKernel(t *testing.T) {
+	src := kernelfeat.Source{
+		Release:   func() (string, error) { return "5.10.0-28-amd64", nil },
+		KernelBTF: func() (*btf.Spec, error) { return nil, errors.New("no BTF") },
+	}
+	reportKernel(src.Detect(), zap.NewNop())
+
+	want := map[string]float64{
+		"btf":                           0,
+		"tracefs":                       0,
+		"tracepoint:tcp/tcp_send_reset": 1, // undetectable, assumed present
+		"field:skb/kfree_skb.reason":    0, // added in 5.17
+	}
+	for feature, v := range want {
+		if got := testutil.ToFloat64(kernelFeature.WithLabelValues(feature)); got != v {
+			t.Errorf("kernel_feature{feature=%q} = %v, want %v", feature, got, v)
+		}
+	}
+}
+
+type attachModule struct {
+	probe.Module
+	mode string
+}
+
+func (m attachModule) Name() string       { return "tcp" }
+func (m attachModule) AttachMode() string { return m.mode }
+
+func TestReportAttachMode(t *testing.T) {
+	reportAttachMode(attachModule{mode: constants.AttachModeKprobe})
+	if got := testutil.ToFloat64(attachMode.WithLabelValues("tcp", constants.AttachModeKprobe)); got != 1 {
+		t.Errorf("module_attach_mode{tcp,kprobe} = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(attachMode); n != 1 {
+		t.Errorf("module_attach_mode has %d series, want 1", n)
+	}
+}
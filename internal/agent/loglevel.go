@@ -0,0 +1,56 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+)
+
+// levelBody is the JSON shape of GET/PUT /admin/loglevel.
+type levelBody struct {
+	Level string `json:"level"`
+}
+
+// LogLevelHandler serves the admin log level endpoint: GET returns the
+// current level, PUT sets it from a {"level": "..."} body or a ?level=
+// query parameter. The change is live and lasts until restart or a
+// config reload that changes agent.log_level.
+func LogLevelHandler(level zap.AtomicLevel, logger *zap.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut:
+			req := levelBody{Level: r.URL.Query().Get("level")}
+			if req.Level == "" {
+				if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&req); err != nil {
+					writeJSON(w, http.StatusBadRequest, map[string]string{"error": "body must be {\"level\": \"...\"}"})
+					return
+				}
+			}
+			lvl, err := config.ParseLogLevel(req.Level)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			if old := level.Level(); old != lvl {
+				level.SetLevel(lvl)
+				logger.Warn("Log level changed via admin endpoint",
+					zap.Stringer("from", old), zap.Stringer("to", lvl), zap.String("remote", r.RemoteAddr))
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT")
+			writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+			return
+		}
+		writeJSON(w, http.StatusOK, levelBody{Level: level.Level().String()})
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,75 @@
+package agent
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func doLevel(t *testing.T, h http.Handler, method, target, body string) (int, map[string]string) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	var out map[string]string
+	if err := json.NewDecoder(rec.Body).Decode(&out); err != nil {
+		t.Fatalf("%s %s: decoding response: %v", method, target, err)
+	}
+	return rec.Code, out
+}
+
+func TestLogLevelHandler(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	h := LogLevelHandler(level, zap.NewNop())
+	path := constants.PathAdminLogLevel
+
+	if code, out := doLevel(t, h, http.MethodGet, path, ""); code != 200 || out["level"] != "info" {
+		t.Errorf("GET = %d %v, want 200 info", code, out)
+	}
+
+	if code, out := doLevel(t, h, http.MethodPut, path, `{"level":"debug"}`); code != 200 || out["level"] != "debug" {
+		t.Errorf("PUT body = %d %v, want 200 debug", code, out)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("level = %v after PUT, want debug", level.Level())
+	}
+
+	if code, _ := doLevel(t, h, http.MethodPut, path+"?level=warn", ""); code != 200 || level.Level() != zapcore.WarnLevel {
+		t.Errorf("PUT ?level=warn = %d, level %v", code, level.Level())
+	}
+}
+
+func TestLogLevelHandler_Rejects(t *testing.T) {
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	h := LogLevelHandler(level, zap.NewNop())
+	path := constants.PathAdminLogLevel
+
+	tests := []struct {
+		name, method, target, body string
+		want                       int
+	}{
+		{"unknown level", http.MethodPut, path, `{"level":"verbose"}`, 400},
+		{"fatal level", http.MethodPut, path + "?level=fatal", "", 400},
+		{"bad json", http.MethodPut, path, `debug`, 400},
+		{"empty body", http.MethodPut, path, "", 400},
+		{"method", http.MethodPost, path, `{"level":"debug"}`, 405},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, out := doLevel(t, h, tt.method, tt.target, tt.body)
+			if code != tt.want || out["error"] == "" {
+				t.Errorf("%s %s = %d %v, want %d with error", tt.method, tt.target, code, out, tt.want)
+			}
+		})
+	}
+	if level.Level() != zapcore.InfoLevel {
+		t.Errorf("rejected requests changed the level to %v", level.Level())
+	}
+}
@@ -0,0 +1,175 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
+)
+
+// errUnknownModule and errNotStarted are DisableModule and EnableModule
+// errors: no module has the name, or the agent never started it (it is
+// disabled in config, unsupported, or failed Init) or it has since failed
+// for good. Starting such a module needs an agent restart.
+var (
+	errUnknownModule = errors.New("no such module")
+	errNotStarted    = errors.New("module is not running under the agent; restart the agent to start it")
+)
+
+// moduleStatus is one module in the GET /admin/modules list.
+type moduleStatus struct {
+	Name      string          `json:"name"`
+	State     readiness.State `json:"state"`
+	Published uint64          `json:"events_published"`
+	// Lost is the events the kernel dropped because the module's ring
+	// buffer was full, since the module was last initialized.
+	Lost     uint64 `json:"ringbuf_lost"`
+	Restarts uint64 `json:"restarts"`
+}
+
+// ModuleStatuses returns every registered module's state and counters,
+// in registration order.
+func (rt *Runtime) ModuleStatuses() []moduleStatus {
+	stats := rt.bus.Stats()
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	out := make([]moduleStatus, 0, len(rt.modules))
+	for _, m := range rt.modules {
+		name := m.Name()
+		st := moduleStatus{Name: name, State: rt.readiness.Get(name), Published: stats.PublishedByType[name]}
+		if s, ok := rt.supervisors[name]; ok {
+			st.Restarts = s.restarts.Load()
+		}
+		// A stopped module's loss counter is closed.
+		if r, ok := m.(probe.LossReporter); ok && rt.started[name] {
+			st.Lost, _ = r.DroppedCount()
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// DisableModule stops module name until EnableModule: its ring buffer is
+// drained and its BPF programs detached. Unlike modules.<name>.enabled,
+// which keeps a module attached and drops its records, nothing of it
+// runs in the kernel while it is disabled.
+func (rt *Runtime) DisableModule(ctx context.Context, name string) error {
+	rt.toggleMu.Lock()
+	defer rt.toggleMu.Unlock()
+	s, err := rt.supervisor(name)
+	if err != nil {
+		return err
+	}
+	// Drop it from started first: its BPF resources are closed by Stop.
+	rt.mu.Lock()
+	rt.started[name] = false
+	rt.mu.Unlock()
+	return toggleError(s.Disable(ctx))
+}
+
+// EnableModule re-initializes and starts a module DisableModule stopped.
+func (rt *Runtime) EnableModule(ctx context.Context, name string) error {
+	rt.toggleMu.Lock()
+	defer rt.toggleMu.Unlock()
+	s, err := rt.supervisor(name)
+	if err != nil {
+		return err
+	}
+	if err := s.Enable(ctx); err != nil {
+		return toggleError(err)
+	}
+	rt.mu.Lock()
+	rt.started[name] = true
+	rt.mu.Unlock()
+	return nil
+}
+
+// supervisor returns the supervisor of the running module name.
+func (rt *Runtime) supervisor(name string) (*supervisor, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	for _, m := range rt.modules {
+		if m.Name() != name {
+			continue
+		}
+		s, ok := rt.supervisors[name]
+		if !ok {
+			return nil, errNotStarted
+		}
+		return s, nil
+	}
+	return nil, errUnknownModule
+}
+
+// toggleError maps a supervisor's Disable or Enable error.
+func toggleError(err error) error {
+	if errors.Is(err, errSupervisorExited) {
+		return errNotStarted
+	}
+	return err
+}
+
+// ModuleHandlers returns the module admin endpoints to mount on the
+// metrics server, keyed by pattern:
+//
+//	GET  /admin/modules                 list modules, as moduleStatus
+//	POST /admin/modules/{name}/disable  stop a module (DisableModule)
+//	POST /admin/modules/{name}/enable   start it again (EnableModule)
+//
+// They can stop the agent's probes, so the caller mounts them only behind
+// the metrics server's basic auth.
+func (rt *Runtime) ModuleHandlers() map[string]http.Handler {
+	return map[string]http.Handler{
+		"GET " + constants.PathAdminModules:                   http.HandlerFunc(rt.serveModules),
+		"POST " + constants.PathAdminModules + "/{name}/{op}": http.HandlerFunc(rt.serveModuleToggle),
+	}
+}
+
+func (rt *Runtime) serveModules(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string][]moduleStatus{"modules": rt.ModuleStatuses()})
+}
+
+func (rt *Runtime) serveModuleToggle(w http.ResponseWriter, r *http.Request) {
+	name, op := r.PathValue("name"), r.PathValue("op")
+	toggle := rt.DisableModule
+	switch op {
+	case "disable":
+	case "enable":
+		toggle = rt.EnableModule
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": fmt.Sprintf("unknown operation %q: want disable or enable", op)})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), constants.ModuleToggleTimeout)
+	defer cancel()
+	err := toggle(ctx, name)
+	switch {
+	case errors.Is(err, errUnknownModule):
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, errNotStarted):
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	case errors.Is(err, context.DeadlineExceeded):
+		writeJSON(w, http.StatusGatewayTimeout, map[string]string{"error": op + " timed out"})
+		return
+	case err != nil:
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	rt.logger.Warn("Module toggled via admin endpoint",
+		zap.String("module", name), zap.String("op", op), zap.String("remote", r.RemoteAddr))
+	for _, st := range rt.ModuleStatuses() {
+		if st.Name == name {
+			writeJSON(w, http.StatusOK, st)
+			return
+		}
+	}
+}
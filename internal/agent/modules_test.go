@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
+)
+
+// modulesServer runs a runtime supervising a fake tcp module, with dns
+// registered but never started, behind the module admin endpoints.
+func modulesServer(t *testing.T) (*Runtime, *flakyModule, *httptest.Server) {
+	t.Helper()
+	rt := NewRuntime(config.Default(), zap.NewNop(), zap.NewAtomicLevel())
+	tcp := &flakyModule{name: constants.ModuleTCP}
+	rt.RegisterModule(tcp)
+	rt.RegisterModule(nopModule{name: constants.ModuleDNS})
+	rt.started[tcp.name] = true
+
+	ctx, cancel := context.WithCancel(context.Background())
+	modules := rt.supervise(ctx, []probe.Module{tcp}, map[string]probe.Dependencies{})
+	t.Cleanup(func() {
+		cancel()
+		modules.Wait()
+	})
+	waitState(t, rt, tcp.name, readiness.Running)
+
+	mux := http.NewServeMux()
+	for pattern, h := range rt.ModuleHandlers() {
+		mux.Handle(pattern, h)
+	}
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return rt, tcp, srv
+}
+
+func waitState(t *testing.T, rt *Runtime, name string, want readiness.State) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for rt.Readiness().Get(name) != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is %s, want %s", name, rt.Readiness().Get(name), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func post(t *testing.T, url string) (int, moduleStatus) {
+	t.Helper()
+	resp, err := http.Post(url, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var st moduleStatus
+	json.NewDecoder(resp.Body).Decode(&st)
+	return resp.StatusCode, st
+}
+
+func TestModuleHandlers_Toggle(t *testing.T) {
+	rt, tcp, srv := modulesServer(t)
+	base := srv.URL + constants.PathAdminModules
+
+	var list struct {
+		Modules []moduleStatus `json:"modules"`
+	}
+	resp := get(t, base)
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Modules) != 2 || list.Modules[0] != (moduleStatus{Name: constants.ModuleTCP, State: readiness.Running}) ||
+		list.Modules[1].Name != constants.ModuleDNS || list.Modules[1].State != "" {
+		t.Errorf("modules = %+v, want tcp running and dns never started", list.Modules)
+	}
+
+	status, st := post(t, base+"/tcp/disable")
+	if status != http.StatusOK || st.State != readiness.Disabled {
+		t.Fatalf("disable = %d %+v", status, st)
+	}
+	if _, _, stops := tcp.counts(); stops != 1 || rt.started[tcp.name] {
+		t.Errorf("stops = %d, started = %v after disable", stops, rt.started[tcp.name])
+	}
+	if got := testutil.ToFloat64(moduleState.WithLabelValues("tcp", "disabled")); got != 1 {
+		t.Errorf(`module_state{state="disabled"} = %v, want 1`, got)
+	}
+	if got := testutil.ToFloat64(moduleState.WithLabelValues("tcp", "running")); got != 0 {
+		t.Errorf(`module_state{state="running"} = %v, want 0`, got)
+	}
+
+	if status, _ := post(t, base+"/tcp/enable"); status != http.StatusOK {
+		t.Fatalf("enable = %d", status)
+	}
+	waitState(t, rt, tcp.name, readiness.Running)
+	if inits, starts, _ := tcp.counts(); inits != 1 || starts != 2 || !rt.started[tcp.name] {
+		t.Errorf("inits, starts = %d, %d after enable; want 1, 2", inits, starts)
+	}
+}
+
+func TestModuleHandlers_Errors(t *testing.T) {
+	_, _, srv := modulesServer(t)
+	base := srv.URL + constants.PathAdminModules
+	for path, want := range map[string]int{
+		"/http/disable": http.StatusNotFound,
+		"/tcp/restart":  http.StatusNotFound,
+		"/dns/enable":   http.StatusConflict,
+		"/dns/disable":  http.StatusConflict,
+	} {
+		if status, _ := post(t, base+path); status != want {
+			t.Errorf("POST %s = %d, want %d", path, status, want)
+		}
+	}
+	if status, _ := post(t, base); status != http.StatusMethodNotAllowed {
+		t.Errorf("POST %s = %d, want 405", constants.PathAdminModules, status)
+	}
+}
@@ -0,0 +1,46 @@
+package agent
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+// openPins returns where modules pin their maps and links, or nil if
+// agent.pin_bpf is off or agent.pin_path is not a usable bpffs, in which
+// case modules load unpinned.
+func openPins(cfg config.AgentConfig, logger *zap.Logger) *bpfutil.PinRoot {
+	if !cfg.PinBPF {
+		return nil
+	}
+	pins, err := bpfutil.NewPinRoot(cfg.PinPath, logger)
+	if err != nil {
+		logger.Warn("BPF pinning unavailable — events will be missed while the agent restarts",
+			zap.String("path", cfg.PinPath), zap.Error(err))
+		return nil
+	}
+	logger.Info("Pinning BPF maps and links", zap.String("path", cfg.PinPath))
+	return pins
+}
+
+// unpin removes the pins of a module that is not running and will not be
+// restarted, so its programs detach rather than run on with no reader.
+func unpin(pins *bpfutil.PinRoot, module string, logger *zap.Logger) {
+	if err := pins.Remove(module); err != nil {
+		logger.Warn("Could not remove BPF pins", zap.String("module", module), zap.Error(err))
+	}
+}
+
+// pruneLinks unpins the links an earlier agent left for m that its Init
+// did not attach again. logger carries the module's name.
+func pruneLinks(m probe.Module, logger *zap.Logger) {
+	r, ok := m.(probe.ResourceReporter)
+	if !ok {
+		return
+	}
+	if err := r.BPFResources().PruneLinks(); err != nil {
+		logger.Warn("Could not remove stale BPF links", zap.Error(err))
+	}
+}
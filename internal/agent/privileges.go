@@ -0,0 +1,25 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/caps"
+)
+
+// checkPrivileges fails unless the agent has the capabilities to load and
+// attach its probes. Root has them; so does a pod granted CAP_BPF and
+// CAP_PERFMON. If the capabilities cannot be read it requires root.
+func checkPrivileges() error {
+	set, err := caps.Effective()
+	if err != nil {
+		if os.Geteuid() != 0 {
+			return fmt.Errorf("KubePulse cannot read its capabilities (%v) and is not root. Run with: sudo ./bin/kubepulse", err)
+		}
+		return nil
+	}
+	if err := caps.Check(set); err != nil {
+		return fmt.Errorf("KubePulse cannot load its BPF probes: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package agent
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+var ringbufLost = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricRingbufLost,
+	Help: "Events dropped in the kernel because the module's ring buffer was full.",
+}, constants.LabelsModule)
+
+var eventsSuppressed = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricEventsSuppressed,
+	Help: "Events a module's BPF program discarded in the kernel, e.g. I/O below modules.fileio.min_latency.",
+}, constants.LabelsModule)
+
+// lossWatcher turns the modules' cumulative kernel-side loss and
+// suppression counts into the ringbuf_lost_events_total and
+// events_suppressed_total counters.
+type lossWatcher struct {
+	reporters map[string]probe.LossReporter
+	last      map[string]uint64
+
+	suppressors    map[string]probe.SuppressionReporter
+	lastSuppressed map[string]uint64
+
+	logger *zap.Logger
+}
+
+func newLossWatcher(modules []probe.Module, logger *zap.Logger) *lossWatcher {
+	w := &lossWatcher{
+		reporters:      make(map[string]probe.LossReporter),
+		last:           make(map[string]uint64),
+		suppressors:    make(map[string]probe.SuppressionReporter),
+		lastSuppressed: make(map[string]uint64),
+		logger:         logger,
+	}
+	for _, m := range modules {
+		if r, ok := m.(probe.LossReporter); ok {
+			w.reporters[m.Name()] = r
+			ringbufLost.WithLabelValues(m.Name()) // export zero until the first loss
+		}
+		if s, ok := m.(probe.SuppressionReporter); ok {
+			w.suppressors[m.Name()] = s
+			eventsSuppressed.WithLabelValues(m.Name())
+		}
+	}
+	return w
+}
+
+// Run collects every constants.StatsCollectInterval until ctx is cancelled.
+func (w *lossWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(constants.StatsCollectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.collect()
+		}
+	}
+}
+
+func (w *lossWatcher) collect() {
+	for name, r := range w.reporters {
+		n, err := r.DroppedCount()
+		if err != nil {
+			w.logger.Debug("Reading ring buffer losses", zap.String("module", name), zap.Error(err))
+			continue
+		}
+		if n > w.last[name] {
+			lost := n - w.last[name]
+			ringbufLost.WithLabelValues(name).Add(float64(lost))
+			w.logger.Warn("Ring buffer full — kernel dropped events",
+				zap.String("module", name), zap.Uint64("lost", lost))
+		}
+		w.last[name] = n
+	}
+	for name, s := range w.suppressors {
+		n, err := s.SuppressedCount()
+		if err != nil {
+			w.logger.Debug("Reading suppressed events", zap.String("module", name), zap.Error(err))
+			continue
+		}
+		// A restarted module counts from zero again.
+		if n > w.lastSuppressed[name] {
+			eventsSuppressed.WithLabelValues(name).Add(float64(n - w.lastSuppressed[name]))
+		}
+		w.lastSuppressed[name] = n
+	}
+}
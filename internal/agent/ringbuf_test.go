@@ -0,0 +1,60 @@
+package agent
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+type lossyModule struct {
+	nopModule
+	lost uint64
+}
+
+func (m *lossyModule) DroppedCount() (uint64, error) { return m.lost, nil }
+
+func TestLossWatcher_ExportsDeltas(t *testing.T) {
+	lossy := &lossyModule{nopModule: nopModule{name: "test_lossy"}}
+	w := newLossWatcher([]probe.Module{lossy, nopModule{name: "test_lossless"}}, zap.NewNop())
+	if _, ok := w.reporters["test_lossless"]; ok {
+		t.Error("module without DroppedCount registered as a reporter")
+	}
+
+	counter := ringbufLost.WithLabelValues("test_lossy")
+	before := testutil.ToFloat64(counter)
+	lossy.lost = 5
+	w.collect()
+	lossy.lost = 12
+	w.collect()
+	w.collect()
+	if got := testutil.ToFloat64(counter) - before; got != 12 {
+		t.Errorf("ringbuf lost delta = %v, want 12", got)
+	}
+}
+
+type suppressingModule struct {
+	nopModule
+	suppressed uint64
+}
+
+func (m *suppressingModule) SuppressedCount() (uint64, error) { return m.suppressed, nil }
+
+func TestLossWatcher_ExportsSuppressed(t *testing.T) {
+	m := &suppressingModule{nopModule: nopModule{name: "test_suppressing"}}
+	w := newLossWatcher([]probe.Module{m}, zap.NewNop())
+
+	counter := eventsSuppressed.WithLabelValues("test_suppressing")
+	before := testutil.ToFloat64(counter)
+	m.suppressed = 100
+	w.collect()
+	m.suppressed = 40 // restarted: counts from zero again
+	w.collect()
+	m.suppressed = 90
+	w.collect()
+	if got := testutil.ToFloat64(counter) - before; got != 150 {
+		t.Errorf("events suppressed delta = %v, want 150", got)
+	}
+}
@@ -5,19 +5,25 @@ package agent
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"sync"
+	"time"
 
+	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/rlimit"
 	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
 
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/config"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
 	"github.com/sureshkrishnan-v/kubePulse/internal/export"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
 	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
 )
 
 // Runtime is the central orchestrator for KubePulse.
@@ -28,21 +34,44 @@ import (
 // orchestrates all subsystems. Also implements the Registry pattern
 // for module/exporter registration.
 type Runtime struct {
-	cfg       *config.Config
 	logger    *zap.Logger
+	level     zap.AtomicLevel
 	modules   []probe.Module
 	exporters []export.Exporter
 	bus       *event.Bus
 	metaCache *metadata.Cache
+
+	// samplers and filters are the config broadcast to modules: one of
+	// each per registered module, updated in place by Reload.
+	samplers map[string]*probe.Sampler
+	filters  map[string]*probe.Filter
+
+	readiness *readiness.Tracker
+
+	mu          sync.Mutex // guards cfg, started and supervisors
+	cfg         *config.Config
+	started     map[string]bool
+	supervisors map[string]*supervisor // the modules Run started
+
+	toggleMu sync.Mutex // serializes DisableModule and EnableModule
 }
 
 // NewRuntime creates a new Runtime with the given configuration.
-// The EventBus is created eagerly so exporters can subscribe before Run().
-func NewRuntime(cfg *config.Config, logger *zap.Logger) *Runtime {
+// The EventBus and metadata cache are created eagerly so exporters can
+// subscribe to and read them before Run().
+// level is the logger's level handle; Reload adjusts it.
+func NewRuntime(cfg *config.Config, logger *zap.Logger, level zap.AtomicLevel) *Runtime {
 	return &Runtime{
-		cfg:    cfg,
-		logger: logger,
-		bus:    event.NewBus(cfg.Performance.EventBusBuffer, logger),
+		cfg:         cfg,
+		logger:      logger,
+		level:       level,
+		bus:         event.NewBus(cfg.Performance.EventBusBuffer, logger),
+		metaCache:   metadata.NewCache(metadata.DefaultCacheConfig()),
+		samplers:    make(map[string]*probe.Sampler),
+		filters:     make(map[string]*probe.Filter),
+		started:     make(map[string]bool),
+		supervisors: make(map[string]*supervisor),
+		readiness:   readiness.New(cfg.Agent.MinReadyModules),
 	}
 }
 
@@ -51,6 +80,15 @@ func NewRuntime(cfg *config.Config, logger *zap.Logger) *Runtime {
 // Must be called before Run.
 func (rt *Runtime) RegisterModule(m probe.Module) {
 	rt.modules = append(rt.modules, m)
+	rt.samplers[m.Name()] = probe.NewSampler(samplingRate(rt.cfg, m.Name()))
+	filter, err := probe.NewFilter(m.Name(), rt.cfg.ModuleConf(m.Name()).Filters)
+	if err != nil {
+		// Unreachable for a validated config; run unfiltered rather than not at all.
+		rt.logger.Error("Invalid module filters — ignoring",
+			zap.String("module", m.Name()), zap.Error(err))
+		filter, _ = probe.NewFilter(m.Name(), config.FilterConfig{})
+	}
+	rt.filters[m.Name()] = filter
 }
 
 // RegisterExporter adds an exporter to the runtime (Registry pattern).
@@ -64,35 +102,49 @@ func (rt *Runtime) EventBus() *event.Bus {
 	return rt.bus
 }
 
+// Readiness returns the module states /readyz reports.
+func (rt *Runtime) Readiness() *readiness.Tracker {
+	return rt.readiness
+}
+
 // MetaCache returns the metadata cache for PID resolution.
 func (rt *Runtime) MetaCache() *metadata.Cache {
 	return rt.metaCache
 }
 
 // Run starts the full runtime lifecycle:
-//  1. Pre-flight checks (root, rlimit)
-//  2. Init metadata cache + K8s watcher
+//  1. Pre-flight checks (capabilities, rlimit, kernel features)
+//  2. Start the K8s watcher feeding the metadata cache
 //  3. Init all enabled modules (skip disabled)
 //  4. Start exporters
 //  5. Start all initialized modules
 //  6. Wait for shutdown signal
-//  7. Stop modules → close bus → stop exporters
+//  7. Drain module ring buffers → stop modules → close bus → stop exporters
 func (rt *Runtime) Run(ctx context.Context) error {
+	cfg := rt.config()
+
 	// Pre-flight checks
-	if os.Geteuid() != 0 {
-		return fmt.Errorf("KubePulse requires root privileges. Run with: sudo ./bin/kubepulse")
+	if err := checkPrivileges(); err != nil {
+		return err
 	}
 	if err := rlimit.RemoveMemlock(); err != nil {
 		rt.logger.Warn("Failed to remove memlock rlimit", zap.Error(err))
 	}
+	// Per-program run time and run counts for the BPF resource metrics.
+	// Needs Linux 5.8; without it those metrics are simply absent.
+	if stats, err := ebpf.EnableStats(uint32(unix.BPF_STATS_RUN_TIME)); err != nil {
+		rt.logger.Info("BPF run-time stats unavailable", zap.Error(err))
+	} else {
+		defer stats.Close()
+	}
+
+	kernel := kernelfeat.Detect()
+	reportKernel(kernel, rt.logger)
 
 	rt.logger.Info("KubePulse runtime starting",
 		zap.Int("modules_registered", len(rt.modules)),
 		zap.Int("exporters_registered", len(rt.exporters)),
-		zap.String("node", rt.cfg.Agent.NodeName))
-
-	// Initialize metadata cache
-	rt.metaCache = metadata.NewCache(metadata.DefaultCacheConfig())
+		zap.String("node", cfg.Agent.NodeName))
 
 	// Start Kubernetes watcher (optional — degrades gracefully)
 	k8sWatcher, err := metadata.NewK8sWatcher(rt.metaCache, rt.logger)
@@ -106,30 +158,64 @@ func (rt *Runtime) Run(ctx context.Context) error {
 		}()
 	}
 
+	if !cfg.Agent.TraceSelf {
+		pids := selfPIDs()
+		for _, f := range rt.filters {
+			f.ExcludePIDs(pids)
+		}
+		rt.logger.Info("Excluding the agent's own processes from tracing", zap.Uint32s("pids", pids))
+	}
+
+	pins := openPins(cfg.Agent, rt.logger)
+
 	// Initialize enabled modules
 	var initialized []probe.Module
+	moduleDeps := make(map[string]probe.Dependencies)
 	for _, m := range rt.modules {
-		if !rt.cfg.ModuleEnabled(m.Name()) {
+		if !cfg.ModuleEnabled(m.Name()) {
+			rt.markState(m.Name(), readiness.Disabled)
 			rt.logger.Info("Module disabled by config — skipping",
 				zap.String("module", m.Name()))
+			unpin(pins, m.Name(), rt.logger)
 			continue
 		}
 
 		deps := probe.NewDependencies(
 			rt.logger.Named(m.Name()),
-			rt.cfg.ModuleConf(m.Name()),
+			cfg.ModuleConf(m.Name()),
 			rt.bus,
 			rt.metaCache,
-			rt.cfg.Agent.NodeName,
+			cfg.Agent.NodeName,
+			rt.samplers[m.Name()],
+			rt.filters[m.Name()],
+			kernel,
+			pins,
 		)
 
 		rt.logger.Info("Initializing module", zap.String("module", m.Name()))
-		if err := m.Init(ctx, deps); err != nil {
+		rt.markState(m.Name(), readiness.Initializing)
+		if err := m.Init(ctx, deps); errors.Is(err, probe.ErrUnsupported) {
+			rt.markState(m.Name(), readiness.Disabled)
+			rt.logger.Warn("Module not supported by this kernel — skipping",
+				zap.String("module", m.Name()), zap.Error(err))
+			unpin(pins, m.Name(), rt.logger)
+			continue
+		} else if err != nil {
+			rt.markState(m.Name(), readiness.Failed)
 			rt.logger.Error("Module init failed — skipping",
 				zap.String("module", m.Name()), zap.Error(err))
+			unpin(pins, m.Name(), rt.logger)
 			continue
 		}
 		initialized = append(initialized, m)
+		moduleDeps[m.Name()] = deps
+		reportAttachMode(m)
+		logger := rt.logger.With(zap.String("module", m.Name()))
+		excludeSelf(m, deps, logger)
+		pruneLinks(m, logger)
+		rt.mu.Lock()
+		rt.started[m.Name()] = true
+		rt.mu.Unlock()
 		rt.logger.Info("Module initialized", zap.String("module", m.Name()))
 	}
 
@@ -137,33 +223,30 @@ func (rt *Runtime) Run(ctx context.Context) error {
 		return fmt.Errorf("no modules initialized successfully")
 	}
 
-	// Start exporters
+	// Start exporters. They run on their own context, cancelled only once
+	// the bus has been drained and closed at shutdown (see stopExporters).
+	exportCtx, cancelExport := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelExport()
+	exporters := rt.startExporters(exportCtx)
+
 	var wg sync.WaitGroup
-	for _, e := range rt.exporters {
-		wg.Add(1)
-		go func(e export.Exporter) {
-			defer wg.Done()
-			rt.logger.Info("Starting exporter", zap.String("exporter", e.Name()))
-			if err := e.Start(ctx); err != nil && ctx.Err() == nil {
-				rt.logger.Error("Exporter error",
-					zap.String("exporter", e.Name()), zap.Error(err))
-			}
-		}(e)
-	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		newLossWatcher(initialized, rt.logger).Run(ctx)
+	}()
 
-	// Start all initialized modules
-	for _, m := range initialized {
+	if cfg.Performance.AdaptiveSampling {
 		wg.Add(1)
-		go func(m probe.Module) {
+		go func() {
 			defer wg.Done()
-			rt.logger.Info("Starting module", zap.String("module", m.Name()))
-			if err := m.Start(ctx); err != nil && ctx.Err() == nil {
-				rt.logger.Error("Module error",
-					zap.String("module", m.Name()), zap.Error(err))
-			}
-		}(m)
+			newAdaptiveSampler(rt.bus, rt.samplers, rt.logger.Named("sampling")).Run(ctx)
+		}()
 	}
 
+	// Start all initialized modules, restarting any that fail
+	modules := rt.supervise(ctx, initialized, moduleDeps)
+
 	// Log active state
 	names := make([]string, len(initialized))
 	for i, m := range initialized {
@@ -181,7 +264,21 @@ func (rt *Runtime) Run(ctx context.Context) error {
 	<-ctx.Done()
 	rt.logger.Info("Shutdown signal received")
 
-	// Stop modules (with timeout)
+	// Let modules drain their ring buffers before detaching them. Start
+	// returns once its buffer is drained or its drain_timeout passes; the
+	// shutdown timeout bounds the wait in case one doesn't.
+	drained := make(chan struct{})
+	go func() {
+		modules.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(constants.ShutdownTimeout):
+		rt.logger.Warn("Modules still draining at shutdown timeout — stopping them")
+	}
+
+	// Stop modules (with timeout): detach links and close readers
 	stopCtx, stopCancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
 	defer stopCancel()
 
@@ -191,26 +288,210 @@ func (rt *Runtime) Run(ctx context.Context) error {
 			rt.logger.Warn("Error stopping module",
 				zap.String("module", m.Name()), zap.Error(err))
 		}
+		rt.markState(m.Name(), readiness.Stopped)
 	}
 
-	// Close event bus (triggers exporter channel close)
+	rt.stopExporters(stopCtx, exporters, cancelExport)
+
+	modules.Wait()
+	wg.Wait()
+
+	rt.logger.Info("KubePulse stopped",
+		zap.Int("modules_stopped", len(initialized)),
+		zap.Uint64("events_published", rt.bus.Published()),
+		zap.Uint64("events_dropped", rt.bus.Dropped()))
+
+	return nil
+}
+
+// supervise starts a supervisor for each module, which runs it until ctx
+// is cancelled. The returned group is done once every supervisor has
+// returned.
+func (rt *Runtime) supervise(ctx context.Context, modules []probe.Module, deps map[string]probe.Dependencies) *sync.WaitGroup {
+	maxRestarts := func() int { return rt.config().Agent.MaxModuleRestarts }
+	var wg sync.WaitGroup
+	for _, m := range modules {
+		s := newSupervisor(m, deps[m.Name()], rt.logger, maxRestarts,
+			func(s readiness.State) { rt.setModuleState(m.Name(), s) })
+		rt.mu.Lock()
+		rt.supervisors[m.Name()] = s
+		rt.mu.Unlock()
+		wg.Go(func() {
+			rt.logger.Info("Starting module", zap.String("module", m.Name()))
+			s.Run(ctx)
+		})
+	}
+	return &wg
+}
+
+// startExporters starts every exporter on its own goroutine. The returned
+// group is done once every Start has returned.
+func (rt *Runtime) startExporters(ctx context.Context) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	for _, e := range rt.exporters {
+		wg.Add(1)
+		go func(e export.Exporter) {
+			defer wg.Done()
+			rt.logger.Info("Starting exporter", zap.String("exporter", e.Name()))
+			if err := e.Start(ctx); err != nil && ctx.Err() == nil {
+				rt.logger.Error("Exporter error",
+					zap.String("exporter", e.Name()), zap.Error(err))
+			}
+		}(e)
+	}
+	return &wg
+}
+
+// stopExporters shuts the exporters down once the modules have stopped
+// publishing, in an order that loses no event:
+//
+//  1. wait for the exporters to take every event queued on the bus;
+//  2. close the bus, so each exporter's loop sees its channel close,
+//     flushes and returns;
+//  3. cancel the exporters' context (cancel) for the goroutines that
+//     outlive the loop, such as flush tickers and HTTP servers;
+//  4. wait for every Start to return, then Stop each exporter, which
+//     flushes synchronously with nothing left to race it.
+//
+// ctx bounds the whole sequence; a step that runs out of time is logged
+// and shutdown continues.
+func (rt *Runtime) stopExporters(ctx context.Context, exporters *sync.WaitGroup, cancel context.CancelFunc) {
+	if err := rt.bus.WaitDrained(ctx); err != nil {
+		rt.logger.Warn("Exporters still draining the event bus at shutdown timeout",
+			zap.Any("queue_depth", rt.bus.Stats().QueueDepth))
+	}
 	rt.bus.Close()
+	cancel()
+
+	returned := make(chan struct{})
+	go func() {
+		exporters.Wait()
+		close(returned)
+	}()
+	select {
+	case <-returned:
+	case <-ctx.Done():
+		rt.logger.Warn("Exporters still running at shutdown timeout — stopping them")
+	}
 
-	// Stop exporters
 	for _, e := range rt.exporters {
 		rt.logger.Debug("Stopping exporter", zap.String("exporter", e.Name()))
-		if err := e.Stop(stopCtx); err != nil {
+		if err := e.Stop(ctx); err != nil {
 			rt.logger.Warn("Error stopping exporter",
 				zap.String("exporter", e.Name()), zap.Error(err))
 		}
 	}
+}
 
-	wg.Wait()
+// Reload re-reads the config from src and applies, without re-attaching
+// any BPF program, the settings that can change live: log level, module
+// sampling rates and filters, and module enable/disable (a disabled module stays
+// attached but drops every record). Other differences are logged as
+// requiring a restart. If the new config fails to load or validate, the
+// running config is kept and the error returned.
+func (rt *Runtime) Reload(src config.Source) error {
+	next, err := src.Load()
+	if err != nil {
+		rt.logger.Error("Config reload rejected — keeping running config", zap.Error(err))
+		return err
+	}
 
-	rt.logger.Info("KubePulse stopped",
-		zap.Int("modules_stopped", len(initialized)),
-		zap.Uint64("events_published", rt.bus.Published()),
-		zap.Uint64("events_dropped", rt.bus.Dropped()))
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
 
+	applied := rt.cfg.Clone()
+	applied.Agent.LogLevel = next.Agent.LogLevel
+	for _, m := range rt.modules {
+		name := m.Name()
+		mod := *applied.ModuleConf(name)
+		want := next.ModuleConf(name)
+		mod.SamplingRate = want.SamplingRate
+		if err := rt.filters[name].Update(want.Filters); err == nil {
+			mod.Filters = want.Filters
+		}
+		// A module skipped at startup was never attached; enabling it
+		// needs a restart.
+		if rt.started[name] || !want.Enabled {
+			mod.Enabled = want.Enabled
+		}
+		applied.Modules[name] = &mod
+		rt.samplers[name].SetRate(samplingRate(applied, name))
+	}
+	// Only a changed log_level touches the level, so a reload doesn't undo
+	// an operator's runtime change via /admin/loglevel.
+	if applied.Agent.LogLevel != rt.cfg.Agent.LogLevel {
+		if lvl, err := config.ParseLogLevel(applied.Agent.LogLevel); err == nil {
+			rt.level.SetLevel(lvl)
+		}
+	}
+
+	for _, c := range config.Diff(rt.cfg, applied) {
+		rt.logger.Info("Config change applied",
+			zap.String("setting", c.Path), zap.String("from", c.Old), zap.String("to", c.New))
+	}
+	for _, c := range config.Diff(applied, next) {
+		rt.logger.Warn("Config change requires restart",
+			zap.String("setting", c.Path), zap.String("from", c.Old), zap.String("to", c.New))
+	}
+	rt.cfg = applied
 	return nil
 }
+
+// BPFResources returns the loaded BPF programs and maps of every
+// initialized module, keyed by module name.
+func (rt *Runtime) BPFResources() map[string]*bpfutil.Resources {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	out := make(map[string]*bpfutil.Resources)
+	for _, m := range rt.modules {
+		if r, ok := m.(probe.ResourceReporter); ok && rt.started[m.Name()] {
+			out[m.Name()] = r.BPFResources()
+		}
+	}
+	return out
+}
+
+// markState records module name's state for /readyz and as
+// kubepulse_module_state.
+func (rt *Runtime) markState(name string, s readiness.State) {
+	rt.readiness.Set(name, s)
+	for _, state := range readiness.States {
+		v := 0.0
+		if state == s {
+			v = 1
+		}
+		moduleState.WithLabelValues(name, string(state)).Set(v)
+	}
+}
+
+// setModuleState records a state reported by a module's supervisor. A
+// module that failed for good is no longer attached, so it is also
+// dropped from started.
+func (rt *Runtime) setModuleState(name string, s readiness.State) {
+	rt.markState(name, s)
+	if s != readiness.Failed {
+		return
+	}
+	rt.mu.Lock()
+	rt.started[name] = false
+	rt.mu.Unlock()
+	if !rt.readiness.Ready() {
+		rt.logger.Error("Too few modules running — reporting not ready")
+	}
+}
+
+// config returns the running config.
+func (rt *Runtime) config() *config.Config {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.cfg
+}
+
+// samplingRate is the rate a module's sampler should run at: its
+// configured rate, or 0 while the module is disabled.
+func samplingRate(cfg *config.Config, name string) float64 {
+	if !cfg.ModuleEnabled(name) {
+		return 0
+	}
+	return cfg.ModuleConf(name).SamplingRate
+}
@@ -0,0 +1,158 @@
+package agent
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+type nopModule struct{ name string }
+
+func (m nopModule) Name() string                                   { return m.name }
+func (m nopModule) Init(context.Context, probe.Dependencies) error { return nil }
+func (m nopModule) Start(context.Context) error                    { return nil }
+func (m nopModule) Stop(context.Context) error                     { return nil }
+
+// newTestRuntime returns a runtime with tcp and dns registered, as if Run
+// had started both.
+func newTestRuntime(t *testing.T) (*Runtime, zap.AtomicLevel) {
+	t.Helper()
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	rt := NewRuntime(config.Default(), zap.NewNop(), level)
+	for _, name := range []string{constants.ModuleTCP, constants.ModuleDNS} {
+		rt.RegisterModule(nopModule{name: name})
+		rt.started[name] = true
+	}
+	return rt, level
+}
+
+func reloadFrom(t *testing.T, rt *Runtime, yaml string) error {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubepulse.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return rt.Reload(config.Source{Path: path})
+}
+
+func TestReload_AppliesLiveChanges(t *testing.T) {
+	rt, level := newTestRuntime(t)
+
+	err := reloadFrom(t, rt, `
+agent:
+  log_level: debug
+modules:
+  tcp:
+    enabled: true
+    sampling_rate: 0.25
+  dns:
+    enabled: false
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rt.samplers[constants.ModuleTCP].Rate(); got != 0.25 {
+		t.Errorf("tcp sampler rate = %v, want 0.25", got)
+	}
+	if got := rt.samplers[constants.ModuleDNS].Rate(); got != 0 {
+		t.Errorf("disabled dns sampler rate = %v, want 0", got)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("log level = %v, want debug", level.Level())
+	}
+	if rt.config().ModuleEnabled(constants.ModuleDNS) {
+		t.Error("running config should record dns as disabled")
+	}
+}
+
+func TestReload_AppliesFilters(t *testing.T) {
+	rt, _ := newTestRuntime(t)
+	filter := rt.filters[constants.ModuleTCP]
+	if !filter.Port(22) {
+		t.Fatal("default filters dropped port 22")
+	}
+
+	if err := reloadFrom(t, rt, "modules:\n  tcp:\n    filters:\n      deny_ports: [22]\n"); err != nil {
+		t.Fatal(err)
+	}
+	if filter.Port(22) {
+		t.Error("tcp filter kept port 22 after reload denied it")
+	}
+	if got := rt.config().ModuleConf(constants.ModuleTCP).Filters.DenyPorts; len(got) != 1 {
+		t.Errorf("running config deny_ports = %v, want [22]", got)
+	}
+}
+
+func TestReload_KeepsRestartOnlySettings(t *testing.T) {
+	rt, _ := newTestRuntime(t)
+	before := rt.config().Performance.EventBusBuffer
+
+	if err := reloadFrom(t, rt, "performance:\n  event_bus_buffer: 65536\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := rt.config().Performance.EventBusBuffer; got != before {
+		t.Errorf("event_bus_buffer = %d, want running value %d until restart", got, before)
+	}
+}
+
+func TestReload_CannotStartSkippedModule(t *testing.T) {
+	rt, _ := newTestRuntime(t)
+	rt.cfg.Modules[constants.ModuleDNS].Enabled = false
+	rt.started[constants.ModuleDNS] = false
+	rt.samplers[constants.ModuleDNS].SetRate(0)
+
+	if err := reloadFrom(t, rt, "modules:\n  dns:\n    enabled: true\n    sampling_rate: 1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if rt.config().ModuleEnabled(constants.ModuleDNS) {
+		t.Error("a module skipped at startup must not be marked enabled by reload")
+	}
+	if got := rt.samplers[constants.ModuleDNS].Rate(); got != 0 {
+		t.Errorf("dns sampler rate = %v, want 0", got)
+	}
+}
+
+func TestReload_InvalidConfigKeepsRunning(t *testing.T) {
+	rt, level := newTestRuntime(t)
+	before := rt.config()
+
+	err := reloadFrom(t, rt, `
+agent:
+  log_level: debug
+modules:
+  tcp:
+    sampling_rate: 7
+`)
+	if err == nil {
+		t.Fatal("Reload accepted an invalid config")
+	}
+	if rt.config() != before {
+		t.Error("running config replaced after a rejected reload")
+	}
+	if got := rt.samplers[constants.ModuleTCP].Rate(); got != 1 {
+		t.Errorf("tcp sampler rate = %v, want unchanged 1", got)
+	}
+	if level.Level() != zapcore.InfoLevel {
+		t.Errorf("log level = %v, want unchanged info", level.Level())
+	}
+}
+
+func TestReload_KeepsAdminLogLevel(t *testing.T) {
+	rt, level := newTestRuntime(t)
+	level.SetLevel(zapcore.DebugLevel) // e.g. via /admin/loglevel
+
+	if err := reloadFrom(t, rt, "modules:\n  tcp:\n    enabled: true\n    sampling_rate: 0.5\n"); err != nil {
+		t.Fatal(err)
+	}
+	if level.Level() != zapcore.DebugLevel {
+		t.Errorf("reload without a log_level change reset the level to %v", level.Level())
+	}
+}
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+// selfPIDs returns the agent's PID and those of its children, read from
+// /proc/self/task/<tid>/children. Children started later are not
+// included.
+func selfPIDs() []uint32 {
+	pids := []uint32{uint32(os.Getpid())}
+	files, _ := filepath.Glob("/proc/self/task/*/children")
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue // the thread exited
+		}
+		for _, field := range strings.Fields(string(data)) {
+			if pid, err := strconv.ParseUint(field, 10, 32); err == nil {
+				pids = append(pids, uint32(pid))
+			}
+		}
+	}
+	return pids
+}
+
+// excludeSelf writes the processes deps.Filter excludes into the BPF
+// objects m just loaded, so their events never reach the ring buffer.
+// Where that fails the filter still drops them in userspace. logger
+// carries the module's name.
+func excludeSelf(m probe.Module, deps probe.Dependencies, logger *zap.Logger) {
+	pids := deps.Filter.ExcludedPIDs()
+	r, ok := m.(probe.ResourceReporter)
+	if len(pids) == 0 || !ok {
+		return
+	}
+	switch ok, err := r.BPFResources().ExcludePIDs(pids); {
+	case err != nil:
+		logger.Warn("Could not exclude the agent in the kernel — filtering its events in userspace",
+			zap.Error(err))
+	case !ok:
+		logger.Debug("BPF object has no excluded_pids map — filtering the agent's events in userspace")
+	}
+}
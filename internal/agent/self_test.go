@@ -0,0 +1,31 @@
+package agent
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestSelfPIDs(t *testing.T) {
+	pids := selfPIDs()
+	if len(pids) == 0 || pids[0] != uint32(os.Getpid()) {
+		t.Fatalf("selfPIDs() = %v, want the test's PID first", pids)
+	}
+
+	if files, _ := filepath.Glob("/proc/self/task/*/children"); len(files) == 0 {
+		t.Skip("kernel does not list child processes")
+	}
+	child := exec.Command("sleep", "10")
+	if err := child.Start(); err != nil {
+		t.Skipf("starting a child: %v", err)
+	}
+	defer func() {
+		child.Process.Kill()
+		child.Wait()
+	}()
+	if pids := selfPIDs(); !slices.Contains(pids, uint32(child.Process.Pid)) {
+		t.Errorf("selfPIDs() = %v, missing child %d", pids, child.Process.Pid)
+	}
+}
@@ -0,0 +1,93 @@
+package agent
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/export"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// countLines returns the number of lines in the file at path.
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	n := 0
+	for sc := bufio.NewScanner(f); sc.Scan(); {
+		n++
+	}
+	return n
+}
+
+// TestShutdown_ExportersLoseNoEvents cancels the agent mid-stream while a
+// publisher, standing in for a module draining its ring buffer, keeps
+// publishing for a while after cancellation, as modules do. Every event
+// published must reach both the file exporter and the store exporter.
+func TestShutdown_ExportersLoseNoEvents(t *testing.T) {
+	cfg := config.Default()
+	cfg.Performance.EventBusBuffer = 1 << 16
+	rt := NewRuntime(cfg, zap.NewNop(), zap.NewAtomicLevelAt(zapcore.InfoLevel))
+
+	fc := export.DefaultFileConfig()
+	fc.Path = filepath.Join(t.TempDir(), "events.ndjson")
+	rt.RegisterExporter(export.NewFileExporter(fc, rt.EventBus(), zap.NewNop()))
+	store := storage.NewMemory()
+	rt.RegisterExporter(export.NewStoreExporter(export.DefaultStoreConfig(), store, rt.EventBus(), zap.NewNop()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	exportCtx, cancelExport := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelExport()
+	exporters := rt.startExporters(exportCtx)
+	for {
+		if len(rt.bus.Stats().QueueDepth) == len(rt.exporters) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	publish := func(n int) {
+		for i := 0; i < n; i++ {
+			e := event.Acquire()
+			e.Type = event.TypeTCP
+			e.PID = uint32(i)
+			e.Timestamp = time.Now()
+			rt.bus.Publish(e)
+		}
+	}
+	publish(5000)
+	cancel()
+	publish(5000) // the modules' drain after the shutdown signal
+
+	stopCtx, stopCancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+	defer stopCancel()
+	rt.stopExporters(stopCtx, exporters, cancelExport)
+
+	if dropped := rt.bus.Dropped(); dropped != 0 {
+		t.Fatalf("bus dropped %d events; the test needs a larger buffer", dropped)
+	}
+	want := rt.bus.Published()
+	if got := countLines(t, fc.Path); got != int(want) {
+		t.Errorf("file exporter wrote %d events, want all %d published", got, want)
+	}
+	types, err := store.EventTypes(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 1 || types[0].Count != want {
+		t.Errorf("store exporter stored %v, want all %d published", types, want)
+	}
+}
@@ -0,0 +1,254 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
+)
+
+var moduleRestarts = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricModuleRestarts,
+	Help: "Times a module was restarted after its read loop failed.",
+}, constants.LabelsModule)
+
+var moduleState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: constants.MetricModuleState,
+	Help: "Each module's lifecycle state: 1 for the current state, 0 for the others.",
+}, constants.LabelsModuleState)
+
+// errSupervisorExited is returned by Disable and Enable once the
+// supervisor's Run has returned: the agent is stopping, or the module
+// failed for good.
+var errSupervisorExited = errors.New("module is no longer supervised")
+
+// supervisor runs a module's Start and, when it fails while the agent is
+// running, restarts the module: Stop, wait, Init, Start. The wait doubles
+// from backoff up to maxBackoff. After maxRestarts() restarts without the
+// module staying up for stableAfter, it gives up. Each state change is
+// passed to state.
+//
+// Disable and Enable turn the module off and on from another goroutine;
+// Run stays the only caller of the module's lifecycle methods.
+type supervisor struct {
+	module      probe.Module
+	deps        probe.Dependencies
+	logger      *zap.Logger
+	maxRestarts func() int // read at each failure, so a reload applies
+	state       func(readiness.State)
+
+	backoff, maxBackoff, stableAfter time.Duration
+
+	restarts atomic.Uint64 // total, for /admin/modules
+
+	mu        sync.Mutex
+	disabled  bool               // set by Disable, cleared once Enable's Init succeeds
+	parked    chan struct{}      // closed once Run has stopped a disabled module
+	cancelRun context.CancelFunc // cancels the running Start
+
+	enables chan chan error // Enable's requests, taken by a parked Run
+	exited  chan struct{}   // closed when Run returns
+}
+
+func newSupervisor(m probe.Module, deps probe.Dependencies, logger *zap.Logger, maxRestarts func() int, state func(readiness.State)) *supervisor {
+	moduleRestarts.WithLabelValues(m.Name()) // export zero until the first restart
+	return &supervisor{
+		module:      m,
+		deps:        deps,
+		logger:      logger.With(zap.String("module", m.Name())),
+		maxRestarts: maxRestarts,
+		state:       state,
+		backoff:     constants.ModuleRestartBackoff,
+		maxBackoff:  constants.ModuleRestartMaxBackoff,
+		stableAfter: constants.ModuleStableAfter,
+		enables:     make(chan chan error),
+		exited:      make(chan struct{}),
+	}
+}
+
+// Run blocks until ctx is cancelled and the module has drained, or the
+// module has failed for good.
+func (s *supervisor) Run(ctx context.Context) {
+	defer close(s.exited)
+	restarts, delay := 0, s.backoff
+	for {
+		runCtx, cancel := context.WithCancel(ctx)
+		if !s.running(cancel) {
+			cancel()
+			if !s.park(ctx) {
+				return
+			}
+			restarts, delay = 0, s.backoff
+			continue
+		}
+		started := time.Now()
+		s.state(readiness.Running)
+		err := s.module.Start(runCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		if s.isDisabled() {
+			// Start drained and returned because Disable cancelled it.
+			if !s.park(ctx) {
+				return
+			}
+			restarts, delay = 0, s.backoff
+			continue
+		}
+		if err == nil {
+			return
+		}
+		s.logger.Error("Module error", zap.Error(err))
+		s.state(readiness.Restarting)
+		if time.Since(started) >= s.stableAfter {
+			restarts, delay = 0, s.backoff
+		}
+
+		// Restart until Init succeeds, the budget runs out, or the module
+		// is disabled, when the loop above parks it.
+		for {
+			s.module.Stop(context.Background())
+			if s.isDisabled() {
+				break
+			}
+			if restarts >= s.maxRestarts() {
+				s.logger.Error("Module failed permanently — giving up",
+					zap.Int("restarts", restarts))
+				unpin(s.deps.Pins, s.module.Name(), s.logger)
+				s.state(readiness.Failed)
+				return
+			}
+			s.logger.Warn("Restarting module",
+				zap.Duration("backoff", delay), zap.Int("restart", restarts+1))
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			restarts, delay = restarts+1, min(2*delay, s.maxBackoff)
+			moduleRestarts.WithLabelValues(s.module.Name()).Inc()
+			s.restarts.Add(1)
+
+			if err := s.module.Init(ctx, s.deps); err != nil {
+				s.logger.Error("Module re-init failed", zap.Error(err))
+				continue
+			}
+			reportAttachMode(s.module)
+			excludeSelf(s.module, s.deps, s.logger)
+			pruneLinks(s.module, s.logger)
+			s.logger.Info("Module restarted")
+			break
+		}
+	}
+}
+
+// running records cancel as the way to stop the Start about to run,
+// unless the module has been disabled.
+func (s *supervisor) running(cancel context.CancelFunc) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.disabled {
+		return false
+	}
+	s.cancelRun = cancel
+	return true
+}
+
+func (s *supervisor) isDisabled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.disabled
+}
+
+// park stops the disabled module and waits for an Enable whose Init
+// succeeds, reporting false if ctx is cancelled first.
+func (s *supervisor) park(ctx context.Context) bool {
+	s.module.Stop(context.Background())
+	unpin(s.deps.Pins, s.module.Name(), s.logger)
+	s.state(readiness.Disabled)
+	s.logger.Info("Module disabled")
+	s.mu.Lock()
+	close(s.parked)
+	s.mu.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case reply := <-s.enables:
+			if err := s.module.Init(ctx, s.deps); err != nil {
+				s.module.Stop(context.Background())
+				s.logger.Error("Module init failed — staying disabled", zap.Error(err))
+				reply <- err
+				continue
+			}
+			reportAttachMode(s.module)
+			excludeSelf(s.module, s.deps, s.logger)
+			pruneLinks(s.module, s.logger)
+			s.mu.Lock()
+			s.disabled = false
+			s.mu.Unlock()
+			s.logger.Info("Module enabled")
+			reply <- nil
+			return true
+		}
+	}
+}
+
+// Disable stops the module: its Start drains and returns, then Run stops
+// it and waits for Enable. It returns once the module is stopped.
+// Disabling a disabled module does nothing.
+func (s *supervisor) Disable(ctx context.Context) error {
+	s.mu.Lock()
+	if !s.disabled {
+		s.disabled = true
+		s.parked = make(chan struct{})
+		if s.cancelRun != nil {
+			s.cancelRun()
+		}
+	}
+	parked := s.parked
+	s.mu.Unlock()
+
+	select {
+	case <-parked:
+		return nil
+	case <-s.exited:
+		return errSupervisorExited
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Enable re-initializes and starts a disabled module, returning Init's
+// error, after which the module stays disabled. Enabling a module that
+// isn't disabled does nothing.
+func (s *supervisor) Enable(ctx context.Context) error {
+	if !s.isDisabled() {
+		return nil
+	}
+	reply := make(chan error, 1)
+	select {
+	case s.enables <- reply:
+	case <-s.exited:
+		return errSupervisorExited
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,302 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
+)
+
+// flakyModule's Start fails the first failures times, then blocks until
+// ctx is cancelled.
+type flakyModule struct {
+	name     string
+	failures int
+	initErr  error // returned by Init while set
+
+	mu                   sync.Mutex
+	inits, starts, stops int
+}
+
+func (m *flakyModule) Name() string { return m.name }
+
+func (m *flakyModule) Init(context.Context, probe.Dependencies) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.initErr != nil {
+		return m.initErr
+	}
+	m.inits++
+	return nil
+}
+
+func (m *flakyModule) Start(ctx context.Context) error {
+	m.mu.Lock()
+	m.starts++
+	fail := m.starts <= m.failures
+	m.mu.Unlock()
+	if fail {
+		return errors.New("ring buffer broke")
+	}
+	<-ctx.Done()
+	return nil
+}
+
+func (m *flakyModule) Stop(context.Context) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stops++
+	return nil
+}
+
+func (m *flakyModule) counts() (inits, starts, stops int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.inits, m.starts, m.stops
+}
+
+// states records the states a supervisor reports.
+type states struct {
+	mu   sync.Mutex
+	seen []readiness.State
+}
+
+func (s *states) set(st readiness.State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seen = append(s.seen, st)
+}
+
+func (s *states) last() readiness.State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.seen) == 0 {
+		return ""
+	}
+	return s.seen[len(s.seen)-1]
+}
+
+func (s *states) count(st readiness.State) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, x := range s.seen {
+		if x == st {
+			n++
+		}
+	}
+	return n
+}
+
+func testSupervisor(m probe.Module, maxRestarts int, st *states) *supervisor {
+	s := newSupervisor(m, probe.Dependencies{}, zap.NewNop(), func() int { return maxRestarts }, st.set)
+	s.backoff, s.maxBackoff, s.stableAfter = time.Millisecond, 4*time.Millisecond, time.Hour
+	return s
+}
+
+// runUntil runs s until cond holds, then cancels it.
+func runUntil(t *testing.T, s *supervisor, cond func() bool) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatal("condition not reached")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+}
+
+func TestSupervisor_RestartsUntilRunning(t *testing.T) {
+	m := &flakyModule{name: "sup-recover", failures: 3}
+	var st states
+	restarts := moduleRestarts.WithLabelValues("sup-recover")
+	before := testutil.ToFloat64(restarts)
+	s := testSupervisor(m, 5, &st)
+	runUntil(t, s, func() bool { _, starts, _ := m.counts(); return starts == 4 })
+
+	inits, starts, stops := m.counts()
+	if inits != 3 || starts != 4 || stops != 3 {
+		t.Errorf("inits, starts, stops = %d, %d, %d; want 3, 4, 3", inits, starts, stops)
+	}
+	if got := testutil.ToFloat64(restarts) - before; got != 3 {
+		t.Errorf("module_restarts_total delta = %v, want 3", got)
+	}
+	if st.last() != readiness.Running || st.count(readiness.Restarting) != 3 || st.count(readiness.Failed) != 0 {
+		t.Errorf("states = %v, want 3 restarts ending running", st.seen)
+	}
+}
+
+func TestSupervisor_GivesUpAfterMaxRestarts(t *testing.T) {
+	m := &flakyModule{name: "sup-fail", failures: 100}
+	var st states
+	restarts := moduleRestarts.WithLabelValues("sup-fail")
+	before := testutil.ToFloat64(restarts)
+	s := testSupervisor(m, 2, &st)
+
+	done := make(chan struct{})
+	go func() {
+		s.Run(context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor did not give up")
+	}
+	if st.last() != readiness.Failed {
+		t.Errorf("states = %v, want failed last", st.seen)
+	}
+
+	inits, starts, stops := m.counts()
+	if inits != 2 || starts != 3 || stops != 3 {
+		t.Errorf("inits, starts, stops = %d, %d, %d; want 2, 3, 3", inits, starts, stops)
+	}
+	if got := testutil.ToFloat64(restarts) - before; got != 2 {
+		t.Errorf("module_restarts_total delta = %v, want 2", got)
+	}
+}
+
+func TestSupervisor_ZeroMaxRestartsDisablesRestart(t *testing.T) {
+	m := &flakyModule{name: "sup-none", failures: 1}
+	var st states
+	testSupervisor(m, 0, &st).Run(context.Background())
+
+	if inits, starts, stops := m.counts(); inits != 0 || starts != 1 || stops != 1 {
+		t.Errorf("inits, starts, stops = %d, %d, %d; want 0, 1, 1", inits, starts, stops)
+	}
+	if st.last() != readiness.Failed {
+		t.Errorf("states = %v, want failed last", st.seen)
+	}
+}
+
+func TestSupervisor_CancelDuringBackoff(t *testing.T) {
+	m := &flakyModule{name: "sup-cancel", failures: 1}
+	var st states
+	s := testSupervisor(m, 5, &st)
+	s.backoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	for _, _, stops := m.counts(); stops == 0; _, _, stops = m.counts() {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("supervisor ignored cancellation during backoff")
+	}
+	if inits, _, _ := m.counts(); inits != 0 {
+		t.Errorf("inits = %d after cancel, want 0", inits)
+	}
+	if st.count(readiness.Failed) != 0 {
+		t.Errorf("states = %v; shutdown reported as failure", st.seen)
+	}
+}
+
+func TestSupervisor_DisableEnable(t *testing.T) {
+	m := &flakyModule{name: "sup-toggle"}
+	var st states
+	s := testSupervisor(m, 5, &st)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+	for _, starts, _ := m.counts(); starts != 1; _, starts, _ = m.counts() {
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := s.Disable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if inits, starts, stops := m.counts(); starts != 1 || stops != 1 || inits != 0 {
+		t.Errorf("after Disable: inits, starts, stops = %d, %d, %d; want 0, 1, 1", inits, starts, stops)
+	}
+	if st.last() != readiness.Disabled {
+		t.Errorf("states = %v, want disabled last", st.seen)
+	}
+	if err := s.Disable(ctx); err != nil {
+		t.Errorf("second Disable: %v", err)
+	}
+
+	m.mu.Lock()
+	m.initErr = errors.New("attaching kprobe: no such file")
+	m.mu.Unlock()
+	if err := s.Enable(ctx); err == nil || !s.isDisabled() {
+		t.Errorf("Enable with Init failing = %v, disabled = %v; want the error, still disabled", err, s.isDisabled())
+	}
+	m.mu.Lock()
+	m.initErr = nil
+	m.mu.Unlock()
+	if err := s.Enable(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for _, starts, _ := m.counts(); starts != 2; _, starts, _ = m.counts() {
+		time.Sleep(time.Millisecond)
+	}
+	if inits, _, _ := m.counts(); inits != 1 || st.last() != readiness.Running {
+		t.Errorf("after Enable: inits = %d, states = %v; want 1 init, running", inits, st.seen)
+	}
+	if got := s.restarts.Load(); got != 0 {
+		t.Errorf("restarts = %d; a toggle is not a restart", got)
+	}
+}
+
+func TestSupervisor_ToggleAfterExit(t *testing.T) {
+	m := &flakyModule{name: "sup-gone", failures: 1}
+	var st states
+	s := testSupervisor(m, 0, &st)
+	s.Run(context.Background())
+	if err := s.Disable(context.Background()); !errors.Is(err, errSupervisorExited) {
+		t.Errorf("Disable after the module failed = %v", err)
+	}
+	if err := s.Enable(context.Background()); !errors.Is(err, errSupervisorExited) {
+		t.Errorf("Enable after the module failed = %v", err)
+	}
+}
+
+func TestRuntime_NotReadyOnceLastModuleFails(t *testing.T) {
+	rt := NewRuntime(config.Default(), zap.NewNop(), zap.NewAtomicLevel())
+	rt.started["tcp"], rt.started["dns"] = true, true
+	rt.setModuleState("tcp", readiness.Running)
+	rt.setModuleState("dns", readiness.Running)
+
+	rt.setModuleState("tcp", readiness.Failed)
+	if !rt.Readiness().Ready() {
+		t.Error("not ready with dns still running")
+	}
+	if rt.started["tcp"] {
+		t.Error("failed module still counted as started")
+	}
+	rt.setModuleState("dns", readiness.Failed)
+	if rt.Readiness().Ready() {
+		t.Error("ready after every module failed")
+	}
+}
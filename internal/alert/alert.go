@@ -0,0 +1,181 @@
+// Package alert evaluates threshold rules against the agent's event stream
+// and sends an alert when one fires, so a node can page on OOM kills or
+// retransmit storms without the NATS → ClickHouse backend.
+//
+// A rule counts the events of one type, optionally narrowed by label
+// matchers, per pod over a sliding window. When the count reaches the
+// rule's threshold the alert fires once; the rule then stays quiet for that
+// pod until its cooldown has passed, and fires again only if the threshold
+// is still met.
+//
+// Fired alerts go to a generic JSON webhook, to Prometheus Alertmanager, or
+// both. Alertmanager is also told when an alert resolves: once the pod's
+// window holds fewer than threshold events.
+package alert
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Config holds the alert exporter settings.
+type Config struct {
+	WebhookURL string        `yaml:"webhook_url"` // receives a JSON POST per alert; empty disables
+	Timeout    time.Duration `yaml:"timeout"`     // per webhook attempt
+	Retries    int           `yaml:"retries"`     // attempts after the first; 0 never retries
+	Cooldown   time.Duration `yaml:"cooldown"`    // for rules that set none
+	Rules      []Rule        `yaml:"rules"`
+}
+
+// DefaultConfig returns defaults with no rules.
+func DefaultConfig() Config {
+	return Config{
+		Timeout:  constants.AlertWebhookTimeout,
+		Retries:  constants.AlertWebhookRetries,
+		Cooldown: constants.AlertDefaultCooldown,
+	}
+}
+
+// Rule fires when Threshold matching events from one pod arrive within
+// Window.
+type Rule struct {
+	Name      string            `yaml:"name"`
+	Type      string            `yaml:"type"`  // event type, e.g. oom or retransmit
+	Match     map[string]string `yaml:"match"` // label → regex the whole value must match
+	Threshold int               `yaml:"threshold"`
+	Window    time.Duration     `yaml:"window"`   // 0 means 1m
+	Cooldown  time.Duration     `yaml:"cooldown"` // 0 means Config.Cooldown
+	Severity  string            `yaml:"severity"` // Alertmanager severity label; default warning
+}
+
+// eventTypes are the types a rule may name.
+var eventTypes = map[string]bool{
+	constants.ModuleTCP:        true,
+	constants.ModuleDNS:        true,
+	constants.ModuleRetransmit: true,
+	constants.ModuleRST:        true,
+	constants.ModuleOOM:        true,
+	constants.ModuleExec:       true,
+	constants.ModuleFileIO:     true,
+	constants.ModuleDrop:       true,
+	constants.ModuleSignals:    true,
+}
+
+// rule is a validated Rule with its matchers compiled and defaults applied.
+type rule struct {
+	name      string
+	typ       string
+	match     map[string]*regexp.Regexp
+	threshold int
+	window    time.Duration
+	cooldown  time.Duration
+	severity  string
+}
+
+// Validate checks cfg without contacting the webhook.
+func (c Config) Validate() error {
+	_, err := c.compile()
+	return err
+}
+
+// compile validates c and returns its rules ready to evaluate.
+func (c Config) compile() ([]*rule, error) {
+	var errs []string
+	if c.WebhookURL != "" && !httpURL(c.WebhookURL) {
+		errs = append(errs, "webhook_url must be an http or https URL")
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, "timeout must be > 0")
+	}
+	if c.Retries < 0 {
+		errs = append(errs, "retries must be >= 0")
+	}
+	if c.Cooldown < 0 {
+		errs = append(errs, "cooldown must be >= 0")
+	}
+	if len(c.Rules) == 0 {
+		errs = append(errs, "at least one rule is required")
+	}
+
+	rules := make([]*rule, 0, len(c.Rules))
+	names := make(map[string]bool, len(c.Rules))
+	for i, r := range c.Rules {
+		prefix := fmt.Sprintf("rules[%d]", i)
+		if r.Name == "" {
+			errs = append(errs, prefix+".name is required")
+		} else if names[r.Name] {
+			errs = append(errs, fmt.Sprintf("%s.name %q is not unique", prefix, r.Name))
+		}
+		names[r.Name] = true
+		if !eventTypes[r.Type] {
+			errs = append(errs, fmt.Sprintf("%s.type %q is not an event type", prefix, r.Type))
+		}
+		if r.Threshold < 1 {
+			errs = append(errs, prefix+".threshold must be >= 1")
+		}
+		if r.Window < 0 {
+			errs = append(errs, prefix+".window must be >= 0")
+		}
+		if r.Cooldown < 0 {
+			errs = append(errs, prefix+".cooldown must be >= 0")
+		}
+		cr := &rule{
+			name:      r.Name,
+			typ:       r.Type,
+			match:     make(map[string]*regexp.Regexp, len(r.Match)),
+			threshold: r.Threshold,
+			window:    r.Window,
+			cooldown:  r.Cooldown,
+			severity:  r.Severity,
+		}
+		if cr.window == 0 {
+			cr.window = constants.AlertDefaultWindow
+		}
+		if cr.cooldown == 0 {
+			cr.cooldown = c.Cooldown
+		}
+		if cr.severity == "" {
+			cr.severity = constants.AlertDefaultSeverity
+		}
+		for key, expr := range r.Match {
+			re, err := regexp.Compile("^(?:" + expr + ")$")
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s.match.%s %q: %v", prefix, key, expr, err))
+				continue
+			}
+			cr.match[key] = re
+		}
+		rules = append(rules, cr)
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return rules, nil
+}
+
+// httpURL reports whether s is an absolute http or https URL.
+func httpURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// RedactedWebhookURL returns the webhook URL with its path and query
+// masked, since chat webhooks embed their token there, for display.
+func (c Config) RedactedWebhookURL() string {
+	u, err := url.Parse(c.WebhookURL)
+	if err != nil {
+		return "<unparsable>"
+	}
+	if u.Path == "" && u.RawQuery == "" {
+		return u.Redacted()
+	}
+	u.Path, u.RawPath, u.RawQuery = "/xxxxx", "", ""
+	return u.Redacted()
+}
@@ -0,0 +1,196 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// AlertmanagerConfig holds Alertmanager delivery settings.
+type AlertmanagerConfig struct {
+	URLs    []string      `yaml:"urls"`    // tried in order until one accepts the alerts
+	Timeout time.Duration `yaml:"timeout"` // per POST
+}
+
+// DefaultAlertmanagerConfig returns defaults with no URLs.
+func DefaultAlertmanagerConfig() AlertmanagerConfig {
+	return AlertmanagerConfig{Timeout: constants.AlertmanagerTimeout}
+}
+
+// Validate checks cfg without contacting Alertmanager.
+func (c AlertmanagerConfig) Validate() error {
+	var errs []string
+	if len(c.URLs) == 0 {
+		errs = append(errs, "at least one url is required")
+	}
+	for i, u := range c.URLs {
+		if !httpURL(u) {
+			errs = append(errs, fmt.Sprintf("urls[%d] must be an http or https URL", i))
+		}
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, "timeout must be > 0")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// amErrors counts POSTs that no Alertmanager accepted.
+var amErrors = promauto.NewCounter(prometheus.CounterOpts{
+	Name: constants.MetricAlertmanagerErrors,
+	Help: "Alert batches no configured Alertmanager accepted.",
+})
+
+// amAlert is one alert in the Alertmanager v2 API (postableAlert).
+type amAlert struct {
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	EndsAt      time.Time         `json:"endsAt"`
+}
+
+// alertmanager posts alerts to the first Alertmanager that accepts them.
+// It remembers firing alerts and re-posts them on every resend, each time
+// with endsAt AlertmanagerAlertTTL ahead, until they resolve. A resolve
+// that fails to post therefore still takes effect once the TTL runs out.
+// Not safe for concurrent use; the exporter drives it from one goroutine.
+type alertmanager struct {
+	urls   []string
+	client *http.Client
+	next   int // index of the URL that last accepted alerts
+	firing map[string]amAlert
+}
+
+func newAlertmanager(cfg AlertmanagerConfig) *alertmanager {
+	return &alertmanager{
+		urls:   cfg.URLs,
+		client: &http.Client{Timeout: cfg.Timeout},
+		firing: make(map[string]amAlert),
+	}
+}
+
+// toAM maps a to Alertmanager labels, which identify the alert, and
+// annotations, which describe the event that fired it.
+func toAM(a Alert) amAlert {
+	annotations := map[string]string{
+		"summary":   fmt.Sprintf("%d %s events within %s", a.Threshold, a.Type, a.Window),
+		"type":      a.Type,
+		"pid":       strconv.FormatUint(uint64(a.PID), 10),
+		"comm":      a.Comm,
+		"threshold": strconv.Itoa(a.Threshold),
+		"window":    a.Window,
+	}
+	for k, v := range a.Labels {
+		if _, taken := annotations[k]; !taken {
+			annotations[k] = v
+		}
+	}
+	return amAlert{
+		Labels: map[string]string{
+			"alertname": a.Rule,
+			"namespace": a.Namespace,
+			"pod":       a.Pod,
+			"node":      a.Node,
+			"severity":  a.Severity,
+		},
+		Annotations: annotations,
+		StartsAt:    a.FiredAt,
+	}
+}
+
+// notify posts a firing or resolved alert.
+func (m *alertmanager) notify(ctx context.Context, a Alert, now time.Time) error {
+	am := toAM(a)
+	if a.ResolvedAt.IsZero() {
+		m.firing[a.Fingerprint] = am
+		am.EndsAt = now.Add(constants.AlertmanagerAlertTTL)
+	} else {
+		delete(m.firing, a.Fingerprint)
+		am.EndsAt = a.ResolvedAt
+	}
+	return m.post(ctx, []amAlert{am})
+}
+
+// resend re-posts every firing alert so Alertmanager keeps them active.
+func (m *alertmanager) resend(ctx context.Context, now time.Time) error {
+	if len(m.firing) == 0 {
+		return nil
+	}
+	batch := make([]amAlert, 0, len(m.firing))
+	for _, am := range m.firing {
+		am.EndsAt = now.Add(constants.AlertmanagerAlertTTL)
+		batch = append(batch, am)
+	}
+	return m.post(ctx, batch)
+}
+
+// post sends batch to each URL in turn, starting with the one that last
+// worked, until one accepts it.
+func (m *alertmanager) post(ctx context.Context, batch []amAlert) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for i := range m.urls {
+		idx := (m.next + i) % len(m.urls)
+		err := m.postTo(ctx, m.urls[idx], body)
+		if err == nil {
+			m.next = idx
+			return nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	amErrors.Inc()
+	return errors.Join(errs...)
+}
+
+func (m *alertmanager) postTo(ctx context.Context, base string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		strings.TrimSuffix(base, "/")+constants.AlertmanagerPath, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := m.client.Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			return fmt.Errorf("%s: %w", redactURL(base), ue.Err)
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: %w", redactURL(base), &statusError{resp.StatusCode})
+	}
+	return nil
+}
+
+// redactURL masks any password in u, for errors and logs.
+func redactURL(u string) string {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return "<unparsable>"
+	}
+	return parsed.Redacted()
+}
@@ -0,0 +1,162 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// amStub records the alert batches POSTed to its Alertmanager v2 API,
+// answering with status (200 when 0).
+type amStub struct {
+	*httptest.Server
+	status int
+
+	mu      sync.Mutex
+	batches [][]amAlert
+}
+
+func newAMStub(t *testing.T, status int) *amStub {
+	t.Helper()
+	s := &amStub{status: status}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != constants.AlertmanagerPath {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var batch []amAlert
+		if err := json.NewDecoder(r.Body).Decode(&batch); err != nil {
+			t.Errorf("decoding alerts: %v", err)
+		}
+		s.mu.Lock()
+		s.batches = append(s.batches, batch)
+		s.mu.Unlock()
+		if s.status != 0 {
+			w.WriteHeader(s.status)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *amStub) received() [][]amAlert {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.batches
+}
+
+func firingAlert() Alert {
+	return Alert{
+		Rule:        "oom-kill",
+		Type:        "oom",
+		Severity:    "critical",
+		Namespace:   "prod",
+		Pod:         "api-0",
+		Node:        "n1",
+		PID:         4242,
+		Comm:        "java",
+		Labels:      map[string]string{"reason": "cgroup"},
+		Threshold:   1,
+		Window:      "1m0s",
+		FiredAt:     t0,
+		Fingerprint: "f1",
+	}
+}
+
+func TestAlertmanager_MapsAlert(t *testing.T) {
+	stub := newAMStub(t, 0)
+	am := newAlertmanager(AlertmanagerConfig{URLs: []string{stub.URL}, Timeout: time.Second})
+
+	now := t0.Add(time.Second)
+	if err := am.notify(context.Background(), firingAlert(), now); err != nil {
+		t.Fatal(err)
+	}
+	got := stub.received()
+	if len(got) != 1 || len(got[0]) != 1 {
+		t.Fatalf("received %v, want one batch of one alert", got)
+	}
+	a := got[0][0]
+	wantLabels := map[string]string{"alertname": "oom-kill", "namespace": "prod", "pod": "api-0", "node": "n1", "severity": "critical"}
+	if len(a.Labels) != len(wantLabels) {
+		t.Errorf("labels = %v, want %v", a.Labels, wantLabels)
+	}
+	for k, v := range wantLabels {
+		if a.Labels[k] != v {
+			t.Errorf("label %s = %q, want %q", k, a.Labels[k], v)
+		}
+	}
+	for k, v := range map[string]string{"comm": "java", "pid": "4242", "reason": "cgroup", "type": "oom"} {
+		if a.Annotations[k] != v {
+			t.Errorf("annotation %s = %q, want %q", k, a.Annotations[k], v)
+		}
+	}
+	if !a.StartsAt.Equal(t0) || !a.EndsAt.Equal(now.Add(constants.AlertmanagerAlertTTL)) {
+		t.Errorf("startsAt=%s endsAt=%s, want fired_at and now+TTL", a.StartsAt, a.EndsAt)
+	}
+}
+
+func TestAlertmanager_ResolveLifecycle(t *testing.T) {
+	stub := newAMStub(t, 0)
+	am := newAlertmanager(AlertmanagerConfig{URLs: []string{stub.URL}, Timeout: time.Second})
+	ctx := context.Background()
+
+	a := firingAlert()
+	am.notify(ctx, a, t0)
+	if err := am.resend(ctx, t0.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	a.ResolvedAt = t0.Add(90 * time.Second)
+	am.notify(ctx, a, a.ResolvedAt)
+	am.resend(ctx, t0.Add(2*time.Minute))
+
+	got := stub.received()
+	if len(got) != 3 {
+		t.Fatalf("received %d batches, want fire, resend, resolve and no second resend", len(got))
+	}
+	if resent := got[1][0]; !resent.EndsAt.Equal(t0.Add(time.Minute + constants.AlertmanagerAlertTTL)) {
+		t.Errorf("resent endsAt = %s, want pushed out by the TTL", resent.EndsAt)
+	}
+	if resolved := got[2][0]; !resolved.EndsAt.Equal(a.ResolvedAt) || resolved.Labels["alertname"] != "oom-kill" {
+		t.Errorf("resolved alert = %+v, want endsAt at resolution", resolved)
+	}
+}
+
+func TestAlertmanager_FailsOver(t *testing.T) {
+	down := newAMStub(t, http.StatusServiceUnavailable)
+	up := newAMStub(t, 0)
+	am := newAlertmanager(AlertmanagerConfig{URLs: []string{down.URL, up.URL + "/"}, Timeout: time.Second})
+	ctx := context.Background()
+
+	if err := am.notify(ctx, firingAlert(), t0); err != nil {
+		t.Fatalf("notify = %v, want the second Alertmanager to accept", err)
+	}
+	if err := am.resend(ctx, t0.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if n, m := len(down.received()), len(up.received()); n != 1 || m != 2 {
+		t.Errorf("down received %d, up received %d; want 1 and 2 (the working URL is tried first)", n, m)
+	}
+
+	up.status = http.StatusInternalServerError
+	err := am.resend(ctx, t0.Add(2*time.Minute))
+	if err == nil || !strings.Contains(err.Error(), "503") || !strings.Contains(err.Error(), "500") {
+		t.Errorf("resend = %v, want both failures", err)
+	}
+}
+
+func TestAlertmanagerConfig_Validate(t *testing.T) {
+	if err := DefaultAlertmanagerConfig().Validate(); err == nil {
+		t.Error("accepted no URLs")
+	}
+	cfg := DefaultAlertmanagerConfig()
+	cfg.URLs = []string{"http://am-0:9093", "am-1:9093"}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "urls[1]") {
+		t.Errorf("Validate() = %v, want urls[1] rejected", err)
+	}
+}
@@ -0,0 +1,199 @@
+package alert
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+var (
+	// fired counts alerts by rule, whether or not they were delivered.
+	fired = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricAlertsFired,
+		Help: "Alerts fired, by rule.",
+	}, constants.LabelsRule)
+
+	// failed counts alerts dropped with a delivery queue full, and alerts
+	// that never reached the webhook: rejected, or still failing after
+	// every retry. Alertmanager failures count in amErrors; firing alerts
+	// are resent to it anyway.
+	failed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricAlertDeliveryFailed,
+		Help: "Alerts not delivered to the webhook or dropped before Alertmanager, by rule.",
+	}, constants.LabelsRule)
+)
+
+// Exporter evaluates alert rules against the EventBus and delivers fired
+// alerts to the webhook and Alertmanager. Implements export.Exporter.
+type Exporter struct {
+	logger *zap.Logger
+	bus    *event.Bus
+	eval   *evaluator
+	hook   *webhook // nil without a webhook URL
+	queue  chan Alert
+	am     *alertmanager // nil unless SendToAlertmanager was called
+	amQ    chan Alert
+}
+
+// New creates an alert exporter (Factory constructor). It fails only if
+// cfg does not pass Validate.
+func New(cfg Config, bus *event.Bus, logger *zap.Logger) (*Exporter, error) {
+	rules, err := cfg.compile()
+	if err != nil {
+		return nil, err
+	}
+	e := &Exporter{
+		logger: logger,
+		bus:    bus,
+		eval:   newEvaluator(rules),
+	}
+	if cfg.WebhookURL != "" {
+		e.hook = &webhook{
+			url:        cfg.WebhookURL,
+			client:     &http.Client{Timeout: cfg.Timeout},
+			retries:    cfg.Retries,
+			backoff:    constants.AlertRetryBackoff,
+			maxBackoff: constants.AlertRetryMaxBackoff,
+		}
+		e.queue = make(chan Alert, constants.AlertQueueSize)
+	}
+	return e, nil
+}
+
+// SendToAlertmanager also delivers alerts, and their resolution, to
+// Alertmanager. Must be called before Start.
+func (e *Exporter) SendToAlertmanager(cfg AlertmanagerConfig) {
+	e.am = newAlertmanager(cfg)
+	e.amQ = make(chan Alert, constants.AlertQueueSize)
+}
+
+func (e *Exporter) Name() string { return constants.ExporterAlert }
+
+func (e *Exporter) Start(ctx context.Context) error {
+	events := e.bus.Subscribe(constants.ExporterAlert)
+	if e.hook != nil {
+		go e.deliver(ctx)
+	}
+	if e.am != nil {
+		go e.deliverAlertmanager(ctx)
+	}
+
+	sweep := time.NewTicker(constants.AlertSweepInterval)
+	defer sweep.Stop()
+	resolve := time.NewTicker(constants.AlertResolveInterval)
+	defer resolve.Stop()
+
+	e.logger.Info("Alert exporter started", zap.Int("rules", e.ruleCount()))
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case evt, ok := <-events:
+			if !ok {
+				return nil
+			}
+			alerts := e.eval.observe(evt)
+			evt.Done()
+			for _, a := range alerts {
+				e.fire(a)
+			}
+		case now := <-resolve.C:
+			for _, a := range e.eval.resolve(now) {
+				e.logger.Info("Alert resolved",
+					zap.String("rule", a.Rule),
+					zap.String("namespace", a.Namespace),
+					zap.String("pod", a.Pod))
+				e.enqueueAlertmanager(a)
+			}
+		case now := <-sweep.C:
+			e.eval.sweep(now)
+		}
+	}
+}
+
+// Stop is a no-op: alerts still queued when ctx is cancelled are dropped.
+func (e *Exporter) Stop(_ context.Context) error { return nil }
+
+func (e *Exporter) ruleCount() int {
+	n := 0
+	for _, rules := range e.eval.byType {
+		n += len(rules)
+	}
+	return n
+}
+
+// fire queues a for delivery without blocking the event loop.
+func (e *Exporter) fire(a Alert) {
+	fired.WithLabelValues(a.Rule).Inc()
+	e.logger.Info("Alert fired",
+		zap.String("rule", a.Rule),
+		zap.String("namespace", a.Namespace),
+		zap.String("pod", a.Pod))
+	if e.hook != nil {
+		select {
+		case e.queue <- a:
+		default:
+			failed.WithLabelValues(a.Rule).Inc()
+			e.logger.Warn("Alert queue full, dropping alert", zap.String("rule", a.Rule))
+		}
+	}
+	e.enqueueAlertmanager(a)
+}
+
+// enqueueAlertmanager queues a firing or resolved alert for Alertmanager,
+// if configured, without blocking the event loop.
+func (e *Exporter) enqueueAlertmanager(a Alert) {
+	if e.am == nil {
+		return
+	}
+	select {
+	case e.amQ <- a:
+	default:
+		failed.WithLabelValues(a.Rule).Inc()
+		e.logger.Warn("Alertmanager queue full, dropping alert", zap.String("rule", a.Rule))
+	}
+}
+
+// deliver sends queued alerts to the webhook one at a time until ctx is
+// cancelled.
+func (e *Exporter) deliver(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-e.queue:
+			if err := e.hook.send(ctx, a); err != nil {
+				failed.WithLabelValues(a.Rule).Inc()
+				e.logger.Warn("Alert delivery failed", zap.String("rule", a.Rule), zap.Error(err))
+			}
+		}
+	}
+}
+
+// deliverAlertmanager posts queued alerts to Alertmanager, and re-posts the
+// firing ones every AlertmanagerResendInterval, until ctx is cancelled.
+func (e *Exporter) deliverAlertmanager(ctx context.Context) {
+	ticker := time.NewTicker(constants.AlertmanagerResendInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case a := <-e.amQ:
+			if err := e.am.notify(ctx, a, time.Now()); err != nil {
+				e.logger.Warn("Alertmanager delivery failed", zap.String("rule", a.Rule), zap.Error(err))
+			}
+		case now := <-ticker.C:
+			if err := e.am.resend(ctx, now); err != nil {
+				e.logger.Warn("Alertmanager resend failed", zap.Error(err))
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// webhook POSTs alerts as JSON, retrying failed deliveries with
+// exponential backoff from backoff up to maxBackoff.
+type webhook struct {
+	url                 string
+	client              *http.Client
+	retries             int
+	backoff, maxBackoff time.Duration
+}
+
+// statusError is a delivery the webhook answered with a non-2xx status.
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("webhook returned %d %s", e.code, http.StatusText(e.code))
+}
+
+// retryable reports whether a failed delivery may succeed if repeated:
+// transport errors, rate limiting and server errors are, other client
+// errors are not.
+func retryable(err error) bool {
+	var se *statusError
+	ok := errors.As(err, &se)
+	return !ok || se.code == http.StatusTooManyRequests || se.code >= 500
+}
+
+// send delivers a, retrying up to w.retries times. It gives up early when
+// ctx is cancelled or the webhook rejects the alert outright.
+func (w *webhook) send(ctx context.Context, a Alert) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	delay := w.backoff
+	for attempt := 0; ; attempt++ {
+		err = w.post(ctx, body)
+		if err == nil || attempt == w.retries || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay = min(delay*2, w.maxBackoff)
+	}
+}
+
+func (w *webhook) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		// Drop the URL from the error: chat webhooks embed their token in it.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			return ue.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{resp.StatusCode}
+	}
+	return nil
+}
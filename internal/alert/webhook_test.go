@@ -0,0 +1,81 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testWebhook answers each POST with the next status in statuses, then 200.
+func testWebhook(t *testing.T, retries int, statuses ...int) (*webhook, *atomic.Int32, chan Alert) {
+	t.Helper()
+	var calls atomic.Int32
+	got := make(chan Alert, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		var a Alert
+		if err := json.NewDecoder(r.Body).Decode(&a); err != nil || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("bad webhook request: %v", err)
+		}
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		got <- a
+	}))
+	t.Cleanup(srv.Close)
+	return &webhook{
+		url:        srv.URL + "/hook",
+		client:     srv.Client(),
+		retries:    retries,
+		backoff:    time.Millisecond,
+		maxBackoff: 4 * time.Millisecond,
+	}, &calls, got
+}
+
+func TestWebhook_RetriesServerErrors(t *testing.T) {
+	w, calls, got := testWebhook(t, 3, 500, 429, 503)
+	if err := w.send(context.Background(), Alert{Rule: "oom", Pod: "api-0"}); err != nil {
+		t.Fatalf("send = %v, want success on the fourth attempt", err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("attempts = %d, want 4", n)
+	}
+	if a := <-got; a.Rule != "oom" || a.Pod != "api-0" {
+		t.Errorf("delivered %+v", a)
+	}
+}
+
+func TestWebhook_GivesUpAfterRetries(t *testing.T) {
+	w, calls, _ := testWebhook(t, 2, 500, 500, 500, 500)
+	err := w.send(context.Background(), Alert{Rule: "oom"})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("send = %v, want the last 500", err)
+	}
+	if n := calls.Load(); n != 3 {
+		t.Errorf("attempts = %d, want 3", n)
+	}
+}
+
+func TestWebhook_DoesNotRetryClientErrors(t *testing.T) {
+	w, calls, _ := testWebhook(t, 3, 404)
+	if err := w.send(context.Background(), Alert{Rule: "oom"}); err == nil {
+		t.Error("send succeeded after a 404")
+	}
+	if n := calls.Load(); n != 1 {
+		t.Errorf("attempts = %d, want 1", n)
+	}
+}
+
+func TestWebhook_ErrorOmitsURL(t *testing.T) {
+	w := &webhook{url: "http://127.0.0.1:1/services/secret-token", client: &http.Client{Timeout: time.Second}}
+	err := w.send(context.Background(), Alert{Rule: "oom"})
+	if err == nil || strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("send = %v, want an error without the URL", err)
+	}
+}
@@ -0,0 +1,210 @@
+package alert
+
+import (
+	"encoding/hex"
+	"hash/fnv"
+	"maps"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// Alert is the JSON body posted to the webhook. The process and labels
+// are those of the event that crossed the threshold.
+type Alert struct {
+	Rule      string            `json:"rule"`
+	Type      string            `json:"type"`
+	Severity  string            `json:"severity"`
+	Namespace string            `json:"namespace"`
+	Pod       string            `json:"pod"`
+	Node      string            `json:"node"`
+	PID       uint32            `json:"pid"`
+	Comm      string            `json:"comm"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Threshold int               `json:"threshold"`
+	Window    string            `json:"window"`
+	FiredAt   time.Time         `json:"fired_at"`
+
+	// Fingerprint is the same for every alert of one rule and pod, so a
+	// receiver can group repeats and drop retried deliveries.
+	Fingerprint string `json:"fingerprint"`
+
+	// ResolvedAt is set on the copy of a firing alert that resolve returns
+	// once the condition clears. Only Alertmanager is told.
+	ResolvedAt time.Time `json:"-"`
+}
+
+// window holds the times of the most recent matching events for one rule
+// and pod. It keeps at most threshold of them: that is all it takes to tell
+// whether threshold events fell within the rule's window, and it bounds
+// memory however fast events arrive.
+type window struct {
+	times      []time.Time // ring; times[head] is the oldest
+	head, n    int
+	span       time.Duration
+	quietUntil time.Time // cooldown after the last alert
+	firing     *Alert    // the last alert, until resolve finds its condition cleared
+}
+
+func newWindow(threshold int, span time.Duration) *window {
+	return &window{times: make([]time.Time, threshold), span: span}
+}
+
+// add records an event at t and returns how many recorded events fall in
+// (t-span, t].
+func (w *window) add(t time.Time) int {
+	w.expire(t.Add(-w.span))
+	if w.n == len(w.times) {
+		w.head = (w.head + 1) % len(w.times)
+		w.n--
+	}
+	w.times[(w.head+w.n)%len(w.times)] = t
+	w.n++
+	return w.n
+}
+
+// expire forgets events at or before cutoff.
+func (w *window) expire(cutoff time.Time) {
+	for w.n > 0 && !w.times[w.head].After(cutoff) {
+		w.head = (w.head + 1) % len(w.times)
+		w.n--
+	}
+}
+
+// windowKey identifies the window of one rule for one pod. Events without
+// a pod share the node-level key with empty namespace and pod.
+type windowKey struct {
+	rule, namespace, pod string
+}
+
+// evaluator applies rules to events. It is not safe for concurrent use;
+// the exporter calls it from its event loop only.
+type evaluator struct {
+	byType  map[string][]*rule
+	windows map[windowKey]*window
+}
+
+func newEvaluator(rules []*rule) *evaluator {
+	ev := &evaluator{
+		byType:  make(map[string][]*rule),
+		windows: make(map[windowKey]*window),
+	}
+	for _, r := range rules {
+		ev.byType[r.typ] = append(ev.byType[r.typ], r)
+	}
+	return ev
+}
+
+// observe counts evt against every rule it matches and returns the alerts
+// that fire, timed by the event.
+func (ev *evaluator) observe(evt *event.Event) []Alert {
+	var fired []Alert
+	for _, r := range ev.byType[evt.Type.String()] {
+		if !r.matches(evt) {
+			continue
+		}
+		key := windowKey{r.name, evt.Namespace, evt.Pod}
+		w := ev.windows[key]
+		if w == nil {
+			w = newWindow(r.threshold, r.window)
+			ev.windows[key] = w
+		}
+		t := evt.Timestamp
+		if w.add(t) < r.threshold || t.Before(w.quietUntil) {
+			continue
+		}
+		w.quietUntil = t.Add(r.cooldown)
+		a := Alert{
+			Rule:        r.name,
+			Type:        r.typ,
+			Severity:    r.severity,
+			Namespace:   evt.Namespace,
+			Pod:         evt.Pod,
+			Node:        evt.Node,
+			PID:         evt.PID,
+			Comm:        evt.Comm,
+			Labels:      maps.Clone(evt.Labels),
+			Threshold:   r.threshold,
+			Window:      r.window.String(),
+			FiredAt:     t,
+			Fingerprint: fingerprint(key),
+		}
+		w.firing = &a
+		fired = append(fired, a)
+	}
+	return fired
+}
+
+// resolve returns the firing alerts whose window holds fewer than
+// threshold events at now, each with ResolvedAt set, and stops tracking
+// them.
+func (ev *evaluator) resolve(now time.Time) []Alert {
+	var resolved []Alert
+	for _, w := range ev.windows {
+		if w.firing == nil {
+			continue
+		}
+		w.expire(now.Add(-w.span))
+		if w.n >= w.firing.Threshold {
+			continue
+		}
+		a := *w.firing
+		a.ResolvedAt = now
+		resolved = append(resolved, a)
+		w.firing = nil
+	}
+	return resolved
+}
+
+// sweep forgets windows with no events in their rule's window, no
+// cooldown running at now and no alert firing, returning how many it
+// removed.
+func (ev *evaluator) sweep(now time.Time) int {
+	removed := 0
+	for key, w := range ev.windows {
+		w.expire(now.Add(-w.span))
+		if w.n == 0 && !now.Before(w.quietUntil) && w.firing == nil {
+			delete(ev.windows, key)
+			removed++
+		}
+	}
+	return removed
+}
+
+// matches reports whether evt satisfies every matcher of r.
+func (r *rule) matches(evt *event.Event) bool {
+	for key, re := range r.match {
+		if !re.MatchString(field(evt, key)) {
+			return false
+		}
+	}
+	return true
+}
+
+// field returns the value a matcher key refers to: the event's namespace,
+// pod, node or comm, or else the label of that name.
+func field(evt *event.Event, key string) string {
+	switch key {
+	case constants.LabelNamespace:
+		return evt.Namespace
+	case constants.LabelPod:
+		return evt.Pod
+	case constants.LabelNode:
+		return evt.Node
+	case "comm":
+		return evt.Comm
+	default:
+		return evt.Labels[key]
+	}
+}
+
+func fingerprint(key windowKey) string {
+	h := fnv.New64a()
+	h.Write([]byte(key.rule))
+	h.Write([]byte{0})
+	h.Write([]byte(key.namespace))
+	h.Write([]byte{0})
+	h.Write([]byte(key.pod))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,154 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+var t0 = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func evt(typ event.EventType, pod string, at time.Duration, labels map[string]string) *event.Event {
+	return &event.Event{Type: typ, Timestamp: t0.Add(at), Namespace: "prod", Pod: pod, Node: "n1", Labels: labels}
+}
+
+func testEvaluator(t *testing.T, rules ...Rule) *evaluator {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.WebhookURL = "http://alerts.local/hook"
+	cfg.Rules = rules
+	compiled, err := cfg.compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return newEvaluator(compiled)
+}
+
+func TestWindow_CountsOnlyRecentEvents(t *testing.T) {
+	w := newWindow(3, time.Minute)
+	for i, tt := range []struct {
+		at   time.Duration
+		want int
+	}{
+		{0, 1},
+		{10 * time.Second, 2},
+		{50 * time.Second, 3},
+		{55 * time.Second, 3},  // capped at the threshold
+		{65 * time.Second, 3},  // 0s and 10s expired; 50s, 55s, 65s remain
+		{115 * time.Second, 2}, // only 65s and 115s are within a minute
+		{10 * time.Minute, 1},
+	} {
+		if got := w.add(t0.Add(tt.at)); got != tt.want {
+			t.Errorf("add #%d at +%s = %d, want %d", i, tt.at, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluator_FiresAtThresholdPerPod(t *testing.T) {
+	ev := testEvaluator(t, Rule{Name: "retransmits", Type: "retransmit", Threshold: 3, Window: time.Minute})
+
+	var fired []Alert
+	for i := range 3 {
+		fired = append(fired, ev.observe(evt(event.TypeRetransmit, "api-0", time.Duration(i)*time.Second, nil))...)
+		fired = append(fired, ev.observe(evt(event.TypeRetransmit, "api-1", time.Duration(i)*time.Minute, nil))...)
+	}
+	if len(fired) != 1 {
+		t.Fatalf("fired %d alerts, want 1: %+v", len(fired), fired)
+	}
+	a := fired[0]
+	if a.Rule != "retransmits" || a.Pod != "api-0" || a.Namespace != "prod" || a.Node != "n1" || a.Threshold != 3 {
+		t.Errorf("alert = %+v", a)
+	}
+	if !a.FiredAt.Equal(t0.Add(2 * time.Second)) {
+		t.Errorf("fired_at = %s, want the third event's time", a.FiredAt)
+	}
+	if ev.observe(evt(event.TypeTCP, "api-0", 3*time.Second, nil)) != nil {
+		t.Error("an event of another type fired the rule")
+	}
+}
+
+func TestEvaluator_CooldownDeduplicates(t *testing.T) {
+	ev := testEvaluator(t, Rule{Name: "oom", Type: "oom", Threshold: 1, Cooldown: 5 * time.Minute})
+
+	var fired []Alert
+	for _, at := range []time.Duration{0, time.Second, 4 * time.Minute, 5 * time.Minute, 6 * time.Minute} {
+		fired = append(fired, ev.observe(evt(event.TypeOOM, "worker-0", at, nil))...)
+	}
+	if len(fired) != 2 {
+		t.Fatalf("fired %d alerts, want 2 (at 0 and after the cooldown): %+v", len(fired), fired)
+	}
+	if !fired[1].FiredAt.Equal(t0.Add(5 * time.Minute)) {
+		t.Errorf("second alert at %s, want when the cooldown ended", fired[1].FiredAt)
+	}
+	if fired[0].Fingerprint != fired[1].Fingerprint {
+		t.Error("alerts for the same rule and pod should share a fingerprint")
+	}
+
+	other := ev.observe(evt(event.TypeOOM, "worker-1", time.Second, nil))
+	if len(other) != 1 {
+		t.Fatal("another pod should not share the first pod's cooldown")
+	}
+	if other[0].Fingerprint == fired[0].Fingerprint {
+		t.Error("alerts for different pods share a fingerprint")
+	}
+}
+
+func TestEvaluator_LabelMatchers(t *testing.T) {
+	ev := testEvaluator(t, Rule{
+		Name:      "db-resets",
+		Type:      "rst",
+		Match:     map[string]string{"direction": "received", "pod": "api-.*"},
+		Threshold: 1,
+	})
+	if ev.observe(evt(event.TypeRST, "api-0", 0, map[string]string{"direction": "sent"})) != nil {
+		t.Error("fired for a non-matching label")
+	}
+	if ev.observe(evt(event.TypeRST, "web-0", 0, map[string]string{"direction": "received"})) != nil {
+		t.Error("fired for a non-matching pod")
+	}
+	if ev.observe(evt(event.TypeRST, "api-0", 0, map[string]string{"direction": "received"})) == nil {
+		t.Error("did not fire for a matching event")
+	}
+}
+
+func TestEvaluator_SweepForgetsIdleWindows(t *testing.T) {
+	ev := testEvaluator(t, Rule{Name: "oom", Type: "oom", Threshold: 2, Window: time.Minute, Cooldown: 10 * time.Minute})
+	ev.observe(evt(event.TypeOOM, "quiet", 0, nil))
+	ev.observe(evt(event.TypeOOM, "firing", 0, nil))
+	ev.observe(evt(event.TypeOOM, "firing", time.Second, nil))
+
+	if n := ev.sweep(t0.Add(30 * time.Second)); n != 0 {
+		t.Errorf("sweep within the window removed %d, want 0", n)
+	}
+	if n := ev.sweep(t0.Add(2 * time.Minute)); n != 1 {
+		t.Errorf("sweep after the window removed %d, want 1 (the cooling-down pod stays)", n)
+	}
+	if n := ev.sweep(t0.Add(11 * time.Minute)); n != 0 {
+		t.Errorf("sweep removed %d windows with an unresolved alert, want 0", n)
+	}
+	ev.resolve(t0.Add(11 * time.Minute))
+	if n := ev.sweep(t0.Add(11 * time.Minute)); n != 1 || len(ev.windows) != 0 {
+		t.Errorf("sweep after the cooldown and resolve removed %d, left %d", n, len(ev.windows))
+	}
+}
+
+func TestEvaluator_ResolvesWhenBelowThreshold(t *testing.T) {
+	ev := testEvaluator(t, Rule{Name: "retransmits", Type: "retransmit", Threshold: 2, Window: time.Minute})
+	ev.observe(evt(event.TypeRetransmit, "api-0", 0, nil))
+	fired := ev.observe(evt(event.TypeRetransmit, "api-0", 30*time.Second, nil))
+	if len(fired) != 1 {
+		t.Fatalf("fired %d alerts, want 1", len(fired))
+	}
+
+	if r := ev.resolve(t0.Add(45 * time.Second)); r != nil {
+		t.Errorf("resolved %+v while both events are in the window", r)
+	}
+	r := ev.resolve(t0.Add(70 * time.Second))
+	if len(r) != 1 || r[0].Fingerprint != fired[0].Fingerprint || !r[0].ResolvedAt.Equal(t0.Add(70*time.Second)) {
+		t.Fatalf("resolve = %+v, want the fired alert resolved at +70s", r)
+	}
+	if r := ev.resolve(t0.Add(2 * time.Minute)); r != nil {
+		t.Errorf("resolved %+v twice", r)
+	}
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// wsTokenParam carries the bearer token on WebSocket upgrades, since
+// browsers can't set headers on a WebSocket handshake.
+const wsTokenParam = "access_token"
+
+// authMiddleware enforces bearer-token auth when tokens are configured
+// and records the matching token's name as the request owner, and its
+// namespaces for a namespace-scoped token. With no tokens it is a no-op.
+// allowQuery also accepts ?access_token= (for /ws).
+func (s *Server) authMiddleware(allowQuery bool) fiber.Handler {
+	// Hash once so every comparison is over equal-length inputs.
+	type entry struct {
+		name       string
+		sum        [sha256.Size]byte
+		namespaces []string // nil for an unscoped token
+	}
+	entries := make([]entry, 0, len(s.cfg.Tokens))
+	for name, tok := range s.cfg.Tokens {
+		var namespaces []string
+		if ns, ok := s.cfg.TokenNamespaces[name]; ok {
+			namespaces = slices.Compact(slices.Sorted(slices.Values(ns)))
+		}
+		entries = append(entries, entry{name, sha256.Sum256([]byte(tok)), namespaces})
+	}
+
+	return func(c *fiber.Ctx) error {
+		if len(entries) == 0 {
+			return c.Next()
+		}
+
+		token, ok := strings.CutPrefix(c.Get(fiber.HeaderAuthorization), "Bearer ")
+		if !ok && allowQuery {
+			token = c.Query(wsTokenParam)
+		}
+		if token == "" {
+			return unauthorized(c, "missing bearer token")
+		}
+
+		sum := sha256.Sum256([]byte(token))
+		var match *entry
+		for i := range entries {
+			// No early exit: every token is compared on every request.
+			if subtle.ConstantTimeCompare(sum[:], entries[i].sum[:]) == 1 {
+				match = &entries[i]
+			}
+		}
+		if match == nil {
+			return unauthorized(c, "invalid bearer token")
+		}
+		c.Locals(localsOwner, match.name)
+		if match.namespaces != nil {
+			c.Locals(localsNamespaces, match.namespaces)
+		}
+		return c.Next()
+	}
+}
+
+// ParseTokens parses "name:token" pairs separated by commas or newlines.
+// Blank entries and lines starting with '#' are ignored.
+func ParseTokens(s string) (map[string]string, error) {
+	tokens := make(map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(s, ",", "\n")))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, tok, ok := strings.Cut(line, ":")
+		name, tok = strings.TrimSpace(name), strings.TrimSpace(tok)
+		if !ok || name == "" || tok == "" {
+			return nil, fmt.Errorf("token entry %q: want name:token", line)
+		}
+		if _, dup := tokens[name]; dup {
+			return nil, fmt.Errorf("token name %q defined twice", name)
+		}
+		tokens[name] = tok
+	}
+	return tokens, sc.Err()
+}
+
+// LoadTokenFile reads a token file in ParseTokens format.
+func LoadTokenFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read token file: %w", err)
+	}
+	return ParseTokens(string(data))
+}
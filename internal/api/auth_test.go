@@ -0,0 +1,121 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func newAuthTestServer() *Server {
+	s := &Server{
+		cfg:    Config{Tokens: map[string]string{"alice": "s3cret-a", "bob": "s3cret-b"}},
+		app:    fiber.New(),
+		views:  newMemViewStore(),
+		logger: zap.NewNop(),
+	}
+	s.registerRoutes()
+	return s
+}
+
+func TestAuth_Middleware(t *testing.T) {
+	s := newAuthTestServer()
+
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"missing token", "/api/v1/views", "", 401},
+		{"wrong token", "/api/v1/views", "Bearer nope", 401},
+		{"wrong scheme", "/api/v1/views", "Basic s3cret-a", 401},
+		{"valid token", "/api/v1/views", "Bearer s3cret-a", 200},
+		{"query token rejected outside ws", "/api/v1/views?access_token=s3cret-a", "", 401},
+		{"ws missing token", "/ws/events", "", 401},
+		{"ws query token", "/ws/events?access_token=s3cret-b", "", 426}, // authenticated, but not an upgrade
+		{"ws header token", "/ws/events", "Bearer s3cret-b", 426},
+		{"health stays open", "/healthz", "", 200},
+		{"version stays open", "/version", "", 200},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			resp, err := s.app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want == 401 {
+				if resp.Header.Get("WWW-Authenticate") == "" {
+					t.Error("401 without WWW-Authenticate header")
+				}
+				var body apiError
+				if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Code != codeUnauthorized || body.Message == "" {
+					t.Errorf("401 body = %+v (%v), want code %s", body, err, codeUnauthorized)
+				}
+			}
+		})
+	}
+}
+
+func TestAuth_TokenNameIsOwner(t *testing.T) {
+	s := newAuthTestServer()
+
+	req := httptest.NewRequest("POST", "/api/v1/views",
+		strings.NewReader(`{"name":"mine","filters":{"type":"oom"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer s3cret-a")
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 201 {
+		t.Fatalf("create status = %d", resp.StatusCode)
+	}
+
+	for owner, want := range map[string]int{"alice": 1, "bob": 0} {
+		views, _ := s.views.List(t.Context(), owner)
+		if len(views) != want {
+			t.Errorf("%s has %d views, want %d", owner, len(views), want)
+		}
+	}
+}
+
+func TestAuth_DisabledWithoutTokens(t *testing.T) {
+	s := newViewsTestServer()
+	resp, _ := doJSON(t, s, "GET", "/api/v1/views", "", nil)
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d, want 200 with auth disabled", resp.StatusCode)
+	}
+}
+
+func TestParseTokens(t *testing.T) {
+	got, err := ParseTokens("alice:a1, bob:b2\n# comment\n\ncarol : c3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"alice": "a1", "bob": "b2", "carol": "c3"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+
+	for _, bad := range []string{"justatoken", "alice:", ":tok", "a:1,a:2"} {
+		if _, err := ParseTokens(bad); err == nil {
+			t.Errorf("ParseTokens(%q) succeeded, want error", bad)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"context"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// errPartial marks a payload respondCached should send but not cache.
+var errPartial = errors.New("partial response")
+
+// respondCached sends the response cached under key, or computes it. The
+// key is extended with the request's namespace scope, so a scoped token
+// is never sent a response computed for another scope. Misses
+// for the same key that arrive while a compute is running wait for it and
+// share its payload, so an expired key under load costs one ClickHouse
+// query rather than one per request. The payload is cached for
+// constants.RedisCacheTTL; a failed compute is not cached and answers a
+// logged 500.
+//
+// A compute that returns its payload with errPartial has the payload sent
+// but not cached, so a response missing parts a failing query should have
+// filled doesn't outlive the failure.
+//
+// X-Cache is HIT for a cached response, MISS for a computed one, and SHARED
+// when one compute answered several concurrent requests.
+//
+// Handlers pass c.UserContext(), not c.Context(), to the store, cache and
+// view store: the fasthttp request context is pooled, and database/sql
+// watches a query's context from its own goroutine.
+func (s *Server) respondCached(c *fiber.Ctx, key string, compute func(ctx context.Context) ([]byte, error)) error {
+	key += scopeCacheKey(c)
+	if cached, err := s.cache.Get(c.UserContext(), key); err == nil {
+		c.Set("X-Cache", "HIT")
+		return c.SendString(cached)
+	}
+
+	v, err, shared := s.flight.Do(key, func() (any, error) {
+		data, err := compute(c.UserContext())
+		if errors.Is(err, errPartial) {
+			return data, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		s.cache.Set(c.UserContext(), key, string(data), constants.RedisCacheTTL)
+		return data, nil
+	})
+	if err != nil {
+		return s.queryError(c, "Query failed", err, zap.String("key", key))
+	}
+	if shared {
+		c.Set("X-Cache", "SHARED")
+	} else {
+		c.Set("X-Cache", "MISS")
+	}
+	return c.Send(v.([]byte))
+}
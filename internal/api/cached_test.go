@@ -0,0 +1,151 @@
+package api
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// slowStore is an empty Memory store whose aggregate queries take a
+// while, counting how often each runs per request path.
+type slowStore struct {
+	*storage.Memory
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *slowStore) record(query string) {
+	time.Sleep(50 * time.Millisecond)
+	s.mu.Lock()
+	s.calls[query]++
+	s.mu.Unlock()
+}
+
+func (s *slowStore) EventTypes(ctx context.Context, namespaces []string) ([]storage.TypeCount, error) {
+	s.record("types")
+	return s.Memory.EventTypes(ctx, namespaces)
+}
+
+func (s *slowStore) Overview(ctx context.Context, w storage.Window) (storage.Overview, error) {
+	s.record("overview")
+	return s.Memory.Overview(ctx, w)
+}
+
+func (s *slowStore) SeriesByType(ctx context.Context, q storage.SeriesQuery) ([]storage.SeriesPoint, error) {
+	s.record("series:" + q.Type)
+	return s.Memory.SeriesByType(ctx, q)
+}
+
+func (s *slowStore) EventRate(ctx context.Context, q storage.RateQuery) ([]storage.RatePoint, error) {
+	s.record("rate")
+	return s.Memory.EventRate(ctx, q)
+}
+
+func (s *slowStore) TopPods(ctx context.Context, q storage.TopQuery) ([]storage.PodCount, error) {
+	s.record("top:" + q.Type)
+	return s.Memory.TopPods(ctx, q)
+}
+
+func (s *slowStore) Topology(ctx context.Context, w storage.Window, limit int) ([]storage.Edge, error) {
+	s.record("topology")
+	return s.Memory.Topology(ctx, w, limit)
+}
+
+// memCache is a Cache whose entries never expire, standing in for Redis
+// within one TTL window.
+type memCache struct {
+	cache.Disabled
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func (m *memCache) Get(_ context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if v, ok := m.data[key]; ok {
+		return v, nil
+	}
+	return "", cache.ErrDisabled
+}
+
+func (m *memCache) Set(_ context.Context, key string, value any, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[key] = value.(string)
+	return nil
+}
+
+func TestRespondCached_OneQueryPerKey(t *testing.T) {
+	store := &slowStore{Memory: storage.NewMemory(), calls: map[string]int{}}
+	s := &Server{
+		app:    fiber.New(),
+		store:  store,
+		cache:  &memCache{data: map[string]string{}},
+		views:  newMemViewStore(),
+		logger: zap.NewNop(),
+	}
+	s.registerRoutes()
+
+	paths := []string{
+		"/api/v1/metrics/overview",
+		"/api/v1/events/types",
+		"/api/v1/events/rate",
+		"/api/v1/topology",
+		"/api/v1/metrics/tcp",
+	}
+	headers := make(map[string]map[string]int, len(paths))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, path := range paths {
+		headers[path] = map[string]int{}
+		for range 20 {
+			wg.Go(func() {
+				resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if resp.StatusCode != 200 {
+					t.Errorf("GET %s = %d", path, resp.StatusCode)
+				}
+				mu.Lock()
+				headers[path][resp.Header.Get("X-Cache")]++
+				mu.Unlock()
+			})
+		}
+	}
+	wg.Wait()
+
+	// Requests after the flight are served from the cache.
+	for _, path := range paths {
+		resp, err := s.app.Test(httptest.NewRequest("GET", path, nil), -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get("X-Cache"); got != "HIT" {
+			t.Errorf("GET %s after the flight: X-Cache = %q, want HIT", path, got)
+		}
+	}
+
+	if len(store.calls) == 0 {
+		t.Fatal("no queries ran")
+	}
+	for query, n := range store.calls {
+		if n != 1 {
+			t.Errorf("query ran %d times, want once:\n%s", n, query)
+		}
+	}
+	for path, h := range headers {
+		if h["SHARED"] == 0 {
+			t.Errorf("GET %s: X-Cache counts %v, want concurrent requests to share a query", path, h)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventCursor marks the last row of a page: the next page starts strictly
+// after (Timestamp, RowKey) in descending order. RowKey is the store's
+// storage.Event.RowKey.
+type eventCursor struct {
+	Timestamp time.Time
+	RowKey    uint64
+}
+
+// encode returns the opaque cursor string: base64url("<unix_ms>:<row_key>").
+func (c eventCursor) encode() string {
+	raw := strconv.FormatInt(c.Timestamp.UnixMilli(), 10) + ":" + strconv.FormatUint(c.RowKey, 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor parses a cursor produced by encode.
+func decodeCursor(s string) (eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return eventCursor{}, fmt.Errorf("malformed cursor")
+	}
+	ms, key, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return eventCursor{}, fmt.Errorf("malformed cursor")
+	}
+	t, err := strconv.ParseInt(ms, 10, 64)
+	if err != nil {
+		return eventCursor{}, fmt.Errorf("malformed cursor")
+	}
+	k, err := strconv.ParseUint(key, 10, 64)
+	if err != nil {
+		return eventCursor{}, fmt.Errorf("malformed cursor")
+	}
+	return eventCursor{Timestamp: time.UnixMilli(t).UTC(), RowKey: k}, nil
+}
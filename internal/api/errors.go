@@ -0,0 +1,143 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// Error codes: the code field of an error response. Statuses without a
+// code of their own (e.g. 404 for an unknown route) get one derived from
+// the status text, see statusCode.
+const (
+	codeInvalidParam = "invalid_param" // a query parameter or body field; details name it
+	codeInvalidBody  = "invalid_body"  // the request body isn't valid JSON
+	codeUnauthorized = "unauthorized"
+	codeForbidden    = "forbidden" // a namespace outside the token's scope
+	codeNotFound     = "not_found"
+	codeConflict     = "conflict"
+	codeRateLimited  = "rate_limited"
+	codeUnavailable  = "unavailable" // a dependency this endpoint needs is missing
+	codeTimeout      = "timeout"     // a storage query ran past its timeout; details carry the request ID
+	codeInternal     = "internal"    // details carry the request ID, which is logged
+)
+
+// localsRequestID is the fiber.Ctx Locals key holding the request ID,
+// which is also sent back in the X-Request-ID header.
+const localsRequestID = "request_id"
+
+// apiError is the body of every error response.
+type apiError struct {
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
+}
+
+// paramError is a client error tied to one query parameter or body field.
+type paramError struct {
+	Param  string
+	Reason string
+}
+
+func (e *paramError) Error() string {
+	return e.Param + ": " + e.Reason
+}
+
+// requestIDMiddleware assigns every request an ID, or keeps the caller's
+// X-Request-ID.
+func requestIDMiddleware() fiber.Handler {
+	return requestid.New(requestid.Config{ContextKey: localsRequestID})
+}
+
+// requestID returns the ID requestIDMiddleware assigned to c. It is a
+// copy: a caller-supplied ID points into a buffer fasthttp reuses once
+// the request is done.
+func requestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(localsRequestID).(string)
+	return strings.Clone(id)
+}
+
+// respondError writes an error response.
+func respondError(c *fiber.Ctx, status int, code, msg string, details map[string]any) error {
+	return c.Status(status).JSON(apiError{Code: code, Message: msg, Details: details})
+}
+
+// badRequest writes a 400. Parameter errors name the offending parameter
+// and why it was rejected in details.
+func badRequest(c *fiber.Ctx, err error) error {
+	var pe *paramError
+	if errors.As(err, &pe) {
+		return respondError(c, fiber.StatusBadRequest, codeInvalidParam, pe.Error(),
+			map[string]any{"param": pe.Param, "reason": pe.Reason})
+	}
+	return respondError(c, fiber.StatusBadRequest, codeInvalidBody, err.Error(), nil)
+}
+
+func unauthorized(c *fiber.Ctx, msg string) error {
+	c.Set(fiber.HeaderWWWAuthenticate, `Bearer realm="kubepulse"`)
+	return respondError(c, fiber.StatusUnauthorized, codeUnauthorized, msg, nil)
+}
+
+func forbidden(c *fiber.Ctx, msg string) error {
+	return respondError(c, fiber.StatusForbidden, codeForbidden, msg, nil)
+}
+
+func notFound(c *fiber.Ctx, msg string) error {
+	return respondError(c, fiber.StatusNotFound, codeNotFound, msg, nil)
+}
+
+func conflict(c *fiber.Ctx, msg string) error {
+	return respondError(c, fiber.StatusConflict, codeConflict, msg, nil)
+}
+
+func unavailable(c *fiber.Ctx, msg string) error {
+	return respondError(c, fiber.StatusServiceUnavailable, codeUnavailable, msg, nil)
+}
+
+// internalError logs err under msg with the request ID and writes a 500
+// carrying the same ID, so a report can be matched to the log line. err
+// itself is never sent to the client.
+func (s *Server) internalError(c *fiber.Ctx, msg string, err error, fields ...zap.Field) error {
+	s.requestLogger(c).Error(msg, append(fields, zap.Error(err))...)
+	return respondError(c, fiber.StatusInternalServerError, codeInternal, "internal error",
+		map[string]any{"request_id": requestID(c)})
+}
+
+// queryError responds to a failed store query: a 504 when it ran past
+// the storage query timeout, otherwise a logged 500. Both carry the
+// request ID.
+func (s *Server) queryError(c *fiber.Ctx, msg string, err error, fields ...zap.Field) error {
+	if !storage.IsTimeout(err) {
+		return s.internalError(c, msg, err, fields...)
+	}
+	s.requestLogger(c).Warn(msg, append(fields, zap.Error(err))...)
+	return respondError(c, fiber.StatusGatewayTimeout, codeTimeout, "query timed out",
+		map[string]any{"request_id": requestID(c)})
+}
+
+// handleError is the app's fiber.ErrorHandler: errors a handler or
+// middleware returned instead of responding, including recovered panics.
+// fiber.Errors keep their status; anything else is a 500.
+func (s *Server) handleError(c *fiber.Ctx, err error) error {
+	var fe *fiber.Error
+	if errors.As(err, &fe) && fe.Code < fiber.StatusInternalServerError {
+		return respondError(c, fe.Code, statusCode(fe.Code), fe.Message, nil)
+	}
+	return s.internalError(c, "Request failed", err, zap.String("path", strings.Clone(c.Path())))
+}
+
+// rateLimited is the limiter's LimitReached handler.
+func rateLimited(c *fiber.Ctx) error {
+	return respondError(c, fiber.StatusTooManyRequests, codeRateLimited, "rate limit exceeded", nil)
+}
+
+// statusCode derives an error code from status: "Not Found" → not_found.
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
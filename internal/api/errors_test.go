@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// errDetails returns the details of an error response body.
+func errDetails(body map[string]any) map[string]any {
+	details, _ := body["details"].(map[string]any)
+	return details
+}
+
+// errParam returns the parameter an error response body names.
+func errParam(body map[string]any) any {
+	return errDetails(body)["param"]
+}
+
+// failingStore is a storage.Store whose queries all fail.
+type failingStore struct{}
+
+var errStoreDown = errors.New("clickhouse: connection refused")
+
+func (failingStore) InsertBatch(context.Context, []storage.EventRow) error { return errStoreDown }
+func (failingStore) ListEvents(context.Context, storage.EventQuery) (storage.EventRows, error) {
+	return nil, errStoreDown
+}
+func (failingStore) EventKeys(context.Context, storage.EventQuery) ([]string, []string, error) {
+	return nil, nil, errStoreDown
+}
+func (failingStore) TypeStats(context.Context, storage.EventQuery) ([]storage.TypeStat, error) {
+	return nil, errStoreDown
+}
+func (failingStore) LabelValues(context.Context, storage.EventQuery, string, int) ([]storage.LabelCount, error) {
+	return nil, errStoreDown
+}
+func (failingStore) EventTypes(context.Context, []string) ([]storage.TypeCount, error) {
+	return nil, errStoreDown
+}
+func (failingStore) Overview(context.Context, storage.Window) (storage.Overview, error) {
+	return storage.Overview{}, errStoreDown
+}
+func (failingStore) SeriesByType(context.Context, storage.SeriesQuery) ([]storage.SeriesPoint, error) {
+	return nil, errStoreDown
+}
+func (failingStore) EventRate(context.Context, storage.RateQuery) ([]storage.RatePoint, error) {
+	return nil, errStoreDown
+}
+func (failingStore) TopPods(context.Context, storage.TopQuery) ([]storage.PodCount, error) {
+	return nil, errStoreDown
+}
+func (failingStore) Topology(context.Context, storage.Window, int) ([]storage.Edge, error) {
+	return nil, errStoreDown
+}
+func (failingStore) Retention() time.Duration   { return 0 }
+func (failingStore) Ping(context.Context) error { return errStoreDown }
+func (failingStore) Close() error               { return nil }
+
+func TestBadRequest_Matrix(t *testing.T) {
+	s := newViewsTestServer()
+	param := func(name, reason string) map[string]any {
+		return map[string]any{
+			"code":    codeInvalidParam,
+			"message": name + ": " + reason,
+			"details": map[string]any{"param": name, "reason": reason},
+		}
+	}
+	tests := []struct {
+		method, path string
+		body         any
+		want         map[string]any
+	}{
+		{"GET", "/api/v1/events?limit=ten", nil, param("limit", "must be an integer")},
+		{"GET", "/api/v1/events?limit=1001", nil, param("limit", "must be in [0, 1000]")},
+		{"GET", "/api/v1/events?limit=-1", nil, param("limit", "must be in [0, 1000]")},
+		{"GET", "/api/v1/events?offset=-5", nil, param("offset", "must be >= 0")},
+		{"GET", "/api/v1/events?offset=2.5", nil, param("offset", "must be an integer")},
+		{"GET", "/api/v1/events?since=yesterday", nil, param("since", "must be an RFC3339 timestamp")},
+		{"GET", "/api/v1/events?until=2026-13-01T00:00:00Z", nil, param("until", "must be an RFC3339 timestamp")},
+		{"GET", "/api/v1/events?pid=-1", nil, param("pid", "must be an unsigned 32-bit integer")},
+		{"GET", "/api/v1/events?format=xml", nil, param("format", "must be json, csv or ndjson")},
+		{"GET", "/api/v1/events?cursor=abc&offset=10", nil, param("cursor", "cannot be combined with offset")},
+		{"GET", "/api/v1/metrics/bogus", nil, param("type", `unknown event type "bogus"`)},
+		{"GET", "/api/v1/metrics/tcp?groups=5", nil, param("groups", "requires group_by")},
+		{"GET", "/api/v1/metrics/tcp?group_by=pod&groups=x", nil, param("groups", "must be an integer in [1, 50]")},
+		{"GET", "/api/v1/metrics/tcp?until=2026-01-01T00:00:00Z", nil, param("since", "is required with until")},
+		{"GET", "/api/v1/top?type=oom&k=ten", nil, param("k", "must be an integer in [1, 100]")},
+		{"GET", "/api/v1/topology?window=0s", nil, param("window", "range must be positive")},
+		{"POST", "/api/v1/views", map[string]any{"filters": map[string]any{}},
+			param("name", "is required and must be at most 128 characters")},
+		{"POST", "/api/v1/views", map[string]any{"name": "x", "filters": map[string]any{"offset": -1}},
+			param("filters.offset", "must be >= 0")},
+		{"GET", "/api/v1/views/nope/events", nil, map[string]any{"code": codeNotFound, "message": "view not found"}},
+	}
+	for _, tt := range tests {
+		resp, body := doJSON(t, s, tt.method, tt.path, "", tt.body)
+		want := 400
+		if tt.want["code"] == codeNotFound {
+			want = 404
+		}
+		if resp.StatusCode != want || !reflect.DeepEqual(body, tt.want) {
+			t.Errorf("%s %s = %d %v, want %d %v", tt.method, tt.path, resp.StatusCode, body, want, tt.want)
+		}
+	}
+}
+
+func TestBadRequest_ViewOverrides(t *testing.T) {
+	s := newViewsTestServer()
+	_, created := doJSON(t, s, "POST", "/api/v1/views", "alice", map[string]any{"name": "all"})
+	resp, body := doJSON(t, s, "GET", "/api/v1/views/"+created["id"].(string)+"/events?limit=many", "alice", nil)
+	if resp.StatusCode != 400 || errParam(body) != "limit" {
+		t.Errorf("status=%d body=%v, want 400 on limit", resp.StatusCode, body)
+	}
+}
+
+func TestInternalError_CarriesLoggedRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.ErrorLevel)
+	s := &Server{store: failingStore{}, views: newMemViewStore(), logger: zap.New(core)}
+	s.app = fiber.New(fiber.Config{ErrorHandler: s.handleError})
+	s.app.Use(requestIDMiddleware())
+	s.registerRoutes()
+
+	resp, body := doJSON(t, s, "GET", "/api/v1/events", "", nil)
+	id := resp.Header.Get(fiber.HeaderXRequestID)
+	if resp.StatusCode != 500 || id == "" {
+		t.Fatalf("status=%d X-Request-ID=%q, want 500 with an ID", resp.StatusCode, id)
+	}
+	want := map[string]any{"code": codeInternal, "message": "internal error", "details": map[string]any{"request_id": id}}
+	if !reflect.DeepEqual(body, want) {
+		t.Errorf("body = %v, want %v", body, want)
+	}
+	entries := logs.FilterField(zap.String("request_id", id)).All()
+	if len(entries) != 1 || entries[0].Message != "Events query failed" {
+		t.Errorf("logged %v, want one error with request_id %s", logs.All(), id)
+	}
+
+	// A caller-supplied ID is kept.
+	req := httptest.NewRequest("GET", "/api/v1/events", nil)
+	req.Header.Set(fiber.HeaderXRequestID, "req-42")
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get(fiber.HeaderXRequestID); got != "req-42" {
+		t.Errorf("X-Request-ID = %q, want req-42", got)
+	}
+}
+
+// timeoutStore is a storage.Store whose queries all run past the query
+// timeout.
+type timeoutStore struct{ failingStore }
+
+func (timeoutStore) ListEvents(context.Context, storage.EventQuery) (storage.EventRows, error) {
+	return nil, fmt.Errorf("query: %w", context.DeadlineExceeded)
+}
+func (timeoutStore) EventTypes(context.Context, []string) ([]storage.TypeCount, error) {
+	return nil, context.DeadlineExceeded
+}
+
+func TestQueryError_TimeoutIs504(t *testing.T) {
+	s := &Server{store: timeoutStore{}, cache: cache.Disabled{}, views: newMemViewStore(), logger: zap.NewNop()}
+	s.app = fiber.New(fiber.Config{ErrorHandler: s.handleError})
+	s.app.Use(requestIDMiddleware())
+	s.registerRoutes()
+
+	for _, path := range []string{"/api/v1/events", "/api/v1/events/types", "/api/v1/events?format=ndjson"} {
+		resp, body := doJSON(t, s, "GET", path, "", nil)
+		id := resp.Header.Get(fiber.HeaderXRequestID)
+		if resp.StatusCode != 504 || body["code"] != codeTimeout || errDetails(body)["request_id"] != id {
+			t.Errorf("GET %s = %d %v, want 504 timeout with request ID %s", path, resp.StatusCode, body, id)
+		}
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	s := &Server{views: newMemViewStore(), logger: zap.NewNop()}
+	s.app = fiber.New(fiber.Config{ErrorHandler: s.handleError})
+	s.registerRoutes()
+
+	resp, body := doJSON(t, s, "GET", "/api/v1/nowhere", "", nil)
+	if resp.StatusCode != 404 || body["code"] != codeNotFound {
+		t.Errorf("unknown route = %d %v, want 404 not_found", resp.StatusCode, body)
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	for status, want := range map[int]string{
+		404: "not_found",
+		405: "method_not_allowed",
+		426: "upgrade_required",
+	} {
+		if got := statusCode(status); got != want {
+			t.Errorf("statusCode(%d) = %q, want %q", status, got, want)
+		}
+	}
+}
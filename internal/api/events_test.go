@@ -0,0 +1,96 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// stubStore is an empty Memory store whose ListEvents returns canned
+// rows, recording the query.
+type stubStore struct {
+	*storage.Memory
+	rows    *fakeRows
+	queries []storage.EventQuery
+}
+
+func (s *stubStore) ListEvents(_ context.Context, q storage.EventQuery) (storage.EventRows, error) {
+	s.queries = append(s.queries, q)
+	return s.rows, nil
+}
+
+func newEventsTestServer(rows *fakeRows) *Server {
+	s := &Server{
+		app:    fiber.New(),
+		store:  &stubStore{Memory: storage.NewMemory(), rows: rows},
+		views:  newMemViewStore(),
+		logger: zap.NewNop(),
+	}
+	s.registerRoutes()
+	return s
+}
+
+func TestEvents_EmptyResultIsArray(t *testing.T) {
+	s := newEventsTestServer(&fakeRows{})
+
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/api/v1/events", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d: %s", resp.StatusCode, raw)
+	}
+	if !strings.Contains(string(raw), `"events":[]`) {
+		t.Errorf("body = %s, want \"events\":[]", raw)
+	}
+	if !strings.Contains(string(raw), `"partial":false`) || !strings.Contains(string(raw), `"next_cursor":null`) {
+		t.Errorf("body = %s", raw)
+	}
+}
+
+func TestEvents_ScanErrorsMarkPartial(t *testing.T) {
+	rows := seedExportRows(5)
+	rows.bad = map[int]bool{1: true, 3: true}
+	s := newEventsTestServer(rows)
+
+	resp, body := doJSON(t, s, "GET", "/api/v1/events?limit=10", "", nil)
+	if resp.StatusCode != 200 {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if events := body["events"].([]any); len(events) != 3 {
+		t.Errorf("got %d events, want the 3 that scanned", len(events))
+	}
+	if body["partial"] != true {
+		t.Errorf("partial = %v, want true", body["partial"])
+	}
+	if !rows.closed {
+		t.Error("rows not closed")
+	}
+}
+
+func TestEvents_FullPageWithScanErrorStillPaginates(t *testing.T) {
+	rows := seedExportRows(3)
+	rows.bad = map[int]bool{2: true}
+	s := newEventsTestServer(rows)
+
+	_, body := doJSON(t, s, "GET", "/api/v1/events?limit=3", "", nil)
+	cur, ok := body["next_cursor"].(string)
+	if !ok {
+		t.Fatalf("next_cursor = %v, want a cursor for the full page", body["next_cursor"])
+	}
+	decoded, err := decodeCursor(cur)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := rows.events[1].Timestamp; !decoded.Timestamp.Equal(want) {
+		t.Errorf("cursor at %s, want last scanned row %s", decoded.Timestamp, want)
+	}
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// Export formats for /events?format=.
+const (
+	exportCSV    = "csv"
+	exportNDJSON = "ndjson"
+)
+
+var exportContentTypes = map[string]string{
+	exportCSV:    "text/csv; charset=utf-8",
+	exportNDJSON: "application/x-ndjson",
+}
+
+// exportColumns are the map keys flattened into CSV columns.
+type exportColumns struct {
+	Labels   []string
+	Numerics []string
+}
+
+// exportEvents streams every event matching a validated f as CSV or
+// NDJSON, up to constants.APIExportMaxRows. Limit and offset are ignored.
+func (s *Server) exportEvents(c *fiber.Ctx, f eventFilter, format string) error {
+	since, until := f.timeRange(time.Now())
+	q := f.query(since, until)
+	// One row past the cap tells a truncated result from one that fits.
+	q.Limit, q.Offset = constants.APIExportMaxRows+1, 0
+
+	var cols exportColumns
+	if format == exportCSV {
+		var err error
+		if cols.Labels, cols.Numerics, err = s.store.EventKeys(c.UserContext(), q); err != nil {
+			return s.queryError(c, "Export key query failed", err)
+		}
+	}
+
+	rows, err := s.store.ListEvents(c.UserContext(), q)
+	if err != nil {
+		return s.queryError(c, "Export query failed", err)
+	}
+	s.streamExport(c, rows, format, cols, constants.APIExportMaxRows)
+	return nil
+}
+
+// streamExport writes rows to the response as they are read, flushing
+// every constants.APIExportFlushRows rows, so the body goes out chunked
+// and is never buffered whole. Past maxRows the export ends with a
+// truncation marker: a "# truncated ..." line in CSV, or a
+// {"truncated":true,...} object in NDJSON. rows is closed when done.
+func (s *Server) streamExport(c *fiber.Ctx, rows storage.EventRows, format string, cols exportColumns, maxRows int) {
+	filename := fmt.Sprintf("kubepulse-events-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	c.Set(fiber.HeaderContentType, exportContentTypes[format])
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	c.Set("X-Row-Cap", strconv.Itoa(maxRows))
+
+	logger := s.requestLogger(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer rows.Close()
+
+		var ew exportWriter
+		if format == exportCSV {
+			ew = newCSVExportWriter(w, cols)
+		} else {
+			ew = &ndjsonExportWriter{enc: json.NewEncoder(w)}
+		}
+		if err := ew.header(); err != nil {
+			return
+		}
+
+		n := 0
+		for rows.Next() {
+			if n == maxRows {
+				ew.truncated(maxRows)
+				break
+			}
+			e, err := rows.Event()
+			if err != nil {
+				continue
+			}
+			if err := ew.event(newEventRecord(e)); err != nil {
+				return
+			}
+			n++
+			if n%constants.APIExportFlushRows == 0 {
+				if ew.flush() != nil || w.Flush() != nil {
+					return // client went away
+				}
+			}
+		}
+		if err := rows.Err(); err != nil {
+			logger.Error("Export stream failed", zap.Int("rows", n), zap.Error(err))
+			ew.failed()
+		}
+		ew.flush()
+		w.Flush()
+	})
+}
+
+// exportWriter encodes one export format.
+type exportWriter interface {
+	header() error
+	event(e eventRecord) error
+	truncated(rowCap int)
+	failed()
+	flush() error
+}
+
+// csvExportWriter writes fixed columns, then one label.<key> and one
+// numeric.<key> column per key. Missing keys are empty cells.
+type csvExportWriter struct {
+	w    *bufio.Writer
+	csv  *csv.Writer
+	cols exportColumns
+	rec  []string
+}
+
+func newCSVExportWriter(w *bufio.Writer, cols exportColumns) *csvExportWriter {
+	return &csvExportWriter{w: w, csv: csv.NewWriter(w), cols: cols}
+}
+
+func (cw *csvExportWriter) header() error {
+	hdr := []string{"timestamp", "type", "pid", "comm", "node", "namespace", "pod"}
+	for _, k := range cw.cols.Labels {
+		hdr = append(hdr, labelParamPrefix+k)
+	}
+	for _, k := range cw.cols.Numerics {
+		hdr = append(hdr, "numeric."+k)
+	}
+	return cw.csv.Write(hdr)
+}
+
+func (cw *csvExportWriter) event(e eventRecord) error {
+	cw.rec = append(cw.rec[:0],
+		e.Timestamp.UTC().Format(time.RFC3339Nano),
+		e.Type,
+		strconv.FormatUint(uint64(e.PID), 10),
+		e.Comm, e.Node, e.Namespace, e.Pod,
+	)
+	for _, k := range cw.cols.Labels {
+		cw.rec = append(cw.rec, e.Labels[k])
+	}
+	for _, k := range cw.cols.Numerics {
+		v := ""
+		if n, ok := e.Numerics[k]; ok {
+			v = strconv.FormatFloat(n, 'g', -1, 64)
+		}
+		cw.rec = append(cw.rec, v)
+	}
+	return cw.csv.Write(cw.rec)
+}
+
+func (cw *csvExportWriter) truncated(rowCap int) {
+	cw.csv.Flush()
+	fmt.Fprintf(cw.w, "# truncated: row cap %d reached\n", rowCap)
+}
+
+func (cw *csvExportWriter) failed() {
+	cw.csv.Flush()
+	fmt.Fprintln(cw.w, "# error: export incomplete")
+}
+
+func (cw *csvExportWriter) flush() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+// ndjsonExportWriter writes one eventRecord per line.
+type ndjsonExportWriter struct {
+	enc *json.Encoder
+}
+
+func (nw *ndjsonExportWriter) header() error             { return nil }
+func (nw *ndjsonExportWriter) event(e eventRecord) error { return nw.enc.Encode(e) }
+func (nw *ndjsonExportWriter) flush() error              { return nil }
+
+func (nw *ndjsonExportWriter) truncated(rowCap int) {
+	nw.enc.Encode(fiber.Map{"truncated": true, "row_cap": rowCap})
+}
+
+func (nw *ndjsonExportWriter) failed() {
+	nw.enc.Encode(fiber.Map{"error": "export incomplete"})
+}
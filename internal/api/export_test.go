@@ -0,0 +1,193 @@
+package api
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// fakeRows is a storage.EventRows over canned events. Event fails for
+// row indexes in bad.
+type fakeRows struct {
+	events []storage.Event
+	bad    map[int]bool
+	i      int
+	closed bool
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.events)
+}
+
+func (r *fakeRows) Event() (storage.Event, error) {
+	if r.bad[r.i-1] {
+		return storage.Event{}, errors.New("converting UInt64 to *uint32 is unsupported")
+	}
+	return r.events[r.i-1], nil
+}
+
+func (r *fakeRows) Err() error   { return nil }
+func (r *fakeRows) Close() error { r.closed = true; return nil }
+
+func seedExportRows(n int) *fakeRows {
+	rows := &fakeRows{}
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := range n {
+		rows.events = append(rows.events, storage.Event{
+			Timestamp: base.Add(-time.Duration(i) * time.Second),
+			Type:      constants.ModuleTCP,
+			PID:       uint32(100 + i),
+			Comm:      "curl",
+			Namespace: "prod",
+			Pod:       "web-0",
+			Labels:    map[string]string{"dst": fmt.Sprintf("10.0.0.%d:443", i)},
+			Numerics:  map[string]float64{"latency_sec": 0.25},
+		})
+	}
+	return rows
+}
+
+// exportThrough serves one streamExport call and returns the response.
+func exportThrough(t *testing.T, rows *fakeRows, format string, cols exportColumns, maxRows int) (*http.Response, string) {
+	t.Helper()
+	s := &Server{app: fiber.New(), logger: zap.NewNop()}
+	s.app.Get("/export", func(c *fiber.Ctx) error {
+		s.streamExport(c, rows, format, cols, maxRows)
+		return nil
+	})
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/export", nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestExport_CSVStreamsChunked(t *testing.T) {
+	rows := seedExportRows(constants.APIExportFlushRows*2 + 5)
+	cols := exportColumns{Labels: []string{"dst", "src"}, Numerics: []string{"latency_sec"}}
+	resp, body := exportThrough(t, rows, exportCSV, cols, constants.APIExportMaxRows)
+
+	if te := resp.TransferEncoding; len(te) == 0 || te[0] != "chunked" {
+		t.Fatalf("Transfer-Encoding = %v, want chunked", te)
+	}
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if cd := resp.Header.Get("Content-Disposition"); !strings.Contains(cd, "attachment") || !strings.Contains(cd, ".csv") {
+		t.Errorf("Content-Disposition = %q", cd)
+	}
+	if !rows.closed {
+		t.Error("rows not closed")
+	}
+
+	records, err := csv.NewReader(strings.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantHdr := "timestamp,type,pid,comm,node,namespace,pod,label.dst,label.src,numeric.latency_sec"
+	if got := strings.Join(records[0], ","); got != wantHdr {
+		t.Fatalf("header = %s, want %s", got, wantHdr)
+	}
+	if len(records) != len(rows.events)+1 {
+		t.Fatalf("%d data rows, want %d", len(records)-1, len(rows.events))
+	}
+	first := records[1]
+	if first[0] != "2026-01-02T03:04:05Z" || first[2] != "100" || first[7] != "10.0.0.0:443" || first[8] != "" || first[9] != "0.25" {
+		t.Errorf("first row = %v", first)
+	}
+}
+
+func TestExport_NDJSONTruncates(t *testing.T) {
+	rows := seedExportRows(10)
+	resp, body := exportThrough(t, rows, exportNDJSON, exportColumns{}, 4)
+
+	if te := resp.TransferEncoding; len(te) == 0 || te[0] != "chunked" {
+		t.Fatalf("Transfer-Encoding = %v, want chunked", te)
+	}
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	sc := bufio.NewScanner(strings.NewReader(body))
+	var lines []map[string]any
+	for sc.Scan() {
+		var m map[string]any
+		if err := json.Unmarshal(sc.Bytes(), &m); err != nil {
+			t.Fatalf("bad line %q: %v", sc.Text(), err)
+		}
+		lines = append(lines, m)
+	}
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want 4 events + marker", len(lines))
+	}
+	if lines[0]["pod"] != "web-0" || lines[0]["labels"].(map[string]any)["dst"] != "10.0.0.0:443" {
+		t.Errorf("first event = %v", lines[0])
+	}
+	if last := lines[4]; last["truncated"] != true || last["row_cap"] != float64(4) {
+		t.Errorf("marker = %v", last)
+	}
+}
+
+func TestExport_CSVTruncationMarker(t *testing.T) {
+	_, body := exportThrough(t, seedExportRows(3), exportCSV, exportColumns{}, 2)
+	lines := strings.Split(strings.TrimSpace(body), "\n")
+	if len(lines) != 4 || lines[3] != "# truncated: row cap 2 reached" {
+		t.Fatalf("body = %q", body)
+	}
+
+	// Exactly at the cap is not truncated.
+	_, body = exportThrough(t, seedExportRows(2), exportCSV, exportColumns{}, 2)
+	if strings.Contains(body, "truncated") {
+		t.Fatalf("body = %q, want no marker", body)
+	}
+}
+
+func TestExport_RejectsUnknownFormat(t *testing.T) {
+	s := newViewsTestServer()
+	resp, body := doJSON(t, s, "GET", "/api/v1/events?format=xml", "", nil)
+	if resp.StatusCode != 400 || errParam(body) != "format" {
+		t.Fatalf("status=%d body=%v, want 400 on format", resp.StatusCode, body)
+	}
+}
+
+func TestExportEvents_QueriesPastTheCap(t *testing.T) {
+	rows := seedExportRows(1)
+	s := newEventsTestServer(rows)
+	cur := eventCursor{Timestamp: time.Now().UTC().Truncate(time.Millisecond), RowKey: 7}.encode()
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/api/v1/events?format=csv&type=tcp&limit=10&cursor="+cur, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.ReadAll(resp.Body)
+
+	queries := s.store.(*stubStore).queries
+	if len(queries) != 1 {
+		t.Fatalf("%d queries, want 1", len(queries))
+	}
+	q := queries[0]
+	if q.Type != constants.ModuleTCP || q.Limit != constants.APIExportMaxRows+1 || q.Offset != 0 {
+		t.Errorf("export query = %+v, want type tcp, limit cap+1, no offset", q)
+	}
+	if q.After == nil || q.After.RowKey != 7 {
+		t.Errorf("export query cursor = %+v, want the request's", q.After)
+	}
+}
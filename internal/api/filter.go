@@ -0,0 +1,386 @@
+package api
+
+import (
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// knownEventType reports whether typ is an event_type the agent can emit.
+func knownEventType(typ string) bool {
+	_, ok := event.SchemaOf(typ)
+	return ok
+}
+
+// knownEnrichments is the set of enrichment label values.
+var knownEnrichments = map[string]bool{
+	constants.EnrichmentOK:    true,
+	constants.EnrichmentMiss:  true,
+	constants.EnrichmentHost:  true,
+	constants.EnrichmentError: true,
+}
+
+// eventFilter holds the /events query parameters.
+// It is also the persisted form of a saved view's filters.
+type eventFilter struct {
+	Type       string            `json:"type,omitempty"`
+	Namespace  string            `json:"namespace,omitempty"`
+	Pod        string            `json:"pod,omitempty"`
+	Node       string            `json:"node,omitempty"`
+	Comm       string            `json:"comm,omitempty"`
+	PID        string            `json:"pid,omitempty"`         // uint32
+	MinLatency string            `json:"min_latency,omitempty"` // seconds
+	Enrichment string            `json:"enrichment,omitempty"`  // ok, miss, host or error
+	Labels     map[string]string `json:"labels,omitempty"`      // label.<key>=<value>, exact match
+	Since      string            `json:"since,omitempty"`       // RFC3339
+	Until      string            `json:"until,omitempty"`       // RFC3339
+	Range      string            `json:"range,omitempty"`       // relative, e.g. "1h"; excludes since/until
+	Limit      int               `json:"limit,omitempty"`
+	Offset     int               `json:"offset,omitempty"` // deprecated: use Cursor
+	Cursor     string            `json:"-"`                // per-request, never stored
+
+	scope []string // the token's namespaces, see scopeNamespaces; per-request
+}
+
+// labelParamPrefix marks label-equality query parameters.
+const labelParamPrefix = "label."
+
+// queryLabels collects label.<key>=<value> parameters from the request.
+func queryLabels(c *fiber.Ctx) map[string]string {
+	var labels map[string]string
+	c.Context().QueryArgs().VisitAll(func(k, v []byte) {
+		key, ok := strings.CutPrefix(string(k), labelParamPrefix)
+		if !ok {
+			return
+		}
+		if labels == nil {
+			labels = make(map[string]string)
+		}
+		labels[key] = string(v)
+	})
+	return labels
+}
+
+// queryInt reads an optional integer query parameter, def if absent. A
+// value that isn't an integer is a paramError rather than def.
+func queryInt(c *fiber.Ctx, param string, def int) (int, error) {
+	v := c.Query(param)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, &paramError{param, "must be an integer"}
+	}
+	return n, nil
+}
+
+// queryIntIn is queryInt limited to [lo, hi].
+func queryIntIn(c *fiber.Ctx, param string, def, lo, hi int) (int, error) {
+	n, err := queryInt(c, param, def)
+	if err != nil || n < lo || n > hi {
+		return 0, &paramError{param, fmt.Sprintf("must be an integer in [%d, %d]", lo, hi)}
+	}
+	return n, nil
+}
+
+// queryWindow reads the relative window parameter (default 1h), returning
+// it as given and as a duration.
+func queryWindow(c *fiber.Ctx) (string, time.Duration, error) {
+	window := c.Query("window", "1h")
+	span, err := parseRange(window)
+	if err != nil {
+		return "", 0, &paramError{"window", err.Error()}
+	}
+	return window, span, nil
+}
+
+// parseEventFilter reads the /events query parameters from the request.
+// Only the integer parameters are checked here; validate checks the rest.
+func parseEventFilter(c *fiber.Ctx) (eventFilter, error) {
+	limit, err := queryInt(c, "limit", constants.APIDefaultPageSize)
+	if err != nil {
+		return eventFilter{}, err
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil {
+		return eventFilter{}, err
+	}
+	return eventFilter{
+		Type:       c.Query("type"),
+		Namespace:  c.Query("namespace"),
+		Pod:        c.Query("pod"),
+		Node:       c.Query("node"),
+		Comm:       c.Query("comm"),
+		PID:        c.Query("pid"),
+		MinLatency: c.Query("min_latency"),
+		Enrichment: c.Query("enrichment"),
+		Labels:     queryLabels(c),
+		Since:      c.Query("since"),
+		Until:      c.Query("until"),
+		Range:      c.Query("range"),
+		Limit:      limit,
+		Offset:     offset,
+		Cursor:     c.Query("cursor"),
+	}, nil
+}
+
+// withOverrides returns a copy of f with any filter parameters present
+// on the request replacing the stored values. An overriding range
+// replaces a stored since/until and vice versa. Like parseEventFilter, it
+// only checks the integer parameters.
+func (f eventFilter) withOverrides(c *fiber.Ctx) (eventFilter, error) {
+	if v := c.Query("type"); v != "" {
+		f.Type = v
+	}
+	if v := c.Query("namespace"); v != "" {
+		f.Namespace = v
+	}
+	for param, dst := range map[string]*string{
+		"pod": &f.Pod, "node": &f.Node, "comm": &f.Comm, "pid": &f.PID, "min_latency": &f.MinLatency,
+		"enrichment": &f.Enrichment,
+	} {
+		if v := c.Query(param); v != "" {
+			*dst = v
+		}
+	}
+	if extra := queryLabels(c); len(extra) > 0 {
+		merged := make(map[string]string, len(f.Labels)+len(extra))
+		maps.Copy(merged, f.Labels)
+		maps.Copy(merged, extra)
+		f.Labels = merged
+	}
+	if v := c.Query("range"); v != "" {
+		f.Range, f.Since, f.Until = v, "", ""
+	}
+	if v := c.Query("since"); v != "" {
+		f.Since, f.Range = v, ""
+	}
+	if v := c.Query("until"); v != "" {
+		f.Until, f.Range = v, ""
+	}
+	for param, dst := range map[string]*int{"limit": &f.Limit, "offset": &f.Offset} {
+		v, err := queryInt(c, param, 0)
+		if err != nil {
+			return f, err
+		}
+		if v != 0 {
+			*dst = v
+		}
+	}
+	if v := c.Query("cursor"); v != "" {
+		f.Cursor, f.Offset = v, 0
+	}
+	return f, nil
+}
+
+// validate checks the filter against the /events parameter rules. With
+// a type, label filters must name labels that type carries (see
+// event.SchemaOf), since any other would match nothing.
+func (f eventFilter) validate() error {
+	schema, ok := event.SchemaOf(f.Type)
+	if f.Type != "" && !ok {
+		return &paramError{"type", fmt.Sprintf("unknown event type %q", f.Type)}
+	}
+	if f.PID != "" {
+		if _, err := strconv.ParseUint(f.PID, 10, 32); err != nil {
+			return &paramError{"pid", "must be an unsigned 32-bit integer"}
+		}
+	}
+	if f.MinLatency != "" {
+		if v, err := strconv.ParseFloat(f.MinLatency, 64); err != nil || v < 0 || math.IsNaN(v) || math.IsInf(v, 0) {
+			return &paramError{"min_latency", "must be a non-negative number of seconds"}
+		}
+	}
+	if f.Enrichment != "" && !knownEnrichments[f.Enrichment] {
+		return &paramError{"enrichment", "must be one of ok, miss, host or error"}
+	}
+	if len(f.Labels) > constants.APIMaxLabelFilters {
+		return &paramError{"label", fmt.Sprintf("at most %d label filters allowed", constants.APIMaxLabelFilters)}
+	}
+	for _, k := range slices.Sorted(maps.Keys(f.Labels)) {
+		if k == "" {
+			return &paramError{"label", "label key must not be empty"}
+		}
+		if ok && !schema.HasLabel(k) {
+			return &paramError{"label", fmt.Sprintf("label '%s' not valid for type '%s'", k, f.Type)}
+		}
+	}
+	if err := validateTimeRange(f.Since, f.Until, f.Range); err != nil {
+		return err
+	}
+	if f.Limit < 0 || f.Limit > constants.APIMaxPageSize {
+		return &paramError{"limit", fmt.Sprintf("must be in [0, %d]", constants.APIMaxPageSize)}
+	}
+	if f.Offset < 0 {
+		return &paramError{"offset", "must be >= 0"}
+	}
+	if f.Cursor != "" {
+		if f.Offset != 0 {
+			return &paramError{"cursor", "cannot be combined with offset"}
+		}
+		if _, err := decodeCursor(f.Cursor); err != nil {
+			return &paramError{"cursor", err.Error()}
+		}
+	}
+	return nil
+}
+
+// pageSize returns the effective row limit.
+func (f eventFilter) pageSize() int {
+	if f.Limit <= 0 {
+		return constants.APIDefaultPageSize
+	}
+	return f.Limit
+}
+
+// timeRange resolves the filter's bounds at now. A zero time is unbounded.
+// Only valid after validate.
+func (f eventFilter) timeRange(now time.Time) (since, until time.Time) {
+	return resolveTimeRange(f.Since, f.Until, f.Range, now)
+}
+
+// validateTimeRange checks since/until (RFC3339) and range (relative
+// duration) parameters: range excludes the other two, and since < until.
+func validateTimeRange(since, until, rng string) error {
+	if rng != "" {
+		if since != "" || until != "" {
+			return &paramError{"range", "cannot be combined with since or until"}
+		}
+		if _, err := parseRange(rng); err != nil {
+			return &paramError{"range", err.Error()}
+		}
+		return nil
+	}
+	var from, to time.Time
+	var err error
+	if since != "" {
+		if from, err = time.Parse(time.RFC3339, since); err != nil {
+			return &paramError{"since", "must be an RFC3339 timestamp"}
+		}
+	}
+	if until != "" {
+		if to, err = time.Parse(time.RFC3339, until); err != nil {
+			return &paramError{"until", "must be an RFC3339 timestamp"}
+		}
+	}
+	if since != "" && until != "" && !from.Before(to) {
+		return &paramError{"until", "must be after since"}
+	}
+	return nil
+}
+
+// resolveTimeRange turns validated parameters into absolute bounds.
+func resolveTimeRange(since, until, rng string, now time.Time) (from, to time.Time) {
+	if rng != "" {
+		d, _ := parseRange(rng)
+		return now.Add(-d), now
+	}
+	if since != "" {
+		from, _ = time.Parse(time.RFC3339, since)
+	}
+	if until != "" {
+		to, _ = time.Parse(time.RFC3339, until)
+	}
+	return from, to
+}
+
+// rangeUnits are the day-scale suffixes parseRange accepts in addition to
+// Go durations.
+var rangeUnits = map[byte]time.Duration{
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+}
+
+// parseRange parses a relative range: a positive Go duration ("30s",
+// "90m", "1h30m") or a whole number of days or weeks ("7d", "2w"), at
+// most constants.APIMaxRange.
+func parseRange(s string) (time.Duration, error) {
+	var d time.Duration
+	if n := len(s); n > 1 && rangeUnits[s[n-1]] != 0 {
+		unit, digits := rangeUnits[s[n-1]], s[:n-1]
+		if strings.Trim(digits, "0123456789") != "" {
+			return 0, fmt.Errorf("invalid range %q", s)
+		}
+		count, err := strconv.ParseUint(digits, 10, 64)
+		if err != nil || count > uint64(constants.APIMaxRange/unit) {
+			return 0, fmt.Errorf("range must be at most %s", constants.APIMaxRange)
+		}
+		d = time.Duration(count) * unit
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, fmt.Errorf("invalid range %q", s)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("range must be positive")
+	}
+	if d > constants.APIMaxRange {
+		return 0, fmt.Errorf("range must be at most %s", constants.APIMaxRange)
+	}
+	return d, nil
+}
+
+// rangeJSON renders effective bounds for a response envelope;
+// unbounded ends are null.
+func rangeJSON(since, until time.Time) fiber.Map {
+	m := fiber.Map{"since": nil, "until": nil}
+	if !since.IsZero() {
+		m["since"] = since.UTC()
+	}
+	if !until.IsZero() {
+		m["until"] = until.UTC()
+	}
+	return m
+}
+
+// query converts a validated f with bounds since/until (zero = unbounded)
+// into a store query for one page.
+func (f eventFilter) query(since, until time.Time) storage.EventQuery {
+	q := storage.EventQuery{
+		Type:       f.Type,
+		Namespace:  f.Namespace,
+		Pod:        f.Pod,
+		Node:       f.Node,
+		Comm:       f.Comm,
+		Enrichment: f.Enrichment,
+		Labels:     f.Labels,
+		Since:      since,
+		Until:      until,
+		Limit:      f.pageSize(),
+		Offset:     f.Offset,
+	}
+	q.Namespaces = f.scope
+	if f.PID != "" {
+		pid64, _ := strconv.ParseUint(f.PID, 10, 32)
+		pid := uint32(pid64)
+		q.PID = &pid
+	}
+	if f.MinLatency != "" {
+		v, _ := strconv.ParseFloat(f.MinLatency, 64)
+		q.MinLatency = &v
+	}
+	if f.Cursor != "" {
+		cur, _ := decodeCursor(f.Cursor)
+		q.After = &storage.Cursor{Timestamp: cur.Timestamp, RowKey: cur.RowKey}
+	}
+	return q
+}
+
+// applied returns the non-paging filters for echoing in a response.
+func (f eventFilter) applied() eventFilter {
+	f.Since, f.Until, f.Range = "", "", "" // reported separately as the effective range
+	f.Limit, f.Offset, f.Cursor = 0, 0, ""
+	return f
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func TestParseRange(t *testing.T) {
+	valid := map[string]time.Duration{
+		"1h":    time.Hour,
+		"90m":   90 * time.Minute,
+		"1h30m": 90 * time.Minute,
+		"30s":   30 * time.Second,
+		"1.5h":  90 * time.Minute,
+		"7d":    7 * 24 * time.Hour,
+		"1d":    24 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"52w":   52 * 7 * 24 * time.Hour,
+		"366d":  366 * 24 * time.Hour,
+		"007d":  7 * 24 * time.Hour,
+	}
+	for in, want := range valid {
+		if got, err := parseRange(in); err != nil || got != want {
+			t.Errorf("parseRange(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+
+	invalid := []string{
+		// malformed
+		"", "abc", "h", "d", "w", "xd", "1 HOUR", "1h ", " 1h", "5dh3", "1d2h", "1.5d", "1e3d",
+		"+7d", "-7d", "7D", "7dd", "１d",
+		// non-positive
+		"0h", "0s", "0d", "0w", "-1h",
+		// too long, including overflow attempts
+		"367d", "53w", "9000h", "99999999999999999999d", "9223372036854775807ns0d",
+		// injection attempts
+		"1h; DROP TABLE kubepulse.events", "1 HOUR) OR 1=1 --", "1d' OR '1'='1",
+		"1h\x00", "1h\n2h", "7d UNION SELECT 1",
+	}
+	for _, in := range invalid {
+		if d, err := parseRange(in); err == nil {
+			t.Errorf("parseRange(%q) = %s, want error", in, d)
+		}
+	}
+}
+
+func TestWindowParamRejectedWith400(t *testing.T) {
+	s := newViewsTestServer()
+	for _, path := range []string{
+		"/api/v1/metrics/overview?window=5dh3",
+		"/api/v1/metrics/overview?window=1h%3B%20DROP%20TABLE%20x",
+		"/api/v1/metrics/tcp?window=1%20HOUR",
+		"/api/v1/topology?window=0d",
+		"/api/v1/top?type=oom&window=400d",
+	} {
+		resp, body := doJSON(t, s, "GET", path, "", nil)
+		if resp.StatusCode != 400 || errParam(body) != "window" {
+			t.Errorf("%s: status=%d body=%v, want 400 on window", path, resp.StatusCode, body)
+		}
+	}
+}
+
+func TestValidateTimeRange(t *testing.T) {
+	tests := []struct {
+		name, since, until, rng string
+		param                   string // "" = valid
+	}{
+		{"open", "", "", "", ""},
+		{"since only", "2026-01-01T00:00:00Z", "", "", ""},
+		{"window", "2026-01-01T00:00:00Z", "2026-01-01T06:00:00Z", "", ""},
+		{"range", "", "", "6h", ""},
+		{"malformed since", "yesterday", "", "", "since"},
+		{"malformed until", "", "2026-13-01", "", "until"},
+		{"inverted", "2026-01-02T00:00:00Z", "2026-01-01T00:00:00Z", "", "until"},
+		{"empty window", "2026-01-01T00:00:00Z", "2026-01-01T00:00:00Z", "", "until"},
+		{"range with since", "2026-01-01T00:00:00Z", "", "1h", "range"},
+		{"bad range", "", "", "soon", "range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTimeRange(tt.since, tt.until, tt.rng)
+			if tt.param == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			pe, ok := err.(*paramError)
+			if !ok || pe.Param != tt.param {
+				t.Fatalf("err = %v, want paramError on %q", err, tt.param)
+			}
+		})
+	}
+}
+
+func TestTimeRange_Resolves(t *testing.T) {
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	since, until := eventFilter{Range: "2h"}.timeRange(now)
+	if !since.Equal(now.Add(-2*time.Hour)) || !until.Equal(now) {
+		t.Errorf("range 2h = [%s, %s)", since, until)
+	}
+
+	f := eventFilter{Since: "2026-01-01T00:00:00Z", Until: "2026-01-01T01:00:00Z"}
+	since, until = f.timeRange(now)
+	q := f.query(since, until)
+	if !q.Since.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) || !q.Until.Equal(time.Date(2026, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("query bounds = [%s, %s)", q.Since, q.Until)
+	}
+}
+
+func TestWithOverrides_RangeExclusive(t *testing.T) {
+	stored := eventFilter{Since: "2026-01-01T00:00:00Z", Until: "2026-01-01T01:00:00Z"}
+	app := fiber.New()
+	var got eventFilter
+	app.Get("/", func(c *fiber.Ctx) error { got, _ = stored.withOverrides(c); return nil })
+
+	app.Test(httptest.NewRequest("GET", "/?range=15m", nil))
+	if got.Range != "15m" || got.Since != "" || got.Until != "" {
+		t.Errorf("range override = %+v, want since/until cleared", got)
+	}
+
+	stored = eventFilter{Range: "1h"}
+	app.Test(httptest.NewRequest("GET", "/?until=2026-01-01T01:00:00Z", nil))
+	if got.Range != "" || got.Until == "" {
+		t.Errorf("until override = %+v, want range cleared", got)
+	}
+}
+
+func TestHandleEvents_StructuredBadRequest(t *testing.T) {
+	s := newViewsTestServer()
+	for query, param := range map[string]string{
+		"since=notatime": "since",
+		"since=2026-01-02T00:00:00Z&until=2026-01-01T00:00:00Z": "until",
+		"range=1h&since=2026-01-01T00:00:00Z":                   "range",
+		"type=bogus":                                            "type",
+		"enrichment=unknown":                                    "enrichment",
+	} {
+		resp, body := doJSON(t, s, "GET", "/api/v1/events?"+query, "", nil)
+		if resp.StatusCode != 400 || errParam(body) != param || errDetails(body)["reason"] == nil {
+			t.Errorf("%s: status=%d body=%v, want 400 naming %q", query, resp.StatusCode, body, param)
+		}
+	}
+}
+
+func TestEventFilter_QueryTypesRichFilters(t *testing.T) {
+	cur := eventCursor{Timestamp: time.UnixMilli(1767225600123).UTC(), RowKey: 42}
+	f := eventFilter{
+		Type:       "tcp",
+		Pod:        "api-7f9c",
+		Node:       "node-a",
+		Comm:       "curl",
+		PID:        "4242",
+		MinLatency: "0.25",
+		Labels:     map[string]string{"dst": "10.0.0.1", "role": "client"},
+		Cursor:     cur.encode(),
+	}
+	if err := f.validate(); err != nil {
+		t.Fatal(err)
+	}
+	q := f.query(time.Time{}, time.Time{})
+
+	if q.Type != "tcp" || q.Pod != "api-7f9c" || q.Node != "node-a" || q.Comm != "curl" || len(q.Labels) != 2 {
+		t.Errorf("query = %+v", q)
+	}
+	if q.PID == nil || *q.PID != 4242 {
+		t.Errorf("pid = %v, want 4242", q.PID)
+	}
+	if q.MinLatency == nil || *q.MinLatency != 0.25 {
+		t.Errorf("min_latency = %v, want 0.25", q.MinLatency)
+	}
+	if q.After == nil || !q.After.Timestamp.Equal(cur.Timestamp) || q.After.RowKey != 42 {
+		t.Errorf("after = %+v, want the cursor", q.After)
+	}
+	if q.Limit != constants.APIDefaultPageSize || q.Offset != 0 {
+		t.Errorf("paging = %d/%d", q.Limit, q.Offset)
+	}
+	if empty := (eventFilter{}).query(time.Time{}, time.Time{}); empty.PID != nil || empty.MinLatency != nil || empty.After != nil {
+		t.Errorf("empty filter query = %+v, want no pid, latency or cursor", empty)
+	}
+	if q := (eventFilter{Enrichment: "miss"}).query(time.Time{}, time.Time{}); q.Enrichment != "miss" || q.Labels != nil {
+		t.Errorf("enrichment query = %+v, want the enrichment column, not a label", q)
+	}
+}
+
+func TestValidate_RichFilters(t *testing.T) {
+	tooMany := map[string]string{}
+	for i := range constants.APIMaxLabelFilters + 1 {
+		tooMany[fmt.Sprintf("k%d", i)] = "v"
+	}
+	tests := []struct {
+		name  string
+		f     eventFilter
+		param string
+	}{
+		{"negative pid", eventFilter{PID: "-1"}, "pid"},
+		{"pid overflow", eventFilter{PID: "4294967296"}, "pid"},
+		{"pid text", eventFilter{PID: "init"}, "pid"},
+		{"negative latency", eventFilter{MinLatency: "-0.1"}, "min_latency"},
+		{"nan latency", eventFilter{MinLatency: "NaN"}, "min_latency"},
+		{"too many labels", eventFilter{Labels: tooMany}, "label"},
+		{"empty label key", eventFilter{Labels: map[string]string{"": "x"}}, "label"},
+		{"label of another type", eventFilter{Type: "oom", Labels: map[string]string{"qname": "x"}}, "label"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pe, ok := tt.f.validate().(*paramError)
+			if !ok || pe.Param != tt.param {
+				t.Errorf("validate = %v, want paramError on %q", pe, tt.param)
+			}
+		})
+	}
+}
+
+func TestParseEventFilter_Labels(t *testing.T) {
+	app := fiber.New()
+	var got eventFilter
+	app.Get("/", func(c *fiber.Ctx) error { got, _ = parseEventFilter(c); return nil })
+	app.Test(httptest.NewRequest("GET", "/?label.app=web&label.tier=db&pid=7&labelx=1", nil))
+
+	if len(got.Labels) != 2 || got.Labels["app"] != "web" || got.Labels["tier"] != "db" {
+		t.Errorf("labels = %v", got.Labels)
+	}
+	if got.PID != "7" {
+		t.Errorf("pid = %q", got.PID)
+	}
+}
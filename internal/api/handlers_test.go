@@ -0,0 +1,322 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// newHandlersTestServer returns a server over a Memory store seeded with
+// a known set of events, all within the last ten minutes:
+//
+//	tcp  prod/web-0 → prod/api   30 events, latency 10ms
+//	tcp  prod/web-1 → external   10 events, latency 200ms
+//	dns  prod/web-0              20 events
+//	oom  staging/batch-0          1 event
+func newHandlersTestServer(t *testing.T) *Server {
+	t.Helper()
+	store := storage.NewMemory()
+	base := time.Now().Add(-5 * time.Minute)
+	var rows []storage.EventRow
+	add := func(n int, row storage.EventRow) {
+		for range n {
+			row.EventID = uint64(len(rows)) + 1
+			row.Timestamp = base.Add(time.Duration(len(rows)) * time.Millisecond)
+			row.PID = uint32(1000 + len(rows))
+			rows = append(rows, row)
+		}
+	}
+	add(30, storage.EventRow{Type: constants.ModuleTCP, Comm: "curl", Node: "node-a", Namespace: "prod", Pod: "web-0",
+		Labels:     map[string]string{constants.KeyDst: "10.0.0.1:443", constants.KeyDstService: "prod/api"},
+		Numerics:   map[string]float64{"latency_sec": 0.010},
+		LatencySec: 0.010})
+	add(10, storage.EventRow{Type: constants.ModuleTCP, Comm: "curl", Node: "node-b", Namespace: "prod", Pod: "web-1",
+		Labels:     map[string]string{constants.KeyDst: "1.1.1.1:443", constants.KeyEnrichment: constants.EnrichmentOK},
+		Numerics:   map[string]float64{"latency_sec": 0.200},
+		LatencySec: 0.200})
+	add(20, storage.EventRow{Type: constants.ModuleDNS, Comm: "coredns", Node: "node-a", Namespace: "prod", Pod: "web-0"})
+	add(1, storage.EventRow{Type: constants.ModuleOOM, Comm: "java", Node: "node-b", Namespace: "staging", Pod: "batch-0"})
+	if err := store.InsertBatch(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{
+		app:    fiber.New(),
+		store:  store,
+		cache:  cache.Disabled{},
+		views:  newMemViewStore(),
+		hub:    newHub(),
+		logger: zap.NewNop(),
+	}
+	s.registerRoutes()
+	return s
+}
+
+// get requests path and decodes a 200 JSON response into out.
+func get(t *testing.T, s *Server, path string, out any) {
+	t.Helper()
+	resp, err := s.app.Test(httptest.NewRequest("GET", path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 200 {
+		t.Fatalf("GET %s = %d: %s", path, resp.StatusCode, raw)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Fatalf("GET %s: %v: %s", path, err, raw)
+	}
+}
+
+type eventsPage struct {
+	Events     []eventRecord `json:"events"`
+	NextCursor *string       `json:"next_cursor"`
+}
+
+func TestHandlers_EventsFilters(t *testing.T) {
+	s := newHandlersTestServer(t)
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"", 61},
+		{"type=tcp", 40},
+		{"type=tcp&pod=web-1", 10},
+		{"namespace=staging", 1},
+		{"node=node-a&comm=coredns", 20},
+		{"min_latency=0.1", 10},
+		{"label.dst=10.0.0.1:443", 30},
+		{"enrichment=ok", 10},
+		{"enrichment=ok&label.dst=10.0.0.1:443", 0},
+		{"enrichment=host", 0},
+		{"pid=1000", 1},
+		{"type=drop", 0},
+		{"range=1m", 0},
+		{"since=" + time.Now().Add(-time.Hour).UTC().Format(time.RFC3339), 61},
+	}
+	for _, tt := range tests {
+		var page eventsPage
+		get(t, s, "/api/v1/events?limit=100&"+tt.query, &page)
+		if len(page.Events) != tt.want {
+			t.Errorf("%q: %d events, want %d", tt.query, len(page.Events), tt.want)
+		}
+	}
+
+	var page eventsPage
+	get(t, s, "/api/v1/events?limit=1&type=oom", &page)
+	e := page.Events[0]
+	if e.Namespace != "staging" || e.Pod != "batch-0" || e.Comm != "java" || e.Node != "node-b" || e.PID != 1060 {
+		t.Errorf("oom event = %+v", e)
+	}
+}
+
+func TestHandlers_EventsCursorWalksEverything(t *testing.T) {
+	s := newHandlersTestServer(t)
+	seen := map[uint32]bool{}
+	path := "/api/v1/events?limit=7"
+	for pages := 0; ; pages++ {
+		if pages > 20 {
+			t.Fatal("cursor never ran out")
+		}
+		var page eventsPage
+		get(t, s, path, &page)
+		for _, e := range page.Events {
+			if seen[e.PID] {
+				t.Fatalf("pid %d on two pages", e.PID)
+			}
+			seen[e.PID] = true
+		}
+		if page.NextCursor == nil {
+			break
+		}
+		path = "/api/v1/events?limit=7&cursor=" + *page.NextCursor
+	}
+	if len(seen) != 61 {
+		t.Errorf("walked %d events, want 61", len(seen))
+	}
+}
+
+func TestHandlers_EventTypes(t *testing.T) {
+	s := newHandlersTestServer(t)
+	var body struct {
+		Types []storage.TypeCount `json:"types"`
+	}
+	get(t, s, "/api/v1/events/types", &body)
+	if got := fmt.Sprint(body.Types); got != "[{tcp 40} {dns 20} {oom 1}]" {
+		t.Errorf("types = %s", got)
+	}
+}
+
+func TestHandlers_Schema(t *testing.T) {
+	s := newHandlersTestServer(t)
+	var body struct {
+		Types []event.Schema `json:"types"`
+	}
+	get(t, s, "/api/v1/schema", &body)
+	if len(body.Types) != len(event.Schemas()) {
+		t.Fatalf("%d types, want %d", len(body.Types), len(event.Schemas()))
+	}
+	dns := body.Types[1]
+	if dns.Type != constants.ModuleDNS || !dns.HasLabel(constants.KeyQName) || dns.HasLabel(constants.KeySrc) {
+		t.Errorf("dns schema = %+v", dns)
+	}
+
+	// Label filters are checked against the type's schema.
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/api/v1/events?type=oom&label.qname=x", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != 400 || !strings.Contains(string(raw), "label 'qname' not valid for type 'oom'") {
+		t.Errorf("label of another type = %d: %s", resp.StatusCode, raw)
+	}
+}
+
+func TestHandlers_Overview(t *testing.T) {
+	s := newHandlersTestServer(t)
+	var body struct {
+		Total        uint64                   `json:"total_events"`
+		TCP          uint64                   `json:"tcp_events"`
+		DNS          uint64                   `json:"dns_events"`
+		OOM          uint64                   `json:"oom_events"`
+		Drop         uint64                   `json:"drop_events"`
+		AvgLatency   float64                  `json:"avg_latency_sec"`
+		Source       string                   `json:"source"`
+		TopOffenders map[string][]topOffender `json:"top_offenders"`
+	}
+	get(t, s, "/api/v1/metrics/overview", &body)
+	if body.Total != 61 || body.TCP != 40 || body.DNS != 20 || body.OOM != 1 || body.Drop != 0 {
+		t.Errorf("counts = %+v", body)
+	}
+	if body.Source != querySource(false) {
+		t.Errorf("source = %q, want raw for the default window", body.Source)
+	}
+	if tcp := body.TopOffenders[constants.ModuleTCP]; len(tcp) != 2 || tcp[0].Pod != "web-0" || tcp[0].Count != 30 {
+		t.Errorf("tcp offenders = %+v", tcp)
+	}
+	if oom := body.TopOffenders[constants.ModuleOOM]; len(oom) != 1 || oom[0].P99Latency != nil {
+		t.Errorf("oom offenders = %+v, want one without a latency", oom)
+	}
+}
+
+func TestHandlers_MetricsSeries(t *testing.T) {
+	s := newHandlersTestServer(t)
+
+	var flat struct {
+		Series []metricPoint `json:"series"`
+	}
+	get(t, s, "/api/v1/metrics/tcp", &flat)
+	var total uint64
+	for _, p := range flat.Series {
+		total += p.Count
+	}
+	if total != 40 {
+		t.Errorf("tcp series total = %d, want 40", total)
+	}
+
+	var grouped struct {
+		GroupBy string        `json:"group_by"`
+		Series  []groupSeries `json:"series"`
+	}
+	get(t, s, "/api/v1/metrics/tcp?group_by=pod&groups=1", &grouped)
+	var keys []string
+	for _, g := range grouped.Series {
+		keys = append(keys, g.Key)
+	}
+	if grouped.GroupBy != "pod" || fmt.Sprint(keys) != "[web-0 "+constants.APIGroupOther+"]" {
+		t.Errorf("group_by=%q keys=%v", grouped.GroupBy, keys)
+	}
+}
+
+func TestHandlers_Top(t *testing.T) {
+	s := newHandlersTestServer(t)
+	var body struct {
+		Top []topOffender `json:"top"`
+	}
+	get(t, s, "/api/v1/top?type=tcp&k=1", &body)
+	if len(body.Top) != 1 || body.Top[0].Pod != "web-0" || body.Top[0].Count != 30 {
+		t.Fatalf("top = %+v", body.Top)
+	}
+	if p99 := body.Top[0].P99Latency; p99 == nil || *p99 != 0.010 {
+		t.Errorf("p99 = %v, want 0.010", p99)
+	}
+}
+
+func TestHandlers_Topology(t *testing.T) {
+	s := newHandlersTestServer(t)
+	var body struct {
+		Edges []topologyEdge `json:"edges"`
+	}
+	get(t, s, "/api/v1/topology", &body)
+	want := []topologyEdge{
+		{SrcNamespace: "prod", SrcPod: "web-0", DstService: "prod/api", Count: 30, P99Latency: 0.010},
+		{SrcNamespace: "prod", SrcPod: "web-1", DstService: constants.APIExternalService, Count: 10, P99Latency: 0.200},
+	}
+	if fmt.Sprint(body.Edges) != fmt.Sprint(want) {
+		t.Errorf("edges = %+v, want %+v", body.Edges, want)
+	}
+}
+
+func TestHandlers_Export(t *testing.T) {
+	s := newHandlersTestServer(t)
+
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/api/v1/events?type=tcp&format=csv", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	lines := strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 41 {
+		t.Fatalf("csv has %d lines, want header + 40", len(lines))
+	}
+	for _, col := range []string{"label." + constants.KeyDst, "label." + constants.KeyDstService, "latency_sec"} {
+		if !strings.Contains(lines[0], col) {
+			t.Errorf("csv header %q missing %s", lines[0], col)
+		}
+	}
+
+	resp, err = s.app.Test(httptest.NewRequest("GET", "/api/v1/events?type=dns&format=ndjson", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ = io.ReadAll(resp.Body)
+	lines = strings.Split(strings.TrimSpace(string(raw)), "\n")
+	if len(lines) != 20 {
+		t.Fatalf("ndjson has %d lines, want 20", len(lines))
+	}
+	var e eventRecord
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil || e.Type != constants.ModuleDNS {
+		t.Errorf("first line = %s (%v)", lines[0], err)
+	}
+}
+
+func TestHandlers_ViewEvents(t *testing.T) {
+	s := newHandlersTestServer(t)
+	err := s.views.Create(context.Background(), savedView{
+		ID:      "slow",
+		Name:    "slow",
+		Owner:   constants.APIAnonymousOwner,
+		Filters: eventFilter{Type: constants.ModuleTCP, Pod: "web-1"},
+	}, constants.APIMaxViewsPerOwner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var page eventsPage
+	get(t, s, "/api/v1/views/slow/events", &page)
+	if len(page.Events) != 10 {
+		t.Errorf("view events = %d, want 10", len(page.Events))
+	}
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// hub fans a single live-event feed out to all WebSocket connections,
+// downsampling high-rate event types per connection.
+type hub struct {
+	mu      sync.Mutex
+	clients map[*wsClient]struct{}
+}
+
+func newHub() *hub {
+	return &hub{clients: make(map[*wsClient]struct{})}
+}
+
+// wsOptions are the per-connection filter and downsampling settings.
+type wsOptions struct {
+	Type       string // exact-match filters; empty matches all
+	Namespace  string
+	Pod        string
+	MaxRate    int // events/sec per type before aggregating
+	SampleSize int // representative events per aggregate
+
+	Namespaces []string // the token's scope; nil matches all
+}
+
+// envelope is the routing subset of a live event payload. It is decoded
+// once per event, not once per connection.
+type envelope struct {
+	Type      string `json:"type"`
+	Namespace string `json:"namespace"`
+	Pod       string `json:"pod"`
+}
+
+// matches reports whether an event passes the connection's filters.
+func (o wsOptions) matches(e envelope) bool {
+	return (o.Type == "" || o.Type == e.Type) &&
+		(o.Namespace == "" || o.Namespace == e.Namespace) &&
+		(o.Pod == "" || o.Pod == e.Pod) &&
+		(o.Namespaces == nil || slices.Contains(o.Namespaces, e.Namespace))
+}
+
+// parseWSOptions reads the type/namespace/pod filters and max_rate/sample
+// from the connection's query string, clamped to the server caps. query
+// is (*websocket.Conn).Query or (*fiber.Ctx).Query; validateWSOptions has
+// already rejected values a clamp would change.
+func parseWSOptions(query func(key string, defaultValue ...string) string) wsOptions {
+	rate, err := strconv.Atoi(query("max_rate"))
+	if err != nil {
+		rate = constants.WSDefaultMaxRate
+	}
+	if rate <= 0 || rate > constants.WSMaxRateCap {
+		rate = constants.WSMaxRateCap
+	}
+	sample, err := strconv.Atoi(query("sample"))
+	if err != nil {
+		sample = constants.WSDefaultSampleSize
+	}
+	sample = max(0, min(sample, constants.WSMaxSampleSize))
+	return wsOptions{
+		Type:       query("type"),
+		Namespace:  query("namespace"),
+		Pod:        query("pod"),
+		MaxRate:    rate,
+		SampleSize: sample,
+	}
+}
+
+// wsClient is one live connection. send is drained by the connection's
+// writer; the hub never blocks on it, so a stalled client only loses its
+// own messages.
+type wsClient struct {
+	opts    wsOptions
+	send    chan []byte
+	buckets map[string]*rateBucket // event type → current window
+	dropped uint64
+}
+
+func newWSClient(opts wsOptions) *wsClient {
+	return &wsClient{
+		opts:    opts,
+		send:    make(chan []byte, constants.WSSendBuffer),
+		buckets: make(map[string]*rateBucket),
+	}
+}
+
+// rateBucket tracks one event type for one connection over the current
+// aggregation window.
+type rateBucket struct {
+	start       time.Time
+	count       int // events seen this window
+	aggregating bool
+	aggCount    int // events folded into the pending aggregate
+	sample      []json.RawMessage
+}
+
+// aggregateMessage replaces individual events for a type whose rate
+// exceeded the connection's max_rate.
+type aggregateMessage struct {
+	Aggregated  bool              `json:"aggregated"`
+	Type        string            `json:"type"`
+	Count       int               `json:"count"`
+	WindowStart time.Time         `json:"window_start"`
+	WindowSec   float64           `json:"window_sec"`
+	Sample      []json.RawMessage `json:"sample"`
+}
+
+func (h *hub) register(c *wsClient) {
+	h.mu.Lock()
+	h.clients[c] = struct{}{}
+	h.mu.Unlock()
+}
+
+func (h *hub) unregister(c *wsClient) {
+	h.mu.Lock()
+	delete(h.clients, c)
+	h.mu.Unlock()
+}
+
+// run broadcasts payloads from src and closes aggregation windows until
+// ctx is cancelled or src is closed.
+func (h *hub) run(ctx context.Context, src <-chan string) {
+	ticker := time.NewTicker(constants.WSAggregateWindow)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case p, ok := <-src:
+			if !ok {
+				return
+			}
+			h.broadcast([]byte(p), time.Now())
+		case now := <-ticker.C:
+			h.tick(now)
+		}
+	}
+}
+
+// broadcast offers one event payload to every client whose filters it
+// matches. Filtered-out events don't count toward a client's rate.
+func (h *hub) broadcast(payload []byte, now time.Time) {
+	var head envelope
+	json.Unmarshal(payload, &head)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		if c.opts.matches(head) {
+			c.offer(head.Type, payload, now)
+		}
+	}
+}
+
+// tick closes every aggregation window that has elapsed, so aggregates
+// are delivered even when a burst stops abruptly.
+func (h *hub) tick(now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for c := range h.clients {
+		for typ, b := range c.buckets {
+			if now.Sub(b.start) >= constants.WSAggregateWindow {
+				c.roll(typ, b, now)
+			}
+		}
+	}
+}
+
+// offer delivers or aggregates one event. A type is delivered per-event
+// until it exceeds MaxRate within a window; the excess, and every
+// following window whose rate stays above MaxRate, is aggregated.
+func (c *wsClient) offer(typ string, payload []byte, now time.Time) {
+	b, ok := c.buckets[typ]
+	if !ok {
+		b = &rateBucket{start: now}
+		c.buckets[typ] = b
+	} else if now.Sub(b.start) >= constants.WSAggregateWindow {
+		c.roll(typ, b, now)
+	}
+
+	b.count++
+	if !b.aggregating && b.count > c.opts.MaxRate {
+		b.aggregating = true
+	}
+	if !b.aggregating {
+		c.enqueue(payload)
+		return
+	}
+
+	// Reservoir sampling keeps the sample uniform over the window.
+	b.aggCount++
+	if len(b.sample) < c.opts.SampleSize {
+		b.sample = append(b.sample, json.RawMessage(payload))
+	} else if j := rand.IntN(b.aggCount); j < c.opts.SampleSize {
+		b.sample[j] = json.RawMessage(payload)
+	}
+}
+
+// roll emits the pending aggregate (if any) and starts a new window.
+// The new window stays aggregated only if the closed one was over rate.
+func (c *wsClient) roll(typ string, b *rateBucket, now time.Time) {
+	if b.aggCount > 0 {
+		data, _ := json.Marshal(aggregateMessage{
+			Aggregated:  true,
+			Type:        typ,
+			Count:       b.aggCount,
+			WindowStart: b.start,
+			WindowSec:   constants.WSAggregateWindow.Seconds(),
+			Sample:      b.sample,
+		})
+		c.enqueue(data)
+	}
+	b.aggregating = b.count > c.opts.MaxRate
+	b.start = now
+	b.count = 0
+	b.aggCount = 0
+	b.sample = nil
+}
+
+func (c *wsClient) enqueue(msg []byte) {
+	select {
+	case c.send <- msg:
+	default:
+		c.dropped++
+	}
+}
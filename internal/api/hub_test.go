@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// drain returns everything queued for c, split into individual events
+// and aggregates.
+func drain(t *testing.T, c *wsClient) (events []map[string]any, aggs []aggregateMessage) {
+	t.Helper()
+	for {
+		select {
+		case msg := <-c.send:
+			var probe map[string]any
+			if err := json.Unmarshal(msg, &probe); err != nil {
+				t.Fatalf("bad message %q: %v", msg, err)
+			}
+			if probe["aggregated"] == true {
+				var a aggregateMessage
+				json.Unmarshal(msg, &a)
+				aggs = append(aggs, a)
+			} else {
+				events = append(events, probe)
+			}
+		default:
+			return events, aggs
+		}
+	}
+}
+
+// burst broadcasts n events of typ spread evenly across one window from start.
+func burst(h *hub, typ string, n int, start time.Time) {
+	step := constants.WSAggregateWindow / time.Duration(n+1)
+	for i := range n {
+		h.broadcast([]byte(fmt.Sprintf(`{"type":%q,"pid":%d}`, typ, i)), start.Add(step*time.Duration(i)))
+	}
+}
+
+func TestHub_LowRatePassesThrough(t *testing.T) {
+	h := newHub()
+	c := newWSClient(wsOptions{MaxRate: 10, SampleSize: 3})
+	h.register(c)
+
+	t0 := time.Unix(1000, 0)
+	burst(h, "tcp", 10, t0)
+	h.tick(t0.Add(constants.WSAggregateWindow))
+
+	events, aggs := drain(t, c)
+	if len(events) != 10 || len(aggs) != 0 {
+		t.Fatalf("events=%d aggs=%d, want 10/0", len(events), len(aggs))
+	}
+}
+
+func TestHub_BurstIsAggregatedThenReverts(t *testing.T) {
+	h := newHub()
+	c := newWSClient(wsOptions{MaxRate: 5, SampleSize: 3})
+	h.register(c)
+	w := constants.WSAggregateWindow
+	t0 := time.Unix(1000, 0)
+
+	// Window 1: burst starts — first MaxRate delivered, excess aggregated.
+	burst(h, "drop", 100, t0)
+	h.tick(t0.Add(w))
+	events, aggs := drain(t, c)
+	if len(events) != 5 {
+		t.Errorf("window 1 individual events = %d, want 5", len(events))
+	}
+	if len(aggs) != 1 || aggs[0].Count != 95 || aggs[0].Type != "drop" || len(aggs[0].Sample) != 3 {
+		t.Fatalf("window 1 aggregates = %+v, want one drop aggregate of 95 with 3 samples", aggs)
+	}
+
+	// Window 2: still bursting — fully aggregated.
+	burst(h, "drop", 100, t0.Add(w))
+	h.tick(t0.Add(2 * w))
+	events, aggs = drain(t, c)
+	if len(events) != 0 || len(aggs) != 1 || aggs[0].Count != 100 {
+		t.Fatalf("window 2 events=%d aggs=%+v, want 0 events and one aggregate of 100", len(events), aggs)
+	}
+
+	// Window 3: rate falls — this window is still summarised...
+	burst(h, "drop", 2, t0.Add(2*w))
+	h.tick(t0.Add(3 * w))
+	events, aggs = drain(t, c)
+	if len(events) != 0 || len(aggs) != 1 || aggs[0].Count != 2 {
+		t.Fatalf("window 3 events=%d aggs=%+v, want one aggregate of 2", len(events), aggs)
+	}
+
+	// ...and window 4 is back to per-event delivery.
+	burst(h, "drop", 3, t0.Add(3*w))
+	h.tick(t0.Add(4 * w))
+	events, aggs = drain(t, c)
+	if len(events) != 3 || len(aggs) != 0 {
+		t.Fatalf("window 4 events=%d aggs=%d, want 3/0", len(events), len(aggs))
+	}
+}
+
+func TestHub_AggregationIsPerTypeAndPerClient(t *testing.T) {
+	h := newHub()
+	strict := newWSClient(wsOptions{MaxRate: 5, SampleSize: 2})
+	loose := newWSClient(wsOptions{MaxRate: 500, SampleSize: 2})
+	h.register(strict)
+	h.register(loose)
+
+	t0 := time.Unix(1000, 0)
+	burst(h, "drop", 50, t0)
+	burst(h, "tcp", 3, t0)
+	h.tick(t0.Add(constants.WSAggregateWindow))
+
+	events, aggs := drain(t, strict)
+	if len(aggs) != 1 || aggs[0].Type != "drop" {
+		t.Errorf("strict aggregates = %+v, want one drop aggregate", aggs)
+	}
+	var tcp int
+	for _, e := range events {
+		if e["type"] == "tcp" {
+			tcp++
+		}
+	}
+	if tcp != 3 {
+		t.Errorf("strict tcp events = %d, want 3 (unaffected by drop burst)", tcp)
+	}
+
+	events, aggs = drain(t, loose)
+	if len(events) != 53 || len(aggs) != 0 {
+		t.Errorf("loose events=%d aggs=%d, want 53/0", len(events), len(aggs))
+	}
+}
+
+func TestHub_SlowClientDoesNotBlock(t *testing.T) {
+	h := newHub()
+	c := newWSClient(wsOptions{MaxRate: constants.WSMaxRateCap, SampleSize: 1})
+	h.register(c)
+
+	burst(h, "tcp", constants.WSSendBuffer+10, time.Unix(1000, 0))
+	if c.dropped != 10 {
+		t.Errorf("dropped = %d, want 10", c.dropped)
+	}
+}
+
+func TestParseWSOptions(t *testing.T) {
+	tests := []struct {
+		query map[string]string
+		want  wsOptions
+	}{
+		{nil, wsOptions{MaxRate: constants.WSDefaultMaxRate, SampleSize: constants.WSDefaultSampleSize}},
+		{map[string]string{"max_rate": "20", "sample": "2"}, wsOptions{MaxRate: 20, SampleSize: 2}},
+		{map[string]string{"max_rate": "100000"}, wsOptions{MaxRate: constants.WSMaxRateCap, SampleSize: constants.WSDefaultSampleSize}},
+		{map[string]string{"max_rate": "0"}, wsOptions{MaxRate: constants.WSMaxRateCap, SampleSize: constants.WSDefaultSampleSize}},
+		{map[string]string{"sample": "1000"}, wsOptions{MaxRate: constants.WSDefaultMaxRate, SampleSize: constants.WSMaxSampleSize}},
+		{map[string]string{"sample": "-3"}, wsOptions{MaxRate: constants.WSDefaultMaxRate, SampleSize: 0}},
+		{
+			map[string]string{"type": "dns", "namespace": "prod", "pod": "api-0"},
+			wsOptions{Type: "dns", Namespace: "prod", Pod: "api-0", MaxRate: constants.WSDefaultMaxRate, SampleSize: constants.WSDefaultSampleSize},
+		},
+	}
+	for _, tt := range tests {
+		got := parseWSOptions(func(key string, _ ...string) string { return tt.query[key] })
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseWSOptions(%v) = %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestHub_FiltersBeforeRateLimiting(t *testing.T) {
+	h := newHub()
+	all := newWSClient(wsOptions{MaxRate: 100, SampleSize: 1})
+	prodDNS := newWSClient(wsOptions{Type: "dns", Namespace: "prod", MaxRate: 2, SampleSize: 1})
+	pod := newWSClient(wsOptions{Pod: "api-0", MaxRate: 100, SampleSize: 1})
+	for _, c := range []*wsClient{all, prodDNS, pod} {
+		h.register(c)
+	}
+
+	t0 := time.Unix(1000, 0)
+	for i, p := range []string{
+		`{"type":"dns","namespace":"prod","pod":"api-0"}`,
+		`{"type":"dns","namespace":"dev","pod":"api-0"}`,
+		`{"type":"tcp","namespace":"prod","pod":"web-1"}`,
+		`{"type":"tcp","namespace":"prod","pod":"web-1"}`,
+		`{"type":"tcp","namespace":"prod","pod":"web-1"}`,
+		`{"type":"dns","namespace":"prod","pod":"web-1"}`,
+	} {
+		h.broadcast([]byte(p), t0.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	if events, _ := drain(t, all); len(events) != 6 {
+		t.Errorf("unfiltered client got %d events, want 6", len(events))
+	}
+	// The tcp events must not push prodDNS over its max_rate of 2.
+	if events, aggs := drain(t, prodDNS); len(events) != 2 || len(aggs) != 0 {
+		t.Errorf("prod/dns client got events=%d aggs=%d, want 2/0", len(events), len(aggs))
+	}
+	if events, _ := drain(t, pod); len(events) != 2 {
+		t.Errorf("pod client got %d events, want 2", len(events))
+	}
+}
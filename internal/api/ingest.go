@@ -0,0 +1,289 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// IngestConfig controls POST /api/v1/ingest, through which agents with
+// exporters.http_push write events straight to the store, for installs
+// without NATS and the consumer.
+type IngestConfig struct {
+	Enabled     bool `yaml:"enabled"`
+	Live        bool `yaml:"live"`          // also publish accepted events to the Redis live channel
+	MaxEvents   int  `yaml:"max_events"`    // per request; larger ones get a 413
+	MaxInFlight int  `yaml:"max_in_flight"` // requests stored at once; more get a 429
+}
+
+// DefaultIngestConfig returns the defaults, with ingest off.
+func DefaultIngestConfig() IngestConfig {
+	return IngestConfig{
+		MaxEvents:   constants.APIIngestMaxEvents,
+		MaxInFlight: constants.APIIngestMaxInFlight,
+	}
+}
+
+var ingested = promauto.NewCounter(prometheus.CounterOpts{
+	Name: constants.MetricAPIIngestedEvents,
+	Help: "Events agents pushed to /api/v1/ingest that were stored.",
+})
+
+// ingester is the state behind /api/v1/ingest.
+type ingester struct {
+	cfg   IngestConfig
+	slots chan struct{} // one per request being stored
+	keys  *recentKeys
+	live  chan []byte // nil unless cfg.Live
+}
+
+func newIngester(cfg IngestConfig) *ingester {
+	in := &ingester{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.MaxInFlight),
+		keys:  newRecentKeys(constants.APIIngestKeys),
+	}
+	if cfg.Live {
+		in.live = make(chan []byte, constants.APIIngestLiveQueue)
+	}
+	return in
+}
+
+// handleIngest stores a batch of wire events an agent pushed: NDJSON, one
+// wire.Event per line, or a pack compressed as its Content-Encoding says.
+// The batch is all or nothing: one invalid event rejects it with a 400
+// naming the event. A retry of a batch already stored, by its
+// Idempotency-Key, is acknowledged without storing it again; one that
+// arrives while the batch is still being stored is asked to retry later.
+func (s *Server) handleIngest(c *fiber.Ctx) error {
+	// An agent's events span namespaces; a token scoped to some of them
+	// is a reader's.
+	if requestNamespaces(c) != nil {
+		return forbidden(c, "namespace-scoped tokens cannot ingest events")
+	}
+	in := s.ingest
+	select {
+	case in.slots <- struct{}{}:
+		defer func() { <-in.slots }()
+	default:
+		return retryLater(c, fiber.StatusTooManyRequests, codeRateLimited,
+			"ingest is at capacity", constants.APIIngestRetryAfter)
+	}
+
+	key := strings.Clone(c.Get(constants.HeaderIdempotencyKey))
+	if len(key) > constants.APIIngestMaxKeyLen {
+		return badRequest(c, &paramError{constants.HeaderIdempotencyKey,
+			fmt.Sprintf("must be at most %d characters", constants.APIIngestMaxKeyLen)})
+	}
+	if key != "" {
+		claimed, stored := in.keys.reserve(key)
+		switch {
+		case stored:
+			return c.JSON(fiber.Map{"accepted": 0, "duplicate": true})
+		case !claimed:
+			return retryLater(c, fiber.StatusTooManyRequests, codeRateLimited,
+				"a batch with this Idempotency-Key is still being stored", constants.APIIngestRetryAfter)
+		}
+		// Frees the key unless the batch is stored, so a retry can store it.
+		defer in.keys.release(key)
+	}
+
+	// The raw body: Ctx.Body would decompress gzip and the like itself,
+	// with no bound on the result.
+	events, err := splitIngestBody(c.Get(fiber.HeaderContentEncoding), c.Request().Body())
+	if errors.Is(err, wire.ErrUnsupportedEncoding) {
+		return respondError(c, fiber.StatusUnsupportedMediaType, statusCode(fiber.StatusUnsupportedMediaType), err.Error(), nil)
+	}
+	if err != nil {
+		return badRequest(c, err)
+	}
+	if len(events) > in.cfg.MaxEvents {
+		return respondError(c, fiber.StatusRequestEntityTooLarge, statusCode(fiber.StatusRequestEntityTooLarge),
+			fmt.Sprintf("%d events, over the limit of %d per request", len(events), in.cfg.MaxEvents),
+			map[string]any{"max_events": in.cfg.MaxEvents})
+	}
+
+	rows := make([]storage.EventRow, len(events))
+	ws := make([]wire.Event, len(events))
+	for i, data := range events {
+		if ws[i], err = decodeIngestEvent(data); err != nil {
+			return badRequest(c, &paramError{fmt.Sprintf("events[%d]", i), err.Error()})
+		}
+		rows[i] = ingestRow(ws[i])
+	}
+
+	if err := s.store.InsertBatch(c.UserContext(), rows); err != nil {
+		s.requestLogger(c).Warn("Storing ingested events failed", zap.Int("events", len(rows)), zap.Error(err))
+		return retryLater(c, fiber.StatusServiceUnavailable, codeUnavailable,
+			"storing events failed", constants.APIIngestStoreRetryAfter)
+	}
+	if key != "" {
+		in.keys.commit(key)
+	}
+	ingested.Add(float64(len(rows)))
+	for _, w := range ws {
+		in.offerLive(w)
+	}
+	return c.JSON(fiber.Map{"accepted": len(rows)})
+}
+
+// splitIngestBody returns the encoded events in body.
+func splitIngestBody(enc string, body []byte) ([][]byte, error) {
+	if len(body) == 0 {
+		return nil, errors.New("empty body")
+	}
+	if enc != "" {
+		return wire.DecodePack(enc, body)
+	}
+	return bytes.Split(bytes.TrimRight(body, "\n"), []byte{'\n'}), nil
+}
+
+// decodeIngestEvent decodes one event and checks the fields the store and
+// the queries rely on.
+func decodeIngestEvent(data []byte) (wire.Event, error) {
+	w, err := wire.Decode(data)
+	switch {
+	case err != nil:
+		return w, err
+	case w.ID == 0:
+		return w, errors.New("id is required")
+	case !knownEventType(w.Type):
+		return w, fmt.Errorf("unknown event type %q", w.Type)
+	case w.Timestamp <= 0:
+		return w, errors.New("ts is required")
+	case w.Node == "":
+		return w, errors.New("node is required")
+	}
+	return w, nil
+}
+
+// ingestRow converts w to a storage row, as the consumer does.
+func ingestRow(w wire.Event) storage.EventRow {
+	row := storage.EventRow{
+		EventID:   w.ID,
+		Timestamp: time.UnixMilli(w.Timestamp),
+		Type:      w.Type,
+		PID:       w.PID,
+		UID:       w.UID,
+		Comm:      w.Comm,
+		Node:      w.Node,
+		Namespace: w.Namespace,
+		Pod:       w.Pod,
+		Labels:    w.Labels,
+		Numerics:  w.Numerics,
+	}
+	row.LatencySec, row.Bytes, row.Value = storage.TypedNumerics(w.Type, w.Numerics)
+	return row
+}
+
+// retryLater writes an error asking the client to retry after wait.
+func retryLater(c *fiber.Ctx, status int, code, msg string, wait time.Duration) error {
+	c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(wait.Seconds())))
+	return respondError(c, status, code, msg, nil)
+}
+
+// offerLive queues w for the live channel unless live publishing is off
+// or the queue is full; the live feed is best effort.
+func (in *ingester) offerLive(w wire.Event) {
+	if in.live == nil {
+		return
+	}
+	data, err := json.Marshal(w.Live())
+	if err != nil {
+		return
+	}
+	select {
+	case in.live <- data:
+	default:
+	}
+}
+
+// runIngestLive publishes queued ingested events to the live channel the
+// WebSocket hub reads, as the consumer does, until ctx is cancelled.
+func (s *Server) runIngestLive(ctx context.Context) {
+	if s.ingest == nil || s.ingest.live == nil || !s.cache.Enabled() {
+		return
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-s.ingest.live:
+			if err := s.cache.Publish(ctx, constants.RedisPubSubChannel, data); err != nil && ctx.Err() == nil {
+				s.logger.Debug("Publishing ingested event", zap.Error(err))
+			}
+		}
+	}
+}
+
+// recentKeys is a bounded set of the most recent idempotency keys, each
+// reserved by the request storing its batch and then committed once the
+// batch is stored; once full, reserving a key evicts the oldest.
+type recentKeys struct {
+	mu   sync.Mutex
+	set  map[string]bool // true once committed
+	ring []string
+	next int
+}
+
+func newRecentKeys(size int) *recentKeys {
+	return &recentKeys{set: make(map[string]bool, size), ring: make([]string, 0, size)}
+}
+
+// reserve claims key for a request about to store its batch. If another
+// request already holds it, claimed is false and stored says whether that
+// request's batch was stored.
+func (r *recentKeys) reserve(key string) (claimed, stored bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.set[key]; ok {
+		return false, stored
+	}
+	if len(r.ring) < cap(r.ring) {
+		r.ring = append(r.ring, key)
+	} else {
+		delete(r.set, r.ring[r.next])
+		r.ring[r.next] = key
+		r.next = (r.next + 1) % len(r.ring)
+	}
+	r.set[key] = false
+	return true, false
+}
+
+// commit marks a reserved key's batch stored.
+func (r *recentKeys) commit(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.set[key]; ok {
+		r.set[key] = true
+	}
+}
+
+// release forgets key unless it was committed.
+func (r *recentKeys) release(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stored, ok := r.set[key]; !ok || stored {
+		return
+	}
+	delete(r.set, key)
+	if i := slices.Index(r.ring, key); i >= 0 {
+		r.ring[i] = "" // never a key; evicting it deletes nothing
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// flakyStore is a Memory store whose inserts fail while err is set.
+type flakyStore struct {
+	*storage.Memory
+	err error
+}
+
+func (s *flakyStore) InsertBatch(ctx context.Context, rows []storage.EventRow) error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.Memory.InsertBatch(ctx, rows)
+}
+
+func newIngestTestServer(cfg IngestConfig) (*Server, *flakyStore) {
+	cfg.Enabled = true
+	store := &flakyStore{Memory: storage.NewMemory()}
+	s := &Server{
+		cfg:    Config{Tokens: map[string]string{"agent": "s3cret"}},
+		app:    fiber.New(),
+		store:  store,
+		cache:  cache.Disabled{},
+		views:  newMemViewStore(),
+		ingest: newIngester(cfg),
+		logger: zap.NewNop(),
+	}
+	s.registerRoutes()
+	return s, store
+}
+
+// ingestEvents encodes n valid events, IDs from 1.
+func ingestEvents(t *testing.T, n int) [][]byte {
+	t.Helper()
+	var events [][]byte
+	for i := range n {
+		data, err := wire.Encode(wire.Event{
+			ID: uint64(i + 1), Type: constants.ModuleTCP, Timestamp: 1_700_000_000_000 + int64(i),
+			Node: "node-1", Namespace: "prod", Pod: "web-0",
+			Numerics: map[string]float64{constants.KeyLatencySec: 0.01},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, data)
+	}
+	return events
+}
+
+// postIngest posts body with the agent's token and returns the status,
+// Retry-After and body.
+func postIngest(t *testing.T, s *Server, body []byte, header map[string]string) (int, string, string) {
+	t.Helper()
+	req := httptest.NewRequest("POST", "/api/v1/ingest", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer s3cret")
+	for k, v := range header {
+		if v == "" {
+			req.Header.Del(k)
+			continue
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, resp.Header.Get("Retry-After"), string(raw)
+}
+
+func storedCount(t *testing.T, store storage.Store) int {
+	t.Helper()
+	types, err := store.EventTypes(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for _, tc := range types {
+		n += int(tc.Count)
+	}
+	return n
+}
+
+func TestIngest_StoresPackOnce(t *testing.T) {
+	cfg := DefaultIngestConfig()
+	cfg.Live = true
+	s, store := newIngestTestServer(cfg)
+	pack, err := wire.EncodePack(wire.EncodingZstd, ingestEvents(t, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := map[string]string{"Content-Encoding": wire.EncodingZstd, constants.HeaderIdempotencyKey: "batch-1"}
+
+	status, _, body := postIngest(t, s, pack, header)
+	if status != 200 || !strings.Contains(body, `"accepted":2`) {
+		t.Fatalf("status = %d: %s", status, body)
+	}
+	if n := storedCount(t, store); n != 2 {
+		t.Errorf("%d events stored, want 2", n)
+	}
+	if n := len(s.ingest.live); n != 2 {
+		t.Errorf("%d events queued for the live channel, want 2", n)
+	}
+
+	// A retry of the same batch is acknowledged, not stored again.
+	status, _, body = postIngest(t, s, pack, header)
+	if status != 200 || !strings.Contains(body, `"duplicate":true`) {
+		t.Fatalf("retry: status = %d: %s", status, body)
+	}
+	if n := storedCount(t, store); n != 2 {
+		t.Errorf("%d events stored after the retry, want 2", n)
+	}
+}
+
+func TestIngest_PlainNDJSON(t *testing.T) {
+	s, store := newIngestTestServer(DefaultIngestConfig())
+	body := append(bytes.Join(ingestEvents(t, 3), []byte{'\n'}), '\n')
+	if status, _, raw := postIngest(t, s, body, nil); status != 200 {
+		t.Fatalf("status = %d: %s", status, raw)
+	}
+	if n := storedCount(t, store); n != 3 {
+		t.Errorf("%d events stored, want 3", n)
+	}
+}
+
+func TestIngest_Rejects(t *testing.T) {
+	valid := bytes.Join(ingestEvents(t, 2), []byte{'\n'})
+	tests := []struct {
+		name   string
+		body   []byte
+		header map[string]string
+		status int
+		param  string // the rejected field, for a 400
+	}{
+		{"no token", valid, map[string]string{"Authorization": ""}, 401, ""},
+		{"empty", nil, nil, 400, ""},
+		{"encoding", valid, map[string]string{"Content-Encoding": "br"}, 415, ""},
+		{"too many", bytes.Join(ingestEvents(t, 3), []byte{'\n'}), nil, 413, ""},
+		{"key", valid, map[string]string{constants.HeaderIdempotencyKey: strings.Repeat("k", 129)}, 400, constants.HeaderIdempotencyKey},
+		{"json", []byte(`{"v":1,"id":1,`), nil, 400, "events[0]"},
+		{"version", []byte(`{"v":99,"id":1,"type":"tcp","ts":1,"node":"n"}`), nil, 400, "events[0]"},
+		{"type", append(append(valid, '\n'), `{"id":3,"type":"http","ts":1,"node":"n"}`...), nil, 400, "events[2]"},
+		{"id", []byte(`{"type":"tcp","ts":1,"node":"n"}`), nil, 400, "events[0]"},
+		{"ts", []byte(`{"id":1,"type":"tcp","node":"n"}`), nil, 400, "events[0]"},
+		{"node", []byte(`{"id":1,"type":"tcp","ts":1}`), nil, 400, "events[0]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultIngestConfig()
+			cfg.MaxEvents = 2
+			if tt.name == "type" {
+				cfg.MaxEvents = 3
+			}
+			s, store := newIngestTestServer(cfg)
+			status, _, body := postIngest(t, s, tt.body, tt.header)
+			if status != tt.status {
+				t.Fatalf("status = %d, want %d: %s", status, tt.status, body)
+			}
+			if tt.param != "" {
+				var e apiError
+				json.Unmarshal([]byte(body), &e)
+				if e.Details["param"] != tt.param {
+					t.Errorf("param = %v, want %s: %s", e.Details["param"], tt.param, body)
+				}
+			}
+			if n := storedCount(t, store); n != 0 {
+				t.Errorf("%d events stored from a rejected batch", n)
+			}
+		})
+	}
+}
+
+func TestIngest_Backpressure(t *testing.T) {
+	cfg := DefaultIngestConfig()
+	cfg.MaxInFlight = 1
+	s, store := newIngestTestServer(cfg)
+	body := bytes.Join(ingestEvents(t, 1), []byte{'\n'})
+	header := map[string]string{constants.HeaderIdempotencyKey: "batch-1"}
+
+	s.ingest.slots <- struct{}{} // another request is being stored
+	status, retryAfter, raw := postIngest(t, s, body, header)
+	if status != 429 || retryAfter != "1" {
+		t.Errorf("at capacity: status = %d, Retry-After = %q: %s", status, retryAfter, raw)
+	}
+	<-s.ingest.slots
+
+	store.err = errors.New("clickhouse: connection refused")
+	status, retryAfter, raw = postIngest(t, s, body, header)
+	if want := fmt.Sprint(int(constants.APIIngestStoreRetryAfter.Seconds())); status != 503 || retryAfter != want {
+		t.Errorf("store down: status = %d, Retry-After = %q: %s", status, retryAfter, raw)
+	}
+
+	// The failed attempt did not record the key, so the retry is stored.
+	store.err = nil
+	if status, _, raw = postIngest(t, s, body, header); status != 200 || !strings.Contains(raw, `"accepted":1`) {
+		t.Errorf("retry: status = %d: %s", status, raw)
+	}
+}
+
+func TestRecentKeys_EvictsOldest(t *testing.T) {
+	r := newRecentKeys(2)
+	for _, key := range []string{"a", "b", "c"} {
+		r.reserve(key)
+		r.commit(key)
+	}
+	if _, ok := r.set["a"]; ok || !r.set["b"] || !r.set["c"] {
+		t.Errorf("keys = %v, want b and c stored", r.set)
+	}
+}
+
+func TestRecentKeys_ReserveOnce(t *testing.T) {
+	r := newRecentKeys(4)
+	if claimed, _ := r.reserve("a"); !claimed {
+		t.Fatal("first reserve did not claim the key")
+	}
+	if claimed, stored := r.reserve("a"); claimed || stored {
+		t.Errorf("reserve while pending = %v, %v; want false, false", claimed, stored)
+	}
+	r.release("a")
+	if claimed, _ := r.reserve("a"); !claimed {
+		t.Fatal("released key was not claimable again")
+	}
+	r.commit("a")
+	r.release("a") // a no-op once committed
+	if claimed, stored := r.reserve("a"); claimed || !stored {
+		t.Errorf("reserve after commit = %v, %v; want false, true", claimed, stored)
+	}
+}
+
+// blockingStore is a Memory store whose inserts wait for release.
+type blockingStore struct {
+	*storage.Memory
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) InsertBatch(ctx context.Context, rows []storage.EventRow) error {
+	s.entered <- struct{}{}
+	<-s.release
+	return s.Memory.InsertBatch(ctx, rows)
+}
+
+func TestIngest_ConcurrentRetryNotStoredTwice(t *testing.T) {
+	s, _ := newIngestTestServer(DefaultIngestConfig())
+	store := &blockingStore{Memory: storage.NewMemory(), entered: make(chan struct{}), release: make(chan struct{})}
+	s.store = store
+	body := bytes.Join(ingestEvents(t, 1), []byte{'\n'})
+	header := map[string]string{constants.HeaderIdempotencyKey: "batch-1"}
+
+	first := make(chan int)
+	go func() {
+		status, _, _ := postIngest(t, s, body, header)
+		first <- status
+	}()
+	<-store.entered
+
+	// The same batch again while the first attempt is still storing it.
+	status, retryAfter, raw := postIngest(t, s, body, header)
+	if status != 429 || retryAfter != "1" {
+		t.Errorf("concurrent retry: status = %d, Retry-After = %q: %s", status, retryAfter, raw)
+	}
+	close(store.release)
+	if status := <-first; status != 200 {
+		t.Fatalf("first attempt: status = %d", status)
+	}
+	if n := storedCount(t, store.Memory); n != 1 {
+		t.Errorf("%d events stored, want 1", n)
+	}
+}
+
+func TestConfig_ValidateTokens(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Ingest.Enabled = true
+	if err := cfg.ValidateTokens(); err == nil {
+		t.Error("ingest enabled without tokens was accepted")
+	}
+	cfg.Tokens = map[string]string{"agent": "s3cret"}
+	if err := cfg.ValidateTokens(); err != nil {
+		t.Error(err)
+	}
+}
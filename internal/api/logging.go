@@ -0,0 +1,71 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+var (
+	requests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricAPIRequests,
+		Help: "API requests answered, by method, route pattern and status.",
+	}, constants.LabelsMethodRouteStatus)
+	requestLatency = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    constants.MetricAPIRequestLatency,
+		Help:    "Time taken to answer API requests, by method and route pattern.",
+		Buckets: constants.IOLatencyBuckets,
+	}, constants.LabelsMethodRoute)
+)
+
+// requestLogger returns the server's logger tagged with c's request ID.
+// Handlers log through it so their lines join up with the access log.
+func (s *Server) requestLogger(c *fiber.Ctx) *zap.Logger {
+	return s.logger.With(zap.String("request_id", requestID(c)))
+}
+
+// accessLog logs every request once it has been answered, and counts it
+// in the request metrics by route pattern (e.g. /api/v1/metrics/:type)
+// rather than path, so unknown paths can't grow their cardinality: no
+// route matches those, and they count under the last middleware's
+// pattern, such as / or /api/v1. Errors a
+// handler returned are rendered first, so the logged status is the one
+// sent. The query string is left out: it may carry an access_token.
+// bytes is -1 for a streamed body, whose size isn't known up front.
+// Strings are copied out of the request, which fasthttp reuses.
+func (s *Server) accessLog() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		if err := c.Next(); err != nil {
+			if herr := c.App().ErrorHandler(c, err); herr != nil {
+				c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}
+
+		latency := time.Since(start)
+		method, status := strings.Clone(c.Method()), c.Response().StatusCode()
+		route := strings.Clone(c.Route().Path)
+		requests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+		requestLatency.WithLabelValues(method, route).Observe(latency.Seconds())
+
+		bytes := -1
+		if c.Response().Header.ContentLength() >= 0 {
+			bytes = len(c.Response().Body())
+		}
+		s.logger.Info("Request",
+			zap.String("method", method),
+			zap.String("path", strings.Clone(c.Path())),
+			zap.Int("status", status),
+			zap.Duration("latency", latency),
+			zap.Int("bytes", bytes),
+			zap.String("request_id", requestID(c)))
+		return nil
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// newLoggingTestServer builds a Server with NewServer's request ID,
+// access log and recover middleware, logging to the returned observer.
+func newLoggingTestServer(store storage.Store) (*Server, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.InfoLevel)
+	s := &Server{store: store, views: newMemViewStore(), hub: newHub(), logger: zap.New(core)}
+	s.app = fiber.New(fiber.Config{ErrorHandler: s.handleError, DisableStartupMessage: true})
+	s.app.Use(requestIDMiddleware(), s.accessLog(), recover.New())
+	s.app.Get("/panic", func(*fiber.Ctx) error { panic("boom") })
+	s.registerRoutes()
+	return s, logs
+}
+
+// accessEntry returns the fields of the one access log line for id.
+func accessEntry(t *testing.T, logs *observer.ObservedLogs, id string) map[string]any {
+	t.Helper()
+	entries := logs.FilterMessage("Request").FilterField(zap.String("request_id", id)).All()
+	if len(entries) != 1 {
+		t.Fatalf("%d access log lines for %s, want 1: %v", len(entries), id, logs.All())
+	}
+	return entries[0].ContextMap()
+}
+
+func TestAccessLog_RequestIDRoundTrips(t *testing.T) {
+	s, logs := newLoggingTestServer(failingStore{})
+
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/healthz", 200},
+		{"/api/v1/events?limit=x", 400},
+		{"/api/v1/events", 500},
+		{"/panic", 500},
+	} {
+		id := "req" + tt.path
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set(fiber.HeaderXRequestID, id)
+		resp, err := s.app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := resp.Header.Get(fiber.HeaderXRequestID); got != id {
+			t.Errorf("%s: X-Request-ID = %q, want %q", tt.path, got, id)
+		}
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s: status = %d, want %d", tt.path, resp.StatusCode, tt.status)
+		}
+		fields := accessEntry(t, logs, id)
+		if fields["method"] != "GET" || fields["status"] != int64(tt.status) || fields["bytes"].(int64) <= 0 {
+			t.Errorf("%s: access log = %v", tt.path, fields)
+		}
+		if want, _, _ := strings.Cut(tt.path, "?"); fields["path"] != want {
+			t.Errorf("%s: logged path %v, want %s without the query", tt.path, fields["path"], want)
+		}
+	}
+
+	// The handler's own error line carries the same ID as the access log.
+	if n := logs.FilterMessage("Events query failed").FilterField(zap.String("request_id", "req/api/v1/events")).Len(); n != 1 {
+		t.Errorf("%d handler error lines with the request ID, want 1: %v", n, logs.All())
+	}
+}
+
+func TestAccessLog_GeneratesRequestID(t *testing.T) {
+	s, logs := newLoggingTestServer(failingStore{})
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/healthz", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := resp.Header.Get(fiber.HeaderXRequestID)
+	if id == "" {
+		t.Fatal("no X-Request-ID on the response")
+	}
+	accessEntry(t, logs, id)
+}
+
+func TestAccessLog_CountsRequestsByRoute(t *testing.T) {
+	s, _ := newLoggingTestServer(storage.NewMemory())
+	s.cache = cache.Disabled{}
+	ok := requests.WithLabelValues("GET", "/api/v1/metrics/:type", "200")
+	bad := requests.WithLabelValues("GET", "/api/v1/metrics/:type", "400")
+	okBefore, badBefore := testutil.ToFloat64(ok), testutil.ToFloat64(bad)
+
+	for _, path := range []string{"/api/v1/metrics/tcp", "/api/v1/metrics/dns", "/api/v1/metrics/tcp?window=forever"} {
+		if _, err := s.app.Test(httptest.NewRequest("GET", path, nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := testutil.ToFloat64(ok) - okBefore; got != 2 {
+		t.Errorf("200s counted = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(bad) - badBefore; got != 1 {
+		t.Errorf("400s counted = %v, want 1", got)
+	}
+	if n := testutil.CollectAndCount(requestLatency, constants.MetricAPIRequestLatency); n == 0 {
+		t.Error("no request latency series")
+	}
+}
+
+// TestNewServer_Twice builds two servers in one process, as the tests and
+// standalone mode do: request metrics are registered once per process, so
+// this must not panic, and both servers count into the same series.
+func TestNewServer_Twice(t *testing.T) {
+	health := requests.WithLabelValues("GET", "/healthz", "200")
+	before := testutil.ToFloat64(health)
+	for range 2 {
+		s := NewServer(DefaultConfig(), storage.NewMemory(), cache.Disabled{}, zap.NewNop())
+		if _, err := s.app.Test(httptest.NewRequest("GET", "/healthz", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := testutil.ToFloat64(health) - before; got != 2 {
+		t.Errorf("health checks counted = %v, want 2", got)
+	}
+}
+
+func TestWS_ConnectionIDLogged(t *testing.T) {
+	s, logs := newLoggingTestServer(failingStore{})
+	addr := startWSTestServer(t, s)
+
+	conn, _, err := fws.DefaultDialer.Dial("ws://"+addr+"/ws/events?type=dns",
+		http.Header{fiber.HeaderXRequestID: {"ws-1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitClients(t, s.hub, 1)
+	conn.Close()
+	waitClients(t, s.hub, 0)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for logs.FilterMessage("WebSocket closed").Len() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, msg := range []string{"WebSocket opened", "WebSocket closed"} {
+		if n := logs.FilterMessage(msg).FilterField(zap.String("conn_id", "ws-1")).Len(); n != 1 {
+			t.Errorf("%d %q lines with conn_id ws-1, want 1: %v", n, msg, logs.All())
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// podRecentTypes are the event types /pods/{namespace}/{pod} lists the
+// latest events of: the rare ones worth reading one by one.
+var podRecentTypes = []string{constants.ModuleOOM, constants.ModuleExec, constants.ModuleDrop}
+
+// podLatency is one event type's latency quantiles, in seconds.
+type podLatency struct {
+	P50 float64 `json:"p50"`
+	P99 float64 `json:"p99"`
+}
+
+// podDomain is one DNS domain a pod queried.
+type podDomain struct {
+	Domain string `json:"domain"`
+	Count  uint64 `json:"count"`
+}
+
+// podSection is one query of a pod response, filling its part.
+type podSection struct {
+	name string
+	run  func(ctx context.Context) error
+}
+
+// podWarning names a section of a pod response whose query failed.
+type podWarning struct {
+	Section string `json:"section"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// handlePod returns everything recorded about one pod over the last
+// window (default 1h): event counts by type, TCP and file I/O latency,
+// its latest OOM, exec and drop events, and the DNS domains it queried.
+// The sections are queried concurrently; a response whose sections
+// partly failed names them in warnings and isn't cached. Only when every
+// section fails is the request an error.
+func (s *Server) handlePod(c *fiber.Ctx) error {
+	ns, pod := c.Params("namespace"), c.Params("pod")
+	namespaces, err := scopeNamespaces(c, ns)
+	if err != nil {
+		return forbidden(c, err.Error())
+	}
+	window, span, err := queryWindow(c)
+	if err != nil {
+		return badRequest(c, err)
+	}
+
+	log := s.requestLogger(c)
+	return s.respondCached(c, "pod:"+ns+"/"+pod+":"+window, func(ctx context.Context) ([]byte, error) {
+		until := time.Now()
+		since := until.Add(-span)
+		q := storage.EventQuery{Namespace: ns, Pod: pod, Namespaces: namespaces, Since: since, Until: until}
+
+		var (
+			stats   []storage.TypeStat
+			recent  = make([][]eventRecord, len(podRecentTypes))
+			domains []storage.LabelCount
+		)
+		sections := []podSection{
+			{"counts", func(ctx context.Context) (err error) {
+				stats, err = s.store.TypeStats(ctx, q)
+				return err
+			}},
+			{"dns_domains", func(ctx context.Context) (err error) {
+				dq := q
+				dq.Type = constants.ModuleDNS
+				domains, err = s.store.LabelValues(ctx, dq, constants.KeyDomain, constants.APIPodMaxDomains)
+				return err
+			}},
+		}
+		for i, typ := range podRecentTypes {
+			sections = append(sections, podSection{"recent." + typ, func(ctx context.Context) (err error) {
+				rq := q
+				rq.Type, rq.Limit = typ, constants.APIPodRecentEvents
+				recent[i], err = s.recentEvents(ctx, rq)
+				return err
+			}})
+		}
+
+		errs := make([]error, len(sections))
+		var g errgroup.Group
+		for i, sec := range sections {
+			g.Go(func() error {
+				errs[i] = sec.run(ctx)
+				return nil
+			})
+		}
+		g.Wait()
+
+		warnings := make([]podWarning, 0)
+		for i, err := range errs {
+			if err == nil {
+				continue
+			}
+			log.Warn("Pod query failed", zap.String("section", sections[i].name), zap.Error(err))
+			w := podWarning{Section: sections[i].name, Code: codeInternal, Message: "query failed"}
+			if storage.IsTimeout(err) {
+				w.Code, w.Message = codeTimeout, "query timed out"
+			}
+			warnings = append(warnings, w)
+		}
+		if len(warnings) == len(sections) {
+			return nil, errs[0]
+		}
+
+		counts := make(map[string]uint64, len(stats))
+		latency := make(map[string]podLatency)
+		for _, t := range stats {
+			counts[t.Type] = t.Count
+			if latencyEventTypes[t.Type] {
+				latency[t.Type] = podLatency{P50: t.P50Latency, P99: t.P99Latency}
+			}
+		}
+		recentByType := make(map[string][]eventRecord, len(podRecentTypes))
+		for i, typ := range podRecentTypes {
+			if recent[i] != nil {
+				recentByType[typ] = recent[i]
+			}
+		}
+		dns := make([]podDomain, 0, len(domains))
+		for _, d := range domains {
+			dns = append(dns, podDomain{Domain: d.Value, Count: d.Count})
+		}
+
+		data, err := json.Marshal(fiber.Map{
+			"namespace":   ns,
+			"pod":         pod,
+			"window":      window,
+			"range":       rangeJSON(since, until),
+			"counts":      counts,
+			"latency":     latency,
+			"recent":      recentByType,
+			"dns_domains": dns,
+			"warnings":    warnings,
+		})
+		if err == nil && len(warnings) > 0 {
+			err = errPartial
+		}
+		return data, err
+	})
+}
+
+// recentEvents reads the events matching q, skipping rows that fail to
+// decode. It never returns nil without an error.
+func (s *Server) recentEvents(ctx context.Context, q storage.EventQuery) ([]eventRecord, error) {
+	rows, err := s.store.ListEvents(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]eventRecord, 0, q.Limit)
+	for rows.Next() {
+		if e, err := rows.Event(); err == nil {
+			events = append(events, newEventRecord(e))
+		}
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+type podResponse struct {
+	Counts     map[string]uint64        `json:"counts"`
+	Latency    map[string]podLatency    `json:"latency"`
+	Recent     map[string][]eventRecord `json:"recent"`
+	DNSDomains []podDomain              `json:"dns_domains"`
+	Warnings   []podWarning             `json:"warnings"`
+}
+
+// seedPod adds web-0's DNS queries, execs and a drop to s's store.
+func seedPod(t *testing.T, s *Server) {
+	t.Helper()
+	now := time.Now().Add(-time.Minute)
+	var rows []storage.EventRow
+	add := func(n int, typ string, labels map[string]string) {
+		for range n {
+			rows = append(rows, storage.EventRow{
+				EventID: uint64(1000 + len(rows)), Timestamp: now.Add(time.Duration(len(rows)) * time.Millisecond),
+				Type: typ, Namespace: "prod", Pod: "web-0", Labels: labels,
+			})
+		}
+	}
+	add(3, constants.ModuleDNS, map[string]string{constants.KeyDomain: "api.example.com"})
+	add(1, constants.ModuleDNS, map[string]string{constants.KeyDomain: "db.example.com"})
+	add(25, constants.ModuleExec, nil)
+	add(1, constants.ModuleDrop, nil)
+	if err := s.store.InsertBatch(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPod_AllSections(t *testing.T) {
+	s := newHandlersTestServer(t)
+	seedPod(t, s)
+
+	var r podResponse
+	get(t, s, "/api/v1/pods/prod/web-0", &r)
+	want := map[string]uint64{constants.ModuleTCP: 30, constants.ModuleDNS: 24, constants.ModuleExec: 25, constants.ModuleDrop: 1}
+	if len(r.Counts) != len(want) {
+		t.Errorf("counts = %v, want %v", r.Counts, want)
+	}
+	for typ, n := range want {
+		if r.Counts[typ] != n {
+			t.Errorf("counts[%s] = %d, want %d", typ, r.Counts[typ], n)
+		}
+	}
+	if l, ok := r.Latency[constants.ModuleTCP]; !ok || l.P50 != 0.010 || l.P99 != 0.010 || len(r.Latency) != 1 {
+		t.Errorf("latency = %+v, want tcp's 10ms only", r.Latency)
+	}
+	if n := len(r.Recent[constants.ModuleExec]); n != constants.APIPodRecentEvents {
+		t.Errorf("%d recent execs, want %d", n, constants.APIPodRecentEvents)
+	}
+	if execs := r.Recent[constants.ModuleExec]; len(execs) > 1 && execs[0].Timestamp.Before(execs[1].Timestamp) {
+		t.Error("recent execs aren't newest first")
+	}
+	if len(r.Recent[constants.ModuleDrop]) != 1 || len(r.Recent[constants.ModuleOOM]) != 0 {
+		t.Errorf("recent = %+v, want one drop and no OOMs", r.Recent)
+	}
+	if len(r.DNSDomains) != 2 || r.DNSDomains[0] != (podDomain{"api.example.com", 3}) {
+		t.Errorf("dns_domains = %+v, want api.example.com first", r.DNSDomains)
+	}
+	if len(r.Warnings) != 0 {
+		t.Errorf("warnings = %+v", r.Warnings)
+	}
+
+	// Another pod's events, and events outside the window, don't count.
+	var web1 podResponse
+	get(t, s, "/api/v1/pods/prod/web-1?window=2m", &web1)
+	if len(web1.Counts) != 0 {
+		t.Errorf("web-1 in the last 2m: counts = %v, want none", web1.Counts)
+	}
+}
+
+// domainsDownStore is a Memory store whose label queries fail.
+type domainsDownStore struct {
+	*storage.Memory
+}
+
+func (domainsDownStore) LabelValues(context.Context, storage.EventQuery, string, int) ([]storage.LabelCount, error) {
+	return nil, errors.New("clickhouse: connection reset")
+}
+
+func TestPod_PartialFailure(t *testing.T) {
+	s := newHandlersTestServer(t)
+	seedPod(t, s)
+	s.store = domainsDownStore{s.store.(*storage.Memory)}
+	s.cache = &memCache{data: map[string]string{}}
+
+	for range 2 {
+		resp, err := s.app.Test(httptest.NewRequest("GET", "/api/v1/pods/prod/web-0", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != 200 || resp.Header.Get("X-Cache") != "MISS" {
+			t.Errorf("status = %d, X-Cache = %q, want an uncached 200", resp.StatusCode, resp.Header.Get("X-Cache"))
+		}
+	}
+	var r podResponse
+	get(t, s, "/api/v1/pods/prod/web-0", &r)
+	if len(r.Warnings) != 1 || r.Warnings[0] != (podWarning{"dns_domains", codeInternal, "query failed"}) {
+		t.Errorf("warnings = %+v, want dns_domains", r.Warnings)
+	}
+	if r.Counts[constants.ModuleExec] != 25 || len(r.DNSDomains) != 0 {
+		t.Errorf("counts = %v, dns_domains = %v", r.Counts, r.DNSDomains)
+	}
+}
+
+func TestPod_Errors(t *testing.T) {
+	s := &Server{store: failingStore{}, cache: cache.Disabled{}, views: newMemViewStore(), logger: zap.NewNop()}
+	s.app = fiber.New(fiber.Config{ErrorHandler: s.handleError})
+	s.registerRoutes()
+	if resp, body := doJSON(t, s, "GET", "/api/v1/pods/prod/web-0", "", nil); resp.StatusCode != 500 {
+		t.Errorf("every section failing = %d %v, want 500", resp.StatusCode, body)
+	}
+	if resp, body := doJSON(t, s, "GET", "/api/v1/pods/prod/web-0?window=0s", "", nil); resp.StatusCode != 400 || errParam(body) != "window" {
+		t.Errorf("window=0s = %d %v, want 400", resp.StatusCode, body)
+	}
+
+	scoped := newScopeTestServer(t)
+	if status, raw := getAs(t, scoped, "tok-batch", "/api/v1/pods/prod/web-0"); status != 403 {
+		t.Errorf("pod outside the token's scope = %d: %s", status, raw)
+	}
+	var r podResponse
+	getJSONAs(t, scoped, "tok-batch", "/api/v1/pods/staging/batch-0", &r)
+	if r.Counts[constants.ModuleOOM] != 1 || len(r.Recent[constants.ModuleOOM]) != 1 {
+		t.Errorf("batch-0 = %+v, want its OOM", r)
+	}
+}
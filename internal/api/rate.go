@@ -0,0 +1,93 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// handleEventRate returns events per type in step-wide buckets (default
+// 1m) over the last window (default 1h), for dashboard sparklines. Every
+// type gets a count for every bucket, zero included. Long windows with
+// whole-minute steps are served from the per-minute rollup.
+func (s *Server) handleEventRate(c *fiber.Ctx) error {
+	window, span, err := queryWindow(c)
+	if err != nil {
+		return badRequest(c, err)
+	}
+	stepQ := c.Query("step", "1m")
+	step, err := parseStep(stepQ, span)
+	if err != nil {
+		return badRequest(c, err)
+	}
+
+	namespaces := requestNamespaces(c)
+	return s.respondCached(c, "event_rate:"+window+":"+stepQ, func(ctx context.Context) ([]byte, error) {
+		until := time.Now()
+		since := until.Add(-span)
+		rollup := useRollup(span) && rollupStep(step)
+		points, err := s.store.EventRate(ctx, storage.RateQuery{
+			Step:   step,
+			Window: storage.Window{Since: since, Until: until, Rollup: rollup, Namespaces: namespaces},
+		})
+		if err != nil {
+			return nil, err
+		}
+		buckets, types := rateSeries(points, since, until, step)
+		return json.Marshal(fiber.Map{
+			"window":  window,
+			"step":    stepQ,
+			"source":  querySource(rollup),
+			"range":   rangeJSON(since, until),
+			"buckets": buckets,
+			"types":   types,
+		})
+	})
+}
+
+// parseStep parses the step parameter: a whole number of seconds, at
+// most span, giving at most constants.APIRateMaxBuckets buckets over it.
+func parseStep(s string, span time.Duration) (time.Duration, error) {
+	step, err := parseRange(s)
+	switch {
+	case err != nil:
+		return 0, &paramError{"step", err.Error()}
+	case step%time.Second != 0:
+		return 0, &paramError{"step", "must be a whole number of seconds"}
+	case step > span:
+		return 0, &paramError{"step", "must not exceed the window"}
+	}
+	// A window not aligned to the step straddles one more bucket.
+	if n := (span+step-1)/step + 1; n > constants.APIRateMaxBuckets {
+		return 0, &paramError{"step", fmt.Sprintf("gives %d buckets over the window, more than %d", n, constants.APIRateMaxBuckets)}
+	}
+	return step, nil
+}
+
+// rateSeries lays EventRate points out as the start of every bucket
+// overlapping [since, until) and, per type, a count for each of them.
+func rateSeries(points []storage.RatePoint, since, until time.Time, step time.Duration) ([]time.Time, map[string][]uint64) {
+	first := since.UnixMilli() - since.UnixMilli()%step.Milliseconds()
+	buckets := make([]time.Time, 0)
+	for ms := first; ms < until.UnixMilli(); ms += step.Milliseconds() {
+		buckets = append(buckets, time.UnixMilli(ms).UTC())
+	}
+	types := make(map[string][]uint64)
+	for _, p := range points {
+		i := int((p.Bucket.UnixMilli() - first) / step.Milliseconds())
+		if i < 0 || i >= len(buckets) {
+			continue
+		}
+		if types[p.Type] == nil {
+			types[p.Type] = make([]uint64, len(buckets))
+		}
+		types[p.Type][i] += p.Count
+	}
+	return buckets, types
+}
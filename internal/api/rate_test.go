@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+type rateResponse struct {
+	Step    string              `json:"step"`
+	Source  string              `json:"source"`
+	Buckets []time.Time         `json:"buckets"`
+	Types   map[string][]uint64 `json:"types"`
+}
+
+func TestEventRate_Buckets(t *testing.T) {
+	s := newHandlersTestServer(t)
+	boundary := time.Now().Add(-10 * time.Minute).Truncate(time.Minute)
+	var rows []storage.EventRow
+	for i, at := range []time.Duration{-time.Millisecond, 0, time.Minute - time.Millisecond, time.Minute} {
+		rows = append(rows, storage.EventRow{
+			EventID: uint64(100 + i), Timestamp: boundary.Add(at), Type: constants.ModuleDrop, Namespace: "prod",
+		})
+	}
+	if err := s.store.InsertBatch(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+
+	var r rateResponse
+	get(t, s, "/api/v1/events/rate?window=15m&step=1m", &r)
+	if r.Step != "1m" || r.Source != "raw" || len(r.Buckets) < 15 || len(r.Buckets) > 16 {
+		t.Fatalf("step=%s source=%s with %d buckets", r.Step, r.Source, len(r.Buckets))
+	}
+	at := -1
+	for i, b := range r.Buckets {
+		if i > 0 && b.Sub(r.Buckets[i-1]) != time.Minute {
+			t.Fatalf("buckets %s and %s aren't a minute apart", r.Buckets[i-1], b)
+		}
+		if b.Equal(boundary) {
+			at = i
+		}
+	}
+	if at < 1 {
+		t.Fatalf("no bucket at %s in %v", boundary, r.Buckets)
+	}
+	drops := r.Types[constants.ModuleDrop]
+	if len(drops) != len(r.Buckets) || drops[at-1] != 1 || drops[at] != 2 || drops[at+1] != 1 {
+		t.Errorf("drop counts = %v, want 1, 2, 1 from bucket %d", drops, at-1)
+	}
+	var tcp uint64
+	for _, n := range r.Types[constants.ModuleTCP] {
+		tcp += n
+	}
+	if tcp != 40 || len(r.Types) != 4 {
+		t.Errorf("tcp total = %d over types %v, want 40 of 4 types", tcp, r.Types)
+	}
+
+	get(t, s, "/api/v1/events/rate?window=1d&step=1h", &r)
+	if r.Source != "rollup" || len(r.Buckets) != 25 {
+		t.Errorf("1d by 1h: source=%s with %d buckets, want the rollup's 25", r.Source, len(r.Buckets))
+	}
+	get(t, s, "/api/v1/events/rate?window=1d&step=90s", &r)
+	if r.Source != "raw" {
+		t.Errorf("1d by 90s: source=%s, want raw", r.Source)
+	}
+}
+
+func TestEventRate_Validation(t *testing.T) {
+	s := newViewsTestServer()
+	for _, tt := range []struct {
+		query, param string
+	}{
+		{"window=bogus", "window"},
+		{"step=bogus", "step"},
+		{"step=-1m", "step"},
+		{"step=1500ms", "step"},
+		{"window=1h&step=2h", "step"},
+		{"window=1d&step=30s", "step"},
+		{"window=366d&step=1m", "step"},
+	} {
+		resp, body := doJSON(t, s, "GET", "/api/v1/events/rate?"+tt.query, "", nil)
+		if resp.StatusCode != 400 || errParam(body) != tt.param {
+			t.Errorf("%q: status=%d body=%v, want 400 on %s", tt.query, resp.StatusCode, body, tt.param)
+		}
+	}
+}
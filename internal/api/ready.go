@@ -0,0 +1,71 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// dependency is a backend /readyz checks. A failing critical dependency
+// makes the API unready; a failing non-critical one only degrades it.
+type dependency struct {
+	name     string
+	critical bool
+	ping     func(ctx context.Context) error
+}
+
+// dependencyStatus is one dependency's entry in the /readyz body.
+type dependencyStatus struct {
+	Status    string  `json:"status"` // "ok" or "error"
+	Critical  bool    `json:"critical"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// handleReadyz pings every dependency concurrently and reports each one.
+// Status is "ready", "degraded" (a non-critical dependency is down; still
+// 200) or "unavailable" (a critical one is down; 503).
+func (s *Server) handleReadyz(c *fiber.Ctx) error {
+	checks := make(map[string]dependencyStatus, len(s.deps))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, d := range s.deps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), constants.APIReadyTimeout)
+			defer cancel()
+
+			start := time.Now()
+			err := d.ping(ctx)
+			st := dependencyStatus{
+				Status:    "ok",
+				Critical:  d.critical,
+				LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+			}
+			if err != nil {
+				st.Status, st.Error = "error", err.Error()
+			}
+			mu.Lock()
+			checks[d.name] = st
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	status, code := "ready", fiber.StatusOK
+	for _, st := range checks {
+		switch {
+		case st.Status == "ok":
+		case st.Critical:
+			status, code = "unavailable", fiber.StatusServiceUnavailable
+		case status == "ready":
+			status = "degraded"
+		}
+	}
+	return c.Status(code).JSON(fiber.Map{"status": status, "checks": checks})
+}
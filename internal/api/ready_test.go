@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+func stubPing(err error) func(context.Context) error {
+	return func(context.Context) error { return err }
+}
+
+func newReadyTestServer(chErr, redisErr error) *Server {
+	s := &Server{
+		cfg:    Config{Tokens: map[string]string{"alice": "s3cret"}}, // readiness must bypass auth
+		app:    fiber.New(),
+		views:  newMemViewStore(),
+		logger: zap.NewNop(),
+		deps: []dependency{
+			{name: "clickhouse", critical: true, ping: stubPing(chErr)},
+			{name: "redis", critical: false, ping: stubPing(redisErr)},
+		},
+	}
+	s.registerRoutes()
+	return s
+}
+
+func TestReadyz(t *testing.T) {
+	down := errors.New("connection refused")
+	tests := []struct {
+		name            string
+		chErr, redisErr error
+		wantCode        int
+		wantStatus      string
+	}{
+		{"all up", nil, nil, 200, "ready"},
+		{"redis down degrades", nil, down, 200, "degraded"},
+		{"clickhouse down", down, nil, 503, "unavailable"},
+		{"both down", down, down, 503, "unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newReadyTestServer(tt.chErr, tt.redisErr)
+			resp, body := doJSON(t, s, "GET", "/readyz", "", nil)
+			if resp.StatusCode != tt.wantCode || body["status"] != tt.wantStatus {
+				t.Fatalf("got %d %v, want %d %s", resp.StatusCode, body["status"], tt.wantCode, tt.wantStatus)
+			}
+
+			checks := body["checks"].(map[string]any)
+			for name, err := range map[string]error{"clickhouse": tt.chErr, "redis": tt.redisErr} {
+				check := checks[name].(map[string]any)
+				want := "ok"
+				if err != nil {
+					want = "error"
+					if check["error"] != err.Error() {
+						t.Errorf("%s error = %v, want %q", name, check["error"], err)
+					}
+				}
+				if check["status"] != want {
+					t.Errorf("%s status = %v, want %s", name, check["status"], want)
+				}
+			}
+		})
+	}
+}
+
+func TestReadyz_PingsTimeOut(t *testing.T) {
+	s := newReadyTestServer(nil, nil)
+	s.deps[0].ping = func(ctx context.Context) error {
+		<-ctx.Done() // a hung backend
+		return ctx.Err()
+	}
+
+	start := time.Now()
+	resp, err := s.app.Test(httptest.NewRequest("GET", "/readyz", nil), -1) // outlives fiber's 1s test timeout
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 503 {
+		t.Fatalf("status = %d, want 503", resp.StatusCode)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("readyz took %s; ping timeout not applied", elapsed)
+	}
+}
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// querySource names the table a metric response was read from.
+func querySource(rollup bool) string {
+	if rollup {
+		return "rollup"
+	}
+	return "raw"
+}
+
+// useRollup reports whether a span is long enough that the per-minute
+// rollup beats scanning raw events.
+func useRollup(span time.Duration) bool {
+	return span > constants.APIRollupThreshold
+}
+
+// groupByDimensions are the values the group_by parameter accepts.
+var groupByDimensions = map[string]bool{
+	storage.GroupNamespace: true,
+	storage.GroupPod:       true,
+	storage.GroupNode:      true,
+}
+
+// rollupGroupable reports whether the rollup can serve a group_by; it is
+// keyed by namespace only.
+func rollupGroupable(groupBy string) bool {
+	return groupBy == "" || groupBy == storage.GroupNamespace
+}
+
+// rollupStep reports whether the rollup can serve a /events/rate step;
+// its rows are whole minutes.
+func rollupStep(step time.Duration) bool {
+	return step%time.Minute == 0
+}
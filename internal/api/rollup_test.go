@@ -0,0 +1,83 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+func TestUseRollup(t *testing.T) {
+	for window, want := range map[string]bool{"1h": false, "6h": false, "7h": true, "2d": true, "90m": false} {
+		span, err := parseRange(window)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := useRollup(span); got != want {
+			t.Errorf("useRollup(%q) = %v, want %v", window, got, want)
+		}
+	}
+}
+
+// openTestClickHouse connects to the disposable ClickHouse named by
+// KUBEPULSE_TEST_CLICKHOUSE_DSN and migrates it, skipping the test when
+// the variable is unset. The connection is closed on cleanup.
+func openTestClickHouse(t *testing.T) *storage.ClickHouse {
+	t.Helper()
+	dsn := os.Getenv("KUBEPULSE_TEST_CLICKHOUSE_DSN")
+	if dsn == "" {
+		t.Skip("KUBEPULSE_TEST_CLICKHOUSE_DSN not set")
+	}
+
+	cfg := storage.DefaultClickHouseConfig()
+	cfg.DSN = dsn
+	ch, err := storage.NewClickHouse(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ch.Close() })
+	if err := ch.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+func TestGroupedSeries_OrdersByVolumeOtherLast(t *testing.T) {
+	m0, m1 := time.Unix(0, 0), time.Unix(60, 0)
+	series := groupedSeries([]storage.SeriesPoint{
+		{Group: "other", Minute: m0, Count: 500},
+		{Group: "prod", Minute: m0, Count: 10},
+		{Group: "prod", Minute: m1, Count: 15},
+		{Group: "staging", Minute: m0, Count: 40},
+	})
+
+	var keys []string
+	for _, g := range series {
+		keys = append(keys, g.Key)
+	}
+	if fmt.Sprint(keys) != "[staging prod other]" {
+		t.Fatalf("keys = %v, want [staging prod other]", keys)
+	}
+	if len(series[1].Points) != 2 || series[1].Points[1].Count != 15 || !series[1].Points[1].Time.Equal(m1) {
+		t.Errorf("prod points = %+v", series[1].Points)
+	}
+}
+
+func TestMetricsByType_GroupByValidation(t *testing.T) {
+	s := newViewsTestServer()
+	for _, tt := range []struct{ query, param string }{
+		{"group_by=container", "group_by"},
+		{"group_by=pod&groups=0", "groups"},
+		{"group_by=pod&groups=51", "groups"},
+	} {
+		resp, body := doJSON(t, s, "GET", "/api/v1/metrics/tcp?"+tt.query, "", nil)
+		if resp.StatusCode != 400 || errParam(body) != tt.param {
+			t.Errorf("%q: status=%d body=%v, want 400 on %s", tt.query, resp.StatusCode, body, tt.param)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// localsNamespaces is the fiber.Ctx Locals key holding the sorted
+// namespaces a namespace-scoped token may read. Unscoped tokens leave it
+// unset.
+const localsNamespaces = "namespaces"
+
+// scopeError is a request for a namespace outside its token's scope.
+type scopeError struct {
+	namespace string
+}
+
+func (e *scopeError) Error() string {
+	return fmt.Sprintf("namespace %q is outside this token's scope", e.namespace)
+}
+
+// requestNamespaces returns the namespaces c's token may read, nil when
+// it may read every namespace. Every store query and live stream for c is
+// limited to them.
+func requestNamespaces(c *fiber.Ctx) []string {
+	namespaces, _ := c.Locals(localsNamespaces).([]string)
+	return namespaces
+}
+
+// scopeNamespaces is the check every handler reading events makes before
+// it queries: it returns the namespaces to limit the query to, as
+// requestNamespaces does, or a *scopeError when the request explicitly
+// asks for a namespace (non-empty) the token may not read.
+func scopeNamespaces(c *fiber.Ctx, asked string) ([]string, error) {
+	namespaces := requestNamespaces(c)
+	if namespaces != nil && asked != "" && !slices.Contains(namespaces, asked) {
+		return nil, &scopeError{asked}
+	}
+	return namespaces, nil
+}
+
+// scopeCacheKey returns the suffix that keeps c's cached responses apart
+// from those of tokens with another scope.
+func scopeCacheKey(c *fiber.Ctx) string {
+	namespaces := requestNamespaces(c)
+	if namespaces == nil {
+		return ""
+	}
+	return ":ns=" + strings.Join(namespaces, ",")
+}
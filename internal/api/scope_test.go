@@ -0,0 +1,277 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	fws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// newScopeTestServer is newHandlersTestServer behind three tokens: admin
+// reads everything, prod-team only prod, and batch-team staging and batch.
+func newScopeTestServer(t *testing.T) *Server {
+	t.Helper()
+	s := newHandlersTestServer(t)
+	s.app = fiber.New(fiber.Config{DisableStartupMessage: true})
+	s.cfg = Config{
+		Tokens: map[string]string{"admin": "tok-admin", "prod-team": "tok-prod", "batch-team": "tok-batch"},
+		TokenNamespaces: map[string][]string{
+			"prod-team":  {"prod"},
+			"batch-team": {"staging", "batch"},
+		},
+	}
+	s.registerRoutes()
+	return s
+}
+
+// getAs requests path with token and returns the status and body.
+func getAs(t *testing.T, s *Server, token, path string) (int, []byte) {
+	t.Helper()
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := s.app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, raw
+}
+
+// getJSONAs is getAs for a 200 JSON response, decoded into out.
+func getJSONAs(t *testing.T, s *Server, token, path string, out any) {
+	t.Helper()
+	status, raw := getAs(t, s, token, path)
+	if status != 200 {
+		t.Fatalf("GET %s = %d: %s", path, status, raw)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		t.Fatalf("GET %s: %v: %s", path, err, raw)
+	}
+}
+
+func TestScope_Events(t *testing.T) {
+	s := newScopeTestServer(t)
+	tests := []struct {
+		token, query string
+		want         int
+	}{
+		{"tok-admin", "", 61},
+		{"tok-admin", "namespace=staging", 1},
+		{"tok-prod", "", 60},
+		{"tok-prod", "namespace=prod&type=dns", 20},
+		{"tok-batch", "", 1},
+		{"tok-batch", "namespace=batch", 0},
+	}
+	for _, tt := range tests {
+		var page eventsPage
+		getJSONAs(t, s, tt.token, "/api/v1/events?limit=100&"+tt.query, &page)
+		if len(page.Events) != tt.want {
+			t.Errorf("%s %q: %d events, want %d", tt.token, tt.query, len(page.Events), tt.want)
+		}
+	}
+
+	status, raw := getAs(t, s, "tok-batch", "/api/v1/events?format=ndjson")
+	if status != 200 || strings.Count(string(raw), "\n") != 1 || !strings.Contains(string(raw), `"namespace":"staging"`) {
+		t.Errorf("ndjson export = %d: %s", status, raw)
+	}
+}
+
+func TestScope_ForbidsOtherNamespaces(t *testing.T) {
+	s := newScopeTestServer(t)
+	for _, path := range []string{
+		"/api/v1/events?namespace=prod",
+		"/api/v1/events?namespace=prod&format=csv",
+	} {
+		status, raw := getAs(t, s, "tok-batch", path)
+		var body apiError
+		json.Unmarshal(raw, &body)
+		if status != 403 || body.Code != codeForbidden {
+			t.Errorf("GET %s = %d: %s, want a 403", path, status, raw)
+		}
+	}
+
+	// Nor can a scoped token save a view of another namespace, or run
+	// one by overriding its namespace.
+	req := httptest.NewRequest("POST", "/api/v1/views", strings.NewReader(`{"name":"prod","filters":{"namespace":"prod"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer tok-batch")
+	if resp, _ := s.app.Test(req); resp.StatusCode != 403 {
+		t.Errorf("creating a view of prod = %d, want 403", resp.StatusCode)
+	}
+	v := savedView{ID: "v1", Name: "mine", Owner: "batch-team", Filters: eventFilter{Type: constants.ModuleOOM}}
+	s.views.Create(t.Context(), v, constants.APIMaxViewsPerOwner)
+	if status, raw := getAs(t, s, "tok-batch", "/api/v1/views/v1/events?namespace=prod"); status != 403 {
+		t.Errorf("view overridden to prod = %d: %s", status, raw)
+	}
+	var page eventsPage
+	getJSONAs(t, s, "tok-batch", "/api/v1/views/v1/events", &page)
+	if len(page.Events) != 1 {
+		t.Errorf("view: %d events, want 1", len(page.Events))
+	}
+}
+
+func TestScope_Aggregates(t *testing.T) {
+	s := newScopeTestServer(t)
+
+	var types struct {
+		Types []struct {
+			Type  string `json:"type"`
+			Count uint64 `json:"count"`
+		} `json:"types"`
+	}
+	getJSONAs(t, s, "tok-batch", "/api/v1/events/types", &types)
+	if len(types.Types) != 1 || types.Types[0].Type != constants.ModuleOOM {
+		t.Errorf("types = %+v, want only oom", types.Types)
+	}
+
+	var rate rateResponse
+	getJSONAs(t, s, "tok-batch", "/api/v1/events/rate", &rate)
+	if len(rate.Types) != 1 || rate.Types[constants.ModuleOOM] == nil {
+		t.Errorf("rate types = %v, want only oom", rate.Types)
+	}
+
+	var overview struct {
+		Total uint64                   `json:"total_events"`
+		TCP   uint64                   `json:"tcp_events"`
+		Top   map[string][]topOffender `json:"top_offenders"`
+	}
+	getJSONAs(t, s, "tok-batch", "/api/v1/metrics/overview", &overview)
+	if overview.Total != 1 || overview.TCP != 0 || len(overview.Top) != 1 || overview.Top[constants.ModuleOOM][0].Pod != "batch-0" {
+		t.Errorf("overview = %+v, want staging's one oom", overview)
+	}
+
+	var series struct {
+		Series []groupSeries `json:"series"`
+	}
+	getJSONAs(t, s, "tok-batch", "/api/v1/metrics/tcp?group_by=namespace", &series)
+	if len(series.Series) != 0 {
+		t.Errorf("tcp series = %+v, want none outside prod", series.Series)
+	}
+	getJSONAs(t, s, "tok-prod", "/api/v1/metrics/oom?group_by=namespace", &series)
+	if len(series.Series) != 0 {
+		t.Errorf("oom series = %+v, want none in prod", series.Series)
+	}
+
+	var top struct {
+		Top []topOffender `json:"top"`
+	}
+	getJSONAs(t, s, "tok-batch", "/api/v1/top?type=tcp", &top)
+	if len(top.Top) != 0 {
+		t.Errorf("top = %+v, want none", top.Top)
+	}
+
+	var topology struct {
+		Edges []topologyEdge `json:"edges"`
+	}
+	getJSONAs(t, s, "tok-batch", "/api/v1/topology", &topology)
+	if len(topology.Edges) != 0 {
+		t.Errorf("batch-team edges = %+v, want none", topology.Edges)
+	}
+	getJSONAs(t, s, "tok-prod", "/api/v1/topology", &topology)
+	if len(topology.Edges) != 2 {
+		t.Errorf("prod-team edges = %+v, want web-0's and web-1's", topology.Edges)
+	}
+}
+
+// TestScope_CacheKeys checks a cached response is only served to tokens
+// with the scope it was computed for.
+func TestScope_CacheKeys(t *testing.T) {
+	s := newScopeTestServer(t)
+	s.cache = &memCache{data: map[string]string{}}
+
+	var overview struct {
+		Total uint64 `json:"total_events"`
+	}
+	for _, tt := range []struct {
+		token string
+		want  uint64
+	}{{"tok-admin", 61}, {"tok-batch", 1}, {"tok-prod", 60}, {"tok-batch", 1}, {"tok-admin", 61}} {
+		getJSONAs(t, s, tt.token, "/api/v1/metrics/overview", &overview)
+		if overview.Total != tt.want {
+			t.Errorf("%s: total = %d, want %d", tt.token, overview.Total, tt.want)
+		}
+	}
+}
+
+func TestScope_Ingest(t *testing.T) {
+	s := newScopeTestServer(t)
+	s.app = fiber.New()
+	s.ingest = newIngester(DefaultIngestConfig())
+	s.registerRoutes()
+
+	req := httptest.NewRequest("POST", "/api/v1/ingest", strings.NewReader(`{"id":1,"type":"tcp","ts":1,"node":"n"}`))
+	req.Header.Set("Authorization", "Bearer tok-prod")
+	if resp, _ := s.app.Test(req); resp.StatusCode != 403 {
+		t.Errorf("ingest with a scoped token = %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestScope_LiveEvents(t *testing.T) {
+	s := newScopeTestServer(t)
+	s.cache = nil
+	addr := startWSTestServer(t, s)
+
+	_, resp, err := fws.DefaultDialer.Dial("ws://"+addr+"/ws/events?namespace=prod&access_token=tok-batch", nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("dial for prod = %v, %v, want a 403", resp, err)
+	}
+
+	conn, _, err := fws.DefaultDialer.Dial("ws://"+addr+"/ws/events?access_token=tok-batch", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitClients(t, s.hub, 1)
+
+	now := time.Now()
+	for i, p := range []string{
+		`{"type":"tcp","namespace":"prod","seq":0}`,
+		`{"type":"oom","namespace":"staging","seq":1}`,
+		`{"type":"dns","namespace":"","seq":2}`,
+		`{"type":"oom","namespace":"batch","seq":3}`,
+	} {
+		s.hub.broadcast([]byte(p), now.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, want := range []string{`"seq":1`, `"seq":3`} {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(msg), want) {
+			t.Fatalf("got %s, want message with %s", msg, want)
+		}
+	}
+}
+
+func TestConfig_ValidateTokenNamespaces(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Tokens = map[string]string{"team": "tok"}
+	cfg.TokenNamespaces = map[string][]string{"team": {"prod"}}
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ValidateTokens(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.TokenNamespaces = map[string][]string{"taem": {"prod"}}
+	if err := cfg.ValidateTokens(); err == nil || !strings.Contains(err.Error(), "taem") {
+		t.Errorf("scope for a missing token: err = %v", err)
+	}
+	for _, namespaces := range [][]string{{}, {"prod", ""}} {
+		cfg.TokenNamespaces = map[string][]string{"team": namespaces}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("namespaces %q accepted", namespaces)
+		}
+	}
+}
@@ -4,381 +4,699 @@ package api
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"slices"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
 	"github.com/gofiber/fiber/v2/middleware/compress"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
-	fiberlogger "github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/fiber/v2/middleware/recover"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
+	"golang.org/x/sync/singleflight"
 
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
 	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
 	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
 )
 
+// Config holds API server settings.
+type Config struct {
+	Addr string `yaml:"addr"`
+	// Tokens maps token name → bearer token. Empty disables auth.
+	// The name identifies the caller, e.g. as the owner of saved views.
+	// Never read from the config file; see cmd/api.
+	Tokens map[string]string `yaml:"-"`
+	// TokenNamespaces limits the named tokens to reading events in the
+	// listed namespaces; tokens not listed read every namespace.
+	TokenNamespaces map[string][]string `yaml:"token_namespaces"`
+	// CORSOrigins are the origins browsers may call the API from, e.g.
+	// "https://dash.example.com"; "*" allows any. Empty disables CORS.
+	CORSOrigins []string `yaml:"cors_origins"`
+	// CORSAllowCredentials lets browsers send cookies and auth headers
+	// cross-origin. Not allowed with the "*" origin.
+	CORSAllowCredentials bool         `yaml:"cors_allow_credentials"`
+	TLS                  TLSConfig    `yaml:"tls"`
+	Ingest               IngestConfig `yaml:"ingest"`
+}
+
+// DefaultConfig returns lean defaults (auth disabled, any CORS origin,
+// plain HTTP).
+func DefaultConfig() Config {
+	return Config{
+		Addr:        constants.APIDefaultAddr,
+		CORSOrigins: []string{constants.APICORSAnyOrigin},
+		Ingest:      DefaultIngestConfig(),
+	}
+}
+
+// Validate checks c without reading the TLS files.
+func (c Config) Validate() error {
+	var errs []string
+	for i, origin := range c.CORSOrigins {
+		if origin == constants.APICORSAnyOrigin {
+			if c.CORSAllowCredentials {
+				errs = append(errs, `cors_origins "*" cannot be combined with cors_allow_credentials`)
+			}
+			continue
+		}
+		if !validOrigin(origin) {
+			errs = append(errs, fmt.Sprintf(`cors_origins[%d] must be "*" or scheme://host[:port], got %q`, i, origin))
+		}
+	}
+	t := c.TLS
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		errs = append(errs, "tls.cert_file and tls.key_file must be set together")
+	}
+	if t.ClientCA != "" && t.CertFile == "" {
+		errs = append(errs, "tls.client_ca requires tls.cert_file and tls.key_file")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.TokenNamespaces)) {
+		namespaces := c.TokenNamespaces[name]
+		if len(namespaces) == 0 || slices.Contains(namespaces, "") {
+			errs = append(errs, fmt.Sprintf("token_namespaces[%s] must list at least one namespace, none empty", name))
+		}
+	}
+	if in := c.Ingest; in.Enabled {
+		if in.MaxEvents < 1 {
+			errs = append(errs, "ingest.max_events must be >= 1")
+		}
+		if in.MaxInFlight < 1 {
+			errs = append(errs, "ingest.max_in_flight must be >= 1")
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// ValidateTokens checks the settings that need Tokens, which are set after
+// the config file is read: ingest writes to the store, so it is never
+// left open, and a namespace scope naming no token is most likely a typo
+// that leaves the token it meant reading every namespace.
+func (c Config) ValidateTokens() error {
+	var errs []string
+	if c.Ingest.Enabled && len(c.Tokens) == 0 {
+		errs = append(errs, "ingest.enabled requires API tokens for agents to authenticate with")
+	}
+	for _, name := range slices.Sorted(maps.Keys(c.TokenNamespaces)) {
+		if _, ok := c.Tokens[name]; !ok {
+			errs = append(errs, fmt.Sprintf("token_namespaces[%s]: no API token named %q", name, name))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// validOrigin reports whether origin is an http(s) origin with nothing
+// after the host and port.
+func validOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" &&
+		u.Path == "" && u.RawQuery == "" && u.Fragment == "" && u.User == nil
+}
+
 // Server is the HTTP API server.
 type Server struct {
+	cfg    Config
 	app    *fiber.App
-	ch     *storage.ClickHouse
-	redis  *cache.Redis
+	store  storage.Store
+	cache  cache.Cache        // cache.Disabled without Redis
+	flight singleflight.Group // cache misses in progress, see respondCached
+	views  viewStore
+	hub    *hub
+	ingest *ingester    // nil unless cfg.Ingest.Enabled
+	deps   []dependency // checked by /readyz
 	logger *zap.Logger
-	addr   string
+
+	cancel context.CancelFunc
 }
 
 // NewServer creates a Fiber API server with all routes.
-func NewServer(addr string, ch *storage.ClickHouse, redis *cache.Redis, logger *zap.Logger) *Server {
+// rc may be cache.Disabled: responses are then always read from the store,
+// and saved views and /ws/events answer 503.
+func NewServer(cfg Config, store storage.Store, rc cache.Cache, logger *zap.Logger) *Server {
+	s := &Server{
+		cfg:    cfg,
+		store:  store,
+		cache:  rc,
+		views:  newRedisViewStore(rc),
+		hub:    newHub(),
+		logger: logger,
+	}
+	if cfg.Ingest.Enabled {
+		s.ingest = newIngester(cfg.Ingest)
+	}
 	app := fiber.New(fiber.Config{
 		Prefork:       false,
 		StrictRouting: false,
 		ReadTimeout:   constants.HTTPReadTimeout,
 		WriteTimeout:  constants.HTTPWriteTimeout,
 		IdleTimeout:   constants.HTTPIdleTimeout,
+		ErrorHandler:  s.handleError,
 	})
-
-	s := &Server{
-		app:    app,
-		ch:     ch,
-		redis:  redis,
-		logger: logger,
-		addr:   addr,
+	s.app = app
+	s.deps = []dependency{
+		{name: storeName(store), critical: true, ping: store.Ping},
+		{name: "redis", critical: false, ping: rc.Ping}, // cache, views and live events
 	}
 
 	// Middleware
+	app.Use(requestIDMiddleware())
+	app.Use(s.accessLog()) // outside recover, so panics are logged as 500s
 	app.Use(recover.New())
-	app.Use(fiberlogger.New(fiberlogger.Config{Format: "${time} ${status} ${method} ${path} ${latency}\n"}))
-	app.Use(cors.New(cors.Config{AllowOrigins: "*"}))
+	if len(cfg.CORSOrigins) > 0 {
+		app.Use(cors.New(cors.Config{
+			AllowOrigins:     strings.Join(cfg.CORSOrigins, ","),
+			AllowCredentials: cfg.CORSAllowCredentials,
+		}))
+	}
 	app.Use(compress.New())
 	app.Use(limiter.New(limiter.Config{
-		Max:        constants.APIRateLimit,
-		Expiration: time.Second,
+		Max:          constants.APIRateLimit,
+		Expiration:   time.Second,
+		LimitReached: rateLimited,
 	}))
 
+	s.registerRoutes()
+
+	return s
+}
+
+// registerRoutes mounts all API, WebSocket, and health routes on s.app.
+func (s *Server) registerRoutes() {
 	// Routes
-	v1 := app.Group("/api/v1")
+	v1 := s.app.Group("/api/v1", s.authMiddleware(false))
 	v1.Get("/events", s.handleEvents)
 	v1.Get("/events/types", s.handleEventTypes)
+	v1.Get("/events/rate", s.handleEventRate)
+	v1.Get("/schema", s.handleSchema)
 	v1.Get("/metrics/overview", s.handleOverview)
 	v1.Get("/metrics/:type", s.handleMetricsByType)
 	v1.Get("/topology", s.handleTopology)
+	v1.Get("/pods/:namespace/:pod", s.handlePod)
+	v1.Get("/top", s.handleTop)
+
+	// Saved views
+	v1.Post("/views", s.handleCreateView)
+	v1.Get("/views", s.handleListViews)
+	v1.Get("/views/:id", s.handleGetView)
+	v1.Delete("/views/:id", s.handleDeleteView)
+	v1.Get("/views/:id/events", s.handleViewEvents)
+
+	// Events pushed by agents without NATS
+	if s.ingest != nil {
+		v1.Post("/ingest", s.handleIngest)
+	}
 
 	// WebSocket for live events
-	app.Use("/ws", func(c *fiber.Ctx) error {
-		if websocket.IsWebSocketUpgrade(c) {
-			return c.Next()
+	s.app.Use("/ws", s.authMiddleware(true), func(c *fiber.Ctx) error {
+		if s.cache != nil && !s.cache.Enabled() {
+			return unavailable(c, "live events need Redis, which this API server is running without")
+		}
+		if !websocket.IsWebSocketUpgrade(c) {
+			return fiber.ErrUpgradeRequired
+		}
+		// Reject bad filters before the upgrade, while a 400 or 403 is
+		// still possible.
+		if err := validateWSOptions(c); err != nil {
+			return badRequest(c, err)
+		}
+		if _, err := scopeNamespaces(c, c.Query("namespace")); err != nil {
+			return forbidden(c, err.Error())
 		}
-		return fiber.ErrUpgradeRequired
+		return c.Next()
 	})
-	app.Get("/ws/events", websocket.New(s.handleWS))
+	s.app.Get("/ws/events", websocket.New(s.handleWS))
 
-	// Health
-	app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	// Health, build info and Prometheus metrics
+	s.app.Get("/healthz", func(c *fiber.Ctx) error { return c.SendString("ok") })
+	s.app.Get("/readyz", s.handleReadyz)
+	s.app.Get(constants.PathVersion, func(c *fiber.Ctx) error { return c.JSON(buildinfo.Get()) })
+	s.app.Get(constants.PathMetrics, adaptor.HTTPHandler(promhttp.Handler()))
+}
 
-	return s
+// storeName names store in /readyz.
+func storeName(store storage.Store) string {
+	if _, ok := store.(*storage.SQLite); ok {
+		return "sqlite"
+	}
+	return "clickhouse"
 }
 
-// Start begins listening. Blocks until shutdown.
+// Start begins listening, over TLS if configured. Blocks until shutdown.
 func (s *Server) Start() error {
-	s.logger.Info("API server listening", zap.String("addr", s.addr))
-	return s.app.Listen(s.addr)
+	ln, err := s.listen()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	go s.runHub(ctx)
+	go s.runIngestLive(ctx)
+
+	s.logger.Info("API server listening",
+		zap.String("addr", s.cfg.Addr),
+		zap.Bool("auth", len(s.cfg.Tokens) > 0),
+		zap.Int("scoped_tokens", len(s.cfg.TokenNamespaces)),
+		zap.Bool("tls", s.cfg.TLS.Enabled()),
+		zap.Bool("mtls", s.cfg.TLS.ClientCA != ""),
+		zap.Bool("ingest", s.ingest != nil))
+	return s.app.Listener(ln)
+}
+
+// listen opens the listener Start serves on: plain TCP, or TLS when a
+// certificate is configured, requiring client certificates when a client
+// CA is.
+func (s *Server) listen() (net.Listener, error) {
+	var tlsCfg *tls.Config
+	if s.cfg.TLS.Enabled() {
+		var err error
+		if tlsCfg, err = s.cfg.TLS.serverConfig(); err != nil {
+			return nil, err
+		}
+	}
+	ln, err := net.Listen("tcp", s.cfg.Addr)
+	if err != nil || tlsCfg == nil {
+		return ln, err
+	}
+	return tls.NewListener(ln, tlsCfg), nil
 }
 
 // Stop gracefully shuts down.
 func (s *Server) Stop() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
 	return s.app.Shutdown()
 }
 
+// runHub feeds the WebSocket hub from a single Redis pub/sub subscription.
+// Without Redis there is nothing to feed it.
+func (s *Server) runHub(ctx context.Context) {
+	if !s.cache.Enabled() {
+		return
+	}
+	sub := s.cache.Subscribe(ctx, constants.RedisPubSubChannel)
+	defer sub.Close()
+
+	payloads := make(chan string)
+	go func() {
+		defer close(payloads)
+		for msg := range sub.Channel() {
+			select {
+			case payloads <- msg.Payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	s.hub.run(ctx, payloads)
+}
+
 // ─── Handlers ────────────────────────────────────────────────────
 
-// handleEvents returns paginated events from ClickHouse.
+// handleEvents returns paginated events from the store.
 func (s *Server) handleEvents(c *fiber.Ctx) error {
-	limit := min(c.QueryInt("limit", constants.APIDefaultPageSize), constants.APIMaxPageSize)
-	offset := c.QueryInt("offset", 0)
-	eventType := c.Query("type")
-	namespace := c.Query("namespace")
-	since := c.Query("since") // ISO8601
-
-	// Build query
-	query := "SELECT timestamp, event_type, pid, comm, node, namespace, pod, labels, numerics FROM kubepulse.events WHERE 1=1"
-	args := make([]any, 0)
-
-	if eventType != "" {
-		query += " AND event_type = ?"
-		args = append(args, eventType)
-	}
-	if namespace != "" {
-		query += " AND namespace = ?"
-		args = append(args, namespace)
-	}
-	if since != "" {
-		t, err := time.Parse(time.RFC3339, since)
-		if err == nil {
-			query += " AND timestamp >= ?"
-			args = append(args, t)
-		}
+	f, err := parseEventFilter(c)
+	if err != nil {
+		return badRequest(c, err)
 	}
+	if err := f.validate(); err != nil {
+		return badRequest(c, err)
+	}
+	return s.respondEvents(c, f)
+}
 
-	query += " ORDER BY timestamp DESC LIMIT ? OFFSET ?"
-	args = append(args, limit, offset)
+// respondEvents runs the events query for a validated f and writes the
+// paginated response, including the effective time range. A full page
+// carries next_cursor; offset paging still works but is deprecated.
+// format=csv|ndjson streams the whole result instead (see exportEvents).
+func (s *Server) respondEvents(c *fiber.Ctx, f eventFilter) error {
+	scope, err := scopeNamespaces(c, f.Namespace)
+	if err != nil {
+		return forbidden(c, err.Error())
+	}
+	f.scope = scope
+
+	switch format := c.Query("format", "json"); format {
+	case "json":
+	case exportCSV, exportNDJSON:
+		return s.exportEvents(c, f, format)
+	default:
+		return badRequest(c, &paramError{"format", "must be json, csv or ndjson"})
+	}
 
-	rows, err := s.ch.Query(c.Context(), query, args...)
+	since, until := f.timeRange(time.Now())
+	rows, err := s.store.ListEvents(c.UserContext(), f.query(since, until))
 	if err != nil {
-		s.logger.Error("Query failed", zap.Error(err))
-		return c.Status(500).JSON(fiber.Map{"error": "query failed"})
+		return s.queryError(c, "Events query failed", err)
 	}
 	defer rows.Close()
 
-	var events []fiber.Map
+	events := make([]eventRecord, 0, f.pageSize())
+	var last eventCursor
+	read, failed := 0, 0
 	for rows.Next() {
-		var (
-			ts       time.Time
-			evtType  string
-			pid      uint32
-			comm     string
-			node     string
-			ns       string
-			pod      string
-			labels   map[string]string
-			numerics map[string]float64
-		)
-		if err := rows.Scan(&ts, &evtType, &pid, &comm, &node, &ns, &pod, &labels, &numerics); err != nil {
+		read++
+		e, err := rows.Event()
+		if err != nil {
+			failed++
 			continue
 		}
-		events = append(events, fiber.Map{
-			"timestamp": ts,
-			"type":      evtType,
-			"pid":       pid,
-			"comm":      comm,
-			"node":      node,
-			"namespace": ns,
-			"pod":       pod,
-			"labels":    labels,
-			"numerics":  numerics,
-		})
+		last = eventCursor{Timestamp: e.Timestamp, RowKey: e.RowKey}
+		events = append(events, newEventRecord(e))
+	}
+	partial := failed > 0
+	if err := rows.Err(); err != nil {
+		s.requestLogger(c).Warn("Events query ended early", zap.Int("rows", read), zap.Error(err))
+		partial = true
+	}
+	if failed > 0 {
+		s.requestLogger(c).Warn("Skipped events that failed to scan", zap.Int("failed", failed), zap.Int("rows", read))
+	}
+
+	// A full page (counting unscannable rows) has a next page.
+	var next any
+	if read == f.pageSize() && len(events) > 0 {
+		next = last.encode()
+	}
+	if f.Offset > 0 {
+		c.Set("Deprecation", "true")
+		c.Set("Warning", `299 - "offset pagination is deprecated; use cursor"`)
 	}
 
 	return c.JSON(fiber.Map{
-		"events": events,
-		"limit":  limit,
-		"offset": offset,
+		"events":      events,
+		"limit":       f.pageSize(),
+		"offset":      f.Offset,
+		"next_cursor": next,
+		"partial":     partial,
+		"range":       rangeJSON(since, until),
+		"filters":     f.applied(),
 	})
 }
 
-// handleEventTypes returns distinct event types.
-func (s *Server) handleEventTypes(c *fiber.Ctx) error {
-	cacheKey := "event_types"
-	if cached, err := s.redis.Get(c.Context(), cacheKey); err == nil {
-		c.Set("X-Cache", "HIT")
-		return c.SendString(cached)
-	}
+// eventRecord is one events row as returned by the API.
+type eventRecord struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Type      string             `json:"type"`
+	PID       uint32             `json:"pid"`
+	Comm      string             `json:"comm"`
+	Node      string             `json:"node"`
+	Namespace string             `json:"namespace"`
+	Pod       string             `json:"pod"`
+	Labels    map[string]string  `json:"labels"`
+	Numerics  map[string]float64 `json:"numerics"`
+}
 
-	rows, err := s.ch.Query(c.Context(),
-		"SELECT event_type, count() AS cnt FROM kubepulse.events GROUP BY event_type ORDER BY cnt DESC")
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "query failed"})
+func newEventRecord(e storage.Event) eventRecord {
+	return eventRecord{
+		Timestamp: e.Timestamp,
+		Type:      e.Type,
+		PID:       e.PID,
+		Comm:      e.Comm,
+		Node:      e.Node,
+		Namespace: e.Namespace,
+		Pod:       e.Pod,
+		Labels:    e.Labels,
+		Numerics:  e.Numerics,
 	}
-	defer rows.Close()
+}
 
-	var types []fiber.Map
-	for rows.Next() {
-		var t string
-		var cnt uint64
-		if err := rows.Scan(&t, &cnt); err != nil {
-			continue
+// handleEventTypes returns distinct event types.
+func (s *Server) handleEventTypes(c *fiber.Ctx) error {
+	namespaces := requestNamespaces(c)
+	return s.respondCached(c, "event_types", func(ctx context.Context) ([]byte, error) {
+		counts, err := s.store.EventTypes(ctx, namespaces)
+		if err != nil {
+			return nil, err
 		}
-		types = append(types, fiber.Map{"type": t, "count": cnt})
-	}
+		types := make([]fiber.Map, 0, len(counts))
+		for _, t := range counts {
+			types = append(types, fiber.Map{"type": t.Type, "count": t.Count})
+		}
+		return json.Marshal(fiber.Map{"types": types})
+	})
+}
 
-	result, _ := json.Marshal(fiber.Map{"types": types})
-	s.redis.Set(c.Context(), cacheKey, string(result), constants.RedisCacheTTL)
-	c.Set("X-Cache", "MISS")
-	return c.Send(result)
+// handleSchema returns the label and numeric keys of each event type.
+func (s *Server) handleSchema(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{"types": event.Schemas()})
 }
 
 // handleOverview returns dashboard summary metrics.
+// Long windows are served from the per-minute rollup.
 func (s *Server) handleOverview(c *fiber.Ctx) error {
-	cacheKey := "overview"
-	if cached, err := s.redis.Get(c.Context(), cacheKey); err == nil {
-		c.Set("X-Cache", "HIT")
-		return c.SendString(cached)
-	}
-
-	row := s.ch.QueryRow(c.Context(), `
-		SELECT 
-			count() AS total_events,
-			countIf(event_type = 'tcp') AS tcp_events,
-			countIf(event_type = 'dns') AS dns_events,
-			countIf(event_type = 'oom') AS oom_events,
-			countIf(event_type = 'drop') AS drop_events,
-			avg(numerics['latency_sec']) AS avg_latency
-		FROM kubepulse.events 
-		WHERE timestamp >= now() - INTERVAL 1 HOUR
-	`)
-
-	var total, tcpN, dnsN, oomN, dropN uint64
-	var avgLat float64
-	if err := row.Scan(&total, &tcpN, &dnsN, &oomN, &dropN, &avgLat); err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "query failed"})
-	}
-
-	result := fiber.Map{
-		"total_events":    total,
-		"tcp_events":      tcpN,
-		"dns_events":      dnsN,
-		"oom_events":      oomN,
-		"drop_events":     dropN,
-		"avg_latency_sec": avgLat,
-		"window":          "1h",
-	}
-
-	data, _ := json.Marshal(result)
-	s.redis.Set(c.Context(), cacheKey, string(data), constants.RedisCacheTTL)
-	c.Set("X-Cache", "MISS")
-	return c.JSON(result)
+	window, span, err := queryWindow(c)
+	if err != nil {
+		return badRequest(c, err)
+	}
+
+	namespaces := requestNamespaces(c)
+	return s.respondCached(c, "overview:"+window, func(ctx context.Context) ([]byte, error) {
+		until := time.Now()
+		since := until.Add(-span)
+		rollup := useRollup(span)
+		o, err := s.store.Overview(ctx, storage.Window{Since: since, Until: until, Rollup: rollup, Namespaces: namespaces})
+		if err != nil {
+			return nil, err
+		}
+		top, err := s.topOffendersByType(ctx, since, until, constants.APIOverviewTopK, namespaces)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(fiber.Map{
+			"total_events":    o.Total,
+			"tcp_events":      o.TCP,
+			"dns_events":      o.DNS,
+			"oom_events":      o.OOM,
+			"drop_events":     o.Drop,
+			"avg_latency_sec": o.AvgLatency,
+			"window":          window,
+			"source":          querySource(rollup),
+			"retention_sec":   s.store.Retention().Seconds(),
+			"top_offenders":   top,
+		})
+	})
 }
 
 // handleMetricsByType returns time-series metrics for a specific event type.
+// The span is a relative window (default 1h), a relative range, or an
+// absolute since/until. Long spans are served from the per-minute rollup.
+// group_by=namespace|pod|node returns one series per group instead: the
+// top `groups` by volume, with the remainder folded into "other".
 func (s *Server) handleMetricsByType(c *fiber.Ctx) error {
 	evtType := c.Params("type")
+	if err := (eventFilter{Type: evtType}).validate(); err != nil {
+		return badRequest(c, err)
+	}
 	window := c.Query("window", "1h")
+	sinceQ, untilQ, rangeQ := c.Query("since"), c.Query("until"), c.Query("range")
 
-	cacheKey := "metrics:" + evtType + ":" + window
-	if cached, err := s.redis.Get(c.Context(), cacheKey); err == nil {
-		c.Set("X-Cache", "HIT")
-		return c.SendString(cached)
-	}
-
-	query := `
-		SELECT 
-			toStartOfMinute(timestamp) AS minute,
-			count() AS cnt,
-			avg(numerics['latency_sec']) AS avg_latency,
-			quantile(0.99)(numerics['latency_sec']) AS p99_latency
-		FROM kubepulse.events
-		WHERE event_type = ? AND timestamp >= now() - INTERVAL ` + sanitizeInterval(window) + `
-		GROUP BY minute
-		ORDER BY minute
-	`
-
-	rows, err := s.ch.Query(c.Context(), query, evtType)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "query failed"})
+	var since, until time.Time
+	if sinceQ == "" && untilQ == "" && rangeQ == "" {
+		_, span, err := queryWindow(c)
+		if err != nil {
+			return badRequest(c, err)
+		}
+		until = time.Now()
+		since = until.Add(-span)
+	} else {
+		if err := validateTimeRange(sinceQ, untilQ, rangeQ); err != nil {
+			return badRequest(c, err)
+		}
+		since, until = resolveTimeRange(sinceQ, untilQ, rangeQ, time.Now())
+		if until.IsZero() {
+			until = time.Now()
+		}
+		if since.IsZero() {
+			return badRequest(c, &paramError{"since", "is required with until"})
+		}
 	}
-	defer rows.Close()
 
-	var series []fiber.Map
-	for rows.Next() {
-		var minute time.Time
-		var cnt uint64
-		var avgLat, p99Lat float64
-		if err := rows.Scan(&minute, &cnt, &avgLat, &p99Lat); err != nil {
-			continue
+	groupBy := c.Query("group_by")
+	groups := constants.APIDefaultGroups
+	if groupBy != "" {
+		if !groupByDimensions[groupBy] {
+			return badRequest(c, &paramError{"group_by", "must be namespace, pod or node"})
 		}
-		series = append(series, fiber.Map{
-			"time":        minute,
-			"count":       cnt,
-			"avg_latency": avgLat,
-			"p99_latency": p99Lat,
-		})
+		n, err := queryIntIn(c, "groups", constants.APIDefaultGroups, 1, constants.APIMaxGroups)
+		if err != nil {
+			return badRequest(c, err)
+		}
+		groups = n
+	} else if c.Query("groups") != "" {
+		return badRequest(c, &paramError{"groups", "requires group_by"})
 	}
 
-	result, _ := json.Marshal(fiber.Map{"type": evtType, "series": series})
-	s.redis.Set(c.Context(), cacheKey, string(result), constants.RedisCacheTTL)
-	c.Set("X-Cache", "MISS")
-	return c.Send(result)
-}
-
-// handleTopology returns namespace→pod topology.
-func (s *Server) handleTopology(c *fiber.Ctx) error {
-	cacheKey := "topology"
-	if cached, err := s.redis.Get(c.Context(), cacheKey); err == nil {
-		c.Set("X-Cache", "HIT")
-		return c.SendString(cached)
-	}
-
-	rows, err := s.ch.Query(c.Context(), `
-		SELECT namespace, pod, node, count() AS cnt
-		FROM kubepulse.events
-		WHERE timestamp >= now() - INTERVAL 1 HOUR AND namespace != ''
-		GROUP BY namespace, pod, node
-		ORDER BY cnt DESC
-		LIMIT 500
-	`)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{"error": "query failed"})
+	cacheKey := "metrics:" + evtType + ":" + window + ":" + sinceQ + ":" + untilQ + ":" + rangeQ
+	if groupBy != "" {
+		cacheKey += ":" + groupBy + ":" + strconv.Itoa(groups)
 	}
-	defer rows.Close()
+	namespaces := requestNamespaces(c)
+	return s.respondCached(c, cacheKey, func(ctx context.Context) ([]byte, error) {
+		rollup := useRollup(until.Sub(since)) && rollupGroupable(groupBy)
+		points, err := s.store.SeriesByType(ctx, storage.SeriesQuery{
+			Type:    evtType,
+			GroupBy: groupBy,
+			Groups:  groups,
+			Window:  storage.Window{Since: since, Until: until, Rollup: rollup, Namespaces: namespaces},
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	var items []fiber.Map
-	for rows.Next() {
-		var ns, pod, node string
-		var cnt uint64
-		if err := rows.Scan(&ns, &pod, &node, &cnt); err != nil {
-			continue
+		body := fiber.Map{
+			"type":   evtType,
+			"source": querySource(rollup),
+			"range":  rangeJSON(since, until),
 		}
-		items = append(items, fiber.Map{
-			"namespace": ns, "pod": pod, "node": node, "count": cnt,
-		})
-	}
+		if groupBy == "" {
+			series := make([]metricPoint, 0, len(points))
+			for _, p := range points {
+				series = append(series, newMetricPoint(p))
+			}
+			body["series"] = series
+		} else {
+			body["group_by"] = groupBy
+			body["groups"] = groups
+			body["series"] = groupedSeries(points)
+		}
+		return json.Marshal(body)
+	})
+}
 
-	result, _ := json.Marshal(fiber.Map{"topology": items})
-	s.redis.Set(c.Context(), cacheKey, string(result), constants.RedisCacheTTL)
-	c.Set("X-Cache", "MISS")
-	return c.Send(result)
+// metricPoint is one minute of a metrics series.
+type metricPoint struct {
+	Time       time.Time `json:"time"`
+	Count      uint64    `json:"count"`
+	AvgLatency float64   `json:"avg_latency"`
+	P99Latency float64   `json:"p99_latency"`
 }
 
-// handleWS streams live events via WebSocket (backed by Redis pub/sub).
-func (s *Server) handleWS(c *websocket.Conn) {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+func newMetricPoint(p storage.SeriesPoint) metricPoint {
+	return metricPoint{Time: p.Minute, Count: p.Count, AvgLatency: p.AvgLatency, P99Latency: p.P99Latency}
+}
 
-	sub := s.redis.Subscribe(ctx, constants.RedisPubSubChannel)
-	defer sub.Close()
+// groupSeries is one group's series in a group_by response.
+type groupSeries struct {
+	Key    string        `json:"key"`
+	Points []metricPoint `json:"points"`
+	total  uint64
+}
 
-	ch := sub.Channel()
-	for msg := range ch {
-		if err := c.WriteMessage(websocket.TextMessage, []byte(msg.Payload)); err != nil {
-			break
+// groupedSeries splits grouped SeriesByType points into per-group
+// series, largest group first and "other" last.
+func groupedSeries(points []storage.SeriesPoint) []groupSeries {
+	byKey := make(map[string]*groupSeries)
+	var out []*groupSeries
+	for _, sp := range points {
+		g, ok := byKey[sp.Group]
+		if !ok {
+			g = &groupSeries{Key: sp.Group}
+			byKey[sp.Group] = g
+			out = append(out, g)
 		}
+		g.Points = append(g.Points, newMetricPoint(sp))
+		g.total += sp.Count
 	}
-}
 
-// sanitizeInterval prevents injection in interval strings.
-func sanitizeInterval(s string) string {
-	// Allow only digits + h/m/d
-	for _, c := range s {
-		if c >= '0' && c <= '9' {
-			continue
+	sort.SliceStable(out, func(i, j int) bool {
+		if oi, oj := out[i].Key == constants.APIGroupOther, out[j].Key == constants.APIGroupOther; oi != oj {
+			return oj
 		}
-		if c == 'h' || c == 'm' || c == 'd' {
-			continue
+		return out[i].total > out[j].total
+	})
+	series := make([]groupSeries, len(out))
+	for i, g := range out {
+		series[i] = *g
+	}
+	return series
+}
+
+// validateWSOptions checks the /ws/events query parameters before the
+// upgrade, while a 400 is still possible.
+func validateWSOptions(c *fiber.Ctx) error {
+	if err := (eventFilter{Type: c.Query("type")}).validate(); err != nil {
+		return err
+	}
+	if _, err := queryIntIn(c, "max_rate", constants.WSDefaultMaxRate, 1, constants.WSMaxRateCap); err != nil {
+		return err
+	}
+	_, err := queryIntIn(c, "sample", constants.WSDefaultSampleSize, 0, constants.WSMaxSampleSize)
+	return err
+}
+
+// handleWS streams live events via WebSocket, fed by the hub.
+// Query params type, namespace and pod filter the stream server-side;
+// max_rate and sample tune per-type downsampling. The connection is
+// logged at open and close under conn_id, the upgrade's request ID.
+func (s *Server) handleWS(c *websocket.Conn) {
+	opts := parseWSOptions(c.Query)
+	opts.Namespaces, _ = c.Locals(localsNamespaces).([]string)
+	client := newWSClient(opts)
+	logger := s.logger.With(zap.String("conn_id", wsConnID(c)))
+	logger.Info("WebSocket opened",
+		zap.String("type", opts.Type),
+		zap.String("namespace", opts.Namespace),
+		zap.String("pod", opts.Pod),
+		zap.Int("max_rate", opts.MaxRate))
+	start := time.Now()
+	s.hub.register(client)
+	defer func() {
+		s.hub.unregister(client)
+		// Unregistered, the hub no longer touches client.dropped.
+		logger.Info("WebSocket closed",
+			zap.Duration("duration", time.Since(start)),
+			zap.Uint64("dropped", client.dropped))
+	}()
+
+	// Reads only serve to notice the peer closing.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
 		}
-		return "1 HOUR"
-	}
-	// Convert shorthand: "1h" → "1 HOUR"
-	if len(s) >= 2 {
-		num := s[:len(s)-1]
-		if _, err := strconv.Atoi(num); err == nil {
-			switch s[len(s)-1] {
-			case 'h':
-				return num + " HOUR"
-			case 'm':
-				return num + " MINUTE"
-			case 'd':
-				return fmt.Sprintf("%d HOUR", mustAtoi(num)*24)
+	}()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case msg := <-client.send:
+			c.SetWriteDeadline(time.Now().Add(constants.WSWriteTimeout))
+			if err := c.WriteMessage(websocket.TextMessage, msg); err != nil {
+				return
 			}
 		}
 	}
-	return "1 HOUR"
 }
 
-func mustAtoi(s string) int {
-	n, _ := strconv.Atoi(s)
-	return n
+// wsConnID returns the ID of the request c was upgraded from.
+func wsConnID(c *websocket.Conn) string {
+	id, _ := c.Locals(localsRequestID).(string)
+	return strings.Clone(id)
 }
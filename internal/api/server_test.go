@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// TestServer_WithoutRedis runs every route against a server built the way
+// cmd/api builds it when Redis is disabled or unreachable.
+func TestServer_WithoutRedis(t *testing.T) {
+	rc := cache.Disabled{}
+	s := &Server{
+		app:    fiber.New(),
+		store:  storage.NewMemory(),
+		cache:  rc,
+		views:  newRedisViewStore(rc),
+		hub:    newHub(),
+		logger: zap.NewNop(),
+	}
+	s.deps = []dependency{{name: "redis", ping: rc.Ping}}
+	s.registerRoutes()
+	s.runHub(context.Background()) // returns at once without Redis
+
+	tests := []struct {
+		method, path, body string
+		want               int
+	}{
+		{"GET", "/api/v1/events", "", 200},
+		{"GET", "/api/v1/events?format=ndjson", "", 200},
+		{"GET", "/api/v1/events/types", "", 200},
+		{"GET", "/api/v1/events/rate?window=6h&step=5m", "", 200},
+		{"GET", "/api/v1/schema", "", 200},
+		{"GET", "/api/v1/metrics/overview", "", 200},
+		{"GET", "/api/v1/metrics/overview?window=24h", "", 200},
+		{"GET", "/api/v1/metrics/tcp", "", 200},
+		{"GET", "/api/v1/metrics/tcp?group_by=namespace", "", 200},
+		{"GET", "/api/v1/topology", "", 200},
+		{"GET", "/api/v1/top?type=tcp", "", 200},
+		{"GET", "/api/v1/pods/prod/web-0?window=6h", "", 200},
+		{"POST", "/api/v1/views", `{"name":"errors","filters":{"type":"tcp"}}`, 503},
+		{"GET", "/api/v1/views", "", 503},
+		{"GET", "/api/v1/views/abc", "", 503},
+		{"DELETE", "/api/v1/views/abc", "", 503},
+		{"GET", "/api/v1/views/abc/events", "", 503},
+		{"GET", "/ws/events", "", 503},
+		{"GET", "/healthz", "", 200},
+		{"GET", "/readyz", "", 200}, // degraded: Redis is not critical
+		{"GET", "/metrics", "", 200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := s.app.Test(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", tt.method, tt.path, err)
+		}
+		raw, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s %s = %d, want %d: %s", tt.method, tt.path, resp.StatusCode, tt.want, raw)
+		}
+		if tt.want == 503 && !strings.Contains(string(raw), "Redis") {
+			t.Errorf("%s %s body = %s, want it to name Redis", tt.method, tt.path, raw)
+		}
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(*Config)
+		want string // "" = valid
+	}{
+		{"defaults", func(*Config) {}, ""},
+		{"origins", func(c *Config) {
+			c.CORSOrigins = []string{"https://dash.example.com", "http://localhost:3000"}
+			c.CORSAllowCredentials = true
+		}, ""},
+		{"no cors", func(c *Config) { c.CORSOrigins = nil }, ""},
+		{"wildcard with credentials", func(c *Config) { c.CORSAllowCredentials = true }, "cors_allow_credentials"},
+		{"origin with path", func(c *Config) { c.CORSOrigins = []string{"https://dash.example.com/ui"} }, "cors_origins[0]"},
+		{"bare host", func(c *Config) { c.CORSOrigins = []string{"dash.example.com"} }, "cors_origins[0]"},
+		{"tls", func(c *Config) { c.TLS = TLSConfig{CertFile: "c.pem", KeyFile: "k.pem", ClientCA: "ca.pem"} }, ""},
+		{"cert without key", func(c *Config) { c.TLS.CertFile = "c.pem" }, "tls.cert_file and tls.key_file"},
+		{"client ca without cert", func(c *Config) { c.TLS.ClientCA = "ca.pem" }, "tls.client_ca"},
+		{"ingest", func(c *Config) { c.Ingest.Enabled = true }, ""},
+		{"ingest max events", func(c *Config) { c.Ingest.Enabled, c.Ingest.MaxEvents = true, 0 }, "ingest.max_events"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.edit(&cfg)
+			err := cfg.Validate()
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("Validate() = %v, want error mentioning %q", err, tt.want)
+			}
+		})
+	}
+}
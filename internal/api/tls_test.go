@@ -0,0 +1,174 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// testCert is a generated certificate, its key, and their PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate signed by parent, or self-signed when
+// parent is nil, and writes it and its key to dir.
+func newTestCert(t *testing.T, dir, name string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tc := &testCert{cert: cert, key: key,
+		certFile: filepath.Join(dir, name+".pem"), keyFile: filepath.Join(dir, name+"-key.pem")}
+	writePEM(t, tc.certFile, "CERTIFICATE", der)
+	writePEM(t, tc.keyFile, "EC PRIVATE KEY", keyDER)
+	return tc
+}
+
+func writePEM(t *testing.T, path, typ string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// startTLSTestServer serves s over s.listen and returns its address.
+func startTLSTestServer(t *testing.T, cfg Config) string {
+	t.Helper()
+	cfg.Addr = "127.0.0.1:0"
+	s := &Server{cfg: cfg, app: fiber.New(fiber.Config{DisableStartupMessage: true}), logger: zap.NewNop()}
+	s.registerRoutes()
+	ln, err := s.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.app.Listener(ln)
+	t.Cleanup(func() { s.app.Shutdown() })
+	return ln.Addr().String()
+}
+
+func httpsGet(client *http.Client, addr string) (string, error) {
+	resp, err := client.Get("https://" + addr + "/healthz")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	return string(body), err
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestCert(t, dir, "server", nil, true)
+	addr := startTLSTestServer(t, Config{TLS: TLSConfig{CertFile: server.certFile, KeyFile: server.keyFile}})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if body, err := httpsGet(client, addr); err != nil || body != "ok" {
+		t.Fatalf("GET /healthz over TLS = %q, %v", body, err)
+	}
+
+	resp, err := http.Get("http://" + addr + "/healthz")
+	if err == nil && resp.StatusCode == 200 {
+		t.Error("plain HTTP served by a TLS listener")
+	}
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", nil, true)
+	server := newTestCert(t, dir, "server", ca, false)
+	client := newTestCert(t, dir, "client", ca, false)
+	stranger := newTestCert(t, dir, "stranger", nil, false)
+	addr := startTLSTestServer(t, Config{TLS: TLSConfig{
+		CertFile: server.certFile, KeyFile: server.keyFile, ClientCA: ca.certFile,
+	}})
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	clientWith := func(c *testCert) *http.Client {
+		cfg := &tls.Config{RootCAs: roots}
+		if c != nil {
+			pair, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Certificates = []tls.Certificate{pair}
+		}
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+	}
+
+	if body, err := httpsGet(clientWith(client), addr); err != nil || body != "ok" {
+		t.Fatalf("GET with a client certificate = %q, %v", body, err)
+	}
+	for name, c := range map[string]*testCert{"no certificate": nil, "untrusted certificate": stranger} {
+		if _, err := httpsGet(clientWith(c), addr); err == nil {
+			t.Errorf("%s: request succeeded, want handshake failure", name)
+		}
+	}
+}
+
+func TestTLSConfig_BadFiles(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestCert(t, dir, "server", nil, true)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for name, cfg := range map[string]TLSConfig{
+		"missing key":   {CertFile: server.certFile, KeyFile: filepath.Join(dir, "nope.pem")},
+		"empty CA file": {CertFile: server.certFile, KeyFile: server.keyFile, ClientCA: notPEM},
+	} {
+		if _, err := cfg.serverConfig(); err == nil {
+			t.Errorf("%s: serverConfig succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// latencyEventTypes are the event types that populate latency_sec, so
+// a p99 latency is meaningful for them.
+var latencyEventTypes = map[string]bool{
+	constants.ModuleTCP:    true,
+	constants.ModuleFileIO: true,
+}
+
+// topOffender is one ranked pod. P99Latency is omitted for event types
+// that carry no latency.
+type topOffender struct {
+	Namespace  string   `json:"namespace"`
+	Pod        string   `json:"pod"`
+	Count      uint64   `json:"count"`
+	P99Latency *float64 `json:"p99_latency,omitempty"`
+}
+
+func newTopOffender(evtType, ns, pod string, cnt uint64, p99 float64) topOffender {
+	o := topOffender{Namespace: ns, Pod: pod, Count: cnt}
+	if latencyEventTypes[evtType] {
+		o.P99Latency = &p99
+	}
+	return o
+}
+
+// handleTop returns the k pods with the most events of one type.
+func (s *Server) handleTop(c *fiber.Ctx) error {
+	evtType := c.Query("type")
+	if evtType == "" {
+		return badRequest(c, &paramError{"type", "is required"})
+	}
+	if err := (eventFilter{Type: evtType}).validate(); err != nil {
+		return badRequest(c, err)
+	}
+	k, err := queryIntIn(c, "k", constants.APITopDefaultK, 1, constants.APITopMaxK)
+	if err != nil {
+		return badRequest(c, err)
+	}
+	window, span, err := queryWindow(c)
+	if err != nil {
+		return badRequest(c, err)
+	}
+
+	cacheKey := "top:" + evtType + ":" + strconv.Itoa(k) + ":" + window
+	namespaces := requestNamespaces(c)
+	return s.respondCached(c, cacheKey, func(ctx context.Context) ([]byte, error) {
+		until := time.Now()
+		since := until.Add(-span)
+		pods, err := s.store.TopPods(ctx, storage.TopQuery{Type: evtType, Since: since, Until: until, K: k, Namespaces: namespaces})
+		if err != nil {
+			return nil, err
+		}
+		top := make([]topOffender, 0, len(pods))
+		for _, p := range pods {
+			top = append(top, newTopOffender(evtType, p.Namespace, p.Pod, p.Count, p.P99Latency))
+		}
+		return json.Marshal(fiber.Map{
+			"type":   evtType,
+			"k":      k,
+			"window": window,
+			"range":  rangeJSON(since, until),
+			"top":    top,
+		})
+	})
+}
+
+// topOffendersByType returns the k noisiest pods for every event type
+// seen in [since, until), in namespaces unless it is nil.
+func (s *Server) topOffendersByType(ctx context.Context, since, until time.Time, k int, namespaces []string) (map[string][]topOffender, error) {
+	pods, err := s.store.TopPods(ctx, storage.TopQuery{Since: since, Until: until, K: k, Namespaces: namespaces})
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string][]topOffender)
+	for _, p := range pods {
+		out[p.Type] = append(out[p.Type], newTopOffender(p.Type, p.Namespace, p.Pod, p.Count, p.P99Latency))
+	}
+	return out, nil
+}
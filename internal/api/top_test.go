@@ -0,0 +1,35 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func TestTop_Validation(t *testing.T) {
+	s := newViewsTestServer()
+	for _, tt := range []struct {
+		query, param string
+	}{
+		{"", "type"},
+		{"type=bogus", "type"},
+		{"type=oom&k=0", "k"},
+		{"type=oom&k=101", "k"},
+		{"type=oom&k=ten", "k"},
+		{"type=oom&window=-1h", "window"},
+	} {
+		resp, body := doJSON(t, s, "GET", "/api/v1/top?"+tt.query, "", nil)
+		if resp.StatusCode != 400 || errParam(body) != tt.param {
+			t.Errorf("%q: status=%d body=%v, want 400 on %s", tt.query, resp.StatusCode, body, tt.param)
+		}
+	}
+}
+
+func TestNewTopOffender_LatencyOnlyWhereApplicable(t *testing.T) {
+	if o := newTopOffender(constants.ModuleTCP, "ns", "p", 3, 0.2); o.P99Latency == nil || *o.P99Latency != 0.2 {
+		t.Errorf("tcp offender p99 = %v, want 0.2", o.P99Latency)
+	}
+	if o := newTopOffender(constants.ModuleRetransmit, "ns", "p", 3, 0); o.P99Latency != nil {
+		t.Errorf("retransmit offender has p99 %v, want none", *o.P99Latency)
+	}
+}
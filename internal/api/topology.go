@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// topologyEdge is one src pod → dst Service edge, with the fields of
+// storage.Edge.
+type topologyEdge struct {
+	SrcNamespace string  `json:"src_namespace"`
+	SrcPod       string  `json:"src_pod"`
+	DstService   string  `json:"dst_service"`
+	Count        uint64  `json:"count"`
+	P99Latency   float64 `json:"p99_latency"`
+}
+
+// handleTopology returns service-to-service edges for a relative window
+// (default 1h).
+func (s *Server) handleTopology(c *fiber.Ctx) error {
+	window, span, err := queryWindow(c)
+	if err != nil {
+		return badRequest(c, err)
+	}
+
+	namespaces := requestNamespaces(c)
+	return s.respondCached(c, "topology:"+window, func(ctx context.Context) ([]byte, error) {
+		until := time.Now()
+		since := until.Add(-span)
+		w := storage.Window{Since: since, Until: until, Namespaces: namespaces}
+		found, err := s.store.Topology(ctx, w, constants.APITopologyMaxEdges)
+		if err != nil {
+			return nil, err
+		}
+		edges := make([]topologyEdge, 0, len(found))
+		for _, e := range found {
+			edges = append(edges, topologyEdge(e))
+		}
+		return json.Marshal(fiber.Map{
+			"window": window,
+			"range":  rangeJSON(since, until),
+			"edges":  edges,
+		})
+	})
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// localsOwner is the fiber.Ctx Locals key holding the authenticated
+// caller's identity (the token name). Unauthenticated requests fall
+// back to constants.APIAnonymousOwner.
+const localsOwner = "owner"
+
+// errViewNotFound is returned by a viewStore when the id does not exist
+// for the requesting owner.
+var errViewNotFound = errors.New("view not found")
+
+// errViewLimit is returned by viewStore.Create when the owner already has
+// the maximum number of views.
+var errViewLimit = errors.New("view limit reached")
+
+// savedView is a named, persisted set of /events filters.
+type savedView struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Owner     string      `json:"owner"`
+	Filters   eventFilter `json:"filters"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// viewStore persists saved views, partitioned by owner.
+// Lookups are always owner-scoped so one caller can't read another's views.
+type viewStore interface {
+	// Create stores v unless its owner already has max views, in which
+	// case it returns errViewLimit. Concurrent creates can't exceed max.
+	Create(ctx context.Context, v savedView, max int) error
+	Get(ctx context.Context, owner, id string) (savedView, error)
+	List(ctx context.Context, owner string) ([]savedView, error)
+	Delete(ctx context.Context, owner, id string) error
+}
+
+// ─── Handlers ────────────────────────────────────────────────────
+
+// handleCreateView stores a new saved view for the caller.
+func (s *Server) handleCreateView(c *fiber.Ctx) error {
+	var req struct {
+		Name    string      `json:"name"`
+		Filters eventFilter `json:"filters"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return badRequest(c, errors.New("invalid request body"))
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" || len(req.Name) > constants.APIMaxViewNameLen {
+		return badRequest(c, &paramError{"name",
+			fmt.Sprintf("is required and must be at most %d characters", constants.APIMaxViewNameLen)})
+	}
+	if err := req.Filters.validate(); err != nil {
+		// Name the field as it appears in the body.
+		var pe *paramError
+		if errors.As(err, &pe) {
+			err = &paramError{"filters." + pe.Param, pe.Reason}
+		}
+		return badRequest(c, err)
+	}
+	if _, err := scopeNamespaces(c, req.Filters.Namespace); err != nil {
+		return forbidden(c, err.Error())
+	}
+
+	v := savedView{
+		ID:        newViewID(),
+		Name:      req.Name,
+		Owner:     requestOwner(c),
+		Filters:   req.Filters,
+		CreatedAt: time.Now().UTC(),
+	}
+	switch err := s.views.Create(c.UserContext(), v, constants.APIMaxViewsPerOwner); {
+	case errors.Is(err, errViewLimit):
+		return conflict(c, fmt.Sprintf("view limit reached (%d per owner)", constants.APIMaxViewsPerOwner))
+	case err != nil:
+		return s.storageError(c, "Saving view failed", err)
+	}
+	return c.Status(201).JSON(v)
+}
+
+// handleListViews returns the caller's saved views, oldest first.
+func (s *Server) handleListViews(c *fiber.Ctx) error {
+	views, err := s.views.List(c.UserContext(), requestOwner(c))
+	if err != nil {
+		return s.storageError(c, "Listing views failed", err)
+	}
+	return c.JSON(fiber.Map{"views": views})
+}
+
+// handleGetView returns one of the caller's saved views.
+func (s *Server) handleGetView(c *fiber.Ctx) error {
+	v, err := s.views.Get(c.UserContext(), requestOwner(c), c.Params("id"))
+	if err != nil {
+		return s.viewError(c, err)
+	}
+	return c.JSON(v)
+}
+
+// handleDeleteView removes one of the caller's saved views.
+func (s *Server) handleDeleteView(c *fiber.Ctx) error {
+	if err := s.views.Delete(c.UserContext(), requestOwner(c), c.Params("id")); err != nil {
+		return s.viewError(c, err)
+	}
+	return c.SendStatus(204)
+}
+
+// handleViewEvents executes a saved view's filters against /events.
+// Query parameters on the request override the stored filters.
+func (s *Server) handleViewEvents(c *fiber.Ctx) error {
+	v, err := s.views.Get(c.UserContext(), requestOwner(c), c.Params("id"))
+	if err != nil {
+		return s.viewError(c, err)
+	}
+	f, err := v.Filters.withOverrides(c)
+	if err != nil {
+		return badRequest(c, err)
+	}
+	if err := f.validate(); err != nil {
+		return badRequest(c, err)
+	}
+	return s.respondEvents(c, f)
+}
+
+func (s *Server) viewError(c *fiber.Ctx, err error) error {
+	if errors.Is(err, errViewNotFound) {
+		return notFound(c, "view not found")
+	}
+	return s.storageError(c, "View lookup failed", err)
+}
+
+// storageError responds to a view store failure: 503 when the API server
+// runs without Redis, otherwise a logged 500.
+func (s *Server) storageError(c *fiber.Ctx, msg string, err error) error {
+	if errors.Is(err, cache.ErrDisabled) {
+		return unavailable(c, "saved views need Redis, which this API server is running without")
+	}
+	return s.internalError(c, msg, err)
+}
+
+// requestOwner returns the identity views are scoped to.
+func requestOwner(c *fiber.Ctx) string {
+	if owner, ok := c.Locals(localsOwner).(string); ok && owner != "" {
+		return owner
+	}
+	return constants.APIAnonymousOwner
+}
+
+// newViewID returns a random 16-hex-char view identifier.
+func newViewID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+func sortViews(views []savedView) {
+	sort.Slice(views, func(i, j int) bool {
+		return views[i].CreatedAt.Before(views[j].CreatedAt)
+	})
+}
+
+// ─── Redis store ─────────────────────────────────────────────────
+
+// redisViewStore keeps one Redis hash per owner: field = view id,
+// value = JSON-encoded savedView.
+type redisViewStore struct {
+	redis cache.Cache
+}
+
+func newRedisViewStore(r cache.Cache) *redisViewStore {
+	return &redisViewStore{redis: r}
+}
+
+func (r *redisViewStore) key(owner string) string {
+	return constants.RedisViewsKeyPrefix + owner
+}
+
+func (r *redisViewStore) Create(ctx context.Context, v savedView, max int) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	set, err := r.redis.HSetCapped(ctx, r.key(v.Owner), v.ID, string(data), int64(max))
+	if err == nil && !set {
+		err = errViewLimit
+	}
+	return err
+}
+
+func (r *redisViewStore) Get(ctx context.Context, owner, id string) (savedView, error) {
+	var v savedView
+	raw, err := r.redis.HGet(ctx, r.key(owner), id)
+	if err != nil {
+		if cache.IsNil(err) {
+			return v, errViewNotFound
+		}
+		return v, err
+	}
+	err = json.Unmarshal([]byte(raw), &v)
+	return v, err
+}
+
+func (r *redisViewStore) List(ctx context.Context, owner string) ([]savedView, error) {
+	all, err := r.redis.HGetAll(ctx, r.key(owner))
+	if err != nil {
+		return nil, err
+	}
+	views := make([]savedView, 0, len(all))
+	for _, raw := range all {
+		var v savedView
+		if err := json.Unmarshal([]byte(raw), &v); err != nil {
+			continue
+		}
+		views = append(views, v)
+	}
+	sortViews(views)
+	return views, nil
+}
+
+func (r *redisViewStore) Delete(ctx context.Context, owner, id string) error {
+	n, err := r.redis.HDel(ctx, r.key(owner), id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errViewNotFound
+	}
+	return nil
+}
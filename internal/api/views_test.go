@@ -0,0 +1,263 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// ─── In-memory store ─────────────────────────────────────────────
+
+// memViewStore is a process-local viewStore used by tests.
+type memViewStore struct {
+	mu    sync.Mutex
+	views map[string]map[string]savedView // owner → id → view
+}
+
+func newMemViewStore() *memViewStore {
+	return &memViewStore{views: make(map[string]map[string]savedView)}
+}
+
+func (m *memViewStore) Create(_ context.Context, v savedView, max int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.views[v.Owner]) >= max {
+		return errViewLimit
+	}
+	if m.views[v.Owner] == nil {
+		m.views[v.Owner] = make(map[string]savedView)
+	}
+	m.views[v.Owner][v.ID] = v
+	return nil
+}
+
+func (m *memViewStore) Get(_ context.Context, owner, id string) (savedView, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.views[owner][id]
+	if !ok {
+		return savedView{}, errViewNotFound
+	}
+	return v, nil
+}
+
+func (m *memViewStore) List(_ context.Context, owner string) ([]savedView, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	views := make([]savedView, 0, len(m.views[owner]))
+	for _, v := range m.views[owner] {
+		views = append(views, v)
+	}
+	sortViews(views)
+	return views, nil
+}
+
+func (m *memViewStore) Delete(_ context.Context, owner, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.views[owner][id]; !ok {
+		return errViewNotFound
+	}
+	delete(m.views[owner], id)
+	return nil
+}
+
+// newViewsTestServer builds a Server with an in-memory view store.
+// The X-Test-Owner header stands in for the authenticated token name.
+func newViewsTestServer() *Server {
+	s := &Server{
+		app:    fiber.New(),
+		store:  storage.NewMemory(),
+		views:  newMemViewStore(),
+		logger: zap.NewNop(),
+	}
+	s.app.Use(func(c *fiber.Ctx) error {
+		if owner := c.Get("X-Test-Owner"); owner != "" {
+			c.Locals(localsOwner, owner)
+		}
+		return c.Next()
+	})
+	s.registerRoutes()
+	return s
+}
+
+func doJSON(t *testing.T, s *Server, method, path, owner string, body any) (*http.Response, map[string]any) {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	if owner != "" {
+		req.Header.Set("X-Test-Owner", owner)
+	}
+	resp, err := s.app.Test(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out map[string]any
+	json.NewDecoder(resp.Body).Decode(&out)
+	return resp, out
+}
+
+func TestViews_CRUD(t *testing.T) {
+	s := newViewsTestServer()
+
+	resp, created := doJSON(t, s, "POST", "/api/v1/views", "alice", map[string]any{
+		"name":    "prod ooms",
+		"filters": map[string]any{"type": "oom", "namespace": "prod", "limit": 50},
+	})
+	if resp.StatusCode != 201 {
+		t.Fatalf("create status = %d, want 201 (%v)", resp.StatusCode, created)
+	}
+	id, _ := created["id"].(string)
+	if id == "" || created["owner"] != "alice" {
+		t.Fatalf("unexpected created view: %v", created)
+	}
+
+	resp, list := doJSON(t, s, "GET", "/api/v1/views", "alice", nil)
+	if resp.StatusCode != 200 {
+		t.Fatalf("list status = %d", resp.StatusCode)
+	}
+	if views, _ := list["views"].([]any); len(views) != 1 {
+		t.Fatalf("list = %v, want 1 view", list)
+	}
+
+	resp, got := doJSON(t, s, "GET", "/api/v1/views/"+id, "alice", nil)
+	if resp.StatusCode != 200 || got["name"] != "prod ooms" {
+		t.Fatalf("get status = %d body = %v", resp.StatusCode, got)
+	}
+
+	resp, _ = doJSON(t, s, "DELETE", "/api/v1/views/"+id, "alice", nil)
+	if resp.StatusCode != 204 {
+		t.Fatalf("delete status = %d, want 204", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, s, "GET", "/api/v1/views/"+id, "alice", nil)
+	if resp.StatusCode != 404 {
+		t.Fatalf("get after delete status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestViews_OwnerScoped(t *testing.T) {
+	s := newViewsTestServer()
+
+	_, created := doJSON(t, s, "POST", "/api/v1/views", "alice", map[string]any{
+		"name": "mine", "filters": map[string]any{"type": "tcp"},
+	})
+	id := created["id"].(string)
+
+	for _, path := range []string{"/api/v1/views/" + id, "/api/v1/views/" + id + "/events"} {
+		resp, _ := doJSON(t, s, "GET", path, "bob", nil)
+		if resp.StatusCode != 404 {
+			t.Errorf("GET %s as other owner = %d, want 404", path, resp.StatusCode)
+		}
+	}
+	resp, _ := doJSON(t, s, "DELETE", "/api/v1/views/"+id, "bob", nil)
+	if resp.StatusCode != 404 {
+		t.Errorf("DELETE as other owner = %d, want 404", resp.StatusCode)
+	}
+	_, list := doJSON(t, s, "GET", "/api/v1/views", "bob", nil)
+	if views, _ := list["views"].([]any); len(views) != 0 {
+		t.Errorf("other owner sees %d views, want 0", len(views))
+	}
+}
+
+func TestViews_Validation(t *testing.T) {
+	s := newViewsTestServer()
+
+	tests := []struct {
+		name string
+		body map[string]any
+	}{
+		{"missing name", map[string]any{"filters": map[string]any{}}},
+		{"unknown type", map[string]any{"name": "x", "filters": map[string]any{"type": "bogus"}}},
+		{"bad since", map[string]any{"name": "x", "filters": map[string]any{"since": "yesterday"}}},
+		{"limit too large", map[string]any{"name": "x", "filters": map[string]any{"limit": constants.APIMaxPageSize + 1}}},
+		{"negative offset", map[string]any{"name": "x", "filters": map[string]any{"offset": -1}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, body := doJSON(t, s, "POST", "/api/v1/views", "alice", tt.body)
+			if resp.StatusCode != 400 {
+				t.Errorf("status = %d, want 400 (%v)", resp.StatusCode, body)
+			}
+		})
+	}
+}
+
+func TestViews_PerOwnerLimit(t *testing.T) {
+	s := newViewsTestServer()
+	for i := 0; i < constants.APIMaxViewsPerOwner; i++ {
+		resp, _ := doJSON(t, s, "POST", "/api/v1/views", "alice", map[string]any{"name": fmt.Sprintf("v%d", i)})
+		if resp.StatusCode != 201 {
+			t.Fatalf("create %d status = %d", i, resp.StatusCode)
+		}
+	}
+	resp, _ := doJSON(t, s, "POST", "/api/v1/views", "alice", map[string]any{"name": "one too many"})
+	if resp.StatusCode != 409 {
+		t.Errorf("status over limit = %d, want 409", resp.StatusCode)
+	}
+	resp, _ = doJSON(t, s, "POST", "/api/v1/views", "bob", map[string]any{"name": "bob's first"})
+	if resp.StatusCode != 201 {
+		t.Errorf("other owner status = %d, want 201", resp.StatusCode)
+	}
+}
+
+func TestViews_PerOwnerLimitConcurrent(t *testing.T) {
+	s := newViewsTestServer()
+	var wg sync.WaitGroup
+	for i := range 2 * constants.APIMaxViewsPerOwner {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			doJSON(t, s, "POST", "/api/v1/views", "alice", map[string]any{"name": fmt.Sprintf("v%d", i)})
+		}()
+	}
+	wg.Wait()
+	views, _ := s.views.List(context.Background(), "alice")
+	if len(views) != constants.APIMaxViewsPerOwner {
+		t.Errorf("concurrent creates stored %d views, want the limit %d", len(views), constants.APIMaxViewsPerOwner)
+	}
+}
+
+func TestViews_ExecutionAppliesOverrides(t *testing.T) {
+	stored := eventFilter{Type: "oom", Namespace: "prod", Since: "2026-01-01T00:00:00Z", Limit: 20}
+
+	app := fiber.New()
+	var got eventFilter
+	app.Get("/", func(c *fiber.Ctx) error {
+		got, _ = stored.withOverrides(c)
+		return nil
+	})
+	if _, err := app.Test(httptest.NewRequest("GET", "/?namespace=staging&limit=5", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	want := eventFilter{Type: "oom", Namespace: "staging", Since: "2026-01-01T00:00:00Z", Limit: 5}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("withOverrides = %+v, want %+v", got, want)
+	}
+
+	since, until := got.timeRange(time.Now())
+	wantSince, _ := time.Parse(time.RFC3339, want.Since)
+	wantQuery := storage.EventQuery{Type: "oom", Namespace: "staging", Since: wantSince, Limit: 5}
+	if q := got.query(since, until); !reflect.DeepEqual(q, wantQuery) {
+		t.Errorf("query = %+v, want %+v", q, wantQuery)
+	}
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	fws "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// startWSTestServer serves the API routes on a loopback listener and
+// returns its address.
+func startWSTestServer(t *testing.T, s *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go s.app.Listener(ln)
+	t.Cleanup(func() { s.app.Shutdown() })
+	return ln.Addr().String()
+}
+
+func newWSTestServer() *Server {
+	return &Server{
+		app:    fiber.New(fiber.Config{DisableStartupMessage: true}),
+		views:  newMemViewStore(),
+		hub:    newHub(),
+		logger: zap.NewNop(),
+	}
+}
+
+// waitClients blocks until the hub has n registered connections.
+func waitClients(t *testing.T, h *hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		h.mu.Lock()
+		got := len(h.clients)
+		h.mu.Unlock()
+		if got == n {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("hub never reached %d clients", n)
+}
+
+func TestWS_FiltersServerSide(t *testing.T) {
+	s := newWSTestServer()
+	s.registerRoutes()
+	addr := startWSTestServer(t, s)
+
+	conn, _, err := fws.DefaultDialer.Dial("ws://"+addr+"/ws/events?type=dns&namespace=prod", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitClients(t, s.hub, 1)
+
+	now := time.Now()
+	for i, p := range []string{
+		`{"type":"tcp","namespace":"prod","seq":0}`,
+		`{"type":"dns","namespace":"dev","seq":1}`,
+		`{"type":"dns","namespace":"prod","seq":2}`,
+		`{"type":"dns","namespace":"prod","seq":3}`,
+	} {
+		s.hub.broadcast([]byte(p), now.Add(time.Duration(i)*time.Millisecond))
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, want := range []string{`"seq":2`, `"seq":3`} {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(msg), want) {
+			t.Fatalf("got %s, want message with %s", msg, want)
+		}
+	}
+}
+
+func TestWS_RejectsUnknownTypeBeforeUpgrade(t *testing.T) {
+	s := newWSTestServer()
+	s.registerRoutes()
+	addr := startWSTestServer(t, s)
+
+	_, resp, err := fws.DefaultDialer.Dial("ws://"+addr+"/ws/events?type=bogus", nil)
+	if err == nil {
+		t.Fatal("dial succeeded, want handshake failure")
+	}
+	if resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("response = %v, want 400", resp)
+	}
+}
+
+func TestWS_SlowClientDoesNotBlockHub(t *testing.T) {
+	s := newWSTestServer()
+	s.registerRoutes()
+	addr := startWSTestServer(t, s)
+
+	// Never read from this connection.
+	conn, _, err := fws.DefaultDialer.Dial("ws://"+addr+"/ws/events?max_rate=500", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitClients(t, s.hub, 1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		payload := []byte(fmt.Sprintf(`{"type":"tcp","pad":%q}`, strings.Repeat("x", 4096)))
+		now := time.Now()
+		for i := range 10000 {
+			s.hub.broadcast(payload, now.Add(time.Duration(i)*time.Second))
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcast blocked on a stalled client")
+	}
+}
@@ -0,0 +1,140 @@
+package bpfutil
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"go.uber.org/zap"
+)
+
+// ErrProgramNotLoaded is returned by a Hook whose program is not in the
+// loaded objects: left out by WithoutPrograms, or absent from objects
+// built before it was added.
+var ErrProgramNotLoaded = errors.New("program not loaded")
+
+// Hook attaches one program and returns its link.
+type Hook func() (link.Link, error)
+
+// Tracing returns a Hook for the fentry or fexit program name, which
+// attaches to the kernel function it was loaded against. Its link is
+// pinned as name when r has pins.
+func (r *Resources) Tracing(name string) Hook {
+	return r.pinned(name, func() (link.Link, error) {
+		var prog *ebpf.Program
+		if r != nil {
+			prog = r.Programs[name]
+		}
+		if prog == nil {
+			return nil, fmt.Errorf("%s: %w", name, ErrProgramNotLoaded)
+		}
+		l, err := link.AttachTracing(link.TracingOptions{Program: prog})
+		if err != nil {
+			return nil, fmt.Errorf("attaching %s: %w", name, err)
+		}
+		return l, nil
+	})
+}
+
+// Kprobe is the package's Kprobe, pinned as kprobe_<symbol> when r has
+// pins.
+func (r *Resources) Kprobe(symbol string, prog *ebpf.Program) Hook {
+	return r.pinned("kprobe_"+symbol, Kprobe(symbol, prog))
+}
+
+// Kretprobe is the package's Kretprobe, pinned as kretprobe_<symbol> when
+// r has pins.
+func (r *Resources) Kretprobe(symbol string, prog *ebpf.Program) Hook {
+	return r.pinned("kretprobe_"+symbol, Kretprobe(symbol, prog))
+}
+
+// Tracepoint returns a Hook attaching prog to tracepoint group/name,
+// pinned as tracepoint_<group>_<name> when r has pins.
+func (r *Resources) Tracepoint(group, name string, prog *ebpf.Program) Hook {
+	return r.pinned("tracepoint_"+group+"_"+name, func() (link.Link, error) {
+		l, err := link.Tracepoint(group, name, prog, nil)
+		if err != nil {
+			return nil, fmt.Errorf("attaching %s tracepoint: %w", name, err)
+		}
+		return l, nil
+	})
+}
+
+// pinned wraps h to reuse or pin its link as name.
+func (r *Resources) pinned(name string, h Hook) Hook {
+	if r == nil || r.pins == nil {
+		return h
+	}
+	return func() (link.Link, error) { return r.pins.attach(name, h) }
+}
+
+// Kprobe returns a Hook attaching prog to the entry of symbol.
+func Kprobe(symbol string, prog *ebpf.Program) Hook {
+	return func() (link.Link, error) {
+		l, err := link.Kprobe(symbol, prog, nil)
+		if err != nil {
+			return nil, fmt.Errorf("attaching %s kprobe: %w", symbol, err)
+		}
+		return l, nil
+	}
+}
+
+// Kretprobe returns a Hook attaching prog to the return of symbol.
+func Kretprobe(symbol string, prog *ebpf.Program) Hook {
+	return func() (link.Link, error) {
+		l, err := link.Kretprobe(symbol, prog, nil)
+		if err != nil {
+			return nil, fmt.Errorf("attaching %s kretprobe: %w", symbol, err)
+		}
+		return l, nil
+	}
+}
+
+// Attachment is one way of attaching a module's programs, named by Mode
+// (constants.AttachMode*).
+type Attachment struct {
+	Mode  string
+	Hooks []Hook
+}
+
+// AttachFirst attaches the first attachment whose hooks all succeed and
+// returns its mode and links. Links of a failed attachment are closed
+// before the next one is tried. Failures are logged as fallbacks, except
+// for attachments whose programs were simply not loaded.
+func AttachFirst(logger *zap.Logger, attachments ...Attachment) (string, []link.Link, error) {
+	var errs []error
+	for i, a := range attachments {
+		links, err := a.attach()
+		if err == nil {
+			return a.Mode, links, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", a.Mode, err))
+		if i+1 < len(attachments) {
+			log := logger.Warn
+			if errors.Is(err, ErrProgramNotLoaded) {
+				log = logger.Debug
+			}
+			log("Attach failed — falling back",
+				zap.String("mode", a.Mode),
+				zap.String("fallback", attachments[i+1].Mode),
+				zap.Error(err))
+		}
+	}
+	return "", nil, errors.Join(errs...)
+}
+
+func (a Attachment) attach() ([]link.Link, error) {
+	links := make([]link.Link, 0, len(a.Hooks))
+	for _, hook := range a.Hooks {
+		l, err := hook()
+		if err != nil {
+			for _, l := range links {
+				l.Close()
+			}
+			return nil, err
+		}
+		links = append(links, l)
+	}
+	return links, nil
+}
@@ -0,0 +1,79 @@
+package bpfutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf/link"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// errENOTSUPP is the kernel-internal ENOTSUPP that fentry attach returns
+// when the kernel has no BPF trampoline for the architecture.
+const errENOTSUPP = unix.Errno(524)
+
+func failingHook(err error) Hook {
+	return func() (link.Link, error) { return nil, err }
+}
+
+func TestAttachFirst_FallsBackOnENOTSUPP(t *testing.T) {
+	fentry := Attachment{Mode: constants.AttachModeFentry, Hooks: []Hook{
+		failingHook(fmt.Errorf("attaching fentry_tcp_connect: %w", errENOTSUPP)),
+	}}
+	var kprobeAttached bool
+	kprobe := Attachment{Mode: constants.AttachModeKprobe, Hooks: []Hook{
+		func() (link.Link, error) { kprobeAttached = true; return nil, nil },
+	}}
+
+	mode, links, err := AttachFirst(zap.NewNop(), fentry, kprobe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode != constants.AttachModeKprobe || !kprobeAttached {
+		t.Errorf("mode = %q, kprobe attached %v; want kprobe fallback", mode, kprobeAttached)
+	}
+	if len(links) != 1 {
+		t.Errorf("got %d links, want 1", len(links))
+	}
+}
+
+func TestAttachFirst_StopsAtFirstSuccess(t *testing.T) {
+	fentry := Attachment{Mode: constants.AttachModeFentry}
+	kprobe := Attachment{Mode: constants.AttachModeKprobe, Hooks: []Hook{
+		func() (link.Link, error) { t.Error("kprobe attached after fentry succeeded"); return nil, nil },
+	}}
+	if mode, _, err := AttachFirst(zap.NewNop(), fentry, kprobe); mode != constants.AttachModeFentry || err != nil {
+		t.Errorf("AttachFirst = %q, %v; want fentry", mode, err)
+	}
+}
+
+func TestAttachFirst_AllFail(t *testing.T) {
+	_, links, err := AttachFirst(zap.NewNop(),
+		Attachment{Mode: constants.AttachModeFentry, Hooks: []Hook{failingHook(errENOTSUPP)}},
+		Attachment{Mode: constants.AttachModeKprobe, Hooks: []Hook{failingHook(unix.ENOENT)}},
+	)
+	if err == nil || links != nil {
+		t.Fatalf("AttachFirst = %v, %v; want error", links, err)
+	}
+	if !errors.Is(err, errENOTSUPP) || !errors.Is(err, unix.ENOENT) {
+		t.Errorf("error %v does not wrap every attempt's error", err)
+	}
+	for _, mode := range []string{constants.AttachModeFentry, constants.AttachModeKprobe} {
+		if !strings.Contains(err.Error(), mode) {
+			t.Errorf("error %q does not name mode %s", err, mode)
+		}
+	}
+}
+
+func TestTracing_NotLoaded(t *testing.T) {
+	for _, res := range []*Resources{nil, {}} {
+		if _, err := res.Tracing(constants.BPFProgFentryTCPConnect)(); !errors.Is(err, ErrProgramNotLoaded) {
+			t.Errorf("Tracing on %+v = %v, want ErrProgramNotLoaded", res, err)
+		}
+	}
+}
@@ -5,6 +5,7 @@ package bpfutil
 import (
 	"bytes"
 	"fmt"
+	"strconv"
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 )
@@ -38,15 +39,39 @@ func FilenameString(filename [constants.FilenameSize]byte) string {
 
 // FormatIPv4 converts a uint32 IPv4 address to dotted-decimal string.
 func FormatIPv4(ip uint32) string {
-	return fmt.Sprintf("%d.%d.%d.%d",
-		byte(ip), byte(ip>>8), byte(ip>>16), byte(ip>>24))
+	var buf [len("255.255.255.255")]byte
+	return string(AppendIPv4(buf[:0], ip))
 }
 
-// DropReasonString maps a kernel SKB drop reason code to a human-readable string.
-// Uses the drop reasons table from the constants package.
-func DropReasonString(reason uint32) string {
-	if s, ok := constants.DropReasons[reason]; ok {
+// AppendIPv4 appends ip in dotted-decimal form to b.
+func AppendIPv4(b []byte, ip uint32) []byte {
+	for i := range 4 {
+		if i > 0 {
+			b = append(b, '.')
+		}
+		b = strconv.AppendUint(b, uint64(byte(ip>>(8*i))), 10)
+	}
+	return b
+}
+
+// FormatAddr formats an IPv4 address and port as "ip:port", the form of the
+// src and dst event labels.
+func FormatAddr(ip uint32, port uint16) string {
+	var buf [len("255.255.255.255:65535")]byte
+	return string(AppendIPv4Port(buf[:0], ip, port))
+}
+
+// AppendIPv4Port appends ip and port to b as FormatAddr formats them.
+func AppendIPv4Port(b []byte, ip uint32, port uint16) []byte {
+	b = append(AppendIPv4(b, ip), ':')
+	return strconv.AppendUint(b, uint64(port), 10)
+}
+
+// TCPStateString maps a kernel TCP socket state to its name, e.g.
+// ESTABLISHED.
+func TCPStateString(state uint32) string {
+	if s, ok := constants.TCPStates[state]; ok {
 		return s
 	}
-	return fmt.Sprintf("REASON_%d", reason)
+	return fmt.Sprintf("STATE_%d", state)
 }
@@ -0,0 +1,69 @@
+package bpfutil
+
+import (
+	"encoding/binary"
+	"fmt"
+	"unsafe"
+)
+
+// Decoder copies ring buffer records into T, a module's struct mirroring
+// its C event, without encoding/binary's reflection and allocations.
+//
+// The record's bytes are copied straight into T's memory, so T's Go layout
+// must match the C one: fixed-size fields with the C padding spelled out,
+// leaving the compiler nothing to insert. NewDecoder checks this. Records
+// are little-endian, like every host the bpfel objects are built for.
+type Decoder[T any] struct {
+	size int
+}
+
+// NewDecoder returns a Decoder for T. It panics if T has padding or
+// variable-size fields, which would misplace fields when decoding; a
+// package-level decoder therefore fails the module's tests at init.
+func NewDecoder[T any]() Decoder[T] {
+	var zero T
+	if size := binary.Size(zero); size < 0 || uintptr(size) != unsafe.Sizeof(zero) {
+		panic(fmt.Sprintf("bpfutil: %T has implicit padding or variable-size fields (%d bytes encoded, %d in memory)",
+			zero, size, unsafe.Sizeof(zero)))
+	}
+	return Decoder[T]{size: int(unsafe.Sizeof(zero))}
+}
+
+// Size is the encoded size of T, sizeof the C event.
+func (d Decoder[T]) Size() int { return d.size }
+
+// Decode decodes sample, failing if it is shorter than T. Bytes past T are
+// ignored.
+func (d Decoder[T]) Decode(sample []byte) (T, error) {
+	var raw T
+	if len(sample) < d.size {
+		return raw, fmt.Errorf("record is %d bytes, want %d", len(sample), d.size)
+	}
+	d.copy(&raw, sample)
+	return raw, nil
+}
+
+// DecodePadded decodes sample, zero-filling the fields a short one lacks;
+// for events that grew fields which objects built before them do not send.
+// Modules decode with DecodeMin, which also rejects records too short for
+// any layout.
+func (d Decoder[T]) DecodePadded(sample []byte) T {
+	var raw T
+	d.copy(&raw, sample)
+	return raw
+}
+
+// DecodeMin is DecodePadded for records of at least min bytes, the size of
+// the oldest layout still accepted; a shorter record is an error rather
+// than an event of zeros.
+func (d Decoder[T]) DecodeMin(sample []byte, min int) (T, error) {
+	if len(sample) < min {
+		var zero T
+		return zero, fmt.Errorf("record is %d bytes, want at least %d", len(sample), min)
+	}
+	return d.DecodePadded(sample), nil
+}
+
+func (d Decoder[T]) copy(raw *T, sample []byte) {
+	copy(unsafe.Slice((*byte)(unsafe.Pointer(raw)), d.size), sample)
+}
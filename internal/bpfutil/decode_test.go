@@ -0,0 +1,146 @@
+package bpfutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// sampleEvent has the shape of the modules' events: mixed widths, explicit
+// padding and a byte array.
+type sampleEvent struct {
+	PID       uint32
+	SAddr     uint32
+	SPort     uint16
+	Family    uint16
+	Pad       uint32
+	Timestamp uint64
+	LatencyNs int64
+	Comm      [constants.CommSize]byte
+}
+
+func encode(t testing.TB, v any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func sample() sampleEvent {
+	in := sampleEvent{PID: 42, SAddr: 0x0100007f, SPort: 8080, Family: 2, Timestamp: 1 << 40, LatencyNs: -5}
+	copy(in.Comm[:], "curl")
+	return in
+}
+
+func TestDecoder_MatchesBinaryRead(t *testing.T) {
+	d := NewDecoder[sampleEvent]()
+	in := sample()
+	data := encode(t, in)
+	if d.Size() != len(data) {
+		t.Fatalf("Size = %d, want %d", d.Size(), len(data))
+	}
+
+	got, err := d.Decode(append(data, 0xff, 0xff)) // trailing bytes ignored
+	if err != nil || got != in {
+		t.Errorf("Decode = %+v, %v; want %+v", got, err, in)
+	}
+	if _, err := d.Decode(data[:len(data)-1]); err == nil {
+		t.Error("Decode accepted a short record")
+	}
+
+	short := d.DecodePadded(data[:16])
+	if short.PID != 42 || short.SPort != 8080 || short.Timestamp != 0 || short.Comm != [constants.CommSize]byte{} {
+		t.Errorf("DecodePadded(short) = %+v, want the first fields only", short)
+	}
+	if got, err := d.DecodeMin(data[:16], 16); err != nil || got != short {
+		t.Errorf("DecodeMin(16 bytes, 16) = %+v, %v; want %+v", got, err, short)
+	}
+	if _, err := d.DecodeMin(data[:15], 16); err == nil {
+		t.Error("DecodeMin accepted a record shorter than min")
+	}
+}
+
+func TestCEventSize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "probe.c")
+	src := "struct probe_event { __u32 pid; };\n_Static_assert(sizeof(struct probe_event) == 4, \"probe_event layout changed\");\n"
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := CEventSize(path, "probe_event"); err != nil || n != 4 {
+		t.Errorf("CEventSize = %d, %v; want 4", n, err)
+	}
+	if _, err := CEventSize(path, "probe"); err == nil {
+		t.Error("CEventSize found a size for a struct the source doesn't assert")
+	}
+}
+
+func TestNewDecoder_RejectsImplicitPadding(t *testing.T) {
+	type padded struct {
+		A uint32
+		B uint64 // the compiler inserts 4 bytes before B
+	}
+	defer func() {
+		if recover() == nil {
+			t.Error("NewDecoder accepted a struct with implicit padding")
+		}
+	}()
+	NewDecoder[padded]()
+}
+
+func FuzzDecoder(f *testing.F) {
+	f.Add(encode(f, sample()))
+	f.Add([]byte{})
+	f.Add(make([]byte, 3))
+	f.Add(make([]byte, 4096))
+	d := NewDecoder[sampleEvent]()
+	f.Fuzz(func(t *testing.T, data []byte) {
+		padded := d.DecodePadded(data)
+		strict, err := d.Decode(data)
+		if _, minErr := d.DecodeMin(data, 16); (minErr == nil) != (len(data) >= 16) {
+			t.Fatalf("DecodeMin(%d bytes, 16) error = %v", len(data), minErr)
+		}
+		if (err == nil) != (len(data) >= d.Size()) {
+			t.Fatalf("Decode(%d bytes) error = %v", len(data), err)
+		}
+		if err == nil && strict != padded {
+			t.Fatalf("Decode = %+v, DecodePadded = %+v", strict, padded)
+		}
+		if len(data) >= d.Size() {
+			var want sampleEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if strict != want {
+				t.Fatalf("Decode = %+v, binary.Read = %+v", strict, want)
+			}
+		}
+	})
+}
+
+// BenchmarkDecode compares the binary.Read the modules used before with
+// Decoder.
+func BenchmarkDecode(b *testing.B) {
+	data := encode(b, sample())
+	b.Run("binary.Read", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var raw sampleEvent
+			if err := binary.Read(bytes.NewReader(data), binary.LittleEndian, &raw); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Decoder", func(b *testing.B) {
+		d := NewDecoder[sampleEvent]()
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := d.Decode(data); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package bpfutil
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// dropReasons is the reason table DropReasonString consults, loaded on
+// first use.
+var dropReasons = sync.OnceValue(func() map[uint32]string {
+	return loadDropReasons(btf.LoadKernelSpec)
+})
+
+// DropReasonString maps a kernel SKB drop reason code to its name, e.g.
+// NO_SOCKET. Codes are numbered differently across kernel versions, so
+// names come from the running kernel's enum skb_drop_reason when its BTF
+// is available, else from constants.DropReasons. Unknown codes are
+// reported as REASON_<code>.
+func DropReasonString(reason uint32) string {
+	if s, ok := dropReasons()[reason]; ok {
+		return s
+	}
+	return fmt.Sprintf("REASON_%d", reason)
+}
+
+// loadDropReasons reads enum skb_drop_reason from the kernel's BTF,
+// falling back to the generated table.
+func loadDropReasons(kernelBTF func() (*btf.Spec, error)) map[uint32]string {
+	spec, err := kernelBTF()
+	if err != nil {
+		return constants.DropReasons
+	}
+	var enum *btf.Enum
+	if err := spec.TypeByName(constants.BTFEnumDropReason, &enum); err != nil {
+		return constants.DropReasons
+	}
+	if reasons := dropReasonNames(enum); len(reasons) > 0 {
+		return reasons
+	}
+	return constants.DropReasons
+}
+
+// dropReasonNames names enum's values the way constants.DropReasons does:
+// without the SKB_DROP_REASON_ (or SKB_) prefix, skipping the MAX and
+// SUBSYS_MASK markers.
+func dropReasonNames(enum *btf.Enum) map[uint32]string {
+	reasons := make(map[uint32]string, len(enum.Values))
+	for _, v := range enum.Values {
+		switch v.Name {
+		case "SKB_DROP_REASON_MAX", "SKB_DROP_REASON_SUBSYS_MASK":
+			continue
+		}
+		name, ok := strings.CutPrefix(v.Name, "SKB_DROP_REASON_")
+		if !ok {
+			name = strings.TrimPrefix(v.Name, "SKB_")
+		}
+		reasons[uint32(v.Value)] = name
+	}
+	return reasons
+}
@@ -0,0 +1,101 @@
+package bpfutil
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// TestDropReasons_Generated pins the generated table to the enum in
+// bpf/headers/vmlinux.h.
+func TestDropReasons_Generated(t *testing.T) {
+	tests := map[uint32]string{
+		1:  "CONSUMED",
+		2:  "NOT_SPECIFIED",
+		3:  "NO_SOCKET",
+		8:  "NETFILTER_DROP",
+		9:  "OTHERHOST",
+		16: "SOCKET_RCVBUFF",
+		17: "PROTO_MEM",
+		27: "TCP_FLAGS",
+		82: "QUEUE_PURGE",
+		86: "TC_RECLASSIFY_LOOP",
+	}
+	for code, want := range tests {
+		if got := constants.DropReasons[code]; got != want {
+			t.Errorf("DropReasons[%d] = %q, want %q", code, got, want)
+		}
+	}
+	for code, name := range constants.DropReasons {
+		if name == "MAX" || name == "SUBSYS_MASK" {
+			t.Errorf("DropReasons[%d] = %q, a marker rather than a reason", code, name)
+		}
+	}
+}
+
+// kernelBTF returns a spec holding enum skb_drop_reason with values.
+func kernelBTF(t *testing.T, values ...btf.EnumValue) func() (*btf.Spec, error) {
+	t.Helper()
+	b, err := btf.NewBuilder([]btf.Type{&btf.Enum{Name: constants.BTFEnumDropReason, Size: 4, Values: values}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := b.Marshal(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := btf.LoadSpecFromReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return func() (*btf.Spec, error) { return spec, nil }
+}
+
+func TestLoadDropReasons_PrefersKernelBTF(t *testing.T) {
+	// Numbering as of Linux 6.18, where SOCKET_CLOSE was inserted at 4.
+	reasons := loadDropReasons(kernelBTF(t,
+		btf.EnumValue{Name: "SKB_NOT_DROPPED_YET", Value: 0},
+		btf.EnumValue{Name: "SKB_DROP_REASON_NOT_SPECIFIED", Value: 2},
+		btf.EnumValue{Name: "SKB_DROP_REASON_SOCKET_CLOSE", Value: 4},
+		btf.EnumValue{Name: "SKB_DROP_REASON_NETFILTER_DROP", Value: 12},
+		btf.EnumValue{Name: "SKB_DROP_REASON_MAX", Value: 128},
+		btf.EnumValue{Name: "SKB_DROP_REASON_SUBSYS_MASK", Value: 0xffff0000},
+	))
+	want := map[uint32]string{0: "NOT_DROPPED_YET", 2: "NOT_SPECIFIED", 4: "SOCKET_CLOSE", 12: "NETFILTER_DROP"}
+	if len(reasons) != len(want) {
+		t.Errorf("loadDropReasons = %v, want %v", reasons, want)
+	}
+	for code, name := range want {
+		if reasons[code] != name {
+			t.Errorf("reasons[%d] = %q, want %q", code, reasons[code], name)
+		}
+	}
+}
+
+func TestLoadDropReasons_FallsBack(t *testing.T) {
+	noBTF := func() (*btf.Spec, error) { return nil, errors.New("no BTF") }
+	if got := loadDropReasons(noBTF); got[16] != constants.DropReasons[16] {
+		t.Errorf("without BTF, reasons[16] = %q, want the generated %q", got[16], constants.DropReasons[16])
+	}
+
+	// BTF without the enum: kernels before 5.17 have no drop reasons.
+	b, err := btf.NewBuilder([]btf.Type{&btf.Int{Name: "int", Size: 4}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := b.Marshal(nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, err := btf.LoadSpecFromReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loadDropReasons(func() (*btf.Spec, error) { return spec, nil }); got[3] != "NO_SOCKET" {
+		t.Errorf("without the enum, reasons[3] = %q, want NO_SOCKET", got[3])
+	}
+}
@@ -0,0 +1,41 @@
+package bpfutil
+
+import (
+	"bytes"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Interner hands out one shared string per distinct value of a label with
+// few values, such as command names or file system types, so modules do
+// not copy the same bytes out of every record. It keeps the first
+// constants.InternMaxValues values it sees and copies the rest per call.
+//
+// An Interner is owned by one module's read loop and is not safe for
+// concurrent use. A nil *Interner copies every value.
+type Interner struct {
+	values map[string]string
+}
+
+// NewInterner returns an empty Interner.
+func NewInterner() *Interner {
+	return &Interner{values: make(map[string]string)}
+}
+
+// CString returns the null-terminated string at the start of b.
+func (in *Interner) CString(b []byte) string {
+	if n := bytes.IndexByte(b, 0); n >= 0 {
+		b = b[:n]
+	}
+	if in == nil {
+		return string(b)
+	}
+	if s, ok := in.values[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(in.values) < constants.InternMaxValues {
+		in.values[s] = s
+	}
+	return s
+}
@@ -0,0 +1,44 @@
+package bpfutil
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func TestInterner_SharesValues(t *testing.T) {
+	in := NewInterner()
+	a := in.CString([]byte("curl\x00junk"))
+	b := in.CString([]byte("curl\x00\x00\x00"))
+	if a != "curl" || b != "curl" || len(in.values) != 1 {
+		t.Errorf("CString = %q, %q with %d values, want one curl", a, b, len(in.values))
+	}
+	if n := testing.AllocsPerRun(100, func() { in.CString([]byte("curl\x00")) }); n != 0 {
+		t.Errorf("interned value: %v allocs, want 0", n)
+	}
+
+	for i := range constants.InternMaxValues + 10 {
+		in.CString(fmt.Appendf(nil, "comm-%d", i))
+	}
+	if len(in.values) != constants.InternMaxValues {
+		t.Errorf("%d values interned, want at most %d", len(in.values), constants.InternMaxValues)
+	}
+	if got := in.CString([]byte("comm-last")); got != "comm-last" {
+		t.Errorf("CString past the cap = %q", got)
+	}
+
+	var none *Interner
+	if got := none.CString([]byte("sh\x00")); got != "sh" {
+		t.Errorf("nil Interner: CString = %q, want sh", got)
+	}
+}
+
+func TestAppendIPv4Port(t *testing.T) {
+	if got := string(AppendIPv4Port([]byte("dst="), 0xffffffff, 65535)); got != "dst=255.255.255.255:65535" {
+		t.Errorf("AppendIPv4Port = %q", got)
+	}
+	if got := FormatAddr(0x0100007f, 0); got != "127.0.0.1:0" {
+		t.Errorf("FormatAddr = %q, want 127.0.0.1:0", got)
+	}
+}
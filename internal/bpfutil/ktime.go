@@ -0,0 +1,59 @@
+package bpfutil
+
+import (
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// KernelClock converts bpf_ktime_get_ns() timestamps, nanoseconds of
+// CLOCK_MONOTONIC, to wall-clock time. It adds the offset between the two
+// clocks, re-measured every constants.KernelClockRefresh: CLOCK_MONOTONIC
+// stops while the node is suspended and ignores clock steps, so the offset
+// grows on resume and moves with NTP corrections. Safe for concurrent use.
+type KernelClock struct {
+	read    func() (mono, real int64) // nanoseconds of each clock
+	now     func() time.Time
+	refresh time.Duration
+
+	offset   atomic.Int64 // real - mono, ns
+	measured atomic.Int64 // now().UnixNano() when offset was measured; 0 before
+}
+
+// NewKernelClock returns a KernelClock reading the system clocks.
+func NewKernelClock() *KernelClock {
+	return &KernelClock{read: readClocks, now: time.Now, refresh: constants.KernelClockRefresh}
+}
+
+func readClocks() (mono, real int64) {
+	var m, r unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &m)
+	unix.ClockGettime(unix.CLOCK_REALTIME, &r)
+	return m.Nano(), r.Nano()
+}
+
+// WallTime returns the wall-clock time of the kernel timestamp ktime, or the
+// current time when ktime is 0 (not captured).
+func (c *KernelClock) WallTime(ktime uint64) time.Time {
+	now := c.now()
+	if ktime == 0 {
+		return now
+	}
+	if at := c.measured.Load(); at == 0 || now.UnixNano()-at >= int64(c.refresh) || now.UnixNano() < at {
+		mono, real := c.read()
+		c.offset.Store(real - mono)
+		c.measured.Store(now.UnixNano())
+	}
+	return time.Unix(0, int64(ktime)+c.offset.Load())
+}
+
+var kernelClock = NewKernelClock()
+
+// WallTime converts a probe's bpf_ktime_get_ns() timestamp to wall-clock
+// time with the process-wide KernelClock.
+func WallTime(ktime uint64) time.Time {
+	return kernelClock.WallTime(ktime)
+}
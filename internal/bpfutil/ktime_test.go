@@ -0,0 +1,84 @@
+package bpfutil
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// fakeClocks is a node whose monotonic and wall clocks the test moves.
+type fakeClocks struct {
+	mono, real int64
+	reads      int
+}
+
+func (f *fakeClocks) clock() *KernelClock {
+	return &KernelClock{
+		read:    func() (int64, int64) { f.reads++; return f.mono, f.real },
+		now:     func() time.Time { return time.Unix(0, f.real) },
+		refresh: time.Second,
+	}
+}
+
+func (f *fakeClocks) advance(mono, real time.Duration) {
+	f.mono += int64(mono)
+	f.real += int64(real)
+}
+
+func TestKernelClock_Converts(t *testing.T) {
+	boot := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := &fakeClocks{mono: int64(time.Hour), real: boot.Add(time.Hour).UnixNano()}
+	c := f.clock()
+
+	// An event captured 250ms ago, read now.
+	ktime := uint64(f.mono - int64(250*time.Millisecond))
+	if got, want := c.WallTime(ktime), boot.Add(time.Hour-250*time.Millisecond); !got.Equal(want) {
+		t.Errorf("WallTime = %v, want %v", got, want)
+	}
+
+	f.advance(100*time.Millisecond, 100*time.Millisecond)
+	c.WallTime(uint64(f.mono))
+	if f.reads != 1 {
+		t.Errorf("clocks read %d times within the refresh interval, want 1", f.reads)
+	}
+}
+
+func TestKernelClock_RefreshesAfterSuspend(t *testing.T) {
+	f := &fakeClocks{mono: int64(time.Hour), real: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC).UnixNano()}
+	c := f.clock()
+	c.WallTime(uint64(f.mono))
+
+	// Suspended for 8 hours: the wall clock moves on, CLOCK_MONOTONIC
+	// does not.
+	f.advance(0, 8*time.Hour)
+	f.advance(2*time.Second, 2*time.Second)
+	ktime := uint64(f.mono)
+	if got, want := c.WallTime(ktime), time.Unix(0, f.real); !got.Equal(want) {
+		t.Errorf("WallTime after resume = %v, want %v", got, want)
+	}
+
+	// A clock stepped backwards re-measures at once.
+	f.advance(0, -time.Minute)
+	if got, want := c.WallTime(uint64(f.mono)), time.Unix(0, f.real); !got.Equal(want) {
+		t.Errorf("WallTime after clock step = %v, want %v", got, want)
+	}
+}
+
+func TestKernelClock_ZeroIsNow(t *testing.T) {
+	f := &fakeClocks{mono: 1, real: 2}
+	if got := f.clock().WallTime(0); !got.Equal(time.Unix(0, 2)) || f.reads != 0 {
+		t.Errorf("WallTime(0) = %v after %d reads, want now without reading", got, f.reads)
+	}
+}
+
+func TestWallTime_SystemClock(t *testing.T) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_MONOTONIC, &ts); err != nil {
+		t.Skip(err)
+	}
+	got := WallTime(uint64(ts.Nano()))
+	if skew := time.Since(got); skew < -50*time.Millisecond || skew > 50*time.Millisecond {
+		t.Errorf("WallTime(now) is %v from time.Now()", skew)
+	}
+}
@@ -0,0 +1,31 @@
+package bpfutil
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// CEventSize returns the size the C source at path asserts for struct
+// name, as in
+//
+//	_Static_assert(sizeof(struct tcp_event) == 64, "tcp_event layout changed");
+//
+// The modules' tests compare their decode structs with it, so a C layout
+// change the Go mirror missed fails them rather than decoding garbage. They
+// also compare it with the event type bpf2go generated from the embedded
+// object (EVENT_TYPE in bpf/headers/ringbuf_lost.h), which catches an
+// object that was not regenerated.
+func CEventSize(path, name string) (int, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	re := regexp.MustCompile(`_Static_assert\(\s*sizeof\(struct ` + regexp.QuoteMeta(name) + `\)\s*==\s*(\d+)`)
+	m := re.FindSubmatch(src)
+	if m == nil {
+		return 0, fmt.Errorf("%s asserts no size for struct %s", path, name)
+	}
+	return strconv.Atoi(string(m[1]))
+}
@@ -0,0 +1,268 @@
+package bpfutil
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+
+	"github.com/cilium/ebpf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// LostCounter reads a program's ringbuf_lost map (bpf/headers/ringbuf_lost.h):
+// per-CPU counts of events dropped because bpf_ringbuf_reserve failed.
+//
+// A nil *LostCounter, as returned for objects built before the map
+// existed, always reports zero.
+type LostCounter struct {
+	m *ebpf.Map
+}
+
+// Resources are a loaded object's programs and maps by ELF name, for
+// resource metrics, and its ring buffer loss counter. The programs and
+// maps are owned by the module's generated objects struct; after the
+// module stops, reading them fails.
+//
+// Programs and maps the objects struct has no field for, such as a
+// fallback added to the C source since the bindings were generated, are
+// owned by Resources and released by Close.
+//
+// A nil *Resources has no programs or maps and reports no losses.
+type Resources struct {
+	Programs map[string]*ebpf.Program
+	Maps     map[string]*ebpf.Map
+	Lost     *LostCounter
+
+	pins        *Pins
+	unbound     []*ebpf.Program
+	unboundMaps []*ebpf.Map
+}
+
+// LoadObjects loads spec into the kernel and assigns its programs and maps
+// to objs, like the generated loadBpfObjects, and also returns them by
+// name along with the ringbuf_lost counter when the object defines one.
+//
+// Program fields of objs whose program spec omits (see WithoutPrograms)
+// are left nil.
+func LoadObjects(spec *ebpf.CollectionSpec, objs any) (*Resources, error) {
+	return loadObjects(spec, objs, nil)
+}
+
+func loadObjects(spec *ebpf.CollectionSpec, objs any, pins *Pins) (*Resources, error) {
+	replacements := pins.replacements(spec)
+	coll, err := ebpf.NewCollectionWithOptions(spec, ebpf.CollectionOptions{MapReplacements: replacements})
+	closeMaps(replacements) // the collection holds clones
+	if err != nil {
+		return nil, err
+	}
+	defer coll.Close()
+	pins.pinMaps(coll.Maps)
+
+	res := &Resources{Programs: maps.Clone(coll.Programs), Maps: maps.Clone(coll.Maps), pins: pins}
+	if m, ok := coll.Maps[constants.BPFMapRingbufLost]; ok {
+		// Clone so objs may also bind the map once bindings are regenerated.
+		c, err := m.Clone()
+		if err != nil {
+			return nil, fmt.Errorf("cloning %s: %w", constants.BPFMapRingbufLost, err)
+		}
+		res.Lost = &LostCounter{m: c}
+		delete(res.Maps, constants.BPFMapRingbufLost)
+	}
+	if err := assign(coll, objs); err != nil {
+		res.Close()
+		return nil, err
+	}
+	for name := range coll.Programs {
+		res.unbound = append(res.unbound, coll.DetachProgram(name))
+	}
+	for name := range coll.Maps {
+		if _, ok := res.Maps[name]; ok {
+			res.unboundMaps = append(res.unboundMaps, coll.DetachMap(name))
+		}
+	}
+	return res, nil
+}
+
+// LoadObjectsOptional is LoadObjects for a spec with optional programs,
+// such as fentry variants of kprobes. They are only attempted if try is
+// set; if loading with them fails, spec is loaded again without them and
+// their fields in objs stay nil.
+func LoadObjectsOptional(logger *zap.Logger, spec *ebpf.CollectionSpec, objs any, try bool, optional ...string) (*Resources, error) {
+	return loadObjectsOptional(logger, spec, objs, nil, try, optional...)
+}
+
+func loadObjectsOptional(logger *zap.Logger, spec *ebpf.CollectionSpec, objs any, pins *Pins, try bool, optional ...string) (*Resources, error) {
+	if try {
+		res, err := loadObjects(spec, objs, pins)
+		if err == nil {
+			return res, nil
+		}
+		logger.Info("Optional BPF programs failed to load — leaving them out",
+			zap.Strings("programs", optional), zap.Error(err))
+	}
+	return loadObjects(WithoutPrograms(spec, optional...), objs, pins)
+}
+
+// WithoutPrograms returns a copy of spec without the named programs, for
+// loading when optional programs, such as fentry variants, fail to load.
+func WithoutPrograms(spec *ebpf.CollectionSpec, names ...string) *ebpf.CollectionSpec {
+	out := spec.Copy()
+	for _, name := range names {
+		delete(out.Programs, name)
+	}
+	return out
+}
+
+// assign is Collection.Assign, except that a program field with no
+// program in coll stays nil instead of failing. Like Assign, it sets
+// nothing unless every map and variable field can be set.
+func assign(coll *ebpf.Collection, to any) error {
+	v := reflect.ValueOf(to)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("assign: %T is not a pointer to a struct", to)
+	}
+	var sets []func()
+	if err := collectFields(coll, v.Elem(), &sets); err != nil {
+		return err
+	}
+	for _, set := range sets {
+		set()
+	}
+	return nil
+}
+
+func collectFields(coll *ebpf.Collection, v reflect.Value, sets *[]func()) error {
+	for i := range v.NumField() {
+		field, fv := v.Type().Field(i), v.Field(i)
+		name, tagged := field.Tag.Lookup("ebpf")
+		if !tagged {
+			if field.Anonymous && fv.Kind() == reflect.Struct {
+				if err := collectFields(coll, fv, sets); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		switch ptr := fv.Addr().Interface().(type) {
+		case **ebpf.Program:
+			*sets = append(*sets, func() { *ptr = coll.DetachProgram(name) })
+		case **ebpf.Map:
+			if coll.Maps[name] == nil {
+				return fmt.Errorf("missing map %q", name)
+			}
+			*sets = append(*sets, func() { *ptr = coll.DetachMap(name) })
+		case **ebpf.Variable:
+			if coll.Variables[name] == nil {
+				return fmt.Errorf("missing variable %q", name)
+			}
+			*sets = append(*sets, func() { *ptr = coll.Variables[name] })
+		default:
+			return fmt.Errorf("field %s: unsupported type %s", field.Name, field.Type)
+		}
+	}
+	return nil
+}
+
+// DroppedCount returns the events lost on ring buffer reserve failure.
+func (r *Resources) DroppedCount() (uint64, error) {
+	if r == nil {
+		return 0, nil
+	}
+	return r.Lost.Count()
+}
+
+// Close releases what Resources owns: the loss counter's map handle and
+// any programs the objects struct did not bind.
+func (r *Resources) Close() error {
+	if r == nil {
+		return nil
+	}
+	for _, p := range r.unbound {
+		p.Close()
+	}
+	for _, m := range r.unboundMaps {
+		m.Close()
+	}
+	return r.Lost.Close()
+}
+
+// Counter returns the total across all CPUs of the per-CPU counter map
+// name, a one-slot BPF_MAP_TYPE_PERCPU_ARRAY like ringbuf_lost. A missing
+// map, as in objects built before it existed, counts zero.
+func (r *Resources) Counter(name string) (uint64, error) {
+	if r == nil || r.Maps[name] == nil {
+		return 0, nil
+	}
+	return sumPerCPU(r.Maps[name], name)
+}
+
+// PruneLinks unpins the links pinned by an earlier agent that were not
+// attached again since loading. Call it once the module has attached
+// everything, after Init.
+func (r *Resources) PruneLinks() error {
+	if r == nil {
+		return nil
+	}
+	return r.pins.prune()
+}
+
+// ExcludePIDs sets the object's excluded_pids map
+// (bpf/headers/self_filter.h) to pids, whose events its programs then
+// skip. PIDs already there, left in a pinned map by an earlier agent, are
+// removed. It reports false for objects built before the map existed.
+func (r *Resources) ExcludePIDs(pids []uint32) (bool, error) {
+	if r == nil || r.Maps[constants.BPFMapExcludedPIDs] == nil {
+		return false, nil
+	}
+	m := r.Maps[constants.BPFMapExcludedPIDs]
+	var (
+		pid   uint32
+		value uint8
+		stale []uint32
+	)
+	for it := m.Iterate(); it.Next(&pid, &value); {
+		stale = append(stale, pid)
+	}
+	for _, pid := range stale {
+		if err := m.Delete(pid); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+			return true, fmt.Errorf("removing pid %d: %w", pid, err)
+		}
+	}
+	for _, pid := range pids {
+		if err := m.Update(pid, uint8(1), ebpf.UpdateAny); err != nil {
+			return true, fmt.Errorf("excluding pid %d: %w", pid, err)
+		}
+	}
+	return true, nil
+}
+
+// Count returns the total events lost across all CPUs.
+func (c *LostCounter) Count() (uint64, error) {
+	if c == nil {
+		return 0, nil
+	}
+	return sumPerCPU(c.m, constants.BPFMapRingbufLost)
+}
+
+func sumPerCPU(m *ebpf.Map, name string) (uint64, error) {
+	var perCPU []uint64
+	if err := m.Lookup(uint32(0), &perCPU); err != nil {
+		return 0, fmt.Errorf("reading %s: %w", name, err)
+	}
+	var total uint64
+	for _, n := range perCPU {
+		total += n
+	}
+	return total, nil
+}
+
+// Close releases the map.
+func (c *LostCounter) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.m.Close()
+}
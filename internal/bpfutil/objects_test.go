@@ -0,0 +1,285 @@
+package bpfutil
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+const (
+	testRingSize   = 4096 // one page, the smallest ring buffer
+	testRecordSize = 1024 // with its 8-byte header, three fit
+)
+
+// lossSpec mirrors what bpf/headers/ringbuf_lost.h compiles to: a program
+// that reserves a record and, when the ring buffer is full, bumps
+// ringbuf_lost instead. Records are submitted but never read.
+func lossSpec(withCounter bool) *ebpf.CollectionSpec {
+	spec := &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			"events": {Name: "events", Type: ebpf.RingBuf, MaxEntries: testRingSize},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{},
+	}
+	insns := asm.Instructions{
+		asm.LoadMapPtr(asm.R1, 0).WithReference("events"),
+		asm.Mov.Imm(asm.R2, testRecordSize),
+		asm.Mov.Imm(asm.R3, 0),
+		asm.FnRingbufReserve.Call(),
+		asm.JEq.Imm(asm.R0, 0, "lost"),
+		asm.Mov.Reg(asm.R1, asm.R0),
+		asm.Mov.Imm(asm.R2, 0),
+		asm.FnRingbufSubmit.Call(),
+		asm.Ja.Label("exit"),
+	}
+	if withCounter {
+		spec.Maps[constants.BPFMapRingbufLost] = &ebpf.MapSpec{
+			Name: constants.BPFMapRingbufLost, Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 8, MaxEntries: 1,
+		}
+		insns = append(insns,
+			asm.StoreImm(asm.RFP, -4, 0, asm.Word).WithSymbol("lost"),
+			asm.Mov.Reg(asm.R2, asm.RFP),
+			asm.Add.Imm(asm.R2, -4),
+			asm.LoadMapPtr(asm.R1, 0).WithReference(constants.BPFMapRingbufLost),
+			asm.FnMapLookupElem.Call(),
+			asm.JEq.Imm(asm.R0, 0, "exit"),
+			asm.LoadMem(asm.R1, asm.R0, 0, asm.DWord),
+			asm.Add.Imm(asm.R1, 1),
+			asm.StoreMem(asm.R0, 0, asm.R1, asm.DWord),
+		)
+	} else {
+		insns = append(insns, asm.Ja.Label("exit").WithSymbol("lost"))
+	}
+	insns = append(insns,
+		asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+		asm.Return(),
+	)
+	spec.Programs["emit"] = &ebpf.ProgramSpec{
+		Name: "emit", Type: ebpf.SocketFilter, License: "GPL", Instructions: insns,
+	}
+	return spec
+}
+
+type lossObjects struct {
+	Emit   *ebpf.Program `ebpf:"emit"`
+	Events *ebpf.Map     `ebpf:"events"`
+}
+
+func (o *lossObjects) Close() {
+	o.Emit.Close()
+	o.Events.Close()
+}
+
+func loadLossSpec(t *testing.T, withCounter bool) (*lossObjects, *Resources) {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	var objs lossObjects
+	res, err := LoadObjects(lossSpec(withCounter), &objs)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("BPF ring buffers unsupported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		objs.Close()
+		res.Close()
+	})
+	return &objs, res
+}
+
+func TestLostCounter_CountsReserveFailures(t *testing.T) {
+	objs, res := loadLossSpec(t, true)
+	if res.Lost == nil {
+		t.Fatal("LoadObjects found no ringbuf_lost map")
+	}
+
+	const runs = 10
+	for range runs {
+		if _, err := objs.Emit.Run(&ebpf.RunOptions{Data: make([]byte, 14)}); err != nil {
+			t.Skipf("BPF_PROG_TEST_RUN unavailable: %v", err)
+		}
+	}
+	got, err := res.DroppedCount()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := uint64(runs - 3); got != want {
+		t.Errorf("lost = %d, want %d (ring holds 3 records)", got, want)
+	}
+}
+
+func TestLostCounter_OldObjects(t *testing.T) {
+	_, res := loadLossSpec(t, false)
+	if res.Lost != nil {
+		t.Fatal("LoadObjects returned a counter for objects without ringbuf_lost")
+	}
+	if n, err := res.DroppedCount(); n != 0 || err != nil {
+		t.Errorf("DroppedCount() = %d, %v; want 0, nil", n, err)
+	}
+	if res.Programs["emit"] == nil || res.Maps["events"] == nil {
+		t.Errorf("Resources = %+v, want emit and events by name", res)
+	}
+}
+
+func TestLoadObjects_KeepsUnboundPrograms(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	spec := lossSpec(false)
+	spec.Programs["fallback"] = &ebpf.ProgramSpec{
+		Name: "fallback", Type: ebpf.SocketFilter, License: "GPL",
+		Instructions: asm.Instructions{asm.Mov.Imm(asm.R0, 7), asm.Return()},
+	}
+	var objs lossObjects
+	res, err := LoadObjects(spec, &objs)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("BPF ring buffers unsupported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer objs.Close()
+
+	fallback := res.Programs["fallback"]
+	if fallback == nil {
+		t.Fatal("unbound program missing from Resources")
+	}
+	ret, err := fallback.Run(&ebpf.RunOptions{Data: make([]byte, 14)})
+	if err != nil {
+		t.Skipf("BPF_PROG_TEST_RUN unavailable: %v", err)
+	}
+	if ret != 7 {
+		t.Errorf("fallback returned %d, want 7", ret)
+	}
+
+	res.Close()
+	if fallback.FD() >= 0 {
+		t.Error("Close left the unbound program open")
+	}
+}
+
+func TestLoadObjects_WithoutPrograms(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	spec := lossSpec(false)
+	var objs struct {
+		lossObjects
+		Optional *ebpf.Program `ebpf:"optional"`
+	}
+	res, err := LoadObjects(WithoutPrograms(spec, "optional", "emit"), &objs)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("BPF ring buffers unsupported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Close()
+	defer objs.Events.Close()
+
+	if objs.Emit != nil || objs.Optional != nil {
+		t.Error("program fields left out of the spec were assigned")
+	}
+	if objs.Events == nil {
+		t.Error("events map not assigned")
+	}
+	if spec.Programs["emit"] == nil {
+		t.Error("WithoutPrograms modified the original spec")
+	}
+
+	delete(spec.Maps, "events")
+	spec.Programs = nil
+	var partial lossObjects
+	if _, err := LoadObjects(spec, &partial); err == nil {
+		t.Error("LoadObjects succeeded without a map objs needs")
+	}
+}
+
+func TestResources_Counter(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	spec := lossSpec(false)
+	spec.Maps["suppressed"] = &ebpf.MapSpec{
+		Name: "suppressed", Type: ebpf.PerCPUArray, KeySize: 4, ValueSize: 8, MaxEntries: 1,
+	}
+	var objs lossObjects // no field for suppressed
+	res, err := LoadObjects(spec, &objs)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("BPF ring buffers unsupported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer objs.Close()
+	defer res.Close()
+
+	cpus, err := ebpf.PossibleCPU()
+	if err != nil {
+		t.Fatal(err)
+	}
+	perCPU := make([]uint64, cpus)
+	for i := range perCPU {
+		perCPU[i] = 2
+	}
+	if err := res.Maps["suppressed"].Update(uint32(0), perCPU, ebpf.UpdateAny); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := res.Counter("suppressed"); n != uint64(2*cpus) || err != nil {
+		t.Errorf("Counter(suppressed) = %d, %v; want %d, nil", n, err, 2*cpus)
+	}
+	if n, err := res.Counter("missing"); n != 0 || err != nil {
+		t.Errorf("Counter(missing) = %d, %v; want 0, nil", n, err)
+	}
+}
+
+func TestResources_ExcludePIDs(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	spec := lossSpec(false)
+	spec.Maps[constants.BPFMapExcludedPIDs] = &ebpf.MapSpec{
+		Name: constants.BPFMapExcludedPIDs, Type: ebpf.Hash, KeySize: 4, ValueSize: 1, MaxEntries: 2,
+	}
+	var objs lossObjects
+	res, err := LoadObjects(spec, &objs)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("BPF ring buffers unsupported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer objs.Close()
+	defer res.Close()
+
+	if ok, err := res.ExcludePIDs([]uint32{1, 42}); !ok || err != nil {
+		t.Fatalf("ExcludePIDs = %v, %v; want true, nil", ok, err)
+	}
+	var v uint8
+	if err := res.Maps[constants.BPFMapExcludedPIDs].Lookup(uint32(42), &v); err != nil || v != 1 {
+		t.Errorf("excluded_pids[42] = %d, %v; want 1", v, err)
+	}
+	// A second call replaces the set, as for a map an earlier agent pinned.
+	if _, err := res.ExcludePIDs([]uint32{7}); err != nil {
+		t.Fatal(err)
+	}
+	if err := res.Maps[constants.BPFMapExcludedPIDs].Lookup(uint32(42), &v); !errors.Is(err, ebpf.ErrKeyNotExist) {
+		t.Errorf("excluded_pids[42] after replacing = %d, %v; want ErrKeyNotExist", v, err)
+	}
+	if _, err := res.ExcludePIDs([]uint32{1, 2, 3}); err == nil {
+		t.Error("ExcludePIDs past max_entries succeeded")
+	}
+
+	_, old := loadLossSpec(t, false)
+	if ok, err := old.ExcludePIDs([]uint32{1}); ok || err != nil {
+		t.Errorf("ExcludePIDs on an object without the map = %v, %v; want false, nil", ok, err)
+	}
+}
@@ -0,0 +1,324 @@
+package bpfutil
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// PinFS is the bpffs operations pinning needs. NewPinRoot uses the real
+// bpffs; tests substitute a fake.
+type PinFS interface {
+	LoadMap(path string) (*ebpf.Map, error)
+	PinMap(m *ebpf.Map, path string) error
+	LoadLink(path string) (link.Link, error)
+	PinLink(l link.Link, path string) error
+
+	// ReadDir lists the names in dir.
+	ReadDir(dir string) ([]string, error)
+	MkdirAll(dir string) error
+
+	// RemoveAll unpins everything under path. Maps and links whose pin
+	// was their last reference are released: their programs detach.
+	RemoveAll(path string) error
+}
+
+// bpffs is the PinFS of a mounted bpffs.
+type bpffs struct{}
+
+func (bpffs) LoadMap(path string) (*ebpf.Map, error)  { return ebpf.LoadPinnedMap(path, nil) }
+func (bpffs) PinMap(m *ebpf.Map, path string) error   { return m.Pin(path) }
+func (bpffs) LoadLink(path string) (link.Link, error) { return link.LoadPinnedLink(path, nil) }
+func (bpffs) PinLink(l link.Link, path string) error  { return l.Pin(path) }
+func (bpffs) MkdirAll(dir string) error               { return os.MkdirAll(dir, 0o700) }
+func (bpffs) RemoveAll(path string) error             { return os.RemoveAll(path) }
+
+func (bpffs) ReadDir(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.Name()
+	}
+	return names, nil
+}
+
+// PinRoot is the directory modules pin their maps and links under, one
+// subdirectory each (agent.pin_path).
+//
+// A nil *PinRoot pins nothing.
+type PinRoot struct {
+	fs     PinFS
+	dir    string
+	logger *zap.Logger
+}
+
+// NewPinRoot creates dir, which must be on a bpffs mount.
+func NewPinRoot(dir string, logger *zap.Logger) (*PinRoot, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return nil, fmt.Errorf("statfs %s: %w", dir, err)
+	}
+	if st.Type != unix.BPF_FS_MAGIC {
+		return nil, fmt.Errorf("%s is not on a bpffs mount", dir)
+	}
+	return newPinRoot(bpffs{}, dir, logger), nil
+}
+
+func newPinRoot(fs PinFS, dir string, logger *zap.Logger) *PinRoot {
+	return &PinRoot{fs: fs, dir: dir, logger: logger}
+}
+
+// Open returns the pins of module's object spec, in a directory named
+// for SpecHash(spec). Pins of other objects, left by an agent with a
+// different build or config, are removed, detaching their programs.
+//
+// Pinning is best effort: if the directory can't be set up, Open logs
+// why and returns nil, and the module loads without pins.
+func (r *PinRoot) Open(module string, spec *ebpf.CollectionSpec) *Pins {
+	if r == nil {
+		return nil
+	}
+	logger := r.logger.With(zap.String("module", module))
+	p, err := r.open(module, spec, logger)
+	if err != nil {
+		logger.Warn("Could not set up BPF pins — events will be missed while the agent restarts", zap.Error(err))
+		return nil
+	}
+	return p
+}
+
+func (r *PinRoot) open(module string, spec *ebpf.CollectionSpec, logger *zap.Logger) (*Pins, error) {
+	hash := SpecHash(spec)
+	moduleDir := filepath.Join(r.dir, module)
+	names, err := r.fs.ReadDir(moduleDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, name := range names {
+		if name == hash {
+			continue
+		}
+		logger.Info("Removing stale BPF pins", zap.String("hash", name))
+		if err := r.fs.RemoveAll(filepath.Join(moduleDir, name)); err != nil {
+			return nil, err
+		}
+	}
+	p := &Pins{fs: r.fs, dir: filepath.Join(moduleDir, hash), logger: logger, attached: make(map[string]bool)}
+	if err := p.mkdirs(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Remove unpins everything module pinned, for a module that no longer
+// runs: its programs detach.
+func (r *PinRoot) Remove(module string) error {
+	if r == nil {
+		return nil
+	}
+	return r.fs.RemoveAll(filepath.Join(r.dir, module))
+}
+
+// Pins are one module's pinned maps and links. When an earlier agent
+// pinned the same object, its maps are loaded in place of new ones and
+// its links reused, so the programs it attached keep running across the
+// restart and their ring buffers keep what they wrote meanwhile.
+//
+// A nil *Pins pins nothing.
+type Pins struct {
+	fs     PinFS
+	dir    string
+	logger *zap.Logger
+
+	reused   bool            // every map was already pinned
+	off      bool            // pinning the maps failed; links are not pinned either
+	attached map[string]bool // links pinned or reused since loading
+}
+
+// LoadObjects is the package's LoadObjects, using and pinning p's maps.
+func (p *Pins) LoadObjects(spec *ebpf.CollectionSpec, objs any) (*Resources, error) {
+	return loadObjects(spec, objs, p)
+}
+
+// LoadObjectsOptional is the package's LoadObjectsOptional, using and
+// pinning p's maps.
+func (p *Pins) LoadObjectsOptional(logger *zap.Logger, spec *ebpf.CollectionSpec, objs any, try bool, optional ...string) (*Resources, error) {
+	return loadObjectsOptional(logger, spec, objs, p, try, optional...)
+}
+
+func (p *Pins) path(kind, name string) string {
+	return filepath.Join(p.dir, kind, name)
+}
+
+func (p *Pins) mkdirs() error {
+	for _, kind := range []string{constants.PinDirMaps, constants.PinDirLinks} {
+		if err := p.fs.MkdirAll(filepath.Join(p.dir, kind)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pinnable reports whether map name is pinned: not one of the internal
+// .rodata, .data or .bss maps, which programs already keep alive.
+func pinnable(name string) bool {
+	return !strings.HasPrefix(name, ".")
+}
+
+// replacements returns the pinned maps to load spec with. Only a full
+// set is used: otherwise links pinned with the other maps would leave
+// programs writing where nobody reads, so everything is unpinned instead.
+func (p *Pins) replacements(spec *ebpf.CollectionSpec) map[string]*ebpf.Map {
+	if p == nil || p.off {
+		return nil
+	}
+	pinned := make(map[string]*ebpf.Map)
+	for name := range spec.Maps {
+		if !pinnable(name) {
+			continue
+		}
+		m, err := p.fs.LoadMap(p.path(constants.PinDirMaps, name))
+		if err != nil {
+			closeMaps(pinned)
+			p.reused = false
+			if err := p.reset(); err != nil {
+				p.disable(err)
+			}
+			return nil
+		}
+		pinned[name] = m
+	}
+	if !p.reused {
+		p.logger.Info("Reusing BPF maps and links pinned by an earlier agent")
+	}
+	p.reused = true
+	return pinned
+}
+
+// reset unpins everything and recreates the empty directories.
+func (p *Pins) reset() error {
+	if err := p.fs.RemoveAll(p.dir); err != nil {
+		return err
+	}
+	return p.mkdirs()
+}
+
+// pinMaps pins the maps of a collection loaded without replacements.
+func (p *Pins) pinMaps(loaded map[string]*ebpf.Map) {
+	if p == nil || p.off || p.reused {
+		return
+	}
+	for _, name := range slices.Sorted(maps.Keys(loaded)) {
+		if !pinnable(name) {
+			continue
+		}
+		if err := p.fs.PinMap(loaded[name], p.path(constants.PinDirMaps, name)); err != nil {
+			p.reset()
+			p.disable(fmt.Errorf("pinning map %s: %w", name, err))
+			return
+		}
+	}
+}
+
+func (p *Pins) disable(err error) {
+	p.logger.Warn("Could not pin BPF maps — events will be missed while the agent restarts", zap.Error(err))
+	p.off = true
+}
+
+// attach reuses the link pinned as name, or attaches a new one with h and
+// pins it. Links that can't be pinned, such as perf-event kprobes on
+// older kernels, still work but detach when the agent exits.
+func (p *Pins) attach(name string, h Hook) (link.Link, error) {
+	if p == nil || p.off {
+		return h()
+	}
+	path := p.path(constants.PinDirLinks, name)
+	if p.reused {
+		if l, err := p.fs.LoadLink(path); err == nil {
+			p.attached[name] = true
+			return l, nil
+		}
+	}
+	l, err := h()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.fs.RemoveAll(path); err == nil {
+		err = p.fs.PinLink(l, path)
+	}
+	if err != nil {
+		p.logger.Debug("BPF link not pinned — it detaches when the agent exits",
+			zap.String("link", name), zap.Error(err))
+		return l, nil
+	}
+	p.attached[name] = true
+	return l, nil
+}
+
+// prune unpins the links not reused or attached since loading, such as
+// those of an attach mode the module no longer chooses, so their programs
+// don't keep running alongside the new ones.
+func (p *Pins) prune() error {
+	if p == nil || p.off {
+		return nil
+	}
+	dir := filepath.Join(p.dir, constants.PinDirLinks)
+	names, err := p.fs.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if p.attached[name] {
+			continue
+		}
+		p.logger.Info("Removing stale BPF link", zap.String("link", name))
+		if err := p.fs.RemoveAll(filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func closeMaps(ms map[string]*ebpf.Map) {
+	for _, m := range ms {
+		m.Close()
+	}
+}
+
+// SpecHash identifies the objects spec loads: its programs' instructions
+// and its maps, including the contents of .rodata that hold load-time
+// constants. Objects with the same hash can share pinned maps and links.
+func SpecHash(spec *ebpf.CollectionSpec) string {
+	h := sha256.New()
+	for _, name := range slices.Sorted(maps.Keys(spec.Programs)) {
+		prog := spec.Programs[name]
+		fmt.Fprintf(h, "prog %s %v %v %s %s\n", name, prog.Type, prog.AttachType, prog.AttachTo, prog.SectionName)
+		for _, ins := range prog.Instructions {
+			fmt.Fprintf(h, "%v %v %v %d %d %s\n", ins.OpCode, ins.Dst, ins.Src, ins.Offset, ins.Constant, ins.Reference())
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(spec.Maps)) {
+		m := spec.Maps[name]
+		fmt.Fprintf(h, "map %s %v %d %d %d %d %v\n", name, m.Type, m.KeySize, m.ValueSize, m.MaxEntries, m.Flags, m.Contents)
+	}
+	return hex.EncodeToString(h.Sum(nil))[:constants.PinHashLen]
+}
@@ -0,0 +1,292 @@
+package bpfutil
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// fakeLink is a link that only fakePinFS can pin.
+type fakeLink struct {
+	link.Link
+	name string
+}
+
+func (*fakeLink) Close() error { return nil }
+
+// fakePinFS pins fakeLinks and directories in memory. It has no maps.
+type fakePinFS struct {
+	pinned map[string]link.Link // nil for directories
+}
+
+func newFakePinFS(paths ...string) *fakePinFS {
+	fs := &fakePinFS{pinned: make(map[string]link.Link)}
+	for _, p := range paths {
+		fs.pinned[p] = &fakeLink{name: filepath.Base(p)}
+	}
+	return fs
+}
+
+func (fs *fakePinFS) LoadMap(string) (*ebpf.Map, error)      { return nil, os.ErrNotExist }
+func (fs *fakePinFS) PinMap(*ebpf.Map, string) error         { return ebpf.ErrNotSupported }
+func (fs *fakePinFS) PinLink(l link.Link, path string) error { fs.pinned[path] = l; return nil }
+func (fs *fakePinFS) MkdirAll(dir string) error              { fs.pinned[dir] = nil; return nil }
+
+func (fs *fakePinFS) LoadLink(path string) (link.Link, error) {
+	if l := fs.pinned[path]; l != nil {
+		return l, nil
+	}
+	return nil, os.ErrNotExist
+}
+
+func (fs *fakePinFS) ReadDir(dir string) ([]string, error) {
+	var names []string
+	for p := range fs.pinned {
+		if rest, ok := strings.CutPrefix(p, dir+"/"); ok {
+			name, _, _ := strings.Cut(rest, "/")
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return slices.Compact(names), nil
+}
+
+func (fs *fakePinFS) RemoveAll(path string) error {
+	for p := range fs.pinned {
+		if p == path || strings.HasPrefix(p, path+"/") {
+			delete(fs.pinned, p)
+		}
+	}
+	return nil
+}
+
+func (fs *fakePinFS) has(path string) bool {
+	_, ok := fs.pinned[path]
+	return ok
+}
+
+func TestPinRoot_OpenRemovesStalePins(t *testing.T) {
+	spec := lossSpec(false)
+	current := "/pins/tcp/" + SpecHash(spec)
+	fs := newFakePinFS(
+		"/pins/tcp/0123456789abcdef/links/kprobe_tcp_connect",
+		current+"/links/kprobe_tcp_connect",
+		"/pins/dns/0123456789abcdef/links/kprobe_udp_sendmsg",
+	)
+	p := newPinRoot(fs, "/pins", zap.NewNop()).Open("tcp", spec)
+	if p == nil {
+		t.Fatal("Open returned nil")
+	}
+	if fs.has("/pins/tcp/0123456789abcdef") || fs.has("/pins/tcp/0123456789abcdef/links/kprobe_tcp_connect") {
+		t.Error("pins of another object were kept")
+	}
+	for _, path := range []string{
+		current + "/links/kprobe_tcp_connect",
+		current + "/" + constants.PinDirMaps,
+		"/pins/dns/0123456789abcdef/links/kprobe_udp_sendmsg",
+	} {
+		if !fs.has(path) {
+			t.Errorf("%s was removed", path)
+		}
+	}
+}
+
+func TestPins_ReusesPinnedLinks(t *testing.T) {
+	spec := lossSpec(false)
+	links := "/pins/tcp/" + SpecHash(spec) + "/links/"
+	fs := newFakePinFS(links+"kprobe_tcp_connect", links+"kprobe_tcp_close")
+	p := newPinRoot(fs, "/pins", zap.NewNop()).Open("tcp", spec)
+	p.reused = true // as replacements sets it, given pinned maps
+	res := &Resources{pins: p}
+
+	l, err := res.pinned("kprobe_tcp_connect", func() (link.Link, error) {
+		t.Error("attached a link that was pinned")
+		return nil, nil
+	})()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fl, ok := l.(*fakeLink); !ok || fl.name != "kprobe_tcp_connect" {
+		t.Errorf("got link %v, want the pinned kprobe_tcp_connect", l)
+	}
+
+	// The earlier agent's tcp_close kprobe was not attached again: prune
+	// unpins it rather than leave it running.
+	if err := res.PruneLinks(); err != nil {
+		t.Fatal(err)
+	}
+	if !fs.has(links + "kprobe_tcp_connect") {
+		t.Error("reused link was unpinned")
+	}
+	if fs.has(links + "kprobe_tcp_close") {
+		t.Error("stale link was kept")
+	}
+}
+
+func TestPins_PinsNewLinks(t *testing.T) {
+	fs := newFakePinFS()
+	p := newPinRoot(fs, "/pins", zap.NewNop()).Open("exec", lossSpec(false))
+	res := &Resources{pins: p}
+
+	var attached int
+	l, err := res.pinned("tracepoint_sched_sched_process_exec", func() (link.Link, error) {
+		attached++
+		return &fakeLink{}, nil
+	})()
+	if err != nil || attached != 1 {
+		t.Fatalf("attach = %v, %v after %d attaches; want one new link", l, err, attached)
+	}
+	path := filepath.Join(p.dir, constants.PinDirLinks, "tracepoint_sched_sched_process_exec")
+	if fs.pinned[path] != l {
+		t.Errorf("link not pinned at %s", path)
+	}
+	if err := res.PruneLinks(); err != nil || !fs.has(path) {
+		t.Errorf("PruneLinks = %v, removed the new link: %v", err, !fs.has(path))
+	}
+
+	if err := newPinRoot(fs, "/pins", zap.NewNop()).Remove("exec"); err != nil {
+		t.Fatal(err)
+	}
+	if fs.has("/pins/exec") || fs.has(path) {
+		t.Error("Remove left pins behind")
+	}
+}
+
+func TestPins_Nil(t *testing.T) {
+	var root *PinRoot
+	if p := root.Open("tcp", lossSpec(false)); p != nil {
+		t.Errorf("nil PinRoot opened %+v", p)
+	}
+	if err := root.Remove("tcp"); err != nil {
+		t.Error(err)
+	}
+	res := &Resources{}
+	attached := false
+	if _, err := res.pinned("x", func() (link.Link, error) { attached = true; return nil, nil })(); err != nil || !attached {
+		t.Errorf("unpinned hook = %v, attached %v; want it attached", err, attached)
+	}
+	if err := res.PruneLinks(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestSpecHash(t *testing.T) {
+	withRodata := func(v byte) *ebpf.CollectionSpec {
+		spec := lossSpec(false)
+		spec.Maps[".rodata"] = &ebpf.MapSpec{
+			Name: ".rodata", Type: ebpf.Array, KeySize: 4, ValueSize: 1, MaxEntries: 1,
+			Contents: []ebpf.MapKV{{Key: uint32(0), Value: []byte{v}}},
+		}
+		return spec
+	}
+	base := SpecHash(withRodata(0))
+	if len(base) != constants.PinHashLen {
+		t.Errorf("hash %q has length %d, want %d", base, len(base), constants.PinHashLen)
+	}
+	if got := SpecHash(withRodata(0)); got != base {
+		t.Errorf("hash of the same spec changed: %s, %s", base, got)
+	}
+
+	insn := withRodata(0)
+	insn.Programs["emit"].Instructions[1] = asm.Mov.Imm(asm.R2, testRecordSize/2)
+	for name, spec := range map[string]*ebpf.CollectionSpec{
+		"constant":    withRodata(1),
+		"instruction": insn,
+		"map":         lossSpec(true),
+	} {
+		if SpecHash(spec) == base {
+			t.Errorf("changing a %s kept hash %s", name, base)
+		}
+	}
+}
+
+func TestNewPinRoot_RejectsNonBPFFS(t *testing.T) {
+	if _, err := NewPinRoot(t.TempDir(), zap.NewNop()); err == nil {
+		t.Error("NewPinRoot accepted a directory outside bpffs")
+	}
+}
+
+// bpffsRoot mounts a bpffs for the test and returns a PinRoot on it.
+func bpffsRoot(t *testing.T) *PinRoot {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("mounting bpffs requires root")
+	}
+	dir := t.TempDir()
+	if err := unix.Mount("bpf", dir, "bpf", 0, ""); err != nil {
+		t.Skipf("mounting bpffs: %v", err)
+	}
+	t.Cleanup(func() { unix.Unmount(dir, 0) })
+	root, err := NewPinRoot(filepath.Join(dir, "kubepulse"), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// loadPinned loads lossSpec with pins from root and returns its events
+// map's ID.
+func loadPinned(t *testing.T, root *PinRoot) ebpf.MapID {
+	t.Helper()
+	var objs lossObjects
+	res, err := root.Open("loss", lossSpec(true)).LoadObjects(lossSpec(true), &objs)
+	if errors.Is(err, ebpf.ErrNotSupported) {
+		t.Skipf("BPF ring buffers unsupported: %v", err)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		objs.Close()
+		res.Close()
+	}()
+	info, err := objs.Events.Info()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, ok := info.ID()
+	if !ok {
+		t.Skip("map IDs unsupported")
+	}
+	return id
+}
+
+func TestPins_ReusesPinnedMaps(t *testing.T) {
+	root := bpffsRoot(t)
+	first := loadPinned(t, root)
+	if second := loadPinned(t, root); second != first {
+		t.Errorf("reloaded events map %d, want pinned map %d", second, first)
+	}
+
+	// With part of the set gone nothing is reused: the maps are replaced
+	// and pinned again.
+	maps := filepath.Join(root.dir, "loss", SpecHash(lossSpec(true)), constants.PinDirMaps)
+	if err := os.Remove(filepath.Join(maps, constants.BPFMapRingbufLost)); err != nil {
+		t.Fatal(err)
+	}
+	third := loadPinned(t, root)
+	if third == first {
+		t.Error("reused a partial set of pinned maps")
+	}
+	if fourth := loadPinned(t, root); fourth != third {
+		t.Errorf("reloaded events map %d, want re-pinned map %d", fourth, third)
+	}
+
+	if err := root.Remove("loss"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(root.dir, "loss")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Remove left %s: %v", filepath.Join(root.dir, "loss"), err)
+	}
+}
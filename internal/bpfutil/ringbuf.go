@@ -0,0 +1,91 @@
+package bpfutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// RecordReader is the part of *ringbuf.Reader ReadLoop uses.
+type RecordReader interface {
+	Read() (ringbuf.Record, error)
+	SetDeadline(t time.Time)
+	Flush() error
+}
+
+// ReadLoop passes each record read from r to handle until ctx is
+// cancelled, then drains r: it keeps reading until the ring buffer has
+// been empty for constants.RingbufDrainPoll, or until drain has elapsed,
+// so events already queued in the kernel at shutdown are still published.
+// The module's links stay attached while it drains; Stop detaches them.
+//
+// ReadLoop returns nil once drained, or when r is closed, and an error
+// after constants.RingbufMaxReadErrors consecutive failed reads.
+func ReadLoop(ctx context.Context, r RecordReader, drain time.Duration, logger *zap.Logger, handle func(ringbuf.Record)) error {
+	// Flush interrupts a blocked Read; records already in the buffer are
+	// returned before ringbuf.ErrFlushed.
+	stop := context.AfterFunc(ctx, func() { r.Flush() })
+	defer stop()
+
+	var (
+		draining bool
+		deadline time.Time
+		drained  int // records read after ctx was cancelled
+		failures int // consecutive read errors
+	)
+	for {
+		if draining {
+			if !time.Now().Before(deadline) {
+				logger.Warn("Ring buffer drain deadline reached — discarding remaining events",
+					zap.Duration("drain_timeout", drain), zap.Int("drained", drained))
+				return nil
+			}
+			r.SetDeadline(minTime(time.Now().Add(constants.RingbufDrainPoll), deadline))
+		}
+
+		record, err := r.Read()
+		if err == nil {
+			failures = 0
+		}
+		switch {
+		case err == nil:
+			if ctx.Err() != nil {
+				drained++
+			}
+			handle(record)
+		case errors.Is(err, ringbuf.ErrClosed):
+			return nil
+		case errors.Is(err, ringbuf.ErrFlushed), errors.Is(err, os.ErrDeadlineExceeded):
+			if !draining {
+				if ctx.Err() == nil {
+					continue
+				}
+				draining, deadline = true, time.Now().Add(drain)
+				continue
+			}
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				logger.Debug("Ring buffer drained", zap.Int("drained", drained))
+				return nil
+			}
+		default:
+			if failures++; failures >= constants.RingbufMaxReadErrors {
+				return fmt.Errorf("reading ring buffer: %d consecutive failures: %w", failures, err)
+			}
+			logger.Warn("Reading ring buffer", zap.Error(err))
+		}
+	}
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,208 @@
+package bpfutil
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+)
+
+// fakeReader behaves like *ringbuf.Reader: Read returns queued records
+// first, then ErrFlushed once after Flush, then ErrDeadlineExceeded once
+// the deadline passes; otherwise it blocks.
+type fakeReader struct {
+	mu       sync.Mutex
+	records  [][]byte
+	deadline time.Time
+	flushed  bool
+	closed   bool
+}
+
+func (r *fakeReader) push(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for range n {
+		r.records = append(r.records, []byte{1})
+	}
+}
+
+func (r *fakeReader) Read() (ringbuf.Record, error) {
+	for {
+		r.mu.Lock()
+		switch {
+		case r.closed:
+			r.mu.Unlock()
+			return ringbuf.Record{}, ringbuf.ErrClosed
+		case len(r.records) > 0:
+			rec := ringbuf.Record{RawSample: r.records[0]}
+			r.records = r.records[1:]
+			r.mu.Unlock()
+			return rec, nil
+		case r.flushed:
+			r.flushed = false
+			r.mu.Unlock()
+			return ringbuf.Record{}, ringbuf.ErrFlushed
+		case !r.deadline.IsZero() && time.Now().After(r.deadline):
+			r.mu.Unlock()
+			return ringbuf.Record{}, os.ErrDeadlineExceeded
+		}
+		r.mu.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (r *fakeReader) SetDeadline(t time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.deadline = t
+}
+
+func (r *fakeReader) Flush() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushed = true
+	return nil
+}
+
+func (r *fakeReader) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+}
+
+// runLoop starts ReadLoop on r and returns a counter of handled records
+// and a channel closed when ReadLoop returns.
+func runLoop(ctx context.Context, r *fakeReader, drain time.Duration) (*atomic.Int64, <-chan error) {
+	var handled atomic.Int64
+	done := make(chan error, 1)
+	go func() {
+		done <- ReadLoop(ctx, r, drain, zap.NewNop(), func(ringbuf.Record) { handled.Add(1) })
+	}()
+	return &handled, done
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timed out")
+		}
+	}
+}
+
+func TestReadLoop_DrainsQueuedRecordsAtShutdown(t *testing.T) {
+	r := &fakeReader{}
+	ctx, cancel := context.WithCancel(context.Background())
+	handled, done := runLoop(ctx, r, 5*time.Second)
+
+	r.push(3)
+	waitFor(t, func() bool { return handled.Load() == 3 })
+
+	// Records still queued in the kernel when shutdown begins, such as
+	// the OOM kill that caused it.
+	r.push(5)
+	cancel()
+	start := time.Now()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := handled.Load(); got != 8 {
+		t.Errorf("handled %d records, want 8", got)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("drain of an idle buffer took %s, want about one poll window", elapsed)
+	}
+}
+
+func TestReadLoop_DrainsRecordsArrivingDuringDrain(t *testing.T) {
+	r := &fakeReader{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.push(2)
+	handled, done := runLoop(ctx, r, 5*time.Second)
+
+	// Links stay attached while draining, so late events still arrive.
+	waitFor(t, func() bool { return handled.Load() == 2 })
+	r.push(1)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if got := handled.Load(); got != 3 {
+		t.Errorf("handled %d records, want 3", got)
+	}
+}
+
+func TestReadLoop_DrainDeadline(t *testing.T) {
+	r := &fakeReader{}
+	ctx, cancel := context.WithCancel(context.Background())
+	_, done := runLoop(ctx, r, 100*time.Millisecond)
+
+	// A producer that never lets the buffer go idle.
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				r.push(1)
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadLoop ignored its drain deadline")
+	}
+}
+
+func TestReadLoop_Closed(t *testing.T) {
+	r := &fakeReader{}
+	_, done := runLoop(context.Background(), r, time.Second)
+	r.Close()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("ReadLoop kept running after its reader closed")
+	}
+}
+
+func TestReadLoop_RealRingBuffer(t *testing.T) {
+	objs, _ := loadLossSpec(t, false)
+	reader, err := ringbuf.NewReader(objs.Events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	for range 3 {
+		if _, err := objs.Emit.Run(&ebpf.RunOptions{Data: make([]byte, 14)}); err != nil {
+			t.Skipf("BPF_PROG_TEST_RUN unavailable: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var handled int
+	if err := ReadLoop(ctx, reader, 5*time.Second, zap.NewNop(), func(ringbuf.Record) { handled++ }); err != nil {
+		t.Fatal(err)
+	}
+	if handled != 3 {
+		t.Errorf("drained %d records, want 3", handled)
+	}
+}
@@ -0,0 +1,94 @@
+// Package buildinfo reports what a KubePulse binary was built from.
+// The Makefile stamps Commit and BuildDate via -ldflags; a plain
+// `go build` falls back to the VCS details the Go toolchain embeds.
+//
+// It is the single source of the version for all three binaries, and
+// exports it as kubepulse_build_info and at /version.
+package buildinfo
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Set with -ldflags "-X github.com/sureshkrishnan-v/kubePulse/internal/buildinfo.Commit=...".
+var (
+	Version   = "4.0.0"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes the running binary and the kernel it runs on.
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"build_date"`
+	GoVersion string `json:"go_version"`
+	Kernel    string `json:"kernel"`
+}
+
+// Get returns the running binary's Info. Fields that cannot be determined
+// are "unknown".
+func Get() Info {
+	commit, date := Commit, BuildDate
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = cmp.Or(commit, s.Value)
+			case "vcs.time":
+				date = cmp.Or(date, s.Value)
+			}
+		}
+	}
+	return Info{
+		Version:   Version,
+		Commit:    cmp.Or(commit, "unknown"),
+		BuildDate: cmp.Or(date, "unknown"),
+		GoVersion: runtime.Version(),
+		Kernel:    cmp.Or(kernelRelease(), "unknown"),
+	}
+}
+
+func kernelRelease() string {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return ""
+	}
+	return unix.ByteSliceToString(u.Release[:])
+}
+
+// String returns a one-line version banner for the named binary.
+func String(binary string) string {
+	i := Get()
+	return fmt.Sprintf("%s %s (commit %s, built %s, %s)",
+		binary, i.Version, i.Commit, i.BuildDate, i.GoVersion)
+}
+
+// Handler serves Info as JSON, for /version on the metrics servers.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Get())
+	})
+}
+
+var buildInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Name: constants.MetricBuildInfo,
+	Help: "Always 1; the labels identify the running build and kernel.",
+}, constants.LabelsBuildInfo)
+
+func init() {
+	i := Get()
+	buildInfo.WithLabelValues(i.Version, i.Commit, i.GoVersion, i.Kernel).Set(1)
+}
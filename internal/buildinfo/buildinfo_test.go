@@ -0,0 +1,52 @@
+package buildinfo
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestGet_Ldflags(t *testing.T) {
+	defer func(c, d string) { Commit, BuildDate = c, d }(Commit, BuildDate)
+	Commit, BuildDate = "abc1234", "2024-05-01T00:00:00Z"
+
+	i := Get()
+	if i.Version != Version || i.Commit != "abc1234" || i.BuildDate != "2024-05-01T00:00:00Z" {
+		t.Errorf("Get() = %+v, want ldflags values", i)
+	}
+	if i.GoVersion != runtime.Version() || i.Kernel == "" {
+		t.Errorf("Get() = %+v, want Go version and kernel", i)
+	}
+	if s := String("kubepulse"); !strings.HasPrefix(s, "kubepulse "+Version+" (commit abc1234,") {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/version", nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	var got Info
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got != Get() {
+		t.Errorf("/version = %+v, want %+v", got, Get())
+	}
+}
+
+func TestBuildInfoMetric(t *testing.T) {
+	i := Get()
+	if v := testutil.ToFloat64(buildInfo.WithLabelValues(i.Version, i.Commit, i.GoVersion, i.Kernel)); v != 1 {
+		t.Errorf("build_info = %v, want 1", v)
+	}
+	if n := testutil.CollectAndCount(buildInfo); n != 1 {
+		t.Errorf("build_info has %d series, want 1", n)
+	}
+}
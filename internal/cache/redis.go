@@ -1,8 +1,11 @@
 // Package cache provides a Redis client for KubePulse API caching + pub/sub.
+// Redis is optional: Disabled stands in for it when it is turned off or
+// unreachable.
 package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -25,6 +28,26 @@ func DefaultRedisConfig() RedisConfig {
 	}
 }
 
+// Cache is the Redis API the API server uses. *Redis implements it;
+// Disabled stands in when Redis is off.
+type Cache interface {
+	Get(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key string, value any, ttl time.Duration) error
+	HSet(ctx context.Context, key, field string, value any) error
+	HGet(ctx context.Context, key, field string) (string, error)
+	HGetAll(ctx context.Context, key string) (map[string]string, error)
+	HDel(ctx context.Context, key, field string) (int64, error)
+	// HSetCapped sets field unless the hash already has max fields,
+	// reporting whether it did. The check and the set are atomic.
+	HSetCapped(ctx context.Context, key, field string, value any, max int64) (bool, error)
+	Publish(ctx context.Context, channel string, msg any) error
+	// Subscribe returns nil when Enabled is false.
+	Subscribe(ctx context.Context, channel string) *redis.PubSub
+	Ping(ctx context.Context) error
+	Enabled() bool
+	Close() error
+}
+
 // Redis wraps go-redis with caching helpers.
 type Redis struct {
 	Client *redis.Client
@@ -58,6 +81,48 @@ func (r *Redis) Set(ctx context.Context, key string, value any, ttl time.Duratio
 	return r.Client.Set(ctx, key, value, ttl).Err()
 }
 
+// HSet stores field=value in the hash at key.
+func (r *Redis) HSet(ctx context.Context, key, field string, value any) error {
+	return r.Client.HSet(ctx, key, field, value).Err()
+}
+
+// HGet fetches one field of the hash at key.
+func (r *Redis) HGet(ctx context.Context, key, field string) (string, error) {
+	return r.Client.HGet(ctx, key, field).Result()
+}
+
+// HGetAll fetches every field of the hash at key.
+func (r *Redis) HGetAll(ctx context.Context, key string) (map[string]string, error) {
+	return r.Client.HGetAll(ctx, key).Result()
+}
+
+// HDel removes a field from the hash at key, returning how many were removed.
+func (r *Redis) HDel(ctx context.Context, key, field string) (int64, error) {
+	return r.Client.HDel(ctx, key, field).Result()
+}
+
+// hsetCapped is HSetCapped's script: Redis runs it without interleaving
+// other commands.
+var hsetCapped = redis.NewScript(`
+if redis.call('HLEN', KEYS[1]) >= tonumber(ARGV[3]) then
+	return 0
+end
+redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+return 1
+`)
+
+// HSetCapped sets field=value in the hash at key unless it already has
+// max fields, reporting whether it did.
+func (r *Redis) HSetCapped(ctx context.Context, key, field string, value any, max int64) (bool, error) {
+	set, err := hsetCapped.Run(ctx, r.Client, []string{key}, field, value, max).Int()
+	return set == 1, err
+}
+
+// IsNil reports whether err means the key or field does not exist.
+func IsNil(err error) bool {
+	return err == redis.Nil
+}
+
 // Publish sends a message to a pub/sub channel (for WebSocket live updates).
 func (r *Redis) Publish(ctx context.Context, channel string, msg any) error {
 	return r.Client.Publish(ctx, channel, msg).Err()
@@ -68,7 +133,40 @@ func (r *Redis) Subscribe(ctx context.Context, channel string) *redis.PubSub {
 	return r.Client.Subscribe(ctx, channel)
 }
 
+// Ping checks that Redis is reachable.
+func (r *Redis) Ping(ctx context.Context) error {
+	return r.Client.Ping(ctx).Err()
+}
+
 // Close closes the Redis connection.
 func (r *Redis) Close() error {
 	return r.Client.Close()
 }
+
+// Enabled reports true: responses are cached and live events fanned out.
+func (r *Redis) Enabled() bool { return true }
+
+// ErrDisabled is returned by Disabled for operations that need Redis.
+var ErrDisabled = errors.New("redis disabled")
+
+// Disabled is the Cache used without Redis: Get always misses, Set and
+// Publish do nothing, and the hash operations behind saved views fail
+// with ErrDisabled.
+type Disabled struct{}
+
+func (Disabled) Get(context.Context, string) (string, error)           { return "", ErrDisabled }
+func (Disabled) Set(context.Context, string, any, time.Duration) error { return nil }
+func (Disabled) HSet(context.Context, string, string, any) error       { return ErrDisabled }
+func (Disabled) HGet(context.Context, string, string) (string, error)  { return "", ErrDisabled }
+func (Disabled) HGetAll(context.Context, string) (map[string]string, error) {
+	return nil, ErrDisabled
+}
+func (Disabled) HDel(context.Context, string, string) (int64, error) { return 0, ErrDisabled }
+func (Disabled) HSetCapped(context.Context, string, string, any, int64) (bool, error) {
+	return false, ErrDisabled
+}
+func (Disabled) Publish(context.Context, string, any) error      { return nil }
+func (Disabled) Subscribe(context.Context, string) *redis.PubSub { return nil }
+func (Disabled) Ping(context.Context) error                      { return ErrDisabled }
+func (Disabled) Enabled() bool                                   { return false }
+func (Disabled) Close() error                                    { return nil }
@@ -0,0 +1,92 @@
+// Package caps reads the agent's effective Linux capabilities, so it can run
+// with CAP_BPF and CAP_PERFMON granted in the pod securityContext instead of
+// as root.
+//
+// The agent only attaches kprobes, tracepoints and fentry programs. On
+// Linux 5.8+ loading them needs CAP_BPF and attaching them CAP_PERFMON;
+// CAP_SYS_ADMIN implies both. CAP_SYS_RESOURCE is also needed to lift the
+// memlock rlimit on kernels before 5.11, which charge BPF maps against it.
+package caps
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Set is a capability bitmask as in /proc/<pid>/status.
+type Set uint64
+
+// Has reports whether s holds capability c, e.g. unix.CAP_BPF.
+func (s Set) Has(c int) bool {
+	return s&(1<<uint(c)) != 0
+}
+
+// ParseStatus returns the effective set (CapEff) of a /proc/<pid>/status
+// file.
+func ParseStatus(r io.Reader) (Set, error) {
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		hex, ok := strings.CutPrefix(sc.Text(), "CapEff:")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseUint(strings.TrimSpace(hex), 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("CapEff %q: %w", strings.TrimSpace(hex), err)
+		}
+		return Set(v), nil
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("no CapEff line")
+}
+
+// Effective returns the agent's effective capabilities.
+func Effective() (Set, error) {
+	f, err := os.Open(constants.ProcSelfStatus)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	s, err := ParseStatus(f)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", constants.ProcSelfStatus, err)
+	}
+	return s, nil
+}
+
+// MissingForBPF returns the capabilities s lacks to load and attach the
+// agent's probes, by name; none if it can.
+func MissingForBPF(s Set) []string {
+	if s.Has(unix.CAP_SYS_ADMIN) {
+		return nil
+	}
+	var missing []string
+	if !s.Has(unix.CAP_BPF) {
+		missing = append(missing, "CAP_BPF")
+	}
+	if !s.Has(unix.CAP_PERFMON) {
+		missing = append(missing, "CAP_PERFMON")
+	}
+	return missing
+}
+
+// Check returns an error naming the missing capabilities if s cannot load
+// the agent's probes.
+func Check(s Set) error {
+	missing := MissingForBPF(s)
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("missing %s: run as root, or grant CAP_BPF and CAP_PERFMON (Linux 5.8+) in the securityContext",
+		strings.Join(missing, " and "))
+}
@@ -0,0 +1,67 @@
+package caps
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+const status = `Name:	kubepulse
+Umask:	0022
+State:	S (sleeping)
+CapInh:	0000000000000000
+CapPrm:	000000c000001000
+CapEff:	000000c000001000
+CapBnd:	000001ffffffffff
+`
+
+func TestParseStatus(t *testing.T) {
+	s, err := ParseStatus(strings.NewReader(status))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []int{unix.CAP_BPF, unix.CAP_PERFMON, unix.CAP_NET_ADMIN} {
+		if !s.Has(c) {
+			t.Errorf("capability %d not in %x", c, s)
+		}
+	}
+	if s.Has(unix.CAP_SYS_ADMIN) {
+		t.Errorf("CAP_SYS_ADMIN in %x", s)
+	}
+
+	for _, bad := range []string{"Name:\tx\n", "CapEff:\tzz\n"} {
+		if _, err := ParseStatus(strings.NewReader(bad)); err == nil {
+			t.Errorf("ParseStatus(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestMissingForBPF(t *testing.T) {
+	bit := func(c int) Set { return 1 << uint(c) }
+	tests := []struct {
+		name string
+		set  Set
+		want string
+	}{
+		{"root", 0x1ffffffffff, ""},
+		{"sys_admin only", bit(unix.CAP_SYS_ADMIN), ""},
+		{"bpf and perfmon", bit(unix.CAP_BPF) | bit(unix.CAP_PERFMON), ""},
+		{"bpf only", bit(unix.CAP_BPF), "CAP_PERFMON"},
+		{"net_admin only", bit(unix.CAP_NET_ADMIN), "CAP_BPF CAP_PERFMON"},
+		{"unprivileged", 0, "CAP_BPF CAP_PERFMON"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(MissingForBPF(tt.set), " "); got != tt.want {
+			t.Errorf("%s: missing %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	err := Check(0)
+	if err == nil || !strings.Contains(err.Error(), "CAP_BPF and CAP_PERFMON") {
+		t.Errorf("Check(0) = %v", err)
+	}
+	if err := Check(bit(unix.CAP_SYS_ADMIN)); err != nil {
+		t.Errorf("Check(CAP_SYS_ADMIN) = %v", err)
+	}
+}
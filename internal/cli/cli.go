@@ -0,0 +1,200 @@
+// Package cli holds the command-line plumbing shared by the kubepulse,
+// consumer and api binaries: flags that fall back to environment
+// variables, --version, --validate-config and logger construction.
+//
+// Precedence everywhere is defaults < YAML file < environment < flags.
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// LookupEnv has the signature of os.LookupEnv; tests pass a map lookup.
+type LookupEnv func(key string) (string, bool)
+
+// Common holds the flags every binary accepts.
+type Common struct {
+	Version        bool
+	ValidateConfig bool
+}
+
+// Register adds --version and --validate-config to fs.
+func (c *Common) Register(fs *flag.FlagSet) {
+	fs.BoolVar(&c.Version, "version", false, "print version, commit and build date, then exit")
+	fs.BoolVar(&c.ValidateConfig, "validate-config", false,
+		"load and validate the configuration, print it as YAML, then exit (non-zero if invalid)")
+}
+
+// StringVar registers a string flag whose default is the env var key if
+// set (non-empty), else *p.
+func StringVar(fs *flag.FlagSet, env LookupEnv, p *string, name, key, usage string) {
+	if v, ok := env(key); ok && v != "" {
+		*p = v
+	}
+	fs.StringVar(p, name, *p, envUsage(usage, key))
+}
+
+func envUsage(usage, key string) string {
+	return fmt.Sprintf("%s (env %s)", usage, key)
+}
+
+// Settings layers defaults < YAML file < environment < flags for the
+// backend binaries. Fields are bound with String, Bool, ...; Parse then
+// loads the file over the defaults and applies the environment and the
+// flags actually given on the command line, in that order.
+type Settings struct {
+	fs         *flag.FlagSet
+	env        LookupEnv
+	configPath string
+	envs       []func() error
+}
+
+// NewSettings creates Settings with a --config flag (env
+// constants.EnvConfigPath, default constants.DefaultConfigPath).
+func NewSettings(name string, env LookupEnv, output io.Writer) *Settings {
+	s := &Settings{fs: flag.NewFlagSet(name, flag.ContinueOnError), env: env}
+	s.fs.SetOutput(output)
+	s.fs.StringVar(&s.configPath, "config", "",
+		envUsage("path to the YAML config file (default "+constants.DefaultConfigPath+")", constants.EnvConfigPath))
+	return s
+}
+
+// FlagSet returns the underlying flag set, for flags with no env var.
+func (s *Settings) FlagSet() *flag.FlagSet {
+	return s.fs
+}
+
+// String binds p to --name and, when set and non-empty, env var key.
+func (s *Settings) String(p *string, name, key, usage string) {
+	s.fs.StringVar(p, name, *p, envUsage(usage, key))
+	s.envs = append(s.envs, func() error {
+		if v, ok := s.env(key); ok && v != "" {
+			*p = v
+		}
+		return nil
+	})
+}
+
+// StringAllowEmpty is String, except that key set to "" clears p.
+func (s *Settings) StringAllowEmpty(p *string, name, key, usage string) {
+	s.fs.StringVar(p, name, *p, envUsage(usage, key))
+	s.envs = append(s.envs, func() error {
+		if v, ok := s.env(key); ok {
+			*p = v
+		}
+		return nil
+	})
+}
+
+// Bool is String for booleans. An unparsable env value fails Parse.
+func (s *Settings) Bool(p *bool, name, key, usage string) {
+	s.fs.BoolVar(p, name, *p, envUsage(usage, key))
+	s.envs = append(s.envs, func() error {
+		if v, ok := s.env(key); ok && v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*p = b
+		}
+		return nil
+	})
+}
+
+// Duration is String for durations. An unparsable env value fails Parse.
+func (s *Settings) Duration(p *time.Duration, name, key, usage string) {
+	s.fs.DurationVar(p, name, *p, envUsage(usage, key))
+	s.envs = append(s.envs, func() error {
+		if v, ok := s.env(key); ok && v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*p = d
+		}
+		return nil
+	})
+}
+
+// Parse parses args, then calls load with the config path so it can
+// replace the bound fields with file values, then re-applies env vars and
+// the flags given in args on top.
+func (s *Settings) Parse(args []string, load func(path string) error) error {
+	if err := s.fs.Parse(args); err != nil {
+		return err
+	}
+	given := make(map[string]string)
+	s.fs.Visit(func(f *flag.Flag) { given[f.Name] = f.Value.String() })
+
+	path := s.configPath
+	if path == "" {
+		path = constants.DefaultConfigPath
+		if v, ok := s.env(constants.EnvConfigPath); ok && v != "" {
+			path = v
+		}
+	}
+	if err := load(path); err != nil {
+		return err
+	}
+	for _, apply := range s.envs {
+		if err := apply(); err != nil {
+			return err
+		}
+	}
+	for name, v := range given {
+		if err := s.fs.Set(name, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ClickHouseVars binds the ClickHouse flags and env vars shared by the
+// consumer and the API.
+func ClickHouseVars(s *Settings, cfg *storage.ClickHouseConfig) {
+	s.String(&cfg.DSN, "clickhouse-dsn", "CLICKHOUSE_DSN", "ClickHouse DSN")
+	s.Bool(&cfg.AutoMigrate, "clickhouse-auto-migrate", "CLICKHOUSE_AUTO_MIGRATE",
+		"create the database and apply schema migrations on start")
+	s.Duration(&cfg.Retention, "clickhouse-retention", "CLICKHOUSE_RETENTION",
+		"events TTL; 0 keeps data forever")
+}
+
+// ValidateConfig implements --validate-config: given the outcome of
+// loading and validating cfg, it prints cfg as YAML to stdout or the
+// error to stderr, and returns the process exit code.
+func ValidateConfig(binary string, cfg any, err error, stdout, stderr io.Writer) int {
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: invalid config: %v\n", binary, err)
+		return 1
+	}
+	enc := yaml.NewEncoder(stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", binary, err)
+		return 1
+	}
+	enc.Close()
+	return 0
+}
+
+// NewLogger builds the production JSON logger at the given level.
+func NewLogger(level string) (*zap.Logger, error) {
+	lvl, err := config.ParseLogLevel(level)
+	if err != nil {
+		return nil, err
+	}
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
+	return cfg.Build()
+}
@@ -0,0 +1,138 @@
+// Package backend provides YAML configuration for the KubePulse backend
+// services — the consumer and the API server. It is separate from package
+// config so the agent's config carries no consumer, ClickHouse or Redis
+// settings; standalone mode has its own, see package standalone.
+//
+// A single file can serve the agent and the services: each reads only its
+// own sections and ignores the rest.
+package backend
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/consumer"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// Config is the backend services' configuration.
+type Config struct {
+	Storage  StorageConfig   `yaml:"storage"`
+	Cache    CacheConfig     `yaml:"cache"`
+	Consumer consumer.Config `yaml:"consumer"`
+}
+
+// StorageConfig holds event storage settings.
+type StorageConfig struct {
+	ClickHouse storage.ClickHouseConfig `yaml:"clickhouse"`
+}
+
+// CacheConfig holds API cache settings. With Enabled false the API server
+// runs without Redis: no response cache, saved views or live events.
+type CacheConfig struct {
+	Enabled bool              `yaml:"enabled"`
+	Redis   cache.RedisConfig `yaml:"redis"`
+}
+
+// Default returns the built-in defaults, sourced from the constants
+// package through each component's Default*Config.
+func Default() Config {
+	return Config{
+		Storage:  StorageConfig{ClickHouse: storage.DefaultClickHouseConfig()},
+		Cache:    CacheConfig{Enabled: true, Redis: cache.DefaultRedisConfig()},
+		Consumer: consumer.DefaultConfig(),
+	}
+}
+
+// Load reads a YAML config file over the defaults. If the file doesn't
+// exist, returns defaults. Load does not validate: callers apply env and
+// flag overrides first, then call Validate.
+func Load(path string) (Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return cfg, fmt.Errorf("reading config %s: %w", path, err)
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the config for logical errors.
+func (c Config) Validate() error {
+	var errs []string
+
+	if err := c.Storage.ClickHouse.Validate(); err != nil {
+		errs = append(errs, "storage.clickhouse: "+err.Error())
+	}
+	if c.Storage.ClickHouse.MaxConns < 1 {
+		errs = append(errs, "storage.clickhouse.max_conns must be >= 1")
+	}
+
+	if c.Cache.Enabled && c.Cache.Redis.Addr == "" {
+		errs = append(errs, "cache.redis.addr is required")
+	}
+	if c.Cache.Enabled && c.Cache.Redis.PoolSize < 1 {
+		errs = append(errs, "cache.redis.pool_size must be >= 1")
+	}
+
+	cc := c.Consumer
+	for field, v := range map[string]string{
+		"nats_url":      cc.NATSURL,
+		"stream":        cc.Stream,
+		"subject":       cc.Subject,
+		"consumer_name": cc.ConsumerName,
+		"dlq_stream":    cc.DLQStream,
+		"dlq_subject":   cc.DLQSubject,
+	} {
+		if v == "" {
+			errs = append(errs, "consumer."+field+" is required")
+		}
+	}
+	for field, v := range map[string]int{
+		"batch_size":     cc.BatchSize,
+		"workers":        cc.Workers,
+		"max_deliveries": cc.MaxDeliveries,
+	} {
+		if v < 1 {
+			errs = append(errs, fmt.Sprintf("consumer.%s must be >= 1", field))
+		}
+	}
+	if cc.FlushInterval <= 0 {
+		errs = append(errs, "consumer.flush_interval must be > 0")
+	}
+	if cc.NakDelay < 0 {
+		errs = append(errs, "consumer.nak_delay must be >= 0")
+	}
+	if cc.LagThreshold < 0 {
+		errs = append(errs, "consumer.lag_threshold must be >= 0")
+	}
+	if cc.DedupSize < 0 {
+		errs = append(errs, "consumer.dedup_size must be >= 0")
+	}
+	if cc.Live.RateLimit < 0 {
+		errs = append(errs, "consumer.live.rate_limit must be >= 0")
+	}
+	if cc.Live.Enabled && c.Cache.Redis.Addr == "" {
+		errs = append(errs, "cache.redis.addr is required when consumer.live.enabled is set")
+	}
+	if cc.SpillDir != "" && cc.SpillMaxBytes < 1 {
+		errs = append(errs, "consumer.spill_max_bytes must be >= 1 when spill_dir is set")
+	}
+
+	if len(errs) > 0 {
+		slices.Sort(errs)
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubepulse.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDefault_MatchesConstants(t *testing.T) {
+	c := Default()
+	if c.Storage.ClickHouse.DSN != constants.ClickHouseDefaultDSN ||
+		c.Storage.ClickHouse.MaxConns != constants.ClickHouseMaxConns ||
+		c.Storage.ClickHouse.Retention != constants.ClickHouseDefaultRetention ||
+		c.Storage.ClickHouse.QueryTimeout != constants.ClickHouseQueryTimeout {
+		t.Errorf("clickhouse defaults = %+v", c.Storage.ClickHouse)
+	}
+	if c.Cache.Redis.Addr != constants.RedisDefaultAddr || c.Cache.Redis.PoolSize != constants.RedisPoolSize {
+		t.Errorf("redis defaults = %+v", c.Cache.Redis)
+	}
+	cc := c.Consumer
+	if cc.NATSURL != constants.NATSDefaultURL || cc.BatchSize != constants.ClickHouseBatchSize ||
+		cc.FlushInterval != constants.ClickHouseFlushInterval || cc.SpillDir != "" {
+		t.Errorf("consumer defaults = %+v", cc)
+	}
+	if err := c.Validate(); err != nil {
+		t.Errorf("defaults fail validation: %v", err)
+	}
+}
+
+func TestLoad_MissingFileReturnsDefaults(t *testing.T) {
+	c, err := Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(c, Default()) {
+		t.Errorf("Load(missing) = %+v, want defaults", c)
+	}
+}
+
+func TestLoad_Sections(t *testing.T) {
+	c, err := Load(writeConfig(t, `
+agent:
+  node_name: ignored-by-backend
+storage:
+  clickhouse:
+    max_conns: 16
+    retention: 72h
+cache:
+  redis:
+    addr: redis:6379
+consumer:
+  batch_size: 50000
+  flush_interval: 2s
+  workers: 8
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Storage.ClickHouse.MaxConns != 16 || c.Storage.ClickHouse.Retention != 72*time.Hour {
+		t.Errorf("clickhouse = %+v", c.Storage.ClickHouse)
+	}
+	if c.Storage.ClickHouse.DSN != constants.ClickHouseDefaultDSN {
+		t.Errorf("unset dsn should keep its default, got %q", c.Storage.ClickHouse.DSN)
+	}
+	if c.Cache.Redis.Addr != "redis:6379" || c.Cache.Redis.PoolSize != constants.RedisPoolSize {
+		t.Errorf("redis = %+v", c.Cache.Redis)
+	}
+	if c.Consumer.BatchSize != 50000 || c.Consumer.FlushInterval != 2*time.Second || c.Consumer.Workers != 8 {
+		t.Errorf("consumer = %+v", c.Consumer)
+	}
+}
+
+func TestLoad_ParseError(t *testing.T) {
+	if _, err := Load(writeConfig(t, "consumer:\n  flush_interval: soon\n")); err == nil {
+		t.Error("Load accepted an unparsable duration")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		mutate func(*Config)
+		want   string
+	}{
+		{"bad dsn", func(c *Config) { c.Storage.ClickHouse.DSN = "://" }, "storage.clickhouse"},
+		{"max conns", func(c *Config) { c.Storage.ClickHouse.MaxConns = 0 }, "storage.clickhouse.max_conns"},
+		{"query timeout", func(c *Config) { c.Storage.ClickHouse.QueryTimeout = -time.Second }, "query_timeout"},
+		{"redis addr", func(c *Config) { c.Cache.Redis.Addr = "" }, "cache.redis.addr"},
+		{"pool size", func(c *Config) { c.Cache.Redis.PoolSize = 0 }, "cache.redis.pool_size"},
+		{"nats url", func(c *Config) { c.Consumer.NATSURL = "" }, "consumer.nats_url"},
+		{"batch size", func(c *Config) { c.Consumer.BatchSize = 0 }, "consumer.batch_size"},
+		{"flush interval", func(c *Config) { c.Consumer.FlushInterval = 0 }, "consumer.flush_interval"},
+		{"spill max", func(c *Config) { c.Consumer.SpillDir, c.Consumer.SpillMaxBytes = "/var/lib/kubepulse/spill", 0 }, "consumer.spill_max_bytes"},
+		{"dedup size", func(c *Config) { c.Consumer.DedupSize = -1 }, "consumer.dedup_size"},
+		{"lag threshold", func(c *Config) { c.Consumer.LagThreshold = -time.Second }, "consumer.lag_threshold"},
+		{"live rate limit", func(c *Config) { c.Consumer.Live.RateLimit = -1 }, "consumer.live.rate_limit"},
+		{"live without redis", func(c *Config) { c.Consumer.Live.Enabled = true; c.Cache.Redis.Addr = "" }, "cache.redis.addr is required when"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := Default()
+			tt.mutate(&c)
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Validate() = %v, want error mentioning %q", err, tt.want)
+			}
+		})
+	}
+
+	c := Default()
+	c.Consumer.SpillDir = ""
+	c.Consumer.SpillMaxBytes = 0
+	if err := c.Validate(); err != nil {
+		t.Errorf("spill_max_bytes should not matter with spilling disabled: %v", err)
+	}
+
+	c = Default()
+	c.Cache.Enabled = false
+	c.Cache.Redis.Addr = ""
+	if err := c.Validate(); err != nil {
+		t.Errorf("cache.redis should not matter with the cache disabled: %v", err)
+	}
+}
@@ -4,20 +4,46 @@ package config
 
 import (
 	"fmt"
+	"maps"
 	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
+	"time"
 
+	"go.uber.org/zap/zapcore"
 	"gopkg.in/yaml.v3"
 
+	"github.com/sureshkrishnan-v/kubePulse/internal/alert"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/dnsname"
+	"github.com/sureshkrishnan-v/kubePulse/internal/export"
+	"github.com/sureshkrishnan-v/kubePulse/internal/redact"
+	"github.com/sureshkrishnan-v/kubePulse/internal/standalone"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// Modes the agent runs in.
+const (
+	ModeAgent      = "agent"      // export only; the backend runs separately
+	ModeStandalone = "standalone" // also store events and serve the API, see package standalone
 )
 
 // Config is the top-level configuration for KubePulse.
 type Config struct {
+	// Mode is agent or standalone. Standalone mode is configured under
+	// standalone and needs no NATS, ClickHouse or Redis.
+	Mode       string            `yaml:"mode"`
+	Standalone standalone.Config `yaml:"standalone"`
+
 	Agent       AgentConfig              `yaml:"agent"`
 	Modules     map[string]*ModuleConfig `yaml:"modules"`
 	Exporters   ExportersConfig          `yaml:"exporters"`
 	Performance PerformanceConfig        `yaml:"performance"`
+
+	// Redaction drops or hashes sensitive labels before any exporter sees
+	// an event. The default keeps every label.
+	Redaction redact.Config `yaml:"redaction"`
 }
 
 // AgentConfig holds global agent settings.
@@ -25,15 +51,98 @@ type AgentConfig struct {
 	MetricsAddr string `yaml:"metrics_addr"`
 	NodeName    string `yaml:"node_name"`
 	LogLevel    string `yaml:"log_level"`
+
+	// DebugEndpoints mounts /debug/pprof/ and /debug/vars on the metrics
+	// server. Off by default: profiles expose process internals.
+	DebugEndpoints bool `yaml:"debug_endpoints"`
+
+	// MaxModuleRestarts is how many times a module whose read loop fails
+	// is restarted before it is given up on; 0 disables restarts.
+	MaxModuleRestarts int `yaml:"max_module_restarts"`
+
+	// MinReadyModules is how many modules must be running for /readyz to
+	// report ready.
+	MinReadyModules int `yaml:"min_ready_modules"`
+
+	// TraceSelf keeps the events of the agent's own processes. Off by
+	// default: its exports, /proc reads and DNS lookups would feed back
+	// into what it reports.
+	TraceSelf bool `yaml:"trace_self"`
+
+	// PinBPF pins each module's maps and links under PinPath, which must
+	// be on a writable bpffs, so a restarted agent picks up the programs
+	// and ring buffers the previous one left attached instead of missing
+	// events while it loads them again.
+	PinBPF  bool   `yaml:"pin_bpf"`
+	PinPath string `yaml:"pin_path"`
 }
 
 // ModuleConfig holds per-module settings.
 type ModuleConfig struct {
-	Enabled        bool    `yaml:"enabled"`
-	RingBufferSize int     `yaml:"ring_buffer_size"`
-	SamplingRate   float64 `yaml:"sampling_rate"`
+	Enabled        bool         `yaml:"enabled"`
+	RingBufferSize int          `yaml:"ring_buffer_size"`
+	SamplingRate   float64      `yaml:"sampling_rate"`
+	Filters        FilterConfig `yaml:"filters"`
+
+	// DrainTimeout bounds how long the module keeps publishing events
+	// already queued in its ring buffer after shutdown begins; 0 means
+	// constants.DefaultDrainTimeout.
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+
+	// MinLatency is the fileio module's in-kernel threshold: faster I/O is
+	// counted but never sent to userspace. 0 means
+	// constants.DefaultFileIOMinLatency. Unlike filters.min_latency it
+	// saves kernel and ring buffer work, but is fixed at load time.
+	MinLatency time.Duration `yaml:"min_latency"`
+
+	// AggregateWindow coalesces the exec module's events: execs of the
+	// same file in the same pod within one window are published once,
+	// with a count. 0 publishes every exec.
+	AggregateWindow time.Duration `yaml:"aggregate_window"`
 }
 
+// Drain returns the module's ring buffer drain timeout.
+func (m *ModuleConfig) Drain() time.Duration {
+	if m == nil || m.DrainTimeout == 0 {
+		return constants.DefaultDrainTimeout
+	}
+	return m.DrainTimeout
+}
+
+// KernelMinLatency returns the module's in-kernel latency threshold.
+func (m *ModuleConfig) KernelMinLatency() time.Duration {
+	if m == nil || m.MinLatency == 0 {
+		return constants.DefaultFileIOMinLatency
+	}
+	return m.MinLatency
+}
+
+// FilterConfig drops events a module would otherwise publish.
+// Port filters apply to network modules (tcp, retransmit, rst) and
+// MinLatency to tcp and fileio; Validate rejects them elsewhere.
+type FilterConfig struct {
+	ExcludeComm []string      `yaml:"exclude_comm"` // regexes matched against the process name
+	AllowPorts  []uint16      `yaml:"allow_ports"`  // destination (or accepting) ports to keep; empty keeps all
+	DenyPorts   []uint16      `yaml:"deny_ports"`   // destination (or accepting) ports to drop; wins over AllowPorts
+	MinLatency  time.Duration `yaml:"min_latency"`  // drop events faster than this
+}
+
+// Empty reports whether f filters nothing.
+func (f FilterConfig) Empty() bool {
+	return len(f.ExcludeComm) == 0 && len(f.AllowPorts) == 0 && len(f.DenyPorts) == 0 && f.MinLatency == 0
+}
+
+// unsampledModules report rare events where each one matters; they always
+// bypass sampling, static or adaptive.
+var unsampledModules = map[string]bool{constants.ModuleOOM: true, constants.ModuleDrop: true, constants.ModuleSignals: true}
+
+// portFilterModules and latencyFilterModules are the modules whose events
+// carry a destination port or a latency.
+var (
+	portFilterModules    = map[string]bool{constants.ModuleTCP: true, constants.ModuleRetransmit: true, constants.ModuleRST: true}
+	latencyFilterModules = map[string]bool{constants.ModuleTCP: true, constants.ModuleFileIO: true}
+)
+
 // NewModuleConfig creates a ModuleConfig with production defaults.
 func NewModuleConfig(ringBufSize int) *ModuleConfig {
 	return &ModuleConfig{
@@ -45,14 +154,23 @@ func NewModuleConfig(ringBufSize int) *ModuleConfig {
 
 // ExportersConfig holds exporter settings.
 type ExportersConfig struct {
-	Prometheus PrometheusConfig `yaml:"prometheus"`
-	OTLP       OTLPConfig       `yaml:"otlp"`
+	Prometheus   PrometheusConfig   `yaml:"prometheus"`
+	OTLP         OTLPConfig         `yaml:"otlp"`
+	NATS         NATSConfig         `yaml:"nats"`
+	File         FileConfig         `yaml:"file"`
+	Alerts       AlertsConfig       `yaml:"alerts"`
+	Alertmanager AlertmanagerConfig `yaml:"alertmanager"`
+	RemoteWrite  RemoteWriteConfig  `yaml:"remote_write"`
+	HTTPPush     HTTPPushConfig     `yaml:"http_push"`
 }
 
 // PrometheusConfig holds Prometheus exporter settings.
 type PrometheusConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Addr    string `yaml:"addr"`
+
+	export.PrometheusOptions `yaml:",inline"`
+	export.MetricsSecurity   `yaml:",inline"`
 }
 
 // OTLPConfig holds OpenTelemetry exporter settings (future).
@@ -61,10 +179,52 @@ type OTLPConfig struct {
 	Endpoint string `yaml:"endpoint"`
 }
 
+// NATSConfig holds NATS JetStream exporter settings.
+type NATSConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	export.NATSConfig `yaml:",inline"`
+}
+
+// FileConfig holds file exporter settings.
+type FileConfig struct {
+	Enabled           bool `yaml:"enabled"`
+	export.FileConfig `yaml:",inline"`
+}
+
+// AlertsConfig holds alert rule exporter settings.
+type AlertsConfig struct {
+	Enabled      bool `yaml:"enabled"`
+	alert.Config `yaml:",inline"`
+}
+
+// AlertmanagerConfig holds Alertmanager delivery settings for the alert
+// rules under exporters.alerts.
+type AlertmanagerConfig struct {
+	Enabled                  bool `yaml:"enabled"`
+	alert.AlertmanagerConfig `yaml:",inline"`
+}
+
+// RemoteWriteConfig holds Prometheus remote write exporter settings.
+type RemoteWriteConfig struct {
+	Enabled                  bool `yaml:"enabled"`
+	export.RemoteWriteConfig `yaml:",inline"`
+}
+
+// HTTPPushConfig holds the settings of the exporter pushing events to
+// the API server's ingest endpoint.
+type HTTPPushConfig struct {
+	Enabled               bool `yaml:"enabled"`
+	export.HTTPPushConfig `yaml:",inline"`
+}
+
 // PerformanceConfig holds performance tuning parameters.
 type PerformanceConfig struct {
 	EventBusBuffer int `yaml:"event_bus_buffer"`
 	WorkerPoolSize int `yaml:"worker_pool_size"`
+
+	// AdaptiveSampling lowers tcp, fileio and exec sampling while the
+	// event bus is dropping events, and restores it when pressure eases.
+	AdaptiveSampling bool `yaml:"adaptive_sampling"`
 }
 
 // Default returns a Config with sensible production defaults.
@@ -73,10 +233,15 @@ func Default() *Config {
 	hostname, _ := os.Hostname()
 
 	return &Config{
+		Mode:       ModeAgent,
+		Standalone: standalone.DefaultConfig(),
 		Agent: AgentConfig{
-			MetricsAddr: constants.DefaultMetricsAddr,
-			NodeName:    hostname,
-			LogLevel:    constants.DefaultLogLevel,
+			MetricsAddr:       constants.DefaultMetricsAddr,
+			NodeName:          hostname,
+			LogLevel:          constants.DefaultLogLevel,
+			MaxModuleRestarts: constants.DefaultMaxModuleRestarts,
+			MinReadyModules:   constants.DefaultMinReadyModules,
+			PinPath:           constants.DefaultPinPath,
 		},
 		Modules: map[string]*ModuleConfig{
 			constants.ModuleTCP:        NewModuleConfig(constants.RingBufLarge),
@@ -87,41 +252,77 @@ func Default() *Config {
 			constants.ModuleExec:       NewModuleConfig(constants.RingBufMedium),
 			constants.ModuleFileIO:     NewModuleConfig(constants.RingBufLarge),
 			constants.ModuleDrop:       NewModuleConfig(constants.RingBufMedium),
+			constants.ModuleSignals:    NewModuleConfig(constants.RingBufSmall),
 		},
 		Exporters: ExportersConfig{
 			Prometheus: PrometheusConfig{
-				Enabled: true,
-				Addr:    constants.DefaultMetricsAddr,
+				Enabled:           true,
+				Addr:              constants.DefaultMetricsAddr,
+				PrometheusOptions: export.DefaultPrometheusOptions(),
+				MetricsSecurity:   export.DefaultMetricsSecurity(),
 			},
 			OTLP: OTLPConfig{Enabled: false},
+			NATS: NATSConfig{
+				Enabled:    false,
+				NATSConfig: export.DefaultNATSConfig(),
+			},
+			File:         FileConfig{FileConfig: export.DefaultFileConfig()},
+			Alerts:       AlertsConfig{Config: alert.DefaultConfig()},
+			Alertmanager: AlertmanagerConfig{AlertmanagerConfig: alert.DefaultAlertmanagerConfig()},
+			RemoteWrite:  RemoteWriteConfig{RemoteWriteConfig: export.DefaultRemoteWriteConfig()},
+			HTTPPush:     HTTPPushConfig{HTTPPushConfig: export.DefaultHTTPPushConfig()},
 		},
 		Performance: PerformanceConfig{
-			EventBusBuffer: constants.DefaultEventBusBuffer,
-			WorkerPoolSize: constants.DefaultWorkerPoolSize,
+			EventBusBuffer:   constants.DefaultEventBusBuffer,
+			WorkerPoolSize:   constants.DefaultWorkerPoolSize,
+			AdaptiveSampling: true,
 		},
+		Redaction: redact.DefaultConfig(),
 	}
 }
 
+// Overrides are settings given on the command line. Non-empty fields
+// take precedence over both the config file and environment variables.
+type Overrides struct {
+	LogLevel    string
+	MetricsAddr string
+	NodeName    string
+}
+
+// Source describes where a Config comes from. Settings are layered
+// defaults < YAML file < environment variables < Overrides.
+type Source struct {
+	Path      string
+	Overrides Overrides
+}
+
 // Load reads a YAML config file and merges with defaults.
 // If the file doesn't exist, returns defaults.
 // Environment variables override file settings.
 func Load(path string) (*Config, error) {
+	return Source{Path: path}.Load()
+}
+
+// Load builds and validates the Config described by s.
+func (s Source) Load() (*Config, error) {
 	cfg := Default()
 
-	data, err := os.ReadFile(path)
-	if err != nil {
-		if os.IsNotExist(err) {
-			cfg.applyEnvOverrides()
-			return cfg, nil
+	data, err := os.ReadFile(s.Path)
+	switch {
+	case err == nil:
+		defaults := maps.Clone(cfg.Modules)
+		if err := yaml.Unmarshal(data, cfg); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", s.Path, err)
 		}
-		return nil, fmt.Errorf("reading config %s: %w", path, err)
-	}
-
-	if err := yaml.Unmarshal(data, cfg); err != nil {
-		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+		if err := cfg.mergeModules(data, defaults); err != nil {
+			return nil, fmt.Errorf("parsing config %s: %w", s.Path, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("reading config %s: %w", s.Path, err)
 	}
 
 	cfg.applyEnvOverrides()
+	cfg.applyOverrides(s.Overrides)
 
 	if err := cfg.Validate(); err != nil {
 		return nil, fmt.Errorf("config validation: %w", err)
@@ -130,6 +331,31 @@ func Load(path string) (*Config, error) {
 	return cfg, nil
 }
 
+// mergeModules decodes each modules.<name> block in data over that
+// module's defaults. yaml.v3 decodes map values into fresh zero values,
+// so without this a block setting only filters would leave the module
+// disabled with a sampling rate of 0.
+func (c *Config) mergeModules(data []byte, defaults map[string]*ModuleConfig) error {
+	var doc struct {
+		Modules map[string]yaml.Node `yaml:"modules"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for name, node := range doc.Modules {
+		def, ok := defaults[name]
+		if !ok || c.Modules[name] == nil {
+			continue // unknown modules and null blocks are left to Validate
+		}
+		mod := *def
+		if err := node.Decode(&mod); err != nil {
+			return err
+		}
+		c.Modules[name] = &mod
+	}
+	return nil
+}
+
 // applyEnvOverrides allows environment variables to override config values.
 func (c *Config) applyEnvOverrides() {
 	if addr := os.Getenv(constants.EnvMetricsAddr); addr != "" {
@@ -142,15 +368,53 @@ func (c *Config) applyEnvOverrides() {
 	if level := os.Getenv(constants.EnvLogLevel); level != "" {
 		c.Agent.LogLevel = level
 	}
+	if url := os.Getenv(constants.EnvNATSURL); url != "" {
+		c.Exporters.NATS.URL = url
+	}
+}
+
+// applyOverrides applies command-line settings, mirroring applyEnvOverrides.
+func (c *Config) applyOverrides(o Overrides) {
+	if o.MetricsAddr != "" {
+		c.Agent.MetricsAddr = o.MetricsAddr
+		c.Exporters.Prometheus.Addr = o.MetricsAddr
+	}
+	if o.NodeName != "" {
+		c.Agent.NodeName = o.NodeName
+	}
+	if o.LogLevel != "" {
+		c.Agent.LogLevel = o.LogLevel
+	}
 }
 
 // Validate checks the config for logical errors.
 func (c *Config) Validate() error {
 	var errs []string
 
+	switch c.Mode {
+	case ModeAgent:
+	case ModeStandalone:
+		if err := c.Standalone.Validate(); err != nil {
+			errs = append(errs, "standalone."+err.Error())
+		}
+	default:
+		errs = append(errs, fmt.Sprintf("mode must be %s or %s, got %q", ModeAgent, ModeStandalone, c.Mode))
+	}
 	if c.Agent.MetricsAddr == "" {
 		errs = append(errs, "agent.metrics_addr is required")
 	}
+	if _, err := ParseLogLevel(c.Agent.LogLevel); err != nil {
+		errs = append(errs, "agent."+err.Error())
+	}
+	if c.Agent.MaxModuleRestarts < 0 {
+		errs = append(errs, "agent.max_module_restarts must be >= 0")
+	}
+	if c.Agent.MinReadyModules < 1 {
+		errs = append(errs, "agent.min_ready_modules must be >= 1")
+	}
+	if c.Agent.PinBPF && !filepath.IsAbs(c.Agent.PinPath) {
+		errs = append(errs, "agent.pin_path must be an absolute path when agent.pin_bpf is set")
+	}
 	if c.Performance.EventBusBuffer < constants.MinEventBusBuffer {
 		errs = append(errs, fmt.Sprintf(
 			"performance.event_bus_buffer must be >= %d", constants.MinEventBusBuffer))
@@ -159,12 +423,129 @@ func (c *Config) Validate() error {
 		errs = append(errs, fmt.Sprintf(
 			"performance.worker_pool_size must be >= %d", constants.MinWorkerPoolSize))
 	}
+	// The remote write exporter aggregates with the Prometheus options too.
+	if pc := c.Exporters.Prometheus; pc.Enabled || c.Exporters.RemoteWrite.Enabled {
+		if pc.MaxPodsPerMetric < 1 {
+			errs = append(errs, "exporters.prometheus.max_pods_per_metric must be >= 1")
+		}
+		if pc.StalePodTTL <= 0 {
+			errs = append(errs, "exporters.prometheus.stale_pod_ttl must be > 0")
+		}
+		if !dnsname.ValidMode(pc.DNSDomainMode) {
+			errs = append(errs, fmt.Sprintf("exporters.prometheus.dns_domain_mode must be %s, %s or %s, got %q",
+				dnsname.ModeRegisteredDomain, dnsname.ModeLevels, dnsname.ModeFull, pc.DNSDomainMode))
+		}
+		if pc.DNSDomainMode == dnsname.ModeLevels && pc.DNSDomainLevels < 1 {
+			errs = append(errs, "exporters.prometheus.dns_domain_levels must be >= 1")
+		}
+		if err := pc.MetricsSecurity.Validate(); err != nil {
+			errs = append(errs, "exporters.prometheus."+err.Error())
+		}
+		if err := pc.ValidateRelabel(); err != nil {
+			errs = append(errs, "exporters.prometheus."+err.Error())
+		}
+	}
+	if nc := c.Exporters.NATS; nc.Enabled {
+		if nc.URL == "" || nc.Stream == "" || nc.Subject == "" {
+			errs = append(errs, "exporters.nats.url, stream and subject are required when enabled")
+		}
+		if nc.BatchSize < 1 {
+			errs = append(errs, "exporters.nats.batch_size must be >= 1")
+		}
+		if nc.FlushInterval <= 0 {
+			errs = append(errs, "exporters.nats.flush_interval must be > 0")
+		}
+		if nc.Compression != "" && !wire.SupportedEncoding(nc.Compression) {
+			errs = append(errs, fmt.Sprintf("exporters.nats.compression must be %s, %s or empty, got %q",
+				wire.EncodingZstd, wire.EncodingSnappy, nc.Compression))
+		}
+		if nc.Compression != "" && nc.PackSize < 1 {
+			errs = append(errs, "exporters.nats.pack_size must be >= 1 when compression is set")
+		}
+		if err := nc.StreamSettings.Validate(); err != nil {
+			errs = append(errs, "exporters.nats."+err.Error())
+		}
+	}
+	if fc := c.Exporters.File; fc.Enabled {
+		if fc.Path == "" {
+			errs = append(errs, "exporters.file.path is required when enabled")
+		}
+		if fc.MaxSizeMB < 1 {
+			errs = append(errs, "exporters.file.max_size_mb must be >= 1")
+		}
+		if fc.MaxFiles < 1 {
+			errs = append(errs, "exporters.file.max_files must be >= 1")
+		}
+		switch fc.Sync {
+		case export.FileSyncAlways, export.FileSyncInterval, export.FileSyncNone:
+		default:
+			errs = append(errs, fmt.Sprintf("exporters.file.sync must be %s, %s or %s, got %q",
+				export.FileSyncAlways, export.FileSyncInterval, export.FileSyncNone, fc.Sync))
+		}
+		if fc.SyncInterval <= 0 {
+			errs = append(errs, "exporters.file.sync_interval must be > 0")
+		}
+	}
+	if ac := c.Exporters.Alerts; ac.Enabled {
+		if err := ac.Validate(); err != nil {
+			errs = append(errs, "exporters.alerts: "+err.Error())
+		}
+		if ac.WebhookURL == "" && !c.Exporters.Alertmanager.Enabled {
+			errs = append(errs, "exporters.alerts.webhook_url is required unless exporters.alertmanager is enabled")
+		}
+	}
+	if am := c.Exporters.Alertmanager; am.Enabled {
+		if err := am.Validate(); err != nil {
+			errs = append(errs, "exporters.alertmanager: "+err.Error())
+		}
+		if !c.Exporters.Alerts.Enabled {
+			errs = append(errs, "exporters.alertmanager needs exporters.alerts enabled: the rules live there")
+		}
+	}
+	if rw := c.Exporters.RemoteWrite; rw.Enabled {
+		if err := rw.Validate(); err != nil {
+			errs = append(errs, "exporters.remote_write: "+err.Error())
+		}
+	}
+	if hp := c.Exporters.HTTPPush; hp.Enabled {
+		if err := hp.Validate(); err != nil {
+			errs = append(errs, "exporters.http_push: "+err.Error())
+		}
+	}
+	if err := c.Redaction.Validate(); err != nil {
+		errs = append(errs, "redaction: "+err.Error())
+	}
 	for name, mod := range c.Modules {
+		if mod == nil {
+			errs = append(errs, fmt.Sprintf("modules.%s must be a mapping", name))
+			continue
+		}
+		errs = append(errs, mod.Filters.validate(name)...)
 		if mod.SamplingRate < constants.MinSamplingRate || mod.SamplingRate > constants.MaxSamplingRate {
 			errs = append(errs, fmt.Sprintf(
 				"modules.%s.sampling_rate must be in [%.1f, %.1f]",
 				name, constants.MinSamplingRate, constants.MaxSamplingRate))
 		}
+		if mod.DrainTimeout < 0 || mod.DrainTimeout > constants.ShutdownTimeout {
+			errs = append(errs, fmt.Sprintf(
+				"modules.%s.drain_timeout must be in [0, %s]", name, constants.ShutdownTimeout))
+		}
+		if mod.MinLatency < 0 {
+			errs = append(errs, fmt.Sprintf("modules.%s.min_latency must be >= 0", name))
+		}
+		if mod.MinLatency > 0 && name != constants.ModuleFileIO {
+			errs = append(errs, fmt.Sprintf("modules.%s.min_latency only applies to fileio", name))
+		}
+		if mod.AggregateWindow < 0 {
+			errs = append(errs, fmt.Sprintf("modules.%s.aggregate_window must be >= 0", name))
+		}
+		if mod.AggregateWindow > 0 && name != constants.ModuleExec {
+			errs = append(errs, fmt.Sprintf("modules.%s.aggregate_window only applies to exec", name))
+		}
+		if unsampledModules[name] && mod.SamplingRate != constants.MaxSamplingRate {
+			errs = append(errs, fmt.Sprintf(
+				"modules.%s.sampling_rate must be %.1f: %s events are never sampled", name, constants.MaxSamplingRate, name))
+		}
 	}
 
 	if len(errs) > 0 {
@@ -173,6 +554,45 @@ func (c *Config) Validate() error {
 	return nil
 }
 
+// logLevels are the levels operators may configure. zap's DPanic, Panic
+// and Fatal levels would silence errors, so they are not accepted.
+var logLevels = map[string]zapcore.Level{
+	"debug": zapcore.DebugLevel,
+	"info":  zapcore.InfoLevel,
+	"warn":  zapcore.WarnLevel,
+	"error": zapcore.ErrorLevel,
+}
+
+// ParseLogLevel parses a log level name (debug, info, warn or error),
+// ignoring case and surrounding space.
+func ParseLogLevel(s string) (zapcore.Level, error) {
+	lvl, ok := logLevels[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, fmt.Errorf("log_level %q must be one of debug, info, warn, error", s)
+	}
+	return lvl, nil
+}
+
+func (f FilterConfig) validate(module string) []string {
+	var errs []string
+	prefix := "modules." + module + ".filters."
+	for _, expr := range f.ExcludeComm {
+		if _, err := regexp.Compile(expr); err != nil {
+			errs = append(errs, fmt.Sprintf("%sexclude_comm %q: %v", prefix, expr, err))
+		}
+	}
+	if (len(f.AllowPorts) > 0 || len(f.DenyPorts) > 0) && !portFilterModules[module] {
+		errs = append(errs, prefix+"allow_ports/deny_ports only apply to tcp, retransmit and rst")
+	}
+	if f.MinLatency < 0 {
+		errs = append(errs, prefix+"min_latency must be >= 0")
+	}
+	if f.MinLatency > 0 && !latencyFilterModules[module] {
+		errs = append(errs, prefix+"min_latency only applies to tcp and fileio")
+	}
+	return errs
+}
+
 // ModuleEnabled returns whether the named module is enabled.
 // Defaults to true if not configured.
 func (c *Config) ModuleEnabled(name string) bool {
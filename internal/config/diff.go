@@ -0,0 +1,202 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Change is one setting that differs between two configs.
+type Change struct {
+	Path string // YAML path, e.g. "modules.tcp.sampling_rate"
+	Old  string
+	New  string
+
+	// Live is true when the running agent can apply the change without a
+	// restart (log level, sampling rate, filters, module enable/disable).
+	// Anything that sizes kernel resources or binds a listener needs a
+	// restart.
+	Live bool
+}
+
+// Diff lists the settings that differ from old to next, in a stable order.
+func Diff(old, next *Config) []Change {
+	var changes []Change
+	add := func(path string, o, n any, live bool) {
+		if o != n {
+			changes = append(changes, Change{Path: path, Old: fmt.Sprint(o), New: fmt.Sprint(n), Live: live})
+		}
+	}
+
+	add("mode", old.Mode, next.Mode, false)
+	ost, nst := old.Standalone, next.Standalone
+	add("standalone.storage.path", ost.Storage.Path, nst.Storage.Path, false)
+	add("standalone.storage.retention", ost.Storage.Retention, nst.Storage.Retention, false)
+	add("standalone.batch_size", ost.BatchSize, nst.BatchSize, false)
+	add("standalone.flush_interval", ost.FlushInterval, nst.FlushInterval, false)
+	add("standalone.api.addr", ost.API.Addr, nst.API.Addr, false)
+	add("standalone.api.cors_origins", fmt.Sprint(ost.API.CORSOrigins), fmt.Sprint(nst.API.CORSOrigins), false)
+	add("standalone.api.cors_allow_credentials", ost.API.CORSAllowCredentials, nst.API.CORSAllowCredentials, false)
+	add("standalone.api.tls.cert_file", ost.API.TLS.CertFile, nst.API.TLS.CertFile, false)
+	add("standalone.api.tls.key_file", ost.API.TLS.KeyFile, nst.API.TLS.KeyFile, false)
+	add("standalone.api.tls.client_ca", ost.API.TLS.ClientCA, nst.API.TLS.ClientCA, false)
+	add("standalone.api.token_namespaces", fmt.Sprint(ost.API.TokenNamespaces), fmt.Sprint(nst.API.TokenNamespaces), false)
+
+	add("agent.log_level", old.Agent.LogLevel, next.Agent.LogLevel, true)
+	add("agent.metrics_addr", old.Agent.MetricsAddr, next.Agent.MetricsAddr, false)
+	add("agent.node_name", old.Agent.NodeName, next.Agent.NodeName, false)
+	add("agent.debug_endpoints", old.Agent.DebugEndpoints, next.Agent.DebugEndpoints, false)
+	add("agent.max_module_restarts", old.Agent.MaxModuleRestarts, next.Agent.MaxModuleRestarts, true)
+	add("agent.min_ready_modules", old.Agent.MinReadyModules, next.Agent.MinReadyModules, false)
+	add("agent.trace_self", old.Agent.TraceSelf, next.Agent.TraceSelf, false)
+	add("agent.pin_bpf", old.Agent.PinBPF, next.Agent.PinBPF, false)
+	add("agent.pin_path", old.Agent.PinPath, next.Agent.PinPath, false)
+
+	names := make(map[string]*ModuleConfig, len(old.Modules)+len(next.Modules))
+	maps.Copy(names, old.Modules)
+	maps.Copy(names, next.Modules)
+	for _, name := range slices.Sorted(maps.Keys(names)) {
+		o, n := old.ModuleConf(name), next.ModuleConf(name)
+		prefix := "modules." + name + "."
+		add(prefix+"enabled", old.ModuleEnabled(name), next.ModuleEnabled(name), true)
+		add(prefix+"sampling_rate", o.SamplingRate, n.SamplingRate, true)
+		add(prefix+"ring_buffer_size", o.RingBufferSize, n.RingBufferSize, false)
+		add(prefix+"drain_timeout", o.Drain(), n.Drain(), false)
+		add(prefix+"min_latency", o.KernelMinLatency(), n.KernelMinLatency(), false)
+		add(prefix+"aggregate_window", o.AggregateWindow, n.AggregateWindow, false)
+		add(prefix+"filters", fmt.Sprintf("%+v", o.Filters), fmt.Sprintf("%+v", n.Filters), true)
+	}
+
+	add("exporters.prometheus.enabled", old.Exporters.Prometheus.Enabled, next.Exporters.Prometheus.Enabled, false)
+	add("exporters.prometheus.addr", old.Exporters.Prometheus.Addr, next.Exporters.Prometheus.Addr, false)
+	add("exporters.prometheus.max_pods_per_metric", old.Exporters.Prometheus.MaxPodsPerMetric, next.Exporters.Prometheus.MaxPodsPerMetric, false)
+	add("exporters.prometheus.stale_pod_ttl", old.Exporters.Prometheus.StalePodTTL, next.Exporters.Prometheus.StalePodTTL, false)
+	add("exporters.prometheus.native_histograms", old.Exporters.Prometheus.NativeHistograms, next.Exporters.Prometheus.NativeHistograms, false)
+	add("exporters.prometheus.dns_domain_mode", old.Exporters.Prometheus.DNSDomainMode, next.Exporters.Prometheus.DNSDomainMode, false)
+	add("exporters.prometheus.dns_domain_levels", old.Exporters.Prometheus.DNSDomainLevels, next.Exporters.Prometheus.DNSDomainLevels, false)
+	add("exporters.prometheus.drop_pod_labels", old.Exporters.Prometheus.DropPodLabels, next.Exporters.Prometheus.DropPodLabels, false)
+	add("exporters.prometheus.relabel", fmt.Sprintf("%+v", old.Exporters.Prometheus.Relabel), fmt.Sprintf("%+v", next.Exporters.Prometheus.Relabel), false)
+	osec, nsec := old.Exporters.Prometheus.MetricsSecurity, next.Exporters.Prometheus.MetricsSecurity
+	add("exporters.prometheus.tls.cert_file", osec.TLS.CertFile, nsec.TLS.CertFile, false)
+	add("exporters.prometheus.tls.key_file", osec.TLS.KeyFile, nsec.TLS.KeyFile, false)
+	add("exporters.prometheus.basic_auth.username", osec.BasicAuth.Username, nsec.BasicAuth.Username, false)
+	if osec.BasicAuth.PasswordHash != nsec.BasicAuth.PasswordHash {
+		changes = append(changes, Change{Path: "exporters.prometheus.basic_auth.password_hash",
+			Old: maskSecret(osec.BasicAuth.PasswordHash), New: maskSecret(nsec.BasicAuth.PasswordHash)})
+	}
+	add("exporters.prometheus.basic_auth.exempt_health", osec.BasicAuth.ExemptHealth, nsec.BasicAuth.ExemptHealth, false)
+	add("exporters.otlp.enabled", old.Exporters.OTLP.Enabled, next.Exporters.OTLP.Enabled, false)
+	add("exporters.otlp.endpoint", old.Exporters.OTLP.Endpoint, next.Exporters.OTLP.Endpoint, false)
+	on, nn := old.Exporters.NATS, next.Exporters.NATS
+	add("exporters.nats.enabled", on.Enabled, nn.Enabled, false)
+	add("exporters.nats.url", on.URL, nn.URL, false)
+	add("exporters.nats.stream", on.Stream, nn.Stream, false)
+	add("exporters.nats.subject", on.Subject, nn.Subject, false)
+	add("exporters.nats.batch_size", on.BatchSize, nn.BatchSize, false)
+	add("exporters.nats.flush_interval", on.FlushInterval, nn.FlushInterval, false)
+	add("exporters.nats.compression", on.Compression, nn.Compression, false)
+	add("exporters.nats.pack_size", on.PackSize, nn.PackSize, false)
+	add("exporters.nats.replicas", on.Replicas, nn.Replicas, false)
+	add("exporters.nats.max_age", on.MaxAge, nn.MaxAge, false)
+	add("exporters.nats.max_bytes", on.MaxBytes, nn.MaxBytes, false)
+	add("exporters.nats.retention", on.Retention, nn.Retention, false)
+	add("exporters.nats.storage", on.Storage, nn.Storage, false)
+	of, nf := old.Exporters.File, next.Exporters.File
+	add("exporters.file.enabled", of.Enabled, nf.Enabled, false)
+	add("exporters.file.path", of.Path, nf.Path, false)
+	add("exporters.file.max_size_mb", of.MaxSizeMB, nf.MaxSizeMB, false)
+	add("exporters.file.max_files", of.MaxFiles, nf.MaxFiles, false)
+	add("exporters.file.sync", of.Sync, nf.Sync, false)
+	add("exporters.file.sync_interval", of.SyncInterval, nf.SyncInterval, false)
+	oa, na := old.Exporters.Alerts, next.Exporters.Alerts
+	add("exporters.alerts.enabled", oa.Enabled, na.Enabled, false)
+	add("exporters.alerts.webhook_url", oa.RedactedWebhookURL(), na.RedactedWebhookURL(), false)
+	add("exporters.alerts.timeout", oa.Timeout, na.Timeout, false)
+	add("exporters.alerts.retries", oa.Retries, na.Retries, false)
+	add("exporters.alerts.cooldown", oa.Cooldown, na.Cooldown, false)
+	add("exporters.alerts.rules", fmt.Sprintf("%+v", oa.Rules), fmt.Sprintf("%+v", na.Rules), false)
+	om, nm := old.Exporters.Alertmanager, next.Exporters.Alertmanager
+	add("exporters.alertmanager.enabled", om.Enabled, nm.Enabled, false)
+	add("exporters.alertmanager.urls", fmt.Sprint(om.URLs), fmt.Sprint(nm.URLs), false)
+	add("exporters.alertmanager.timeout", om.Timeout, nm.Timeout, false)
+	orw, nrw := old.Exporters.RemoteWrite, next.Exporters.RemoteWrite
+	add("exporters.remote_write.enabled", orw.Enabled, nrw.Enabled, false)
+	add("exporters.remote_write.url", orw.RedactedURL(), nrw.RedactedURL(), false)
+	add("exporters.remote_write.interval", orw.Interval, nrw.Interval, false)
+	add("exporters.remote_write.timeout", orw.Timeout, nrw.Timeout, false)
+	add("exporters.remote_write.retries", orw.Retries, nrw.Retries, false)
+	if orw.BearerToken != nrw.BearerToken {
+		changes = append(changes, Change{Path: "exporters.remote_write.bearer_token",
+			Old: maskSecret(orw.BearerToken), New: maskSecret(nrw.BearerToken)})
+	}
+	add("exporters.remote_write.bearer_token_file", orw.BearerTokenFile, nrw.BearerTokenFile, false)
+	add("exporters.remote_write.basic_auth.username", orw.BasicAuth.Username, nrw.BasicAuth.Username, false)
+	if orw.BasicAuth.Password != nrw.BasicAuth.Password {
+		changes = append(changes, Change{Path: "exporters.remote_write.basic_auth.password",
+			Old: maskSecret(orw.BasicAuth.Password), New: maskSecret(nrw.BasicAuth.Password)})
+	}
+	add("exporters.remote_write.basic_auth.password_file", orw.BasicAuth.PasswordFile, nrw.BasicAuth.PasswordFile, false)
+	add("exporters.remote_write.external_labels", fmt.Sprint(orw.ExternalLabels), fmt.Sprint(nrw.ExternalLabels), false)
+	ohp, nhp := old.Exporters.HTTPPush, next.Exporters.HTTPPush
+	add("exporters.http_push.enabled", ohp.Enabled, nhp.Enabled, false)
+	add("exporters.http_push.url", ohp.URL, nhp.URL, false)
+	if ohp.BearerToken != nhp.BearerToken {
+		changes = append(changes, Change{Path: "exporters.http_push.bearer_token",
+			Old: maskSecret(ohp.BearerToken), New: maskSecret(nhp.BearerToken)})
+	}
+	add("exporters.http_push.bearer_token_file", ohp.BearerTokenFile, nhp.BearerTokenFile, false)
+	add("exporters.http_push.batch_size", ohp.BatchSize, nhp.BatchSize, false)
+	add("exporters.http_push.flush_interval", ohp.FlushInterval, nhp.FlushInterval, false)
+	add("exporters.http_push.timeout", ohp.Timeout, nhp.Timeout, false)
+	add("exporters.http_push.compression", ohp.Compression, nhp.Compression, false)
+	add("exporters.http_push.max_buffered", ohp.MaxBuffered, nhp.MaxBuffered, false)
+	add("performance.event_bus_buffer", old.Performance.EventBusBuffer, next.Performance.EventBusBuffer, false)
+	add("performance.worker_pool_size", old.Performance.WorkerPoolSize, next.Performance.WorkerPoolSize, false)
+	add("performance.adaptive_sampling", old.Performance.AdaptiveSampling, next.Performance.AdaptiveSampling, false)
+	or, nr := old.Redaction, next.Redaction
+	add("redaction.labels", fmt.Sprint(or.Labels), fmt.Sprint(nr.Labels), false)
+	if or.Salt != nr.Salt {
+		// The salt is what keeps hashes irreversible; never print it.
+		changes = append(changes, Change{Path: "redaction.salt", Old: maskSecret(or.Salt), New: maskSecret(nr.Salt)})
+	}
+	add("redaction.salt_file", or.SaltFile, nr.SaltFile, false)
+
+	return changes
+}
+
+// maskSecret hides a non-empty secret for display.
+func maskSecret(s string) string {
+	if s == "" {
+		return ""
+	}
+	return "xxxxx"
+}
+
+// Clone returns a deep copy of c.
+func (c *Config) Clone() *Config {
+	out := *c
+	out.Exporters.Prometheus.Relabel = slices.Clone(c.Exporters.Prometheus.Relabel)
+	out.Exporters.Alerts.Rules = slices.Clone(c.Exporters.Alerts.Rules)
+	out.Exporters.Alertmanager.URLs = slices.Clone(c.Exporters.Alertmanager.URLs)
+	out.Exporters.RemoteWrite.ExternalLabels = maps.Clone(c.Exporters.RemoteWrite.ExternalLabels)
+	out.Redaction.Labels = maps.Clone(c.Redaction.Labels)
+	out.Standalone.API.CORSOrigins = slices.Clone(c.Standalone.API.CORSOrigins)
+	out.Standalone.API.Tokens = maps.Clone(c.Standalone.API.Tokens)
+	if c.Standalone.API.TokenNamespaces != nil {
+		out.Standalone.API.TokenNamespaces = make(map[string][]string, len(c.Standalone.API.TokenNamespaces))
+		for name, namespaces := range c.Standalone.API.TokenNamespaces {
+			out.Standalone.API.TokenNamespaces[name] = slices.Clone(namespaces)
+		}
+	}
+	out.Modules = make(map[string]*ModuleConfig, len(c.Modules))
+	for name, mod := range c.Modules {
+		if mod != nil {
+			m := *mod
+			m.Filters.ExcludeComm = slices.Clone(mod.Filters.ExcludeComm)
+			m.Filters.AllowPorts = slices.Clone(mod.Filters.AllowPorts)
+			m.Filters.DenyPorts = slices.Clone(mod.Filters.DenyPorts)
+			out.Modules[name] = &m
+		}
+	}
+	return &out
+}
@@ -17,27 +17,64 @@ var IOLatencyBuckets = []float64{
 }
 
 // ─── Drop Reasons ──────────────────────────────────────────────────
-// Kernel SKB drop reason codes mapped to human-readable strings.
-
-// DropReasons maps kernel drop reason codes to descriptive names.
-var DropReasons = map[uint32]string{
-	2:  "NOT_SPECIFIED",
-	3:  "NO_SOCKET",
-	4:  "PKT_TOO_SMALL",
-	5:  "TCP_CSUM",
-	6:  "SOCKET_FILTER",
-	7:  "UDP_CSUM",
-	16: "NETFILTER_DROP",
-	17: "OTHERHOST",
-	27: "QUEUE_PURGE",
+// DropReasons is generated into drop_reasons.go from bpf/headers/vmlinux.h.
+
+//go:generate go run ./internal/gendropreasons -o drop_reasons.go ../../bpf/headers/vmlinux.h
+
+// ─── TCP States ────────────────────────────────────────────────────
+// TCPStates maps the kernel's TCP_* socket states (include/net/tcp_states.h)
+// to names.
+var TCPStates = map[uint32]string{
+	1:  "ESTABLISHED",
+	2:  "SYN_SENT",
+	3:  "SYN_RECV",
+	4:  "FIN_WAIT1",
+	5:  "FIN_WAIT2",
+	6:  "TIME_WAIT",
+	7:  "CLOSE",
+	8:  "CLOSE_WAIT",
+	9:  "LAST_ACK",
+	10: "LISTEN",
+	11: "CLOSING",
+	12: "NEW_SYN_RECV",
+	13: "BOUND_INACTIVE",
+}
+
+// ─── Signals ───────────────────────────────────────────────────────
+// TracedSignals are the signals the signals module reports, by number.
+// They are the ones that usually end a process; the BPF program drops
+// every other signal before it reaches the ring buffer.
+var TracedSignals = map[uint32]string{
+	6:  "SIGABRT",
+	9:  "SIGKILL",
+	11: "SIGSEGV",
+	15: "SIGTERM",
 }
 
 // ─── Common Prometheus Label Sets ──────────────────────────────────
 // Pre-defined label slices to avoid repeated allocations.
 
 var LabelsNamespacePodNode = []string{LabelNamespace, LabelPod, LabelNode}
-var LabelsNamespacePodDomainNode = []string{LabelNamespace, LabelPod, LabelDomain, LabelNode}
-var LabelsNamespacePodOpNode = []string{LabelNamespace, LabelPod, LabelOp, LabelNode}
-var LabelsReasonNode = []string{LabelReason, LabelNode}
+var LabelsNamespacePodDomainQTypeNode = []string{LabelNamespace, LabelPod, LabelDomain, LabelQType, LabelNode}
+var LabelsNamespacePodOpFSNode = []string{LabelNamespace, LabelPod, LabelOp, LabelFS, LabelNode}
+var LabelsNamespacePodErrorNode = []string{LabelNamespace, LabelPod, LabelError, LabelNode}
+var LabelsNamespacePodDirectionStateNode = []string{LabelNamespace, LabelPod, LabelDirection, LabelState, LabelNode}
+var LabelsNamespacePodSignalNode = []string{LabelNamespace, LabelPod, LabelSignal, LabelNode}
+var LabelsReasonProtocolNode = []string{LabelReason, LabelProtocol, LabelNode}
+var LabelsNamespacePodReasonProtocolNode = []string{LabelNamespace, LabelPod, LabelReason, LabelProtocol, LabelNode}
 var LabelsModule = []string{LabelModule}
+var LabelsReason = []string{LabelReason}
+var LabelsModuleReason = []string{LabelModule, LabelReason}
 var LabelsSubscriber = []string{LabelSubscriber}
+var LabelsMetric = []string{LabelMetric}
+var LabelsModuleProg = []string{LabelModule, LabelProg}
+var LabelsModuleMap = []string{LabelModule, LabelMap}
+var LabelsFeature = []string{LabelFeature}
+var LabelsModuleMode = []string{LabelModule, LabelMode}
+var LabelsModuleState = []string{LabelModule, LabelState}
+var LabelsRule = []string{LabelRule}
+var LabelsResult = []string{LabelResult}
+var LabelsTypeKey = []string{LabelType, LabelKey}
+var LabelsBuildInfo = []string{LabelVersion, LabelCommit, LabelGoVersion, LabelKernel}
+var LabelsMethodRoute = []string{LabelMethod, LabelRoute}
+var LabelsMethodRouteStatus = []string{LabelMethod, LabelRoute, LabelStatus}
@@ -0,0 +1,97 @@
+// Code generated by gendropreasons from ../../bpf/headers/vmlinux.h; DO NOT EDIT.
+
+package constants
+
+// DropReasons maps enum skb_drop_reason values to names without the
+// SKB_DROP_REASON_ prefix, as of the kernel bpf/headers/vmlinux.h was
+// generated from. Values shift between kernel versions, so
+// bpfutil.DropReasonString prefers the running kernel's BTF.
+var DropReasons = map[uint32]string{
+	0:  "NOT_DROPPED_YET",
+	1:  "CONSUMED",
+	2:  "NOT_SPECIFIED",
+	3:  "NO_SOCKET",
+	4:  "PKT_TOO_SMALL",
+	5:  "TCP_CSUM",
+	6:  "SOCKET_FILTER",
+	7:  "UDP_CSUM",
+	8:  "NETFILTER_DROP",
+	9:  "OTHERHOST",
+	10: "IP_CSUM",
+	11: "IP_INHDR",
+	12: "IP_RPFILTER",
+	13: "UNICAST_IN_L2_MULTICAST",
+	14: "XFRM_POLICY",
+	15: "IP_NOPROTO",
+	16: "SOCKET_RCVBUFF",
+	17: "PROTO_MEM",
+	18: "TCP_AUTH_HDR",
+	19: "TCP_MD5NOTFOUND",
+	20: "TCP_MD5UNEXPECTED",
+	21: "TCP_MD5FAILURE",
+	22: "TCP_AONOTFOUND",
+	23: "TCP_AOUNEXPECTED",
+	24: "TCP_AOKEYNOTFOUND",
+	25: "TCP_AOFAILURE",
+	26: "SOCKET_BACKLOG",
+	27: "TCP_FLAGS",
+	28: "TCP_ZEROWINDOW",
+	29: "TCP_OLD_DATA",
+	30: "TCP_OVERWINDOW",
+	31: "TCP_OFOMERGE",
+	32: "TCP_RFC7323_PAWS",
+	33: "TCP_OLD_SEQUENCE",
+	34: "TCP_INVALID_SEQUENCE",
+	35: "TCP_RESET",
+	36: "TCP_INVALID_SYN",
+	37: "TCP_CLOSE",
+	38: "TCP_FASTOPEN",
+	39: "TCP_OLD_ACK",
+	40: "TCP_TOO_OLD_ACK",
+	41: "TCP_ACK_UNSENT_DATA",
+	42: "TCP_OFO_QUEUE_PRUNE",
+	43: "TCP_OFO_DROP",
+	44: "IP_OUTNOROUTES",
+	45: "BPF_CGROUP_EGRESS",
+	46: "IPV6DISABLED",
+	47: "NEIGH_CREATEFAIL",
+	48: "NEIGH_FAILED",
+	49: "NEIGH_QUEUEFULL",
+	50: "NEIGH_DEAD",
+	51: "TC_EGRESS",
+	52: "QDISC_DROP",
+	53: "CPU_BACKLOG",
+	54: "XDP",
+	55: "TC_INGRESS",
+	56: "UNHANDLED_PROTO",
+	57: "SKB_CSUM",
+	58: "SKB_GSO_SEG",
+	59: "SKB_UCOPY_FAULT",
+	60: "DEV_HDR",
+	61: "DEV_READY",
+	62: "FULL_RING",
+	63: "NOMEM",
+	64: "HDR_TRUNC",
+	65: "TAP_FILTER",
+	66: "TAP_TXFILTER",
+	67: "ICMP_CSUM",
+	68: "INVALID_PROTO",
+	69: "IP_INADDRERRORS",
+	70: "IP_INNOROUTES",
+	71: "PKT_TOO_BIG",
+	72: "DUP_FRAG",
+	73: "FRAG_REASM_TIMEOUT",
+	74: "FRAG_TOO_FAR",
+	75: "TCP_MINTTL",
+	76: "IPV6_BAD_EXTHDR",
+	77: "IPV6_NDISC_FRAG",
+	78: "IPV6_NDISC_HOP_LIMIT",
+	79: "IPV6_NDISC_BAD_CODE",
+	80: "IPV6_NDISC_BAD_OPTIONS",
+	81: "IPV6_NDISC_NS_OTHERHOST",
+	82: "QUEUE_PURGE",
+	83: "TC_COOKIE_ERROR",
+	84: "PACKET_SOCK_ERROR",
+	85: "TC_CHAIN_NOTFOUND",
+	86: "TC_RECLASSIFY_LOOP",
+}
@@ -1,20 +1,50 @@
 // Package consumer implements the NATS→ClickHouse event pipeline.
 // Pull-based batching: consumes from NATS JetStream, accumulates events,
 // flushes to ClickHouse in optimized batches (time-or-size triggered).
+//
+// Delivery semantics are at-least-once: a message is acked only after the
+// batch containing it has been written to ClickHouse, and NAK'd (with delay)
+// when the write fails so JetStream redelivers it. A crash between a
+// successful insert and the acks can therefore insert the same event twice.
+// Each event carries an ID assigned by the agent, stored in the event_id
+// column; the consumer remembers the last DedupSize IDs it accepted and
+// acks redeliveries of them without inserting again. Duplicates that
+// outlive that window, such as across a consumer restart, remain as rows
+// with the same event_id, which raw queries count once.
+//
+// Messages that can never succeed are dead-lettered: the raw payload is
+// published to the DLQ subject and the original is acked. Malformed
+// payloads are dead-lettered on first delivery. Events in a wire version or
+// encoding this build does not know, which a newer consumer may handle, and
+// rows ClickHouse rejects as invalid are retried up to MaxDeliveries times
+// first.
+//
+// With Live enabled, each newly accepted event is also published to the
+// Redis live channel for the API's WebSocket clients. That feed is best
+// effort: it is filtered, rate limited and dropped rather than queued when
+// Redis falls behind, and a NAK'd batch may publish its events again.
+//
+// With SpillDir set, failed batches spill to a bounded on-disk queue while
+// ClickHouse is unavailable (and are acked once durable); a background
+// drainer replays them in order when inserts succeed again. Without a
+// spill, or when it is full, messages are NAK'd back to JetStream.
 package consumer
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"go.uber.org/zap"
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
 )
 
 // Config holds consumer settings.
@@ -26,6 +56,18 @@ type Config struct {
 	BatchSize     int           `yaml:"batch_size"`
 	FlushInterval time.Duration `yaml:"flush_interval"`
 	Workers       int           `yaml:"workers"`
+	NakDelay      time.Duration `yaml:"nak_delay"`
+	MaxDeliveries int           `yaml:"max_deliveries"`
+	DLQStream     string        `yaml:"dlq_stream"`
+	DLQSubject    string        `yaml:"dlq_subject"`
+	SpillDir      string        `yaml:"spill_dir"` // empty (the default) disables spilling
+	SpillMaxBytes int64         `yaml:"spill_max_bytes"`
+	DedupSize     int           `yaml:"dedup_size"` // 0 disables deduplication
+	Live          LiveConfig    `yaml:"live"`
+
+	// LagThreshold is the lag past which the consumer logs a warning and
+	// its /healthz answers 503; 0 disables both. See Lag.
+	LagThreshold time.Duration `yaml:"lag_threshold"`
 }
 
 // DefaultConfig returns lean defaults.
@@ -38,46 +80,118 @@ func DefaultConfig() Config {
 		BatchSize:     constants.ClickHouseBatchSize,
 		FlushInterval: constants.ClickHouseFlushInterval,
 		Workers:       constants.DefaultWorkerPoolSize,
+		NakDelay:      constants.ConsumerNakDelay,
+		MaxDeliveries: constants.ConsumerMaxDeliveries,
+		DLQStream:     constants.NATSDLQStream,
+		DLQSubject:    constants.NATSDLQSubject,
+		SpillMaxBytes: constants.ConsumerSpillMaxBytes,
+		DedupSize:     constants.ConsumerDedupSize,
+		Live:          LiveConfig{RateLimit: constants.ConsumerLiveRateLimit},
+		LagThreshold:  constants.ConsumerLagThreshold,
 	}
 }
 
-// wireEvent matches the NATS exporter wire format.
-type wireEvent struct {
-	Type      string             `json:"type"`
-	Timestamp int64              `json:"ts"`
-	PID       uint32             `json:"pid"`
-	UID       uint32             `json:"uid"`
-	Comm      string             `json:"comm"`
-	Node      string             `json:"node"`
-	Namespace string             `json:"ns"`
-	Pod       string             `json:"pod"`
-	Labels    map[string]string  `json:"l,omitempty"`
-	Numerics  map[string]float64 `json:"n,omitempty"`
+// BatchInserter is the storage dependency of the consumer.
+// Every storage.Store implements it; tests substitute a stub.
+type BatchInserter interface {
+	InsertBatch(ctx context.Context, rows []storage.EventRow) error
+}
+
+var (
+	messagesConsumed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: constants.MetricConsumerMessages,
+		Help: "JetStream messages received, including redeliveries.",
+	})
+	decodeFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricConsumerDecodeFailed,
+		Help: "Messages that failed to decode, by dead-letter reason.",
+	}, []string{constants.LabelReason})
+	flushLatency = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    constants.MetricConsumerFlushLatency,
+		Help:    "Time taken by each batch insert to ClickHouse, successful or not.",
+		Buckets: constants.IOLatencyBuckets,
+	})
+	rowsInserted = promauto.NewCounter(prometheus.CounterOpts{
+		Name: constants.MetricConsumerRowsInserted,
+		Help: "Rows written to ClickHouse, including spill replays.",
+	})
+	insertErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricConsumerInsertErrors,
+		Help: "Failed ClickHouse inserts: data (rows rejected) or transient.",
+	}, []string{constants.LabelReason})
+)
+
+// countInsert records the outcome of inserting rows.
+func countInsert(rows int, err error) {
+	switch {
+	case err == nil:
+		rowsInserted.Add(float64(rows))
+	case storage.IsDataError(err):
+		insertErrors.WithLabelValues(constants.InsertErrorData).Inc()
+	default:
+		insertErrors.WithLabelValues(constants.InsertErrorTransient).Inc()
+	}
 }
 
 // Consumer reads from NATS and batch-inserts into ClickHouse.
 type Consumer struct {
 	cfg    Config
-	ch     *storage.ClickHouse
+	ch     BatchInserter
+	dlq    DeadLetterPublisher
+	spill  *spillQueue // nil when spilling is disabled
+	seen   *recentIDs  // nil when deduplication is disabled
+	live   *liveFeed   // nil unless EnableLive was called
 	logger *zap.Logger
 
+	// batch and msgs are index-aligned: msgs[i] is the JetStream
+	// message that produced batch[i], acked only after a successful flush.
+	// The rows of a packed message are adjacent and share it.
 	mu    sync.Mutex
 	batch []storage.EventRow
+	msgs  []jetstream.Msg
+
+	streamWait   time.Duration // how often waitStream looks for the stream
+	infoInterval time.Duration // how often watchLag reads the consumer info
+	lagMu        sync.Mutex
+	lag          *Lag // nil until the first report
 }
 
 // New creates a consumer instance.
-func New(cfg Config, ch *storage.ClickHouse, logger *zap.Logger) *Consumer {
+func New(cfg Config, ch BatchInserter, logger *zap.Logger) *Consumer {
 	return &Consumer{
 		cfg:    cfg,
 		ch:     ch,
 		logger: logger,
+		seen:   newRecentIDs(cfg.DedupSize),
 		batch:  make([]storage.EventRow, 0, cfg.BatchSize),
+		msgs:   make([]jetstream.Msg, 0, cfg.BatchSize),
+
+		streamWait:   constants.ConsumerStreamWait,
+		infoInterval: constants.ConsumerInfoInterval,
 	}
 }
 
+// EnableLive publishes every newly accepted event that passes cfg.Live's
+// filters to the Redis live channel through pub. Call before Run.
+func (c *Consumer) EnableLive(pub LivePublisher) {
+	c.live = newLiveFeed(c.cfg.Live, pub, c.logger)
+}
+
 // Run starts consuming from NATS JetStream and flushing to ClickHouse.
 // Blocks until ctx is cancelled.
 func (c *Consumer) Run(ctx context.Context) error {
+	if c.cfg.SpillDir != "" {
+		q, err := newSpillQueue(c.cfg.SpillDir, c.cfg.SpillMaxBytes, c.logger)
+		if err != nil {
+			return err
+		}
+		c.spill = q
+		go c.drainer(ctx)
+	}
+	if c.live != nil {
+		go c.live.run(ctx)
+	}
+
 	nc, err := nats.Connect(c.cfg.NATSURL,
 		nats.MaxReconnects(-1),
 		nats.ReconnectWait(time.Second),
@@ -92,8 +206,24 @@ func (c *Consumer) Run(ctx context.Context) error {
 		return err
 	}
 
+	// Dead-letter stream — a separate stream so DLQ payloads never
+	// match the main consumer's filter.
+	if _, err := js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:     c.cfg.DLQStream,
+		Subjects: []string{c.cfg.DLQSubject},
+		Storage:  jetstream.FileStorage,
+	}); err != nil {
+		return err
+	}
+	c.dlq = js
+
+	stream, err := c.waitStream(ctx, js)
+	if err != nil {
+		return err
+	}
+
 	// Create durable consumer
-	cons, err := js.CreateOrUpdateConsumer(ctx, c.cfg.Stream, jetstream.ConsumerConfig{
+	cons, err := stream.CreateOrUpdateConsumer(ctx, jetstream.ConsumerConfig{
 		Durable:       c.cfg.ConsumerName,
 		FilterSubject: c.cfg.Subject,
 		AckPolicy:     jetstream.AckExplicitPolicy,
@@ -103,23 +233,70 @@ func (c *Consumer) Run(ctx context.Context) error {
 		return err
 	}
 
-	// Start flush ticker
+	// Start flush ticker and lag reporting
 	go c.flusher(ctx)
+	go c.watchLag(ctx, cons, stream)
 
 	c.logger.Info("Consumer started",
 		zap.String("stream", c.cfg.Stream),
 		zap.Int("batch_size", c.cfg.BatchSize))
 
 	// Consume messages
-	_, err = cons.Consume(func(msg jetstream.Msg) {
-		var w wireEvent
-		if err := json.Unmarshal(msg.Data(), &w); err != nil {
+	cc, err := cons.Consume(func(msg jetstream.Msg) {
+		c.handle(ctx, msg)
+	})
+	if err != nil {
+		return err
+	}
+
+	<-ctx.Done()
+	cc.Stop()
+
+	// ctx is already cancelled — give the final flush its own deadline
+	// so the tail of the batch is written (and acked) rather than NAK'd.
+	flushCtx, cancel := context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+	defer cancel()
+	c.flush(flushCtx)
+	return nil
+}
+
+// handle decodes one message and appends its events to the pending batch.
+// A message holds one event, or a compressed pack of them when it has a
+// content encoding header. The message is not acked here — see flush —
+// unless all its events are already pending or written, in which case it
+// is acked and dropped.
+func (c *Consumer) handle(ctx context.Context, msg jetstream.Msg) {
+	messagesConsumed.Inc()
+	events, err := unpack(msg)
+	if err != nil {
+		c.logger.Warn("Failed to decode message", zap.Error(err))
+		decodeFailures.WithLabelValues(rejectReason(err)).Inc()
+		c.reject(ctx, msg, rejectReason(err), err)
+		return
+	}
+	ws := make([]wire.Event, len(events))
+	for i, data := range events {
+		if ws[i], err = wire.Decode(data); err != nil {
 			c.logger.Warn("Failed to decode event", zap.Error(err))
-			msg.Nak()
+			decodeFailures.WithLabelValues(rejectReason(err)).Inc()
+			c.reject(ctx, msg, rejectReason(err), err)
 			return
 		}
+		if ws[i].ID == 0 {
+			ws[i].ID = payloadID(data)
+		}
+	}
 
+	rows := make([]storage.EventRow, 0, len(ws))
+	for _, w := range ws {
+		if !c.seen.add(w.ID) {
+			duplicates.Inc()
+			continue
+		}
+		c.checkSchema(w)
+		c.live.offer(w)
 		row := storage.EventRow{
+			EventID:   w.ID,
 			Timestamp: time.UnixMilli(w.Timestamp),
 			Type:      w.Type,
 			PID:       w.PID,
@@ -131,44 +308,194 @@ func (c *Consumer) Run(ctx context.Context) error {
 			Labels:    w.Labels,
 			Numerics:  w.Numerics,
 		}
+		row.LatencySec, row.Bytes, row.Value = storage.TypedNumerics(w.Type, w.Numerics)
+		rows = append(rows, row)
+	}
+	if len(rows) == 0 {
+		if err := msg.Ack(); err != nil {
+			c.logger.Warn("Failed to ack duplicate message", zap.Error(err))
+		}
+		return
+	}
 
-		c.mu.Lock()
-		c.batch = append(c.batch, row)
-		full := len(c.batch) >= c.cfg.BatchSize
-		c.mu.Unlock()
+	c.mu.Lock()
+	c.batch = append(c.batch, rows...)
+	for range rows {
+		c.msgs = append(c.msgs, msg)
+	}
+	full := len(c.batch) >= c.cfg.BatchSize
+	c.mu.Unlock()
 
-		msg.Ack()
+	if full {
+		c.flush(ctx)
+	}
+}
 
-		if full {
-			c.flush(ctx)
-		}
-	})
-	if err != nil {
-		return err
+// unpack returns the encoded events in msg.
+func unpack(msg jetstream.Msg) ([][]byte, error) {
+	enc := msg.Headers().Get(constants.NATSHeaderContentEncoding)
+	if enc == "" {
+		return [][]byte{msg.Data()}, nil
 	}
+	return wire.DecodePack(enc, msg.Data())
+}
 
-	<-ctx.Done()
-	c.flush(ctx)
-	return nil
+// rejectReason is the dead-letter reason for a message that failed to
+// decode with err.
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, wire.ErrUnsupportedVersion):
+		return constants.DLQReasonVersion
+	case errors.Is(err, wire.ErrUnsupportedEncoding):
+		return constants.DLQReasonEncoding
+	default:
+		return constants.DLQReasonDecode
+	}
 }
 
-// flush writes accumulated rows to ClickHouse.
+// flush writes accumulated rows to ClickHouse, then acks their messages.
+// On a transient insert failure every message in the batch is NAK'd with a
+// delay so JetStream redelivers it once ClickHouse has had time to recover.
+// On a data error the batch is retried row by row to isolate the bad rows.
 func (c *Consumer) flush(ctx context.Context) {
 	c.mu.Lock()
 	if len(c.batch) == 0 {
 		c.mu.Unlock()
 		return
 	}
-	batch := c.batch
+	batch, msgs := c.batch, c.msgs
 	c.batch = make([]storage.EventRow, 0, c.cfg.BatchSize)
+	c.msgs = make([]jetstream.Msg, 0, c.cfg.BatchSize)
 	c.mu.Unlock()
 
-	if err := c.ch.InsertBatch(ctx, batch); err != nil {
-		c.logger.Error("ClickHouse batch insert failed",
+	start := time.Now()
+	err := c.ch.InsertBatch(ctx, batch)
+	flushLatency.Observe(time.Since(start).Seconds())
+	countInsert(len(batch), err)
+	switch {
+	case err == nil:
+		c.ackAll(msgs)
+		c.logger.Info("Flushed to ClickHouse", zap.Int("rows", len(batch)))
+	case storage.IsDataError(err):
+		c.logger.Warn("ClickHouse rejected batch — isolating bad rows",
 			zap.Error(err), zap.Int("rows", len(batch)))
-		return
+		c.isolate(ctx, batch, msgs)
+	default:
+		if c.spill != nil {
+			serr := c.spill.write(batch)
+			if serr == nil {
+				c.logger.Warn("ClickHouse batch insert failed — spilled to disk",
+					zap.Error(err), zap.Int("rows", len(batch)))
+				c.ackAll(msgs)
+				return
+			}
+			c.logger.Error("Spill failed", zap.Error(serr))
+		}
+		c.logger.Error("ClickHouse batch insert failed — NAKing for redelivery",
+			zap.Error(err), zap.Int("rows", len(batch)))
+		c.nakAll(batch, msgs)
+	}
+}
+
+// isolate inserts rows one at a time so only the rows ClickHouse rejects
+// are rejected. A message is acked after its last row is written unless one
+// of its rows was rejected; a rejected pack that is redelivered writes only
+// the rows that were not. A transient failure part-way NAKs the remainder.
+func (c *Consumer) isolate(ctx context.Context, batch []storage.EventRow, msgs []jetstream.Msg) {
+	var rejected jetstream.Msg
+	for i := range batch {
+		err := c.ch.InsertBatch(ctx, batch[i:i+1])
+		countInsert(1, err)
+		switch {
+		case err == nil:
+			if msgs[i] != rejected && (i+1 == len(msgs) || msgs[i+1] != msgs[i]) {
+				c.ackAll(msgs[i : i+1])
+			}
+		case storage.IsDataError(err):
+			c.seen.forget(batch[i].EventID)
+			if msgs[i] != rejected {
+				rejected = msgs[i]
+				c.reject(ctx, msgs[i], constants.DLQReasonInsert, err)
+			}
+		default:
+			c.logger.Error("ClickHouse insert failed during isolation — NAKing remainder",
+				zap.Error(err), zap.Int("rows", len(batch)-i))
+			c.nakAll(batch[i:], msgs[i:])
+			return
+		}
+	}
+}
+
+// ackAll acks msgs, once for the adjacent rows of a pack.
+func (c *Consumer) ackAll(msgs []jetstream.Msg) {
+	for i, msg := range msgs {
+		if i > 0 && msgs[i-1] == msg {
+			continue
+		}
+		if err := msg.Ack(); err != nil {
+			c.logger.Warn("Failed to ack message", zap.Error(err))
+		}
+	}
+}
+
+// nakAll NAKs msgs, once for the adjacent rows of a pack, and forgets the
+// IDs of their rows, so the redeliveries are not mistaken for duplicates.
+func (c *Consumer) nakAll(batch []storage.EventRow, msgs []jetstream.Msg) {
+	for i, msg := range msgs {
+		c.seen.forget(batch[i].EventID)
+		if i > 0 && msgs[i-1] == msg {
+			continue
+		}
+		msg.NakWithDelay(c.cfg.NakDelay)
+	}
+}
+
+// drainer periodically replays spilled batches into ClickHouse.
+func (c *Consumer) drainer(ctx context.Context) {
+	ticker := time.NewTicker(constants.ConsumerSpillDrainInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := c.spill.drain(ctx, c.ch)
+			if n > 0 {
+				c.logger.Info("Replayed spilled batches", zap.Int("batches", n))
+			}
+			if err != nil && ctx.Err() == nil {
+				c.logger.Debug("Spill replay paused", zap.Error(err))
+			}
+			c.spill.updateGauges()
+		}
+	}
+}
+
+// streamLookup is the part of jetstream.JetStream waitStream uses.
+type streamLookup interface {
+	Stream(ctx context.Context, name string) (jetstream.Stream, error)
+}
+
+// waitStream returns the event stream, waiting for it to exist. Agents own
+// the stream and create it when they start; the consumer only reads it, so
+// on a fresh install it may start first.
+func (c *Consumer) waitStream(ctx context.Context, js streamLookup) (jetstream.Stream, error) {
+	logged := false
+	for {
+		stream, err := js.Stream(ctx, c.cfg.Stream)
+		if !errors.Is(err, jetstream.ErrStreamNotFound) {
+			return stream, err
+		}
+		if !logged {
+			c.logger.Info("Waiting for an agent to create the stream", zap.String("stream", c.cfg.Stream))
+			logged = true
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.streamWait):
+		}
 	}
-	c.logger.Info("Flushed to ClickHouse", zap.Int("rows", len(batch)))
 }
 
 func (c *Consumer) flusher(ctx context.Context) {
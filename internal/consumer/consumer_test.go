@@ -0,0 +1,559 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// fakeMsg is an in-memory jetstream.Msg recording ack/nak calls.
+type fakeMsg struct {
+	data      []byte
+	header    nats.Header
+	delivered uint64
+
+	mu    sync.Mutex
+	acks  int
+	naks  int
+	terms int
+	delay time.Duration
+}
+
+func (m *fakeMsg) Metadata() (*jetstream.MsgMetadata, error) {
+	return &jetstream.MsgMetadata{NumDelivered: m.delivered}, nil
+}
+func (m *fakeMsg) Data() []byte                    { return m.data }
+func (m *fakeMsg) Headers() nats.Header            { return m.header }
+func (m *fakeMsg) Subject() string                 { return "kubepulse.events" }
+func (m *fakeMsg) Reply() string                   { return "" }
+func (m *fakeMsg) DoubleAck(context.Context) error { return m.Ack() }
+func (m *fakeMsg) InProgress() error               { return nil }
+func (m *fakeMsg) Term() error                     { return m.TermWithReason("") }
+
+func (m *fakeMsg) Ack() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.acks++
+	return nil
+}
+
+func (m *fakeMsg) TermWithReason(string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.terms++
+	return nil
+}
+
+func (m *fakeMsg) Nak() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.naks++
+	return nil
+}
+
+func (m *fakeMsg) NakWithDelay(delay time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.naks++
+	m.delay = delay
+	return nil
+}
+
+func (m *fakeMsg) counts() (acks, naks int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.acks, m.naks
+}
+
+// stubStore fails the first failures inserts, then persists rows.
+// Any batch containing a row whose PID is in badPIDs fails with a data error.
+type stubStore struct {
+	mu       sync.Mutex
+	failures int
+	badPIDs  map[uint32]bool
+	calls    int
+	rows     []storage.EventRow
+}
+
+func (s *stubStore) InsertBatch(_ context.Context, rows []storage.EventRow) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls++
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("clickhouse unavailable")
+	}
+	for _, r := range rows {
+		if s.badPIDs[r.PID] {
+			return fmt.Errorf("append row: %w: bad value", storage.ErrInvalidRow)
+		}
+	}
+	s.rows = append(s.rows, rows...)
+	return nil
+}
+
+// recordingDLQ captures dead-lettered messages.
+type recordingDLQ struct {
+	mu   sync.Mutex
+	msgs []*nats.Msg
+}
+
+func (d *recordingDLQ) PublishMsg(_ context.Context, msg *nats.Msg, _ ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.msgs = append(d.msgs, msg)
+	return &jetstream.PubAck{}, nil
+}
+
+func newMsg(t *testing.T, pid uint32) *fakeMsg {
+	t.Helper()
+	data, err := wire.Encode(wire.Event{Type: "tcp", Timestamp: time.Now().UnixMilli(), PID: pid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeMsg{data: data, delivered: 1}
+}
+
+func testConsumer(store BatchInserter, batchSize int) *Consumer {
+	cfg := DefaultConfig()
+	cfg.BatchSize = batchSize
+	cfg.NakDelay = 2 * time.Second
+	cfg.MaxDeliveries = 3
+	c := New(cfg, store, zap.NewNop())
+	c.dlq = &recordingDLQ{}
+	return c
+}
+
+func TestHandle_DoesNotAckBeforeFlush(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+
+	msg := newMsg(t, 1)
+	c.handle(context.Background(), msg)
+
+	if acks, naks := msg.counts(); acks != 0 || naks != 0 {
+		t.Fatalf("before flush: acks=%d naks=%d, want 0/0", acks, naks)
+	}
+
+	c.flush(context.Background())
+	if acks, _ := msg.counts(); acks != 1 {
+		t.Errorf("after flush: acks=%d, want 1", acks)
+	}
+	if len(store.rows) != 1 {
+		t.Errorf("persisted rows = %d, want 1", len(store.rows))
+	}
+}
+
+func TestFlush_FailureNaksThenRedeliveryPersists(t *testing.T) {
+	store := &stubStore{failures: 1}
+	c := testConsumer(store, 3)
+
+	msgs := []*fakeMsg{newMsg(t, 1), newMsg(t, 2), newMsg(t, 3)}
+
+	// First delivery: batch fills, flush fails → every message NAK'd.
+	for _, m := range msgs {
+		c.handle(context.Background(), m)
+	}
+	for i, m := range msgs {
+		acks, naks := m.counts()
+		if acks != 0 || naks != 1 {
+			t.Fatalf("msg %d after failed flush: acks=%d naks=%d, want 0/1", i, acks, naks)
+		}
+		if m.delay != 2*time.Second {
+			t.Errorf("msg %d nak delay = %v, want 2s", i, m.delay)
+		}
+	}
+	if len(store.rows) != 0 {
+		t.Fatalf("rows persisted after failed flush = %d, want 0", len(store.rows))
+	}
+
+	// JetStream redelivers the same messages; the second flush succeeds.
+	for _, m := range msgs {
+		m.delivered++
+		c.handle(context.Background(), m)
+	}
+	for i, m := range msgs {
+		if acks, _ := m.counts(); acks != 1 {
+			t.Errorf("msg %d after redelivery: acks=%d, want 1", i, acks)
+		}
+	}
+	if len(store.rows) != 3 {
+		t.Errorf("rows persisted after redelivery = %d, want 3", len(store.rows))
+	}
+	if store.calls != 2 {
+		t.Errorf("InsertBatch calls = %d, want 2", store.calls)
+	}
+}
+
+func TestHandle_UndecodableWithoutDLQIsTerminated(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+	c.dlq = nil
+
+	msg := &fakeMsg{data: []byte("{not json"), delivered: 1}
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+
+	if acks, naks := msg.counts(); acks != 0 || naks != 0 || msg.terms != 1 {
+		t.Errorf("acks=%d naks=%d terms=%d, want 0/0/1", acks, naks, msg.terms)
+	}
+	if store.calls != 0 {
+		t.Errorf("InsertBatch calls = %d, want 0 for empty batch", store.calls)
+	}
+}
+
+func TestReject_CorruptPayloadDeadLetteredOnce(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+	dlq := c.dlq.(*recordingDLQ)
+	before := testutil.ToFloat64(deadLettered.WithLabelValues(constants.DLQReasonDecode))
+
+	msg := &fakeMsg{data: []byte("{not json"), delivered: 1}
+	c.handle(context.Background(), msg)
+
+	if len(dlq.msgs) != 1 {
+		t.Fatalf("DLQ messages = %d, want 1", len(dlq.msgs))
+	}
+	got := dlq.msgs[0]
+	if got.Subject != constants.NATSDLQSubject || string(got.Data) != "{not json" {
+		t.Errorf("DLQ msg = %s %q, want raw payload on %s", got.Subject, got.Data, constants.NATSDLQSubject)
+	}
+	if r := got.Header.Get(constants.NATSHeaderDLQReason); r != constants.DLQReasonDecode {
+		t.Errorf("reason header = %q, want %q", r, constants.DLQReasonDecode)
+	}
+	if acks, naks := msg.counts(); acks != 1 || naks != 0 {
+		t.Errorf("acks=%d naks=%d, want 1/0 (dead-lettered on first delivery)", acks, naks)
+	}
+	after := testutil.ToFloat64(deadLettered.WithLabelValues(constants.DLQReasonDecode))
+	if after-before != 1 {
+		t.Errorf("dead-lettered counter delta = %v, want 1", after-before)
+	}
+}
+
+func TestReject_UnknownVersionDeadLettered(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+	dlq := c.dlq.(*recordingDLQ)
+	before := testutil.ToFloat64(deadLettered.WithLabelValues(constants.DLQReasonVersion))
+
+	msg := &fakeMsg{data: []byte(`{"v":99,"type":"tcp","ts":1,"pid":1,"extra":{"new":"shape"}}`)}
+	for attempt := 1; attempt <= c.cfg.MaxDeliveries; attempt++ {
+		msg.delivered = uint64(attempt)
+		c.handle(context.Background(), msg)
+	}
+	c.flush(context.Background())
+
+	if len(dlq.msgs) != 1 || dlq.msgs[0].Header.Get(constants.NATSHeaderDLQReason) != constants.DLQReasonVersion {
+		t.Fatalf("DLQ = %v, want one version-reason message", dlq.msgs)
+	}
+	if store.calls != 0 {
+		t.Errorf("InsertBatch calls = %d, want 0", store.calls)
+	}
+	after := testutil.ToFloat64(deadLettered.WithLabelValues(constants.DLQReasonVersion))
+	if after-before != 1 {
+		t.Errorf("dead-lettered counter delta = %v, want 1", after-before)
+	}
+}
+
+func TestFlush_DataErrorIsolatesBadRow(t *testing.T) {
+	store := &stubStore{badPIDs: map[uint32]bool{2: true}}
+	c := testConsumer(store, 10)
+	dlq := c.dlq.(*recordingDLQ)
+
+	msgs := []*fakeMsg{newMsg(t, 1), newMsg(t, 2), newMsg(t, 3)}
+	msgs[1].delivered = uint64(c.cfg.MaxDeliveries)
+	for _, m := range msgs {
+		c.handle(context.Background(), m)
+	}
+	c.flush(context.Background())
+
+	for _, i := range []int{0, 2} {
+		if acks, naks := msgs[i].counts(); acks != 1 || naks != 0 {
+			t.Errorf("good msg %d: acks=%d naks=%d, want 1/0", i, acks, naks)
+		}
+	}
+	if acks, _ := msgs[1].counts(); acks != 1 {
+		t.Errorf("bad msg acks = %d, want 1 (after dead-lettering)", acks)
+	}
+	if len(dlq.msgs) != 1 || dlq.msgs[0].Header.Get(constants.NATSHeaderDLQReason) != constants.DLQReasonInsert {
+		t.Fatalf("DLQ = %v, want one insert-reason message", dlq.msgs)
+	}
+	if len(store.rows) != 2 {
+		t.Errorf("persisted rows = %d, want 2", len(store.rows))
+	}
+}
+
+func TestFlush_DataErrorBelowMaxDeliveriesNaks(t *testing.T) {
+	store := &stubStore{badPIDs: map[uint32]bool{1: true}}
+	c := testConsumer(store, 10)
+
+	msg := newMsg(t, 1)
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+
+	if acks, naks := msg.counts(); acks != 0 || naks != 1 {
+		t.Errorf("acks=%d naks=%d, want 0/1", acks, naks)
+	}
+	if n := len(c.dlq.(*recordingDLQ).msgs); n != 0 {
+		t.Errorf("DLQ messages = %d, want 0 before MaxDeliveries", n)
+	}
+}
+
+func TestHandle_RedeliveredBatchNotCountedTwice(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+	before := testutil.ToFloat64(duplicates)
+
+	var msgs []*fakeMsg
+	for id := uint64(1); id <= 3; id++ {
+		data, err := wire.Encode(wire.Event{ID: id, Type: "tcp", Timestamp: time.Now().UnixMilli(), PID: 7})
+		if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, &fakeMsg{data: data, delivered: 1})
+	}
+	for _, m := range msgs {
+		c.handle(context.Background(), m)
+	}
+	c.flush(context.Background())
+
+	// The acks were lost, so JetStream redelivers the whole batch.
+	for _, m := range msgs {
+		m.delivered++
+		c.handle(context.Background(), m)
+	}
+	c.flush(context.Background())
+
+	if len(store.rows) != 3 || store.calls != 1 {
+		t.Errorf("persisted rows = %d in %d inserts, want 3 in 1", len(store.rows), store.calls)
+	}
+	for i, m := range msgs {
+		if acks, naks := m.counts(); acks != 2 || naks != 0 {
+			t.Errorf("msg %d: acks=%d naks=%d, want 2/0", i, acks, naks)
+		}
+	}
+	if got := testutil.ToFloat64(duplicates) - before; got != 3 {
+		t.Errorf("duplicates counter delta = %v, want 3", got)
+	}
+}
+
+func TestHandle_LegacyMessagesDedupedByPayload(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+
+	msg := newMsg(t, 1)
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+
+	if len(store.rows) != 1 {
+		t.Fatalf("persisted rows = %d, want 1", len(store.rows))
+	}
+	if store.rows[0].EventID != payloadID(msg.data) {
+		t.Errorf("event ID = %d, want the payload hash", store.rows[0].EventID)
+	}
+}
+
+// newPack returns a zstd-packed message holding one event per PID, as the
+// exporter publishes with compression enabled.
+func newPack(t *testing.T, pids ...uint32) *fakeMsg {
+	t.Helper()
+	var events [][]byte
+	for _, pid := range pids {
+		data, err := wire.Encode(wire.Event{ID: uint64(pid), Type: "tcp", Timestamp: time.Now().UnixMilli(), PID: pid})
+		if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, data)
+	}
+	data, err := wire.EncodePack(wire.EncodingZstd, events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := nats.Header{}
+	header.Set(constants.NATSHeaderContentEncoding, wire.EncodingZstd)
+	return &fakeMsg{data: data, header: header, delivered: 1}
+}
+
+func TestHandle_PackAckedOnce(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+
+	msg := newPack(t, 1, 2, 3)
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+	if len(store.rows) != 3 {
+		t.Errorf("persisted rows = %d, want 3", len(store.rows))
+	}
+	if acks, naks := msg.counts(); acks != 1 || naks != 0 {
+		t.Errorf("acks=%d naks=%d, want 1/0", acks, naks)
+	}
+
+	// A redelivered pack adds nothing and is acked straight away.
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+	if len(store.rows) != 3 || store.calls != 1 {
+		t.Errorf("after redelivery: rows = %d in %d inserts, want 3 in 1", len(store.rows), store.calls)
+	}
+	if acks, _ := msg.counts(); acks != 2 {
+		t.Errorf("acks after redelivery = %d, want 2", acks)
+	}
+}
+
+func TestFlush_DataErrorInPackWritesGoodRowsOnce(t *testing.T) {
+	store := &stubStore{badPIDs: map[uint32]bool{2: true}}
+	c := testConsumer(store, 10)
+	dlq := c.dlq.(*recordingDLQ)
+
+	msg := newPack(t, 1, 2, 3)
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+	if acks, naks := msg.counts(); acks != 0 || naks != 1 {
+		t.Fatalf("first delivery: acks=%d naks=%d, want 0/1", acks, naks)
+	}
+
+	// The last delivery dead-letters the pack without rewriting rows 1 and 3.
+	msg.delivered = uint64(c.cfg.MaxDeliveries)
+	c.handle(context.Background(), msg)
+	c.flush(context.Background())
+	if len(store.rows) != 2 {
+		t.Errorf("persisted rows = %d, want 2", len(store.rows))
+	}
+	if acks, _ := msg.counts(); acks != 1 {
+		t.Errorf("acks = %d, want 1 after dead-lettering", acks)
+	}
+	if len(dlq.msgs) != 1 || dlq.msgs[0].Header.Get(constants.NATSHeaderContentEncoding) != wire.EncodingZstd {
+		t.Errorf("DLQ = %v, want the pack with its content encoding", dlq.msgs)
+	}
+}
+
+func TestHandle_ConnectionLabelsReachRows(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+
+	tests := []struct {
+		typ    string
+		labels map[string]string
+	}{
+		{constants.ModuleRetransmit, map[string]string{
+			constants.KeySrc: "10.0.0.1:33000",
+			constants.KeyDst: "10.0.0.2:443",
+		}},
+		{constants.ModuleRST, map[string]string{
+			constants.KeySrc:       "10.0.0.1:8080",
+			constants.KeyDst:       "10.0.0.2:51000",
+			constants.KeyState:     "ESTABLISHED",
+			constants.KeyDirection: constants.RSTDirectionSent,
+		}},
+	}
+	for i, tt := range tests {
+		data, err := wire.Encode(wire.Event{Type: tt.typ, Timestamp: time.Now().UnixMilli(), PID: uint32(i + 1), Labels: tt.labels})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.handle(context.Background(), &fakeMsg{data: data, delivered: 1})
+	}
+	c.flush(context.Background())
+
+	if len(store.rows) != len(tests) {
+		t.Fatalf("persisted rows = %d, want %d", len(store.rows), len(tests))
+	}
+	for i, tt := range tests {
+		row := store.rows[i]
+		if row.Type != tt.typ {
+			t.Errorf("rows[%d].Type = %q, want %q", i, row.Type, tt.typ)
+		}
+		for k, v := range tt.labels {
+			if got := row.Labels[k]; got != v {
+				t.Errorf("%s row label %s = %q, want %q", tt.typ, k, got, v)
+			}
+		}
+	}
+}
+
+func TestMetrics_MessagesAndInserts(t *testing.T) {
+	value := func(c prometheus.Collector) float64 { return testutil.ToFloat64(c) }
+	messages, rows := value(messagesConsumed), value(rowsInserted)
+	decode := value(decodeFailures.WithLabelValues(constants.DLQReasonDecode))
+	data := value(insertErrors.WithLabelValues(constants.InsertErrorData))
+	transient := value(insertErrors.WithLabelValues(constants.InsertErrorTransient))
+
+	store := &stubStore{failures: 1, badPIDs: map[uint32]bool{2: true}}
+	c := testConsumer(store, 10)
+	c.handle(context.Background(), &fakeMsg{data: []byte("{not json"), delivered: 1})
+	c.handle(context.Background(), newMsg(t, 1))
+	c.flush(context.Background()) // transient failure
+	for _, pid := range []uint32{1, 2, 3} {
+		c.handle(context.Background(), newMsg(t, pid))
+	}
+	c.flush(context.Background()) // data error, then rows 1 and 3 one at a time
+
+	for _, tt := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"messages", value(messagesConsumed) - messages, 5},
+		{"decode failures", value(decodeFailures.WithLabelValues(constants.DLQReasonDecode)) - decode, 1},
+		{"rows inserted", value(rowsInserted) - rows, 2},
+		{"data errors", value(insertErrors.WithLabelValues(constants.InsertErrorData)) - data, 2},
+		{"transient errors", value(insertErrors.WithLabelValues(constants.InsertErrorTransient)) - transient, 1},
+	} {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+	if n := testutil.CollectAndCount(flushLatency); n != 1 {
+		t.Errorf("flush latency series = %d, want 1", n)
+	}
+}
+
+// TestNew_MetricsRegisteredOnce builds several consumers, as a process
+// restarting its pipeline would: metrics are registered once per process,
+// not per consumer, so this must not panic.
+func TestNew_MetricsRegisteredOnce(t *testing.T) {
+	for range 2 {
+		c := testConsumer(&stubStore{}, 10)
+		c.handle(context.Background(), newMsg(t, 1))
+		c.flush(context.Background())
+	}
+}
+
+// lateStream is a streamLookup whose stream appears on the third lookup.
+type lateStream struct{ lookups int }
+
+func (s *lateStream) Stream(context.Context, string) (jetstream.Stream, error) {
+	s.lookups++
+	if s.lookups < 3 {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	return nil, nil
+}
+
+func TestWaitStream(t *testing.T) {
+	c := testConsumer(&stubStore{}, 10)
+	c.streamWait = time.Millisecond
+	js := &lateStream{}
+	if _, err := c.waitStream(context.Background(), js); err != nil || js.lookups != 3 {
+		t.Errorf("err %v after %d lookups, want the stream on the third", err, js.lookups)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.waitStream(ctx, &lateStream{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want the context's", err)
+	}
+}
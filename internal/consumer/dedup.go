@@ -0,0 +1,80 @@
+package consumer
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// duplicates counts redelivered messages acked without being inserted.
+var duplicates = promauto.NewCounter(prometheus.CounterOpts{
+	Name: constants.MetricConsumerDuplicates,
+	Help: "Redelivered events the consumer dropped because their event ID was already written.",
+})
+
+// payloadID identifies a message from an agent that predates event IDs by
+// its payload, which is byte-identical on every redelivery. Events with
+// identical contents therefore count once, as they do for rows backfilled
+// by 007_event_id_column.sql.
+func payloadID(data []byte) uint64 {
+	h := fnv.New64a()
+	h.Write(data)
+	return h.Sum64()
+}
+
+// recentIDs is a bounded set of the most recently accepted event IDs. Once
+// full, adding an ID evicts the oldest. A nil *recentIDs accepts
+// everything.
+type recentIDs struct {
+	mu   sync.Mutex
+	pos  map[uint64]int // ID → its slot in ring
+	ring []uint64
+	next int
+}
+
+func newRecentIDs(size int) *recentIDs {
+	if size <= 0 {
+		return nil
+	}
+	return &recentIDs{pos: make(map[uint64]int, size), ring: make([]uint64, 0, size)}
+}
+
+// add records id and reports whether it was new.
+func (r *recentIDs) add(id uint64) bool {
+	if r == nil {
+		return true
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.pos[id]; ok {
+		return false
+	}
+	if len(r.ring) < cap(r.ring) {
+		r.pos[id] = len(r.ring)
+		r.ring = append(r.ring, id)
+		return true
+	}
+	// Evict the oldest unless its slot was reused after a forget.
+	if old := r.ring[r.next]; r.pos[old] == r.next {
+		delete(r.pos, old)
+	}
+	r.ring[r.next] = id
+	r.pos[id] = r.next
+	r.next = (r.next + 1) % len(r.ring)
+	return true
+}
+
+// forget removes id, so a redelivery of an event that was never written is
+// accepted again.
+func (r *recentIDs) forget(id uint64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	delete(r.pos, id)
+	r.mu.Unlock()
+}
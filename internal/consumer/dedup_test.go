@@ -0,0 +1,44 @@
+package consumer
+
+import "testing"
+
+func TestRecentIDs_EvictsOldest(t *testing.T) {
+	r := newRecentIDs(2)
+	for _, id := range []uint64{1, 2} {
+		if !r.add(id) {
+			t.Fatalf("add(%d) on first sight = false", id)
+		}
+	}
+	if r.add(1) {
+		t.Error("add(1) twice = true, want duplicate")
+	}
+	r.add(3) // evicts 1
+	if !r.add(1) {
+		t.Error("add(1) after eviction = false, want new")
+	}
+	if r.add(3) {
+		t.Error("add(3) twice = true, want duplicate")
+	}
+}
+
+func TestRecentIDs_Forget(t *testing.T) {
+	r := newRecentIDs(2)
+	r.add(1)
+	r.forget(1)
+	if !r.add(1) {
+		t.Fatal("add(1) after forget = false, want new")
+	}
+	// 1 now sits in slot 1; evicting the stale slot 0 must keep it.
+	r.add(2)
+	if r.add(1) {
+		t.Error("add(1) after evicting its stale slot = true, want duplicate")
+	}
+}
+
+func TestRecentIDs_Disabled(t *testing.T) {
+	r := newRecentIDs(0)
+	if !r.add(1) || !r.add(1) {
+		t.Error("disabled set reported a duplicate")
+	}
+	r.forget(1)
+}
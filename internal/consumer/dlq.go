@@ -0,0 +1,74 @@
+package consumer
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// DeadLetterPublisher publishes rejected payloads to the DLQ subject.
+// Implemented by jetstream.JetStream; tests substitute a recorder.
+type DeadLetterPublisher interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// deadLettered counts messages moved to the DLQ, by reason.
+var deadLettered = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricConsumerDeadLettered,
+	Help: "Messages dead-lettered by the consumer after exhausting delivery attempts.",
+}, []string{constants.LabelReason})
+
+// reject handles a message that failed for a reason redelivery is unlikely
+// to fix. It is NAK'd until it has been delivered MaxDeliveries times, then
+// published to the DLQ and acked so it stops cycling through the stream.
+// A malformed payload can never succeed, so it skips the retries; without
+// a DLQ it is terminated instead.
+func (c *Consumer) reject(ctx context.Context, msg jetstream.Msg, reason string, cause error) {
+	var delivered uint64
+	if md, err := msg.Metadata(); err == nil {
+		delivered = md.NumDelivered
+	}
+	final := reason == constants.DLQReasonDecode
+	switch {
+	case final && c.dlq == nil:
+		if err := msg.TermWithReason(reason); err != nil {
+			c.logger.Warn("Failed to terminate message", zap.Error(err))
+		}
+		return
+	case !final && (delivered < uint64(c.cfg.MaxDeliveries) || c.dlq == nil):
+		msg.NakWithDelay(c.cfg.NakDelay)
+		return
+	}
+
+	dl := nats.NewMsg(c.cfg.DLQSubject)
+	dl.Data = msg.Data()
+	if enc := msg.Headers().Get(constants.NATSHeaderContentEncoding); enc != "" {
+		dl.Header.Set(constants.NATSHeaderContentEncoding, enc)
+	}
+	dl.Header.Set(constants.NATSHeaderDLQReason, reason)
+	dl.Header.Set(constants.NATSHeaderDLQError, cause.Error())
+	dl.Header.Set(constants.NATSHeaderOrigSubject, msg.Subject())
+	dl.Header.Set(constants.NATSHeaderNumDelivered, strconv.FormatUint(delivered, 10))
+
+	if _, err := c.dlq.PublishMsg(ctx, dl); err != nil {
+		// Leave the original in the stream; the next delivery retries the DLQ.
+		c.logger.Error("Failed to publish to DLQ", zap.Error(err), zap.String("reason", reason))
+		msg.NakWithDelay(c.cfg.NakDelay)
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		c.logger.Warn("Failed to ack dead-lettered message", zap.Error(err))
+	}
+	deadLettered.WithLabelValues(reason).Inc()
+	c.logger.Warn("Message dead-lettered",
+		zap.String("reason", reason),
+		zap.Uint64("deliveries", delivered),
+		zap.Error(cause))
+}
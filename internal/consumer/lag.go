@@ -0,0 +1,170 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+var (
+	pending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerPending,
+		Help: "Messages in the stream not yet delivered to the consumer.",
+	})
+	ackPending = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerAckPending,
+		Help: "Messages delivered to the consumer and not yet acked.",
+	})
+	deliveredSeq = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerDeliveredSeq,
+		Help: "Stream sequence of the last message delivered to the consumer.",
+	})
+	ackFloorSeq = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerAckFloorSeq,
+		Help: "Stream sequence below which the consumer has acked every message.",
+	})
+	lagSeconds = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerLag,
+		Help: "Age of the oldest stream message the consumer has not acked; 0 when caught up.",
+	})
+)
+
+// Lag is the consumer's position in the stream, from its JetStream
+// consumer info.
+type Lag struct {
+	Pending    uint64    `json:"pending"`     // in the stream, not yet delivered
+	AckPending int       `json:"ack_pending"` // delivered, not yet acked
+	Delivered  uint64    `json:"delivered_seq"`
+	AckFloor   uint64    `json:"ack_floor_seq"`
+	Seconds    float64   `json:"lag_seconds"` // age of the oldest message not yet acked
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// consumerInfo and streamMessages are the JetStream calls watchLag
+// makes; jetstream.Consumer and jetstream.Stream implement them.
+type consumerInfo interface {
+	Info(ctx context.Context) (*jetstream.ConsumerInfo, error)
+}
+
+type streamMessages interface {
+	GetMsg(ctx context.Context, seq uint64, opts ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error)
+}
+
+// Lag returns the last lag report, and false before the first.
+func (c *Consumer) Lag() (Lag, bool) {
+	c.lagMu.Lock()
+	defer c.lagMu.Unlock()
+	if c.lag == nil {
+		return Lag{}, false
+	}
+	return *c.lag, true
+}
+
+// watchLag reports the consumer's lag every infoInterval until ctx is
+// cancelled, starting at once.
+func (c *Consumer) watchLag(ctx context.Context, cons consumerInfo, stream streamMessages) {
+	ticker := time.NewTicker(c.infoInterval)
+	defer ticker.Stop()
+	for {
+		c.checkLag(ctx, cons, stream)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkLag reads the consumer info and the oldest message not yet acked,
+// the first one on the subject after the ack floor, whose age is the lag.
+// It warns when the lag crosses LagThreshold, and again when it recovers.
+func (c *Consumer) checkLag(ctx context.Context, cons consumerInfo, stream streamMessages) {
+	info, err := cons.Info(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			c.logger.Debug("Reading consumer info failed", zap.Error(err))
+		}
+		return
+	}
+	now := time.Now()
+	lag := Lag{
+		Pending:    info.NumPending,
+		AckPending: info.NumAckPending,
+		Delivered:  info.Delivered.Stream,
+		AckFloor:   info.AckFloor.Stream,
+		CheckedAt:  now,
+	}
+	if lag.Pending > 0 || lag.AckPending > 0 {
+		msg, err := stream.GetMsg(ctx, lag.AckFloor+1, jetstream.WithGetMsgSubject(c.cfg.Subject))
+		if err == nil {
+			lag.Seconds = max(0, now.Sub(msg.Time).Seconds())
+		} else if ctx.Err() == nil {
+			c.logger.Debug("Reading the oldest pending message failed", zap.Error(err))
+		}
+	}
+
+	pending.Set(float64(lag.Pending))
+	ackPending.Set(float64(lag.AckPending))
+	deliveredSeq.Set(float64(lag.Delivered))
+	ackFloorSeq.Set(float64(lag.AckFloor))
+	lagSeconds.Set(lag.Seconds)
+
+	c.lagMu.Lock()
+	wasLagging := c.lag != nil && c.lagging(*c.lag)
+	c.lag = &lag
+	c.lagMu.Unlock()
+
+	switch lagging := c.lagging(lag); {
+	case lagging && !wasLagging:
+		c.logger.Warn("Consumer is falling behind the stream",
+			zap.Duration("lag", time.Duration(lag.Seconds*float64(time.Second))),
+			zap.Duration("threshold", c.cfg.LagThreshold),
+			zap.Uint64("pending", lag.Pending),
+			zap.Int("ack_pending", lag.AckPending))
+	case !lagging && wasLagging:
+		c.logger.Info("Consumer caught up with the stream",
+			zap.Duration("lag", time.Duration(lag.Seconds*float64(time.Second))))
+	}
+}
+
+// lagging reports whether lag is past LagThreshold.
+func (c *Consumer) lagging(lag Lag) bool {
+	return c.cfg.LagThreshold > 0 && lag.Seconds > c.cfg.LagThreshold.Seconds()
+}
+
+// HealthHandler serves the consumer's health with its last lag report as
+// JSON. It answers 503 before the first report, when the last one is
+// older than three report intervals (JetStream is unreachable), and while
+// the lag is past LagThreshold, so a readiness probe can gate on it.
+func (c *Consumer) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := struct {
+			Status string `json:"status"`
+			*Lag
+		}{Status: "ok"}
+		lag, ok := c.Lag()
+		switch {
+		case !ok:
+			body.Status = "starting"
+		case time.Since(lag.CheckedAt) > 3*c.infoInterval:
+			body.Status, body.Lag = "stale", &lag
+		case c.lagging(lag):
+			body.Status, body.Lag = "lagging", &lag
+		default:
+			body.Lag = &lag
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if body.Status != "ok" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(body)
+	})
+}
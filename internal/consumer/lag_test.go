@@ -0,0 +1,129 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// infoConsumer is a consumerInfo reporting fixed consumer info.
+type infoConsumer struct {
+	info jetstream.ConsumerInfo
+}
+
+func (c *infoConsumer) Info(context.Context) (*jetstream.ConsumerInfo, error) { return &c.info, nil }
+
+// oldestStream is a streamMessages whose every message was published at
+// a fixed time; it records the sequence asked for.
+type oldestStream struct {
+	published time.Time
+	asked     uint64
+}
+
+func (s *oldestStream) GetMsg(_ context.Context, seq uint64, _ ...jetstream.GetMsgOpt) (*jetstream.RawStreamMsg, error) {
+	s.asked = seq
+	if s.published.IsZero() {
+		return nil, errors.New("no message found")
+	}
+	return &jetstream.RawStreamMsg{Sequence: seq, Time: s.published}, nil
+}
+
+// health returns the status and body of c's health handler.
+func health(t *testing.T, c *Consumer) (int, map[string]any) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	c.HealthHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/healthz", nil))
+	var body map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("health body %q: %v", rec.Body, err)
+	}
+	return rec.Code, body
+}
+
+func TestCheckLag(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	c := testConsumer(&stubStore{}, 10)
+	c.logger = zap.New(core)
+	c.cfg.LagThreshold = time.Minute
+
+	if code, body := health(t, c); code != 503 || body["status"] != "starting" {
+		t.Errorf("health before a report = %d %v, want 503 starting", code, body)
+	}
+
+	cons := &infoConsumer{info: jetstream.ConsumerInfo{
+		NumPending:    1200,
+		NumAckPending: 40,
+		Delivered:     jetstream.SequenceInfo{Stream: 560},
+		AckFloor:      jetstream.SequenceInfo{Stream: 520},
+	}}
+	stream := &oldestStream{published: time.Now().Add(-2 * time.Minute)}
+	c.checkLag(context.Background(), cons, stream)
+
+	lag, ok := c.Lag()
+	if !ok || lag.Pending != 1200 || lag.AckPending != 40 || lag.Delivered != 560 || lag.AckFloor != 520 {
+		t.Fatalf("lag = %+v, %v", lag, ok)
+	}
+	if stream.asked != 521 {
+		t.Errorf("oldest message asked for = %d, want the one after the ack floor", stream.asked)
+	}
+	if lag.Seconds < 119 || lag.Seconds > 130 {
+		t.Errorf("lag = %vs, want about 120s", lag.Seconds)
+	}
+	if got := testutil.ToFloat64(lagSeconds); got != lag.Seconds {
+		t.Errorf("lag gauge = %v, want %v", got, lag.Seconds)
+	}
+	if got := testutil.ToFloat64(ackFloorSeq); got != 520 {
+		t.Errorf("ack floor gauge = %v, want 520", got)
+	}
+	if code, body := health(t, c); code != 503 || body["status"] != "lagging" || body["pending"] != 1200.0 {
+		t.Errorf("health while lagging = %d %v", code, body)
+	}
+
+	// Still behind: no second warning.
+	c.checkLag(context.Background(), cons, stream)
+	if n := logs.FilterMessage("Consumer is falling behind the stream").Len(); n != 1 {
+		t.Errorf("%d lag warnings, want 1", n)
+	}
+
+	// Caught up: nothing pending, so no oldest message to read.
+	cons.info = jetstream.ConsumerInfo{Delivered: jetstream.SequenceInfo{Stream: 2000}, AckFloor: jetstream.SequenceInfo{Stream: 2000}}
+	stream.asked = 0
+	c.checkLag(context.Background(), cons, stream)
+	if lag, _ := c.Lag(); lag.Seconds != 0 || stream.asked != 0 {
+		t.Errorf("caught up: lag = %+v, asked for %d", lag, stream.asked)
+	}
+	if logs.FilterMessage("Consumer caught up with the stream").Len() != 1 {
+		t.Errorf("logged %v, want a recovery line", logs.All())
+	}
+	if code, body := health(t, c); code != 200 || body["status"] != "ok" || body["lag_seconds"] != 0.0 {
+		t.Errorf("health when caught up = %d %v", code, body)
+	}
+
+	// A report older than three intervals means JetStream is unreachable.
+	c.lagMu.Lock()
+	c.lag.CheckedAt = time.Now().Add(-4 * c.infoInterval)
+	c.lagMu.Unlock()
+	if code, body := health(t, c); code != 503 || body["status"] != "stale" {
+		t.Errorf("health with a stale report = %d %v", code, body)
+	}
+}
+
+func TestCheckLag_ThresholdZeroNeverLags(t *testing.T) {
+	c := testConsumer(&stubStore{}, 10)
+	c.cfg.LagThreshold = 0
+	cons := &infoConsumer{info: jetstream.ConsumerInfo{NumPending: 1}}
+	c.checkLag(context.Background(), cons, &oldestStream{published: time.Now().Add(-24 * time.Hour)})
+
+	if code, body := health(t, c); code != 200 || body["lag_seconds"].(float64) < 86000 {
+		t.Errorf("health = %d %v, want 200 with the lag reported", code, body)
+	}
+}
@@ -0,0 +1,117 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// LiveConfig controls publishing decoded events to the Redis pub/sub
+// channel the API's WebSocket hub fans out. Storage-only deployments leave
+// it disabled and need no Redis.
+type LiveConfig struct {
+	Enabled    bool     `yaml:"enabled"`
+	Types      []string `yaml:"types"`      // empty publishes every type
+	Namespaces []string `yaml:"namespaces"` // empty publishes every namespace
+	RateLimit  int      `yaml:"rate_limit"` // events per second; 0 is unlimited
+}
+
+// LivePublisher is the pub/sub dependency of the live feed.
+// Implemented by *cache.Redis; tests substitute miniredis behind it.
+type LivePublisher interface {
+	Publish(ctx context.Context, channel string, msg any) error
+}
+
+// liveDropped counts events the live feed skipped, by reason.
+var liveDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricConsumerLiveDropped,
+	Help: "Events not published to the live channel, by reason.",
+}, []string{"reason"})
+
+// liveFeed publishes accepted events to Redis off the insert path: offer
+// never blocks, and events beyond the rate limit or the queue are dropped
+// rather than slowing the consumer down.
+type liveFeed struct {
+	pub        LivePublisher
+	types      map[string]bool // nil matches every type
+	namespaces map[string]bool // nil matches every namespace
+	limit      *rate.Limiter   // nil is unlimited
+	queue      chan []byte
+	logger     *zap.Logger
+}
+
+func newLiveFeed(cfg LiveConfig, pub LivePublisher, logger *zap.Logger) *liveFeed {
+	f := &liveFeed{
+		pub:        pub,
+		types:      setOf(cfg.Types),
+		namespaces: setOf(cfg.Namespaces),
+		queue:      make(chan []byte, constants.ConsumerLiveQueueSize),
+		logger:     logger,
+	}
+	if cfg.RateLimit > 0 {
+		f.limit = rate.NewLimiter(rate.Limit(cfg.RateLimit), cfg.RateLimit)
+	}
+	return f
+}
+
+func setOf(vs []string) map[string]bool {
+	if len(vs) == 0 {
+		return nil
+	}
+	m := make(map[string]bool, len(vs))
+	for _, v := range vs {
+		m[v] = true
+	}
+	return m
+}
+
+// offer queues w for publishing if it passes the filters. A nil *liveFeed
+// ignores every event.
+func (f *liveFeed) offer(w wire.Event) {
+	if f == nil {
+		return
+	}
+	if f.types != nil && !f.types[w.Type] {
+		return
+	}
+	if f.namespaces != nil && !f.namespaces[w.Namespace] {
+		return
+	}
+	if f.limit != nil && !f.limit.Allow() {
+		liveDropped.WithLabelValues(constants.LiveDropRateLimit).Inc()
+		return
+	}
+	data, err := json.Marshal(w.Live())
+	if err != nil {
+		return
+	}
+	select {
+	case f.queue <- data:
+	default:
+		liveDropped.WithLabelValues(constants.LiveDropQueueFull).Inc()
+	}
+}
+
+// run publishes queued events until ctx is cancelled.
+func (f *liveFeed) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case data := <-f.queue:
+			if err := f.pub.Publish(ctx, constants.RedisPubSubChannel, data); err != nil {
+				liveDropped.WithLabelValues(constants.LiveDropPublish).Inc()
+				if ctx.Err() == nil {
+					f.logger.Debug("Publishing live event", zap.Error(err))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+func liveMsg(t *testing.T, w wire.Event) *fakeMsg {
+	t.Helper()
+	data, err := wire.Encode(w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &fakeMsg{data: data, delivered: 1}
+}
+
+func TestLive_PublishesAcceptedEventsToRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	rc, err := cache.NewRedis(cache.RedisConfig{Addr: mr.Addr(), PoolSize: 2}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sub := rc.Subscribe(ctx, constants.RedisPubSubChannel)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c := testConsumer(&stubStore{}, 10)
+	c.cfg.Live = LiveConfig{Enabled: true, Types: []string{"tcp"}, Namespaces: []string{"shop"}}
+	c.EnableLive(rc)
+	go c.live.run(ctx)
+
+	ts := time.Now().Truncate(time.Millisecond)
+	tcp := wire.Event{ID: 1, Type: "tcp", Timestamp: ts.UnixMilli(), PID: 7, Namespace: "shop", Pod: "cart-0"}
+	for _, m := range []*fakeMsg{
+		liveMsg(t, tcp),
+		liveMsg(t, tcp), // redelivery: not published twice
+		liveMsg(t, wire.Event{ID: 2, Type: "dns", Timestamp: ts.UnixMilli(), Namespace: "shop"}),
+		liveMsg(t, wire.Event{ID: 3, Type: "tcp", Timestamp: ts.UnixMilli(), Namespace: "kube-system"}),
+		liveMsg(t, wire.Event{ID: 4, Type: "tcp", Timestamp: ts.UnixMilli(), PID: 8, Namespace: "shop"}),
+	} {
+		c.handle(ctx, m)
+	}
+
+	var got []map[string]any
+	for len(got) < 2 {
+		msg, err := sub.ReceiveTimeout(ctx, 2*time.Second)
+		if err != nil {
+			t.Fatalf("after %d live events: %v", len(got), err)
+		}
+		pm, ok := msg.(*redis.Message)
+		if !ok {
+			t.Fatalf("received %T, want *redis.Message", msg)
+		}
+		var m map[string]any
+		if err := json.Unmarshal([]byte(pm.Payload), &m); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, m)
+	}
+	if got[0]["namespace"] != "shop" || got[0]["pod"] != "cart-0" || got[0]["type"] != "tcp" {
+		t.Errorf("first live event = %v, want tcp in shop/cart-0", got[0])
+	}
+	if got[1]["pid"] != float64(8) {
+		t.Errorf("second live event = %v, want pid 8", got[1])
+	}
+	if _, err := sub.ReceiveTimeout(ctx, 100*time.Millisecond); err == nil {
+		t.Error("filtered or duplicate event was published")
+	}
+}
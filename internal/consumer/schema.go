@@ -0,0 +1,42 @@
+package consumer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+var (
+	unknownTypes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: constants.MetricConsumerUnknownTypes,
+		Help: "Events of a type the consumer has no schema for, such as one added by a newer agent.",
+	})
+	missingKeys = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricConsumerMissingKeys,
+		Help: "Events lacking a label or numeric their type's schema requires, by type and key.",
+	}, constants.LabelsTypeKey)
+)
+
+// checkSchema counts w if its type is unknown or it lacks required keys.
+// Such events are still written: a newer agent may emit types and keys
+// this consumer doesn't know, and redaction can drop required labels.
+func (c *Consumer) checkSchema(w wire.Event) {
+	schema, ok := event.SchemaOf(w.Type)
+	if !ok {
+		unknownTypes.Inc()
+		c.logger.Debug("Event of unknown type", zap.String("type", w.Type), zap.Uint64("id", w.ID))
+		return
+	}
+	missing := schema.Missing(w.Labels, w.Numerics)
+	for _, key := range missing {
+		missingKeys.WithLabelValues(w.Type, key).Inc()
+	}
+	if len(missing) > 0 {
+		c.logger.Debug("Event lacks required keys", zap.String("type", w.Type),
+			zap.Uint64("id", w.ID), zap.Strings("keys", missing))
+	}
+}
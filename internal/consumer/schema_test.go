@@ -0,0 +1,51 @@
+package consumer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+func TestHandle_SchemaChecked(t *testing.T) {
+	store := &stubStore{}
+	c := testConsumer(store, 10)
+	unknownBefore := testutil.ToFloat64(unknownTypes)
+	missing := missingKeys.WithLabelValues(constants.ModuleDNS, constants.KeyTransport)
+	missingBefore := testutil.ToFloat64(missing)
+	completeBefore := testutil.ToFloat64(missingKeys.WithLabelValues(constants.ModuleDNS, constants.KeyQName))
+
+	for i, w := range []wire.Event{
+		{Type: "gpu"},
+		{Type: constants.ModuleDNS, Labels: map[string]string{
+			constants.KeyQName: "api.example.com.", constants.KeyDomain: "example.com",
+			constants.KeyQType: "A",
+		}},
+	} {
+		w.Timestamp, w.PID = time.Now().UnixMilli(), uint32(i+1)
+		data, err := wire.Encode(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.handle(context.Background(), &fakeMsg{data: data, delivered: 1})
+	}
+	c.flush(context.Background())
+
+	if got := testutil.ToFloat64(unknownTypes) - unknownBefore; got != 1 {
+		t.Errorf("unknown type events = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(missing) - missingBefore; got != 1 {
+		t.Errorf("dns events missing transport = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(missingKeys.WithLabelValues(constants.ModuleDNS, constants.KeyQName)) - completeBefore; got != 0 {
+		t.Errorf("dns events missing qname = %v, want 0", got)
+	}
+	// Both are still written.
+	if len(store.rows) != 2 {
+		t.Errorf("persisted rows = %d, want 2", len(store.rows))
+	}
+}
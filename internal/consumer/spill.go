@@ -0,0 +1,236 @@
+package consumer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+const (
+	spillExt     = ".spill"
+	rejectedExt  = ".rejected"
+	spillTmpExt  = ".tmp"
+	spillNameLen = 20 // zero-padded sequence, so lexical order == write order
+)
+
+// errSpillFull is returned by spillQueue.write when the batch would push
+// the spill directory past its size cap.
+var errSpillFull = errors.New("spill queue full")
+
+var (
+	spillBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerSpillBytes,
+		Help: "Bytes of event batches spilled to disk awaiting replay.",
+	})
+	spillLag = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: constants.MetricConsumerSpillLag,
+		Help: "Age of the oldest spilled batch awaiting replay.",
+	})
+)
+
+// spillQueue is a bounded on-disk FIFO of event batches, used while
+// ClickHouse is unavailable. Each batch is one segment file; segments are
+// replayed oldest first and deleted once inserted.
+type spillQueue struct {
+	dir      string
+	maxBytes int64
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	bytes   int64
+	seq     uint64
+	pending []spillSegment // oldest first, for the lag gauge
+}
+
+// spillSegment is a segment awaiting replay.
+type spillSegment struct {
+	path    string
+	written time.Time
+}
+
+// newSpillQueue opens (creating if needed) a spill directory, picking up
+// any segments left by a previous run.
+func newSpillQueue(dir string, maxBytes int64, logger *zap.Logger) (*spillQueue, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("create spill dir: %w", err)
+	}
+	q := &spillQueue{dir: dir, maxBytes: maxBytes, logger: logger}
+
+	segs, err := q.segments()
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range segs {
+		info, err := os.Stat(s)
+		if err != nil {
+			continue
+		}
+		q.bytes += info.Size()
+		q.pending = append(q.pending, spillSegment{s, info.ModTime()})
+		if n, err := strconv.ParseUint(strings.TrimSuffix(filepath.Base(s), spillExt), 10, 64); err == nil && n >= q.seq {
+			q.seq = n + 1
+		}
+	}
+	if len(segs) > 0 {
+		logger.Info("Found spilled batches from previous run",
+			zap.Int("segments", len(segs)), zap.Int64("bytes", q.bytes))
+	}
+	q.updateGauges()
+	return q, nil
+}
+
+// write persists rows as a new segment. The segment is fsynced and
+// renamed into place, so a crash never leaves a partial segment behind.
+func (q *spillQueue) write(rows []storage.EventRow) error {
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.bytes+int64(len(data)) > q.maxBytes {
+		return errSpillFull
+	}
+
+	name := filepath.Join(q.dir, fmt.Sprintf("%0*d%s", spillNameLen, q.seq, spillExt))
+	tmp := name + spillTmpExt
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, name); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+
+	q.seq++
+	q.bytes += int64(len(data))
+	q.pending = append(q.pending, spillSegment{name, time.Now()})
+	q.updateGaugesLocked()
+	return nil
+}
+
+// drain replays segments oldest first until the queue is empty or an
+// insert fails transiently. A segment ClickHouse rejects as invalid is
+// renamed to *.rejected for inspection so it doesn't block the queue.
+func (q *spillQueue) drain(ctx context.Context, ins BatchInserter) (int, error) {
+	segs, err := q.segments()
+	if err != nil {
+		return 0, err
+	}
+
+	replayed := 0
+	for _, seg := range segs {
+		if ctx.Err() != nil {
+			return replayed, ctx.Err()
+		}
+		data, err := os.ReadFile(seg)
+		if err != nil {
+			return replayed, err
+		}
+		var rows []storage.EventRow
+		if err := json.Unmarshal(data, &rows); err != nil {
+			q.logger.Error("Corrupt spill segment — quarantining", zap.String("file", seg), zap.Error(err))
+			q.remove(seg, int64(len(data)), true)
+			continue
+		}
+
+		err = ins.InsertBatch(ctx, rows)
+		countInsert(len(rows), err)
+		if err != nil {
+			if storage.IsDataError(err) {
+				q.logger.Error("Spilled batch rejected by ClickHouse — quarantining",
+					zap.String("file", seg), zap.Error(err))
+				q.remove(seg, int64(len(data)), true)
+				continue
+			}
+			return replayed, err
+		}
+		q.remove(seg, int64(len(data)), false)
+		replayed++
+	}
+	return replayed, nil
+}
+
+// remove drops a segment from the queue, deleting it or (quarantine)
+// renaming it out of the replay set.
+func (q *spillQueue) remove(seg string, size int64, quarantine bool) {
+	var err error
+	if quarantine {
+		err = os.Rename(seg, strings.TrimSuffix(seg, spillExt)+rejectedExt)
+	} else {
+		err = os.Remove(seg)
+	}
+	if err != nil {
+		q.logger.Warn("Failed to remove spill segment", zap.String("file", seg), zap.Error(err))
+	}
+
+	q.mu.Lock()
+	q.bytes -= size
+	q.pending = slices.DeleteFunc(q.pending, func(s spillSegment) bool { return s.path == seg })
+	q.updateGaugesLocked()
+	q.mu.Unlock()
+}
+
+// segments returns pending segment paths, oldest first.
+func (q *spillQueue) segments() ([]string, error) {
+	segs, err := filepath.Glob(filepath.Join(q.dir, "*"+spillExt))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(segs)
+	return segs, nil
+}
+
+// size returns the bytes currently spilled.
+func (q *spillQueue) size() int64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.bytes
+}
+
+func (q *spillQueue) updateGauges() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.updateGaugesLocked()
+}
+
+func (q *spillQueue) updateGaugesLocked() {
+	spillBytes.Set(float64(q.bytes))
+	lag := 0.0
+	if len(q.pending) > 0 {
+		lag = time.Since(q.pending[0].written).Seconds()
+	}
+	spillLag.Set(lag)
+}
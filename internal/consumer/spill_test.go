@@ -0,0 +1,146 @@
+package consumer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+)
+
+func spillConsumer(t *testing.T, store BatchInserter, batchSize int, maxBytes int64) *Consumer {
+	t.Helper()
+	c := testConsumer(store, batchSize)
+	q, err := newSpillQueue(t.TempDir(), maxBytes, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.spill = q
+	return c
+}
+
+func TestSpill_OutageWindowLosesNothing(t *testing.T) {
+	store := &stubStore{failures: 3} // ClickHouse down for three flushes
+	c := spillConsumer(t, store, 2, 1<<20)
+
+	var msgs []*fakeMsg
+	for pid := uint32(1); pid <= 8; pid++ {
+		m := newMsg(t, pid)
+		msgs = append(msgs, m)
+		c.handle(context.Background(), m)
+	}
+
+	// Outage: three batches spilled and acked; the fourth went straight in.
+	for i, m := range msgs {
+		if acks, naks := m.counts(); acks != 1 || naks != 0 {
+			t.Fatalf("msg %d: acks=%d naks=%d, want 1/0", i, acks, naks)
+		}
+	}
+	if len(store.rows) != 2 {
+		t.Fatalf("rows inserted during outage = %d, want 2", len(store.rows))
+	}
+	if c.spill.size() == 0 || testutil.ToFloat64(spillBytes) == 0 {
+		t.Fatal("expected spilled bytes during outage")
+	}
+
+	// Recovery: drain replays everything, oldest segment first.
+	n, err := c.spill.drain(context.Background(), store)
+	if err != nil || n != 3 {
+		t.Fatalf("drain = %d, %v; want 3 batches", n, err)
+	}
+	if len(store.rows) != 8 {
+		t.Fatalf("rows after replay = %d, want 8", len(store.rows))
+	}
+	wantPIDs := []uint32{7, 8, 1, 2, 3, 4, 5, 6}
+	for i, r := range store.rows {
+		if r.PID != wantPIDs[i] {
+			t.Errorf("row %d pid = %d, want %d (spill order)", i, r.PID, wantPIDs[i])
+		}
+	}
+	if c.spill.size() != 0 || testutil.ToFloat64(spillBytes) != 0 || testutil.ToFloat64(spillLag) != 0 {
+		t.Errorf("spill not empty after drain: size=%d", c.spill.size())
+	}
+}
+
+func TestSpill_DrainStopsOnTransientFailure(t *testing.T) {
+	store := &stubStore{failures: 2}
+	c := spillConsumer(t, store, 1, 1<<20)
+
+	for pid := uint32(1); pid <= 2; pid++ {
+		c.handle(context.Background(), newMsg(t, pid))
+	}
+	store.failures = 1 // still down for the first replay attempt
+
+	if n, err := c.spill.drain(context.Background(), store); err == nil || n != 0 {
+		t.Fatalf("drain during outage = %d, %v; want 0 and an error", n, err)
+	}
+	segs, _ := c.spill.segments()
+	if len(segs) != 2 {
+		t.Fatalf("segments after failed drain = %d, want 2", len(segs))
+	}
+	if n, err := c.spill.drain(context.Background(), store); err != nil || n != 2 {
+		t.Fatalf("drain after recovery = %d, %v; want 2", n, err)
+	}
+}
+
+func TestSpill_CapFallsBackToNak(t *testing.T) {
+	store := &stubStore{failures: 100}
+	c := spillConsumer(t, store, 1, 1<<20)
+
+	first, second := newMsg(t, 1), newMsg(t, 2)
+	c.handle(context.Background(), first)
+	c.spill.maxBytes = c.spill.size() // full after one batch
+	c.handle(context.Background(), second)
+
+	if acks, _ := first.counts(); acks != 1 {
+		t.Errorf("first msg acks = %d, want 1 (spilled)", acks)
+	}
+	if acks, naks := second.counts(); acks != 0 || naks != 1 {
+		t.Errorf("second msg acks=%d naks=%d, want 0/1 (spill full)", acks, naks)
+	}
+	if segs, _ := c.spill.segments(); len(segs) != 1 {
+		t.Errorf("segments = %d, want 1", len(segs))
+	}
+}
+
+func TestSpill_ReopenResumesQueue(t *testing.T) {
+	dir := t.TempDir()
+	q, err := newSpillQueue(dir, 1<<20, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := testConsumer(&stubStore{failures: 1}, 1)
+	c.spill = q
+	c.handle(context.Background(), newMsg(t, 42))
+
+	// Simulate a restart: a new queue over the same dir sees the segment,
+	// and a stray temp file from a crashed write is ignored.
+	os.WriteFile(filepath.Join(dir, "00000000000000000099.spill.tmp"), []byte("partial"), 0o640)
+	q2, err := newSpillQueue(dir, 1<<20, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q2.size() != q.size() || q2.seq != 1 {
+		t.Fatalf("reopened size=%d seq=%d, want size=%d seq=1", q2.size(), q2.seq, q.size())
+	}
+	store := &stubStore{}
+	if n, err := q2.drain(context.Background(), store); err != nil || n != 1 || store.rows[0].PID != 42 {
+		t.Fatalf("drain after reopen = %d, %v, rows=%v", n, err, store.rows)
+	}
+}
+
+func TestSpill_RejectedSegmentIsQuarantined(t *testing.T) {
+	c := spillConsumer(t, &stubStore{failures: 1}, 1, 1<<20)
+	c.handle(context.Background(), newMsg(t, 7))
+
+	store := &stubStore{badPIDs: map[uint32]bool{7: true}}
+	if n, err := c.spill.drain(context.Background(), store); err != nil || n != 0 {
+		t.Fatalf("drain = %d, %v; want 0, nil", n, err)
+	}
+	rejected, _ := filepath.Glob(filepath.Join(c.spill.dir, "*"+rejectedExt))
+	if len(rejected) != 1 || c.spill.size() != 0 {
+		t.Errorf("rejected files = %v, size = %d; want one quarantined segment", rejected, c.spill.size())
+	}
+}
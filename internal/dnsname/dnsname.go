@@ -0,0 +1,108 @@
+// Package dnsname reduces DNS query names to the domain reported in the
+// domain label, so a label value stands for many names rather than one.
+package dnsname
+
+import (
+	"net/netip"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Modes: how a query name is reduced to its domain.
+const (
+	ModeRegisteredDomain = "registered_domain" // the public suffix plus one label
+	ModeLevels           = "levels"            // the last Levels labels
+	ModeFull             = "full"              // the whole name; unbounded cardinality
+)
+
+// Truncator reduces query names to domains. Whatever the mode, names are
+// lower-cased and lose any trailing dot. Except in ModeFull, reverse
+// lookups reduce to their zone (in-addr.arpa or ip6.arpa), IP literals to
+// constants.DomainIPLiteral, and cluster Service names to
+// service.namespace.svc.<cluster domain>.
+type Truncator struct {
+	Mode   string
+	Levels int // for ModeLevels
+}
+
+// Default reduces names to their registered domain.
+var Default = Truncator{Mode: ModeRegisteredDomain, Levels: constants.DefaultDNSDomainLevels}
+
+// ValidMode reports whether mode is one of the modes above.
+func ValidMode(mode string) bool {
+	return mode == ModeRegisteredDomain || mode == ModeLevels || mode == ModeFull
+}
+
+// Domain returns the domain of qname.
+func (t Truncator) Domain(qname string) string {
+	name := strings.ToLower(strings.TrimSuffix(qname, "."))
+	if t.Mode == ModeFull || name == "" {
+		return name
+	}
+	if zone, ok := reverseZone(name); ok {
+		return zone
+	}
+	if ip := strings.Trim(name, "[]"); maybeIP(ip) {
+		if _, err := netip.ParseAddr(ip); err == nil {
+			return constants.DomainIPLiteral
+		}
+	}
+	if svc, ok := serviceName(name); ok {
+		return svc
+	}
+	if t.Mode == ModeLevels {
+		return lastLabels(name, t.Levels)
+	}
+	domain, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		// name is itself a public suffix, or a single label.
+		return name
+	}
+	return domain
+}
+
+// maybeIP reports whether name could be an IP literal: IPv6 addresses
+// have a colon, and IPv4 ones end in a digit, as no top-level domain does.
+// It spares netip.ParseAddr, whose errors allocate, every other name.
+func maybeIP(name string) bool {
+	return strings.IndexByte(name, ':') >= 0 || name != "" && name[len(name)-1] >= '0' && name[len(name)-1] <= '9'
+}
+
+// reverseZone reports whether name is a reverse lookup, returning its zone.
+func reverseZone(name string) (string, bool) {
+	for _, zone := range []string{constants.ReverseZoneIPv4, constants.ReverseZoneIPv6} {
+		if name == zone || strings.HasSuffix(name, "."+zone) {
+			return zone, true
+		}
+	}
+	return "", false
+}
+
+// serviceName reports whether name is in the cluster's Service zone,
+// returning it as service.namespace.svc.<cluster domain>: per-pod and
+// per-port prefixes are dropped.
+func serviceName(name string) (string, bool) {
+	zone := ".svc." + constants.DefaultClusterDomain
+	prefix, ok := strings.CutSuffix(name, zone)
+	if !ok {
+		return "", false
+	}
+	labels := strings.Split(prefix, ".")
+	if len(labels) < 2 {
+		// namespace.svc.cluster.local names no Service.
+		return name, true
+	}
+	return strings.Join(labels[len(labels)-2:], ".") + zone, true
+}
+
+// lastLabels returns the last n labels of name.
+func lastLabels(name string, n int) string {
+	labels := strings.Split(name, ".")
+	if len(labels) <= n {
+		return name
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
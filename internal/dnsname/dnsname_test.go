@@ -0,0 +1,66 @@
+package dnsname
+
+import (
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func TestDomain(t *testing.T) {
+	registered := Truncator{Mode: ModeRegisteredDomain}
+	levels3 := Truncator{Mode: ModeLevels, Levels: 3}
+	full := Truncator{Mode: ModeFull}
+
+	tests := []struct {
+		name  string
+		t     Truncator
+		qname string
+		want  string
+	}{
+		{"registered", registered, "a.b.c.example.com", "example.com"},
+		{"registered multi-label suffix", registered, "www.shop.example.co.uk", "example.co.uk"},
+		{"registered private suffix", registered, "user.github.io", "user.github.io"},
+		{"registered suffix itself", registered, "co.uk", "co.uk"},
+		{"registered single label", registered, "localhost", "localhost"},
+		{"trailing dot and case", registered, "WWW.Example.COM.", "example.com"},
+		{"service", registered, "kubernetes.default.svc.cluster.local", "kubernetes.default.svc.cluster.local"},
+		{"service pod prefix", registered, "web-0.web.prod.svc.cluster.local.", "web.prod.svc.cluster.local"},
+		{"service srv prefix", registered, "_grpc._tcp.api.prod.svc.cluster.local", "api.prod.svc.cluster.local"},
+		{"namespace only", registered, "prod.svc.cluster.local", "prod.svc.cluster.local"},
+		{"reverse ipv4", registered, "5.0.244.10.in-addr.arpa", constants.ReverseZoneIPv4},
+		{"reverse ipv6", registered, "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.", constants.ReverseZoneIPv6},
+		{"reverse zone case", registered, "10.IN-ADDR.ARPA", constants.ReverseZoneIPv4},
+		{"not a reverse zone", registered, "in-addr.example.com", "example.com"},
+		{"ipv4 literal", registered, "10.0.0.1", constants.DomainIPLiteral},
+		{"ipv6 literal", registered, "fd00::1", constants.DomainIPLiteral},
+		{"bracketed ipv6 literal", registered, "[fd00::1]", constants.DomainIPLiteral},
+		{"empty", registered, "", ""},
+		{"levels", levels3, "a.b.eu-west-1.amazonaws.com", "eu-west-1.amazonaws.com"},
+		{"levels short name", levels3, "example.com.", "example.com"},
+		{"levels service", levels3, "web-0.web.prod.svc.cluster.local", "web.prod.svc.cluster.local"},
+		{"levels ip literal", levels3, "10.0.0.1", constants.DomainIPLiteral},
+		{"full", full, "A.b.Example.com.", "a.b.example.com"},
+		{"full service", full, "web-0.web.prod.svc.cluster.local", "web-0.web.prod.svc.cluster.local"},
+		{"full ip literal", full, "10.0.0.1", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.t.Domain(tt.qname); got != tt.want {
+				t.Errorf("Domain(%q) = %q, want %q", tt.qname, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidMode(t *testing.T) {
+	for _, mode := range []string{ModeRegisteredDomain, ModeLevels, ModeFull} {
+		if !ValidMode(mode) {
+			t.Errorf("ValidMode(%q) = false", mode)
+		}
+	}
+	for _, mode := range []string{"", "last_two", "FULL"} {
+		if ValidMode(mode) {
+			t.Errorf("ValidMode(%q) = true", mode)
+		}
+	}
+}
@@ -4,6 +4,7 @@ package event
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
@@ -22,6 +23,9 @@ const (
 	TypeExec                 // Process execution
 	TypeFileIO               // File I/O latency
 	TypeDrop                 // Packet drop
+	TypeSignal               // Fatal signal
+
+	numTypes = iota // number of EventType values; keep last
 )
 
 // String returns the human-readable name of the event type.
@@ -43,20 +47,31 @@ func (t EventType) String() string {
 		return constants.ModuleFileIO
 	case TypeDrop:
 		return constants.ModuleDrop
+	case TypeSignal:
+		return constants.ModuleSignals
 	default:
 		return "unknown"
 	}
 }
 
 // Event is the unified envelope for all eBPF events flowing through KubePulse.
-// Pool-allocated — call Release() when done to avoid GC pressure.
+// Pool-allocated — call Release() when done to avoid GC pressure. Once
+// published, an event belongs to the Bus: each subscriber calls Done when
+// finished with it, and the last one returns it to the pool.
 //
 // Design: structured fields for common attributes + maps for type-specific data.
 // This avoids massive union structs while keeping a single pipeline type.
 type Event struct {
-	Type      EventType
+	Type EventType
+
+	// Timestamp is when the probe captured the event: KernelTime converted
+	// to wall-clock time, not when the event was read off the ring buffer.
 	Timestamp time.Time
 
+	// KernelTime is the probe's bpf_ktime_get_ns() capture time; 0 when
+	// the module does not report one.
+	KernelTime uint64
+
 	// Process identity
 	PID  uint32
 	UID  uint32
@@ -72,29 +87,65 @@ type Event struct {
 
 	// Type-specific numeric values (latency, bytes, scores)
 	Numeric map[string]float64
+
+	refs   int32 // holders of a published event; see Done
+	pooled bool  // came from Acquire; Release returns only these to the pool
 }
 
 // pool is the sync.Pool for Event objects, reducing GC pressure on the hot path.
 var pool = sync.Pool{
 	New: func() any {
+		poolAllocated.Add(1)
 		return &Event{
 			Labels:  make(map[string]string, constants.EventPoolMapCapacity),
 			Numeric: make(map[string]float64, constants.EventPoolMapCapacity),
+			pooled:  true,
 		}
 	},
 }
 
+// poolAcquired and poolAllocated count Acquire calls and the pool misses
+// among them; their ratio shows how often events are reused.
+var poolAcquired, poolAllocated atomic.Uint64
+
 // Acquire retrieves a pre-allocated Event from the pool.
-// The caller must call Release() when done processing the event.
+// The caller must call Release() when done processing the event, or
+// publish it.
 func Acquire() *Event {
+	poolAcquired.Add(1)
 	return pool.Get().(*Event)
 }
 
+// PoolStats returns how many events Acquire has handed out, and how many
+// of those it had to allocate because the pool was empty.
+func PoolStats() (acquired, allocated uint64) {
+	return poolAcquired.Load(), poolAllocated.Load()
+}
+
+// hold adds n holders to a published event.
+func (e *Event) hold(n int32) {
+	atomic.AddInt32(&e.refs, n)
+}
+
+// Done drops a subscriber's hold on an event received from the Bus; the
+// last holder returns it to the pool. The event must not be used after
+// calling Done.
+func (e *Event) Done() {
+	if atomic.AddInt32(&e.refs, -1) == 0 {
+		e.Release()
+	}
+}
+
 // Release returns the Event to the pool after clearing all fields.
-// The event must not be used after calling Release.
+// The event must not be used after calling Release. Events not from
+// Acquire are left alone.
 func (e *Event) Release() {
+	if !e.pooled {
+		return
+	}
 	e.Type = TypeUnknown
 	e.Timestamp = time.Time{}
+	e.KernelTime = 0
 	e.PID = 0
 	e.UID = 0
 	e.Comm = ""
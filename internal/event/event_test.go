@@ -1,6 +1,12 @@
 package event
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
 
 func TestEventType_String(t *testing.T) {
 	tests := []struct {
@@ -15,6 +21,7 @@ func TestEventType_String(t *testing.T) {
 		{TypeExec, "exec"},
 		{TypeFileIO, "fileio"},
 		{TypeDrop, "drop"},
+		{TypeSignal, "signals"},
 		{TypeUnknown, "unknown"},
 	}
 	for _, tt := range tests {
@@ -104,6 +111,22 @@ func TestBus_DropOnOverflow(t *testing.T) {
 	}
 }
 
+func TestBus_PublishedByType(t *testing.T) {
+	bus := NewBus(16, nil)
+	defer bus.Close()
+
+	for _, typ := range []EventType{TypeTCP, TypeTCP, TypeDNS} {
+		e := Acquire()
+		e.Type = typ
+		bus.Publish(e)
+	}
+
+	got := bus.Stats().PublishedByType
+	if got["tcp"] != 2 || got["dns"] != 1 || len(got) != 2 {
+		t.Errorf("PublishedByType = %v, want tcp:2 dns:1", got)
+	}
+}
+
 func TestBus_MultipleSubscribers(t *testing.T) {
 	bus := NewBus(16, nil)
 	defer bus.Close()
@@ -122,6 +145,72 @@ func TestBus_MultipleSubscribers(t *testing.T) {
 	}
 }
 
+func TestBus_ReleasesAfterLastSubscriber(t *testing.T) {
+	bus := NewBus(1, nil)
+	defer bus.Close()
+	ch1 := bus.Subscribe("sub1")
+	ch2 := bus.Subscribe("sub2")
+
+	e := Acquire()
+	e.PID = 42
+	bus.Publish(e)
+	(<-ch1).Done()
+	if e.PID != 42 {
+		t.Fatal("event released while sub2 still holds it")
+	}
+	(<-ch2).Done()
+	if e.PID != 0 {
+		t.Error("event not released after the last subscriber's Done")
+	}
+
+	// sub1 is full, so the event is dropped for it and held by sub2 only.
+	bus.Publish(Acquire())
+	(<-ch2).Done()
+	dropped := Acquire()
+	dropped.PID = 7
+	bus.Publish(dropped)
+	if dropped.PID != 7 {
+		t.Fatal("event released while sub2 holds it")
+	}
+	(<-ch2).Done()
+	if dropped.PID != 0 {
+		t.Error("event dropped for one subscriber not released after the other's Done")
+	}
+}
+
+func TestBus_Intercept(t *testing.T) {
+	bus := NewBus(10, nil)
+	defer bus.Close()
+	ch1 := bus.Subscribe("sub1")
+	ch2 := bus.Subscribe("sub2")
+	bus.Intercept(func(e *Event) { delete(e.Labels, "qname") })
+
+	e := Acquire()
+	e.SetLabel("qname", "secret.example.com")
+	e.SetLabel("qtype", "A")
+	bus.Publish(e)
+	for _, ch := range []<-chan *Event{ch1, ch2} {
+		got := <-ch
+		if _, ok := got.Labels["qname"]; ok || got.Label("qtype") != "A" {
+			t.Errorf("labels = %v, want qtype only", got.Labels)
+		}
+		got.Done()
+	}
+}
+
+func TestEvent_ReleaseIgnoresUnpooled(t *testing.T) {
+	bus := NewBus(1, nil)
+	defer bus.Close()
+	ch := bus.Subscribe("sub")
+
+	e := &Event{Type: TypeTCP, PID: 42}
+	bus.Publish(e)
+	(<-ch).Done()
+	if e.PID != 42 {
+		t.Error("an event not from Acquire was cleared")
+	}
+}
+
 func BenchmarkBus_Publish(b *testing.B) {
 	bus := NewBus(8192, nil)
 	defer bus.Close()
@@ -134,3 +223,70 @@ func BenchmarkBus_Publish(b *testing.B) {
 		bus.Publish(e)
 	}
 }
+
+// BenchmarkBus_PublishExport publishes through two subscribers standing in
+// for exporters. With Done the events are reused; without, every publish
+// allocates an event and its maps.
+func BenchmarkBus_PublishExport(b *testing.B) {
+	for _, done := range []bool{true, false} {
+		name := "done"
+		if !done {
+			name = "no_done"
+		}
+		b.Run(name, func(b *testing.B) {
+			bus := NewBus(8192, nil)
+			var wg sync.WaitGroup
+			for _, sub := range []string{"prometheus", "nats"} {
+				ch := bus.Subscribe(sub)
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					for e := range ch {
+						if done {
+							e.Done()
+						}
+					}
+				}()
+			}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				e := Acquire()
+				e.Type = TypeTCP
+				e.SetLabel("op", "connect")
+				e.SetNumeric("latency_ns", 1000)
+				bus.Publish(e)
+			}
+			bus.Close()
+			wg.Wait()
+		})
+	}
+}
+
+func TestBus_WaitDrained(t *testing.T) {
+	bus := NewBus(16, nil)
+	defer bus.Close()
+	ch := bus.Subscribe("slow")
+	for i := 0; i < 3; i++ {
+		bus.Publish(Acquire())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.WaitDrained(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("WaitDrained with 3 queued events = %v, want the deadline", err)
+	}
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			(<-ch).Done()
+			time.Sleep(time.Millisecond)
+		}
+	}()
+	if err := bus.WaitDrained(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if depth := bus.Stats().QueueDepth["slow"]; depth != 0 {
+		t.Errorf("queue depth after WaitDrained = %d", depth)
+	}
+}
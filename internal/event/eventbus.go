@@ -1,8 +1,10 @@
 package event
 
 import (
+	"context"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"go.uber.org/zap"
 
@@ -23,9 +25,11 @@ type Bus struct {
 	subscribers map[string]chan *Event
 	mu          sync.RWMutex
 	closed      atomic.Bool
+	intercept   func(*Event) // see Intercept
 
 	// Metrics
 	published atomic.Uint64
+	byType    [numTypes]atomic.Uint64
 	dropped   map[string]*atomic.Uint64
 	dropMu    sync.RWMutex
 }
@@ -69,25 +73,44 @@ func (b *Bus) Subscribe(name string) <-chan *Event {
 	return ch
 }
 
-// Publish sends an event to all subscribers.
+// Intercept makes Publish pass every event to fn before any subscriber
+// sees it, e.g. to redact labels. fn may modify the event. Must be called
+// before the first Publish.
+func (b *Bus) Intercept(fn func(*Event)) {
+	b.intercept = fn
+}
+
+// Publish sends an event to all subscribers, taking ownership of it.
 // Non-blocking: if a subscriber's buffer is full, the event is dropped
-// for that subscriber and a drop counter is incremented.
+// for that subscriber and a drop counter is incremented. The event returns
+// to the pool once every subscriber that received it has called Done.
 func (b *Bus) Publish(e *Event) {
 	if b.closed.Load() {
+		e.Release()
 		return
 	}
 
+	if b.intercept != nil {
+		b.intercept(e)
+	}
 	b.published.Add(1)
+	if e.Type < numTypes {
+		b.byType[e.Type].Add(1)
+	}
 
 	b.mu.RLock()
 	defer b.mu.RUnlock()
 
+	// One hold per subscriber, plus Publish's own until the loop is done,
+	// so a fast subscriber's Done cannot release the event mid-loop.
+	e.hold(int32(len(b.subscribers)) + 1)
 	for name, ch := range b.subscribers {
 		select {
 		case ch <- e:
 			// delivered
 		default:
 			// subscriber buffer full — drop
+			e.Done()
 			b.dropMu.RLock()
 			if counter, ok := b.dropped[name]; ok {
 				counter.Add(1)
@@ -95,6 +118,36 @@ func (b *Bus) Publish(e *Event) {
 			b.dropMu.RUnlock()
 		}
 	}
+	e.Done()
+}
+
+// WaitDrained blocks until every subscriber has taken every event queued
+// for it, or ctx is done, when it returns ctx's error. At shutdown, call
+// it once publishers have stopped and before Close, so subscribers see the
+// whole stream before their channels close.
+func (b *Bus) WaitDrained(ctx context.Context) error {
+	ticker := time.NewTicker(constants.EventBusDrainPoll)
+	defer ticker.Stop()
+	for !b.drained() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// drained reports whether every subscriber queue is empty.
+func (b *Bus) drained() bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, ch := range b.subscribers {
+		if len(ch) > 0 {
+			return false
+		}
+	}
+	return true
 }
 
 // Close stops the bus and closes all subscriber channels.
@@ -116,6 +169,7 @@ func (b *Bus) Close() {
 // Stats holds a snapshot of bus metrics.
 type Stats struct {
 	Published           uint64
+	PublishedByType     map[string]uint64 // by EventType.String(); types never published are omitted
 	DroppedBySubscriber map[string]uint64
 	QueueDepth          map[string]int
 }
@@ -124,10 +178,17 @@ type Stats struct {
 func (b *Bus) Stats() Stats {
 	s := Stats{
 		Published:           b.published.Load(),
+		PublishedByType:     make(map[string]uint64),
 		DroppedBySubscriber: make(map[string]uint64),
 		QueueDepth:          make(map[string]int),
 	}
 
+	for t := range b.byType {
+		if n := b.byType[t].Load(); n > 0 {
+			s.PublishedByType[EventType(t).String()] = n
+		}
+	}
+
 	b.mu.RLock()
 	for name, ch := range b.subscribers {
 		s.QueueDepth[name] = len(ch)
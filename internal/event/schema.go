@@ -0,0 +1,205 @@
+package event
+
+import (
+	"slices"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Field describes one label or numeric key of an event type.
+type Field struct {
+	Key         string `json:"key"`
+	Required    bool   `json:"required"` // on every event of the type, unless redacted
+	Description string `json:"description"`
+}
+
+// Schema lists the label and numeric keys events of one type carry, on
+// top of the common fields (pid, comm, node, namespace, pod, ...).
+// Consumers read it from the API's /api/v1/schema rather than from the
+// modules' code.
+type Schema struct {
+	Type     string  `json:"type"`
+	Labels   []Field `json:"labels"`
+	Numerics []Field `json:"numerics"`
+}
+
+func required(key, description string) Field { return Field{key, true, description} }
+func optional(key, description string) Field { return Field{key, false, description} }
+
+// commonLabels are the labels any event type may carry.
+var commonLabels = []Field{
+	optional(constants.KeyEnrichment, "how the PID resolved to a pod: ok, miss, host or error"),
+}
+
+// endpointLabels are the labels of the TCP modules' connection endpoints.
+var endpointLabels = []Field{
+	required(constants.KeySrc, "source ip:port"),
+	required(constants.KeyDst, "destination ip:port"),
+	optional(constants.KeyDstService, "namespace/name of the Service the destination IP belongs to"),
+}
+
+// schemas is the registry, indexed by EventType. Module tests check what
+// they publish against it (probetest.CheckSchema), so it can't drift from
+// what the modules emit.
+var schemas = [numTypes]Schema{
+	TypeTCP: {
+		Labels: append(slices.Clone(endpointLabels),
+			required(constants.KeyRole, "client for outbound connects, server for accepted connections"),
+			optional(constants.KeyOutcome, "client connects: success or failure"),
+			optional(constants.KeyError, "failed connects: why, e.g. refused or timeout"),
+		),
+		Numerics: []Field{
+			optional(constants.KeyLatencySec, "successful connects: time to establish, in seconds"),
+			optional(constants.KeyLatencyNs, "successful connects: time to establish, in nanoseconds"),
+			optional(constants.KeyAcceptLatencySec, "server connections: time spent in the accept queue, in seconds"),
+		},
+	},
+	TypeDNS: {
+		Labels: []Field{
+			required(constants.KeyQName, "query name"),
+			required(constants.KeyDomain, "registered domain of the query name"),
+			required(constants.KeyQType, "question type: A, AAAA, SRV, PTR or other"),
+			required(constants.KeyTransport, "udp or tcp"),
+		},
+	},
+	TypeRetransmit: {
+		Labels: slices.Clone(endpointLabels),
+		Numerics: []Field{
+			required(constants.KeyDelta, "retransmits since the flow's previous event"),
+			optional(constants.KeyCount, "the flow's retransmits so far"),
+		},
+	},
+	TypeRST: {
+		Labels: append(slices.Clone(endpointLabels),
+			required(constants.KeyDirection, "sent or received"),
+			required(constants.KeyState, "TCP state of the socket, e.g. ESTABLISHED"),
+		),
+	},
+	TypeOOM: {
+		Labels: []Field{
+			optional(constants.KeyContainer, "container name within the pod"),
+		},
+		Numerics: []Field{
+			required(constants.KeyTotalVMKB, "total virtual memory of the killed process, in KiB"),
+			required(constants.KeyOOMScoreAdj, "oom_score_adj of the killed process"),
+			optional(constants.KeyMemoryLimitBytes, "the container's memory limit, if it has one"),
+			optional(constants.KeyUsagePct, "resident memory as a percentage of the limit"),
+		},
+	},
+	TypeExec: {
+		Labels: []Field{
+			required(constants.KeyFilename, "executed file"),
+			optional(constants.KeyParentComm, "process name of the parent"),
+		},
+		Numerics: []Field{
+			optional(constants.KeyPPID, "PID of the parent"),
+			optional(constants.KeyCount, "execs this event stands for, with modules.exec.aggregate_window"),
+		},
+	},
+	TypeFileIO: {
+		Labels: []Field{
+			required(constants.KeyOp, "read or write"),
+			required(constants.KeyFS, "file system type, e.g. ext4"),
+			optional(constants.KeyPath, "last components of the file's path"),
+		},
+		Numerics: []Field{
+			required(constants.KeyLatencySec, "time the call took, in seconds"),
+			required(constants.KeyBytes, "bytes read or written"),
+		},
+	},
+	TypeDrop: {
+		Labels: []Field{
+			required(constants.KeyReason, "kernel drop reason, e.g. NO_SOCKET"),
+			required(constants.KeyProtocol, "tcp, udp, icmp or other"),
+			optional(constants.KeyLocation, "kernel function the packet was dropped in"),
+			optional(constants.KeySrc, "source address, ip:port for TCP and UDP"),
+			optional(constants.KeyDst, "destination address, ip:port for TCP and UDP"),
+		},
+	},
+	TypeSignal: {
+		Labels: []Field{
+			required(constants.KeySignal, "signal name, e.g. SIGKILL"),
+			required(constants.KeySenderComm, "process name of the sender"),
+			optional(constants.KeySenderNamespace, "the sender's pod namespace, if it has a pod"),
+			optional(constants.KeySenderPod, "the sender's pod name, if it has a pod"),
+		},
+		Numerics: []Field{
+			required(constants.KeySenderPID, "PID of the sender"),
+		},
+	},
+}
+
+// schemasByName indexes schemas by type name.
+var schemasByName = make(map[string]*Schema, numTypes)
+
+func init() {
+	for t := TypeUnknown + 1; t < numTypes; t++ {
+		s := &schemas[t]
+		s.Type = t.String()
+		s.Labels = append(s.Labels, commonLabels...)
+		if s.Numerics == nil {
+			s.Numerics = []Field{}
+		}
+		schemasByName[s.Type] = s
+	}
+}
+
+// Schemas returns the schema of every event type, in EventType order.
+func Schemas() []Schema {
+	return slices.Clone(schemas[TypeUnknown+1:])
+}
+
+// SchemaOf returns the schema of the event type named typ, and false for
+// a type the agent doesn't emit.
+func SchemaOf(typ string) (Schema, bool) {
+	s, ok := schemasByName[typ]
+	if !ok {
+		return Schema{}, false
+	}
+	return *s, true
+}
+
+// HasLabel reports whether events of the type can carry label key.
+func (s Schema) HasLabel(key string) bool {
+	return slices.ContainsFunc(s.Labels, func(f Field) bool { return f.Key == key })
+}
+
+// HasNumeric reports whether events of the type can carry numeric key.
+func (s Schema) HasNumeric(key string) bool {
+	return slices.ContainsFunc(s.Numerics, func(f Field) bool { return f.Key == key })
+}
+
+// Missing returns the required keys absent from labels and numerics,
+// labels first, or nil if there are none.
+func (s Schema) Missing(labels map[string]string, numerics map[string]float64) []string {
+	var missing []string
+	for _, f := range s.Labels {
+		if _, ok := labels[f.Key]; f.Required && !ok {
+			missing = append(missing, f.Key)
+		}
+	}
+	for _, f := range s.Numerics {
+		if _, ok := numerics[f.Key]; f.Required && !ok {
+			missing = append(missing, f.Key)
+		}
+	}
+	return missing
+}
+
+// Unknown returns the keys of labels and numerics the schema doesn't
+// list, sorted, or nil if there are none.
+func (s Schema) Unknown(labels map[string]string, numerics map[string]float64) []string {
+	var unknown []string
+	for k := range labels {
+		if !s.HasLabel(k) {
+			unknown = append(unknown, k)
+		}
+	}
+	for k := range numerics {
+		if !s.HasNumeric(k) {
+			unknown = append(unknown, k)
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
@@ -0,0 +1,51 @@
+package event
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSchemas(t *testing.T) {
+	all := Schemas()
+	if len(all) != int(numTypes)-1 {
+		t.Fatalf("%d schemas, want one per event type (%d)", len(all), numTypes-1)
+	}
+	for typ := TypeUnknown + 1; typ < numTypes; typ++ {
+		s, ok := SchemaOf(typ.String())
+		if !ok {
+			t.Errorf("no schema for %s", typ)
+			continue
+		}
+		if !s.HasLabel("enrichment") {
+			t.Errorf("%s schema lacks the common enrichment label", typ)
+		}
+		if s.Numerics == nil {
+			t.Errorf("%s schema has nil numerics, which encode as null", typ)
+		}
+	}
+	if _, ok := SchemaOf("unknown"); ok {
+		t.Error("unknown has a schema")
+	}
+	if _, ok := SchemaOf("bogus"); ok {
+		t.Error("bogus has a schema")
+	}
+}
+
+func TestSchema_MissingUnknown(t *testing.T) {
+	s, _ := SchemaOf("dns")
+	labels := map[string]string{"qname": "api.example.com.", "qtype": "A", "src": "10.0.0.1:53"}
+	if got, want := s.Missing(labels, nil), []string{"domain", "transport"}; !slices.Equal(got, want) {
+		t.Errorf("Missing = %v, want %v", got, want)
+	}
+	if got, want := s.Unknown(labels, map[string]float64{"latency_sec": 1}), []string{"latency_sec", "src"}; !slices.Equal(got, want) {
+		t.Errorf("Unknown = %v, want %v", got, want)
+	}
+
+	s, _ = SchemaOf("retransmit")
+	if got, want := s.Missing(map[string]string{"src": "a", "dst": "b"}, nil), []string{"delta"}; !slices.Equal(got, want) {
+		t.Errorf("Missing = %v, want %v", got, want)
+	}
+	if got := s.Missing(map[string]string{"src": "a", "dst": "b"}, map[string]float64{"delta": 1}); got != nil {
+		t.Errorf("Missing = %v for a complete event", got)
+	}
+}
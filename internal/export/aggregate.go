@@ -0,0 +1,251 @@
+package export
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/dnsname"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+)
+
+// aggregator turns events into the event metrics: latency histograms and
+// counters by pod, relabeled and bounded as PrometheusOptions set. The
+// Prometheus exporter serves them and the remote write exporter pushes
+// them, each from an aggregator of its own.
+//
+// Not safe for concurrent use, but its metrics may be gathered at any
+// time; the exporter calls it from its event loop.
+type aggregator struct {
+	logger *zap.Logger
+	guard  *podGuard
+	pods   *metadata.Cache   // live pods for the stale-series sweep; see WatchPods
+	domain dnsname.Truncator // for the DNS domain label
+	qnames bool              // events carry their query name; see QNamesRedacted
+	drops  bool              // packet drops have namespace and pod labels; see DropPodLabels
+
+	// Network metrics
+	tcpLatency      relabeled[prometheus.Observer]
+	tcpConnectFails relabeled[prometheus.Counter]
+	tcpAcceptLat    relabeled[prometheus.Observer]
+	dnsQueries      relabeled[prometheus.Counter]
+	dnsLatency      relabeled[prometheus.Observer]
+	retransmits     relabeled[prometheus.Counter]
+	tcpResets       relabeled[prometheus.Counter]
+	packetDrops     relabeled[prometheus.Counter]
+
+	// System metrics
+	oomKills      relabeled[prometheus.Counter]
+	processExecs  relabeled[prometheus.Counter]
+	fileIOLatency relabeled[prometheus.Observer]
+	fileIOOps     relabeled[prometheus.Counter]
+	signals       relabeled[prometheus.Counter]
+
+	// Self-observability metrics
+	eventsProcessed *prometheus.CounterVec
+	podOverflow     *prometheus.CounterVec
+}
+
+// newAggregator registers the event metrics with factory's registry.
+func newAggregator(opts PrometheusOptions, factory promauto.Factory, logger *zap.Logger) *aggregator {
+	labels, err := opts.relabeler()
+	if err != nil {
+		// Config validation rejects these at startup; only a caller
+		// skipping it gets here.
+		logger.Error("Ignoring invalid relabel rules", zap.Error(err))
+		labels, _ = PrometheusOptions{DropPodLabels: opts.DropPodLabels}.relabeler()
+	}
+	// Event metrics take their labels from eventMetricLabels, relabeled.
+	counter := func(name, help string) relabeled[prometheus.Counter] {
+		m := labels[name]
+		return relabeled[prometheus.Counter]{factory.NewCounterVec(prometheus.CounterOpts{Name: name, Help: help}, m.names), m}
+	}
+	histogram := func(ho prometheus.HistogramOpts) relabeled[prometheus.Observer] {
+		m := labels[ho.Name]
+		return relabeled[prometheus.Observer]{factory.NewHistogramVec(ho, m.names), m}
+	}
+	a := &aggregator{
+		logger: logger,
+		domain: opts.DNSDomain(),
+		qnames: true,
+		drops:  opts.DropPodLabels,
+
+		// --- Network Metrics ---
+		tcpLatency: histogram(opts.latency(
+			constants.MetricTCPLatency, "TCP connection latency.", constants.NetworkLatencyBuckets)),
+
+		dnsQueries: counter(constants.MetricDNSQueries, "Total DNS queries observed, by question type."),
+
+		dnsLatency: histogram(opts.latency(
+			constants.MetricDNSLatency, "DNS query latency.", constants.NetworkLatencyBuckets)),
+
+		retransmits: counter(constants.MetricTCPRetransmits, "Total TCP retransmissions."),
+
+		tcpConnectFails: counter(constants.MetricTCPConnectFailures,
+			"Total TCP connects that never completed their handshake, by cause."),
+
+		tcpAcceptLat: histogram(opts.latency(
+			constants.MetricTCPAcceptLatency, "Time accepted TCP connections waited in the accept queue.", constants.NetworkLatencyBuckets)),
+
+		tcpResets: counter(constants.MetricTCPResets,
+			"Total TCP connection resets, by whether a local socket sent or received them and the socket's TCP state."),
+
+		packetDrops: counter(constants.MetricPacketDrops, "Total packets dropped by kernel."),
+
+		// --- System Metrics ---
+		oomKills: counter(constants.MetricOOMKills, "Total OOM kill events."),
+
+		processExecs: counter(constants.MetricProcessExecs, "Total process executions."),
+
+		fileIOLatency: histogram(opts.latency(
+			constants.MetricFileIOLatency, "Latency of file reads and writes at or above modules.fileio.min_latency.", constants.IOLatencyBuckets)),
+
+		fileIOOps: counter(constants.MetricFileIOOps, "File reads and writes at or above modules.fileio.min_latency."),
+
+		signals: counter(constants.MetricSignals, "Total fatal signals sent, by the receiving pod and signal."),
+
+		// --- Self-Observability ---
+		eventsProcessed: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: constants.MetricEventsProcessed,
+			Help: "Total events processed by exporter.",
+		}, constants.LabelsModule),
+
+		podOverflow: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: constants.MetricPodOverflow,
+			Help: "Total observations recorded under the overflow pod because the metric hit its pod limit.",
+		}, constants.LabelsMetric),
+	}
+
+	a.guard = newPodGuard(opts.CardinalityConfig, a.podOverflow, logger)
+	guard := func(metric string, series podSeries) {
+		// Metrics relabeled without a pod label need no bound.
+		if labels[metric].kept(constants.LabelPod) {
+			a.guard.register(metric, series)
+		}
+	}
+	guard(constants.MetricTCPLatency, a.tcpLatency)
+	guard(constants.MetricTCPConnectFailures, a.tcpConnectFails)
+	guard(constants.MetricTCPAcceptLatency, a.tcpAcceptLat)
+	guard(constants.MetricDNSQueries, a.dnsQueries)
+	guard(constants.MetricDNSLatency, a.dnsLatency)
+	guard(constants.MetricTCPRetransmits, a.retransmits)
+	guard(constants.MetricTCPResets, a.tcpResets)
+	guard(constants.MetricOOMKills, a.oomKills)
+	guard(constants.MetricProcessExecs, a.processExecs)
+	guard(constants.MetricFileIOLatency, a.fileIOLatency)
+	guard(constants.MetricFileIOOps, a.fileIOOps)
+	guard(constants.MetricSignals, a.signals)
+	guard(constants.MetricPacketDrops, a.packetDrops)
+	return a
+}
+
+// QNamesRedacted tells the exporter that events' qname labels are dropped
+// or hashed, so it reports DNS events' own domain label instead of
+// deriving one by dns_domain_mode. Must be called before Start.
+func (a *aggregator) QNamesRedacted() {
+	a.qnames = false
+}
+
+// WatchPods enables the periodic sweep deleting series of pods that have
+// left the metadata cache. Must be called before Start.
+func (a *aggregator) WatchPods(cache *metadata.Cache) {
+	a.pods = cache
+}
+
+// sweepTicker returns the channel the stale-pod sweep runs on, nil without
+// WatchPods, and a function stopping it.
+func (a *aggregator) sweepTicker() (<-chan time.Time, func()) {
+	if a.pods == nil {
+		return nil, func() {}
+	}
+	ticker := time.NewTicker(constants.CardinalitySweepInterval)
+	return ticker.C, ticker.Stop
+}
+
+// sweep deletes the series of pods gone from the metadata cache for
+// StalePodTTL.
+func (a *aggregator) sweep(now time.Time) {
+	if n := a.guard.sweep(a.pods.Pods(), now); n > 0 {
+		a.logger.Debug("Deleted series of departed pods", zap.Int("pods", n))
+	}
+}
+
+// processEvent dispatches an event to the correct Prometheus metric.
+// Uses constants for event label/numeric keys — Strategy pattern for dispatch.
+func (a *aggregator) processEvent(e *event.Event) {
+	a.eventsProcessed.WithLabelValues(e.Type.String()).Inc()
+
+	pod := func(metric string) string { return a.guard.pod(metric, e.Namespace, e.Pod) }
+
+	switch e.Type {
+	case event.TypeTCP:
+		if e.Label(constants.KeyRole) == constants.RoleServer {
+			a.tcpAcceptLat.WithLabelValues(e.Namespace, pod(constants.MetricTCPAcceptLatency), e.Node).
+				Observe(e.NumericVal(constants.KeyAcceptLatencySec))
+			return
+		}
+		if e.Label(constants.KeyOutcome) == constants.OutcomeFailure {
+			a.tcpConnectFails.WithLabelValues(e.Namespace, pod(constants.MetricTCPConnectFailures), e.Label(constants.KeyError), e.Node).Inc()
+			return
+		}
+		a.tcpLatency.WithLabelValues(e.Namespace, pod(constants.MetricTCPLatency), e.Node).
+			Observe(e.NumericVal(constants.KeyLatencySec))
+
+	case event.TypeDNS:
+		a.dnsQueries.WithLabelValues(e.Namespace, pod(constants.MetricDNSQueries), a.dnsDomain(e), e.Label(constants.KeyQType), e.Node).Inc()
+		if latency := e.NumericVal(constants.KeyLatencySec); latency > 0 {
+			a.dnsLatency.WithLabelValues(e.Namespace, pod(constants.MetricDNSLatency), e.Node).Observe(latency)
+		}
+
+	case event.TypeRetransmit:
+		// An event stands for the retransmits since its flow's last one.
+		n := e.NumericVal(constants.KeyDelta)
+		if n == 0 {
+			n = 1
+		}
+		a.retransmits.WithLabelValues(e.Namespace, pod(constants.MetricTCPRetransmits), e.Node).Add(n)
+
+	case event.TypeRST:
+		a.tcpResets.WithLabelValues(e.Namespace, pod(constants.MetricTCPResets), e.Label(constants.KeyDirection), e.Label(constants.KeyState), e.Node).Inc()
+
+	case event.TypeOOM:
+		a.oomKills.WithLabelValues(e.Namespace, pod(constants.MetricOOMKills), e.Node).Inc()
+
+	case event.TypeExec:
+		// Coalesced events stand for several execs.
+		n := e.NumericVal(constants.KeyCount)
+		if n == 0 {
+			n = 1
+		}
+		a.processExecs.WithLabelValues(e.Namespace, pod(constants.MetricProcessExecs), e.Node).Add(n)
+
+	case event.TypeFileIO:
+		op, fs := e.Label(constants.KeyOp), e.Label(constants.KeyFS)
+		a.fileIOLatency.WithLabelValues(e.Namespace, pod(constants.MetricFileIOLatency), op, fs, e.Node).
+			Observe(e.NumericVal(constants.KeyLatencySec))
+		a.fileIOOps.WithLabelValues(e.Namespace, pod(constants.MetricFileIOOps), op, fs, e.Node).Inc()
+
+	case event.TypeDrop:
+		reason, protocol := e.Label(constants.KeyReason), e.Label(constants.KeyProtocol)
+		if a.drops {
+			a.packetDrops.WithLabelValues(e.Namespace, pod(constants.MetricPacketDrops), reason, protocol, e.Node).Inc()
+			return
+		}
+		a.packetDrops.WithLabelValues(reason, protocol, e.Node).Inc()
+
+	case event.TypeSignal:
+		a.signals.WithLabelValues(e.Namespace, pod(constants.MetricSignals), e.Label(constants.KeySignal), e.Node).Inc()
+	}
+}
+
+// dnsDomain returns the domain label for a DNS event.
+func (a *aggregator) dnsDomain(e *event.Event) string {
+	if !a.qnames {
+		return e.Label(constants.KeyDomain)
+	}
+	return a.domain.Domain(e.Label(constants.KeyQName))
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"github.com/cilium/ebpf"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+)
+
+// bpfStats exports the kernel resources the modules' BPF objects use:
+// program run time and run count (needs Linux 5.8 and BPF stats enabled,
+// see Runtime.Run), map capacity, and current entries for hash maps.
+// Kernels without the stats API simply yield no program metrics.
+type bpfStats struct {
+	logger *zap.Logger
+
+	progRuntime *prometheus.CounterVec // module, prog
+	progRuns    *prometheus.CounterVec // module, prog
+	mapMax      *prometheus.GaugeVec   // module, map
+	mapEntries  *prometheus.GaugeVec   // module, map
+
+	last        map[[2]string]ebpf.ProgramStats
+	statsFailed bool // logged once
+}
+
+func newBPFStats(progRuntime, progRuns *prometheus.CounterVec, mapMax, mapEntries *prometheus.GaugeVec, logger *zap.Logger) *bpfStats {
+	return &bpfStats{
+		logger:      logger,
+		progRuntime: progRuntime,
+		progRuns:    progRuns,
+		mapMax:      mapMax,
+		mapEntries:  mapEntries,
+		last:        make(map[[2]string]ebpf.ProgramStats),
+	}
+}
+
+// collect reads every module's programs and maps once.
+func (b *bpfStats) collect(modules map[string]*bpfutil.Resources) {
+	for module, res := range modules {
+		if res == nil {
+			continue
+		}
+		for name, prog := range res.Programs {
+			b.collectProgram(module, name, prog)
+		}
+		for name, m := range res.Maps {
+			b.mapMax.WithLabelValues(module, name).Set(float64(m.MaxEntries()))
+			if n, ok := countEntries(m); ok {
+				b.mapEntries.WithLabelValues(module, name).Set(float64(n))
+			}
+		}
+	}
+}
+
+func (b *bpfStats) collectProgram(module, name string, prog *ebpf.Program) {
+	stats, err := prog.Stats()
+	if err != nil {
+		if !b.statsFailed {
+			b.statsFailed = true
+			b.logger.Info("BPF program stats unavailable — skipping program metrics", zap.Error(err))
+		}
+		return
+	}
+	key := [2]string{module, name}
+	prev := b.last[key]
+	if stats.Runtime >= prev.Runtime {
+		b.progRuntime.WithLabelValues(module, name).Add((stats.Runtime - prev.Runtime).Seconds())
+	}
+	if stats.RunCount >= prev.RunCount {
+		b.progRuns.WithLabelValues(module, name).Add(float64(stats.RunCount - prev.RunCount))
+	}
+	b.last[key] = *stats
+}
+
+// countEntries walks a hash map's keys. Other map types have no notion of
+// current entries (arrays are always full, ring buffers hold bytes).
+// The walk is capped at MaxEntries so concurrent churn can't loop forever.
+func countEntries(m *ebpf.Map) (int, bool) {
+	switch m.Type() {
+	case ebpf.Hash, ebpf.LRUHash, ebpf.PerCPUHash, ebpf.LRUCPUHash:
+	default:
+		return 0, false
+	}
+	var key any
+	n := 0
+	for n < int(m.MaxEntries()) {
+		next, err := m.NextKeyBytes(key)
+		if err != nil {
+			return 0, false
+		}
+		if next == nil {
+			break
+		}
+		key = next
+		n++
+	}
+	return n, true
+}
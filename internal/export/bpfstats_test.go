@@ -0,0 +1,103 @@
+package export
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func newTestBPFStats() *bpfStats {
+	return newBPFStats(
+		prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_prog_runtime"}, constants.LabelsModuleProg),
+		prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_prog_runs"}, constants.LabelsModuleProg),
+		prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_map_max"}, constants.LabelsModuleMap),
+		prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_map_entries"}, constants.LabelsModuleMap),
+		zap.NewNop(),
+	)
+}
+
+func testResources(t *testing.T) *bpfutil.Resources {
+	t.Helper()
+	if os.Geteuid() != 0 {
+		t.Skip("creating BPF objects requires root")
+	}
+	conns, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.Hash, KeySize: 4, ValueSize: 8, MaxEntries: 64})
+	if err != nil {
+		t.Skipf("BPF unavailable: %v", err)
+	}
+	t.Cleanup(func() { conns.Close() })
+	for k := range uint32(5) {
+		if err := conns.Put(k, uint64(k)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	events, err := ebpf.NewMap(&ebpf.MapSpec{Type: ebpf.RingBuf, MaxEntries: 4096})
+	if err != nil {
+		t.Skipf("BPF ring buffers unavailable: %v", err)
+	}
+	t.Cleanup(func() { events.Close() })
+	prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+		Type:         ebpf.SocketFilter,
+		License:      "GPL",
+		Instructions: asm.Instructions{asm.Mov.Imm(asm.R0, 0), asm.Return()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { prog.Close() })
+
+	return &bpfutil.Resources{
+		Programs: map[string]*ebpf.Program{"handle": prog},
+		Maps:     map[string]*ebpf.Map{"conns": conns, "events": events},
+	}
+}
+
+func TestBPFStats_Maps(t *testing.T) {
+	res := testResources(t)
+	b := newTestBPFStats()
+	b.collect(map[string]*bpfutil.Resources{"test": res, "failed": nil})
+
+	if got := testutil.ToFloat64(b.mapMax.WithLabelValues("test", "conns")); got != 64 {
+		t.Errorf("conns max entries = %v, want 64", got)
+	}
+	if got := testutil.ToFloat64(b.mapEntries.WithLabelValues("test", "conns")); got != 5 {
+		t.Errorf("conns entries = %v, want 5", got)
+	}
+	if got := testutil.CollectAndCount(b.mapEntries); got != 1 {
+		t.Errorf("entries series = %d, want only the hash map", got)
+	}
+}
+
+func TestBPFStats_Programs(t *testing.T) {
+	res := testResources(t)
+	stats, err := ebpf.EnableStats(uint32(unix.BPF_STATS_RUN_TIME))
+	if err != nil {
+		t.Skipf("BPF stats unavailable: %v", err)
+	}
+	defer stats.Close()
+
+	b := newTestBPFStats()
+	b.collect(map[string]*bpfutil.Resources{"test": res})
+	for range 10 {
+		if _, err := res.Programs["handle"].Run(&ebpf.RunOptions{Data: make([]byte, 14)}); err != nil {
+			t.Skipf("BPF_PROG_TEST_RUN unavailable: %v", err)
+		}
+	}
+	b.collect(map[string]*bpfutil.Resources{"test": res})
+
+	if got := testutil.ToFloat64(b.progRuns.WithLabelValues("test", "handle")); got != 10 {
+		t.Errorf("prog runs = %v, want 10", got)
+	}
+	if got := testutil.ToFloat64(b.progRuntime.WithLabelValues("test", "handle")); got <= 0 {
+		t.Errorf("prog runtime = %v, want > 0", got)
+	}
+}
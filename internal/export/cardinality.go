@@ -0,0 +1,142 @@
+package export
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+)
+
+// CardinalityConfig bounds the pod label values the Prometheus exporter
+// keeps, so pod churn can't grow the agent's or Prometheus' memory without
+// limit.
+type CardinalityConfig struct {
+	MaxPodsPerMetric int           `yaml:"max_pods_per_metric"`
+	StalePodTTL      time.Duration `yaml:"stale_pod_ttl"`
+}
+
+// DefaultCardinalityConfig returns the default limits.
+func DefaultCardinalityConfig() CardinalityConfig {
+	return CardinalityConfig{
+		MaxPodsPerMetric: constants.DefaultMaxPodsPerMetric,
+		StalePodTTL:      constants.DefaultStalePodTTL,
+	}
+}
+
+// podSeries is the part of a metric vector the guard needs.
+type podSeries interface {
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// podGuard tracks the distinct (namespace, pod) pairs each metric family
+// has series for. Past MaxPodsPerMetric, new pods are reported as
+// constants.OverflowPod; sweep deletes the series of pods that have been
+// gone from the metadata cache for StalePodTTL, freeing their slots.
+//
+// Not safe for concurrent use; the exporter calls it from its event loop.
+type podGuard struct {
+	cfg      CardinalityConfig
+	logger   *zap.Logger
+	overflow *prometheus.CounterVec // by metric
+	families map[string]*podFamily
+	absent   map[metadata.PodKey]time.Time // first sweep each tracked pod was missing
+}
+
+type podFamily struct {
+	series podSeries
+	pods   map[metadata.PodKey]struct{}
+	full   bool // limit reached; logged once until a sweep frees a slot
+}
+
+func newPodGuard(cfg CardinalityConfig, overflow *prometheus.CounterVec, logger *zap.Logger) *podGuard {
+	return &podGuard{
+		cfg:      cfg,
+		logger:   logger,
+		overflow: overflow,
+		families: make(map[string]*podFamily),
+		absent:   make(map[metadata.PodKey]time.Time),
+	}
+}
+
+// register adds a metric family whose series carry namespace and pod labels.
+func (g *podGuard) register(metric string, series podSeries) {
+	g.families[metric] = &podFamily{series: series, pods: make(map[metadata.PodKey]struct{})}
+}
+
+// pod returns the pod label value to record for metric: pod itself, or
+// constants.OverflowPod once metric is at its limit. Events without pod
+// metadata pass through.
+func (g *podGuard) pod(metric, namespace, pod string) string {
+	f := g.families[metric]
+	if pod == "" || f == nil {
+		return pod
+	}
+	key := metadata.PodKey{Namespace: namespace, Name: pod}
+	if _, ok := f.pods[key]; ok {
+		return pod
+	}
+	if len(f.pods) < g.cfg.MaxPodsPerMetric {
+		f.pods[key] = struct{}{}
+		return pod
+	}
+	g.overflow.WithLabelValues(metric).Inc()
+	if !f.full {
+		f.full = true
+		g.logger.Warn("Pod label limit reached — new pods share the overflow series",
+			zap.String("metric", metric),
+			zap.Int("limit", g.cfg.MaxPodsPerMetric),
+			zap.String("pod", constants.OverflowPod))
+	}
+	return constants.OverflowPod
+}
+
+// sweep deletes the series of tracked pods that have been missing from
+// live for at least StalePodTTL, and returns how many pods it removed.
+func (g *podGuard) sweep(live map[metadata.PodKey]struct{}, now time.Time) int {
+	tracked := make(map[metadata.PodKey]struct{})
+	for _, f := range g.families {
+		for key := range f.pods {
+			tracked[key] = struct{}{}
+		}
+	}
+	for key := range g.absent {
+		if _, ok := tracked[key]; !ok {
+			delete(g.absent, key)
+		}
+	}
+
+	removed := 0
+	for key := range tracked {
+		if _, ok := live[key]; ok {
+			delete(g.absent, key)
+			continue
+		}
+		since, ok := g.absent[key]
+		if !ok {
+			g.absent[key] = now
+			continue
+		}
+		if now.Sub(since) < g.cfg.StalePodTTL {
+			continue
+		}
+		labels := prometheus.Labels{constants.LabelNamespace: key.Namespace, constants.LabelPod: key.Name}
+		for _, f := range g.families {
+			if _, ok := f.pods[key]; ok {
+				f.series.DeletePartialMatch(labels)
+				delete(f.pods, key)
+			}
+		}
+		delete(g.absent, key)
+		removed++
+	}
+
+	for _, f := range g.families {
+		if f.full && len(f.pods) < g.cfg.MaxPodsPerMetric {
+			f.full = false
+		}
+	}
+	return removed
+}
@@ -0,0 +1,103 @@
+package export
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+)
+
+const testMetric = "test_execs_total"
+
+func newTestGuard(limit int, ttl time.Duration) (*podGuard, *prometheus.CounterVec, *prometheus.CounterVec) {
+	series := prometheus.NewCounterVec(prometheus.CounterOpts{Name: testMetric}, constants.LabelsNamespacePodNode)
+	overflow := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_overflow_total"}, constants.LabelsMetric)
+	g := newPodGuard(CardinalityConfig{MaxPodsPerMetric: limit, StalePodTTL: ttl}, overflow, zap.NewNop())
+	g.register(testMetric, series)
+	return g, series, overflow
+}
+
+func record(g *podGuard, series *prometheus.CounterVec, namespace, pod string) {
+	series.WithLabelValues(namespace, g.pod(testMetric, namespace, pod), "node-1").Inc()
+}
+
+func TestPodGuard_OverflowPastLimit(t *testing.T) {
+	g, series, overflow := newTestGuard(3, time.Minute)
+	for i := range 10 {
+		record(g, series, "default", fmt.Sprintf("web-%d", i))
+	}
+	record(g, series, "default", "web-0") // tracked pods keep their own series
+	record(g, series, "", "")             // host processes are never collapsed
+
+	if got := testutil.CollectAndCount(series); got != 5 {
+		t.Errorf("series = %d, want 3 pods + overflow + host", got)
+	}
+	if got := testutil.ToFloat64(series.WithLabelValues("default", constants.OverflowPod, "node-1")); got != 7 {
+		t.Errorf("overflow series = %v, want 7", got)
+	}
+	if got := testutil.ToFloat64(overflow.WithLabelValues(testMetric)); got != 7 {
+		t.Errorf("overflow counter = %v, want 7", got)
+	}
+	if got := testutil.ToFloat64(series.WithLabelValues("default", "web-0", "node-1")); got != 2 {
+		t.Errorf("web-0 = %v, want 2", got)
+	}
+}
+
+func TestPodGuard_ChurnStaysBounded(t *testing.T) {
+	const limit, perMinute = 50, 40
+	ttl := 5 * time.Minute
+	g, series, _ := newTestGuard(limit, ttl)
+
+	// Each minute a deployment rolls: perMinute new pods start and emit,
+	// and the previous minute's pods leave the metadata cache.
+	now := time.Unix(0, 0)
+	for minute := range 60 {
+		live := make(map[metadata.PodKey]struct{})
+		for i := range perMinute {
+			name := fmt.Sprintf("api-%d-%d", minute, i)
+			live[metadata.PodKey{Namespace: "prod", Name: name}] = struct{}{}
+			record(g, series, "prod", name)
+		}
+		g.sweep(live, now)
+		now = now.Add(time.Minute)
+
+		if got := testutil.CollectAndCount(series); got > limit+1 {
+			t.Fatalf("minute %d: %d series, want <= %d", minute, got, limit+1)
+		}
+	}
+
+	// Once churn stops, departed pods age out and free their slots.
+	for range 7 {
+		g.sweep(nil, now)
+		now = now.Add(time.Minute)
+	}
+	if got := len(g.families[testMetric].pods); got != 0 {
+		t.Errorf("tracked pods after churn = %d, want 0", got)
+	}
+	if got := g.pod(testMetric, "prod", "api-new"); got != "api-new" {
+		t.Errorf("new pod after sweep recorded as %q, want its own series", got)
+	}
+}
+
+func TestPodGuard_KeepsLivePods(t *testing.T) {
+	g, series, _ := newTestGuard(10, time.Minute)
+	record(g, series, "default", "db-0")
+	live := map[metadata.PodKey]struct{}{{Namespace: "default", Name: "db-0"}: {}}
+
+	now := time.Unix(0, 0)
+	for range 5 {
+		if n := g.sweep(live, now); n != 0 {
+			t.Fatalf("sweep removed %d live pods", n)
+		}
+		now = now.Add(time.Hour)
+	}
+	if got := testutil.CollectAndCount(series); got != 1 {
+		t.Errorf("series = %d, want 1", got)
+	}
+}
@@ -12,9 +12,13 @@ type Exporter interface {
 	Name() string
 
 	// Start begins consuming events and exporting metrics/traces.
-	// Blocks until ctx is cancelled.
+	// Blocks until ctx is cancelled or the bus closes its subscription.
+	// At shutdown the runtime closes the bus before cancelling ctx, so an
+	// exporter sees every event.
 	Start(ctx context.Context) error
 
-	// Stop gracefully shuts down the exporter.
+	// Stop gracefully shuts down the exporter, flushing anything still
+	// buffered before it returns. The runtime calls it once Start has
+	// returned, or once the shutdown timeout has passed.
 	Stop(ctx context.Context) error
 }
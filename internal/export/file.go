@@ -0,0 +1,316 @@
+package export
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// File sync policies: when written events are fsynced to disk.
+const (
+	FileSyncAlways   = "always"   // after every event
+	FileSyncInterval = "interval" // every sync_interval
+	FileSyncNone     = "none"     // left to the OS
+)
+
+// FileConfig holds file exporter settings.
+type FileConfig struct {
+	Path         string        `yaml:"path"`
+	MaxSizeMB    int           `yaml:"max_size_mb"` // rotate once the file would exceed this
+	MaxFiles     int           `yaml:"max_files"`   // files kept, the one being written included
+	Sync         string        `yaml:"sync"`        // always, interval or none
+	SyncInterval time.Duration `yaml:"sync_interval"`
+}
+
+// DefaultFileConfig returns defaults keeping 5 × 100 MB.
+func DefaultFileConfig() FileConfig {
+	return FileConfig{
+		Path:         constants.FileExporterPath,
+		MaxSizeMB:    constants.FileExporterMaxSizeMB,
+		MaxFiles:     constants.FileExporterMaxFiles,
+		Sync:         FileSyncInterval,
+		SyncInterval: constants.FileExporterSyncInterval,
+	}
+}
+
+// FileExporter appends events to a local file as NDJSON, one wire.Event
+// per line, for clusters with no network sink. Each line is exactly a NATS
+// message body, so a capture can later be published to the consumer's
+// subject and loaded into ClickHouse.
+//
+// When the file would exceed MaxSizeMB it is rotated: path becomes path.1,
+// path.1 becomes path.2 and so on, and the oldest beyond MaxFiles is
+// deleted.
+type FileExporter struct {
+	cfg      FileConfig
+	maxBytes int64
+	logger   *zap.Logger
+	bus      *event.Bus
+	events   <-chan *event.Event
+
+	mu      sync.Mutex
+	f       *os.File
+	w       *bufio.Writer
+	size    int64 // bytes in the current file, buffered ones included
+	seq     uint64
+	failing bool // the last write failed; logged once until one succeeds
+	stopped bool
+}
+
+// NewFileExporter creates a file exporter (Factory constructor).
+func NewFileExporter(cfg FileConfig, bus *event.Bus, logger *zap.Logger) *FileExporter {
+	return &FileExporter{
+		cfg:      cfg,
+		maxBytes: int64(cfg.MaxSizeMB) * 1024 * 1024,
+		logger:   logger,
+		bus:      bus,
+	}
+}
+
+func (e *FileExporter) Name() string { return constants.ExporterFile }
+
+func (e *FileExporter) Start(ctx context.Context) error {
+	e.mu.Lock()
+	err := e.open()
+	e.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	e.events = e.bus.Subscribe(constants.ExporterFile)
+	e.logger.Info("File exporter started",
+		zap.String("path", e.cfg.Path),
+		zap.Int("max_size_mb", e.cfg.MaxSizeMB),
+		zap.Int("max_files", e.cfg.MaxFiles))
+
+	ticker := time.NewTicker(e.cfg.SyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case evt, ok := <-e.events:
+			if !ok {
+				return nil
+			}
+			e.mu.Lock()
+			e.write(evt)
+			e.mu.Unlock()
+			evt.Done()
+		case <-ticker.C:
+			e.tick()
+		}
+	}
+}
+
+// tick writes out buffered events, reopening the file if a failed
+// rotation left none open.
+func (e *FileExporter) tick() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopped {
+		return
+	}
+	if e.f == nil {
+		if err := e.open(); err != nil {
+			e.report(err)
+			return
+		}
+	}
+	e.report(e.sync(e.cfg.Sync != FileSyncNone))
+}
+
+// Stop writes the events still queued for the exporter, then flushes,
+// fsyncs and closes the file.
+func (e *FileExporter) Stop(_ context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stopped = true
+	if e.f == nil {
+		return nil
+	}
+	for drained := false; !drained; {
+		select {
+		case evt, ok := <-e.events:
+			if !ok {
+				drained = true
+				break
+			}
+			e.write(evt)
+			evt.Done()
+		default:
+			drained = true
+		}
+	}
+	err := e.sync(true)
+	if cerr := e.f.Close(); err == nil {
+		err = cerr
+	}
+	e.f = nil
+	return err
+}
+
+// open opens cfg.Path for appending, creating its directory, after
+// cutting off any line a crash left half-written. Callers hold e.mu.
+func (e *FileExporter) open() error {
+	if err := os.MkdirAll(filepath.Dir(e.cfg.Path), 0o750); err != nil {
+		return err
+	}
+	size, dropped, err := recoverTail(e.cfg.Path)
+	if err != nil {
+		return fmt.Errorf("recovering %s: %w", e.cfg.Path, err)
+	}
+	if dropped > 0 {
+		e.logger.Warn("Dropped a partly written event left by a crash",
+			zap.String("path", e.cfg.Path), zap.Int64("bytes", dropped))
+	}
+	f, err := os.OpenFile(e.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o640)
+	if err != nil {
+		return err
+	}
+	e.f, e.w, e.size = f, bufio.NewWriter(f), size
+	return nil
+}
+
+// write appends evt as one line, rotating first if it would overflow the
+// file. Callers hold e.mu.
+func (e *FileExporter) write(evt *event.Event) {
+	if e.f == nil {
+		return
+	}
+	e.seq++
+	data, err := wire.Encode(toWire(evt, eventID(evt, e.seq)))
+	if err != nil {
+		return
+	}
+	n := int64(len(data)) + 1
+	if e.size > 0 && e.size+n > e.maxBytes {
+		if err := e.rotate(); err != nil {
+			e.report(err)
+			if e.f == nil {
+				return
+			}
+		}
+	}
+	e.w.Write(data)
+	e.w.WriteByte('\n')
+	e.size += n
+	if e.cfg.Sync == FileSyncAlways {
+		e.report(e.sync(true))
+	}
+}
+
+// sync writes buffered events to the file and, with fsync, to disk.
+// Callers hold e.mu.
+func (e *FileExporter) sync(fsync bool) error {
+	if e.f == nil {
+		return nil
+	}
+	if err := e.w.Flush(); err != nil {
+		return err
+	}
+	if fsync {
+		return e.f.Sync()
+	}
+	return nil
+}
+
+// rotate closes the current file, shifts the older ones up by one,
+// deleting the oldest, and opens a fresh file. Callers hold e.mu.
+func (e *FileExporter) rotate() error {
+	err := e.sync(e.cfg.Sync != FileSyncNone)
+	if cerr := e.f.Close(); err == nil {
+		err = cerr
+	}
+	e.f = nil
+
+	path := e.cfg.Path
+	rotated := func(i int) string { return fmt.Sprintf("%s.%d", path, i) }
+	if e.cfg.MaxFiles <= 1 {
+		err = errors.Join(err, removeIfExists(path))
+	} else {
+		err = errors.Join(err, removeIfExists(rotated(e.cfg.MaxFiles-1)))
+		for i := e.cfg.MaxFiles - 2; i >= 1; i-- {
+			err = errors.Join(err, renameIfExists(rotated(i), rotated(i+1)))
+		}
+		err = errors.Join(err, os.Rename(path, rotated(1)))
+	}
+	return errors.Join(err, e.open())
+}
+
+// report logs a write error once, and logs again when writes recover.
+// Callers hold e.mu.
+func (e *FileExporter) report(err error) {
+	switch {
+	case err != nil && !e.failing:
+		e.logger.Warn("File exporter write failed", zap.String("path", e.cfg.Path), zap.Error(err))
+		e.failing = true
+	case err == nil && e.failing:
+		e.logger.Info("File exporter writes recovered", zap.String("path", e.cfg.Path))
+		e.failing = false
+	}
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func renameIfExists(from, to string) error {
+	if err := os.Rename(from, to); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// recoverTail truncates path after its last newline, dropping a line a
+// crash left partly written, and returns the resulting size and how many
+// bytes it dropped. A missing file has size 0.
+func recoverTail(path string) (size, dropped int64, err error) {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, 0, err
+	}
+	size = info.Size()
+	buf := make([]byte, 64*1024)
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+		chunk := buf[:end-start]
+		if _, err := f.ReadAt(chunk, start); err != nil && err != io.EOF {
+			return 0, 0, err
+		}
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			keep := start + int64(i) + 1
+			if keep == size {
+				return size, 0, nil
+			}
+			return keep, size - keep, f.Truncate(keep)
+		}
+		end = start
+	}
+	return 0, size, f.Truncate(0)
+}
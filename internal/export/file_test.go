@@ -0,0 +1,169 @@
+package export
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+func testFileExporter(t *testing.T, path string, maxBytes int64, maxFiles int) *FileExporter {
+	t.Helper()
+	cfg := DefaultFileConfig()
+	cfg.Path = path
+	cfg.MaxFiles = maxFiles
+	cfg.Sync = FileSyncNone
+	e := NewFileExporter(cfg, event.NewBus(64, nil), zap.NewNop())
+	e.maxBytes = maxBytes
+	if err := e.open(); err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+func fileEvent(pid uint32) *event.Event {
+	return &event.Event{Type: event.TypeTCP, Timestamp: time.UnixMilli(1700000000000), PID: pid, Node: "n1", Pod: "api-0"}
+}
+
+// readEvents decodes every line of path, failing on a partial or
+// malformed one.
+func readEvents(t *testing.T, path string) []wire.Event {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var out []wire.Event
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		w, err := wire.Decode(sc.Bytes())
+		if err != nil {
+			t.Fatalf("%s: line %d: %v", path, len(out)+1, err)
+		}
+		out = append(out, w)
+	}
+	return out
+}
+
+func TestFileExporter_RotatesAndDeletesOldest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	e := testFileExporter(t, path, 400, 3)
+	for pid := uint32(1); pid <= 40; pid++ {
+		e.write(fileEvent(pid))
+	}
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("%s.3 exists; max_files is 3", path)
+	}
+	var pids []uint32
+	for _, p := range []string{path + ".2", path + ".1", path} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > 400 {
+			t.Errorf("%s is %d bytes, over the 400 byte limit", p, info.Size())
+		}
+		for _, w := range readEvents(t, p) {
+			pids = append(pids, w.PID)
+		}
+	}
+	if len(pids) == 0 || pids[len(pids)-1] != 40 {
+		t.Fatalf("kept pids %v, want the newest last", pids)
+	}
+	if pids[0] == 1 {
+		t.Error("the oldest events survived rotation")
+	}
+	for i := 1; i < len(pids); i++ {
+		if pids[i] != pids[i-1]+1 {
+			t.Fatalf("kept pids %v are not contiguous across files", pids)
+		}
+	}
+}
+
+func TestRecoverTail(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"complete", "a\nb\n", "a\nb\n"},
+		{"partial last line", "a\nb\n{\"v\":1,\"ty", "a\nb\n"},
+		{"no newline", "{\"v\":1", ""},
+		{"empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "events.ndjson")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			size, dropped, err := recoverTail(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _ := os.ReadFile(path)
+			if string(got) != tt.want || size != int64(len(tt.want)) || dropped != int64(len(tt.content)-len(tt.want)) {
+				t.Errorf("recovered %q (size %d, dropped %d), want %q", got, size, dropped, tt.want)
+			}
+		})
+	}
+
+	if size, _, err := recoverTail(filepath.Join(t.TempDir(), "missing")); err != nil || size != 0 {
+		t.Errorf("missing file: size %d, err %v", size, err)
+	}
+}
+
+func TestFileExporter_RecoversPartialWriteOnRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	e := testFileExporter(t, path, 1<<20, 2)
+	e.write(fileEvent(1))
+	e.Stop(context.Background())
+
+	// A crash mid-write leaves half a line behind.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fmt.Fprint(f, `{"v":1,"id":99,"type":"tc`)
+	f.Close()
+
+	e = testFileExporter(t, path, 1<<20, 2)
+	e.write(fileEvent(2))
+	e.Stop(context.Background())
+
+	got := readEvents(t, path)
+	if len(got) != 2 || got[0].PID != 1 || got[1].PID != 2 {
+		t.Errorf("events after restart = %+v, want pids 1 and 2", got)
+	}
+}
+
+func TestFileExporter_StopWritesQueuedEvents(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.ndjson")
+	e := testFileExporter(t, path, 1<<20, 2)
+	e.events = e.bus.Subscribe(constants.ExporterFile)
+	for pid := uint32(1); pid <= 3; pid++ {
+		e.bus.Publish(fileEvent(pid))
+	}
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	got := readEvents(t, path)
+	if len(got) != 3 {
+		t.Fatalf("wrote %d events, want 3", len(got))
+	}
+	if got[0].ID == 0 || got[0].ID == got[1].ID || got[0].Node != "n1" || got[0].Pod != "api-0" {
+		t.Errorf("events = %+v, want distinct IDs and enriched fields", got)
+	}
+}
@@ -0,0 +1,374 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// HTTPPushConfig holds HTTP push exporter settings.
+type HTTPPushConfig struct {
+	URL             string        `yaml:"url"` // the API's ingest endpoint, e.g. https://kubepulse-api:8080/api/v1/ingest
+	BearerToken     string        `yaml:"bearer_token"`
+	BearerTokenFile string        `yaml:"bearer_token_file"` // read on every push, so it can be rotated
+	BatchSize       int           `yaml:"batch_size"`
+	FlushInterval   time.Duration `yaml:"flush_interval"`
+	Timeout         time.Duration `yaml:"timeout"`      // per POST
+	Compression     string        `yaml:"compression"`  // zstd, snappy or "" for plain NDJSON
+	MaxBuffered     int           `yaml:"max_buffered"` // batches held while the API is unreachable
+}
+
+// DefaultHTTPPushConfig returns defaults with no URL or token.
+func DefaultHTTPPushConfig() HTTPPushConfig {
+	return HTTPPushConfig{
+		BatchSize:     constants.HTTPPushBatchSize,
+		FlushInterval: constants.HTTPPushFlushInterval,
+		Timeout:       constants.HTTPPushTimeout,
+		Compression:   wire.EncodingZstd,
+		MaxBuffered:   constants.HTTPPushMaxBuffered,
+	}
+}
+
+// Validate checks c without reading the token file.
+func (c HTTPPushConfig) Validate() error {
+	var errs []string
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, "url must be an http or https URL")
+	}
+	if (c.BearerToken == "") == (c.BearerTokenFile == "") {
+		errs = append(errs, "one of bearer_token and bearer_token_file is required")
+	}
+	if c.BatchSize < 1 {
+		errs = append(errs, "batch_size must be >= 1")
+	}
+	if c.FlushInterval <= 0 {
+		errs = append(errs, "flush_interval must be > 0")
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, "timeout must be > 0")
+	}
+	if c.Compression != "" && !wire.SupportedEncoding(c.Compression) {
+		errs = append(errs, fmt.Sprintf("compression must be %s, %s or empty, got %q",
+			wire.EncodingZstd, wire.EncodingSnappy, c.Compression))
+	}
+	if c.MaxBuffered < 1 {
+		errs = append(errs, "max_buffered must be >= 1")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+var (
+	httpPushEvents = promauto.NewCounter(prometheus.CounterOpts{
+		Name: constants.MetricHTTPPushEvents,
+		Help: "Events the API's ingest endpoint stored.",
+	})
+	httpPushDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricHTTPPushDropped,
+		Help: "Events the HTTP push exporter gave up on, by reason.",
+	}, constants.LabelsReason)
+)
+
+// pushBatch is a batch of events ready to POST.
+type pushBatch struct {
+	key    string // Idempotency-Key, the same on every retry
+	body   []byte
+	events int
+}
+
+// HTTPPush is an Exporter that POSTs events to the API server's ingest
+// endpoint, for installs without NATS. Events are batched by size or
+// time, in the wire format the NATS exporter publishes. A batch is
+// retried until the API takes it, as long as it answers with a 5xx or
+// 429 or not at all, waiting as long as its Retry-After asks; meanwhile
+// up to MaxBuffered newer batches wait behind it, and the oldest of them
+// is dropped to make room. A batch the API rejects with any other 4xx is
+// dropped.
+type HTTPPush struct {
+	cfg    HTTPPushConfig
+	logger *zap.Logger
+	bus    *event.Bus
+	events <-chan *event.Event
+	client *http.Client
+
+	// The batch being filled, touched only by Start's loop.
+	seq     uint64 // per-agent event sequence, see eventID
+	pending [][]byte
+	keyHash hash.Hash64 // over the pending events' IDs
+
+	mu     sync.Mutex
+	queue  []pushBatch   // sealed batches, oldest first; at most MaxBuffered
+	ready  chan struct{} // signalled when a batch is queued
+	sealed chan struct{} // closed when Start returns: no more batches
+	cancel context.CancelFunc
+	sent   chan struct{} // closed when the sender returns
+
+	backoff, maxBackoff time.Duration
+}
+
+// NewHTTPPush creates an HTTP push exporter (Factory constructor).
+func NewHTTPPush(cfg HTTPPushConfig, bus *event.Bus, logger *zap.Logger) *HTTPPush {
+	return &HTTPPush{
+		cfg:        cfg,
+		logger:     logger,
+		bus:        bus,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		pending:    make([][]byte, 0, cfg.BatchSize),
+		keyHash:    fnv.New64a(),
+		ready:      make(chan struct{}, 1),
+		sealed:     make(chan struct{}),
+		sent:       make(chan struct{}),
+		backoff:    constants.HTTPPushRetryBackoff,
+		maxBackoff: constants.HTTPPushRetryMaxBackoff,
+	}
+}
+
+func (e *HTTPPush) Name() string { return constants.ExporterHTTPPush }
+
+// Start batches events until the bus closes, so events modules drain at
+// shutdown are still sent. Batches are sent from a goroutine of their
+// own, which outlives Start so Stop can wait for it to deliver the last
+// ones.
+func (e *HTTPPush) Start(context.Context) error {
+	e.events = e.bus.Subscribe(constants.ExporterHTTPPush)
+
+	// Not Start's context: the runtime cancels it before Stop waits for
+	// the tail.
+	sendCtx, cancel := context.WithCancel(context.Background())
+	e.mu.Lock()
+	e.cancel = cancel
+	e.mu.Unlock()
+	go e.sendLoop(sendCtx)
+	defer close(e.sealed)
+
+	e.logger.Info("HTTP push exporter started",
+		zap.String("url", e.cfg.URL),
+		zap.Int("batch_size", e.cfg.BatchSize),
+		zap.String("compression", e.cfg.Compression))
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case evt, ok := <-e.events:
+			if !ok {
+				e.seal()
+				return nil
+			}
+			e.enqueue(evt)
+			evt.Done()
+		case <-ticker.C:
+			e.seal()
+		}
+	}
+}
+
+// Stop waits for the sender to deliver the batches still queued, and
+// drops them once ctx expires. The runtime calls it once Start has
+// returned.
+func (e *HTTPPush) Stop(ctx context.Context) error {
+	e.mu.Lock()
+	cancel := e.cancel
+	e.mu.Unlock()
+	if cancel == nil {
+		return nil
+	}
+	select {
+	case <-e.sent:
+		return nil
+	case <-ctx.Done():
+		cancel()
+		<-e.sent
+		return fmt.Errorf("pushing the last events: %w", ctx.Err())
+	}
+}
+
+// enqueue adds evt to the pending batch, sealing the batch once full.
+func (e *HTTPPush) enqueue(evt *event.Event) {
+	e.seq++
+	id := eventID(evt, e.seq)
+	data, err := wire.Encode(toWire(evt, id))
+	if err != nil {
+		return
+	}
+	e.pending = append(e.pending, data)
+	e.keyHash.Write(binary.LittleEndian.AppendUint64(nil, id))
+	if len(e.pending) >= e.cfg.BatchSize {
+		e.seal()
+	}
+}
+
+// seal queues the pending batch for sending, dropping the oldest queued
+// batch when MaxBuffered are already waiting.
+func (e *HTTPPush) seal() {
+	if len(e.pending) == 0 {
+		return
+	}
+	b := pushBatch{
+		key:    fmt.Sprintf("%016x", e.keyHash.Sum64()),
+		events: len(e.pending),
+	}
+	if e.cfg.Compression == "" {
+		b.body = bytes.Join(e.pending, []byte{'\n'})
+	} else {
+		var err error
+		if b.body, err = wire.EncodePack(e.cfg.Compression, e.pending); err != nil {
+			e.logger.Warn("Packing events", zap.Error(err), zap.Int("events", len(e.pending)))
+			b.body = nil
+		}
+	}
+	e.pending = make([][]byte, 0, e.cfg.BatchSize)
+	e.keyHash.Reset()
+	if b.body == nil {
+		return
+	}
+
+	e.mu.Lock()
+	if len(e.queue) >= e.cfg.MaxBuffered {
+		httpPushDropped.WithLabelValues(constants.HTTPPushDropBufferFull).Add(float64(e.queue[0].events))
+		e.logger.Warn("HTTP push buffer full — dropping the oldest batch", zap.Int("events", e.queue[0].events))
+		e.queue = e.queue[1:]
+	}
+	e.queue = append(e.queue, b)
+	e.mu.Unlock()
+
+	select {
+	case e.ready <- struct{}{}:
+	default:
+	}
+}
+
+// sendLoop sends queued batches in order until Start has returned and the
+// queue is empty, or ctx is cancelled. A batch being retried holds up the
+// ones behind it.
+func (e *HTTPPush) sendLoop(ctx context.Context) {
+	defer close(e.sent)
+	for {
+		b, ok := e.next(ctx)
+		if !ok {
+			break
+		}
+		err := e.send(ctx, b)
+		switch {
+		case err == nil:
+			httpPushEvents.Add(float64(b.events))
+		case ctx.Err() != nil:
+			httpPushDropped.WithLabelValues(constants.HTTPPushDropShutdown).Add(float64(b.events))
+		default:
+			httpPushDropped.WithLabelValues(constants.HTTPPushDropRejected).Add(float64(b.events))
+			e.logger.Warn("API rejected pushed events — dropping them", zap.Error(err), zap.Int("events", b.events))
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, b := range e.queue {
+		httpPushDropped.WithLabelValues(constants.HTTPPushDropShutdown).Add(float64(b.events))
+	}
+	e.queue = nil
+}
+
+// next takes the oldest queued batch, waiting for one. It reports false
+// once Start has returned with nothing left to send, or ctx is cancelled.
+func (e *HTTPPush) next(ctx context.Context) (pushBatch, bool) {
+	for {
+		e.mu.Lock()
+		if len(e.queue) > 0 {
+			b := e.queue[0]
+			e.queue = e.queue[1:]
+			e.mu.Unlock()
+			return b, true
+		}
+		e.mu.Unlock()
+
+		select {
+		case <-e.ready:
+		case <-e.sealed:
+			e.mu.Lock()
+			empty := len(e.queue) == 0
+			e.mu.Unlock()
+			if empty {
+				return pushBatch{}, false
+			}
+		case <-ctx.Done():
+			return pushBatch{}, false
+		}
+	}
+}
+
+// send posts b until the API takes or rejects it, or ctx is cancelled,
+// backing off exponentially between attempts unless a Retry-After header
+// sets the wait.
+func (e *HTTPPush) send(ctx context.Context, b pushBatch) error {
+	delay := e.backoff
+	for attempt := 1; ; attempt++ {
+		err := e.post(ctx, b)
+		var we *writeError
+		isWE := errors.As(err, &we)
+		if err == nil || (isWE && !we.retryable()) || ctx.Err() != nil {
+			return err
+		}
+		wait := delay
+		if isWE && we.retryAfter > 0 {
+			wait = we.retryAfter
+		}
+		e.logger.Warn("HTTP push failed — retrying", zap.Error(err),
+			zap.Int("attempt", attempt), zap.Duration("wait", wait))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, e.maxBackoff)
+	}
+}
+
+func (e *HTTPPush) post(ctx context.Context, b pushBatch) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.cfg.URL, bytes.NewReader(b.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if e.cfg.Compression != "" {
+		req.Header.Set("Content-Encoding", e.cfg.Compression)
+	}
+	req.Header.Set(constants.HeaderIdempotencyKey, b.key)
+	req.Header.Set("User-Agent", "kubepulse/"+buildinfo.Version)
+	token := e.cfg.BearerToken
+	if e.cfg.BearerTokenFile != "" {
+		data, err := os.ReadFile(e.cfg.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("reading bearer_token_file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return responseError(resp)
+}
@@ -0,0 +1,253 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
+)
+
+// ingestReceiver is an ingest endpoint answering with statuses in turn,
+// then 200, and recording the requests and the events it accepted.
+type ingestReceiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header // Retry-After etc. for the non-2xx answers
+	keys     []string    // Idempotency-Key of every request
+	auth     []string    // Authorization of every request
+	events   []wire.Event
+}
+
+func (ir *ingestReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	ir.keys = append(ir.keys, r.Header.Get(constants.HeaderIdempotencyKey))
+	ir.auth = append(ir.auth, r.Header.Get("Authorization"))
+	if len(ir.statuses) > 0 {
+		code := ir.statuses[0]
+		ir.statuses = ir.statuses[1:]
+		for k, v := range ir.header {
+			w.Header()[k] = v
+		}
+		http.Error(w, "no", code)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	lines := bytes.Split(body, []byte{'\n'})
+	if enc := r.Header.Get("Content-Encoding"); enc != "" {
+		var err error
+		if lines, err = wire.DecodePack(enc, body); err != nil {
+			ir.t.Errorf("decoding body: %v", err)
+		}
+	}
+	for _, data := range lines {
+		e, err := wire.Decode(data)
+		if err != nil {
+			ir.t.Errorf("decoding event: %v", err)
+		}
+		ir.events = append(ir.events, e)
+	}
+}
+
+func (ir *ingestReceiver) requests() int {
+	ir.mu.Lock()
+	defer ir.mu.Unlock()
+	return len(ir.keys)
+}
+
+func newHTTPPushTest(t *testing.T, cfg HTTPPushConfig, ir *ingestReceiver) (*HTTPPush, *event.Bus) {
+	t.Helper()
+	ir.t = t
+	srv := httptest.NewServer(ir)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL + "/api/v1/ingest"
+	cfg.BearerToken = "s3cret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(64, nil)
+	e := NewHTTPPush(cfg, bus, zap.NewNop())
+	e.backoff, e.maxBackoff = time.Millisecond, time.Millisecond
+	return e, bus
+}
+
+// runHTTPPush starts e and returns a function closing the bus and
+// stopping e as the runtime does at shutdown.
+func runHTTPPush(t *testing.T, e *HTTPPush, bus *event.Bus) (stop func() error) {
+	t.Helper()
+	done := make(chan error)
+	go func() { done <- e.Start(context.Background()) }()
+	for {
+		if _, ok := bus.Stats().QueueDepth[constants.ExporterHTTPPush]; ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return func() error {
+		bus.Close()
+		if err := <-done; err != nil {
+			return err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return e.Stop(ctx)
+	}
+}
+
+func publishOOMs(bus *event.Bus, n int) {
+	for pid := range n {
+		bus.Publish(&event.Event{Type: event.TypeOOM, Timestamp: time.Now(), PID: uint32(pid), Node: "node-1"})
+	}
+}
+
+func TestHTTPPush_RetriesWithSameKey(t *testing.T) {
+	ir := &ingestReceiver{
+		statuses: []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+		header:   http.Header{"Retry-After": {"0"}},
+	}
+	cfg := DefaultHTTPPushConfig()
+	cfg.BatchSize = 3
+	e, bus := newHTTPPushTest(t, cfg, ir)
+	stop := runHTTPPush(t, e, bus)
+	publishOOMs(bus, 3)
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ir.keys) != 3 {
+		t.Fatalf("%d requests, want 2 failures and a success", len(ir.keys))
+	}
+	if ir.keys[0] == "" || ir.keys[1] != ir.keys[0] || ir.keys[2] != ir.keys[0] {
+		t.Errorf("idempotency keys = %q, want one non-empty key on every attempt", ir.keys)
+	}
+	if ir.auth[0] != "Bearer s3cret" {
+		t.Errorf("Authorization = %q", ir.auth[0])
+	}
+	if len(ir.events) != 3 {
+		t.Fatalf("%d events delivered, want 3", len(ir.events))
+	}
+	for _, ev := range ir.events {
+		if ev.ID == 0 || ev.Type != constants.ModuleOOM || ev.Node != "node-1" {
+			t.Errorf("event = %+v", ev)
+		}
+	}
+}
+
+func TestHTTPPush_DropsRejectedBatch(t *testing.T) {
+	ir := &ingestReceiver{statuses: []int{http.StatusBadRequest}}
+	cfg := DefaultHTTPPushConfig()
+	cfg.BatchSize = 2
+	cfg.Compression = ""
+	e, bus := newHTTPPushTest(t, cfg, ir)
+	rejected := httpPushDropped.WithLabelValues(constants.HTTPPushDropRejected)
+	before := testutil.ToFloat64(rejected)
+
+	stop := runHTTPPush(t, e, bus)
+	publishOOMs(bus, 2)
+	for ir.requests() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	publishOOMs(bus, 2)
+	if err := stop(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ir.keys) != 2 || ir.keys[0] == ir.keys[1] {
+		t.Errorf("idempotency keys = %q, want one per batch and no retry", ir.keys)
+	}
+	if len(ir.events) != 2 {
+		t.Errorf("%d events delivered, want the second batch's 2", len(ir.events))
+	}
+	if got := testutil.ToFloat64(rejected) - before; got != 2 {
+		t.Errorf("rejected = %v, want 2", got)
+	}
+}
+
+func TestHTTPPush_BufferDropsOldest(t *testing.T) {
+	cfg := DefaultHTTPPushConfig()
+	cfg.BatchSize = 1
+	cfg.MaxBuffered = 2
+	e, _ := newHTTPPushTest(t, cfg, &ingestReceiver{})
+	full := httpPushDropped.WithLabelValues(constants.HTTPPushDropBufferFull)
+	before := testutil.ToFloat64(full)
+
+	// With no sender running, every batch stays queued.
+	var keys []string
+	for pid := range 3 {
+		e.enqueue(&event.Event{Type: event.TypeOOM, Timestamp: time.Now(), PID: uint32(pid)})
+		keys = append(keys, e.queue[len(e.queue)-1].key)
+	}
+	if len(e.queue) != 2 || e.queue[0].key != keys[1] || e.queue[1].key != keys[2] {
+		t.Errorf("queue = %+v, want the newest 2 of %q", e.queue, keys)
+	}
+	if got := testutil.ToFloat64(full) - before; got != 1 {
+		t.Errorf("buffer_full = %v, want 1", got)
+	}
+}
+
+func TestHTTPPush_StopDropsUnsentAtTimeout(t *testing.T) {
+	ir := &ingestReceiver{header: http.Header{"Retry-After": {"3600"}}}
+	for range 10 {
+		ir.statuses = append(ir.statuses, http.StatusServiceUnavailable)
+	}
+	cfg := DefaultHTTPPushConfig()
+	cfg.BatchSize = 1
+	e, bus := newHTTPPushTest(t, cfg, ir)
+	shutdown := httpPushDropped.WithLabelValues(constants.HTTPPushDropShutdown)
+	before := testutil.ToFloat64(shutdown)
+
+	done := make(chan error)
+	go func() { done <- e.Start(context.Background()) }()
+	for {
+		if _, ok := bus.Stats().QueueDepth[constants.ExporterHTTPPush]; ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	publishOOMs(bus, 2)
+	bus.Close()
+	<-done
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := e.Stop(ctx); err == nil {
+		t.Error("Stop succeeded with the API asking for an hour's wait")
+	}
+	if got := testutil.ToFloat64(shutdown) - before; got != 2 {
+		t.Errorf("shutdown = %v, want both events", got)
+	}
+}
+
+func TestHTTPPushConfig_Validate(t *testing.T) {
+	valid := DefaultHTTPPushConfig()
+	valid.URL = "https://kubepulse-api:8080/api/v1/ingest"
+	valid.BearerTokenFile = "/var/run/secrets/kubepulse/token"
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, mutate := range map[string]func(*HTTPPushConfig){
+		"url":         func(c *HTTPPushConfig) { c.URL = "kubepulse-api:8080" },
+		"no token":    func(c *HTTPPushConfig) { c.BearerTokenFile = "" },
+		"two tokens":  func(c *HTTPPushConfig) { c.BearerToken = "s3cret" },
+		"compression": func(c *HTTPPushConfig) { c.Compression = "gzip" },
+		"buffer":      func(c *HTTPPushConfig) { c.MaxBuffered = 0 },
+	} {
+		c := valid
+		mutate(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%s: accepted %+v", name, c)
+		}
+	}
+}
@@ -4,8 +4,11 @@ package export
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -14,6 +17,7 @@ import (
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/wire"
 )
 
 // NATSConfig holds NATS exporter settings.
@@ -23,6 +27,10 @@ type NATSConfig struct {
 	Subject       string        `yaml:"subject"`
 	BatchSize     int           `yaml:"batch_size"`
 	FlushInterval time.Duration `yaml:"flush_interval"`
+	Compression   string        `yaml:"compression"` // "", zstd or snappy; "" publishes one plain event per message
+	PackSize      int           `yaml:"pack_size"`   // events per compressed message
+
+	StreamSettings `yaml:",inline"`
 }
 
 // DefaultNATSConfig returns a lean default for small instances.
@@ -33,21 +41,10 @@ func DefaultNATSConfig() NATSConfig {
 		Subject:       constants.NATSSubject,
 		BatchSize:     constants.NATSBatchSize,
 		FlushInterval: constants.NATSFlushInterval,
-	}
-}
+		PackSize:      constants.NATSPackSize,
 
-// wireEvent is the JSON wire format (flat, compact).
-type wireEvent struct {
-	Type      string             `json:"type"`
-	Timestamp int64              `json:"ts"`
-	PID       uint32             `json:"pid"`
-	UID       uint32             `json:"uid"`
-	Comm      string             `json:"comm"`
-	Node      string             `json:"node"`
-	Namespace string             `json:"ns"`
-	Pod       string             `json:"pod"`
-	Labels    map[string]string  `json:"l,omitempty"`
-	Numerics  map[string]float64 `json:"n,omitempty"`
+		StreamSettings: DefaultStreamSettings(),
+	}
 }
 
 // NATSExporter publishes events to NATS JetStream.
@@ -57,9 +54,11 @@ type NATSExporter struct {
 	bus    *event.Bus
 	events <-chan *event.Event
 
-	nc *nats.Conn
-	js jetstream.JetStream
+	nc     *nats.Conn
+	js     jetstream.JetStream
+	closed chan struct{} // closed once nc is, after Stop's drain
 
+	seq   atomic.Uint64 // per-agent event sequence, see eventID
 	batch [][]byte
 	mu    sync.Mutex
 }
@@ -71,6 +70,7 @@ func NewNATSExporter(cfg NATSConfig, bus *event.Bus, logger *zap.Logger) *NATSEx
 		logger: logger,
 		bus:    bus,
 		batch:  make([][]byte, 0, cfg.BatchSize),
+		closed: make(chan struct{}),
 	}
 }
 
@@ -86,6 +86,9 @@ func (e *NATSExporter) Start(ctx context.Context) error {
 		nats.ReconnectHandler(func(_ *nats.Conn) {
 			e.logger.Info("NATS reconnected")
 		}),
+		nats.ClosedHandler(func(_ *nats.Conn) {
+			close(e.closed)
+		}),
 	)
 	if err != nil {
 		return err
@@ -98,15 +101,7 @@ func (e *NATSExporter) Start(ctx context.Context) error {
 	}
 	e.js = js
 
-	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
-		Name:      e.cfg.Stream,
-		Subjects:  []string{e.cfg.Subject},
-		Retention: jetstream.WorkQueuePolicy,
-		MaxBytes:  constants.NATSStreamMaxBytes,
-		Discard:   jetstream.DiscardOld,
-		Storage:   jetstream.FileStorage,
-	})
-	if err != nil {
+	if err := ensureStream(ctx, js, e.cfg.streamConfig(e.cfg.Stream, e.cfg.Subject), e.logger); err != nil {
 		return err
 	}
 
@@ -128,20 +123,50 @@ func (e *NATSExporter) Start(ctx context.Context) error {
 				return nil
 			}
 			e.enqueue(evt)
+			evt.Done()
 		}
 	}
 }
 
-func (e *NATSExporter) Stop(_ context.Context) error {
+// Stop publishes the pending batch and waits for the connection to drain,
+// so every event is with the server when it returns. The runtime calls it
+// once Start has returned.
+func (e *NATSExporter) Stop(ctx context.Context) error {
+	if e.nc == nil {
+		return nil
+	}
 	e.flush()
-	if e.nc != nil {
-		e.nc.Drain()
+	if err := e.nc.Drain(); err != nil {
+		return err
+	}
+	select {
+	case <-e.closed:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("draining the NATS connection: %w", ctx.Err())
 	}
-	return nil
 }
 
-func (e *NATSExporter) enqueue(evt *event.Event) {
-	w := wireEvent{
+// eventID derives an event's deterministic ID from the node, PID, type,
+// kernel capture time and the agent's sequence number. It is assigned once,
+// before publishing, so every redelivery of the message carries the same
+// ID and the consumer can drop the copies.
+func eventID(evt *event.Event, seq uint64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(evt.Node))
+	var buf [21]byte
+	binary.LittleEndian.PutUint32(buf[0:], evt.PID)
+	buf[4] = byte(evt.Type)
+	binary.LittleEndian.PutUint64(buf[5:], evt.KernelTime)
+	binary.LittleEndian.PutUint64(buf[13:], seq)
+	h.Write(buf[:])
+	return h.Sum64()
+}
+
+// toWire converts evt to its wire form with the given ID.
+func toWire(evt *event.Event, id uint64) wire.Event {
+	return wire.Event{
+		ID:        id,
 		Type:      evt.Type.String(),
 		Timestamp: evt.Timestamp.UnixMilli(),
 		PID:       evt.PID,
@@ -153,7 +178,10 @@ func (e *NATSExporter) enqueue(evt *event.Event) {
 		Labels:    evt.Labels,
 		Numerics:  evt.Numeric,
 	}
-	data, err := json.Marshal(w)
+}
+
+func (e *NATSExporter) enqueue(evt *event.Event) {
+	data, err := wire.Encode(toWire(evt, eventID(evt, e.seq.Add(1))))
 	if err != nil {
 		return
 	}
@@ -178,12 +206,34 @@ func (e *NATSExporter) flush() {
 	e.batch = make([][]byte, 0, e.cfg.BatchSize)
 	e.mu.Unlock()
 
-	for _, data := range batch {
-		e.nc.Publish(e.cfg.Subject, data)
+	if e.cfg.Compression == "" {
+		for _, data := range batch {
+			e.nc.Publish(e.cfg.Subject, data)
+		}
+	} else {
+		e.publishPacks(batch)
 	}
 	e.nc.Flush()
 }
 
+// publishPacks publishes batch as compressed messages of up to PackSize
+// events each.
+func (e *NATSExporter) publishPacks(batch [][]byte) {
+	for len(batch) > 0 {
+		n := min(e.cfg.PackSize, len(batch))
+		data, err := wire.EncodePack(e.cfg.Compression, batch[:n])
+		batch = batch[n:]
+		if err != nil {
+			e.logger.Warn("Packing events", zap.Error(err), zap.Int("events", n))
+			continue
+		}
+		msg := nats.NewMsg(e.cfg.Subject)
+		msg.Header.Set(constants.NATSHeaderContentEncoding, e.cfg.Compression)
+		msg.Data = data
+		e.nc.PublishMsg(msg)
+	}
+}
+
 func (e *NATSExporter) flusher(ctx context.Context) {
 	ticker := time.NewTicker(e.cfg.FlushInterval)
 	defer ticker.Stop()
@@ -0,0 +1,22 @@
+package export
+
+import (
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+func TestEventID(t *testing.T) {
+	e := &event.Event{Type: event.TypeTCP, PID: 42, Node: "node-a", KernelTime: 123456789}
+	if eventID(e, 1) != eventID(e, 1) {
+		t.Error("eventID is not deterministic")
+	}
+	if eventID(e, 1) == eventID(e, 2) {
+		t.Error("events with different sequence numbers share an ID")
+	}
+	other := *e
+	other.Node = "node-b"
+	if eventID(e, 1) == eventID(&other, 1) {
+		t.Error("events from different nodes share an ID")
+	}
+}
@@ -2,7 +2,10 @@ package export
 
 import (
 	"context"
+	"crypto/tls"
+	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
 	"sync/atomic"
 	"time"
@@ -12,125 +15,172 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.uber.org/zap"
 
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/dnsname"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
 )
 
 // Prometheus is an Exporter that consumes events from the EventBus
 // and updates Prometheus metrics. Implements the Exporter interface.
 type Prometheus struct {
+	*aggregator // the event metrics
+
 	addr   string
 	logger *zap.Logger
 	bus    *event.Bus
 	events <-chan *event.Event
 	server *http.Server
 	ready  atomic.Bool
+	mods   *readiness.Tracker      // module states for /readyz; see WatchModules
+	extra  map[string]http.Handler // mounted next to /metrics by Handle
+	bpf    *bpfStats
+	bpfSrc func() map[string]*bpfutil.Resources // see WatchBPF
+	auth   *BasicAuth                           // see Secure
+	tlsCfg *tls.Config                          // see Secure
+	scrape http.Handler                         // /metrics, over the registry the metrics are in
 
-	// Network metrics
-	tcpLatency  *prometheus.HistogramVec
-	dnsQueries  *prometheus.CounterVec
-	dnsLatency  *prometheus.HistogramVec
-	retransmits *prometheus.CounterVec
-	tcpResets   *prometheus.CounterVec
-	packetDrops *prometheus.CounterVec
+	// Self-observability metrics
+	eventsDropped  *prometheus.CounterVec
+	busQueueDepth  *prometheus.GaugeVec
+	moduleErrors   *prometheus.CounterVec
+	bpfProgRuntime *prometheus.CounterVec
+	bpfProgRuns    *prometheus.CounterVec
+	bpfMapMax      *prometheus.GaugeVec
+	bpfMapEntries  *prometheus.GaugeVec
+}
 
-	// System metrics
-	oomKills      *prometheus.CounterVec
-	processExecs  *prometheus.CounterVec
-	fileIOLatency *prometheus.HistogramVec
-	fileIOOps     *prometheus.CounterVec
+// PrometheusOptions tune the metrics the Prometheus exporter emits.
+type PrometheusOptions struct {
+	CardinalityConfig `yaml:",inline"`
+
+	// NativeHistograms emits the latency histograms as native (sparse)
+	// histograms instead of classic fixed buckets. Only Prometheus 2.40+
+	// with native histograms enabled can scrape them; see README.
+	NativeHistograms bool `yaml:"native_histograms"`
+
+	// DNSDomainMode and DNSDomainLevels select how query names are reduced
+	// to the domain label of kubepulse_dns_queries_total; see
+	// dnsname.Truncator.
+	DNSDomainMode   string `yaml:"dns_domain_mode"`
+	DNSDomainLevels int    `yaml:"dns_domain_levels"`
+
+	// DropPodLabels adds namespace and pod labels to
+	// kubepulse_packet_drops_total. Drops are attributed to the task that
+	// freed the packet, which is often not the pod it was for, so they are
+	// off by default.
+	DropPodLabels bool `yaml:"drop_pod_labels"`
+
+	// Relabel rewrites the labels of the event metrics; see RelabelRule.
+	Relabel []RelabelRule `yaml:"relabel"`
+}
 
-	// Self-observability metrics
-	eventsProcessed *prometheus.CounterVec
-	eventsDropped   *prometheus.CounterVec
-	busQueueDepth   *prometheus.GaugeVec
-	moduleErrors    *prometheus.CounterVec
+// DefaultPrometheusOptions returns the defaults: classic histograms, the
+// default cardinality limits and registered domains.
+func DefaultPrometheusOptions() PrometheusOptions {
+	return PrometheusOptions{
+		CardinalityConfig: DefaultCardinalityConfig(),
+		DNSDomainMode:     dnsname.Default.Mode,
+		DNSDomainLevels:   dnsname.Default.Levels,
+	}
+}
+
+// DNSDomain returns the truncator the options select.
+func (o PrometheusOptions) DNSDomain() dnsname.Truncator {
+	return dnsname.Truncator{Mode: o.DNSDomainMode, Levels: o.DNSDomainLevels}
+}
+
+// latency returns the options for a latency histogram: classic buckets,
+// or native buckets growing by constants.NativeHistogramBucketFactor.
+func (o PrometheusOptions) latency(name, help string, buckets []float64) prometheus.HistogramOpts {
+	opts := prometheus.HistogramOpts{Name: name, Help: help}
+	if !o.NativeHistograms {
+		opts.Buckets = buckets
+		return opts
+	}
+	opts.NativeHistogramBucketFactor = constants.NativeHistogramBucketFactor
+	opts.NativeHistogramMaxBucketNumber = constants.NativeHistogramMaxBuckets
+	opts.NativeHistogramMinResetDuration = constants.NativeHistogramMinResetDuration
+	return opts
+}
+
+// dropLabels returns the labels of the packet drop counter.
+func dropLabels(pods bool) []string {
+	if pods {
+		return constants.LabelsNamespacePodReasonProtocolNode
+	}
+	return constants.LabelsReasonProtocolNode
 }
 
 // NewPrometheus creates a Prometheus exporter that subscribes to the EventBus.
 // All metric names, buckets, and labels are sourced from the constants package.
-func NewPrometheus(addr string, bus *event.Bus, logger *zap.Logger) *Prometheus {
+//
+// The metrics are registered with reg, or with the default registry when
+// reg is nil, so each exporter built on its own registry is independent.
+// /metrics serves reg when it is also a Gatherer, as a *prometheus.Registry
+// is; the default registry also holds the agent's package-level metrics.
+func NewPrometheus(addr string, opts PrometheusOptions, bus *event.Bus, reg prometheus.Registerer, logger *zap.Logger) *Prometheus {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	gatherer, ok := reg.(prometheus.Gatherer)
+	if !ok {
+		gatherer = prometheus.DefaultGatherer
+	}
+	factory := promauto.With(reg)
 	p := &Prometheus{
-		addr:   addr,
-		logger: logger,
-		bus:    bus,
-
-		// --- Network Metrics ---
-		tcpLatency: promauto.NewHistogramVec(prometheus.HistogramOpts{
-			Name:    constants.MetricTCPLatency,
-			Help:    "TCP connection latency.",
-			Buckets: constants.NetworkLatencyBuckets,
-		}, constants.LabelsNamespacePodNode),
-
-		dnsQueries: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricDNSQueries,
-			Help: "Total DNS queries observed.",
-		}, constants.LabelsNamespacePodDomainNode),
-
-		dnsLatency: promauto.NewHistogramVec(prometheus.HistogramOpts{
-			Name:    constants.MetricDNSLatency,
-			Help:    "DNS query latency.",
-			Buckets: constants.NetworkLatencyBuckets,
-		}, constants.LabelsNamespacePodNode),
-
-		retransmits: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricTCPRetransmits,
-			Help: "Total TCP retransmissions.",
-		}, constants.LabelsNamespacePodNode),
-
-		tcpResets: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricTCPResets,
-			Help: "Total TCP connection resets.",
-		}, constants.LabelsNamespacePodNode),
-
-		packetDrops: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricPacketDrops,
-			Help: "Total packets dropped by kernel.",
-		}, constants.LabelsReasonNode),
-
-		// --- System Metrics ---
-		oomKills: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricOOMKills,
-			Help: "Total OOM kill events.",
-		}, constants.LabelsNamespacePodNode),
-
-		processExecs: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricProcessExecs,
-			Help: "Total process executions.",
-		}, constants.LabelsNamespacePodNode),
-
-		fileIOLatency: promauto.NewHistogramVec(prometheus.HistogramOpts{
-			Name:    constants.MetricFileIOLatency,
-			Help:    "File I/O latency.",
-			Buckets: constants.IOLatencyBuckets,
-		}, constants.LabelsNamespacePodOpNode),
-
-		fileIOOps: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricFileIOOps,
-			Help: "Total slow file I/O operations.",
-		}, constants.LabelsNamespacePodOpNode),
+		aggregator: newAggregator(opts, factory, logger),
+		addr:       addr,
+		logger:     logger,
+		bus:        bus,
+		scrape:     promhttp.InstrumentMetricHandler(reg, promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{})),
 
 		// --- Self-Observability ---
-		eventsProcessed: promauto.NewCounterVec(prometheus.CounterOpts{
-			Name: constants.MetricEventsProcessed,
-			Help: "Total events processed by exporter.",
-		}, constants.LabelsModule),
-
-		eventsDropped: promauto.NewCounterVec(prometheus.CounterOpts{
+		eventsDropped: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: constants.MetricEventsDropped,
 			Help: "Total events dropped due to backpressure.",
 		}, constants.LabelsSubscriber),
 
-		busQueueDepth: promauto.NewGaugeVec(prometheus.GaugeOpts{
+		busQueueDepth: factory.NewGaugeVec(prometheus.GaugeOpts{
 			Name: constants.MetricBusQueueDepth,
 			Help: "Current event bus queue depth per subscriber.",
 		}, constants.LabelsSubscriber),
 
-		moduleErrors: promauto.NewCounterVec(prometheus.CounterOpts{
+		moduleErrors: factory.NewCounterVec(prometheus.CounterOpts{
 			Name: constants.MetricModuleErrors,
 			Help: "Total errors by module.",
 		}, constants.LabelsModule),
+
+		bpfProgRuntime: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: constants.MetricBPFProgRuntime,
+			Help: "CPU time spent in each BPF program (needs kernel BPF stats).",
+		}, constants.LabelsModuleProg),
+
+		bpfProgRuns: factory.NewCounterVec(prometheus.CounterOpts{
+			Name: constants.MetricBPFProgRuns,
+			Help: "Times each BPF program ran (needs kernel BPF stats).",
+		}, constants.LabelsModuleProg),
+
+		bpfMapMax: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: constants.MetricBPFMapMax,
+			Help: "Capacity of each BPF map (bytes for ring buffers).",
+		}, constants.LabelsModuleMap),
+
+		bpfMapEntries: factory.NewGaugeVec(prometheus.GaugeOpts{
+			Name: constants.MetricBPFMapEntries,
+			Help: "Current entries in each BPF hash map.",
+		}, constants.LabelsModuleMap),
 	}
+	factory.NewCounterFunc(prometheus.CounterOpts{
+		Name: constants.MetricEventPoolAcquired,
+		Help: "Events taken from the event pool.",
+	}, func() float64 { acquired, _ := event.PoolStats(); return float64(acquired) })
+	factory.NewCounterFunc(prometheus.CounterOpts{
+		Name: constants.MetricEventPoolAllocated,
+		Help: "Events allocated because the event pool was empty; close to acquired means events are not reused.",
+	}, func() float64 { _, allocated := event.PoolStats(); return float64(allocated) })
+	p.bpf = newBPFStats(p.bpfProgRuntime, p.bpfProgRuns, p.bpfMapMax, p.bpfMapEntries, logger)
 
 	// Subscribe to event bus
 	p.events = bus.Subscribe(constants.ExporterPrometheus)
@@ -140,36 +190,99 @@ func NewPrometheus(addr string, bus *event.Bus, logger *zap.Logger) *Prometheus
 
 func (p *Prometheus) Name() string { return constants.ExporterPrometheus }
 
-func (p *Prometheus) Start(ctx context.Context) error {
+// WatchBPF enables BPF program and map metrics for the modules source
+// returns. Must be called before Start.
+func (p *Prometheus) WatchBPF(source func() map[string]*bpfutil.Resources) {
+	p.bpfSrc = source
+}
+
+// WatchModules makes /readyz report ready only while t is, and list each
+// module's state. Must be called before Start.
+func (p *Prometheus) WatchModules(t *readiness.Tracker) {
+	p.mods = t
+}
+
+// Handle mounts an additional handler (e.g. an admin endpoint) on the
+// metrics server. Must be called before Start.
+func (p *Prometheus) Handle(pattern string, h http.Handler) {
+	if p.extra == nil {
+		p.extra = make(map[string]http.Handler)
+	}
+	p.extra[pattern] = h
+}
+
+// Secure serves the metrics server over TLS and behind basic auth as
+// cfg sets. It loads the key pair now, so a bad certificate fails startup
+// rather than leaving the agent without a metrics endpoint. Must be
+// called before Start.
+func (p *Prometheus) Secure(cfg MetricsSecurity) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	tlsCfg, err := cfg.TLS.tlsConfig()
+	if err != nil {
+		return err
+	}
+	p.tlsCfg = tlsCfg
+	if cfg.BasicAuth.Username != "" {
+		p.auth = &cfg.BasicAuth
+	}
+	return nil
+}
+
+// handler returns the metrics server's routes, behind basic auth if
+// Secure enabled it.
+func (p *Prometheus) handler() http.Handler {
 	mux := http.NewServeMux()
-	mux.Handle(constants.PathMetrics, promhttp.Handler())
+	mux.Handle(constants.PathMetrics, p.scrape)
 	mux.HandleFunc(constants.PathHealthz, func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("ok\n"))
 	})
-	mux.HandleFunc(constants.PathReadyz, func(w http.ResponseWriter, r *http.Request) {
-		if p.ready.Load() {
-			w.WriteHeader(http.StatusOK)
-			w.Write([]byte("ready\n"))
-		} else {
-			w.WriteHeader(http.StatusServiceUnavailable)
-			w.Write([]byte("not ready\n"))
-		}
-	})
+	mux.HandleFunc(constants.PathReadyz, p.serveReadyz)
+	for pattern, h := range p.extra {
+		mux.Handle(pattern, h)
+	}
+	if p.auth != nil {
+		return newBasicAuthHandler(mux, *p.auth)
+	}
+	return mux
+}
 
+// listen opens the metrics listener, over TLS if Secure configured it.
+func (p *Prometheus) listen() (net.Listener, error) {
+	ln, err := net.Listen("tcp", p.addr)
+	if err != nil || p.tlsCfg == nil {
+		return ln, err
+	}
+	return tls.NewListener(ln, p.tlsCfg), nil
+}
+
+func (p *Prometheus) Start(ctx context.Context) error {
 	p.server = &http.Server{
 		Addr:         p.addr,
-		Handler:      mux,
+		Handler:      p.handler(),
 		ReadTimeout:  constants.HTTPReadTimeout,
 		WriteTimeout: constants.HTTPWriteTimeout,
 		IdleTimeout:  constants.HTTPIdleTimeout,
 	}
 
+	if p.domain.Mode == dnsname.ModeFull {
+		p.logger.Warn("DNS domain label holds full query names; its cardinality is unbounded",
+			zap.String("metric", constants.MetricDNSQueries))
+	}
 	go func() {
+		ln, err := p.listen()
+		if err != nil {
+			p.logger.Error("Prometheus HTTP server error", zap.Error(err))
+			return
+		}
 		p.logger.Info("Prometheus exporter listening",
 			zap.String("addr", p.addr),
-			zap.String("path", constants.PathMetrics))
-		if err := p.server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			zap.String("path", constants.PathMetrics),
+			zap.Bool("tls", p.tlsCfg != nil),
+			zap.Bool("basic_auth", p.auth != nil))
+		if err := p.server.Serve(ln); err != nil && err != http.ErrServerClosed {
 			p.logger.Error("Prometheus HTTP server error", zap.Error(err))
 		}
 	}()
@@ -178,6 +291,10 @@ func (p *Prometheus) Start(ctx context.Context) error {
 
 	p.ready.Store(true)
 
+	// The sweep runs on this goroutine so the guard needs no lock.
+	sweep, stopSweep := p.sweepTicker()
+	defer stopSweep()
+
 	// Main event consumption loop
 	for {
 		select {
@@ -188,6 +305,9 @@ func (p *Prometheus) Start(ctx context.Context) error {
 				return nil
 			}
 			p.processEvent(evt)
+			evt.Done()
+		case now := <-sweep:
+			p.sweep(now)
 		}
 	}
 }
@@ -200,51 +320,36 @@ func (p *Prometheus) Stop(ctx context.Context) error {
 	return nil
 }
 
+// serveReadyz reports ready once the exporter has started and, with
+// WatchModules, while enough modules are running. The JSON body lists
+// each module's state.
+func (p *Prometheus) serveReadyz(w http.ResponseWriter, _ *http.Request) {
+	var report readiness.Report
+	if p.mods != nil {
+		report = p.mods.Report()
+	} else {
+		report.Status = "ready"
+	}
+	if !p.ready.Load() {
+		report.Status = "not ready"
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if report.Status == "ready" {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(report)
+}
+
 // SetReady marks the exporter as ready for readiness probes.
 func (p *Prometheus) SetReady() {
 	p.ready.Store(true)
 }
 
-// processEvent dispatches an event to the correct Prometheus metric.
-// Uses constants for event label/numeric keys — Strategy pattern for dispatch.
-func (p *Prometheus) processEvent(e *event.Event) {
-	p.eventsProcessed.WithLabelValues(e.Type.String()).Inc()
-
-	switch e.Type {
-	case event.TypeTCP:
-		p.tcpLatency.WithLabelValues(e.Namespace, e.Pod, e.Node).
-			Observe(e.NumericVal(constants.KeyLatencySec))
-
-	case event.TypeDNS:
-		p.dnsQueries.WithLabelValues(e.Namespace, e.Pod, e.Label(constants.KeyDomain), e.Node).Inc()
-		if latency := e.NumericVal(constants.KeyLatencySec); latency > 0 {
-			p.dnsLatency.WithLabelValues(e.Namespace, e.Pod, e.Node).Observe(latency)
-		}
-
-	case event.TypeRetransmit:
-		p.retransmits.WithLabelValues(e.Namespace, e.Pod, e.Node).Inc()
-
-	case event.TypeRST:
-		p.tcpResets.WithLabelValues(e.Namespace, e.Pod, e.Node).Inc()
-
-	case event.TypeOOM:
-		p.oomKills.WithLabelValues(e.Namespace, e.Pod, e.Node).Inc()
-
-	case event.TypeExec:
-		p.processExecs.WithLabelValues(e.Namespace, e.Pod, e.Node).Inc()
-
-	case event.TypeFileIO:
-		op := e.Label(constants.KeyOp)
-		p.fileIOLatency.WithLabelValues(e.Namespace, e.Pod, op, e.Node).
-			Observe(e.NumericVal(constants.KeyLatencySec))
-		p.fileIOOps.WithLabelValues(e.Namespace, e.Pod, op, e.Node).Inc()
-
-	case event.TypeDrop:
-		p.packetDrops.WithLabelValues(e.Label(constants.KeyReason), e.Node).Inc()
-	}
-}
-
-// collectBusStats periodically updates event bus self-observability metrics.
+// collectBusStats periodically updates event bus and BPF resource
+// self-observability metrics.
 func (p *Prometheus) collectBusStats(ctx context.Context) {
 	ticker := time.NewTicker(constants.StatsCollectInterval)
 	defer ticker.Stop()
@@ -261,6 +366,9 @@ func (p *Prometheus) collectBusStats(ctx context.Context) {
 			for name, drops := range stats.DroppedBySubscriber {
 				p.eventsDropped.WithLabelValues(name).Add(float64(drops))
 			}
+			if p.bpfSrc != nil {
+				p.bpf.collect(p.bpfSrc())
+			}
 		}
 	}
 }
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/readiness"
+)
+
+// newTestPrometheus returns an exporter whose metrics are in a registry of
+// its own, also returned.
+func newTestPrometheus(t *testing.T) (*Prometheus, *prometheus.Registry) {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	return NewPrometheus("127.0.0.1:0", DefaultPrometheusOptions(), event.NewBus(16, nil), reg, zap.NewNop()), reg
+}
+
+// scrapeHistogram registers a TCP latency histogram built from opts,
+// observes a few latencies, and scrapes it through promhttp the way a
+// native-histogram-capable Prometheus does (protobuf exposition).
+func scrapeHistogram(t *testing.T, opts PrometheusOptions) *dto.Histogram {
+	t.Helper()
+	reg := prometheus.NewRegistry()
+	h := prometheus.NewHistogramVec(opts.latency(
+		constants.MetricTCPLatency, "TCP connection latency.", constants.NetworkLatencyBuckets,
+	), constants.LabelsNamespacePodNode)
+	reg.MustRegister(h)
+	for _, v := range []float64{0.0003, 0.002, 0.002, 0.04, 1.2} {
+		h.WithLabelValues("default", "web-0", "node-1").Observe(v)
+	}
+
+	srv := httptest.NewServer(promhttp.HandlerFor(reg, promhttp.HandlerOpts{}))
+	defer srv.Close()
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	req.Header.Set("Accept", string(expfmt.NewFormat(expfmt.TypeProtoDelim)))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var mf dto.MetricFamily
+	if err := expfmt.NewDecoder(resp.Body, expfmt.ResponseFormat(resp.Header)).Decode(&mf); err != nil {
+		t.Fatal(err)
+	}
+	if mf.GetName() != constants.MetricTCPLatency || len(mf.Metric) != 1 {
+		t.Fatalf("scraped %s with %d series, want one %s series", mf.GetName(), len(mf.Metric), constants.MetricTCPLatency)
+	}
+	hist := mf.Metric[0].GetHistogram()
+	if hist.GetSampleCount() != 5 {
+		t.Errorf("sample count = %d, want 5", hist.GetSampleCount())
+	}
+	return hist
+}
+
+func TestLatencyHistogram_Classic(t *testing.T) {
+	hist := scrapeHistogram(t, DefaultPrometheusOptions())
+	if got := len(hist.GetBucket()); got != len(constants.NetworkLatencyBuckets) {
+		t.Errorf("classic buckets = %d, want %d", got, len(constants.NetworkLatencyBuckets))
+	}
+	if len(hist.GetPositiveSpan()) != 0 {
+		t.Error("classic histogram exposed native buckets")
+	}
+}
+
+func TestLatencyHistogram_Native(t *testing.T) {
+	opts := DefaultPrometheusOptions()
+	opts.NativeHistograms = true
+	hist := scrapeHistogram(t, opts)
+	if len(hist.GetPositiveSpan()) == 0 || len(hist.GetPositiveDelta()) == 0 {
+		t.Fatal("native histogram exposed no sparse buckets")
+	}
+	if hist.GetSchema() < 3 {
+		t.Errorf("schema = %d, want >= 3 for a %.1f bucket factor", hist.GetSchema(), constants.NativeHistogramBucketFactor)
+	}
+	if got := len(hist.GetBucket()); got != 0 {
+		t.Errorf("native histogram also exposed %d classic buckets", got)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	p, _ := newTestPrometheus(t)
+	mods := readiness.New(1)
+	p.WatchModules(mods)
+
+	get := func() (int, readiness.Report) {
+		rec := httptest.NewRecorder()
+		p.serveReadyz(rec, httptest.NewRequest(http.MethodGet, constants.PathReadyz, nil))
+		var r readiness.Report
+		if err := json.Unmarshal(rec.Body.Bytes(), &r); err != nil {
+			t.Fatalf("body %q: %v", rec.Body, err)
+		}
+		return rec.Code, r
+	}
+
+	mods.Set("tcp", readiness.Running)
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("before start: %d, want 503", code)
+	}
+	p.SetReady()
+	mods.Set("tcp", readiness.Initializing)
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("started, module still initializing: %d, want 503", code)
+	}
+	mods.Set("tcp", readiness.Running)
+	mods.Set("dns", readiness.Disabled)
+	code, r := get()
+	if code != http.StatusOK || r.Status != "ready" {
+		t.Errorf("started, module running: %d %q, want 200 ready", code, r.Status)
+	}
+	if r.Modules["tcp"] != readiness.Running || r.Modules["dns"] != readiness.Disabled {
+		t.Errorf("modules = %v", r.Modules)
+	}
+	mods.Set("tcp", readiness.Failed)
+	if code, r := get(); code != http.StatusServiceUnavailable || r.Status != "not ready" {
+		t.Errorf("started, no module running: %d %q, want 503 not ready", code, r.Status)
+	}
+}
+
+func TestNewPrometheus_IndependentRegistries(t *testing.T) {
+	a, regA := newTestPrometheus(t)
+	b, regB := newTestPrometheus(t)
+
+	e := event.Acquire()
+	e.Type = event.TypeOOM
+	e.Namespace, e.Pod, e.Node = "default", "web-0", "node-1"
+	a.processEvent(e)
+	e.Release()
+
+	if got := testutil.ToFloat64(a.oomKills.WithLabelValues("default", "web-0", "node-1")); got != 1 {
+		t.Errorf("first exporter's OOM kills = %v, want 1", got)
+	}
+	if n, err := testutil.GatherAndCount(regB, constants.MetricOOMKills); err != nil || n != 0 {
+		t.Errorf("second exporter's registry has %d OOM kill series (%v), want none", n, err)
+	}
+	if n, err := testutil.GatherAndCount(regA, constants.MetricOOMKills); err != nil || n != 1 {
+		t.Errorf("first exporter's registry has %d OOM kill series (%v), want 1", n, err)
+	}
+
+	// /metrics serves the exporter's own registry.
+	rec := httptest.NewRecorder()
+	a.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, constants.PathMetrics, nil))
+	if !strings.Contains(rec.Body.String(), constants.MetricOOMKills+`{namespace="default",node="node-1",pod="web-0"} 1`) {
+		t.Errorf("/metrics does not show the OOM kill:\n%s", rec.Body)
+	}
+	rec = httptest.NewRecorder()
+	b.handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, constants.PathMetrics, nil))
+	if strings.Contains(rec.Body.String(), constants.MetricOOMKills+"{") {
+		t.Error("second exporter's /metrics shows the first one's OOM kill")
+	}
+}
+
+func TestPacketDrops_Labels(t *testing.T) {
+	drop := func(ns, pod string) *event.Event {
+		e := event.Acquire()
+		e.Type = event.TypeDrop
+		e.Namespace, e.Pod, e.Node = ns, pod, "node-1"
+		e.SetLabel(constants.KeyReason, "NO_SOCKET")
+		e.SetLabel(constants.KeyProtocol, constants.ProtocolUDP)
+		return e
+	}
+
+	p, reg := newTestPrometheus(t)
+	for _, e := range []*event.Event{drop("default", "web-0"), drop("", "")} {
+		p.processEvent(e)
+		e.Release()
+	}
+	if got := testutil.ToFloat64(p.packetDrops.WithLabelValues("NO_SOCKET", constants.ProtocolUDP, "node-1")); got != 2 {
+		t.Errorf("drops without pod labels = %v, want both in one series", got)
+	}
+	if n, _ := testutil.GatherAndCount(reg, constants.MetricPacketDrops); n != 1 {
+		t.Errorf("%d drop series, want 1", n)
+	}
+
+	opts := DefaultPrometheusOptions()
+	opts.DropPodLabels = true
+	reg = prometheus.NewRegistry()
+	p = NewPrometheus("127.0.0.1:0", opts, event.NewBus(16, nil), reg, zap.NewNop())
+	for _, e := range []*event.Event{drop("default", "web-0"), drop("", "")} {
+		p.processEvent(e)
+		e.Release()
+	}
+	if got := testutil.ToFloat64(p.packetDrops.WithLabelValues("default", "web-0", "NO_SOCKET", constants.ProtocolUDP, "node-1")); got != 1 {
+		t.Errorf("web-0 drops = %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(p.packetDrops.WithLabelValues("", "", "NO_SOCKET", constants.ProtocolUDP, "node-1")); got != 1 {
+		t.Errorf("unattributed drops = %v, want 1 with an empty namespace", got)
+	}
+}
@@ -0,0 +1,254 @@
+package export
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// RelabelRule rewrites the labels of the exporter's event metrics as they
+// are observed, so dashboards can be matched without a relabeling proxy.
+// Label always names one of the exporter's own labels, before any rename.
+type RelabelRule struct {
+	Action      string `yaml:"action"`       // drop, rename or replace
+	Label       string `yaml:"label"`        // e.g. pod
+	Metric      string `yaml:"metric"`       // one metric; empty applies to every metric with Label
+	TargetLabel string `yaml:"target_label"` // rename: the new name
+	Regex       string `yaml:"regex"`        // replace: must match the whole value
+	Replacement string `yaml:"replacement"`  // replace: may use $1 and ${name}
+}
+
+// labelNameRE matches the label names Prometheus accepts; names starting
+// with __ are reserved.
+var labelNameRE = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// eventMetricLabels returns the labels of the metrics built from events,
+// the ones relabel rules apply to, before relabeling.
+func eventMetricLabels(dropPods bool) map[string][]string {
+	return map[string][]string{
+		constants.MetricTCPLatency:         constants.LabelsNamespacePodNode,
+		constants.MetricTCPConnectFailures: constants.LabelsNamespacePodErrorNode,
+		constants.MetricTCPAcceptLatency:   constants.LabelsNamespacePodNode,
+		constants.MetricDNSQueries:         constants.LabelsNamespacePodDomainQTypeNode,
+		constants.MetricDNSLatency:         constants.LabelsNamespacePodNode,
+		constants.MetricTCPRetransmits:     constants.LabelsNamespacePodNode,
+		constants.MetricTCPResets:          constants.LabelsNamespacePodDirectionStateNode,
+		constants.MetricPacketDrops:        dropLabels(dropPods),
+		constants.MetricOOMKills:           constants.LabelsNamespacePodNode,
+		constants.MetricProcessExecs:       constants.LabelsNamespacePodNode,
+		constants.MetricFileIOLatency:      constants.LabelsNamespacePodOpFSNode,
+		constants.MetricFileIOOps:          constants.LabelsNamespacePodOpFSNode,
+		constants.MetricSignals:            constants.LabelsNamespacePodSignalNode,
+	}
+}
+
+// ValidateRelabel checks the relabel rules against the metrics these
+// options produce.
+func (o PrometheusOptions) ValidateRelabel() error {
+	_, err := o.relabeler()
+	return err
+}
+
+// relabeler compiles the relabel rules into a labelMap for each event
+// metric. Rules apply in order.
+func (o PrometheusOptions) relabeler() (map[string]*labelMap, error) {
+	base := eventMetricLabels(o.DropPodLabels)
+	lms := make(map[string]*labelMap, len(base))
+	for metric, labels := range base {
+		lms[metric] = newLabelMap(labels)
+	}
+
+	var errs []string
+	for i, r := range o.Relabel {
+		prefix := fmt.Sprintf("relabel[%d]", i)
+		var re *regexp.Regexp
+		switch r.Action {
+		case constants.RelabelDrop:
+		case constants.RelabelRename:
+			if !labelNameRE.MatchString(r.TargetLabel) || strings.HasPrefix(r.TargetLabel, "__") {
+				errs = append(errs, fmt.Sprintf("%s.target_label %q is not a valid label name", prefix, r.TargetLabel))
+				continue
+			}
+		case constants.RelabelReplace:
+			var err error
+			if re, err = regexp.Compile("^(?:" + r.Regex + ")$"); err != nil || r.Regex == "" {
+				errs = append(errs, fmt.Sprintf("%s.regex %q must be a valid, non-empty regex", prefix, r.Regex))
+				continue
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("%s.action must be %s, %s or %s, got %q",
+				prefix, constants.RelabelDrop, constants.RelabelRename, constants.RelabelReplace, r.Action))
+			continue
+		}
+
+		metrics, target := slices.Sorted(maps.Keys(lms)), "any event metric"
+		if r.Metric != "" {
+			if lms[r.Metric] == nil {
+				errs = append(errs, fmt.Sprintf("%s.metric %q is not an event metric", prefix, r.Metric))
+				continue
+			}
+			metrics, target = []string{r.Metric}, r.Metric
+		}
+		applied := false
+		for _, metric := range metrics {
+			m := lms[metric]
+			if _, ok := m.from[r.Label]; !ok {
+				continue
+			}
+			applied = true
+			if err := m.apply(r, re); err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %s: %v", prefix, metric, err))
+			}
+		}
+		if !applied {
+			errs = append(errs, fmt.Sprintf("%s.label %q is not a label of %s", prefix, r.Label, target))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	return lms, nil
+}
+
+// labelMap turns the label values the exporter observes a metric with
+// into the values of its relabeled series.
+type labelMap struct {
+	names   []string       // the metric's labels after relabeling
+	from    map[string]int // original label → position in the values observed
+	to      []int          // by position observed: position in names, -1 if dropped
+	rewrite [][]valueRule  // by position observed
+	ruled   bool           // false while no rule applies: values pass through
+}
+
+// valueRule is a compiled replace rule.
+type valueRule struct {
+	re          *regexp.Regexp
+	replacement string
+}
+
+func newLabelMap(labels []string) *labelMap {
+	m := &labelMap{
+		names:   slices.Clone(labels),
+		from:    make(map[string]int, len(labels)),
+		to:      make([]int, len(labels)),
+		rewrite: make([][]valueRule, len(labels)),
+	}
+	for i, l := range labels {
+		m.from[l] = i
+		m.to[i] = i
+	}
+	return m
+}
+
+// apply adds rule r, whose regex is re for replace, to m.
+func (m *labelMap) apply(r RelabelRule, re *regexp.Regexp) error {
+	i := m.from[r.Label]
+	if m.to[i] < 0 {
+		return fmt.Errorf("label %s is already dropped", r.Label)
+	}
+	m.ruled = true
+	switch r.Action {
+	case constants.RelabelDrop:
+		m.names = slices.Delete(m.names, m.to[i], m.to[i]+1)
+		for j, pos := range m.to {
+			if pos > m.to[i] {
+				m.to[j]--
+			}
+		}
+		m.to[i] = -1
+	case constants.RelabelRename:
+		if slices.Contains(m.names, r.TargetLabel) {
+			return fmt.Errorf("renaming %s to %s clashes with an existing label", r.Label, r.TargetLabel)
+		}
+		m.names[m.to[i]] = r.TargetLabel
+	case constants.RelabelReplace:
+		m.rewrite[i] = append(m.rewrite[i], valueRule{re: re, replacement: r.Replacement})
+	}
+	return nil
+}
+
+// kept reports whether the original label survives relabeling, under any
+// name.
+func (m *labelMap) kept(label string) bool {
+	i, ok := m.from[label]
+	return ok && m.to[i] >= 0
+}
+
+// value rewrites the value observed at position i.
+func (m *labelMap) value(i int, v string) string {
+	for _, r := range m.rewrite[i] {
+		if match := r.re.FindStringSubmatchIndex(v); match != nil {
+			v = string(r.re.ExpandString(nil, r.replacement, v, match))
+		}
+	}
+	return v
+}
+
+// values maps the values a metric is observed with to its relabeled series.
+func (m *labelMap) values(vals []string) []string {
+	if !m.ruled {
+		return vals
+	}
+	out := make([]string, len(m.names))
+	for i, v := range vals {
+		if m.to[i] >= 0 {
+			out[m.to[i]] = m.value(i, v)
+		}
+	}
+	return out
+}
+
+// labels maps a partial match on the original labels to the relabeled
+// ones. Dropped labels are left out of the match.
+func (m *labelMap) labels(match prometheus.Labels) prometheus.Labels {
+	if !m.ruled {
+		return match
+	}
+	out := make(prometheus.Labels, len(match))
+	for name, v := range match {
+		if i, ok := m.from[name]; ok && m.to[i] >= 0 {
+			out[m.names[m.to[i]]] = m.value(i, v)
+		}
+	}
+	return out
+}
+
+// labeledVec is the part of a metric vector relabeled wraps:
+// *prometheus.CounterVec with M prometheus.Counter, *prometheus.HistogramVec
+// with prometheus.Observer.
+type labeledVec[M any] interface {
+	WithLabelValues(lvs ...string) M
+	DeletePartialMatch(labels prometheus.Labels) int
+}
+
+// relabeled is a metric vector observed with the exporter's own labels
+// and stored with the relabeled ones. Series whose labels become equal,
+// when a label is dropped or values are rewritten to the same one, are a
+// single series: counters sum and histograms merge.
+type relabeled[M any] struct {
+	vec labeledVec[M]
+	m   *labelMap
+}
+
+// WithLabelValues returns the series for the original label values.
+func (r relabeled[M]) WithLabelValues(vals ...string) M {
+	return r.vec.WithLabelValues(r.m.values(vals)...)
+}
+
+// DeletePartialMatch deletes the series matching labels, given by their
+// original names and values. A match on dropped labels only deletes
+// nothing, rather than every series.
+func (r relabeled[M]) DeletePartialMatch(labels prometheus.Labels) int {
+	match := r.m.labels(labels)
+	if len(match) == 0 && len(labels) > 0 {
+		return 0
+	}
+	return r.vec.DeletePartialMatch(match)
+}
@@ -0,0 +1,135 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// relabeledPrometheus returns an exporter applying rules, with its own
+// registry.
+func relabeledPrometheus(t *testing.T, rules ...RelabelRule) (*Prometheus, *prometheus.Registry) {
+	t.Helper()
+	opts := DefaultPrometheusOptions()
+	opts.Relabel = rules
+	if err := opts.ValidateRelabel(); err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+	return NewPrometheus("127.0.0.1:0", opts, event.NewBus(16, nil), reg, zap.NewNop()), reg
+}
+
+// observe feeds the exporter one event of typ from each pod in default.
+func observe(p *Prometheus, typ event.EventType, latency float64, pods ...string) {
+	for _, pod := range pods {
+		e := event.Acquire()
+		e.Type = typ
+		e.Namespace, e.Pod, e.Node = "default", pod, "node-1"
+		e.SetNumeric(constants.KeyLatencySec, latency)
+		p.processEvent(e)
+		e.Release()
+	}
+}
+
+func TestRelabel_DropSumsSeries(t *testing.T) {
+	p, reg := relabeledPrometheus(t, RelabelRule{Action: constants.RelabelDrop, Label: constants.LabelPod})
+	observe(p, event.TypeRetransmit, 0, "web-0", "web-1", "web-1")
+	observe(p, event.TypeTCP, 0.001, "web-0", "web-1")
+
+	// The pods' series collapse into one per namespace: counters sum,
+	// histograms merge their observations.
+	want := `
+# HELP kubepulse_tcp_retransmits_total Total TCP retransmissions.
+# TYPE kubepulse_tcp_retransmits_total counter
+kubepulse_tcp_retransmits_total{namespace="default",node="node-1"} 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), constants.MetricTCPRetransmits); err != nil {
+		t.Error(err)
+	}
+	if got := testutil.ToFloat64(p.retransmits.WithLabelValues("default", "any-pod", "node-1")); got != 3 {
+		t.Errorf("retransmits = %v, want 3 whatever the pod", got)
+	}
+	if n, _ := testutil.GatherAndCount(reg, constants.MetricTCPLatency); n != 1 {
+		t.Errorf("%d latency series, want 1", n)
+	}
+	if p.guard.families[constants.MetricTCPRetransmits] != nil {
+		t.Error("pod guard tracks a metric without a pod label")
+	}
+}
+
+func TestRelabel_RenameAndReplace(t *testing.T) {
+	p, reg := relabeledPrometheus(t,
+		RelabelRule{Action: constants.RelabelRename, Label: constants.LabelNamespace, TargetLabel: "kubernetes_namespace"},
+		RelabelRule{Action: constants.RelabelReplace, Label: constants.LabelPod, Metric: constants.MetricTCPRetransmits,
+			Regex: `(.+)-[0-9]+`, Replacement: "$1"},
+	)
+	observe(p, event.TypeRetransmit, 0, "web-0", "web-1", "db")
+	observe(p, event.TypeOOM, 0, "web-0")
+
+	want := `
+# HELP kubepulse_oom_kills_total Total OOM kill events.
+# TYPE kubepulse_oom_kills_total counter
+kubepulse_oom_kills_total{kubernetes_namespace="default",node="node-1",pod="web-0"} 1
+# HELP kubepulse_tcp_retransmits_total Total TCP retransmissions.
+# TYPE kubepulse_tcp_retransmits_total counter
+kubepulse_tcp_retransmits_total{kubernetes_namespace="default",node="node-1",pod="db"} 1
+kubepulse_tcp_retransmits_total{kubernetes_namespace="default",node="node-1",pod="web"} 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(want), constants.MetricOOMKills, constants.MetricTCPRetransmits); err != nil {
+		t.Error(err)
+	}
+
+	// The sweep deletes by the original labels; they are relabeled too.
+	if n := p.retransmits.DeletePartialMatch(prometheus.Labels{constants.LabelNamespace: "default", constants.LabelPod: "web-1"}); n != 1 {
+		t.Errorf("deleted %d series for web-1, want its rewritten one", n)
+	}
+}
+
+func TestRelabel_DeleteByDroppedLabelOnly(t *testing.T) {
+	p, _ := relabeledPrometheus(t, RelabelRule{Action: constants.RelabelDrop, Label: constants.LabelPod})
+	observe(p, event.TypeOOM, 0, "web-0")
+	if n := p.oomKills.DeletePartialMatch(prometheus.Labels{constants.LabelPod: "web-0"}); n != 0 {
+		t.Errorf("a match on a dropped label deleted %d series", n)
+	}
+}
+
+func TestValidateRelabel_Rejects(t *testing.T) {
+	tests := map[string][]RelabelRule{
+		"action":          {{Action: "keep", Label: constants.LabelPod}},
+		"unknown label":   {{Action: constants.RelabelDrop, Label: "container"}},
+		"unknown metric":  {{Action: constants.RelabelDrop, Label: constants.LabelPod, Metric: "kubepulse_events_total"}},
+		"label of metric": {{Action: constants.RelabelDrop, Label: constants.LabelQType, Metric: constants.MetricTCPLatency}},
+		"target name":     {{Action: constants.RelabelRename, Label: constants.LabelPod, TargetLabel: "pod-name"}},
+		"reserved target": {{Action: constants.RelabelRename, Label: constants.LabelPod, TargetLabel: "__name__"}},
+		"target clash":    {{Action: constants.RelabelRename, Label: constants.LabelPod, TargetLabel: constants.LabelNode}},
+		"regex":           {{Action: constants.RelabelReplace, Label: constants.LabelPod, Regex: "("}},
+		"empty regex":     {{Action: constants.RelabelReplace, Label: constants.LabelPod}},
+		"dropped twice": {
+			{Action: constants.RelabelDrop, Label: constants.LabelPod},
+			{Action: constants.RelabelDrop, Label: constants.LabelPod, Metric: constants.MetricOOMKills},
+		},
+	}
+	for name, rules := range tests {
+		t.Run(name, func(t *testing.T) {
+			opts := DefaultPrometheusOptions()
+			opts.Relabel = rules
+			if err := opts.ValidateRelabel(); err == nil {
+				t.Errorf("accepted %+v", rules)
+			}
+		})
+	}
+
+	// Without drop_pod_labels the drop counter has no pod label, but a rule
+	// for every metric still applies to the rest.
+	opts := DefaultPrometheusOptions()
+	opts.Relabel = []RelabelRule{{Action: constants.RelabelDrop, Label: constants.LabelPod}}
+	if err := opts.ValidateRelabel(); err != nil {
+		t.Error(err)
+	}
+}
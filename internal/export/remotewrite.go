@@ -0,0 +1,360 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/buildinfo"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// RemoteWriteConfig holds the remote write exporter settings.
+type RemoteWriteConfig struct {
+	URL             string            `yaml:"url"`      // e.g. https://prometheus-prod-01.grafana.net/api/prom/push
+	Interval        time.Duration     `yaml:"interval"` // between snapshots
+	Timeout         time.Duration     `yaml:"timeout"`  // per POST
+	Retries         int               `yaml:"retries"`  // attempts after the first; 0 never retries
+	BearerToken     string            `yaml:"bearer_token"`
+	BearerTokenFile string            `yaml:"bearer_token_file"` // read on every push, so it can be rotated
+	BasicAuth       RemoteWriteAuth   `yaml:"basic_auth"`
+	ExternalLabels  map[string]string `yaml:"external_labels"` // e.g. cluster, added to every series
+}
+
+// RemoteWriteAuth is HTTP basic auth for the remote write endpoint, e.g. a
+// Grafana Cloud instance ID and API key. With no Username it is off.
+type RemoteWriteAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"` // read on every push
+}
+
+// DefaultRemoteWriteConfig returns defaults with no URL.
+func DefaultRemoteWriteConfig() RemoteWriteConfig {
+	return RemoteWriteConfig{
+		Interval: constants.RemoteWriteInterval,
+		Timeout:  constants.RemoteWriteTimeout,
+		Retries:  constants.RemoteWriteRetries,
+	}
+}
+
+// RedactedURL returns the URL with any password masked, for display.
+func (c RemoteWriteConfig) RedactedURL() string {
+	u, err := url.Parse(c.URL)
+	if err != nil {
+		return "<unparsable>"
+	}
+	return u.Redacted()
+}
+
+// Validate checks c without reading the credential files.
+func (c RemoteWriteConfig) Validate() error {
+	var errs []string
+	if u, err := url.Parse(c.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, "url must be an http or https URL")
+	}
+	if c.Interval <= 0 {
+		errs = append(errs, "interval must be > 0")
+	}
+	if c.Timeout <= 0 {
+		errs = append(errs, "timeout must be > 0")
+	}
+	if c.Retries < 0 {
+		errs = append(errs, "retries must be >= 0")
+	}
+	bearer := c.BearerToken != "" || c.BearerTokenFile != ""
+	if c.BearerToken != "" && c.BearerTokenFile != "" {
+		errs = append(errs, "bearer_token and bearer_token_file are mutually exclusive")
+	}
+	ba := c.BasicAuth
+	if ba.Username != "" && bearer {
+		errs = append(errs, "basic_auth and a bearer token are mutually exclusive")
+	}
+	if ba.Username != "" && (ba.Password == "") == (ba.PasswordFile == "") {
+		errs = append(errs, "basic_auth needs one of password and password_file")
+	}
+	if ba.Username == "" && (ba.Password != "" || ba.PasswordFile != "") {
+		errs = append(errs, "basic_auth.username is required with a password")
+	}
+	for name := range c.ExternalLabels {
+		if !labelNameRE.MatchString(name) || strings.HasPrefix(name, "__") {
+			errs = append(errs, fmt.Sprintf("external_labels: %q is not a valid label name", name))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+var (
+	rwSamples = promauto.NewCounter(prometheus.CounterOpts{
+		Name: constants.MetricRemoteWriteSamples,
+		Help: "Samples the remote write endpoint accepted.",
+	})
+	rwFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: constants.MetricRemoteWriteFailures,
+		Help: "Snapshots the remote write endpoint never accepted.",
+	})
+)
+
+// RemoteWrite is an Exporter that keeps the same event metrics as the
+// Prometheus exporter, in a registry of its own, and pushes a snapshot of
+// them to a Prometheus remote write endpoint every Interval. Snapshots
+// are cumulative, so one that fails only costs resolution.
+type RemoteWrite struct {
+	*aggregator // the event metrics
+
+	cfg    RemoteWriteConfig
+	logger *zap.Logger
+	events <-chan *event.Event
+	reg    *prometheus.Registry
+	client *http.Client
+
+	backoff, maxBackoff time.Duration
+}
+
+// NewRemoteWrite creates a remote write exporter that subscribes to the
+// EventBus. opts are the Prometheus exporter's, so both report the same
+// series; histograms are always sent with classic buckets.
+func NewRemoteWrite(cfg RemoteWriteConfig, opts PrometheusOptions, bus *event.Bus, logger *zap.Logger) *RemoteWrite {
+	opts.NativeHistograms = false
+	reg := prometheus.NewRegistry()
+	return &RemoteWrite{
+		aggregator: newAggregator(opts, promauto.With(reg), logger),
+		cfg:        cfg,
+		logger:     logger,
+		events:     bus.Subscribe(constants.ExporterRemoteWrite),
+		reg:        reg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		backoff:    constants.RemoteWriteRetryBackoff,
+		maxBackoff: constants.RemoteWriteRetryMaxBackoff,
+	}
+}
+
+func (r *RemoteWrite) Name() string { return constants.ExporterRemoteWrite }
+
+// Start aggregates events until the bus closes or ctx is cancelled,
+// pushing from a goroutine of its own so a slow endpoint never holds up
+// the event loop.
+func (r *RemoteWrite) Start(ctx context.Context) error {
+	r.logger.Info("Remote write exporter started",
+		zap.String("url", r.cfg.RedactedURL()),
+		zap.Duration("interval", r.cfg.Interval))
+
+	pushCtx, cancel := context.WithCancel(ctx)
+	pushed := make(chan struct{})
+	go func() {
+		defer close(pushed)
+		r.pushLoop(pushCtx)
+	}()
+	defer func() {
+		cancel()
+		<-pushed
+	}()
+
+	// The sweep runs on this goroutine so the guard needs no lock.
+	sweep, stopSweep := r.sweepTicker()
+	defer stopSweep()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case evt, ok := <-r.events:
+			if !ok {
+				return nil
+			}
+			r.processEvent(evt)
+			evt.Done()
+		case now := <-sweep:
+			r.sweep(now)
+		}
+	}
+}
+
+// Stop pushes a last snapshot, with every event the bus delivered.
+func (r *RemoteWrite) Stop(ctx context.Context) error {
+	return r.push(ctx)
+}
+
+// pushLoop pushes a snapshot every Interval until ctx is cancelled.
+func (r *RemoteWrite) pushLoop(ctx context.Context) {
+	ticker := time.NewTicker(r.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.push(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Warn("Remote write push failed", zap.Error(err))
+			}
+		}
+	}
+}
+
+// push sends a snapshot of the event metrics.
+func (r *RemoteWrite) push(ctx context.Context) error {
+	families, err := r.reg.Gather()
+	if err != nil {
+		return fmt.Errorf("gathering metrics: %w", err)
+	}
+	req := newWriteRequest(families, r.cfg.ExternalLabels, time.Now().UnixMilli())
+	if req.samples() == 0 {
+		return nil
+	}
+	if err := r.send(ctx, s2.EncodeSnappy(nil, req.marshal())); err != nil {
+		rwFailures.Inc()
+		return err
+	}
+	rwSamples.Add(float64(req.samples()))
+	return nil
+}
+
+// writeError is a push the endpoint answered with a non-2xx status.
+type writeError struct {
+	code       int
+	retryAfter time.Duration // from Retry-After; 0 when absent
+	body       string
+}
+
+func (e *writeError) Error() string {
+	msg := fmt.Sprintf("server returned %d %s", e.code, http.StatusText(e.code))
+	if e.body != "" {
+		msg += ": " + e.body
+	}
+	return msg
+}
+
+// retryable reports whether a failed push may succeed if repeated:
+// transport errors, rate limiting and server errors are; other client
+// errors, such as out-of-order samples, are not.
+func (e *writeError) retryable() bool {
+	return e.code == http.StatusTooManyRequests || e.code >= 500
+}
+
+// send posts body, retrying up to Retries times. It waits as long as the
+// endpoint's Retry-After asks, or backs off exponentially, and gives up
+// early when ctx is cancelled, the endpoint rejects the snapshot outright,
+// or the wait would run into the next snapshot, which supersedes this one.
+func (r *RemoteWrite) send(ctx context.Context, body []byte) error {
+	delay := r.backoff
+	for attempt := 0; ; attempt++ {
+		err := r.post(ctx, body)
+		var we *writeError
+		isWE := errors.As(err, &we)
+		if err == nil || attempt == r.cfg.Retries || (isWE && !we.retryable()) || ctx.Err() != nil {
+			return err
+		}
+		wait := delay
+		if isWE && we.retryAfter > 0 {
+			wait = we.retryAfter
+		}
+		if wait >= r.cfg.Interval {
+			return fmt.Errorf("%w (not retrying: waiting %s would overlap the next snapshot)", err, wait)
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		delay = min(delay*2, r.maxBackoff)
+	}
+}
+
+func (r *RemoteWrite) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("User-Agent", "kubepulse/"+buildinfo.Version)
+	req.Header.Set("X-Prometheus-Remote-Write-Version", constants.RemoteWriteVersion)
+	if err := r.authorize(req); err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// Drop the URL from the error: it may embed credentials.
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			return ue.Err
+		}
+		return err
+	}
+	defer resp.Body.Close()
+	return responseError(resp)
+}
+
+// responseError drains resp's body and returns nil for a 2xx status, or
+// a *writeError with the start of the body and any Retry-After.
+func responseError(resp *http.Response) error {
+	defer io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, constants.PushErrorBody))
+	return &writeError{
+		code:       resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+		body:       strings.TrimSpace(string(msg)),
+	}
+}
+
+// authorize sets the request's credentials, reading them from their files
+// when configured so.
+func (r *RemoteWrite) authorize(req *http.Request) error {
+	c := r.cfg
+	switch {
+	case c.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+c.BearerToken)
+	case c.BearerTokenFile != "":
+		token, err := os.ReadFile(c.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("reading bearer_token_file: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	case c.BasicAuth.Username != "":
+		password := c.BasicAuth.Password
+		if c.BasicAuth.PasswordFile != "" {
+			b, err := os.ReadFile(c.BasicAuth.PasswordFile)
+			if err != nil {
+				return fmt.Errorf("reading basic_auth.password_file: %w", err)
+			}
+			password = strings.TrimSpace(string(b))
+		}
+		req.SetBasicAuth(c.BasicAuth.Username, password)
+	}
+	return nil
+}
+
+// parseRetryAfter returns the wait a Retry-After header asks for, in
+// seconds or as an HTTP date, or 0 when it is absent or invalid.
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return max(0, time.Duration(secs)*time.Second)
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(0, t.Sub(now))
+	}
+	return 0
+}
@@ -0,0 +1,283 @@
+package export
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// writeReceiver is a remote write endpoint answering with statuses in
+// turn, then 204, and recording what it was sent.
+type writeReceiver struct {
+	t        *testing.T
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header // Retry-After etc. for the non-2xx answers
+	requests []*http.Request
+	series   map[string]float64 // from the last accepted request
+}
+
+func (wr *writeReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	wr.mu.Lock()
+	defer wr.mu.Unlock()
+	wr.requests = append(wr.requests, r)
+	if len(wr.statuses) > 0 {
+		code := wr.statuses[0]
+		wr.statuses = wr.statuses[1:]
+		for k, v := range wr.header {
+			w.Header()[k] = v
+		}
+		http.Error(w, "try again", code)
+		return
+	}
+	compressed, _ := io.ReadAll(r.Body)
+	body, err := s2.Decode(nil, compressed)
+	if err != nil {
+		wr.t.Errorf("body is not snappy: %v", err)
+	}
+	wr.series = decodeSeries(wr.t, body)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newRemoteWriteTest(t *testing.T, cfg RemoteWriteConfig, wr *writeReceiver) *RemoteWrite {
+	t.Helper()
+	wr.t = t
+	srv := httptest.NewServer(wr)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL + "/api/v1/push"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRemoteWrite(cfg, DefaultPrometheusOptions(), event.NewBus(16, nil), zap.NewNop())
+	r.backoff, r.maxBackoff = time.Millisecond, time.Millisecond
+	return r
+}
+
+func TestRemoteWrite_Push(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	os.WriteFile(tokenFile, []byte("s3cret\n"), 0o600)
+
+	cfg := DefaultRemoteWriteConfig()
+	cfg.BearerTokenFile = tokenFile
+	cfg.ExternalLabels = map[string]string{"cluster": "prod"}
+	wr := &writeReceiver{}
+	r := newRemoteWriteTest(t, cfg, wr)
+
+	for range 2 {
+		e := event.Acquire()
+		e.Type = event.TypeOOM
+		e.Namespace, e.Pod, e.Node = "default", "web-0", "node-1"
+		r.processEvent(e)
+		e.Release()
+	}
+	if err := r.push(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	req := wr.requests[0]
+	for k, want := range map[string]string{
+		"Content-Encoding":                  "snappy",
+		"Content-Type":                      "application/x-protobuf",
+		"X-Prometheus-Remote-Write-Version": constants.RemoteWriteVersion,
+		"Authorization":                     "Bearer s3cret",
+	} {
+		if got := req.Header.Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	key := "__name__=" + constants.MetricOOMKills + ",cluster=prod,namespace=default,node=node-1,pod=web-0"
+	if got := wr.series[key]; got != 2 {
+		t.Errorf("%s = %v, want 2; sent %v", key, got, wr.series)
+	}
+}
+
+func TestRemoteWrite_StopPushesLastSnapshot(t *testing.T) {
+	wr := &writeReceiver{t: t}
+	srv := httptest.NewServer(wr)
+	defer srv.Close()
+	cfg := DefaultRemoteWriteConfig()
+	cfg.URL = srv.URL
+	bus := event.NewBus(16, nil)
+	r := NewRemoteWrite(cfg, DefaultPrometheusOptions(), bus, zap.NewNop())
+
+	done := make(chan error)
+	go func() { done <- r.Start(context.Background()) }()
+	for range 3 {
+		e := event.Acquire()
+		e.Type = event.TypeExec
+		e.Namespace, e.Pod, e.Node = "default", "job-1", "node-1"
+		bus.Publish(e)
+	}
+	bus.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	key := "__name__=" + constants.MetricProcessExecs + ",namespace=default,node=node-1,pod=job-1"
+	if got := wr.series[key]; got != 3 {
+		t.Errorf("%s = %v, want all 3 execs; sent %v", key, got, wr.series)
+	}
+}
+
+func TestRemoteWrite_BasicAuth(t *testing.T) {
+	cfg := DefaultRemoteWriteConfig()
+	cfg.BasicAuth = RemoteWriteAuth{Username: "12345", Password: "key"}
+	wr := &writeReceiver{}
+	r := newRemoteWriteTest(t, cfg, wr)
+	if err := r.send(context.Background(), s2.EncodeSnappy(nil, nil)); err != nil {
+		t.Fatal(err)
+	}
+	if user, pass, ok := wr.requests[0].BasicAuth(); !ok || user != "12345" || pass != "key" {
+		t.Errorf("basic auth = %q %q %v", user, pass, ok)
+	}
+}
+
+func TestRemoteWrite_Retries(t *testing.T) {
+	tests := []struct {
+		name       string
+		statuses   []int
+		retryAfter string
+		wantErr    bool
+		wantPosts  int
+	}{
+		{"server errors retried", []int{503, 500}, "", false, 3},
+		{"rate limit retried", []int{429}, "", false, 2},
+		{"gives up after retries", []int{503, 503, 503, 503}, "", true, 4},
+		{"client error not retried", []int{400}, "", true, 1},
+		{"retry-after past the next push", []int{429}, "120", true, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wr := &writeReceiver{statuses: tt.statuses, header: http.Header{}}
+			if tt.retryAfter != "" {
+				wr.header.Set("Retry-After", tt.retryAfter)
+			}
+			r := newRemoteWriteTest(t, DefaultRemoteWriteConfig(), wr)
+			err := r.send(context.Background(), s2.EncodeSnappy(nil, nil))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want error %v", err, tt.wantErr)
+			}
+			if len(wr.requests) != tt.wantPosts {
+				t.Errorf("%d posts, want %d", len(wr.requests), tt.wantPosts)
+			}
+		})
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	for v, want := range map[string]time.Duration{
+		"":                              0,
+		"7":                             7 * time.Second,
+		"-3":                            0,
+		"soon":                          0,
+		"Fri, 02 Jan 2026 03:04:35 GMT": 30 * time.Second,
+		"Fri, 02 Jan 2026 03:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(v, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", v, got, want)
+		}
+	}
+}
+
+func TestRemoteWriteConfig_Validate(t *testing.T) {
+	valid := DefaultRemoteWriteConfig()
+	valid.URL = "https://mimir.example/api/v1/push"
+	tests := map[string]func(*RemoteWriteConfig){
+		"url":        func(c *RemoteWriteConfig) { c.URL = "mimir:9009" },
+		"interval":   func(c *RemoteWriteConfig) { c.Interval = 0 },
+		"retries":    func(c *RemoteWriteConfig) { c.Retries = -1 },
+		"two tokens": func(c *RemoteWriteConfig) { c.BearerToken, c.BearerTokenFile = "a", "/b" },
+		"bearer + basic": func(c *RemoteWriteConfig) {
+			c.BearerToken, c.BasicAuth = "a", RemoteWriteAuth{Username: "u", Password: "p"}
+		},
+		"no password":    func(c *RemoteWriteConfig) { c.BasicAuth.Username = "u" },
+		"no username":    func(c *RemoteWriteConfig) { c.BasicAuth.PasswordFile = "/p" },
+		"external label": func(c *RemoteWriteConfig) { c.ExternalLabels = map[string]string{"__cluster": "a"} },
+	}
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	for name, mutate := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := valid
+			mutate(&c)
+			if c.Validate() == nil {
+				t.Errorf("accepted %+v", c)
+			}
+		})
+	}
+}
+
+// decodeSeries decodes the series of an encoded WriteRequest into
+// "name=value,..." keys and their sample values.
+func decodeSeries(t *testing.T, b []byte) map[string]float64 {
+	t.Helper()
+	// field returns the next field number and, for length-delimited
+	// fields, its bytes; fixed64 and varint values come back raw.
+	field := func(b []byte) (int, []byte, []byte) {
+		tag, n := binary.Uvarint(b)
+		b = b[n:]
+		switch tag & 7 {
+		case wireVarint:
+			_, m := binary.Uvarint(b)
+			return int(tag >> 3), b[:m], b[m:]
+		case wireFixed64:
+			return int(tag >> 3), b[:8], b[8:]
+		case wireBytes:
+			l, m := binary.Uvarint(b)
+			return int(tag >> 3), b[m : m+int(l)], b[m+int(l):]
+		}
+		t.Fatalf("unexpected wire type %d", tag&7)
+		return 0, nil, nil
+	}
+
+	out := make(map[string]float64)
+	for len(b) > 0 {
+		num, msg, rest := field(b)
+		b = rest
+		if num != 1 {
+			continue
+		}
+		var labels []string
+		var value float64
+		for len(msg) > 0 {
+			num, sub, rest := field(msg)
+			msg = rest
+			switch num {
+			case 1:
+				_, name, lrest := field(sub)
+				_, val, _ := field(lrest)
+				labels = append(labels, string(name)+"="+string(val))
+			case 2:
+				for len(sub) > 0 {
+					num, v, srest := field(sub)
+					sub = srest
+					if num == 1 {
+						value = math.Float64frombits(binary.LittleEndian.Uint64(v))
+					}
+				}
+			}
+		}
+		out[strings.Join(labels, ",")] = value
+	}
+	return out
+}
@@ -0,0 +1,120 @@
+package export
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// MetricsSecurity holds the metrics server's optional TLS and basic auth.
+type MetricsSecurity struct {
+	TLS       MetricsTLS `yaml:"tls"`
+	BasicAuth BasicAuth  `yaml:"basic_auth"`
+}
+
+// MetricsTLS holds the metrics server's certificate, e.g. from a mounted
+// Secret. With no CertFile the server speaks plain HTTP.
+type MetricsTLS struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+}
+
+// BasicAuth requires HTTP basic auth on the metrics server. With no
+// Username it is off.
+type BasicAuth struct {
+	Username     string `yaml:"username"`
+	PasswordHash string `yaml:"password_hash"` // bcrypt, e.g. from htpasswd -nbB
+	// ExemptHealth serves /healthz and /readyz without auth, so kubelet
+	// probes keep working.
+	ExemptHealth bool `yaml:"exempt_health"`
+}
+
+// DefaultMetricsSecurity returns defaults: plain HTTP, no auth, and health
+// endpoints exempt once auth is on.
+func DefaultMetricsSecurity() MetricsSecurity {
+	return MetricsSecurity{BasicAuth: BasicAuth{ExemptHealth: true}}
+}
+
+// Validate checks s without reading the TLS files.
+func (s MetricsSecurity) Validate() error {
+	var errs []string
+	if (s.TLS.CertFile == "") != (s.TLS.KeyFile == "") {
+		errs = append(errs, "tls.cert_file and tls.key_file must be set together")
+	}
+	ba := s.BasicAuth
+	if (ba.Username == "") != (ba.PasswordHash == "") {
+		errs = append(errs, "basic_auth.username and basic_auth.password_hash must be set together")
+	}
+	if ba.PasswordHash != "" {
+		if _, err := bcrypt.Cost([]byte(ba.PasswordHash)); err != nil {
+			errs = append(errs, "basic_auth.password_hash must be a bcrypt hash")
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// tlsConfig loads the key pair, or returns nil without a certificate.
+func (t MetricsTLS) tlsConfig() (*tls.Config, error) {
+	if t.CertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("loading metrics TLS key pair: %w", err)
+	}
+	return &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// basicAuthHandler wraps next in HTTP basic auth. bcrypt is slow by design,
+// so the last password that matched is remembered by its SHA-256 and
+// later scrapes with it skip the bcrypt comparison.
+type basicAuthHandler struct {
+	next     http.Handler
+	cfg      BasicAuth
+	user     [sha256.Size]byte
+	verified atomic.Pointer[[sha256.Size]byte]
+}
+
+func newBasicAuthHandler(next http.Handler, cfg BasicAuth) *basicAuthHandler {
+	return &basicAuthHandler{next: next, cfg: cfg, user: sha256.Sum256([]byte(cfg.Username))}
+}
+
+func (h *basicAuthHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h.cfg.ExemptHealth && (r.URL.Path == constants.PathHealthz || r.URL.Path == constants.PathReadyz) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	if user, pass, ok := r.BasicAuth(); ok && h.allow(user, pass) {
+		h.next.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="kubepulse", charset="UTF-8"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+func (h *basicAuthHandler) allow(user, pass string) bool {
+	userSum := sha256.Sum256([]byte(user))
+	userOK := subtle.ConstantTimeCompare(userSum[:], h.user[:]) == 1
+	passSum := sha256.Sum256([]byte(pass))
+	if v := h.verified.Load(); v != nil && subtle.ConstantTimeCompare(passSum[:], v[:]) == 1 {
+		return userOK
+	}
+	// Compared even for a wrong user, so timing doesn't reveal the username.
+	if bcrypt.CompareHashAndPassword([]byte(h.cfg.PasswordHash), []byte(pass)) != nil {
+		return false
+	}
+	h.verified.Store(&passSum)
+	return userOK
+}
@@ -0,0 +1,216 @@
+package export
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func bcryptHash(t *testing.T, password string) string {
+	t.Helper()
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(h)
+}
+
+func TestMetricsSecurity_Validate(t *testing.T) {
+	hash := bcryptHash(t, "secret")
+	for _, tt := range []struct {
+		name string
+		cfg  MetricsSecurity
+		want string // substring of the error; "" for valid
+	}{
+		{"defaults", DefaultMetricsSecurity(), ""},
+		{"tls", MetricsSecurity{TLS: MetricsTLS{CertFile: "c", KeyFile: "k"}}, ""},
+		{"basic auth", MetricsSecurity{BasicAuth: BasicAuth{Username: "prom", PasswordHash: hash}}, ""},
+		{"cert without key", MetricsSecurity{TLS: MetricsTLS{CertFile: "c"}}, "tls.cert_file and tls.key_file"},
+		{"key without cert", MetricsSecurity{TLS: MetricsTLS{KeyFile: "k"}}, "tls.cert_file and tls.key_file"},
+		{"user without hash", MetricsSecurity{BasicAuth: BasicAuth{Username: "prom"}}, "basic_auth.username and basic_auth.password_hash"},
+		{"plaintext password", MetricsSecurity{BasicAuth: BasicAuth{Username: "prom", PasswordHash: "secret"}}, "bcrypt"},
+	} {
+		err := tt.cfg.Validate()
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%s: %v", tt.name, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%s: err = %v, want it to mention %q", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestBasicAuthHandler(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "ok") })
+	cfg := BasicAuth{Username: "prom", PasswordHash: bcryptHash(t, "secret"), ExemptHealth: true}
+
+	serve := func(h http.Handler, path, user, pass string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if user != "" || pass != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code == http.StatusUnauthorized && !strings.HasPrefix(rec.Header().Get("WWW-Authenticate"), "Basic ") {
+			t.Errorf("%s: 401 without a Basic challenge", path)
+		}
+		return rec.Code
+	}
+
+	h := newBasicAuthHandler(next, cfg)
+	for _, tt := range []struct {
+		name, path, user, pass string
+		want                   int
+	}{
+		{"no credentials", constants.PathMetrics, "", "", 401},
+		{"wrong password", constants.PathMetrics, "prom", "wrong", 401},
+		{"right credentials", constants.PathMetrics, "prom", "secret", 200},
+		// Now cached: the cache must still check the username and password.
+		{"cached password, wrong user", constants.PathMetrics, "other", "secret", 401},
+		{"cached, right credentials", constants.PathMetrics, "prom", "secret", 200},
+		{"wrong password after cache", constants.PathMetrics, "prom", "secre", 401},
+		{"healthz exempt", constants.PathHealthz, "", "", 200},
+		{"readyz exempt", constants.PathReadyz, "", "", 200},
+		{"admin not exempt", constants.PathAdminLogLevel, "", "", 401},
+	} {
+		if got := serve(h, tt.path, tt.user, tt.pass); got != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	cfg.ExemptHealth = false
+	strict := newBasicAuthHandler(next, cfg)
+	if got := serve(strict, constants.PathHealthz, "", ""); got != 401 {
+		t.Errorf("healthz without exemption: status %d, want 401", got)
+	}
+	if got := serve(strict, constants.PathHealthz, "prom", "secret"); got != 200 {
+		t.Errorf("healthz with credentials: status %d, want 200", got)
+	}
+}
+
+// writeTestCert writes a self-signed certificate for 127.0.0.1 and its
+// key to dir.
+func writeTestCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kubepulse-metrics"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err = x509.ParseCertificate(der); err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile, cert
+}
+
+func TestPrometheus_TLSAndBasicAuth(t *testing.T) {
+	certFile, keyFile, cert := writeTestCert(t, t.TempDir())
+	p, _ := newTestPrometheus(t)
+	p.addr = "127.0.0.1:0"
+	err := p.Secure(MetricsSecurity{
+		TLS:       MetricsTLS{CertFile: certFile, KeyFile: keyFile},
+		BasicAuth: BasicAuth{Username: "prom", PasswordHash: bcryptHash(t, "secret"), ExemptHealth: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ln, err := p.listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := &http.Server{Handler: p.handler()}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+	addr := ln.Addr().String()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	get := func(path, user, pass string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "https://"+addr+path, nil)
+		if user != "" {
+			req.SetBasicAuth(user, pass)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s over TLS: %v", path, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	if got := get(constants.PathHealthz, "", ""); got != http.StatusOK {
+		t.Errorf("healthz: %d, want 200", got)
+	}
+	if got := get(constants.PathMetrics, "", ""); got != http.StatusUnauthorized {
+		t.Errorf("metrics without credentials: %d, want 401", got)
+	}
+	if got := get(constants.PathMetrics, "prom", "secret"); got != http.StatusOK {
+		t.Errorf("metrics with credentials: %d, want 200", got)
+	}
+
+	// Plain HTTP is refused by the TLS listener.
+	if resp, err := http.Get("http://" + addr + constants.PathHealthz); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusOK {
+			t.Error("plain HTTP request served by the TLS listener")
+		}
+	}
+}
+
+func TestPrometheus_SecureRejectsBadKeyPair(t *testing.T) {
+	dir := t.TempDir()
+	certFile, _, _ := writeTestCert(t, dir)
+	p, _ := newTestPrometheus(t)
+	if err := p.Secure(MetricsSecurity{TLS: MetricsTLS{CertFile: certFile, KeyFile: filepath.Join(dir, "missing.key")}}); err == nil {
+		t.Fatal("Secure accepted a missing key file")
+	}
+	if p.tlsCfg != nil {
+		t.Error("TLS enabled despite the error")
+	}
+}
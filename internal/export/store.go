@@ -0,0 +1,149 @@
+package export
+
+import (
+	"context"
+	"maps"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// StoreConfig holds store exporter settings.
+type StoreConfig struct {
+	BatchSize     int           `yaml:"batch_size"`
+	FlushInterval time.Duration `yaml:"flush_interval"`
+}
+
+// DefaultStoreConfig returns defaults sized for a single node.
+func DefaultStoreConfig() StoreConfig {
+	return StoreConfig{
+		BatchSize:     constants.StoreExporterBatchSize,
+		FlushInterval: constants.StoreExporterFlushInterval,
+	}
+}
+
+// BatchInserter is the store dependency of the store exporter; every
+// storage.Store implements it.
+type BatchInserter interface {
+	InsertBatch(ctx context.Context, rows []storage.EventRow) error
+}
+
+// StoreExporter writes events straight to a storage.Store, batched by
+// size or time: the agent→NATS→consumer pipeline in one process, for
+// standalone mode. There is no queue behind it: a batch the store rejects
+// is logged and dropped.
+type StoreExporter struct {
+	cfg    StoreConfig
+	store  BatchInserter
+	logger *zap.Logger
+	bus    *event.Bus
+	events <-chan *event.Event
+
+	mu    sync.Mutex
+	seq   uint64 // per-agent event sequence, see eventID
+	batch []storage.EventRow
+}
+
+// NewStoreExporter creates a store exporter (Factory constructor).
+func NewStoreExporter(cfg StoreConfig, store BatchInserter, bus *event.Bus, logger *zap.Logger) *StoreExporter {
+	return &StoreExporter{
+		cfg:    cfg,
+		store:  store,
+		logger: logger,
+		bus:    bus,
+		batch:  make([]storage.EventRow, 0, cfg.BatchSize),
+	}
+}
+
+func (e *StoreExporter) Name() string { return constants.ExporterStore }
+
+// Start consumes events until the bus closes, so events modules drain at
+// shutdown are still stored.
+func (e *StoreExporter) Start(ctx context.Context) error {
+	e.events = e.bus.Subscribe(constants.ExporterStore)
+	e.logger.Info("Store exporter started",
+		zap.Int("batch_size", e.cfg.BatchSize),
+		zap.Duration("flush_interval", e.cfg.FlushInterval))
+
+	ticker := time.NewTicker(e.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case evt, ok := <-e.events:
+			if !ok {
+				e.flush(ctx)
+				return nil
+			}
+			if e.enqueue(evt) {
+				e.flush(ctx)
+			}
+		case <-ticker.C:
+			e.flush(ctx)
+		}
+	}
+}
+
+// Stop writes the pending batch.
+func (e *StoreExporter) Stop(ctx context.Context) error {
+	e.flush(ctx)
+	return nil
+}
+
+// enqueue converts evt to a row and releases it. It reports whether the
+// batch is full.
+func (e *StoreExporter) enqueue(evt *event.Event) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.seq++
+	row := toRow(evt, eventID(evt, e.seq))
+	evt.Done()
+	e.batch = append(e.batch, row)
+	return len(e.batch) >= e.cfg.BatchSize
+}
+
+// toRow converts evt to a storage row with the given ID, as the consumer
+// does with a wire event. The maps are copied: evt returns to its pool.
+func toRow(evt *event.Event, id uint64) storage.EventRow {
+	row := storage.EventRow{
+		EventID:   id,
+		Timestamp: evt.Timestamp,
+		Type:      evt.Type.String(),
+		PID:       evt.PID,
+		UID:       evt.UID,
+		Comm:      evt.Comm,
+		Node:      evt.Node,
+		Namespace: evt.Namespace,
+		Pod:       evt.Pod,
+		Labels:    maps.Clone(evt.Labels),
+		Numerics:  maps.Clone(evt.Numeric),
+	}
+	row.LatencySec, row.Bytes, row.Value = storage.TypedNumerics(row.Type, row.Numerics)
+	return row
+}
+
+// flush writes the pending batch. A cancelled ctx (shutdown) gets a fresh
+// deadline so the tail is still written.
+func (e *StoreExporter) flush(ctx context.Context) {
+	e.mu.Lock()
+	if len(e.batch) == 0 {
+		e.mu.Unlock()
+		return
+	}
+	batch := e.batch
+	e.batch = make([]storage.EventRow, 0, e.cfg.BatchSize)
+	e.mu.Unlock()
+
+	if ctx.Err() != nil {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(context.Background(), constants.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := e.store.InsertBatch(ctx, batch); err != nil {
+		e.logger.Warn("Store insert failed — dropping batch", zap.Error(err), zap.Int("rows", len(batch)))
+	}
+}
@@ -0,0 +1,105 @@
+package export
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+func storedEvents(t *testing.T, s storage.Store) []storage.Event {
+	t.Helper()
+	rows, err := s.ListEvents(context.Background(), storage.EventQuery{Limit: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var out []storage.Event
+	for rows.Next() {
+		e, err := rows.Event()
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+func TestStoreExporter_BatchesAndCopiesEvents(t *testing.T) {
+	mem := storage.NewMemory()
+	cfg := StoreConfig{BatchSize: 2, FlushInterval: time.Hour}
+	e := NewStoreExporter(cfg, mem, event.NewBus(64, nil), zap.NewNop())
+
+	evt := &event.Event{
+		Type:      event.TypeTCP,
+		Timestamp: time.Now(),
+		PID:       42,
+		Pod:       "web-0",
+		Labels:    map[string]string{constants.KeyDst: "10.0.0.1:443"},
+		Numeric:   map[string]float64{constants.KeyLatencySec: 0.25},
+	}
+	if e.enqueue(evt) {
+		t.Fatal("batch full after one event")
+	}
+	// The pool clears an event's maps once every subscriber is done.
+	evt.Labels[constants.KeyDst] = "reused"
+	if !e.enqueue(&event.Event{Type: event.TypeDNS, Timestamp: time.Now()}) {
+		t.Fatal("batch not full after two events")
+	}
+	e.flush(context.Background())
+	e.enqueue(&event.Event{Type: event.TypeOOM, Timestamp: time.Now()})
+	if n := len(storedEvents(t, mem)); n != 2 {
+		t.Fatalf("%d events stored before Stop, want 2", n)
+	}
+	if err := e.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	events := storedEvents(t, mem)
+	if len(events) != 3 {
+		t.Fatalf("%d events stored, want 3", len(events))
+	}
+	for _, ev := range events {
+		if ev.Type == constants.ModuleTCP && (ev.Labels[constants.KeyDst] != "10.0.0.1:443" || ev.PID != 42) {
+			t.Errorf("tcp event = %+v", ev)
+		}
+	}
+	top, err := mem.TopPods(context.Background(), storage.TopQuery{Type: constants.ModuleTCP, K: 1})
+	if err != nil || len(top) != 1 || top[0].P99Latency != 0.25 {
+		t.Errorf("top = %+v, %v; want the latency typed from numerics", top, err)
+	}
+}
+
+func TestStoreExporter_DrainsWhenBusCloses(t *testing.T) {
+	mem := storage.NewMemory()
+	bus := event.NewBus(64, nil)
+	e := NewStoreExporter(StoreConfig{BatchSize: 100, FlushInterval: time.Hour}, mem, bus, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- e.Start(ctx) }()
+	for {
+		if _, ok := bus.Stats().QueueDepth[constants.ExporterStore]; ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for pid := range 5 {
+		bus.Publish(&event.Event{Type: event.TypeTCP, Timestamp: time.Now(), PID: uint32(pid)})
+	}
+	cancel() // shutdown begins; modules may still drain into the bus
+	bus.Publish(&event.Event{Type: event.TypeTCP, Timestamp: time.Now(), PID: 5})
+	bus.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if n := len(storedEvents(t, mem)); n != 6 {
+		t.Errorf("%d events stored, want 6", n)
+	}
+}
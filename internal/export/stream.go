@@ -0,0 +1,202 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// StreamSettings are the JetStream stream the NATS exporter publishes to.
+// The agent owns the stream: it creates it when missing and brings an
+// existing one in line with these settings at startup. The consumer never
+// creates or changes it.
+type StreamSettings struct {
+	Replicas  int           `yaml:"replicas"`
+	MaxAge    time.Duration `yaml:"max_age"`   // 0 keeps events until max_bytes
+	MaxBytes  int64         `yaml:"max_bytes"` // 0 is unlimited
+	Retention string        `yaml:"retention"` // workqueue, limits or interest
+	Storage   string        `yaml:"storage"`   // file or memory
+}
+
+// DefaultStreamSettings returns a single-replica, 256 MB file-backed work
+// queue: events are removed once the consumer acks them.
+func DefaultStreamSettings() StreamSettings {
+	return StreamSettings{
+		Replicas:  1,
+		MaxBytes:  constants.NATSStreamMaxBytes,
+		Retention: constants.NATSRetentionWorkQueue,
+		Storage:   constants.NATSStorageFile,
+	}
+}
+
+var (
+	retentionPolicies = map[string]jetstream.RetentionPolicy{
+		constants.NATSRetentionWorkQueue: jetstream.WorkQueuePolicy,
+		constants.NATSRetentionLimits:    jetstream.LimitsPolicy,
+		constants.NATSRetentionInterest:  jetstream.InterestPolicy,
+	}
+	storageTypes = map[string]jetstream.StorageType{
+		constants.NATSStorageFile:   jetstream.FileStorage,
+		constants.NATSStorageMemory: jetstream.MemoryStorage,
+	}
+)
+
+// Validate reports settings JetStream would reject, and combinations that
+// would let the stream grow without bound.
+func (s StreamSettings) Validate() error {
+	var errs []string
+	if s.Replicas < 1 || s.Replicas > constants.NATSMaxReplicas {
+		errs = append(errs, fmt.Sprintf("replicas must be between 1 and %d", constants.NATSMaxReplicas))
+	}
+	if s.MaxAge < 0 {
+		errs = append(errs, "max_age must be >= 0")
+	}
+	if s.MaxBytes < 0 {
+		errs = append(errs, "max_bytes must be >= 0")
+	}
+	if _, ok := retentionPolicies[s.Retention]; !ok {
+		errs = append(errs, fmt.Sprintf("retention must be %s, %s or %s, got %q",
+			constants.NATSRetentionWorkQueue, constants.NATSRetentionLimits, constants.NATSRetentionInterest, s.Retention))
+	}
+	if _, ok := storageTypes[s.Storage]; !ok {
+		errs = append(errs, fmt.Sprintf("storage must be %s or %s, got %q",
+			constants.NATSStorageFile, constants.NATSStorageMemory, s.Storage))
+	}
+	if s.Storage == constants.NATSStorageMemory && s.MaxBytes == 0 {
+		errs = append(errs, "max_bytes is required with memory storage")
+	}
+	if s.Retention == constants.NATSRetentionLimits && s.MaxAge == 0 && s.MaxBytes == 0 {
+		errs = append(errs, "limits retention needs max_age or max_bytes")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// streamConfig is the stream for subject under name with these settings.
+// Unlimited is -1 to JetStream, as it reports it back.
+func (s StreamSettings) streamConfig(name, subject string) jetstream.StreamConfig {
+	maxBytes := s.MaxBytes
+	if maxBytes == 0 {
+		maxBytes = -1
+	}
+	return jetstream.StreamConfig{
+		Name:      name,
+		Subjects:  []string{subject},
+		Retention: retentionPolicies[s.Retention],
+		MaxAge:    s.MaxAge,
+		MaxBytes:  maxBytes,
+		Discard:   jetstream.DiscardOld,
+		Storage:   storageTypes[s.Storage],
+		Replicas:  s.Replicas,
+	}
+}
+
+// streamManager is the part of jetstream.JetStream ensureStream uses.
+type streamManager interface {
+	Stream(ctx context.Context, name string) (jetstream.Stream, error)
+	CreateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+	UpdateStream(ctx context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error)
+}
+
+// ensureStream creates the stream want describes, or reconciles an
+// existing one with it (see reconcileStream). Every agent in a cluster
+// does this at startup; when two race to create the stream, the loser
+// finds it on its second pass and reconciles instead.
+func ensureStream(ctx context.Context, js streamManager, want jetstream.StreamConfig, logger *zap.Logger) error {
+	for range 2 {
+		s, err := js.Stream(ctx, want.Name)
+		if errors.Is(err, jetstream.ErrStreamNotFound) {
+			_, err = js.CreateStream(ctx, want)
+			if errors.Is(err, jetstream.ErrStreamNameAlreadyInUse) {
+				continue
+			}
+			if err == nil {
+				logger.Info("Created NATS stream", zap.String("stream", want.Name))
+			}
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		update, err := reconcileStream(s.CachedInfo().Config, want, logger)
+		if err != nil || update == nil {
+			return err
+		}
+		if _, err := js.UpdateStream(ctx, *update); err != nil {
+			return fmt.Errorf("updating stream %s: %w", want.Name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("stream %s was created with a different configuration and then not found", want.Name)
+}
+
+// reconcileStream compares an existing stream with want and returns the
+// config to update it to, or nil when nothing needs to change.
+//
+//   - A stream that does not capture want's subject is an error: the
+//     agent's events would not be stored.
+//   - Replicas, max age and max bytes can change in place; they are
+//     updated to want.
+//   - Retention and storage cannot change without recreating the stream,
+//     which would lose its events, so the existing ones are kept with a
+//     warning. Recreate the stream to apply them.
+//
+// Fields the agent does not configure, such as extra subjects, are left as
+// they are.
+func reconcileStream(have, want jetstream.StreamConfig, logger *zap.Logger) (*jetstream.StreamConfig, error) {
+	for _, subject := range want.Subjects {
+		if !slices.ContainsFunc(have.Subjects, func(pattern string) bool { return subjectMatches(pattern, subject) }) {
+			return nil, fmt.Errorf("stream %s does not capture subject %s (it has %v)", have.Name, subject, have.Subjects)
+		}
+	}
+
+	log := logger.With(zap.String("stream", have.Name))
+	if have.Retention != want.Retention {
+		log.Warn("NATS stream retention differs from the config; keeping the stream's",
+			zap.Stringer("stream_retention", have.Retention), zap.Stringer("config_retention", want.Retention))
+	}
+	if have.Storage != want.Storage {
+		log.Warn("NATS stream storage differs from the config; keeping the stream's",
+			zap.Stringer("stream_storage", have.Storage), zap.Stringer("config_storage", want.Storage))
+	}
+
+	update := have
+	update.Replicas = want.Replicas
+	update.MaxAge = want.MaxAge
+	update.MaxBytes = want.MaxBytes
+	if max(have.Replicas, 1) == update.Replicas && have.MaxAge == update.MaxAge && have.MaxBytes == update.MaxBytes {
+		return nil, nil
+	}
+	log.Info("Updating NATS stream to match the config",
+		zap.Int("replicas", update.Replicas),
+		zap.Duration("max_age", update.MaxAge),
+		zap.Int64("max_bytes", update.MaxBytes))
+	return &update, nil
+}
+
+// subjectMatches reports whether the NATS subject pattern, which may use
+// the * and > wildcards, matches subject.
+func subjectMatches(pattern, subject string) bool {
+	pt, st := strings.Split(pattern, "."), strings.Split(subject, ".")
+	for i, tok := range pt {
+		switch {
+		case tok == ">":
+			return len(st) > i
+		case i >= len(st):
+			return false
+		case tok != "*" && tok != st[i]:
+			return false
+		}
+	}
+	return len(pt) == len(st)
+}
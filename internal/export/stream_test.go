@@ -0,0 +1,174 @@
+package export
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// fakeStreams is a streamManager holding at most one stream. lostRace
+// makes the first CreateStream fail as if another agent had just created
+// the stream with other settings.
+type fakeStreams struct {
+	have     *jetstream.StreamConfig
+	lostRace *jetstream.StreamConfig
+	created  int
+	updated  []jetstream.StreamConfig
+}
+
+type cachedStream struct {
+	jetstream.Stream
+	info *jetstream.StreamInfo
+}
+
+func (s cachedStream) CachedInfo() *jetstream.StreamInfo { return s.info }
+
+func (f *fakeStreams) Stream(_ context.Context, _ string) (jetstream.Stream, error) {
+	if f.have == nil {
+		return nil, jetstream.ErrStreamNotFound
+	}
+	return cachedStream{info: &jetstream.StreamInfo{Config: *f.have}}, nil
+}
+
+func (f *fakeStreams) CreateStream(_ context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	if f.lostRace != nil {
+		f.have, f.lostRace = f.lostRace, nil
+		return nil, jetstream.ErrStreamNameAlreadyInUse
+	}
+	f.created++
+	f.have = &cfg
+	return nil, nil
+}
+
+func (f *fakeStreams) UpdateStream(_ context.Context, cfg jetstream.StreamConfig) (jetstream.Stream, error) {
+	f.updated = append(f.updated, cfg)
+	f.have = &cfg
+	return nil, nil
+}
+
+func defaultStream() jetstream.StreamConfig {
+	return DefaultStreamSettings().streamConfig(constants.NATSStream, constants.NATSSubject)
+}
+
+func TestEnsureStream_CreatesThenKeeps(t *testing.T) {
+	js := &fakeStreams{}
+	want := defaultStream()
+	for range 2 {
+		if err := ensureStream(context.Background(), js, want, zap.NewNop()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if js.created != 1 || len(js.updated) != 0 {
+		t.Errorf("created %d, updated %v; want one create and no updates", js.created, js.updated)
+	}
+	if js.have.Retention != jetstream.WorkQueuePolicy || js.have.Storage != jetstream.FileStorage || js.have.MaxBytes != constants.NATSStreamMaxBytes {
+		t.Errorf("created %+v, want the default work queue", *js.have)
+	}
+}
+
+func TestEnsureStream_LostCreateRaceReconciles(t *testing.T) {
+	other := defaultStream()
+	other.Replicas = 1
+	js := &fakeStreams{lostRace: &other}
+
+	want := defaultStream()
+	want.Replicas = 3
+	if err := ensureStream(context.Background(), js, want, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if js.created != 0 || len(js.updated) != 1 || js.updated[0].Replicas != 3 {
+		t.Errorf("created %d, updated %+v; want the winner's stream updated to 3 replicas", js.created, js.updated)
+	}
+}
+
+func TestReconcileStream(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	logger := zap.New(core)
+	want := defaultStream()
+
+	// The server reports unset replicas as 0 on some versions.
+	same := want
+	same.Replicas = 0
+	if update, err := reconcileStream(same, want, logger); update != nil || err != nil {
+		t.Errorf("same stream: update %+v, err %v; want neither", update, err)
+	}
+
+	// Mutable limits are updated; extra subjects and other fields survive.
+	have := want
+	have.Subjects = []string{"other.events", "kubepulse.>"}
+	have.MaxAge = 0
+	have.MaxBytes = -1
+	have.Description = "shared"
+	want.MaxAge = time.Hour
+	update, err := reconcileStream(have, want, logger)
+	if err != nil || update == nil {
+		t.Fatalf("update %v, err %v; want an update", update, err)
+	}
+	if update.MaxAge != time.Hour || update.MaxBytes != constants.NATSStreamMaxBytes || update.Description != "shared" || len(update.Subjects) != 2 {
+		t.Errorf("update = %+v", *update)
+	}
+
+	// Retention and storage cannot change in place: keep, and warn.
+	have = want
+	have.Retention = jetstream.LimitsPolicy
+	have.Storage = jetstream.MemoryStorage
+	if update, err := reconcileStream(have, want, logger); update != nil || err != nil {
+		t.Errorf("incompatible fields only: update %+v, err %v; want the stream kept", update, err)
+	}
+	if n := logs.FilterLevelExact(zapcore.WarnLevel).Len(); n != 2 {
+		t.Errorf("%d warnings, want one each for retention and storage", n)
+	}
+
+	// A stream that would not store the agent's events is an error.
+	have = want
+	have.Subjects = []string{"other.>"}
+	if _, err := reconcileStream(have, want, logger); err == nil {
+		t.Error("reconciled a stream that does not capture the subject")
+	}
+}
+
+func TestSubjectMatches(t *testing.T) {
+	for _, tt := range []struct {
+		pattern, subject string
+		want             bool
+	}{
+		{"kubepulse.events", "kubepulse.events", true},
+		{"kubepulse.*", "kubepulse.events", true},
+		{"kubepulse.>", "kubepulse.events", true},
+		{">", "kubepulse.events", true},
+		{"kubepulse.>", "kubepulse", false},
+		{"kubepulse.*", "kubepulse.events.tcp", false},
+		{"kubepulse.events.tcp", "kubepulse.events", false},
+		{"other.events", "kubepulse.events", false},
+	} {
+		if got := subjectMatches(tt.pattern, tt.subject); got != tt.want {
+			t.Errorf("subjectMatches(%q, %q) = %v, want %v", tt.pattern, tt.subject, got, tt.want)
+		}
+	}
+}
+
+func TestEnsureStream_UpdateRefused(t *testing.T) {
+	have := defaultStream()
+	js := &refusingStreams{fakeStreams{have: &have}}
+	want := defaultStream()
+	want.Replicas = 3
+	if err := ensureStream(context.Background(), js, want, zap.NewNop()); err == nil {
+		t.Error("ensureStream hid a refused update")
+	}
+}
+
+// refusingStreams refuses every update, as a single server does for
+// replicas > 1.
+type refusingStreams struct{ fakeStreams }
+
+func (*refusingStreams) UpdateStream(context.Context, jetstream.StreamConfig) (jetstream.Stream, error) {
+	return nil, errors.New("replicas > 1 not supported in non-clustered mode")
+}
@@ -0,0 +1,201 @@
+package export
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+// This file encodes the Prometheus remote write 1.0 WriteRequest by hand,
+// so the agent needs neither the Prometheus server module nor generated
+// code. The messages, from prometheus/prompb, are:
+//
+//	message WriteRequest   { repeated TimeSeries timeseries = 1; repeated MetricMetadata metadata = 3; }
+//	message TimeSeries     { repeated Label labels = 1; repeated Sample samples = 2; }
+//	message Label          { string name = 1; string value = 2; }
+//	message Sample         { double value = 1; int64 timestamp = 2; }
+//	message MetricMetadata { MetricType type = 1; string metric_family_name = 2; string help = 4; string unit = 5; }
+//
+// Fields are written in field order and zero values are left out, as the
+// generated marshalers do, so the bytes match theirs.
+
+// Protobuf wire types.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+)
+
+// metricType is prompb.MetricMetadata_MetricType.
+type metricType uint64
+
+const (
+	metricUnknown   metricType = 0
+	metricCounter   metricType = 1
+	metricGauge     metricType = 2
+	metricHistogram metricType = 3
+)
+
+// promLabel is a label of a series.
+type promLabel struct {
+	name, value string
+}
+
+// timeSeries is a series with one sample, as a snapshot has.
+type timeSeries struct {
+	labels    []promLabel // sorted by name
+	value     float64
+	timestamp int64 // milliseconds since the epoch
+}
+
+// metricMetadata describes a metric family.
+type metricMetadata struct {
+	typ  metricType
+	name string
+	help string
+}
+
+// writeRequest is a snapshot of metric families at one time.
+type writeRequest struct {
+	series   []timeSeries
+	metadata []metricMetadata
+}
+
+// newWriteRequest turns gathered metric families into one sample per
+// series, all at timestamp. A histogram becomes its _bucket series, one
+// per le including +Inf, and its _sum and _count, as Prometheus stores a
+// scraped classic histogram. external labels are added to every series
+// that does not have them; labels with empty values are left out, as
+// Prometheus treats them as absent.
+func newWriteRequest(families []*dto.MetricFamily, external map[string]string, timestamp int64) writeRequest {
+	var req writeRequest
+	for _, mf := range families {
+		name := mf.GetName()
+		md := metricMetadata{name: name, help: mf.GetHelp()}
+		for _, m := range mf.GetMetric() {
+			add := func(name string, value float64, extra ...promLabel) {
+				req.series = append(req.series, timeSeries{
+					labels:    seriesLabels(name, m.GetLabel(), external, extra...),
+					value:     value,
+					timestamp: timestamp,
+				})
+			}
+			switch mf.GetType() {
+			case dto.MetricType_COUNTER:
+				md.typ = metricCounter
+				add(name, m.GetCounter().GetValue())
+			case dto.MetricType_GAUGE:
+				md.typ = metricGauge
+				add(name, m.GetGauge().GetValue())
+			case dto.MetricType_HISTOGRAM:
+				md.typ = metricHistogram
+				h := m.GetHistogram()
+				for _, b := range h.GetBucket() {
+					if !math.IsInf(b.GetUpperBound(), 1) {
+						add(name+"_bucket", float64(b.GetCumulativeCount()), promLabel{"le", formatLE(b.GetUpperBound())})
+					}
+				}
+				add(name+"_bucket", float64(h.GetSampleCount()), promLabel{"le", "+Inf"})
+				add(name+"_sum", h.GetSampleSum())
+				add(name+"_count", float64(h.GetSampleCount()))
+			case dto.MetricType_UNTYPED:
+				md.typ = metricUnknown
+				add(name, m.GetUntyped().GetValue())
+			}
+		}
+		req.metadata = append(req.metadata, md)
+	}
+	return req
+}
+
+// seriesLabels returns a series' labels sorted by name, __name__ first.
+func seriesLabels(name string, pairs []*dto.LabelPair, external map[string]string, extra ...promLabel) []promLabel {
+	labels := make([]promLabel, 0, 1+len(pairs)+len(extra)+len(external))
+	labels = append(labels, promLabel{"__name__", name})
+	for _, lp := range pairs {
+		if lp.GetValue() != "" {
+			labels = append(labels, promLabel{lp.GetName(), lp.GetValue()})
+		}
+	}
+	labels = append(labels, extra...)
+	for n, v := range external {
+		if v != "" && !slices.ContainsFunc(labels, func(l promLabel) bool { return l.name == n }) {
+			labels = append(labels, promLabel{n, v})
+		}
+	}
+	slices.SortFunc(labels, func(a, b promLabel) int { return strings.Compare(a.name, b.name) })
+	return labels
+}
+
+// formatLE formats a bucket bound as the text exposition format does.
+func formatLE(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// samples returns the number of samples in r.
+func (r writeRequest) samples() int { return len(r.series) }
+
+// marshal encodes r as a protobuf WriteRequest.
+func (r writeRequest) marshal() []byte {
+	var b, msg []byte
+	for _, ts := range r.series {
+		msg = ts.marshal(msg[:0])
+		b = appendBytes(b, 1, msg)
+	}
+	for _, md := range r.metadata {
+		msg = md.marshal(msg[:0])
+		b = appendBytes(b, 3, msg)
+	}
+	return b
+}
+
+func (ts timeSeries) marshal(b []byte) []byte {
+	var msg []byte
+	for _, l := range ts.labels {
+		msg = appendString(msg[:0], 1, l.name)
+		msg = appendString(msg, 2, l.value)
+		b = appendBytes(b, 1, msg)
+	}
+	msg = msg[:0]
+	if ts.value != 0 {
+		msg = appendTag(msg, 1, wireFixed64)
+		msg = binary.LittleEndian.AppendUint64(msg, math.Float64bits(ts.value))
+	}
+	if ts.timestamp != 0 {
+		msg = appendTag(msg, 2, wireVarint)
+		msg = binary.AppendUvarint(msg, uint64(ts.timestamp))
+	}
+	return appendBytes(b, 2, msg)
+}
+
+func (md metricMetadata) marshal(b []byte) []byte {
+	if md.typ != metricUnknown {
+		b = appendTag(b, 1, wireVarint)
+		b = binary.AppendUvarint(b, uint64(md.typ))
+	}
+	b = appendString(b, 2, md.name)
+	return appendString(b, 4, md.help)
+}
+
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendBytes appends a length-delimited field: an embedded message or,
+// through appendString, a string.
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytes(b, field, []byte(s))
+}
@@ -0,0 +1,131 @@
+package export
+
+import (
+	"bytes"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TestWriteRequest_Golden checks the encoding byte for byte against a
+// WriteRequest assembled by hand from the prompb message definitions.
+func TestWriteRequest_Golden(t *testing.T) {
+	req := writeRequest{
+		series: []timeSeries{{
+			labels:    []promLabel{{"__name__", "x_total"}, {"pod", "web"}},
+			value:     2,
+			timestamp: 1000,
+		}},
+		metadata: []metricMetadata{{typ: metricCounter, name: "x_total", help: "Help."}},
+	}
+
+	var golden []byte
+	add := func(b ...any) {
+		for _, v := range b {
+			switch v := v.(type) {
+			case int:
+				golden = append(golden, byte(v))
+			case string:
+				golden = append(golden, v...)
+			}
+		}
+	}
+	add(0x0a, 0x2f)                           // timeseries (1), 47 bytes
+	add(0x0a, 0x13)                           //   labels (1), 19 bytes
+	add(0x0a, 0x08, "__name__")               //     name (1)
+	add(0x12, 0x07, "x_total")                //     value (2)
+	add(0x0a, 0x0a)                           //   labels (1), 10 bytes
+	add(0x0a, 0x03, "pod", 0x12, 0x03, "web") //     name, value
+	add(0x12, 0x0c)                           //   samples (2), 12 bytes
+	add(0x09, 0, 0, 0, 0, 0, 0, 0, 0x40)      //     value (1), fixed64 2.0
+	add(0x10, 0xe8, 0x07)                     //     timestamp (2), varint 1000
+	add(0x1a, 0x12)                           // metadata (3), 18 bytes
+	add(0x08, 0x01)                           //   type (1), COUNTER
+	add(0x12, 0x07, "x_total")                //   metric_family_name (2)
+	add(0x22, 0x05, "Help.")                  //   help (4)
+
+	if got := req.marshal(); !bytes.Equal(got, golden) {
+		t.Errorf("marshal =\n% x\nwant\n% x", got, golden)
+	}
+}
+
+// TestWriteRequest_ZeroValuesOmitted checks proto3 zero values are left
+// out, but an empty sample is still sent.
+func TestWriteRequest_ZeroValuesOmitted(t *testing.T) {
+	req := writeRequest{
+		series:   []timeSeries{{labels: []promLabel{{"__name__", "x"}}}},
+		metadata: []metricMetadata{{typ: metricUnknown, name: "x"}},
+	}
+	golden := []byte{
+		0x0a, 0x11, // timeseries (1), 17 bytes
+		0x0a, 0x0d, //   labels (1), 13 bytes
+		0x0a, 0x08, '_', '_', 'n', 'a', 'm', 'e', '_', '_',
+		0x12, 0x01, 'x',
+		0x12, 0x00, //   samples (2), empty: value 0 and timestamp 0 omitted
+		0x1a, 0x03, // metadata (3): type UNKNOWN and help omitted
+		0x12, 0x01, 'x',
+	}
+	if got := req.marshal(); !bytes.Equal(got, golden) {
+		t.Errorf("marshal =\n% x\nwant\n% x", got, golden)
+	}
+}
+
+func TestNewWriteRequest(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	c := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "x_total", Help: "X."}, []string{"pod", "namespace"})
+	h := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "lat_seconds", Help: "Lat.", Buckets: []float64{0.001, 0.5}})
+	reg.MustRegister(c, h)
+	c.WithLabelValues("web-0", "").Add(3)
+	h.Observe(0.0002)
+	h.Observe(0.2)
+	h.Observe(7)
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := newWriteRequest(families, map[string]string{"cluster": "prod", "pod": "ignored"}, 1234)
+	got := make(map[string]float64)
+	for _, ts := range req.series {
+		var parts []string
+		for _, l := range ts.labels {
+			parts = append(parts, l.name+"="+l.value)
+		}
+		if !slices.IsSortedFunc(ts.labels, func(a, b promLabel) int { return strings.Compare(a.name, b.name) }) {
+			t.Errorf("labels %v not sorted", parts)
+		}
+		if ts.timestamp != 1234 {
+			t.Errorf("%v at %d, want 1234", parts, ts.timestamp)
+		}
+		got[strings.Join(parts, ",")] = ts.value
+	}
+	want := map[string]float64{
+		// The empty namespace is left out; the series' own pod wins over
+		// the external label.
+		"__name__=x_total,cluster=prod,pod=web-0":                       3,
+		"__name__=lat_seconds_bucket,cluster=prod,le=0.001,pod=ignored": 1,
+		"__name__=lat_seconds_bucket,cluster=prod,le=0.5,pod=ignored":   2,
+		"__name__=lat_seconds_bucket,cluster=prod,le=+Inf,pod=ignored":  3,
+		"__name__=lat_seconds_sum,cluster=prod,pod=ignored":             7.2002,
+		"__name__=lat_seconds_count,cluster=prod,pod=ignored":           3,
+	}
+	if len(got) != len(want) {
+		t.Errorf("series = %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if math.Abs(got[k]-v) > 1e-9 {
+			t.Errorf("%s = %v, want %v", k, got[k], v)
+		}
+	}
+
+	types := map[string]metricType{}
+	for _, md := range req.metadata {
+		types[md.name] = md.typ
+	}
+	if types["x_total"] != metricCounter || types["lat_seconds"] != metricHistogram {
+		t.Errorf("metadata = %+v", req.metadata)
+	}
+}
@@ -0,0 +1,302 @@
+// Package kernelfeat detects what the running kernel offers the agent's
+// BPF programs: its version, kernel BTF, and which tracepoints and
+// tracepoint fields exist. Modules use it to pick an attach strategy on
+// older kernels instead of failing to attach.
+//
+// Tracepoints are looked up in tracefs when it is mounted, else in kernel
+// BTF (every tracepoint has a btf_trace_<name> typedef). Fields are read
+// from the tracepoint's tracefs format file, else from BTF, else inferred
+// from the kernel version they were added in.
+package kernelfeat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Version is a kernel release's numeric prefix, e.g. 5.15.0.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses the leading major.minor[.patch] of a uname release
+// such as "6.1.0-18-amd64".
+func ParseVersion(release string) (Version, error) {
+	end := strings.IndexFunc(release, func(r rune) bool { return r != '.' && (r < '0' || r > '9') })
+	if end < 0 {
+		end = len(release)
+	}
+	parts := strings.Split(strings.Trim(release[:end], "."), ".")
+	if len(parts) < 2 {
+		return Version{}, fmt.Errorf("kernel release %q: want major.minor", release)
+	}
+	var nums [3]int
+	for i := 0; i < len(parts) && i < 3; i++ {
+		n, err := strconv.Atoi(parts[i])
+		if err != nil {
+			return Version{}, fmt.Errorf("kernel release %q: %w", release, err)
+		}
+		nums[i] = n
+	}
+	return Version{Major: nums[0], Minor: nums[1], Patch: nums[2]}, nil
+}
+
+// AtLeast reports whether v is major.minor or later.
+func (v Version) AtLeast(major, minor int) bool {
+	return v.Major > major || v.Major == major && v.Minor >= minor
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Hook is a tracepoint, or one of its fields when Field is set.
+type Hook struct {
+	Group, Name, Field string
+}
+
+func (h Hook) String() string {
+	if h.Field != "" {
+		return "field:" + h.Group + "/" + h.Name + "." + h.Field
+	}
+	return "tracepoint:" + h.Group + "/" + h.Name
+}
+
+// AgentHooks are the hooks the agent's modules ask about. Detect probes
+// them up front so the startup log and metric cover all of them.
+var AgentHooks = []Hook{
+	{Group: constants.TracepointGroupSkb, Name: constants.TracepointKfreeSkb},
+	{Group: constants.TracepointGroupSkb, Name: constants.TracepointKfreeSkb, Field: constants.TracepointFieldDropReason},
+	{Group: constants.TracepointGroupTCP, Name: constants.TracepointTCPSendReset},
+	{Group: constants.TracepointGroupTCP, Name: constants.TracepointTCPReceiveReset},
+	{Group: constants.TracepointGroupTCP, Name: constants.TracepointTCPRetransmit},
+	{Group: constants.TracepointGroupSched, Name: constants.TracepointSchedProcessExec},
+	{Group: constants.TracepointGroupOOM, Name: constants.TracepointOOMMarkVictim},
+	{Group: constants.TracepointGroupSock, Name: constants.TracepointInetSockSetState},
+	{Group: constants.TracepointGroupSignal, Name: constants.TracepointSignalGenerate},
+}
+
+// fieldSince is when tracepoint fields the agent relies on were added,
+// for kernels with neither tracefs nor BTF to look them up in.
+var fieldSince = map[Hook]Version{
+	{Group: constants.TracepointGroupSkb, Name: constants.TracepointKfreeSkb, Field: constants.TracepointFieldDropReason}: {Major: 5, Minor: 17},
+}
+
+// Source is where Detect looks. DefaultSource reads the running system;
+// tests point it elsewhere.
+type Source struct {
+	// TracefsDirs are candidate tracefs mount points, in order.
+	TracefsDirs []string
+
+	// Release returns the kernel release string (uname -r).
+	Release func() (string, error)
+
+	// KernelBTF returns the kernel's BTF.
+	KernelBTF func() (*btf.Spec, error)
+}
+
+// DefaultSource returns a Source for the running kernel.
+func DefaultSource() Source {
+	return Source{
+		TracefsDirs: []string{constants.TracefsDir, constants.DebugTracefsDir},
+		Release:     uname,
+		KernelBTF:   btf.LoadKernelSpec,
+	}
+}
+
+func uname() (string, error) {
+	var u unix.Utsname
+	if err := unix.Uname(&u); err != nil {
+		return "", err
+	}
+	return unix.ByteSliceToString(u.Release[:]), nil
+}
+
+// Features is what Detect found about the running kernel.
+//
+// A nil *Features reports every hook as present, leaving the attach
+// itself to fail.
+type Features struct {
+	Release string
+	Version Version // zero if Release could not be parsed
+	BTF     bool    // kernel BTF is available
+	Tracefs string  // tracefs events directory, "" if not mounted
+
+	spec *btf.Spec
+
+	mu    sync.Mutex
+	hooks map[Hook]bool
+}
+
+// Detect probes the running kernel, including AgentHooks.
+func Detect() *Features {
+	return DefaultSource().Detect()
+}
+
+// Detect probes the kernel s describes, including AgentHooks.
+func (s Source) Detect() *Features {
+	f := &Features{hooks: make(map[Hook]bool)}
+	if s.Release != nil {
+		if rel, err := s.Release(); err == nil {
+			f.Release = rel
+			f.Version, _ = ParseVersion(rel)
+		}
+	}
+	if s.KernelBTF != nil {
+		if spec, err := s.KernelBTF(); err == nil {
+			f.spec, f.BTF = spec, true
+		}
+	}
+	for _, dir := range s.TracefsDirs {
+		events := filepath.Join(dir, "events")
+		if fi, err := os.Stat(events); err == nil && fi.IsDir() {
+			f.Tracefs = events
+			break
+		}
+	}
+	for _, h := range AgentHooks {
+		f.Has(h)
+	}
+	return f
+}
+
+// Tracepoint reports whether tracepoint group/name exists.
+func (f *Features) Tracepoint(group, name string) bool {
+	return f.Has(Hook{Group: group, Name: name})
+}
+
+// TracepointField reports whether tracepoint group/name exists and
+// carries field.
+func (f *Features) TracepointField(group, name, field string) bool {
+	return f.Has(Hook{Group: group, Name: name, Field: field})
+}
+
+// Fentry reports whether the kernel can attach fentry/fexit programs:
+// BPF trampolines need kernel BTF and Linux 5.5, or 6.0 on arm64. Loading
+// and attaching can still fail, so callers keep a kprobe fallback.
+func (f *Features) Fentry() bool {
+	if f == nil {
+		return true
+	}
+	if !f.BTF {
+		return false
+	}
+	if runtime.GOARCH == "arm64" {
+		return f.Version.AtLeast(6, 0)
+	}
+	return f.Version.AtLeast(5, 5)
+}
+
+// Has reports whether h exists. When the kernel offers no way to tell,
+// tracepoints are assumed present and fields present unless fieldSince
+// says the kernel predates them.
+func (f *Features) Has(h Hook) bool {
+	if f == nil {
+		return true
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if ok, cached := f.hooks[h]; cached {
+		return ok
+	}
+	ok := f.detect(h)
+	f.hooks[h] = ok
+	return ok
+}
+
+func (f *Features) detect(h Hook) bool {
+	if h.Field != "" && !f.detect(Hook{Group: h.Group, Name: h.Name}) {
+		return false
+	}
+	switch {
+	case f.Tracefs != "":
+		dir := filepath.Join(f.Tracefs, h.Group, h.Name)
+		if h.Field == "" {
+			_, err := os.Stat(dir)
+			return err == nil
+		}
+		ok, err := formatHasField(filepath.Join(dir, "format"), h.Field)
+		if err == nil {
+			return ok
+		}
+	case f.spec != nil:
+		if h.Field == "" {
+			var td *btf.Typedef
+			return f.spec.TypeByName("btf_trace_"+h.Name, &td) == nil
+		}
+		// Tracepoints sharing an event class have no trace_event_raw_<name>
+		// of their own; fall through to the version table for those.
+		var s *btf.Struct
+		if f.spec.TypeByName("trace_event_raw_"+h.Name, &s) == nil {
+			for _, m := range s.Members {
+				if m.Name == h.Field {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	if h.Field != "" && f.Version != (Version{}) {
+		if since, known := fieldSince[h]; known {
+			return f.Version.AtLeast(since.Major, since.Minor)
+		}
+	}
+	return true
+}
+
+// formatHasField reports whether a tracefs format file declares field,
+// from lines like "\tfield:enum skb_drop_reason reason;\toffset:28;...".
+func formatHasField(path, field string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	sc := bufio.NewScanner(file)
+	for sc.Scan() {
+		decl, ok := strings.CutPrefix(strings.TrimSpace(sc.Text()), "field:")
+		if !ok {
+			continue
+		}
+		decl, _, _ = strings.Cut(decl, ";")
+		decl, _, _ = strings.Cut(decl, "[")
+		words := strings.Fields(decl)
+		if len(words) > 0 && words[len(words)-1] == field {
+			return true, nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return false, err
+	}
+	return false, nil
+}
+
+// Map returns every capability probed so far by name, for logging and
+// metrics: "btf", "tracefs", "fentry" and one entry per Hook.
+func (f *Features) Map() map[string]bool {
+	if f == nil {
+		return nil
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[string]bool, len(f.hooks)+3)
+	out["btf"] = f.BTF
+	out["tracefs"] = f.Tracefs != ""
+	out["fentry"] = f.Fentry()
+	for h, ok := range f.hooks {
+		out[h.String()] = ok
+	}
+	return out
+}
@@ -0,0 +1,163 @@
+package kernelfeat
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/btf"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		release string
+		want    Version
+		wantErr bool
+	}{
+		{"6.1.0-18-amd64", Version{6, 1, 0}, false},
+		{"5.15.133.1-microsoft-standard-WSL2", Version{5, 15, 133}, false},
+		{"5.4", Version{5, 4, 0}, false},
+		{"6.8.0+", Version{6, 8, 0}, false},
+		{"6", Version{}, true},
+		{"", Version{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.release)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseVersion(%q) = %v, %v; want %v, error %v", tt.release, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestVersion_AtLeast(t *testing.T) {
+	v := Version{5, 17, 0}
+	if !v.AtLeast(5, 17) || !v.AtLeast(4, 19) || v.AtLeast(5, 18) || v.AtLeast(6, 0) {
+		t.Errorf("AtLeast comparisons wrong for %v", v)
+	}
+}
+
+// fakeTracefs builds a tracefs events tree holding skb/kfree_skb, with
+// the reason field if withReason, and no tcp tracepoints.
+func fakeTracefs(t *testing.T, withReason bool) string {
+	t.Helper()
+	root := t.TempDir()
+	dir := filepath.Join(root, "events", "skb", "kfree_skb")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	format := "name: kfree_skb\nID: 1\nformat:\n" +
+		"\tfield:unsigned short common_type;\toffset:0;\tsize:2;\tsigned:0;\n\n" +
+		"\tfield:void * skbaddr;\toffset:8;\tsize:8;\tsigned:0;\n" +
+		"\tfield:unsigned short protocol;\toffset:24;\tsize:2;\tsigned:0;\n"
+	if withReason {
+		format += "\tfield:enum skb_drop_reason reason;\toffset:28;\tsize:4;\tsigned:0;\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "format"), []byte(format), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func noBTF() (*btf.Spec, error) { return nil, errors.New("no BTF") }
+
+func release(r string) func() (string, error) {
+	return func() (string, error) { return r, nil }
+}
+
+func TestDetect_Tracefs(t *testing.T) {
+	for _, withReason := range []bool{true, false} {
+		src := Source{
+			TracefsDirs: []string{filepath.Join(t.TempDir(), "missing"), fakeTracefs(t, withReason)},
+			Release:     release("6.1.0"),
+			KernelBTF:   noBTF,
+		}
+		f := src.Detect()
+		if f.Tracefs == "" {
+			t.Fatal("tracefs not found")
+		}
+		if !f.Tracepoint(constants.TracepointGroupSkb, constants.TracepointKfreeSkb) {
+			t.Error("skb/kfree_skb not detected")
+		}
+		if f.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPSendReset) {
+			t.Error("tcp/tcp_send_reset detected but absent")
+		}
+		// tracefs wins over the version table.
+		got := f.TracepointField(constants.TracepointGroupSkb, constants.TracepointKfreeSkb, constants.TracepointFieldDropReason)
+		if got != withReason {
+			t.Errorf("reason field = %v, want %v", got, withReason)
+		}
+		if !f.TracepointField(constants.TracepointGroupSkb, constants.TracepointKfreeSkb, "skbaddr") {
+			t.Error("skbaddr field not detected")
+		}
+		if f.TracepointField(constants.TracepointGroupTCP, constants.TracepointTCPSendReset, "state") {
+			t.Error("field of a missing tracepoint detected")
+		}
+	}
+}
+
+func TestDetect_VersionFallback(t *testing.T) {
+	for rel, want := range map[string]bool{"5.10.0": false, "5.17.0": true, "6.6.0": true} {
+		f := Source{Release: release(rel), KernelBTF: noBTF}.Detect()
+		if !f.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPSendReset) {
+			t.Errorf("%s: undetectable tracepoint reported missing", rel)
+		}
+		got := f.TracepointField(constants.TracepointGroupSkb, constants.TracepointKfreeSkb, constants.TracepointFieldDropReason)
+		if got != want {
+			t.Errorf("%s: reason field = %v, want %v", rel, got, want)
+		}
+	}
+}
+
+func TestDetect_KernelBTF(t *testing.T) {
+	if _, err := btf.LoadKernelSpec(); err != nil {
+		t.Skipf("kernel BTF unavailable: %v", err)
+	}
+	f := Source{Release: uname, KernelBTF: btf.LoadKernelSpec}.Detect()
+	if !f.BTF {
+		t.Fatal("BTF not detected")
+	}
+	if !f.Tracepoint(constants.TracepointGroupSkb, constants.TracepointKfreeSkb) {
+		t.Error("skb/kfree_skb not found in kernel BTF")
+	}
+	if f.Tracepoint("kubepulse", "no_such_tracepoint") {
+		t.Error("nonexistent tracepoint found in kernel BTF")
+	}
+	want := f.Version.AtLeast(5, 17)
+	if got := f.TracepointField(constants.TracepointGroupSkb, constants.TracepointKfreeSkb, constants.TracepointFieldDropReason); got != want {
+		t.Errorf("reason field = %v on %s, want %v", got, f.Release, want)
+	}
+}
+
+func TestFeatures_Map(t *testing.T) {
+	f := Source{TracefsDirs: []string{fakeTracefs(t, true)}, KernelBTF: noBTF}.Detect()
+	m := f.Map()
+	want := map[string]bool{
+		"btf":                               false,
+		"tracefs":                           true,
+		"tracepoint:skb/kfree_skb":          true,
+		"field:skb/kfree_skb.reason":        true,
+		"tracepoint:tcp/tcp_send_reset":     false,
+		"tracepoint:tcp/tcp_retransmit_skb": false,
+	}
+	for k, v := range want {
+		if got, ok := m[k]; !ok || got != v {
+			t.Errorf("Map()[%q] = %v, %v; want %v", k, got, ok, v)
+		}
+	}
+	if len(m) != len(AgentHooks)+3 {
+		t.Errorf("Map() has %d entries, want %d", len(m), len(AgentHooks)+3)
+	}
+}
+
+func TestFeatures_Nil(t *testing.T) {
+	var f *Features
+	if !f.Tracepoint("tcp", "tcp_send_reset") || !f.TracepointField("skb", "kfree_skb", "reason") {
+		t.Error("nil Features reported a hook missing")
+	}
+	if f.Map() != nil {
+		t.Error("nil Features has capabilities")
+	}
+}
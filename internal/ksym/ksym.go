@@ -0,0 +1,146 @@
+// Package ksym resolves kernel text addresses, such as the location a
+// packet was dropped at, to function names using /proc/kallsyms.
+//
+// The symbol table is read on first use and sorted for binary search.
+// An address past the last known symbol may belong to a module loaded
+// since, so a miss re-reads the table, at most once per
+// constants.KsymRefreshInterval. When kallsyms hides addresses
+// (kptr_restrict, or missing CAP_SYSLOG) every address is reported as hex.
+package ksym
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// symbol is one function in the table.
+type symbol struct {
+	addr uint64
+	name string
+}
+
+// Resolver maps kernel addresses to function names. It is safe for
+// concurrent use.
+type Resolver struct {
+	open func() (io.ReadCloser, error)
+	now  func() time.Time
+
+	mu     sync.Mutex
+	syms   []symbol // sorted by addr
+	loaded time.Time
+	cache  map[uint64]string // resolved addresses only
+	hidden bool              // kallsyms shows every address as 0
+}
+
+// New returns a Resolver reading constants.KallsymsPath.
+func New() *Resolver {
+	return newResolver(func() (io.ReadCloser, error) { return os.Open(constants.KallsymsPath) })
+}
+
+func newResolver(open func() (io.ReadCloser, error)) *Resolver {
+	return &Resolver{open: open, now: time.Now, cache: make(map[uint64]string)}
+}
+
+// Resolve returns the name of the function containing addr, or addr in
+// hex if it cannot be resolved.
+func (r *Resolver) Resolve(addr uint64) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name, ok := r.cache[addr]; ok {
+		return name
+	}
+
+	if r.loaded.IsZero() {
+		r.load()
+	}
+	name, ok := r.lookup(addr)
+	if !ok && !r.hidden && r.now().Sub(r.loaded) >= constants.KsymRefreshInterval {
+		r.load()
+		name, ok = r.lookup(addr)
+	}
+	if !ok {
+		return Hex(addr)
+	}
+	if len(r.cache) < constants.KsymCacheSize {
+		r.cache[addr] = name
+	}
+	return name
+}
+
+// Hex formats addr the way unresolved addresses are reported.
+func Hex(addr uint64) string {
+	return fmt.Sprintf("0x%x", addr)
+}
+
+// lookup finds the last symbol at or below addr. Addresses past the last
+// symbol are misses: they may lie in a module loaded since the table was
+// read.
+func (r *Resolver) lookup(addr uint64) (string, bool) {
+	if len(r.syms) == 0 || addr < r.syms[0].addr || addr > r.syms[len(r.syms)-1].addr {
+		return "", false
+	}
+	i, found := slices.BinarySearchFunc(r.syms, addr, func(s symbol, a uint64) int {
+		return cmp.Compare(s.addr, a)
+	})
+	if !found {
+		i-- // the symbol before the insertion point contains addr
+	}
+	return r.syms[i].name, true
+}
+
+// load (re)reads the symbol table. If it cannot be read, the previous
+// table is kept.
+func (r *Resolver) load() {
+	r.loaded = r.now()
+	f, err := r.open()
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	if syms, hidden, err := parse(f); err == nil {
+		r.syms, r.hidden = syms, hidden
+	}
+}
+
+// parse reads the text symbols from a kallsyms listing, whose lines are
+// "address type name [module]", sorted by address. hidden reports that
+// every address was zero.
+func parse(rd io.Reader) (syms []symbol, hidden bool, err error) {
+	sawZero := false
+	sc := bufio.NewScanner(rd)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		switch fields[1] {
+		case "t", "T", "w", "W":
+		default:
+			continue
+		}
+		addr, err := strconv.ParseUint(fields[0], 16, 64)
+		if err != nil {
+			continue
+		}
+		if addr == 0 {
+			sawZero = true
+			continue
+		}
+		syms = append(syms, symbol{addr: addr, name: fields[2]})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, false, err
+	}
+	slices.SortFunc(syms, func(a, b symbol) int { return cmp.Compare(a.addr, b.addr) })
+	return syms, sawZero && len(syms) == 0, nil
+}
@@ -0,0 +1,113 @@
+package ksym
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+const kallsyms = `ffffffff81000000 T _stext
+ffffffff81a00000 T tcp_v4_rcv
+ffffffff81a00400 t tcp_v4_do_rcv
+ffffffff81a00800 D some_data
+ffffffff81b00000 T ip_rcv_finish
+ffffffff81b00100 W weak_fn
+ffffffffc0a00000 t nf_hook_slow	[nf_tables]
+`
+
+// table serves kallsyms listings, counting reads.
+type table struct {
+	listing string
+	reads   int
+	err     error
+}
+
+func (tb *table) open() (io.ReadCloser, error) {
+	tb.reads++
+	if tb.err != nil {
+		return nil, tb.err
+	}
+	return io.NopCloser(strings.NewReader(tb.listing)), nil
+}
+
+func TestResolve(t *testing.T) {
+	tb := &table{listing: kallsyms}
+	r := newResolver(tb.open)
+	tests := map[uint64]string{
+		0xffffffff81a00000: "tcp_v4_rcv",    // exact
+		0xffffffff81a0012c: "tcp_v4_rcv",    // inside
+		0xffffffff81a00900: "tcp_v4_do_rcv", // data symbols are skipped
+		0xffffffff81b00104: "weak_fn",
+		0xffffffffc0a00000: "nf_hook_slow",       // module symbol, last in the table
+		0xffffffffc0a00010: "0xffffffffc0a00010", // past the last symbol: size unknown
+		0xffffffff80000000: "0xffffffff80000000",
+	}
+	for addr, want := range tests {
+		if got := r.Resolve(addr); got != want {
+			t.Errorf("Resolve(%#x) = %q, want %q", addr, got, want)
+		}
+	}
+	if tb.reads != 1 {
+		t.Errorf("kallsyms read %d times, want 1", tb.reads)
+	}
+}
+
+func TestResolve_RefreshesOnMiss(t *testing.T) {
+	tb := &table{listing: kallsyms}
+	r := newResolver(tb.open)
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	const modAddr = 0xffffffffc0b00020
+	if got := r.Resolve(modAddr); got != Hex(modAddr) {
+		t.Fatalf("Resolve before the module loaded = %q", got)
+	}
+
+	// A module loads; misses within the refresh interval don't re-read.
+	tb.listing += "ffffffffc0b00000 t br_handle_frame\t[bridge]\nffffffffc0c00000 t br_end\t[bridge]\n"
+	r.Resolve(modAddr)
+	if tb.reads != 1 {
+		t.Fatalf("kallsyms read %d times within the refresh interval, want 1", tb.reads)
+	}
+
+	now = now.Add(constants.KsymRefreshInterval)
+	if got := r.Resolve(modAddr); got != "br_handle_frame" {
+		t.Errorf("Resolve after refresh = %q, want br_handle_frame", got)
+	}
+	if tb.reads != 2 {
+		t.Errorf("kallsyms read %d times, want 2", tb.reads)
+	}
+
+	// Resolved addresses are cached.
+	r.Resolve(modAddr)
+	if tb.reads != 2 {
+		t.Errorf("cached address re-read kallsyms")
+	}
+}
+
+func TestResolve_Restricted(t *testing.T) {
+	// With kptr_restrict, kallsyms lists every address as zero.
+	tb := &table{listing: "0000000000000000 T _stext\n0000000000000000 T tcp_v4_rcv\n"}
+	r := newResolver(tb.open)
+	r.now = func() time.Time { return time.Unix(0, 0).Add(time.Duration(tb.reads) * time.Hour) }
+
+	for range 3 {
+		if got := r.Resolve(0xffffffff81a00010); got != "0xffffffff81a00010" {
+			t.Errorf("Resolve = %q, want hex", got)
+		}
+	}
+	if tb.reads != 1 {
+		t.Errorf("restricted kallsyms read %d times, want 1", tb.reads)
+	}
+}
+
+func TestResolve_Unreadable(t *testing.T) {
+	r := newResolver((&table{err: errors.New("permission denied")}).open)
+	if got := r.Resolve(0x1234); got != "0x1234" {
+		t.Errorf("Resolve = %q, want hex", got)
+	}
+}
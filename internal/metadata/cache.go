@@ -4,6 +4,8 @@ package metadata
 import (
 	"sync"
 	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 )
 
 // PodMeta holds Kubernetes pod metadata for metrics labeling.
@@ -13,6 +15,15 @@ type PodMeta struct {
 	NodeName      string
 	ContainerName string
 	ContainerID   string
+
+	// MemoryLimitBytes is the container's memory limit, 0 if it has none.
+	MemoryLimitBytes int64
+}
+
+// PodKey identifies a pod across namespaces.
+type PodKey struct {
+	Namespace string
+	Name      string
 }
 
 // cacheEntry wraps PodMeta with an expiry time for TTL eviction.
@@ -33,6 +44,10 @@ type Cache struct {
 	containerIndex map[string]PodMeta
 	ciMu           sync.RWMutex
 
+	// serviceIndex maps IP → Service (ClusterIPs and endpoint addresses)
+	serviceIndex map[string]ServiceRef
+	siMu         sync.RWMutex
+
 	// resolver function: PID → containerID
 	resolveContainerID func(pid uint32) (string, error)
 }
@@ -41,6 +56,10 @@ type Cache struct {
 type CacheConfig struct {
 	MaxSize int           // Maximum number of PID entries (default: 8192)
 	TTL     time.Duration // TTL for cache entries (default: 60s)
+
+	// ResolveContainerID maps a PID to its container ID, "" for a process
+	// outside a container (default: ContainerIDFromPID).
+	ResolveContainerID func(pid uint32) (string, error)
 }
 
 // DefaultCacheConfig returns sensible default cache configuration.
@@ -59,13 +78,17 @@ func NewCache(config CacheConfig) *Cache {
 	if config.TTL <= 0 {
 		config.TTL = 60 * time.Second
 	}
+	if config.ResolveContainerID == nil {
+		config.ResolveContainerID = ContainerIDFromPID
+	}
 
 	return &Cache{
 		entries:            make(map[uint32]cacheEntry, config.MaxSize),
 		maxSize:            config.MaxSize,
 		ttl:                config.TTL,
 		containerIndex:     make(map[string]PodMeta),
-		resolveContainerID: ContainerIDFromPID,
+		serviceIndex:       make(map[string]ServiceRef),
+		resolveContainerID: config.ResolveContainerID,
 	}
 }
 
@@ -73,19 +96,35 @@ func NewCache(config CacheConfig) *Cache {
 // If the PID is cached and not expired, returns the cached value.
 // If not cached, resolves container ID via /proc and looks up k8s metadata.
 func (c *Cache) Lookup(pid uint32) (PodMeta, bool) {
+	meta, result := c.Resolve(pid)
+	return meta, result == constants.EnrichmentOK
+}
+
+// Resolve is Lookup reporting how the PID resolved: constants.EnrichmentOK
+// with its pod, or why it has none — EnrichmentHost outside a container
+// (PID 0, the idle task, included), EnrichmentMiss for a container the
+// index doesn't hold, EnrichmentError if its cgroup couldn't be read.
+func (c *Cache) Resolve(pid uint32) (PodMeta, string) {
+	if pid == 0 {
+		return PodMeta{}, constants.EnrichmentHost
+	}
+
 	// Check cache first
 	c.mu.RLock()
 	entry, found := c.entries[pid]
 	c.mu.RUnlock()
 
 	if found && time.Now().Before(entry.expires) {
-		return entry.meta, true
+		return entry.meta, constants.EnrichmentOK
 	}
 
 	// Cache miss or expired — resolve container ID
 	containerID, err := c.resolveContainerID(pid)
-	if err != nil || containerID == "" {
-		return PodMeta{}, false
+	if err != nil {
+		return PodMeta{}, constants.EnrichmentError
+	}
+	if containerID == "" {
+		return PodMeta{}, constants.EnrichmentHost
 	}
 
 	// Look up pod metadata by container ID
@@ -94,12 +133,12 @@ func (c *Cache) Lookup(pid uint32) (PodMeta, bool) {
 	c.ciMu.RUnlock()
 
 	if !found {
-		return PodMeta{}, false
+		return PodMeta{}, constants.EnrichmentMiss
 	}
 
 	// Cache the result
 	c.set(pid, meta)
-	return meta, true
+	return meta, constants.EnrichmentOK
 }
 
 // UpdatePod updates the container-to-pod index when a pod is discovered.
@@ -118,6 +157,17 @@ func (c *Cache) DeletePod(containerID string) {
 	c.ciMu.Unlock()
 }
 
+// Pods returns the set of pods currently known to the index.
+func (c *Cache) Pods() map[PodKey]struct{} {
+	c.ciMu.RLock()
+	defer c.ciMu.RUnlock()
+	pods := make(map[PodKey]struct{}, len(c.containerIndex))
+	for _, meta := range c.containerIndex {
+		pods[PodKey{Namespace: meta.Namespace, Name: meta.PodName}] = struct{}{}
+	}
+	return pods
+}
+
 // set stores a PID → PodMeta entry in the cache with TTL.
 func (c *Cache) set(pid uint32, meta PodMeta) {
 	c.mu.Lock()
@@ -3,12 +3,15 @@ package metadata
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 
 	"go.uber.org/zap"
 	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/client-go/informers"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
@@ -17,6 +20,7 @@ import (
 )
 
 // K8sWatcher watches Kubernetes pod events and updates the metadata cache.
+// It also indexes Service IPs cluster-wide for destination resolution.
 type K8sWatcher struct {
 	clientset *kubernetes.Clientset
 	cache     *Cache
@@ -28,17 +32,11 @@ type K8sWatcher struct {
 // It uses in-cluster config when running inside a pod, or kubeconfig from
 // KUBECONFIG env / ~/.kube/config when running outside.
 func NewK8sWatcher(metaCache *Cache, logger *zap.Logger) (*K8sWatcher, error) {
-	config, err := rest.InClusterConfig()
+	config, kubeconfig, err := restConfig()
 	if err != nil {
-		// Fall back to kubeconfig for development
-		kubeconfig := os.Getenv("KUBECONFIG")
-		if kubeconfig == "" {
-			kubeconfig = os.ExpandEnv("$HOME/.kube/config")
-		}
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
-		if err != nil {
-			return nil, fmt.Errorf("building kubernetes config: %w", err)
-		}
+		return nil, err
+	}
+	if kubeconfig != "" {
 		logger.Info("Using kubeconfig for Kubernetes access", zap.String("path", kubeconfig))
 	} else {
 		logger.Info("Using in-cluster Kubernetes config")
@@ -62,6 +60,47 @@ func NewK8sWatcher(metaCache *Cache, logger *zap.Logger) (*K8sWatcher, error) {
 	}, nil
 }
 
+// restConfig returns the in-cluster config or, outside a cluster, the
+// config from KUBECONFIG or ~/.kube/config along with that path.
+func restConfig() (*rest.Config, string, error) {
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		return config, "", nil
+	}
+	// Fall back to kubeconfig for development
+	kubeconfig := os.Getenv("KUBECONFIG")
+	if kubeconfig == "" {
+		kubeconfig = os.ExpandEnv("$HOME/.kube/config")
+	}
+	config, err = clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, "", fmt.Errorf("building kubernetes config: %w", err)
+	}
+	return config, kubeconfig, nil
+}
+
+// Ping checks that the Kubernetes API server is reachable with the
+// credentials NewK8sWatcher would use, and returns its version.
+func Ping(ctx context.Context) (string, error) {
+	config, _, err := restConfig()
+	if err != nil {
+		return "", err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return "", fmt.Errorf("creating kubernetes client: %w", err)
+	}
+	raw, err := clientset.Discovery().RESTClient().Get().AbsPath("/version").Do(ctx).Raw()
+	if err != nil {
+		return "", fmt.Errorf("querying kubernetes API server: %w", err)
+	}
+	var info version.Info
+	if err := json.Unmarshal(raw, &info); err != nil {
+		return "", fmt.Errorf("decoding kubernetes version: %w", err)
+	}
+	return info.GitVersion, nil
+}
+
 // Run starts watching pod events on the local node and populating the cache.
 // It blocks until ctx is cancelled.
 func (w *K8sWatcher) Run(ctx context.Context) error {
@@ -109,11 +148,67 @@ func (w *K8sWatcher) Run(ctx context.Context) error {
 		},
 	})
 
+	// Services and their endpoints are watched cluster-wide: a local pod
+	// can connect to a Service backed by pods on any node.
+	clusterFactory := informers.NewSharedInformerFactory(w.clientset, 0)
+	svcInformer := clusterFactory.Core().V1().Services().Informer()
+	svcInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if svc, ok := obj.(*corev1.Service); ok {
+				w.cache.UpdateServiceIPs(serviceRef(svc.ObjectMeta), serviceIPs(svc))
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSvc, ok1 := oldObj.(*corev1.Service)
+			newSvc, ok2 := newObj.(*corev1.Service)
+			if ok1 && ok2 {
+				w.cache.DeleteServiceIPs(serviceRef(oldSvc.ObjectMeta), serviceIPs(oldSvc))
+				w.cache.UpdateServiceIPs(serviceRef(newSvc.ObjectMeta), serviceIPs(newSvc))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if svc, ok := unwrapTombstone(obj).(*corev1.Service); ok {
+				w.cache.DeleteServiceIPs(serviceRef(svc.ObjectMeta), serviceIPs(svc))
+			}
+		},
+	})
+	sliceInformer := clusterFactory.Discovery().V1().EndpointSlices().Informer()
+	sliceInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if slice, ok := obj.(*discoveryv1.EndpointSlice); ok {
+				if ref, ok := sliceServiceRef(slice); ok {
+					w.cache.UpdateServiceIPs(ref, sliceIPs(slice))
+				}
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			oldSlice, ok1 := oldObj.(*discoveryv1.EndpointSlice)
+			newSlice, ok2 := newObj.(*discoveryv1.EndpointSlice)
+			if !ok1 || !ok2 {
+				return
+			}
+			if ref, ok := sliceServiceRef(oldSlice); ok {
+				w.cache.DeleteServiceIPs(ref, sliceIPs(oldSlice))
+			}
+			if ref, ok := sliceServiceRef(newSlice); ok {
+				w.cache.UpdateServiceIPs(ref, sliceIPs(newSlice))
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if slice, ok := unwrapTombstone(obj).(*discoveryv1.EndpointSlice); ok {
+				if ref, ok := sliceServiceRef(slice); ok {
+					w.cache.DeleteServiceIPs(ref, sliceIPs(slice))
+				}
+			}
+		},
+	})
+
 	w.logger.Info("Starting Kubernetes pod watcher",
 		zap.String("node", w.nodeName))
 
-	// Start the informer
+	// Start the informers
 	factory.Start(ctx.Done())
+	clusterFactory.Start(ctx.Done())
 
 	// Wait for cache sync
 	if !cache.WaitForCacheSync(ctx.Done(), podInformer.HasSynced) {
@@ -121,10 +216,28 @@ func (w *K8sWatcher) Run(ctx context.Context) error {
 	}
 	w.logger.Info("Kubernetes pod cache synced")
 
+	// A missing service index only costs dst_service labels, so don't fail.
+	if !cache.WaitForCacheSync(ctx.Done(), svcInformer.HasSynced, sliceInformer.HasSynced) {
+		w.logger.Warn("Service index did not sync — destinations will show as external")
+	} else {
+		w.logger.Info("Kubernetes service index synced")
+	}
+
 	<-ctx.Done()
 	return ctx.Err()
 }
 
+// memoryLimit returns the memory limit in bytes of the named container in
+// pod's spec, or 0 if it sets none.
+func memoryLimit(pod *corev1.Pod, container string) int64 {
+	for _, c := range pod.Spec.Containers {
+		if c.Name == container {
+			return c.Resources.Limits.Memory().Value()
+		}
+	}
+	return 0
+}
+
 // updatePodContainers updates the cache with container IDs from a pod.
 func (w *K8sWatcher) updatePodContainers(pod *corev1.Pod) {
 	for _, status := range pod.Status.ContainerStatuses {
@@ -134,11 +247,12 @@ func (w *K8sWatcher) updatePodContainers(pod *corev1.Pod) {
 		}
 
 		meta := PodMeta{
-			PodName:       pod.Name,
-			Namespace:     pod.Namespace,
-			NodeName:      pod.Spec.NodeName,
-			ContainerName: status.Name,
-			ContainerID:   containerID,
+			PodName:          pod.Name,
+			Namespace:        pod.Namespace,
+			NodeName:         pod.Spec.NodeName,
+			ContainerName:    status.Name,
+			ContainerID:      containerID,
+			MemoryLimitBytes: memoryLimit(pod, status.Name),
 		}
 
 		w.cache.UpdatePod(containerID, meta)
@@ -164,6 +278,53 @@ func (w *K8sWatcher) deletePodContainers(pod *corev1.Pod) {
 	}
 }
 
+// serviceRef returns the ServiceRef for an object's metadata.
+func serviceRef(meta metav1.ObjectMeta) ServiceRef {
+	return ServiceRef{Namespace: meta.Namespace, Name: meta.Name}
+}
+
+// serviceIPs returns a Service's cluster IPs. Headless Services have none;
+// their pods are indexed from EndpointSlices instead.
+func serviceIPs(svc *corev1.Service) []string {
+	var ips []string
+	for _, ip := range svc.Spec.ClusterIPs {
+		if ip != corev1.ClusterIPNone {
+			ips = append(ips, ip)
+		}
+	}
+	if len(ips) == 0 && svc.Spec.ClusterIP != "" && svc.Spec.ClusterIP != corev1.ClusterIPNone {
+		ips = append(ips, svc.Spec.ClusterIP)
+	}
+	return ips
+}
+
+// sliceServiceRef returns the Service that owns an EndpointSlice.
+func sliceServiceRef(slice *discoveryv1.EndpointSlice) (ServiceRef, bool) {
+	name := slice.Labels[discoveryv1.LabelServiceName]
+	if name == "" {
+		return ServiceRef{}, false
+	}
+	return ServiceRef{Namespace: slice.Namespace, Name: name}, true
+}
+
+// sliceIPs returns every endpoint address in an EndpointSlice.
+func sliceIPs(slice *discoveryv1.EndpointSlice) []string {
+	var ips []string
+	for _, ep := range slice.Endpoints {
+		ips = append(ips, ep.Addresses...)
+	}
+	return ips
+}
+
+// unwrapTombstone returns the last known object from a delete
+// notification, which may arrive as DeletedFinalStateUnknown.
+func unwrapTombstone(obj interface{}) interface{} {
+	if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		return tombstone.Obj
+	}
+	return obj
+}
+
 // extractContainerIDFromStatus parses the container ID from a Kubernetes
 // container status string like "containerd://abc123..." or "docker://abc123..."
 func extractContainerIDFromStatus(raw string) string {
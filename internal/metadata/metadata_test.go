@@ -1,10 +1,19 @@
 package metadata
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
 	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 )
 
 func TestExtractContainerID(t *testing.T) {
@@ -129,6 +138,37 @@ func TestCache_BasicLookup(t *testing.T) {
 	}
 }
 
+func TestCache_Resolve(t *testing.T) {
+	cache := NewCache(CacheConfig{MaxSize: 100, TTL: time.Minute,
+		ResolveContainerID: func(pid uint32) (string, error) {
+			switch pid {
+			case 1, 2:
+				return fmt.Sprintf("container%d", pid), nil
+			case 3:
+				return "", errors.New("open /proc/3/cgroup: no such file or directory")
+			}
+			return "", nil
+		},
+	})
+	cache.UpdatePod("container1", PodMeta{PodName: "my-pod", Namespace: "default"})
+
+	for pid, want := range map[uint32]string{
+		0: constants.EnrichmentHost,
+		1: constants.EnrichmentOK,
+		2: constants.EnrichmentMiss, // container the informer hasn't reported
+		3: constants.EnrichmentError,
+		4: constants.EnrichmentHost,
+	} {
+		meta, got := cache.Resolve(pid)
+		if got != want {
+			t.Errorf("Resolve(%d) = %q, want %q", pid, got, want)
+		}
+		if (meta.PodName != "") != (want == constants.EnrichmentOK) {
+			t.Errorf("Resolve(%d) returned pod %q with result %q", pid, meta.PodName, got)
+		}
+	}
+}
+
 func TestCache_TTLExpiry(t *testing.T) {
 	cache := NewCache(CacheConfig{MaxSize: 100, TTL: 10 * time.Millisecond})
 
@@ -205,3 +245,85 @@ func TestCache_Stats(t *testing.T) {
 		t.Errorf("expected 2 container entries, got %d", containers)
 	}
 }
+
+func TestCache_ServiceIndex(t *testing.T) {
+	cache := NewCache(DefaultCacheConfig())
+	api := ServiceRef{Namespace: "prod", Name: "api"}
+	db := ServiceRef{Namespace: "prod", Name: "db"}
+
+	cache.UpdateServiceIPs(api, []string{"10.96.0.10", "10.1.2.3"})
+	cache.UpdateServiceIPs(db, []string{"10.96.0.20", "10.1.2.3"}) // shared endpoint, db wins
+
+	if svc, found := cache.LookupService("10.96.0.10"); !found || svc != api {
+		t.Fatalf("LookupService(cluster IP) = %v, %v", svc, found)
+	}
+	if svc, _ := cache.LookupService("10.1.2.3"); svc != db {
+		t.Fatalf("shared IP resolved to %v, want %v", svc, db)
+	}
+	if svc := api.String(); svc != "prod/api" {
+		t.Errorf("String() = %q", svc)
+	}
+
+	// Deleting api must not unmap the address db now owns.
+	cache.DeleteServiceIPs(api, []string{"10.96.0.10", "10.1.2.3"})
+	if _, found := cache.LookupService("10.96.0.10"); found {
+		t.Error("api cluster IP still indexed after delete")
+	}
+	if svc, found := cache.LookupService("10.1.2.3"); !found || svc != db {
+		t.Errorf("shared IP = %v, %v after deleting api, want %v", svc, found, db)
+	}
+}
+
+func TestServiceIPs(t *testing.T) {
+	svc := &corev1.Service{Spec: corev1.ServiceSpec{
+		ClusterIP:  "10.96.0.10",
+		ClusterIPs: []string{"10.96.0.10", "fd00::10"},
+	}}
+	if got := serviceIPs(svc); len(got) != 2 {
+		t.Errorf("serviceIPs = %v, want both families", got)
+	}
+
+	headless := &corev1.Service{Spec: corev1.ServiceSpec{
+		ClusterIP:  corev1.ClusterIPNone,
+		ClusterIPs: []string{corev1.ClusterIPNone},
+	}}
+	if got := serviceIPs(headless); len(got) != 0 {
+		t.Errorf("headless serviceIPs = %v, want none", got)
+	}
+
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "prod",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "db"},
+		},
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.1.2.3"}},
+			{Addresses: []string{"10.1.2.4"}},
+		},
+	}
+	ref, ok := sliceServiceRef(slice)
+	if !ok || ref != (ServiceRef{"prod", "db"}) {
+		t.Errorf("sliceServiceRef = %v, %v", ref, ok)
+	}
+	if got := sliceIPs(slice); len(got) != 2 {
+		t.Errorf("sliceIPs = %v", got)
+	}
+}
+
+func TestMemoryLimit(t *testing.T) {
+	pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+		{Name: "app", Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("256Mi"),
+		}}},
+		{Name: "sidecar"},
+	}}}
+	for container, want := range map[string]int64{
+		"app":     256 << 20,
+		"sidecar": 0, // no limit
+		"gone":    0, // not in the spec
+	} {
+		if got := memoryLimit(pod, container); got != want {
+			t.Errorf("memoryLimit(%q) = %d, want %d", container, got, want)
+		}
+	}
+}
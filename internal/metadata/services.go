@@ -0,0 +1,45 @@
+package metadata
+
+// ServiceRef identifies a Kubernetes Service.
+type ServiceRef struct {
+	Namespace string
+	Name      string
+}
+
+// String returns "namespace/name", the form stored in event labels.
+func (s ServiceRef) String() string {
+	return s.Namespace + "/" + s.Name
+}
+
+// LookupService resolves an IP (a ClusterIP or a ready endpoint address)
+// to the Service it belongs to.
+func (c *Cache) LookupService(ip string) (ServiceRef, bool) {
+	c.siMu.RLock()
+	svc, ok := c.serviceIndex[ip]
+	c.siMu.RUnlock()
+	return svc, ok
+}
+
+// UpdateServiceIPs maps each IP to svc. An IP backing several Services
+// resolves to the one indexed last.
+func (c *Cache) UpdateServiceIPs(svc ServiceRef, ips []string) {
+	c.siMu.Lock()
+	for _, ip := range ips {
+		if ip != "" {
+			c.serviceIndex[ip] = svc
+		}
+	}
+	c.siMu.Unlock()
+}
+
+// DeleteServiceIPs removes IPs from the index, but only where they still
+// map to svc, so removing one Service doesn't unmap another's addresses.
+func (c *Cache) DeleteServiceIPs(svc ServiceRef, ips []string) {
+	c.siMu.Lock()
+	for _, ip := range ips {
+		if c.serviceIndex[ip] == svc {
+			delete(c.serviceIndex, ip)
+		}
+	}
+	c.siMu.Unlock()
+}
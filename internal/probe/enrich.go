@@ -0,0 +1,39 @@
+package probe
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+)
+
+var enrichmentResults = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricEnrichmentResults,
+	Help: "Events by how their PID resolved to a pod: ok, miss, host or error.",
+}, constants.LabelsResult)
+
+// Enrich sets e's namespace and pod from the first of pids that resolves
+// to a pod, trying the next only when one doesn't, and labels e with how
+// the last one tried resolved (constants.KeyEnrichment). It returns the
+// pod for modules that report more of it.
+//
+// Without a metadata cache it leaves e alone.
+func (d Dependencies) Enrich(e *event.Event, pids ...uint32) (metadata.PodMeta, bool) {
+	if d.Metadata == nil {
+		return metadata.PodMeta{}, false
+	}
+	var meta metadata.PodMeta
+	result := constants.EnrichmentHost
+	for _, pid := range pids {
+		if meta, result = d.Metadata.Resolve(pid); result == constants.EnrichmentOK {
+			e.Namespace = meta.Namespace
+			e.Pod = meta.PodName
+			break
+		}
+	}
+	e.SetLabel(constants.KeyEnrichment, result)
+	enrichmentResults.WithLabelValues(result).Inc()
+	return meta, result == constants.EnrichmentOK
+}
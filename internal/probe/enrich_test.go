@@ -0,0 +1,61 @@
+package probe
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+)
+
+func TestEnrich(t *testing.T) {
+	cache := metadata.NewCache(metadata.CacheConfig{
+		ResolveContainerID: func(pid uint32) (string, error) {
+			switch pid {
+			case 10:
+				return "known", nil
+			case 20:
+				return "unknown", nil
+			}
+			return "", nil
+		},
+	})
+	cache.UpdatePod("known", metadata.PodMeta{Namespace: "prod", PodName: "api", ContainerName: "app"})
+	deps := Dependencies{Metadata: cache}
+
+	tests := []struct {
+		name   string
+		pids   []uint32
+		want   string
+		wantNS string
+	}{
+		{"ok", []uint32{10}, constants.EnrichmentOK, "prod"},
+		{"miss", []uint32{20}, constants.EnrichmentMiss, ""},
+		{"host", []uint32{30}, constants.EnrichmentHost, ""},
+		{"fallback", []uint32{20, 10}, constants.EnrichmentOK, "prod"},
+		{"last tried", []uint32{20, 30}, constants.EnrichmentHost, ""},
+	}
+	for _, tt := range tests {
+		before := testutil.ToFloat64(enrichmentResults.WithLabelValues(tt.want))
+		e := &event.Event{Labels: map[string]string{}}
+		meta, found := deps.Enrich(e, tt.pids...)
+		if got := e.Label(constants.KeyEnrichment); got != tt.want {
+			t.Errorf("%s: enrichment = %q, want %q", tt.name, got, tt.want)
+		}
+		if e.Namespace != tt.wantNS || found != (tt.wantNS != "") || meta.Namespace != tt.wantNS {
+			t.Errorf("%s: namespace %q, returned %+v, %v; want %q", tt.name, e.Namespace, meta, found, tt.wantNS)
+		}
+		if got := testutil.ToFloat64(enrichmentResults.WithLabelValues(tt.want)) - before; got != 1 {
+			t.Errorf("%s: counted %v %s results, want 1", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEnrich_NoMetadata(t *testing.T) {
+	e := &event.Event{Labels: map[string]string{}}
+	if _, found := (Dependencies{}).Enrich(e, 10); found || len(e.Labels) != 0 {
+		t.Errorf("Enrich without metadata = %v, labels %v; want nothing set", found, e.Labels)
+	}
+}
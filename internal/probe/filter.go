@@ -0,0 +1,192 @@
+package probe
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+var eventsFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: constants.MetricEventsFiltered,
+	Help: "Total events dropped by module filters.",
+}, constants.LabelsModuleReason)
+
+// Filter drops a module's events by process name, destination port or
+// latency, and the agent's own events, before they are enriched and
+// published. Rules are swapped
+// atomically so the runtime can change them on config reload while the
+// module's read loop is running.
+//
+// Filtering happens in userspace, after the record has crossed the ring
+// buffer; it saves enrichment and export work, not kernel work.
+//
+// A nil *Filter keeps everything.
+type Filter struct {
+	module string
+	rules  atomic.Pointer[filterRules]
+	self   atomic.Pointer[pidSet]
+}
+
+// pidSet is one immutable set of excluded processes.
+type pidSet struct {
+	pids []uint32
+	set  map[uint32]bool
+}
+
+// filterRules is one immutable compiled FilterConfig.
+type filterRules struct {
+	excludeComm []*regexp.Regexp
+	allowPorts  map[uint16]bool
+	denyPorts   map[uint16]bool
+	minLatency  uint64 // ns
+
+	// commCache memoises excludeComm results per process name.
+	commMu    sync.Mutex
+	commCache map[string]bool
+}
+
+// NewFilter creates a Filter for module applying cfg.
+func NewFilter(module string, cfg config.FilterConfig) (*Filter, error) {
+	f := &Filter{module: module}
+	if err := f.Update(cfg); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Update replaces the filter rules. On error the old rules stay in place.
+func (f *Filter) Update(cfg config.FilterConfig) error {
+	r := &filterRules{
+		minLatency: uint64(max(cfg.MinLatency, 0)),
+		commCache:  make(map[string]bool),
+	}
+	for _, expr := range cfg.ExcludeComm {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("exclude_comm %q: %w", expr, err)
+		}
+		r.excludeComm = append(r.excludeComm, re)
+	}
+	r.allowPorts = portSet(cfg.AllowPorts)
+	r.denyPorts = portSet(cfg.DenyPorts)
+	f.rules.Store(r)
+	return nil
+}
+
+func portSet(ports []uint16) map[uint16]bool {
+	if len(ports) == 0 {
+		return nil
+	}
+	set := make(map[uint16]bool, len(ports))
+	for _, p := range ports {
+		set[p] = true
+	}
+	return set
+}
+
+// ExcludePIDs drops the events of processes pids, replacing the set from
+// any earlier call. The runtime passes the agent's own processes unless
+// agent.trace_self is set. Config updates leave the set alone.
+func (f *Filter) ExcludePIDs(pids []uint32) {
+	ps := &pidSet{pids: slices.Clone(pids), set: make(map[uint32]bool, len(pids))}
+	for _, pid := range pids {
+		ps.set[pid] = true
+	}
+	f.self.Store(ps)
+}
+
+// ExcludedPIDs returns the processes set by ExcludePIDs, for the runtime
+// to also exclude them in the module's BPF programs.
+func (f *Filter) ExcludedPIDs() []uint32 {
+	if f == nil {
+		return nil
+	}
+	if ps := f.self.Load(); ps != nil {
+		return ps.pids
+	}
+	return nil
+}
+
+// PID reports whether an event from process pid should be kept: it is not
+// one of the excluded processes.
+func (f *Filter) PID(pid uint32) bool {
+	if f == nil {
+		return true
+	}
+	ps := f.self.Load()
+	if ps == nil {
+		return true
+	}
+	return f.keep(!ps.set[pid], constants.FilterReasonSelf)
+}
+
+// Comm reports whether an event from process comm should be kept.
+func (f *Filter) Comm(comm string) bool {
+	if f == nil {
+		return true
+	}
+	r := f.rules.Load()
+	if len(r.excludeComm) == 0 {
+		return true
+	}
+	return f.keep(r.keepComm(comm), constants.FilterReasonComm)
+}
+
+// Port reports whether an event to destination port should be kept: not
+// denied, and allowed if an allow list is set. Server-side events pass the
+// local port they were accepted on.
+func (f *Filter) Port(port uint16) bool {
+	if f == nil {
+		return true
+	}
+	r := f.rules.Load()
+	keep := !r.denyPorts[port] && (r.allowPorts == nil || r.allowPorts[port])
+	return f.keep(keep, constants.FilterReasonPort)
+}
+
+// Latency reports whether an event taking ns nanoseconds should be kept.
+func (f *Filter) Latency(ns uint64) bool {
+	if f == nil {
+		return true
+	}
+	return f.keep(ns >= f.rules.Load().minLatency, constants.FilterReasonLatency)
+}
+
+func (f *Filter) keep(keep bool, reason string) bool {
+	if !keep {
+		eventsFiltered.WithLabelValues(f.module, reason).Inc()
+	}
+	return keep
+}
+
+func (r *filterRules) keepComm(comm string) bool {
+	r.commMu.Lock()
+	keep, ok := r.commCache[comm]
+	r.commMu.Unlock()
+	if ok {
+		return keep
+	}
+
+	keep = true
+	for _, re := range r.excludeComm {
+		if re.MatchString(comm) {
+			keep = false
+			break
+		}
+	}
+
+	r.commMu.Lock()
+	if len(r.commCache) < constants.FilterCommCacheSize {
+		r.commCache[comm] = keep
+	}
+	r.commMu.Unlock()
+	return keep
+}
@@ -0,0 +1,103 @@
+package probe
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+func TestFilter_NilKeepsEverything(t *testing.T) {
+	var f *Filter
+	if !f.Comm("sshd") || !f.Port(22) || !f.Latency(0) || !f.PID(1) {
+		t.Error("nil filter dropped an event")
+	}
+}
+
+func TestFilter_Rules(t *testing.T) {
+	f, err := NewFilter("test_rules", config.FilterConfig{
+		ExcludeComm: []string{"^kube-probe$", "^health"},
+		AllowPorts:  []uint16{80, 443, 22},
+		DenyPorts:   []uint16{22},
+		MinLatency:  time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := eventsFiltered.WithLabelValues("test_rules", constants.FilterReasonPort)
+	before := testutil.ToFloat64(filtered)
+
+	for comm, want := range map[string]bool{"kube-probe": false, "healthcheck": false, "curl": true, "kube-probe2": true} {
+		for range 2 { // second pass hits the cache
+			if got := f.Comm(comm); got != want {
+				t.Errorf("Comm(%q) = %v, want %v", comm, got, want)
+			}
+		}
+	}
+	for port, want := range map[uint16]bool{80: true, 443: true, 22: false, 8080: false} {
+		if got := f.Port(port); got != want {
+			t.Errorf("Port(%d) = %v, want %v", port, got, want)
+		}
+	}
+	if f.Latency(999_999) || !f.Latency(1_000_000) {
+		t.Error("Latency did not cut at 1ms")
+	}
+
+	if got := testutil.ToFloat64(filtered) - before; got != 2 {
+		t.Errorf("filtered port events delta = %v, want 2", got)
+	}
+}
+
+func TestFilter_UpdateKeepsRulesOnError(t *testing.T) {
+	f, err := NewFilter("test_update", config.FilterConfig{DenyPorts: []uint16{22}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Update(config.FilterConfig{ExcludeComm: []string{"("}}); err == nil {
+		t.Fatal("Update accepted an invalid regex")
+	}
+	if f.Port(22) {
+		t.Error("failed Update replaced the rules")
+	}
+	if err := f.Update(config.FilterConfig{}); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Port(22) {
+		t.Error("Update did not replace the rules")
+	}
+}
+
+func TestFilter_ExcludePIDs(t *testing.T) {
+	f, err := NewFilter("test_self", config.FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	filtered := eventsFiltered.WithLabelValues("test_self", constants.FilterReasonSelf)
+	before := testutil.ToFloat64(filtered)
+	if !f.PID(42) || f.ExcludedPIDs() != nil {
+		t.Fatal("new filter excludes processes")
+	}
+
+	f.ExcludePIDs([]uint32{42, 43})
+	for pid, want := range map[uint32]bool{42: false, 43: false, 1: true} {
+		if got := f.PID(pid); got != want {
+			t.Errorf("PID(%d) = %v, want %v", pid, got, want)
+		}
+	}
+	// Config updates keep the excluded processes.
+	if err := f.Update(config.FilterConfig{DenyPorts: []uint16{22}}); err != nil {
+		t.Fatal(err)
+	}
+	if f.PID(42) {
+		t.Error("Update dropped the excluded processes")
+	}
+	if got := f.ExcludedPIDs(); len(got) != 2 {
+		t.Errorf("ExcludedPIDs() = %v, want [42 43]", got)
+	}
+	if got := testutil.ToFloat64(filtered) - before; got != 3 {
+		t.Errorf("filtered self events delta = %v, want 3", got)
+	}
+}
@@ -9,21 +9,34 @@ package probe
 
 import (
 	"context"
+	"errors"
 
 	"go.uber.org/zap"
 
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/config"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
 	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
 )
 
+// ErrUnsupported is wrapped by Init errors for modules whose hooks the
+// running kernel lacks. The runtime skips such modules with a warning
+// rather than reporting a failure.
+var ErrUnsupported = errors.New("not supported by this kernel")
+
 // Module is the lifecycle interface for all eBPF modules.
 //
 // Lifecycle:
 //  1. Name() — returns unique module identifier
 //  2. Init(ctx, deps) — load BPF objects, attach hooks, create readers
-//  3. Start(ctx) — consume ring buffer events and publish to EventBus
-//  4. Stop(ctx) — release kernel resources within deadline
+//  3. Start(ctx) — consume ring buffer events and publish to EventBus;
+//     once ctx is cancelled, drain what is still queued, then return
+//  4. Stop(ctx) — detach hooks and release kernel resources within deadline
+//
+// The runtime restarts a module whose Start fails, calling Stop, Init and
+// Start again, so Stop must leave the module ready for another Init and
+// be safe to call more than once.
 type Module interface {
 	// Name returns a unique, human-readable identifier.
 	Name() string
@@ -32,14 +45,41 @@ type Module interface {
 	// Dependencies are injected here (DI pattern).
 	Init(ctx context.Context, deps Dependencies) error
 
-	// Start begins consuming events from the ring buffer.
-	// Blocks until ctx is cancelled. Publishes to EventBus.
+	// Start begins consuming events from the ring buffer. Publishes to
+	// EventBus. Blocks until ctx is cancelled and the events already in
+	// the ring buffer are published, or the module's drain timeout passes.
 	Start(ctx context.Context) error
 
 	// Stop releases all kernel resources within the context deadline.
 	Stop(ctx context.Context) error
 }
 
+// LossReporter is implemented by modules that can report how many events
+// their BPF program dropped because the ring buffer was full.
+type LossReporter interface {
+	DroppedCount() (uint64, error)
+}
+
+// SuppressionReporter is implemented by modules whose BPF programs
+// discard events in the kernel by design, e.g. below a latency threshold.
+// SuppressedCount is cumulative since Init.
+type SuppressionReporter interface {
+	SuppressedCount() (uint64, error)
+}
+
+// ResourceReporter is implemented by modules that expose their loaded BPF
+// programs and maps for resource metrics.
+type ResourceReporter interface {
+	BPFResources() *bpfutil.Resources
+}
+
+// AttachReporter is implemented by modules that choose between attach
+// strategies at Init, e.g. fentry or kprobe. AttachMode returns one of
+// constants.AttachMode*.
+type AttachReporter interface {
+	AttachMode() string
+}
+
 // Dependencies holds all shared resources injected into modules.
 // This implements the Dependency Injection (DI) pattern — modules
 // declare what they need, the runtime provides it.
@@ -49,6 +89,22 @@ type Dependencies struct {
 	EventBus *event.Bus
 	Metadata *metadata.Cache
 	NodeName string
+
+	// Sampler gates each ring buffer record. Its rate follows
+	// Config.SamplingRate and is updated live on config reload.
+	Sampler *Sampler
+
+	// Filter drops events matching Config.Filters; updated live on
+	// config reload.
+	Filter *Filter
+
+	// Kernel reports the running kernel's tracepoints and fields, for
+	// modules with a fallback attach strategy.
+	Kernel *kernelfeat.Features
+
+	// Pins is where modules pin their maps and links to outlive the
+	// agent (agent.pin_bpf); nil when pinning is off.
+	Pins *bpfutil.PinRoot
 }
 
 // NewDependencies creates a Dependencies struct with all required fields.
@@ -59,6 +115,10 @@ func NewDependencies(
 	bus *event.Bus,
 	meta *metadata.Cache,
 	nodeName string,
+	sampler *Sampler,
+	filter *Filter,
+	kernel *kernelfeat.Features,
+	pins *bpfutil.PinRoot,
 ) Dependencies {
 	return Dependencies{
 		Logger:   logger,
@@ -66,5 +126,9 @@ func NewDependencies(
 		EventBus: bus,
 		Metadata: meta,
 		NodeName: nodeName,
+		Sampler:  sampler,
+		Filter:   filter,
+		Kernel:   kernel,
+		Pins:     pins,
 	}
 }
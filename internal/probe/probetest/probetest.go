@@ -0,0 +1,168 @@
+// Package probetest runs modules' Start loops in unit tests: a Reader
+// stands in for the ring buffer, fed records built with Sample, and the
+// events the module publishes are read off the bus with Next.
+package probetest
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// timeout bounds how long Next waits for an event, and the Cleanup Start
+// registers for the loop to return.
+const timeout = 5 * time.Second
+
+// Reader is a channel-backed probe.RecordReader that behaves like
+// *ringbuf.Reader: Read blocks until a record is sent, Flush makes a
+// blocked Read return ringbuf.ErrFlushed once sent records are read, a
+// Read past the deadline returns os.ErrDeadlineExceeded, and after Close
+// Read returns ringbuf.ErrClosed.
+type Reader struct {
+	records   chan []byte
+	flushed   chan struct{}
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+// NewReader returns an empty Reader.
+func NewReader() *Reader {
+	return &Reader{
+		records: make(chan []byte, 64),
+		flushed: make(chan struct{}, 1),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Send queues sample for Read, blocking while 64 records are queued.
+func (r *Reader) Send(sample []byte) {
+	r.records <- sample
+}
+
+// Read returns the next record sent.
+func (r *Reader) Read() (ringbuf.Record, error) {
+	select {
+	case sample := <-r.records:
+		return ringbuf.Record{RawSample: sample}, nil
+	default:
+	}
+
+	var expired <-chan time.Time
+	r.mu.Lock()
+	deadline := r.deadline
+	r.mu.Unlock()
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case sample := <-r.records:
+		return ringbuf.Record{RawSample: sample}, nil
+	case <-r.flushed:
+		return ringbuf.Record{}, ringbuf.ErrFlushed
+	case <-r.closed:
+		return ringbuf.Record{}, ringbuf.ErrClosed
+	case <-expired:
+		return ringbuf.Record{}, os.ErrDeadlineExceeded
+	}
+}
+
+// SetDeadline makes Reads that find no record return at t; the zero time
+// blocks forever.
+func (r *Reader) SetDeadline(t time.Time) {
+	r.mu.Lock()
+	r.deadline = t
+	r.mu.Unlock()
+}
+
+// Flush interrupts a blocked Read.
+func (r *Reader) Flush() error {
+	select {
+	case r.flushed <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// Close makes every later Read return ringbuf.ErrClosed.
+func (r *Reader) Close() error {
+	r.closeOnce.Do(func() { close(r.closed) })
+	return nil
+}
+
+// Sample encodes raw, a module's rawEvent, as the BPF program writes it
+// to the ring buffer.
+func Sample(t testing.TB, raw any) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, raw); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// Start runs a module's Start loop until the test ends, then cancels it
+// and fails the test unless it returns nil.
+func Start(t testing.TB, start func(context.Context) error) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- start(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-errc:
+			if err != nil {
+				t.Errorf("Start: %v", err)
+			}
+		case <-time.After(timeout):
+			t.Error("Start did not return once cancelled")
+		}
+	})
+}
+
+// Next returns the next event on events, failing the test if none
+// arrives or it doesn't match its schema (see CheckSchema). The caller
+// calls Done on it.
+func Next(t testing.TB, events <-chan *event.Event) *event.Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		CheckSchema(t, e)
+		return e
+	case <-time.After(timeout):
+		t.Fatal("no event published")
+		return nil
+	}
+}
+
+// CheckSchema fails the test unless e carries every required key of its
+// type's event.Schema and no key the schema doesn't list. Module tests
+// pass what they publish through it, keeping the schema served to
+// consumers in step with the modules.
+func CheckSchema(t testing.TB, e *event.Event) {
+	t.Helper()
+	s, ok := event.SchemaOf(e.Type.String())
+	if !ok {
+		t.Errorf("event type %v has no schema", e.Type)
+		return
+	}
+	if missing := s.Missing(e.Labels, e.Numeric); missing != nil {
+		t.Errorf("%s event lacks required keys %v", s.Type, missing)
+	}
+	if unknown := s.Unknown(e.Labels, e.Numeric); unknown != nil {
+		t.Errorf("%s event carries keys %v not in its schema", s.Type, unknown)
+	}
+}
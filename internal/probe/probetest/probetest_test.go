@@ -0,0 +1,53 @@
+package probetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+var _ probe.RecordReader = (*Reader)(nil)
+
+func TestReader_ReadLoop(t *testing.T) {
+	r := NewReader()
+	got := make(chan []byte, 3)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- bpfutil.ReadLoop(ctx, r, time.Second, zap.NewNop(), func(rec ringbuf.Record) { got <- rec.RawSample })
+	}()
+
+	r.Send([]byte{1})
+	if s := <-got; s[0] != 1 {
+		t.Fatalf("read %v, want [1]", s)
+	}
+
+	// Records sent before the loop is cancelled are drained.
+	r.Send([]byte{2})
+	r.Send([]byte{3})
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Errorf("%d records drained, want 2", len(got))
+	}
+}
+
+func TestReader_Closed(t *testing.T) {
+	r := NewReader()
+	r.Close()
+	if _, err := r.Read(); !errors.Is(err, ringbuf.ErrClosed) {
+		t.Errorf("Read after Close = %v, want ErrClosed", err)
+	}
+	if err := bpfutil.ReadLoop(context.Background(), r, time.Second, zap.NewNop(), func(ringbuf.Record) {}); err != nil {
+		t.Errorf("ReadLoop on a closed reader = %v, want nil", err)
+	}
+}
@@ -0,0 +1,11 @@
+package probe
+
+import "github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+
+// RecordReader is the ring buffer a module consumes in Start and closes in
+// Stop: the *ringbuf.Reader Init creates, or a probetest.Reader that tests
+// feed crafted records through without loading any BPF.
+type RecordReader interface {
+	bpfutil.RecordReader
+	Close() error
+}
@@ -0,0 +1,80 @@
+package probe
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Sampler decides which ring buffer records a module keeps. The rate is
+// stored atomically so the runtime can change it on config reload while
+// the module's read loop is running — no restart, no BPF re-attach.
+//
+// The effective probability is the configured rate times a scale in
+// (0, 1] that adaptive sampling lowers while the event bus is dropping.
+//
+// A nil *Sampler keeps everything.
+type Sampler struct {
+	bits  atomic.Uint64 // math.Float64bits(rate)
+	scale atomic.Uint64 // math.Float64bits(scale)
+}
+
+// NewSampler creates a Sampler keeping the given fraction of records.
+func NewSampler(rate float64) *Sampler {
+	s := &Sampler{}
+	s.SetRate(rate)
+	s.SetScale(1)
+	return s
+}
+
+// SetRate changes the fraction of records kept, clamped to [0, 1].
+// A rate of 0 pauses the module without detaching it.
+func (s *Sampler) SetRate(rate float64) {
+	s.bits.Store(math.Float64bits(min(max(rate, 0), 1)))
+}
+
+// Rate returns the configured sampling rate.
+func (s *Sampler) Rate() float64 {
+	if s == nil {
+		return 1
+	}
+	return math.Float64frombits(s.bits.Load())
+}
+
+// SetScale sets the adaptive multiplier on the rate, clamped to [0, 1].
+func (s *Sampler) SetScale(scale float64) {
+	s.scale.Store(math.Float64bits(min(max(scale, 0), 1)))
+}
+
+// Scale returns the adaptive multiplier on the rate.
+func (s *Sampler) Scale() float64 {
+	if s == nil {
+		return 1
+	}
+	return math.Float64frombits(s.scale.Load())
+}
+
+// Effective returns the probability Keep currently applies.
+func (s *Sampler) Effective() float64 {
+	return s.Rate() * s.Scale()
+}
+
+// Paused reports whether the rate is 0, as it is while the module is
+// disabled. Modules whose events are never sampled (oom, drop, signals)
+// check it instead of Keep.
+func (s *Sampler) Paused() bool {
+	return s.Rate() <= 0
+}
+
+// Keep reports whether the next record should be processed.
+func (s *Sampler) Keep() bool {
+	r := s.Effective()
+	switch {
+	case r >= 1:
+		return true
+	case r <= 0:
+		return false
+	default:
+		return rand.Float64() < r
+	}
+}
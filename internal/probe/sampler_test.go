@@ -0,0 +1,79 @@
+package probe
+
+import "testing"
+
+func TestSampler_Bounds(t *testing.T) {
+	s := NewSampler(1)
+	for range 100 {
+		if !s.Keep() {
+			t.Fatal("rate 1 dropped a record")
+		}
+	}
+	s.SetRate(0)
+	for range 100 {
+		if s.Keep() {
+			t.Fatal("rate 0 kept a record")
+		}
+	}
+}
+
+func TestSampler_Clamps(t *testing.T) {
+	if got := NewSampler(3).Rate(); got != 1 {
+		t.Errorf("NewSampler(3).Rate() = %v, want 1", got)
+	}
+	if got := NewSampler(-1).Rate(); got != 0 {
+		t.Errorf("NewSampler(-1).Rate() = %v, want 0", got)
+	}
+}
+
+func TestSampler_Fraction(t *testing.T) {
+	s := NewSampler(0.25)
+	kept := 0
+	const n = 100_000
+	for range n {
+		if s.Keep() {
+			kept++
+		}
+	}
+	if frac := float64(kept) / n; frac < 0.23 || frac > 0.27 {
+		t.Errorf("kept %.3f of records at rate 0.25", frac)
+	}
+}
+
+func TestSampler_NilKeepsAll(t *testing.T) {
+	var s *Sampler
+	if !s.Keep() {
+		t.Error("nil sampler should keep every record")
+	}
+}
+
+func TestSampler_Paused(t *testing.T) {
+	s := NewSampler(0.01)
+	s.SetScale(0)
+	if s.Paused() {
+		t.Error("a low rate and scale paused the sampler")
+	}
+	s.SetRate(0)
+	if !s.Paused() {
+		t.Error("rate 0 did not pause the sampler")
+	}
+	var nilSampler *Sampler
+	if nilSampler.Paused() {
+		t.Error("nil sampler is paused")
+	}
+}
+
+func TestSampler_Scale(t *testing.T) {
+	s := NewSampler(0.5)
+	if got := s.Effective(); got != 0.5 {
+		t.Errorf("Effective() = %v, want 0.5", got)
+	}
+	s.SetScale(0.5)
+	if s.Rate() != 0.5 || s.Effective() != 0.25 {
+		t.Errorf("Rate() = %v, Effective() = %v; want 0.5 and 0.25", s.Rate(), s.Effective())
+	}
+	var nilSampler *Sampler
+	if nilSampler.Effective() != 1 {
+		t.Error("nil sampler should keep everything")
+	}
+}
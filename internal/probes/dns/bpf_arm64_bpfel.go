@@ -0,0 +1,165 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package dns
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpfDnsEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Uid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Pad0      uint32
+	LatencyNs uint64
+	Timestamp uint64
+	Qname     [128]int8
+	QnameLen  uint16
+	Comm      [16]int8
+	Pad       [6]uint8
+	Qtype     uint16
+	Transport uint8
+	Pad2      [5]uint8
+}
+
+// loadBpf returns the embedded CollectionSpec for bpf.
+func loadBpf() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BpfBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpfObjects loads bpf and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpfObjects
+//	*bpfPrograms
+//	*bpfMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpfObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpfSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfSpecs struct {
+	bpfProgramSpecs
+	bpfMapSpecs
+	bpfVariableSpecs
+}
+
+// bpfProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfProgramSpecs struct {
+	KprobeTcpSendmsg *ebpf.ProgramSpec `ebpf:"kprobe_tcp_sendmsg"`
+	KprobeUdpSendmsg *ebpf.ProgramSpec `ebpf:"kprobe_udp_sendmsg"`
+}
+
+// bpfMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfMapSpecs struct {
+	DnsEvents    *ebpf.MapSpec `ebpf:"dns_events"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+}
+
+// bpfVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfVariableSpecs struct {
+	UnusedDnsEvent *ebpf.VariableSpec `ebpf:"unused_dns_event"`
+}
+
+// bpfObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfObjects struct {
+	bpfPrograms
+	bpfMaps
+	bpfVariables
+}
+
+func (o *bpfObjects) Close() error {
+	return _BpfClose(
+		&o.bpfPrograms,
+		&o.bpfMaps,
+	)
+}
+
+// bpfMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfMaps struct {
+	DnsEvents    *ebpf.Map `ebpf:"dns_events"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+}
+
+func (m *bpfMaps) Close() error {
+	return _BpfClose(
+		m.DnsEvents,
+		m.ExcludedPids,
+		m.RingbufLost,
+	)
+}
+
+// bpfVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfVariables struct {
+	UnusedDnsEvent *ebpf.Variable `ebpf:"unused_dns_event"`
+}
+
+// bpfPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfPrograms struct {
+	KprobeTcpSendmsg *ebpf.Program `ebpf:"kprobe_tcp_sendmsg"`
+	KprobeUdpSendmsg *ebpf.Program `ebpf:"kprobe_udp_sendmsg"`
+}
+
+func (p *bpfPrograms) Close() error {
+	return _BpfClose(
+		p.KprobeTcpSendmsg,
+		p.KprobeUdpSendmsg,
+	)
+}
+
+func _BpfClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_arm64_bpfel.o
+var _BpfBytes []byte
@@ -8,10 +8,31 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"structs"
 
 	"github.com/cilium/ebpf"
 )
 
+type bpfDnsEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Uid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Pad0      uint32
+	LatencyNs uint64
+	Timestamp uint64
+	Qname     [128]int8
+	QnameLen  uint16
+	Comm      [16]int8
+	Pad       [6]uint8
+	Qtype     uint16
+	Transport uint8
+	Pad2      [5]uint8
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -54,6 +75,7 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
+	KprobeTcpSendmsg *ebpf.ProgramSpec `ebpf:"kprobe_tcp_sendmsg"`
 	KprobeUdpSendmsg *ebpf.ProgramSpec `ebpf:"kprobe_udp_sendmsg"`
 }
 
@@ -61,13 +83,16 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	DnsEvents *ebpf.MapSpec `ebpf:"dns_events"`
+	DnsEvents    *ebpf.MapSpec `ebpf:"dns_events"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	UnusedDnsEvent *ebpf.VariableSpec `ebpf:"unused_dns_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -90,12 +115,16 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	DnsEvents *ebpf.Map `ebpf:"dns_events"`
+	DnsEvents    *ebpf.Map `ebpf:"dns_events"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
 		m.DnsEvents,
+		m.ExcludedPids,
+		m.RingbufLost,
 	)
 }
 
@@ -103,17 +132,20 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	UnusedDnsEvent *ebpf.Variable `ebpf:"unused_dns_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
+	KprobeTcpSendmsg *ebpf.Program `ebpf:"kprobe_tcp_sendmsg"`
 	KprobeUdpSendmsg *ebpf.Program `ebpf:"kprobe_udp_sendmsg"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.KprobeTcpSendmsg,
 		p.KprobeUdpSendmsg,
 	)
 }
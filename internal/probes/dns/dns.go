@@ -2,13 +2,9 @@
 package dns
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"strings"
-	"time"
+	"unsafe"
 
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
@@ -16,19 +12,67 @@ import (
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/dnsname"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct dns_event in bpf/dns_tracer.c.
 type rawEvent struct {
 	PID       uint32
 	UID       uint32
+	SAddr     uint32
 	DAddr     uint32
+	SPort     uint16
 	DPort     uint16
-	Pad1      uint16
-	QName     [constants.QNameSize]byte
+	Pad1      uint32
+	LatencyNs uint64
 	Timestamp uint64
+	QName     [constants.QNameSize]byte
+	QNameLen  uint16
 	Comm      [constants.CommSize]byte
+	Pad2      [6]byte
+	QType     uint16 // 0 if the tracer could not read it
+	Transport uint8  // transportUDP or transportTCP
+	Pad3      [5]byte
+}
+
+// Values of rawEvent.Transport.
+const (
+	transportUDP = 0
+	transportTCP = 1
+)
+
+// decoder decodes struct dns_event. Objects generated before the
+// tracer read the question type emit only the fields before QType, which
+// decode as UDP queries of unknown type.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct dns_event), which bpf/dns_tracer.c asserts.
+// The assignment below fails the build if rawEvent is any other size.
+const rawEventSize = 200
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// transport names the protocol the query was sent over.
+func (r rawEvent) transport() string {
+	if r.Transport == transportTCP {
+		return constants.TransportTCP
+	}
+	return constants.TransportUDP
+}
+
+// minRawEventSize is the record size of objects generated before the
+// tracer read the question type: the fields before QType. The assignment
+// below fails the build if QType moves.
+const minRawEventSize = 192
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.QType)]byte{}
+
+// decode parses one ring buffer record, zero-padding records from older
+// objects and rejecting any shorter than those.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
 }
 
 // Module implements probe.Module for DNS query monitoring.
@@ -37,13 +81,15 @@ type Module struct {
 	logger *zap.Logger
 
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	reader probe.RecordReader
+	comms  *bpfutil.Interner
 }
 
 // New creates a new DNS module instance (Factory constructor).
 func New() *Module {
-	return &Module{}
+	return &Module{comms: bpfutil.NewInterner()}
 }
 
 func (m *Module) Name() string { return constants.ModuleDNS }
@@ -52,71 +98,82 @@ func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
 
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
 
-	kp, err := link.Kprobe("udp_sendmsg", m.objs.KprobeUdpSendmsg, nil)
+	kp, err := m.bpf.Kprobe(constants.KprobeUDPSendmsg, m.objs.KprobeUdpSendmsg)()
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching udp_sendmsg kprobe: %w", err)
+		return err
 	}
 	m.links = append(m.links, kp)
 
-	m.reader, err = ringbuf.NewReader(m.objs.DnsEvents)
+	if prog := m.bpf.Programs[constants.BPFProgKprobeTCPSendmsg]; prog != nil {
+		kp, err := m.bpf.Kprobe(constants.KprobeTCPSendmsg, prog)()
+		if err != nil {
+			m.Stop(context.Background())
+			return err
+		}
+		m.links = append(m.links, kp)
+	} else {
+		m.logger.Warn("BPF object has no tcp_sendmsg program — DNS over TCP will not be traced")
+	}
+
+	reader, err := ringbuf.NewReader(m.objs.DnsEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("DNS module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
 
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading DNS event", zap.Error(err))
-			continue
-		}
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if !m.deps.Sampler.Keep() {
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing DNS event", zap.Error(err))
+		return
+	}
 
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing DNS event", zap.Error(err))
-			continue
-		}
+	comm := m.comms.CString(raw.Comm[:])
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.Comm(comm) {
+		return
+	}
 
-		e := event.Acquire()
-		e.Type = event.TypeDNS
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
-		}
+	e := event.Acquire()
+	e.Type = event.TypeDNS
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.UID = raw.UID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
 
-		qname := bpfutil.QNameString(raw.QName)
-		e.SetLabel(constants.KeyQName, qname)
-		e.SetLabel(constants.KeyDomain, TruncateDomain(qname))
+	m.deps.Enrich(e, raw.PID)
 
-		m.deps.EventBus.Publish(e)
-	}
+	qname := bpfutil.QNameString(raw.QName)
+	e.SetLabel(constants.KeyQName, qname)
+	e.SetLabel(constants.KeyDomain, dnsname.Default.Domain(qname))
+	e.SetLabel(constants.KeyQType, QTypeString(raw.QType))
+	e.SetLabel(constants.KeyTransport, raw.transport())
+
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -127,15 +184,34 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
 
-// TruncateDomain reduces a FQDN to its top-level registered domain
-// for low-cardinality Prometheus labels.
-func TruncateDomain(domain string) string {
-	parts := strings.Split(domain, ".")
-	if len(parts) <= 2 {
-		return domain
+// QTypeString names a DNS question type for the qtype label: A, AAAA,
+// SRV, PTR, or QTypeOther for the rest and for unknown (0).
+func QTypeString(qtype uint16) string {
+	switch qtype {
+	case constants.DNSTypeA:
+		return "A"
+	case constants.DNSTypeAAAA:
+		return "AAAA"
+	case constants.DNSTypeSRV:
+		return "SRV"
+	case constants.DNSTypePTR:
+		return "PTR"
 	}
-	return strings.Join(parts[len(parts)-2:], ".")
+	return constants.QTypeOther
+}
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
 }
@@ -1,9 +1,23 @@
 package dns
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/dnsname"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
 )
 
 func TestNew(t *testing.T) {
@@ -16,19 +30,344 @@ func TestNew(t *testing.T) {
 	}
 }
 
-func TestTruncateDomain(t *testing.T) {
+// TestRawEventSize pins the decode struct to sizeof(struct dns_event), which
+// bpf/dns_tracer.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/dns_tracer.c", "dns_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfDnsEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfDnsEvent{}); got != want {
+		t.Errorf("embedded object's dns_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestRawEventDecode(t *testing.T) {
+	// struct dns_event: qname at offset 40, comm at 170.
+	sample := make([]byte, 192)
+	binary.LittleEndian.PutUint32(sample[0:], 4242)
+	binary.LittleEndian.PutUint16(sample[18:], 53)
+	copy(sample[40:], "example.com")
+	copy(sample[170:], "curl")
+
+	raw := mustDecode(t, sample) // objects without qtype emit 192 bytes
+	if raw.PID != 4242 || raw.DPort != 53 {
+		t.Errorf("PID, DPort = %d, %d, want 4242, 53", raw.PID, raw.DPort)
+	}
+	if got := bpfutil.QNameString(raw.QName); got != "example.com" {
+		t.Errorf("QName = %q, want %q", got, "example.com")
+	}
+	if got := bpfutil.CommString(raw.Comm); got != "curl" {
+		t.Errorf("Comm = %q, want %q", got, "curl")
+	}
+}
+
+// TestDecode_CLayout decodes a record laid out by hand at the offsets of
+// struct dns_event, independent of rawEvent.
+func TestDecode_CLayout(t *testing.T) {
+	rec := make([]byte, 200)
+	le := binary.LittleEndian
+	le.PutUint32(rec[0:], 4242)        // pid
+	le.PutUint32(rec[4:], 1000)        // uid
+	le.PutUint32(rec[8:], 0x0100000a)  // saddr 10.0.0.1
+	le.PutUint32(rec[12:], 0x0a00600a) // daddr 10.96.0.10
+	le.PutUint16(rec[16:], 40000)      // sport
+	le.PutUint16(rec[18:], 53)         // dport
+	le.PutUint64(rec[24:], 1500000)    // latency_ns
+	le.PutUint64(rec[32:], 123456789)  // timestamp
+	copy(rec[40:], "api.svc")          // qname
+	le.PutUint16(rec[168:], 7)         // qname_len
+	copy(rec[170:], "coredns")         // comm
+	le.PutUint16(rec[192:], 28)        // qtype AAAA
+	rec[194] = transportTCP            // transport
+
+	raw := mustDecode(t, rec)
+	if raw.PID != 4242 || raw.UID != 1000 || raw.SAddr != 0x0100000a || raw.DAddr != 0x0a00600a ||
+		raw.SPort != 40000 || raw.DPort != 53 || raw.LatencyNs != 1500000 || raw.Timestamp != 123456789 ||
+		raw.QNameLen != 7 || bpfutil.QNameString(raw.QName) != "api.svc" ||
+		bpfutil.CommString(raw.Comm) != "coredns" || raw.QType != 28 || raw.transport() != constants.TransportTCP {
+		t.Errorf("decode = %+v", raw)
+	}
+}
+
+func TestQTypeString(t *testing.T) {
 	tests := []struct {
-		domain string
-		want   string
+		qtype uint16
+		want  string
 	}{
-		{"api.svc.cluster.local", "cluster.local"},
-		{"example.com", "example.com"},
-		{"a.b.c.example.com", "example.com"},
+		{1, "A"},
+		{28, "AAAA"},
+		{33, "SRV"},
+		{12, "PTR"},
+		{5, "other"},  // CNAME
+		{65, "other"}, // HTTPS
+		{0, "other"},  // not read
 	}
 	for _, tt := range tests {
-		got := TruncateDomain(tt.domain)
-		if got != tt.want {
-			t.Errorf("TruncateDomain(%q) = %q, want %q", tt.domain, got, tt.want)
+		if got := QTypeString(tt.qtype); got != tt.want {
+			t.Errorf("QTypeString(%d) = %q, want %q", tt.qtype, got, tt.want)
+		}
+	}
+}
+
+// question builds the event the tracer emits for a query whose question
+// section is payload, as captured on the wire: the name as dotted labels
+// and the big-endian QTYPE after its terminating zero label.
+func question(t *testing.T, payload []byte) []byte {
+	t.Helper()
+	var labels []string
+	pos := 0
+	for payload[pos] != 0 {
+		n := int(payload[pos])
+		labels = append(labels, string(payload[pos+1:pos+1+n]))
+		pos += 1 + n
+	}
+	qtype := binary.BigEndian.Uint16(payload[pos+1:])
+
+	sample := make([]byte, decoder.Size())
+	copy(sample[40:], strings.Join(labels, "."))
+	binary.LittleEndian.PutUint16(sample[192:], qtype)
+	return sample
+}
+
+func TestRawEventDecode_Question(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte // question section after the 12-byte header
+		qname   string
+		qtype   string
+		domain  string
+	}{
+		{
+			name: "AAAA",
+			payload: []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+				0x00, 0x1c, 0x00, 0x01},
+			qname: "example.com", qtype: "AAAA", domain: "example.com",
+		},
+		{
+			name: "SRV",
+			payload: []byte{5, '_', 'g', 'r', 'p', 'c', 4, '_', 't', 'c', 'p', 3, 'a', 'p', 'i',
+				7, 'd', 'e', 'f', 'a', 'u', 'l', 't', 3, 's', 'v', 'c', 0,
+				0x00, 0x21, 0x00, 0x01},
+			qname: "_grpc._tcp.api.default.svc", qtype: "SRV", domain: "default.svc",
+		},
+		{
+			name: "PTR",
+			payload: []byte{1, '5', 1, '0', 3, '2', '4', '4', 2, '1', '0',
+				7, 'i', 'n', '-', 'a', 'd', 'd', 'r', 4, 'a', 'r', 'p', 'a', 0,
+				0x00, 0x0c, 0x00, 0x01},
+			qname: "5.0.244.10.in-addr.arpa", qtype: "PTR", domain: "in-addr.arpa",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := mustDecode(t, question(t, tt.payload))
+			qname := bpfutil.QNameString(raw.QName)
+			if qname != tt.qname {
+				t.Errorf("qname = %q, want %q", qname, tt.qname)
+			}
+			if got := QTypeString(raw.QType); got != tt.qtype {
+				t.Errorf("qtype = %q, want %q", got, tt.qtype)
+			}
+			if got := dnsname.Default.Domain(qname); got != tt.domain {
+				t.Errorf("domain = %q, want %q", got, tt.domain)
+			}
+		})
+	}
+}
+
+// tcpQuestion builds the event the tracer emits for a DNS-over-TCP
+// message: it checks the 2-byte length prefix, then skips it and the
+// header as the tracer does.
+func tcpQuestion(t *testing.T, framed []byte) []byte {
+	t.Helper()
+	if n := int(binary.BigEndian.Uint16(framed)); n != len(framed)-2 {
+		t.Fatalf("length prefix %d, message is %d bytes", n, len(framed)-2)
+	}
+	sample := question(t, framed[2+12:])
+	sample[194] = transportTCP
+	return sample
+}
+
+func TestRawEventDecode_TCP(t *testing.T) {
+	// A query for api.example.com IN A as sent to a resolver over TCP.
+	framed := []byte{
+		0x00, 0x21, // length: 33
+		0xab, 0xcd, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // header: id, RD, 1 question
+		3, 'a', 'p', 'i', 7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 3, 'c', 'o', 'm', 0,
+		0x00, 0x01, 0x00, 0x01, // A, IN
+	}
+	raw := mustDecode(t, tcpQuestion(t, framed))
+	if got := bpfutil.QNameString(raw.QName); got != "api.example.com" {
+		t.Errorf("qname = %q, want api.example.com", got)
+	}
+	if got := QTypeString(raw.QType); got != "A" {
+		t.Errorf("qtype = %q, want A", got)
+	}
+	if got := raw.transport(); got != constants.TransportTCP {
+		t.Errorf("transport = %q, want tcp", got)
+	}
+
+	// Events from objects that only traced udp_sendmsg end before Transport.
+	raw = mustDecode(t, make([]byte, 192))
+	if got := raw.transport(); got != constants.TransportUDP {
+		t.Errorf("transport of a short record = %q, want udp", got)
+	}
+}
+
+// FuzzHandle feeds handle truncated, oversized and arbitrary records; it
+// must publish or skip them, never panic.
+func FuzzHandle(f *testing.F) {
+	f.Add(make([]byte, decoder.Size()))
+	f.Add(make([]byte, decoder.Size()+64))
+	f.Add([]byte{1, 2, 3})
+	f.Add([]byte{})
+
+	filter, err := probe.NewFilter(constants.ModuleDNS, config.FilterConfig{})
+	if err != nil {
+		f.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	m := &Module{logger: zap.NewNop(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m.handle(ringbuf.Record{RawSample: data})
+	})
+}
+
+func mustDecode(t *testing.T, sample []byte) rawEvent {
+	t.Helper()
+	raw, err := decode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
+
+// replayReader is a ring buffer that holds sample n times, then closes.
+type replayReader struct {
+	sample []byte
+	n      int
+}
+
+func (r *replayReader) Read() (ringbuf.Record, error) {
+	if r.n == 0 {
+		return ringbuf.Record{}, ringbuf.ErrClosed
+	}
+	r.n--
+	return ringbuf.Record{RawSample: r.sample}, nil
+}
+
+func (*replayReader) SetDeadline(time.Time) {}
+
+func (*replayReader) Flush() error { return nil }
+
+// BenchmarkPipeline measures one event from the ring buffer to a bus
+// subscriber: decoding, filtering, enrichment and publishing.
+func BenchmarkPipeline(b *testing.B) {
+	var buf bytes.Buffer
+	in := rawEvent{PID: 9, DAddr: 0x0a00000a, DPort: 53, LatencyNs: 2e6, QType: constants.DNSTypeA}
+	copy(in.QName[:], "api.example.com")
+	copy(in.Comm[:], "curl")
+	if err := binary.Write(&buf, binary.LittleEndian, in); err != nil {
+		b.Fatal(err)
+	}
+
+	filter, err := probe.NewFilter(constants.ModuleDNS, config.FilterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	bus := event.NewBus(constants.DefaultEventBusBuffer, nil)
+	events := bus.Subscribe("bench")
+	done := make(chan struct{})
+	go func() {
+		for e := range events {
+			e.Done()
+		}
+		close(done)
+	}()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	bpfutil.ReadLoop(context.Background(), &replayReader{buf.Bytes(), b.N}, 0, zap.NewNop(), m.handle)
+	b.StopTimer()
+	bus.Close()
+	<-done
+}
+
+func TestStart(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleDNS, config.FilterConfig{ExcludeComm: []string{"^coredns$"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const agentPID = 4242
+	filter.ExcludePIDs([]uint32{agentPID})
+	bus := event.NewBus(16, nil)
+	events := bus.Subscribe("test")
+	reader := probetest.NewReader()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)
+	m.reader = reader
+	probetest.Start(t, m.Start)
+
+	query := rawEvent{PID: 9, QType: constants.DNSTypeAAAA, Transport: transportTCP}
+	copy(query.QName[:], "API.Example.com.")
+	copy(query.Comm[:], "curl")
+	coredns := query
+	copy(coredns.Comm[:], "coredns")
+	self := query
+	self.PID = agentPID
+	reader.Send(probetest.Sample(t, coredns))
+	reader.Send(probetest.Sample(t, self))
+	reader.Send([]byte{1, 2, 3}) // too short to decode: skipped
+	reader.Send(probetest.Sample(t, query))
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if e.Type != event.TypeDNS || e.Comm != "curl" || e.PID != 9 || e.Node != "node-a" {
+		t.Errorf("event = %+v, want curl's query on node-a", e)
+	}
+	for key, want := range map[string]string{
+		constants.KeyQName:     "API.Example.com.",
+		constants.KeyDomain:    "example.com",
+		constants.KeyQType:     "AAAA",
+		constants.KeyTransport: constants.TransportTCP,
+	} {
+		if got := e.Label(key); got != want {
+			t.Errorf("label %s = %q, want %q", key, got, want)
 		}
 	}
 }
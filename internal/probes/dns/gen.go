@@ -1,3 +1,3 @@
 package dns
 
-//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64 bpf ../../../bpf/dns_tracer.c -- -I../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 -type dns_event bpf ../../../bpf/dns_tracer.c -- -I../../../bpf
@@ -0,0 +1,164 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package drop
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpfDropEvent struct {
+	_          structs.HostLayout
+	Pid        uint32
+	DropReason uint32
+	Protocol   uint16
+	Pad        uint16
+	Pad2       uint32
+	Location   uint64
+	Timestamp  uint64
+	Comm       [16]int8
+	Family     uint8
+	L4Proto    uint8
+	Sport      uint16
+	Dport      uint16
+	Pad3       uint16
+	Saddr      [16]uint8
+	Daddr      [16]uint8
+}
+
+// loadBpf returns the embedded CollectionSpec for bpf.
+func loadBpf() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BpfBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpfObjects loads bpf and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpfObjects
+//	*bpfPrograms
+//	*bpfMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpfObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpfSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfSpecs struct {
+	bpfProgramSpecs
+	bpfMapSpecs
+	bpfVariableSpecs
+}
+
+// bpfProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfProgramSpecs struct {
+	KprobeKfreeSkb     *ebpf.ProgramSpec `ebpf:"kprobe_kfree_skb"`
+	TracepointKfreeSkb *ebpf.ProgramSpec `ebpf:"tracepoint_kfree_skb"`
+}
+
+// bpfMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfMapSpecs struct {
+	DropEvents   *ebpf.MapSpec `ebpf:"drop_events"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+}
+
+// bpfVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfVariableSpecs struct {
+	UnusedDropEvent *ebpf.VariableSpec `ebpf:"unused_drop_event"`
+}
+
+// bpfObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfObjects struct {
+	bpfPrograms
+	bpfMaps
+	bpfVariables
+}
+
+func (o *bpfObjects) Close() error {
+	return _BpfClose(
+		&o.bpfPrograms,
+		&o.bpfMaps,
+	)
+}
+
+// bpfMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfMaps struct {
+	DropEvents   *ebpf.Map `ebpf:"drop_events"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+}
+
+func (m *bpfMaps) Close() error {
+	return _BpfClose(
+		m.DropEvents,
+		m.ExcludedPids,
+		m.RingbufLost,
+	)
+}
+
+// bpfVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfVariables struct {
+	UnusedDropEvent *ebpf.Variable `ebpf:"unused_drop_event"`
+}
+
+// bpfPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfPrograms struct {
+	KprobeKfreeSkb     *ebpf.Program `ebpf:"kprobe_kfree_skb"`
+	TracepointKfreeSkb *ebpf.Program `ebpf:"tracepoint_kfree_skb"`
+}
+
+func (p *bpfPrograms) Close() error {
+	return _BpfClose(
+		p.KprobeKfreeSkb,
+		p.TracepointKfreeSkb,
+	)
+}
+
+func _BpfClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_arm64_bpfel.o
+var _BpfBytes []byte
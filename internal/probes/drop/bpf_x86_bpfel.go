@@ -8,10 +8,30 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"structs"
 
 	"github.com/cilium/ebpf"
 )
 
+type bpfDropEvent struct {
+	_          structs.HostLayout
+	Pid        uint32
+	DropReason uint32
+	Protocol   uint16
+	Pad        uint16
+	Pad2       uint32
+	Location   uint64
+	Timestamp  uint64
+	Comm       [16]int8
+	Family     uint8
+	L4Proto    uint8
+	Sport      uint16
+	Dport      uint16
+	Pad3       uint16
+	Saddr      [16]uint8
+	Daddr      [16]uint8
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -54,6 +74,7 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
+	KprobeKfreeSkb     *ebpf.ProgramSpec `ebpf:"kprobe_kfree_skb"`
 	TracepointKfreeSkb *ebpf.ProgramSpec `ebpf:"tracepoint_kfree_skb"`
 }
 
@@ -61,13 +82,16 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	DropEvents *ebpf.MapSpec `ebpf:"drop_events"`
+	DropEvents   *ebpf.MapSpec `ebpf:"drop_events"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	UnusedDropEvent *ebpf.VariableSpec `ebpf:"unused_drop_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -90,12 +114,16 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	DropEvents *ebpf.Map `ebpf:"drop_events"`
+	DropEvents   *ebpf.Map `ebpf:"drop_events"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
 		m.DropEvents,
+		m.ExcludedPids,
+		m.RingbufLost,
 	)
 }
 
@@ -103,17 +131,20 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	UnusedDropEvent *ebpf.Variable `ebpf:"unused_drop_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
+	KprobeKfreeSkb     *ebpf.Program `ebpf:"kprobe_kfree_skb"`
 	TracepointKfreeSkb *ebpf.Program `ebpf:"tracepoint_kfree_skb"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.KprobeKfreeSkb,
 		p.TracepointKfreeSkb,
 	)
 }
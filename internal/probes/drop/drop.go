@@ -2,12 +2,10 @@
 package drop
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"time"
+	"net/netip"
+	"unsafe"
 
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
@@ -16,9 +14,12 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+	"github.com/sureshkrishnan-v/kubePulse/internal/ksym"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct drop_event in bpf/drop_tracer.c.
 type rawEvent struct {
 	PID        uint32
 	DropReason uint32
@@ -28,6 +29,77 @@ type rawEvent struct {
 	Location   uint64
 	Timestamp  uint64
 	Comm       [constants.CommSize]byte
+	Family     uint8 // constants.AFInet, constants.AFInet6, or 0 if not parsed
+	L4Proto    uint8
+	SPort      uint16
+	DPort      uint16
+	Pad3       uint16
+	SAddr      [constants.IPAddrSize]byte
+	DAddr      [constants.IPAddrSize]byte
+}
+
+// decoder decodes struct drop_event. Objects generated before the
+// tracer read packet headers emit only the fields before Family, which
+// decode as a packet without addresses.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct drop_event), which bpf/drop_tracer.c
+// asserts. The assignment below fails the build if rawEvent is any other
+// size.
+const rawEventSize = 88
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// minRawEventSize is the record size of objects generated before the
+// tracer read packet headers: the fields before Family. The assignment
+// below fails the build if Family moves.
+const minRawEventSize = 48
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.Family)]byte{}
+
+// decode parses one ring buffer record, zero-padding records from older
+// objects and rejecting any shorter than those.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
+}
+
+// endpoints formats the packet's source and destination as "ip:port", or
+// just "ip" when it carried no TCP or UDP ports. ok is false for non-IP
+// packets and packets whose headers could not be read.
+func (r rawEvent) endpoints() (src, dst string, ok bool) {
+	var saddr, daddr netip.Addr
+	switch r.Family {
+	case constants.AFInet:
+		saddr = netip.AddrFrom4([4]byte(r.SAddr[:4]))
+		daddr = netip.AddrFrom4([4]byte(r.DAddr[:4]))
+	case constants.AFInet6:
+		saddr = netip.AddrFrom16(r.SAddr)
+		daddr = netip.AddrFrom16(r.DAddr)
+	default:
+		return "", "", false
+	}
+	if r.SPort == 0 && r.DPort == 0 {
+		return saddr.String(), daddr.String(), true
+	}
+	return netip.AddrPortFrom(saddr, r.SPort).String(), netip.AddrPortFrom(daddr, r.DPort).String(), true
+}
+
+// protocol names the packet's transport for the protocol label: tcp, udp
+// or icmp (v4 or v6), else other, which includes non-IP packets and those
+// whose headers could not be read.
+func (r rawEvent) protocol() string {
+	if r.Family != constants.AFInet && r.Family != constants.AFInet6 {
+		return constants.ProtocolOther
+	}
+	switch r.L4Proto {
+	case constants.IPProtoTCP:
+		return constants.ProtocolTCP
+	case constants.IPProtoUDP:
+		return constants.ProtocolUDP
+	case constants.IPProtoICMP, constants.IPProtoICMPv6:
+		return constants.ProtocolICMP
+	}
+	return constants.ProtocolOther
 }
 
 // Module implements probe.Module for packet drop detection.
@@ -35,8 +107,11 @@ type Module struct {
 	deps   probe.Dependencies
 	logger *zap.Logger
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	mode   string // constants.AttachMode*
+	reader probe.RecordReader
+	ksyms  *ksym.Resolver // names drop locations
 }
 
 // New creates a new Drop module instance (Factory constructor).
@@ -49,53 +124,104 @@ func (m *Module) Name() string { return constants.ModuleDrop }
 func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	if m.ksyms == nil {
+		m.ksyms = ksym.New()
+	}
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
-	tp, err := link.Tracepoint("skb", "kfree_skb", m.objs.TracepointKfreeSkb, nil)
+	l, err := m.attach(deps.Kernel)
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching tracepoint: %w", err)
+		return err
 	}
-	m.links = append(m.links, tp)
-	m.reader, err = ringbuf.NewReader(m.objs.DropEvents)
+	m.links = append(m.links, l)
+	reader, err := ringbuf.NewReader(m.objs.DropEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 	return nil
 }
 
-func (m *Module) Start(ctx context.Context) error {
-	m.logger.Info("Drop module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+// attach hooks kfree_skb: the tracepoint where the kernel has it, with
+// drop reasons from Linux 5.17, else a kprobe that reports every drop as
+// NOT_SPECIFIED.
+func (m *Module) attach(k *kernelfeat.Features) (link.Link, error) {
+	group, name := constants.TracepointGroupSkb, constants.TracepointKfreeSkb
+	if k.Tracepoint(group, name) {
+		if !k.TracepointField(group, name, constants.TracepointFieldDropReason) {
+			m.logger.Warn("kfree_skb tracepoint has no reason field (Linux < 5.17) — drop reasons will be NOT_SPECIFIED")
 		}
-		record, err := m.reader.Read()
+		tp, err := m.bpf.Tracepoint(group, name, m.objs.TracepointKfreeSkb)()
 		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading drop event", zap.Error(err))
-			continue
+			return nil, err
 		}
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing drop event", zap.Error(err))
-			continue
-		}
-		e := event.Acquire()
-		e.Type = event.TypeDrop
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-		e.SetLabel(constants.KeyReason, bpfutil.DropReasonString(raw.DropReason))
-		m.deps.EventBus.Publish(e)
+		m.mode = constants.AttachModeTracepoint
+		return tp, nil
+	}
+
+	prog := m.bpf.Programs[constants.BPFProgKprobeKfreeSkb]
+	if prog == nil {
+		return nil, fmt.Errorf("tracepoint %s/%s: %w", group, name, probe.ErrUnsupported)
+	}
+	m.logger.Warn("kfree_skb tracepoint unavailable — falling back to kprobe, drop reasons will be NOT_SPECIFIED")
+	kp, err := m.bpf.Kprobe(constants.KprobeKfreeSkb, prog)()
+	if err != nil {
+		return nil, err
+	}
+	m.mode = constants.AttachModeKprobe
+	return kp, nil
+}
+
+func (m *Module) Start(ctx context.Context) error {
+	m.logger.Info("Drop module consumer started")
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
+
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if m.deps.Sampler.Paused() { // never sampled, only paused
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing drop event", zap.Error(err))
+		return
+	}
+	comm := bpfutil.CommString(raw.Comm)
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.Comm(comm) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeDrop
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	e.SetLabel(constants.KeyReason, bpfutil.DropReasonString(raw.DropReason))
+	e.SetLabel(constants.KeyProtocol, raw.protocol())
+	if raw.Location != 0 { // the kprobe fallback has no location
+		e.SetLabel(constants.KeyLocation, m.ksyms.Resolve(raw.Location))
+	}
+	if src, dst, ok := raw.endpoints(); ok {
+		e.SetLabel(constants.KeySrc, src)
+		e.SetLabel(constants.KeyDst, dst)
 	}
+	// The PID is the task running when the packet was freed. Drops in
+	// softirq context, most receive-path drops, run with PID 0 or on an
+	// unrelated task, so the pod is a best guess; PID 0 has none and is
+	// enriched as a host process.
+	m.deps.Enrich(e, raw.PID)
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -106,5 +232,24 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// AttachMode returns how the module's program is attached: tracepoint or
+// kprobe.
+func (m *Module) AttachMode() string {
+	return m.mode
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
@@ -1,10 +1,21 @@
 package drop
 
 import (
+	"bytes"
+	"encoding/binary"
 	"testing"
 
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
 	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/ksym"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
 )
 
 func TestNew(t *testing.T) {
@@ -24,7 +35,7 @@ func TestDropReasonString(t *testing.T) {
 	}{
 		{2, "NOT_SPECIFIED"},
 		{3, "NO_SOCKET"},
-		{99, "REASON_99"},
+		{1000, "REASON_1000"}, // past the enum in every kernel
 	}
 	for _, tt := range tests {
 		got := bpfutil.DropReasonString(tt.reason)
@@ -33,3 +44,166 @@ func TestDropReasonString(t *testing.T) {
 		}
 	}
 }
+
+// TestRawEventSize pins the decode struct to sizeof(struct drop_event), which
+// bpf/drop_tracer.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/drop_tracer.c", "drop_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfDropEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfDropEvent{}); got != want {
+		t.Errorf("embedded object's drop_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+// sample encodes raw the way the tracer writes it to the ring buffer.
+func sample(t *testing.T, raw rawEvent) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, raw); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode_Endpoints(t *testing.T) {
+	v6 := func(last byte) (a [constants.IPAddrSize]byte) {
+		a[0], a[1], a[15] = 0x20, 0x01, last // 2001::<last>
+		return a
+	}
+	tests := []struct {
+		name     string
+		raw      rawEvent
+		src, dst string
+		ok       bool
+	}{
+		{
+			name: "ipv4 tcp",
+			raw: rawEvent{Family: constants.AFInet, L4Proto: 6, SPort: 43210, DPort: 443,
+				SAddr: [16]byte{10, 0, 0, 1}, DAddr: [16]byte{10, 0, 0, 2}},
+			src: "10.0.0.1:43210", dst: "10.0.0.2:443", ok: true,
+		},
+		{
+			name: "ipv6 udp",
+			raw:  rawEvent{Family: constants.AFInet6, L4Proto: 17, SPort: 5353, DPort: 53, SAddr: v6(1), DAddr: v6(2)},
+			src:  "[2001::1]:5353", dst: "[2001::2]:53", ok: true,
+		},
+		{
+			name: "ipv4 icmp has no ports",
+			raw:  rawEvent{Family: constants.AFInet, L4Proto: 1, SAddr: [16]byte{192, 168, 1, 1}, DAddr: [16]byte{8, 8, 8, 8}},
+			src:  "192.168.1.1", dst: "8.8.8.8", ok: true,
+		},
+		{
+			name: "not ip",
+			raw:  rawEvent{Protocol: 0x0806}, // ARP
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := mustDecode(t, sample(t, tt.raw))
+			src, dst, ok := raw.endpoints()
+			if src != tt.src || dst != tt.dst || ok != tt.ok {
+				t.Errorf("endpoints() = %q, %q, %v, want %q, %q, %v", src, dst, ok, tt.src, tt.dst, tt.ok)
+			}
+		})
+	}
+}
+
+// TestDecode_ShortSample covers objects built before the tracer read
+// packet headers, whose events end after Comm.
+func TestDecode_ShortSample(t *testing.T) {
+	full := sample(t, rawEvent{PID: 42, DropReason: 3, Location: 0xffffffff81a00000})
+	raw := mustDecode(t, full[:48])
+	if raw.PID != 42 || raw.DropReason != 3 || raw.Location != 0xffffffff81a00000 {
+		t.Errorf("decode(short) = %+v", raw)
+	}
+	if _, _, ok := raw.endpoints(); ok {
+		t.Error("short sample reported endpoints")
+	}
+}
+
+func TestProtocol(t *testing.T) {
+	tests := []struct {
+		raw  rawEvent
+		want string
+	}{
+		{rawEvent{Family: constants.AFInet, L4Proto: constants.IPProtoTCP}, constants.ProtocolTCP},
+		{rawEvent{Family: constants.AFInet6, L4Proto: constants.IPProtoUDP}, constants.ProtocolUDP},
+		{rawEvent{Family: constants.AFInet, L4Proto: constants.IPProtoICMP}, constants.ProtocolICMP},
+		{rawEvent{Family: constants.AFInet6, L4Proto: constants.IPProtoICMPv6}, constants.ProtocolICMP},
+		{rawEvent{Family: constants.AFInet, L4Proto: 132}, constants.ProtocolOther}, // SCTP
+		{rawEvent{Protocol: 0x0806}, constants.ProtocolOther},                       // ARP
+		{rawEvent{L4Proto: constants.IPProtoTCP}, constants.ProtocolOther},          // headers not read
+	}
+	for _, tt := range tests {
+		if got := tt.raw.protocol(); got != tt.want {
+			t.Errorf("protocol(%+v) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestHandle_Labels(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleDrop, config.FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	events := bus.Subscribe("test")
+	m := &Module{logger: zap.NewNop(), ksyms: ksym.New(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, metadata.NewCache(metadata.DefaultCacheConfig()), "node-a", probe.NewSampler(1), filter, nil, nil)}
+
+	raw := rawEvent{DropReason: 3, Family: constants.AFInet, L4Proto: constants.IPProtoUDP,
+		SPort: 5353, DPort: 53, SAddr: [16]byte{10, 0, 0, 1}, DAddr: [16]byte{10, 0, 0, 2}}
+	m.handle(ringbuf.Record{RawSample: sample(t, raw)})
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if got := e.Label(constants.KeyProtocol); got != constants.ProtocolUDP {
+		t.Errorf("protocol = %q, want udp", got)
+	}
+	if e.Namespace != "" || e.Pod != "" {
+		t.Errorf("PID 0 drop attributed to %s/%s", e.Namespace, e.Pod)
+	}
+	if got := e.Label(constants.KeyEnrichment); got != constants.EnrichmentHost {
+		t.Errorf("PID 0 drop enrichment = %q, want host", got)
+	}
+}
+
+func mustDecode(t *testing.T, sample []byte) rawEvent {
+	t.Helper()
+	raw, err := decode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
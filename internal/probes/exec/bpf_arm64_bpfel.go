@@ -0,0 +1,156 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package exec
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpfExecEvent struct {
+	_          structs.HostLayout
+	Pid        uint32
+	Uid        uint32
+	OldPid     uint32
+	Pad        uint32
+	Timestamp  uint64
+	Comm       [16]int8
+	Filename   [128]int8
+	Ppid       uint32
+	ParentComm [16]int8
+	Pad2       uint32
+}
+
+// loadBpf returns the embedded CollectionSpec for bpf.
+func loadBpf() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BpfBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpfObjects loads bpf and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpfObjects
+//	*bpfPrograms
+//	*bpfMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpfObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpfSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfSpecs struct {
+	bpfProgramSpecs
+	bpfMapSpecs
+	bpfVariableSpecs
+}
+
+// bpfProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfProgramSpecs struct {
+	TracepointSchedProcessExec *ebpf.ProgramSpec `ebpf:"tracepoint_sched_process_exec"`
+}
+
+// bpfMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfMapSpecs struct {
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	ExecEvents   *ebpf.MapSpec `ebpf:"exec_events"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+}
+
+// bpfVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfVariableSpecs struct {
+	UnusedExecEvent *ebpf.VariableSpec `ebpf:"unused_exec_event"`
+}
+
+// bpfObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfObjects struct {
+	bpfPrograms
+	bpfMaps
+	bpfVariables
+}
+
+func (o *bpfObjects) Close() error {
+	return _BpfClose(
+		&o.bpfPrograms,
+		&o.bpfMaps,
+	)
+}
+
+// bpfMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfMaps struct {
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	ExecEvents   *ebpf.Map `ebpf:"exec_events"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+}
+
+func (m *bpfMaps) Close() error {
+	return _BpfClose(
+		m.ExcludedPids,
+		m.ExecEvents,
+		m.RingbufLost,
+	)
+}
+
+// bpfVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfVariables struct {
+	UnusedExecEvent *ebpf.Variable `ebpf:"unused_exec_event"`
+}
+
+// bpfPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfPrograms struct {
+	TracepointSchedProcessExec *ebpf.Program `ebpf:"tracepoint_sched_process_exec"`
+}
+
+func (p *bpfPrograms) Close() error {
+	return _BpfClose(
+		p.TracepointSchedProcessExec,
+	)
+}
+
+func _BpfClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_arm64_bpfel.o
+var _BpfBytes []byte
@@ -8,10 +8,25 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"structs"
 
 	"github.com/cilium/ebpf"
 )
 
+type bpfExecEvent struct {
+	_          structs.HostLayout
+	Pid        uint32
+	Uid        uint32
+	OldPid     uint32
+	Pad        uint32
+	Timestamp  uint64
+	Comm       [16]int8
+	Filename   [128]int8
+	Ppid       uint32
+	ParentComm [16]int8
+	Pad2       uint32
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -61,13 +76,16 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	ExecEvents *ebpf.MapSpec `ebpf:"exec_events"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	ExecEvents   *ebpf.MapSpec `ebpf:"exec_events"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	UnusedExecEvent *ebpf.VariableSpec `ebpf:"unused_exec_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -90,12 +108,16 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	ExecEvents *ebpf.Map `ebpf:"exec_events"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	ExecEvents   *ebpf.Map `ebpf:"exec_events"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.ExcludedPids,
 		m.ExecEvents,
+		m.RingbufLost,
 	)
 }
 
@@ -103,6 +125,7 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	UnusedExecEvent *ebpf.Variable `ebpf:"unused_exec_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
@@ -0,0 +1,74 @@
+package exec
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// coalesceKey identifies execs that are merged: the same file run in the
+// same pod. Host processes share the empty pod.
+type coalesceKey struct {
+	namespace, pod, filename string
+}
+
+// coalescer merges exec events over fixed windows, so a pod forking
+// thousands of short-lived processes publishes one event per file per
+// window instead of one per exec. The first exec in a window is kept,
+// with constants.KeyCount set to how many it stands for.
+type coalescer struct {
+	publish func(*event.Event)
+
+	mu      sync.Mutex
+	pending map[coalesceKey]*event.Event
+}
+
+func newCoalescer(publish func(*event.Event)) *coalescer {
+	return &coalescer{publish: publish, pending: make(map[coalesceKey]*event.Event)}
+}
+
+// add takes ownership of e, holding it until the next flush or merging it
+// into an event already held.
+func (c *coalescer) add(e *event.Event) {
+	k := coalesceKey{e.Namespace, e.Pod, e.Label(constants.KeyFilename)}
+	c.mu.Lock()
+	held, ok := c.pending[k]
+	if ok {
+		held.Numeric[constants.KeyCount]++
+	} else {
+		e.SetNumeric(constants.KeyCount, 1)
+		c.pending[k] = e
+	}
+	c.mu.Unlock()
+	if ok {
+		e.Release()
+	}
+}
+
+// flush publishes every held event.
+func (c *coalescer) flush() {
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = make(map[coalesceKey]*event.Event, len(pending))
+	c.mu.Unlock()
+	for _, e := range pending {
+		c.publish(e)
+	}
+}
+
+// run flushes every window until stop is closed, then flushes once more.
+func (c *coalescer) run(window time.Duration, stop <-chan struct{}) {
+	t := time.NewTicker(window)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			c.flush()
+		case <-stop:
+			c.flush()
+			return
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package exec
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// published collects events a coalescer publishes, keyed by pod/filename.
+type published struct {
+	mu     sync.Mutex
+	counts map[string]float64
+}
+
+func (p *published) publish(e *event.Event) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.counts[e.Pod+"/"+e.Label(constants.KeyFilename)] += e.NumericVal(constants.KeyCount)
+	e.Release()
+}
+
+func execEvent(pod, filename string) *event.Event {
+	e := event.Acquire()
+	e.Type = event.TypeExec
+	e.Pod = pod
+	e.SetLabel(constants.KeyFilename, filename)
+	return e
+}
+
+func TestCoalescer(t *testing.T) {
+	p := &published{counts: map[string]float64{}}
+	c := newCoalescer(p.publish)
+	for range 3 {
+		c.add(execEvent("build", "/usr/bin/cc"))
+	}
+	c.add(execEvent("build", "/bin/sh"))
+	c.add(execEvent("web", "/usr/bin/cc"))
+	if len(p.counts) != 0 {
+		t.Fatalf("published %v before a flush", p.counts)
+	}
+
+	c.flush()
+	want := map[string]float64{"build//usr/bin/cc": 3, "build//bin/sh": 1, "web//usr/bin/cc": 1}
+	for k, n := range want {
+		if p.counts[k] != n {
+			t.Errorf("count[%s] = %v, want %v", k, p.counts[k], n)
+		}
+	}
+
+	// The next window starts empty.
+	c.add(execEvent("build", "/usr/bin/cc"))
+	c.flush()
+	if got := p.counts["build//usr/bin/cc"]; got != 4 {
+		t.Errorf("count after second window = %v, want 4", got)
+	}
+}
+
+func TestCoalescer_FlushesOnStop(t *testing.T) {
+	p := &published{counts: map[string]float64{}}
+	c := newCoalescer(p.publish)
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		c.run(time.Hour, stop)
+		close(done)
+	}()
+
+	c.add(execEvent("cron", "/bin/date"))
+	c.add(execEvent("cron", "/bin/date"))
+	close(stop)
+	<-done
+
+	if got := p.counts["cron//bin/date"]; got != 2 {
+		t.Errorf("count after stop = %v, want 2", got)
+	}
+}
@@ -2,13 +2,12 @@
 package exec
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"time"
+	"sync"
+	"unsafe"
 
+	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
 	"go.uber.org/zap"
@@ -19,14 +18,43 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct exec_event in bpf/exec_tracer.c.
 type rawEvent struct {
-	PID       uint32
-	UID       uint32
-	OldPID    uint32
-	Pad1      uint32
-	Timestamp uint64
-	Comm      [constants.CommSize]byte
-	Filename  [constants.FilenameSize]byte
+	PID        uint32
+	UID        uint32
+	OldPID     uint32
+	Pad1       uint32
+	Timestamp  uint64
+	Comm       [constants.CommSize]byte
+	Filename   [constants.FilenameSize]byte
+	PPID       uint32
+	ParentComm [constants.CommSize]byte
+	Pad2       uint32
+}
+
+// decoder decodes struct exec_event. Objects generated before the
+// tracer reported the parent emit only the fields up to Filename, which
+// decode with PPID 0.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct exec_event), which bpf/exec_tracer.c
+// asserts. The assignment below fails the build if rawEvent is any other
+// size.
+const rawEventSize = 192
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// minRawEventSize is the record size of objects generated before the
+// tracer reported the parent: the fields before PPID. The assignment below
+// fails the build if PPID moves.
+const minRawEventSize = 168
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.PPID)]byte{}
+
+// decode parses one ring buffer record, zero-padding records from older
+// objects and rejecting any shorter than those.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
 }
 
 // Module implements probe.Module for process execution monitoring.
@@ -34,8 +62,12 @@ type Module struct {
 	deps   probe.Dependencies
 	logger *zap.Logger
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	reader probe.RecordReader
+
+	// coalesce merges execs when modules.exec.aggregate_window is set.
+	coalesce *coalescer
 }
 
 // New creates a new Exec module instance (Factory constructor).
@@ -45,63 +77,102 @@ func New() *Module {
 
 func (m *Module) Name() string { return constants.ModuleExec }
 
+// LoadSpec returns the module's BPF collection spec. Its single tracepoint
+// program makes it the smallest probe, which `kubepulse check` loads to
+// exercise the verifier.
+func LoadSpec() (*ebpf.CollectionSpec, error) {
+	return loadBpf()
+}
+
 func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	if deps.Config != nil && deps.Config.AggregateWindow > 0 {
+		m.coalesce = newCoalescer(deps.EventBus.Publish)
+	}
+	if !deps.Kernel.Tracepoint(constants.TracepointGroupSched, constants.TracepointSchedProcessExec) {
+		return fmt.Errorf("tracepoint %s/%s: %w", constants.TracepointGroupSched, constants.TracepointSchedProcessExec, probe.ErrUnsupported)
+	}
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
-	tp, err := link.Tracepoint("sched", "sched_process_exec", m.objs.TracepointSchedProcessExec, nil)
+	tp, err := m.bpf.Tracepoint(constants.TracepointGroupSched, constants.TracepointSchedProcessExec, m.objs.TracepointSchedProcessExec)()
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching tracepoint: %w", err)
+		return err
 	}
 	m.links = append(m.links, tp)
-	m.reader, err = ringbuf.NewReader(m.objs.ExecEvents)
+	reader, err := ringbuf.NewReader(m.objs.ExecEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("Exec module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading exec event", zap.Error(err))
-			continue
-		}
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing exec event", zap.Error(err))
-			continue
-		}
-		e := event.Acquire()
-		e.Type = event.TypeExec
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
-		}
-		e.SetLabel(constants.KeyFilename, bpfutil.FilenameString(raw.Filename))
-		m.deps.EventBus.Publish(e)
+	if m.coalesce == nil {
+		return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
 	}
+
+	// Events held in the current window are published once the ring
+	// buffer has drained.
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() { m.coalesce.run(m.deps.Config.AggregateWindow, stop) })
+	err := bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+	close(stop)
+	wg.Wait()
+	return err
+}
+
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if !m.deps.Sampler.Keep() {
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing exec event", zap.Error(err))
+		return
+	}
+	comm := bpfutil.CommString(raw.Comm)
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.PID(raw.PPID) || !m.deps.Filter.Comm(comm) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeExec
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.UID = raw.UID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	// A short-lived child can exit before its cgroup is read; it runs in
+	// its parent's pod, so fall back to the parent.
+	if raw.PPID != 0 {
+		m.deps.Enrich(e, raw.PID, raw.PPID)
+	} else {
+		m.deps.Enrich(e, raw.PID)
+	}
+	e.SetLabel(constants.KeyFilename, bpfutil.FilenameString(raw.Filename))
+	if raw.PPID != 0 {
+		e.SetNumeric(constants.KeyPPID, float64(raw.PPID))
+		e.SetLabel(constants.KeyParentComm, bpfutil.CommString(raw.ParentComm))
+	}
+	if m.coalesce != nil {
+		m.coalesce.add(e)
+		return
+	}
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -112,5 +183,18 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
@@ -0,0 +1,127 @@
+package exec
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
+)
+
+// TestRawEventSize pins the decode struct to sizeof(struct exec_event), which
+// bpf/exec_tracer.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/exec_tracer.c", "exec_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfExecEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfExecEvent{}); got != want {
+		t.Errorf("embedded object's exec_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	in := rawEvent{PID: 42, PPID: 7}
+	copy(in.Comm[:], "sh")
+	copy(in.Filename[:], "/bin/sh")
+	copy(in.ParentComm[:], "nginx")
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, in); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := mustDecode(t, buf.Bytes())
+	if raw.PPID != 7 || bpfutil.CommString(raw.ParentComm) != "nginx" {
+		t.Errorf("decode parent = %d %q, want 7 nginx", raw.PPID, bpfutil.CommString(raw.ParentComm))
+	}
+
+	// Objects built before the parent was reported end after Filename.
+	raw = mustDecode(t, buf.Bytes()[:168])
+	if raw.PID != 42 || raw.PPID != 0 || bpfutil.FilenameString(raw.Filename) != "/bin/sh" {
+		t.Errorf("decode(short) = %+v, want pid 42 with no parent", raw)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	// The child exited before its cgroup was read; its parent is in a pod.
+	meta := metadata.NewCache(metadata.CacheConfig{
+		ResolveContainerID: func(pid uint32) (string, error) {
+			if pid == 7 {
+				return "parent", nil
+			}
+			return "", errors.New("process exited")
+		},
+	})
+	meta.UpdatePod("parent", metadata.PodMeta{Namespace: "ci", PodName: "build-0"})
+	filter, err := probe.NewFilter(constants.ModuleExec, config.FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	events := bus.Subscribe("test")
+	m := &Module{logger: zap.NewNop(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, meta, "node-a", probe.NewSampler(1), filter, nil, nil)}
+
+	raw := rawEvent{PID: 42, PPID: 7}
+	copy(raw.Filename[:], "/usr/bin/cc")
+	copy(raw.ParentComm[:], "make")
+	m.handle(ringbuf.Record{RawSample: probetest.Sample(t, raw)})
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if e.Namespace != "ci" || e.Pod != "build-0" || e.Label(constants.KeyEnrichment) != constants.EnrichmentOK {
+		t.Errorf("exec attributed to %s/%s (%s), want the parent's pod ci/build-0",
+			e.Namespace, e.Pod, e.Label(constants.KeyEnrichment))
+	}
+	if e.Label(constants.KeyParentComm) != "make" || e.NumericVal(constants.KeyPPID) != 7 {
+		t.Errorf("parent = %q %v, want make 7", e.Label(constants.KeyParentComm), e.NumericVal(constants.KeyPPID))
+	}
+}
+
+func mustDecode(t *testing.T, sample []byte) rawEvent {
+	t.Helper()
+	raw, err := decode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
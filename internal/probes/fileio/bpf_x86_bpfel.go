@@ -13,6 +13,20 @@ import (
 	"github.com/cilium/ebpf"
 )
 
+type bpfFileioEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Uid       uint32
+	LatencyNs uint64
+	Bytes     uint64
+	Timestamp uint64
+	Op        uint8
+	Pad       [7]uint8
+	Comm      [16]int8
+	Path      [3][64]int8
+	Fs        [16]int8
+}
+
 type bpfIoKey struct {
 	_   structs.HostLayout
 	Pid uint32
@@ -22,6 +36,7 @@ type bpfIoKey struct {
 type bpfIoVal struct {
 	_       structs.HostLayout
 	StartNs uint64
+	File    uint64
 	Op      uint8
 	Pad     [7]uint8
 }
@@ -68,6 +83,10 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
+	FentryVfsRead     *ebpf.ProgramSpec `ebpf:"fentry_vfs_read"`
+	FentryVfsWrite    *ebpf.ProgramSpec `ebpf:"fentry_vfs_write"`
+	FexitVfsRead      *ebpf.ProgramSpec `ebpf:"fexit_vfs_read"`
+	FexitVfsWrite     *ebpf.ProgramSpec `ebpf:"fexit_vfs_write"`
 	KprobeVfsRead     *ebpf.ProgramSpec `ebpf:"kprobe_vfs_read"`
 	KprobeVfsWrite    *ebpf.ProgramSpec `ebpf:"kprobe_vfs_write"`
 	KretprobeVfsRead  *ebpf.ProgramSpec `ebpf:"kretprobe_vfs_read"`
@@ -78,14 +97,19 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	FileioEvents *ebpf.MapSpec `ebpf:"fileio_events"`
-	IoStart      *ebpf.MapSpec `ebpf:"io_start"`
+	ExcludedPids     *ebpf.MapSpec `ebpf:"excluded_pids"`
+	FileioEvents     *ebpf.MapSpec `ebpf:"fileio_events"`
+	FileioSuppressed *ebpf.MapSpec `ebpf:"fileio_suppressed"`
+	IoStart          *ebpf.MapSpec `ebpf:"io_start"`
+	RingbufLost      *ebpf.MapSpec `ebpf:"ringbuf_lost"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	MinLatencyNs      *ebpf.VariableSpec `ebpf:"min_latency_ns"`
+	UnusedFileioEvent *ebpf.VariableSpec `ebpf:"unused_fileio_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -108,14 +132,20 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	FileioEvents *ebpf.Map `ebpf:"fileio_events"`
-	IoStart      *ebpf.Map `ebpf:"io_start"`
+	ExcludedPids     *ebpf.Map `ebpf:"excluded_pids"`
+	FileioEvents     *ebpf.Map `ebpf:"fileio_events"`
+	FileioSuppressed *ebpf.Map `ebpf:"fileio_suppressed"`
+	IoStart          *ebpf.Map `ebpf:"io_start"`
+	RingbufLost      *ebpf.Map `ebpf:"ringbuf_lost"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.ExcludedPids,
 		m.FileioEvents,
+		m.FileioSuppressed,
 		m.IoStart,
+		m.RingbufLost,
 	)
 }
 
@@ -123,12 +153,18 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	MinLatencyNs      *ebpf.Variable `ebpf:"min_latency_ns"`
+	UnusedFileioEvent *ebpf.Variable `ebpf:"unused_fileio_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
+	FentryVfsRead     *ebpf.Program `ebpf:"fentry_vfs_read"`
+	FentryVfsWrite    *ebpf.Program `ebpf:"fentry_vfs_write"`
+	FexitVfsRead      *ebpf.Program `ebpf:"fexit_vfs_read"`
+	FexitVfsWrite     *ebpf.Program `ebpf:"fexit_vfs_write"`
 	KprobeVfsRead     *ebpf.Program `ebpf:"kprobe_vfs_read"`
 	KprobeVfsWrite    *ebpf.Program `ebpf:"kprobe_vfs_write"`
 	KretprobeVfsRead  *ebpf.Program `ebpf:"kretprobe_vfs_read"`
@@ -137,6 +173,10 @@ type bpfPrograms struct {
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.FentryVfsRead,
+		p.FentryVfsWrite,
+		p.FexitVfsRead,
+		p.FexitVfsWrite,
 		p.KprobeVfsRead,
 		p.KprobeVfsWrite,
 		p.KretprobeVfsRead,
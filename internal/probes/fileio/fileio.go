@@ -4,11 +4,11 @@ package fileio
 import (
 	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
 	"time"
+	"unsafe"
 
+	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
 	"go.uber.org/zap"
@@ -19,29 +19,103 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct fileio_event in bpf/fileio_tracer.c.
 type rawEvent struct {
 	PID       uint32
 	UID       uint32
 	LatencyNs uint64
 	Bytes     uint64
-	Op        uint32 // 0=read, 1=write
-	Pad1      uint32
 	Timestamp uint64
+	Op        uint8 // 0=read, 1=write
+	Pad1      [7]byte
 	Comm      [constants.CommSize]byte
+	Path      [constants.FileIOPathDepth][constants.FileIONameSize]byte // file first
+	FS        [constants.FSTypeSize]byte
+}
+
+// decoder decodes struct fileio_event. Objects generated before
+// the tracer read Path and FS emit only the fields before them.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct fileio_event), which bpf/fileio_tracer.c
+// asserts. The assignment below fails the build if rawEvent is any other
+// size.
+const rawEventSize = 264
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// minRawEventSize is the record size of objects generated before the
+// tracer read Path and FS. The assignment below fails the build if Path
+// moves.
+const minRawEventSize = 56
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.Path)]byte{}
+
+// decode parses one ring buffer record, zero-padding records from older
+// objects and rejecting any shorter than those.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
+}
+
+// filePath joins the dentry names in path into the file's path below its
+// file system's root: "/lib/x.db" when the tracer reached the root,
+// "lib/x.db" when the path is deeper than FileIOPathDepth, and "" for
+// anonymous files such as pipes and sockets.
+func filePath(path [constants.FileIOPathDepth][constants.FileIONameSize]byte) string {
+	depth, absolute := 0, false
+	for depth < len(path) {
+		name := cBytes(path[depth][:])
+		if len(name) == 0 {
+			break
+		}
+		if string(name) == "/" {
+			absolute = true
+			break
+		}
+		depth++
+	}
+	if depth == 0 {
+		return ""
+	}
+	// Joined on the stack, so the path costs one allocation.
+	var buf [len(path) * (constants.FileIONameSize + 1)]byte
+	p := buf[:0]
+	if absolute {
+		p = append(p, '/')
+	}
+	for i := depth - 1; i >= 0; i-- {
+		p = append(p, cBytes(path[i][:])...)
+		if i > 0 {
+			p = append(p, '/')
+		}
+	}
+	return string(p)
+}
+
+// cBytes returns b up to its first null byte.
+func cBytes(b []byte) []byte {
+	if n := bytes.IndexByte(b, 0); n >= 0 {
+		return b[:n]
+	}
+	return b
 }
 
 // Module implements probe.Module for file I/O latency monitoring.
 type Module struct {
-	deps   probe.Dependencies
-	logger *zap.Logger
-	objs   bpfObjects
-	links  []link.Link
-	reader *ringbuf.Reader
+	deps    probe.Dependencies
+	logger  *zap.Logger
+	objs    bpfObjects
+	bpf     *bpfutil.Resources
+	links   []link.Link
+	mode    string // constants.AttachMode*
+	reader  probe.RecordReader
+	comms   *bpfutil.Interner
+	fsTypes *bpfutil.Interner
 }
 
 // New creates a new FileIO module instance (Factory constructor).
 func New() *Module {
-	return &Module{}
+	return &Module{comms: bpfutil.NewInterner(), fsTypes: bpfutil.NewInterner()}
 }
 
 func (m *Module) Name() string { return constants.ModuleFileIO }
@@ -49,89 +123,91 @@ func (m *Module) Name() string { return constants.ModuleFileIO }
 func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
-		return fmt.Errorf("loading BPF objects: %w", err)
-	}
-
-	kpRead, err := link.Kprobe("vfs_read", m.objs.KprobeVfsRead, nil)
+	spec, err := loadBpf()
 	if err != nil {
-		m.Stop(context.Background())
-		return fmt.Errorf("attaching vfs_read kprobe: %w", err)
+		return fmt.Errorf("loading BPF spec: %w", err)
 	}
-	m.links = append(m.links, kpRead)
-
-	krpRead, err := link.Kretprobe("vfs_read", m.objs.KretprobeVfsRead, nil)
-	if err != nil {
-		m.Stop(context.Background())
-		return fmt.Errorf("attaching vfs_read kretprobe: %w", err)
+	if err := setMinLatency(spec, deps.Config.KernelMinLatency(), m.logger); err != nil {
+		return fmt.Errorf("setting %s: %w", constants.BPFVarMinLatencyNs, err)
 	}
-	m.links = append(m.links, krpRead)
-
-	kpWrite, err := link.Kprobe("vfs_write", m.objs.KprobeVfsWrite, nil)
+	pins := deps.Pins.Open(m.Name(), spec)
+	m.bpf, err = pins.LoadObjectsOptional(m.logger, spec, &m.objs, deps.Kernel.Fentry(),
+		constants.BPFProgFentryVFSRead, constants.BPFProgFexitVFSRead,
+		constants.BPFProgFentryVFSWrite, constants.BPFProgFexitVFSWrite)
 	if err != nil {
-		m.Stop(context.Background())
-		return fmt.Errorf("attaching vfs_write kprobe: %w", err)
+		return fmt.Errorf("loading BPF objects: %w", err)
 	}
-	m.links = append(m.links, kpWrite)
 
-	krpWrite, err := link.Kretprobe("vfs_write", m.objs.KretprobeVfsWrite, nil)
+	m.mode, m.links, err = bpfutil.AttachFirst(m.logger,
+		bpfutil.Attachment{Mode: constants.AttachModeFentry, Hooks: []bpfutil.Hook{
+			m.bpf.Tracing(constants.BPFProgFentryVFSRead),
+			m.bpf.Tracing(constants.BPFProgFexitVFSRead),
+			m.bpf.Tracing(constants.BPFProgFentryVFSWrite),
+			m.bpf.Tracing(constants.BPFProgFexitVFSWrite),
+		}},
+		bpfutil.Attachment{Mode: constants.AttachModeKprobe, Hooks: []bpfutil.Hook{
+			m.bpf.Kprobe(constants.KprobeVFSRead, m.objs.KprobeVfsRead),
+			m.bpf.Kretprobe(constants.KprobeVFSRead, m.objs.KretprobeVfsRead),
+			m.bpf.Kprobe(constants.KprobeVFSWrite, m.objs.KprobeVfsWrite),
+			m.bpf.Kretprobe(constants.KprobeVFSWrite, m.objs.KretprobeVfsWrite),
+		}},
+	)
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching vfs_write kretprobe: %w", err)
+		return err
 	}
-	m.links = append(m.links, krpWrite)
+	m.logger.Info("FileIO hooks attached", zap.String("mode", m.mode))
 
-	m.reader, err = ringbuf.NewReader(m.objs.FileioEvents)
+	reader, err := ringbuf.NewReader(m.objs.FileioEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("FileIO module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading fileio event", zap.Error(err))
-			continue
-		}
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing fileio event", zap.Error(err))
-			continue
-		}
-		e := event.Acquire()
-		e.Type = event.TypeFileIO
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
-		}
-		op := constants.FileOpRead
-		if raw.Op == 1 {
-			op = constants.FileOpWrite
-		}
-		e.SetLabel(constants.KeyOp, op)
-		e.SetNumeric(constants.KeyLatencySec, float64(raw.LatencyNs)/constants.NsPerSecond)
-		e.SetNumeric(constants.KeyBytes, float64(raw.Bytes))
-		m.deps.EventBus.Publish(e)
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
+
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if !m.deps.Sampler.Keep() {
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing file I/O event", zap.Error(err))
+		return
+	}
+	comm := m.comms.CString(raw.Comm[:])
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.Comm(comm) || !m.deps.Filter.Latency(raw.LatencyNs) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeFileIO
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.UID = raw.UID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	m.deps.Enrich(e, raw.PID)
+	op := constants.FileOpRead
+	if raw.Op == 1 {
+		op = constants.FileOpWrite
+	}
+	e.SetLabel(constants.KeyOp, op)
+	e.SetLabel(constants.KeyFS, m.fsTypes.CString(raw.FS[:]))
+	if path := filePath(raw.Path); path != "" {
+		e.SetLabel(constants.KeyPath, path)
 	}
+	e.SetNumeric(constants.KeyLatencySec, float64(raw.LatencyNs)/constants.NsPerSecond)
+	e.SetNumeric(constants.KeyBytes, float64(raw.Bytes))
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -142,5 +218,41 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// SuppressedCount returns the I/O the BPF program did not report for
+// being faster than modules.fileio.min_latency.
+func (m *Module) SuppressedCount() (uint64, error) {
+	return m.bpf.Counter(constants.BPFMapFileIOSuppressed)
+}
+
+// setMinLatency sets the BPF program's latency threshold. Objects built
+// before it was configurable keep their compiled-in 1ms.
+func setMinLatency(spec *ebpf.CollectionSpec, d time.Duration, logger *zap.Logger) error {
+	v, ok := spec.Variables[constants.BPFVarMinLatencyNs]
+	if !ok {
+		logger.Info("BPF object has no configurable latency threshold — using its built-in 1ms")
+		return nil
+	}
+	return v.Set(uint64(d))
+}
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// AttachMode returns how the module's programs are attached: fentry or
+// kprobe.
+func (m *Module) AttachMode() string {
+	return m.mode
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
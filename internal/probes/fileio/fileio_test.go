@@ -0,0 +1,225 @@
+package fileio
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
+)
+
+// TestRawEventSize pins the decode struct to sizeof(struct fileio_event), which
+// bpf/fileio_tracer.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/fileio_tracer.c", "fileio_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfFileioEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfFileioEvent{}); got != want {
+		t.Errorf("embedded object's fileio_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestFilePath(t *testing.T) {
+	tests := []struct {
+		names []string // as the tracer reads them, file first
+		want  string
+	}{
+		{[]string{"passwd", "etc", "/"}, "/etc/passwd"},
+		{[]string{"hosts", "/"}, "/hosts"},
+		{[]string{"000123.log", "wal", "data"}, "data/wal/000123.log"}, // deeper than PATH_DEPTH
+		{nil, ""}, // pipe or socket
+		{[]string{"/"}, ""},
+	}
+	for _, tt := range tests {
+		var path [constants.FileIOPathDepth][constants.FileIONameSize]byte
+		for i, name := range tt.names {
+			copy(path[i][:], name)
+		}
+		if got := filePath(path); got != tt.want {
+			t.Errorf("filePath(%q) = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestSetMinLatency_PrebuiltObject(t *testing.T) {
+	spec, err := loadBpf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Variables[constants.BPFVarMinLatencyNs]; ok {
+		var ns uint64
+		if err := setMinLatency(spec, 5*time.Millisecond, zap.NewNop()); err != nil {
+			t.Fatal(err)
+		}
+		if err := spec.Variables[constants.BPFVarMinLatencyNs].Get(&ns); err != nil || ns != 5e6 {
+			t.Errorf("min_latency_ns = %d, %v; want 5000000", ns, err)
+		}
+		return
+	}
+	// Objects generated before the threshold was added keep working.
+	if err := setMinLatency(spec, 5*time.Millisecond, zap.NewNop()); err != nil {
+		t.Errorf("setMinLatency on an object without the variable: %v", err)
+	}
+}
+
+// FuzzHandle feeds handle truncated, oversized and arbitrary records; it
+// must publish or skip them, never panic.
+func FuzzHandle(f *testing.F) {
+	f.Add(make([]byte, decoder.Size()))
+	f.Add(make([]byte, decoder.Size()+64))
+	f.Add([]byte{1, 2, 3})
+	f.Add([]byte{})
+
+	filter, err := probe.NewFilter(constants.ModuleFileIO, config.FilterConfig{})
+	if err != nil {
+		f.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	m := &Module{logger: zap.NewNop(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		m.handle(ringbuf.Record{RawSample: data})
+	})
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
+
+// replayReader is a ring buffer that holds sample n times, then closes.
+type replayReader struct {
+	sample []byte
+	n      int
+}
+
+func (r *replayReader) Read() (ringbuf.Record, error) {
+	if r.n == 0 {
+		return ringbuf.Record{}, ringbuf.ErrClosed
+	}
+	r.n--
+	return ringbuf.Record{RawSample: r.sample}, nil
+}
+
+func (*replayReader) SetDeadline(time.Time) {}
+
+func (*replayReader) Flush() error { return nil }
+
+// BenchmarkPipeline measures one event from the ring buffer to a bus
+// subscriber: decoding, filtering, enrichment and publishing.
+func BenchmarkPipeline(b *testing.B) {
+	var buf bytes.Buffer
+	in := rawEvent{PID: 9, LatencyNs: 2e6, Bytes: 4096, Op: 1}
+	copy(in.Comm[:], "postgres")
+	for i, name := range []string{"base.db", "data", "/"} {
+		copy(in.Path[i][:], name)
+	}
+	copy(in.FS[:], "ext4")
+	if err := binary.Write(&buf, binary.LittleEndian, in); err != nil {
+		b.Fatal(err)
+	}
+
+	filter, err := probe.NewFilter(constants.ModuleFileIO, config.FilterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	bus := event.NewBus(constants.DefaultEventBusBuffer, nil)
+	events := bus.Subscribe("bench")
+	done := make(chan struct{})
+	go func() {
+		for e := range events {
+			e.Done()
+		}
+		close(done)
+	}()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	bpfutil.ReadLoop(context.Background(), &replayReader{buf.Bytes(), b.N}, 0, zap.NewNop(), m.handle)
+	b.StopTimer()
+	bus.Close()
+	<-done
+}
+
+func TestStart(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleFileIO, config.FilterConfig{MinLatency: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(16, nil)
+	events := bus.Subscribe("test")
+	reader := probetest.NewReader()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)
+	m.reader = reader
+	probetest.Start(t, m.Start)
+
+	write := rawEvent{PID: 9, LatencyNs: 5e6, Bytes: 4096, Op: 1}
+	copy(write.Comm[:], "postgres")
+	for i, name := range []string{"base.db", "data", "/"} {
+		copy(write.Path[i][:], name)
+	}
+	copy(write.FS[:], "ext4")
+	fast := write
+	fast.LatencyNs = 1e3
+	reader.Send(probetest.Sample(t, fast))
+	reader.Send(probetest.Sample(t, write)[:minRawEventSize-1]) // truncated: skipped
+	reader.Send(probetest.Sample(t, write))
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if e.Type != event.TypeFileIO || e.Comm != "postgres" || e.Node != "node-a" {
+		t.Errorf("event = %+v, want postgres's write on node-a", e)
+	}
+	for key, want := range map[string]string{
+		constants.KeyOp:   constants.FileOpWrite,
+		constants.KeyFS:   "ext4",
+		constants.KeyPath: "/data/base.db",
+	} {
+		if got := e.Label(key); got != want {
+			t.Errorf("label %s = %q, want %q", key, got, want)
+		}
+	}
+	if e.NumericVal(constants.KeyLatencySec) != 0.005 || e.NumericVal(constants.KeyBytes) != 4096 {
+		t.Errorf("numerics = %v", e.Numeric)
+	}
+}
@@ -8,10 +8,27 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"structs"
 
 	"github.com/cilium/ebpf"
 )
 
+type bpfOomEvent struct {
+	_           structs.HostLayout
+	Pid         uint32
+	Uid         uint32
+	TotalVm     uint64
+	AnonRss     uint64
+	FileRss     uint64
+	ShmemRss    uint64
+	Pgtables    uint64
+	OomScoreAdj int16
+	Pad         uint16
+	Pad2        uint32
+	Timestamp   uint64
+	Comm        [16]int8
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -61,13 +78,15 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	OomEvents *ebpf.MapSpec `ebpf:"oom_events"`
+	OomEvents   *ebpf.MapSpec `ebpf:"oom_events"`
+	RingbufLost *ebpf.MapSpec `ebpf:"ringbuf_lost"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	UnusedOomEvent *ebpf.VariableSpec `ebpf:"unused_oom_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -90,12 +109,14 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	OomEvents *ebpf.Map `ebpf:"oom_events"`
+	OomEvents   *ebpf.Map `ebpf:"oom_events"`
+	RingbufLost *ebpf.Map `ebpf:"ringbuf_lost"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
 		m.OomEvents,
+		m.RingbufLost,
 	)
 }
 
@@ -103,6 +124,7 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	UnusedOomEvent *ebpf.Variable `ebpf:"unused_oom_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
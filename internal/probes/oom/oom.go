@@ -2,12 +2,9 @@
 package oom
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"time"
+	"unsafe"
 
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
@@ -19,6 +16,8 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct oom_event in bpf/oomkill.c. The mark_victim
+// tracepoint reports memory sizes in kB, not pages.
 type rawEvent struct {
 	PID         uint32
 	UID         uint32
@@ -34,13 +33,38 @@ type rawEvent struct {
 	Comm        [constants.CommSize]byte
 }
 
+// decoder decodes struct oom_event.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct oom_event), which bpf/oomkill.c asserts.
+// The assignment below fails the build if rawEvent is any other size.
+const rawEventSize = 80
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// rssBytes is the victim's resident memory: anonymous, file-backed and
+// shared pages, which is what the kernel charges against its memory limit.
+func (r rawEvent) rssBytes() float64 {
+	return kbToBytes(r.AnonRSS + r.FileRSS + r.ShmemRSS)
+}
+
+func kbToBytes(kb uint64) float64 {
+	return float64(kb) * 1024
+}
+
+// usagePct is used bytes as a percentage of limit.
+func usagePct(used, limit float64) float64 {
+	return used / limit * 100
+}
+
 // Module implements probe.Module for OOM kill detection.
 type Module struct {
 	deps   probe.Dependencies
 	logger *zap.Logger
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	reader probe.RecordReader
 }
 
 // New creates a new OOM module instance (Factory constructor).
@@ -53,61 +77,71 @@ func (m *Module) Name() string { return constants.ModuleOOM }
 func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	if !deps.Kernel.Tracepoint(constants.TracepointGroupOOM, constants.TracepointOOMMarkVictim) {
+		return fmt.Errorf("tracepoint %s/%s: %w", constants.TracepointGroupOOM, constants.TracepointOOMMarkVictim, probe.ErrUnsupported)
+	}
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
-	tp, err := link.Tracepoint("oom", "mark_victim", m.objs.TracepointOomMarkVictim, nil)
+	tp, err := m.bpf.Tracepoint(constants.TracepointGroupOOM, constants.TracepointOOMMarkVictim, m.objs.TracepointOomMarkVictim)()
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching tracepoint: %w", err)
+		return err
 	}
 	m.links = append(m.links, tp)
-	m.reader, err = ringbuf.NewReader(m.objs.OomEvents)
+	reader, err := ringbuf.NewReader(m.objs.OomEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("OOM module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading OOM event", zap.Error(err))
-			continue
-		}
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing OOM event", zap.Error(err))
-			continue
-		}
-		e := event.Acquire()
-		e.Type = event.TypeOOM
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
+
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if m.deps.Sampler.Paused() { // never sampled, only paused
+		return
+	}
+	raw, err := decoder.Decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing OOM event", zap.Error(err))
+		return
+	}
+	comm := bpfutil.CommString(raw.Comm)
+	if !m.deps.Filter.Comm(comm) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeOOM
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.UID = raw.UID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	e.SetNumeric(constants.KeyTotalVMKB, float64(raw.TotalVM))
+	e.SetNumeric(constants.KeyOOMScoreAdj, float64(raw.OOMScoreAdj))
+	if meta, found := m.deps.Enrich(e, raw.PID); found {
+		e.SetLabel(constants.KeyContainer, meta.ContainerName)
+		if meta.MemoryLimitBytes > 0 {
+			limit := float64(meta.MemoryLimitBytes)
+			e.SetNumeric(constants.KeyMemoryLimitBytes, limit)
+			e.SetNumeric(constants.KeyUsagePct, usagePct(raw.rssBytes(), limit))
 		}
-		e.SetNumeric(constants.KeyTotalVMKB, float64(raw.TotalVM*4))
-		e.SetNumeric(constants.KeyOOMScoreAdj, float64(raw.OOMScoreAdj))
-		m.deps.EventBus.Publish(e)
 	}
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -118,5 +152,18 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
@@ -0,0 +1,111 @@
+package oom
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
+)
+
+// TestRawEventSize pins the decode struct to sizeof(struct oom_event), which
+// bpf/oomkill.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/oomkill.c", "oom_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfOomEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfOomEvent{}); got != want {
+		t.Errorf("embedded object's oom_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestRSSBytes(t *testing.T) {
+	// The tracepoint reports kB, whatever the page size.
+	raw := rawEvent{AnonRSS: 200 * 1024, FileRSS: 50 * 1024, ShmemRSS: 6 * 1024, TotalVM: 1 << 30}
+	if got, want := raw.rssBytes(), float64(256<<20); got != want {
+		t.Errorf("rssBytes = %v, want %v", got, want)
+	}
+}
+
+func TestUsagePct(t *testing.T) {
+	tests := []struct {
+		used, limit, want float64
+	}{
+		{256 << 20, 256 << 20, 100},
+		{192 << 20, 256 << 20, 75},
+		{300 << 20, 200 << 20, 150}, // not clamped
+	}
+	for _, tt := range tests {
+		if got := usagePct(tt.used, tt.limit); got != tt.want {
+			t.Errorf("usagePct(%v, %v) = %v, want %v", tt.used, tt.limit, got, tt.want)
+		}
+	}
+}
+
+// FuzzDecode feeds the decoder records of any length: it must reject
+// those shorter than struct oom_event, and decode the rest as binary.Read
+// does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, rawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decoder.Decode(data)
+		if (err == nil) != (len(data) >= rawEventSize) {
+			t.Fatalf("Decode(%d bytes) error = %v", len(data), err)
+		}
+		if err == nil {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("Decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
+
+func TestStart(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleOOM, config.FilterConfig{ExcludeComm: []string{"^stress$"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(16, nil)
+	events := bus.Subscribe("test")
+	reader := probetest.NewReader()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)
+	m.reader = reader
+	probetest.Start(t, m.Start)
+
+	kill := rawEvent{PID: 42, UID: 1000, TotalVM: 2048, AnonRSS: 1024, OOMScoreAdj: -500, Timestamp: 7}
+	copy(kill.Comm[:], "java")
+	stress := kill
+	copy(stress.Comm[:], "stress")
+	reader.Send(probetest.Sample(t, stress))
+	reader.Send(probetest.Sample(t, kill)[:rawEventSize-1]) // truncated: skipped
+	reader.Send(probetest.Sample(t, kill))
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if e.Type != event.TypeOOM || e.Comm != "java" || e.PID != 42 || e.UID != 1000 || e.KernelTime != 7 || e.Node != "node-a" {
+		t.Errorf("event = %+v, want java's OOM kill on node-a", e)
+	}
+	if e.NumericVal(constants.KeyTotalVMKB) != 2048 || e.NumericVal(constants.KeyOOMScoreAdj) != -500 {
+		t.Errorf("numerics = %v", e.Numeric)
+	}
+}
@@ -8,10 +8,40 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"structs"
 
 	"github.com/cilium/ebpf"
 )
 
+type bpfFlowKey struct {
+	_     structs.HostLayout
+	Saddr uint32
+	Daddr uint32
+	Sport uint16
+	Dport uint16
+}
+
+type bpfFlowState struct {
+	_     structs.HostLayout
+	Count uint64
+	Next  uint64
+}
+
+type bpfRetransmitEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Family    uint16
+	Pad       uint16
+	Pad2      uint32
+	Timestamp uint64
+	Comm      [16]int8
+	Count     uint64
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -61,13 +91,17 @@ type bpfProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
+	ExcludedPids     *ebpf.MapSpec `ebpf:"excluded_pids"`
 	RetransmitEvents *ebpf.MapSpec `ebpf:"retransmit_events"`
+	RetransmitFlows  *ebpf.MapSpec `ebpf:"retransmit_flows"`
+	RingbufLost      *ebpf.MapSpec `ebpf:"ringbuf_lost"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	UnusedRetransmitEvent *ebpf.VariableSpec `ebpf:"unused_retransmit_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -90,12 +124,18 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
+	ExcludedPids     *ebpf.Map `ebpf:"excluded_pids"`
 	RetransmitEvents *ebpf.Map `ebpf:"retransmit_events"`
+	RetransmitFlows  *ebpf.Map `ebpf:"retransmit_flows"`
+	RingbufLost      *ebpf.Map `ebpf:"ringbuf_lost"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.ExcludedPids,
 		m.RetransmitEvents,
+		m.RetransmitFlows,
+		m.RingbufLost,
 	)
 }
 
@@ -103,6 +143,7 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	UnusedRetransmitEvent *ebpf.Variable `ebpf:"unused_retransmit_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
@@ -1,3 +1,3 @@
 package retransmit
 
-//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64 bpf ../../../bpf/tcp_retransmit.c -- -I../../../bpf
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 -type retransmit_event bpf ../../../bpf/tcp_retransmit.c -- -I../../../bpf
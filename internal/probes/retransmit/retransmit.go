@@ -2,12 +2,9 @@
 package retransmit
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"time"
+	"unsafe"
 
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
@@ -19,26 +16,63 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct retransmit_event in bpf/tcp_retransmit.c.
 type rawEvent struct {
 	PID       uint32
-	UID       uint32
 	SAddr     uint32
 	DAddr     uint32
 	SPort     uint16
 	DPort     uint16
-	State     uint32
-	Pad1      uint32
+	Family    uint16
+	Pad1      uint16
+	Pad2      uint32
 	Timestamp uint64
 	Comm      [constants.CommSize]byte
+	Count     uint64 // the flow's retransmits so far; 0 from older objects
 }
 
+// decoder decodes struct retransmit_event.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct retransmit_event), which
+// bpf/tcp_retransmit.c asserts. The assignment below fails the build if
+// rawEvent is any other size.
+const rawEventSize = 56
+
+// minRawEventSize is the record size of objects built before retransmits
+// were counted per flow, which report every retransmit with no count. The
+// assignment below fails the build if Count moves.
+const minRawEventSize = 48
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.Count)]byte{}
+
+// decode parses one ring buffer record, accepting records from older
+// objects, which lack Count.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
+}
+
+// delta returns how many retransmits an event with the given flow count
+// stands for. The program reports a flow at counts 1, 10, 100, …, so the
+// previous event reported count/RetransmitEmitFactor of them. A count of 0
+// comes from an older object that reports each retransmit.
+func delta(count uint64) uint64 {
+	if count == 0 {
+		return 1
+	}
+	return count - count/constants.RetransmitEmitFactor
+}
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
 // Module implements probe.Module for TCP retransmission detection.
 type Module struct {
 	deps   probe.Dependencies
 	logger *zap.Logger
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	reader probe.RecordReader
 }
 
 // New creates a new Retransmit module instance (Factory constructor).
@@ -51,59 +85,72 @@ func (m *Module) Name() string { return constants.ModuleRetransmit }
 func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	if !deps.Kernel.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPRetransmit) {
+		return fmt.Errorf("tracepoint %s/%s: %w", constants.TracepointGroupTCP, constants.TracepointTCPRetransmit, probe.ErrUnsupported)
+	}
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
-	tp, err := link.Tracepoint("tcp", "tcp_retransmit_skb", m.objs.TracepointTcpRetransmit, nil)
+	tp, err := m.bpf.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPRetransmit, m.objs.TracepointTcpRetransmit)()
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching tracepoint: %w", err)
+		return err
 	}
 	m.links = append(m.links, tp)
-	m.reader, err = ringbuf.NewReader(m.objs.RetransmitEvents)
+	reader, err := ringbuf.NewReader(m.objs.RetransmitEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("Retransmit module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading retransmit event", zap.Error(err))
-			continue
-		}
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing retransmit event", zap.Error(err))
-			continue
-		}
-		e := event.Acquire()
-		e.Type = event.TypeRetransmit
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
+
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if !m.deps.Sampler.Keep() {
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing retransmit event", zap.Error(err))
+		return
+	}
+	comm := bpfutil.CommString(raw.Comm)
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.Comm(comm) || !m.deps.Filter.Port(raw.DPort) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeRetransmit
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	if raw.Count > 0 {
+		e.SetNumeric(constants.KeyCount, float64(raw.Count))
+	}
+	e.SetNumeric(constants.KeyDelta, float64(delta(raw.Count)))
+	e.SetLabel(constants.KeySrc, bpfutil.FormatAddr(raw.SAddr, raw.SPort))
+	e.SetLabel(constants.KeyDst, bpfutil.FormatAddr(raw.DAddr, raw.DPort))
+	m.deps.Enrich(e, raw.PID)
+	if m.deps.Metadata != nil {
+		if svc, found := m.deps.Metadata.LookupService(bpfutil.FormatIPv4(raw.DAddr)); found {
+			e.SetLabel(constants.KeyDstService, svc.String())
 		}
-		m.deps.EventBus.Publish(e)
 	}
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -114,5 +161,18 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
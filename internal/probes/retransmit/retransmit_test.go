@@ -0,0 +1,172 @@
+package retransmit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
+)
+
+// TestRawEventSize pins the decode struct to sizeof(struct retransmit_event), which
+// bpf/tcp_retransmit.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/tcp_retransmit.c", "retransmit_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfRetransmitEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfRetransmitEvent{}); got != want {
+		t.Errorf("embedded object's retransmit_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+// TestDecode_CLayout decodes a record laid out by hand at the offsets of
+// struct retransmit_event, independent of rawEvent.
+func TestDecode_CLayout(t *testing.T) {
+	rec := make([]byte, 56)
+	le := binary.LittleEndian
+	le.PutUint32(rec[0:], 4242)       // pid
+	le.PutUint32(rec[4:], 0x0100000a) // saddr 10.0.0.1
+	le.PutUint32(rec[8:], 0x0200000a) // daddr 10.0.0.2
+	le.PutUint16(rec[12:], 33000)     // sport
+	le.PutUint16(rec[14:], 443)       // dport
+	le.PutUint16(rec[16:], 2)         // family AF_INET
+	le.PutUint64(rec[24:], 123456789) // timestamp
+	copy(rec[32:], "curl")            // comm
+	le.PutUint64(rec[48:], 100)       // count
+
+	raw, err := decode(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.PID != 4242 || raw.SAddr != 0x0100000a || raw.DAddr != 0x0200000a ||
+		raw.SPort != 33000 || raw.DPort != 443 || raw.Family != 2 ||
+		raw.Timestamp != 123456789 || bpfutil.CommString(raw.Comm) != "curl" || raw.Count != 100 {
+		t.Errorf("decode = %+v", raw)
+	}
+
+	// Objects built before per-flow counting end after comm.
+	raw, err = decode(rec[:48])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raw.PID != 4242 || raw.Count != 0 {
+		t.Errorf("decode(short) = %+v", raw)
+	}
+	if _, err := decode(rec[:40]); err == nil {
+		t.Error("decode(40 bytes) succeeded, want an error")
+	}
+}
+
+func TestDelta(t *testing.T) {
+	tests := []struct {
+		count, want uint64
+	}{
+		{0, 1}, // older object: one event per retransmit
+		{1, 1},
+		{10, 9},
+		{100, 90},
+		{1000, 900},
+	}
+	for _, tt := range tests {
+		if got := delta(tt.count); got != tt.want {
+			t.Errorf("delta(%d) = %d, want %d", tt.count, got, tt.want)
+		}
+	}
+}
+
+// TestDelta_SumsToCount replays the program's reporting of one flow: it
+// reports counts 1, 10, 100, …, and the deltas of the events reported so
+// far must add up to the last count.
+func TestDelta_SumsToCount(t *testing.T) {
+	var reported []uint64
+	next := uint64(1)
+	for count := uint64(1); count <= 100000; count++ {
+		if count != next {
+			continue
+		}
+		reported = append(reported, count)
+		next = count * constants.RetransmitEmitFactor
+	}
+	if want := []uint64{1, 10, 100, 1000, 10000, 100000}; !slices.Equal(reported, want) {
+		t.Fatalf("reported counts = %v, want %v", reported, want)
+	}
+
+	var total uint64
+	for _, count := range reported {
+		total += delta(count)
+		if total != count {
+			t.Errorf("after count %d, deltas sum to %d", count, total)
+		}
+	}
+}
+
+func TestHandle(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleRetransmit, config.FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	events := bus.Subscribe("test")
+	m := &Module{logger: zap.NewNop(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)}
+
+	rec := make([]byte, rawEventSize)
+	le := binary.LittleEndian
+	le.PutUint32(rec[4:], 0x0100000a) // saddr 10.0.0.1
+	le.PutUint32(rec[8:], 0x0200000a) // daddr 10.0.0.2
+	le.PutUint16(rec[12:], 33000)
+	le.PutUint16(rec[14:], 443)
+	le.PutUint64(rec[48:], 10) // count
+	m.handle(ringbuf.Record{RawSample: rec})
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if got := e.Label(constants.KeySrc); got != "10.0.0.1:33000" {
+		t.Errorf("src = %q, want 10.0.0.1:33000", got)
+	}
+	if got := e.Label(constants.KeyDst); got != "10.0.0.2:443" {
+		t.Errorf("dst = %q, want 10.0.0.2:443", got)
+	}
+	if count, d := e.NumericVal(constants.KeyCount), e.NumericVal(constants.KeyDelta); count != 10 || d != 9 {
+		t.Errorf("count, delta = %v, %v, want 10, 9", count, d)
+	}
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
@@ -0,0 +1,163 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package rst
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpfRstEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Family    uint16
+	Pad       uint16
+	State     uint32
+	Pad2      uint32
+	Pad3      uint32
+	Timestamp uint64
+	Comm      [16]int8
+	Direction uint8
+	Pad4      [7]uint8
+}
+
+// loadBpf returns the embedded CollectionSpec for bpf.
+func loadBpf() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BpfBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpfObjects loads bpf and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpfObjects
+//	*bpfPrograms
+//	*bpfMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpfObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpfSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfSpecs struct {
+	bpfProgramSpecs
+	bpfMapSpecs
+	bpfVariableSpecs
+}
+
+// bpfProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfProgramSpecs struct {
+	TracepointTcpReceiveReset *ebpf.ProgramSpec `ebpf:"tracepoint_tcp_receive_reset"`
+	TracepointTcpSendReset    *ebpf.ProgramSpec `ebpf:"tracepoint_tcp_send_reset"`
+}
+
+// bpfMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfMapSpecs struct {
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+	RstEvents    *ebpf.MapSpec `ebpf:"rst_events"`
+}
+
+// bpfVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfVariableSpecs struct {
+	UnusedRstEvent *ebpf.VariableSpec `ebpf:"unused_rst_event"`
+}
+
+// bpfObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfObjects struct {
+	bpfPrograms
+	bpfMaps
+	bpfVariables
+}
+
+func (o *bpfObjects) Close() error {
+	return _BpfClose(
+		&o.bpfPrograms,
+		&o.bpfMaps,
+	)
+}
+
+// bpfMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfMaps struct {
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+	RstEvents    *ebpf.Map `ebpf:"rst_events"`
+}
+
+func (m *bpfMaps) Close() error {
+	return _BpfClose(
+		m.ExcludedPids,
+		m.RingbufLost,
+		m.RstEvents,
+	)
+}
+
+// bpfVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfVariables struct {
+	UnusedRstEvent *ebpf.Variable `ebpf:"unused_rst_event"`
+}
+
+// bpfPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfPrograms struct {
+	TracepointTcpReceiveReset *ebpf.Program `ebpf:"tracepoint_tcp_receive_reset"`
+	TracepointTcpSendReset    *ebpf.Program `ebpf:"tracepoint_tcp_send_reset"`
+}
+
+func (p *bpfPrograms) Close() error {
+	return _BpfClose(
+		p.TracepointTcpReceiveReset,
+		p.TracepointTcpSendReset,
+	)
+}
+
+func _BpfClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_arm64_bpfel.o
+var _BpfBytes []byte
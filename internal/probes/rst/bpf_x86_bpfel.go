@@ -8,10 +8,29 @@ import (
 	_ "embed"
 	"fmt"
 	"io"
+	"structs"
 
 	"github.com/cilium/ebpf"
 )
 
+type bpfRstEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Family    uint16
+	Pad       uint16
+	State     uint32
+	Pad2      uint32
+	Pad3      uint32
+	Timestamp uint64
+	Comm      [16]int8
+	Direction uint8
+	Pad4      [7]uint8
+}
+
 // loadBpf returns the embedded CollectionSpec for bpf.
 func loadBpf() (*ebpf.CollectionSpec, error) {
 	reader := bytes.NewReader(_BpfBytes)
@@ -54,20 +73,24 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
-	TracepointTcpSendReset *ebpf.ProgramSpec `ebpf:"tracepoint_tcp_send_reset"`
+	TracepointTcpReceiveReset *ebpf.ProgramSpec `ebpf:"tracepoint_tcp_receive_reset"`
+	TracepointTcpSendReset    *ebpf.ProgramSpec `ebpf:"tracepoint_tcp_send_reset"`
 }
 
 // bpfMapSpecs contains maps before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	RstEvents *ebpf.MapSpec `ebpf:"rst_events"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+	RstEvents    *ebpf.MapSpec `ebpf:"rst_events"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	UnusedRstEvent *ebpf.VariableSpec `ebpf:"unused_rst_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -90,11 +113,15 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	RstEvents *ebpf.Map `ebpf:"rst_events"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+	RstEvents    *ebpf.Map `ebpf:"rst_events"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.ExcludedPids,
+		m.RingbufLost,
 		m.RstEvents,
 	)
 }
@@ -103,17 +130,20 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	UnusedRstEvent *ebpf.Variable `ebpf:"unused_rst_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
-	TracepointTcpSendReset *ebpf.Program `ebpf:"tracepoint_tcp_send_reset"`
+	TracepointTcpReceiveReset *ebpf.Program `ebpf:"tracepoint_tcp_receive_reset"`
+	TracepointTcpSendReset    *ebpf.Program `ebpf:"tracepoint_tcp_send_reset"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.TracepointTcpReceiveReset,
 		p.TracepointTcpSendReset,
 	)
 }
@@ -2,12 +2,9 @@
 package rst
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"time"
+	"unsafe"
 
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
@@ -16,20 +13,64 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
+// rawEvent mirrors struct rst_event in bpf/tcp_rst.c.
 type rawEvent struct {
 	PID       uint32
-	UID       uint32
 	SAddr     uint32
 	DAddr     uint32
 	SPort     uint16
 	DPort     uint16
+	Family    uint16
+	Pad1      uint16
 	State     uint32
-	Pad1      uint32
+	Pad2      uint32
+	Pad3      uint32
 	Timestamp uint64
 	Comm      [constants.CommSize]byte
+	Direction uint8 // rstSent or rstReceived
+	Pad4      [7]uint8
+}
+
+// Values of rawEvent.Direction.
+const (
+	rstSent     = 0
+	rstReceived = 1
+)
+
+// decoder decodes struct rst_event. Objects generated before the
+// tracer reported received resets emit only the fields before Direction,
+// which decode as sent.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct rst_event), which bpf/tcp_rst.c asserts.
+// The assignment below fails the build if rawEvent is any other size.
+const rawEventSize = 64
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// minRawEventSize is the record size of objects generated before the
+// tracer reported received resets: the fields before Direction. The
+// assignment below fails the build if Direction moves.
+const minRawEventSize = 56
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.Direction)]byte{}
+
+// decode parses one ring buffer record, zero-padding records from older
+// objects and rejecting any shorter than those.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
+}
+
+// direction names which way the reset went.
+func (r rawEvent) direction() string {
+	if r.Direction == rstReceived {
+		return constants.RSTDirectionReceived
+	}
+	return constants.RSTDirectionSent
 }
 
 // Module implements probe.Module for TCP connection reset detection.
@@ -37,8 +78,9 @@ type Module struct {
 	deps   probe.Dependencies
 	logger *zap.Logger
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	reader probe.RecordReader
 }
 
 // New creates a new RST module instance (Factory constructor).
@@ -51,59 +93,94 @@ func (m *Module) Name() string { return constants.ModuleRST }
 func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	if !deps.Kernel.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPSendReset) {
+		return fmt.Errorf("tracepoint %s/%s: %w", constants.TracepointGroupTCP, constants.TracepointTCPSendReset, probe.ErrUnsupported)
+	}
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
-	tp, err := link.Tracepoint("tcp", "tcp_send_reset", m.objs.TracepointTcpSendReset, nil)
+	tp, err := m.bpf.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPSendReset, m.objs.TracepointTcpSendReset)()
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching tracepoint: %w", err)
+		return err
 	}
 	m.links = append(m.links, tp)
-	m.reader, err = ringbuf.NewReader(m.objs.RstEvents)
+	if err := m.attachReceive(deps.Kernel); err != nil {
+		m.Stop(context.Background())
+		return err
+	}
+	reader, err := ringbuf.NewReader(m.objs.RstEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
+	return nil
+}
+
+// attachReceive hooks tcp_receive_reset where both the kernel and the
+// BPF object have it; without it only sent resets are reported.
+func (m *Module) attachReceive(k *kernelfeat.Features) error {
+	prog := m.bpf.Programs[constants.BPFProgTracepointTCPReceiveReset]
+	if prog == nil {
+		m.logger.Warn("BPF object has no tcp_receive_reset program — only sent resets will be reported")
+		return nil
+	}
+	if !k.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPReceiveReset) {
+		m.logger.Warn("tcp_receive_reset tracepoint unavailable — only sent resets will be reported")
+		return nil
+	}
+	tp, err := m.bpf.Tracepoint(constants.TracepointGroupTCP, constants.TracepointTCPReceiveReset, prog)()
+	if err != nil {
+		return err
+	}
+	m.links = append(m.links, tp)
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("RST module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading RST event", zap.Error(err))
-			continue
-		}
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing RST event", zap.Error(err))
-			continue
-		}
-		e := event.Acquire()
-		e.Type = event.TypeRST
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
+
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if !m.deps.Sampler.Keep() {
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing RST event", zap.Error(err))
+		return
+	}
+	comm := bpfutil.CommString(raw.Comm)
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.Comm(comm) || !m.deps.Filter.Port(raw.DPort) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeRST
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	e.SetLabel(constants.KeyDirection, raw.direction())
+	e.SetLabel(constants.KeyState, bpfutil.TCPStateString(raw.State))
+	e.SetLabel(constants.KeySrc, bpfutil.FormatAddr(raw.SAddr, raw.SPort))
+	e.SetLabel(constants.KeyDst, bpfutil.FormatAddr(raw.DAddr, raw.DPort))
+	m.deps.Enrich(e, raw.PID)
+	if m.deps.Metadata != nil {
+		if svc, found := m.deps.Metadata.LookupService(bpfutil.FormatIPv4(raw.DAddr)); found {
+			e.SetLabel(constants.KeyDstService, svc.String())
 		}
-		m.deps.EventBus.Publish(e)
 	}
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -114,5 +191,18 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
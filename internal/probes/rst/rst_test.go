@@ -0,0 +1,184 @@
+package rst
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
+)
+
+// TestRawEventSize pins the decode struct to sizeof(struct rst_event), which
+// bpf/tcp_rst.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/tcp_rst.c", "rst_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfRstEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfRstEvent{}); got != want {
+		t.Errorf("embedded object's rst_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestTCPStateString(t *testing.T) {
+	tests := []struct {
+		state uint32
+		want  string
+	}{
+		{1, "ESTABLISHED"},
+		{2, "SYN_SENT"},
+		{3, "SYN_RECV"},
+		{7, "CLOSE"},
+		{8, "CLOSE_WAIT"},
+		{10, "LISTEN"},
+		{12, "NEW_SYN_RECV"},
+		{0, "STATE_0"},
+		{99, "STATE_99"},
+	}
+	for _, tt := range tests {
+		if got := bpfutil.TCPStateString(tt.state); got != tt.want {
+			t.Errorf("TCPStateString(%d) = %q, want %q", tt.state, got, tt.want)
+		}
+	}
+}
+
+func TestDecode_Direction(t *testing.T) {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, rawEvent{PID: 7, State: 1, Direction: rstReceived}); err != nil {
+		t.Fatal(err)
+	}
+	full := buf.Bytes()
+
+	raw := mustDecode(t, full)
+	if raw.PID != 7 || raw.State != 1 || raw.direction() != constants.RSTDirectionReceived {
+		t.Errorf("decode = %+v, direction %q", raw, raw.direction())
+	}
+
+	// Objects built before tcp_receive_reset was traced end after Comm.
+	raw = mustDecode(t, full[:56])
+	if raw.PID != 7 || raw.direction() != constants.RSTDirectionSent {
+		t.Errorf("decode(short) = %+v, direction %q", raw, raw.direction())
+	}
+}
+
+// TestDecode_CLayout decodes a record laid out by hand at the offsets of
+// struct rst_event, independent of rawEvent.
+func TestDecode_CLayout(t *testing.T) {
+	rec := make([]byte, 64)
+	le := binary.LittleEndian
+	le.PutUint32(rec[0:], 4242)       // pid
+	le.PutUint32(rec[4:], 0x0100000a) // saddr 10.0.0.1
+	le.PutUint32(rec[8:], 0x0200000a) // daddr 10.0.0.2
+	le.PutUint16(rec[12:], 8080)      // sport
+	le.PutUint16(rec[14:], 51000)     // dport
+	le.PutUint16(rec[16:], 2)         // family AF_INET
+	le.PutUint32(rec[20:], 1)         // state TCP_ESTABLISHED
+	le.PutUint64(rec[32:], 123456789) // timestamp
+	copy(rec[40:], "nginx")           // comm
+	rec[56] = rstReceived             // direction
+
+	raw := mustDecode(t, rec)
+	if raw.PID != 4242 || raw.SAddr != 0x0100000a || raw.DAddr != 0x0200000a ||
+		raw.SPort != 8080 || raw.DPort != 51000 || raw.Family != 2 || raw.State != 1 ||
+		raw.Timestamp != 123456789 || bpfutil.CommString(raw.Comm) != "nginx" ||
+		raw.direction() != constants.RSTDirectionReceived {
+		t.Errorf("decode = %+v", raw)
+	}
+}
+
+func TestHandle_Labels(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleRST, config.FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	events := bus.Subscribe("test")
+	m := &Module{logger: zap.NewNop(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)}
+
+	var buf bytes.Buffer
+	raw := rawEvent{SAddr: 0x0100000a, DAddr: 0x0200000a, SPort: 8080, DPort: 51000, State: 2, Direction: rstReceived}
+	if err := binary.Write(&buf, binary.LittleEndian, raw); err != nil {
+		t.Fatal(err)
+	}
+	m.handle(ringbuf.Record{RawSample: buf.Bytes()})
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	want := map[string]string{
+		constants.KeySrc:       "10.0.0.1:8080",
+		constants.KeyDst:       "10.0.0.2:51000",
+		constants.KeyState:     "SYN_SENT",
+		constants.KeyDirection: constants.RSTDirectionReceived,
+	}
+	for k, v := range want {
+		if got := e.Label(k); got != v {
+			t.Errorf("label %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestInit_NoTracepoint(t *testing.T) {
+	root := t.TempDir()
+	if err := os.Mkdir(filepath.Join(root, "events"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	kernel := kernelfeat.Source{TracefsDirs: []string{root}}.Detect()
+	deps := probe.NewDependencies(zap.NewNop(), nil, nil, nil, "", nil, nil, kernel, nil)
+
+	err := New().Init(context.Background(), deps)
+	if !errors.Is(err, probe.ErrUnsupported) {
+		t.Errorf("Init() = %v, want ErrUnsupported", err)
+	}
+}
+
+func mustDecode(t *testing.T, sample []byte) rawEvent {
+	t.Helper()
+	raw, err := decode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
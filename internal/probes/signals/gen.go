@@ -0,0 +1,3 @@
+package signals
+
+//go:generate go run github.com/cilium/ebpf/cmd/bpf2go -cc clang -cflags "-O2 -g -Wall -Werror" -target amd64,arm64 -type signal_event bpf ../../../bpf/signal_tracer.c -- -I../../../bpf
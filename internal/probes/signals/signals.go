@@ -0,0 +1,185 @@
+// Package signals implements the fatal signal tracking module.
+package signals
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+)
+
+// rawEvent mirrors struct signal_event in bpf/signal_tracer.c.
+type rawEvent struct {
+	SenderPID  uint32
+	SenderUID  uint32
+	TargetPID  uint32
+	Sig        uint32
+	Timestamp  uint64
+	SenderComm [constants.CommSize]byte
+	TargetComm [constants.CommSize]byte
+}
+
+// decoder decodes struct signal_event.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct signal_event), which bpf/signal_tracer.c
+// asserts. The assignment below fails the build if rawEvent is any other
+// size.
+const rawEventSize = 56
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// signalMask is the BPF program's signal_mask for signals: bit n reports
+// signal n.
+func signalMask(signals map[uint32]string) uint64 {
+	var mask uint64
+	for sig := range signals {
+		if sig < 64 {
+			mask |= 1 << sig
+		}
+	}
+	return mask
+}
+
+// signalName names a reported signal, e.g. "SIGKILL".
+func signalName(sig uint32) string {
+	if name, ok := constants.TracedSignals[sig]; ok {
+		return name
+	}
+	return "SIG" + strconv.FormatUint(uint64(sig), 10)
+}
+
+// Module implements probe.Module for fatal signal tracking.
+type Module struct {
+	deps   probe.Dependencies
+	logger *zap.Logger
+	objs   bpfObjects
+	bpf    *bpfutil.Resources
+	links  []link.Link
+	reader probe.RecordReader
+}
+
+// New creates a new Signals module instance (Factory constructor).
+func New() *Module {
+	return &Module{}
+}
+
+func (m *Module) Name() string { return constants.ModuleSignals }
+
+func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
+	m.deps = deps
+	m.logger = deps.Logger
+	if !deps.Kernel.Tracepoint(constants.TracepointGroupSignal, constants.TracepointSignalGenerate) {
+		return fmt.Errorf("tracepoint %s/%s: %w", constants.TracepointGroupSignal, constants.TracepointSignalGenerate, probe.ErrUnsupported)
+	}
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	if err := setSignalMask(spec); err != nil {
+		return fmt.Errorf("setting %s: %w", constants.BPFVarSignalMask, err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	if m.bpf, err = pins.LoadObjects(spec, &m.objs); err != nil {
+		return fmt.Errorf("loading BPF objects: %w", err)
+	}
+	tp, err := m.bpf.Tracepoint(constants.TracepointGroupSignal, constants.TracepointSignalGenerate, m.objs.TracepointSignalGenerate)()
+	if err != nil {
+		m.Stop(context.Background())
+		return err
+	}
+	m.links = append(m.links, tp)
+	reader, err := ringbuf.NewReader(m.objs.SignalEvents)
+	if err != nil {
+		m.Stop(context.Background())
+		return fmt.Errorf("creating ring buffer reader: %w", err)
+	}
+	m.reader = reader
+	return nil
+}
+
+// setSignalMask limits the BPF program to constants.TracedSignals.
+func setSignalMask(spec *ebpf.CollectionSpec) error {
+	v, ok := spec.Variables[constants.BPFVarSignalMask]
+	if !ok {
+		return fmt.Errorf("BPF object has no %s", constants.BPFVarSignalMask)
+	}
+	return v.Set(signalMask(constants.TracedSignals))
+}
+
+func (m *Module) Start(ctx context.Context) error {
+	m.logger.Info("Signals module consumer started")
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
+
+// handle publishes the event in one ring buffer record. The event
+// describes the target: its PID, name and pod. The sender is carried in
+// labels, with its own pod when it has one.
+func (m *Module) handle(record ringbuf.Record) {
+	if m.deps.Sampler.Paused() { // never sampled, only paused
+		return
+	}
+	raw, err := decoder.Decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing signal event", zap.Error(err))
+		return
+	}
+	comm := bpfutil.CommString(raw.TargetComm)
+	if !m.deps.Filter.PID(raw.SenderPID) || !m.deps.Filter.Comm(comm) {
+		return
+	}
+
+	e := event.Acquire()
+	e.Type = event.TypeSignal
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.TargetPID
+	e.UID = raw.SenderUID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+	e.SetLabel(constants.KeySignal, signalName(raw.Sig))
+	e.SetLabel(constants.KeySenderComm, bpfutil.CommString(raw.SenderComm))
+	e.SetNumeric(constants.KeySenderPID, float64(raw.SenderPID))
+	m.deps.Enrich(e, raw.TargetPID)
+	if m.deps.Metadata != nil {
+		if meta, found := m.deps.Metadata.Lookup(raw.SenderPID); found {
+			e.SetLabel(constants.KeySenderNamespace, meta.Namespace)
+			e.SetLabel(constants.KeySenderPod, meta.PodName)
+		}
+	}
+	m.deps.EventBus.Publish(e)
+}
+
+func (m *Module) Stop(_ context.Context) error {
+	if m.reader != nil {
+		m.reader.Close()
+	}
+	for _, l := range m.links {
+		l.Close()
+	}
+	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
+	return nil
+}
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
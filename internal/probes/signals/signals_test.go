@@ -0,0 +1,158 @@
+package signals
+
+import (
+	"bytes"
+	"encoding/binary"
+	"syscall"
+	"testing"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
+)
+
+func TestNew(t *testing.T) {
+	m := New()
+	if m.Name() != constants.ModuleSignals {
+		t.Errorf("Name() = %q, want %q", m.Name(), constants.ModuleSignals)
+	}
+}
+
+// TestRawEventSize pins the decode struct to sizeof(struct signal_event),
+// which bpf/signal_tracer.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/signal_tracer.c", "signal_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfSignalEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfSignalEvent{}); got != want {
+		t.Errorf("embedded object's signal_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// A SIGTERM from the kubelet (pid 812) to pid 4321, as the program
+	// writes it.
+	sample := []byte{
+		0x2c, 0x03, 0x00, 0x00, // sender_pid 812
+		0x00, 0x00, 0x00, 0x00, // sender_uid 0
+		0xe1, 0x10, 0x00, 0x00, // target_pid 4321
+		0x0f, 0x00, 0x00, 0x00, // sig 15
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // timestamp
+		'k', 'u', 'b', 'e', 'l', 'e', 't', 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		'n', 'g', 'i', 'n', 'x', 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+	var raw rawEvent
+	if err := binary.Read(bytes.NewReader(sample), binary.LittleEndian, &raw); err != nil {
+		t.Fatal(err)
+	}
+	if raw.SenderPID != 812 || raw.TargetPID != 4321 || signalName(raw.Sig) != "SIGTERM" {
+		t.Errorf("decode = %+v", raw)
+	}
+	if s, d := bpfutil.CommString(raw.SenderComm), bpfutil.CommString(raw.TargetComm); s != "kubelet" || d != "nginx" {
+		t.Errorf("comms = %q -> %q, want kubelet -> nginx", s, d)
+	}
+}
+
+func TestHandle(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleSignals, config.FilterConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(1, nil)
+	events := bus.Subscribe("test")
+	sampler := probe.NewSampler(1)
+	sampler.SetScale(0) // signals are never sampled, even under adaptive backoff
+	m := &Module{logger: zap.NewNop(), deps: probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{},
+		bus, nil, "node-a", sampler, filter, nil, nil)}
+
+	raw := rawEvent{SenderPID: 812, TargetPID: 4321, Sig: uint32(syscall.SIGTERM)}
+	copy(raw.SenderComm[:], "kubelet")
+	copy(raw.TargetComm[:], "nginx")
+	m.handle(ringbuf.Record{RawSample: probetest.Sample(t, raw)})
+
+	e := probetest.Next(t, events)
+	defer e.Done()
+	if e.PID != 4321 || e.Comm != "nginx" || e.Label(constants.KeySignal) != "SIGTERM" {
+		t.Errorf("event = pid %d %q %q, want the target 4321 nginx and SIGTERM", e.PID, e.Comm, e.Label(constants.KeySignal))
+	}
+	if e.Label(constants.KeySenderComm) != "kubelet" || e.NumericVal(constants.KeySenderPID) != 812 {
+		t.Errorf("sender = %q %v, want kubelet 812", e.Label(constants.KeySenderComm), e.NumericVal(constants.KeySenderPID))
+	}
+}
+
+func TestSignalMask(t *testing.T) {
+	mask := signalMask(constants.TracedSignals)
+	for _, sig := range []syscall.Signal{syscall.SIGKILL, syscall.SIGTERM, syscall.SIGSEGV, syscall.SIGABRT} {
+		if mask&(1<<sig) == 0 {
+			t.Errorf("%v not in signal mask %#x", sig, mask)
+		}
+	}
+	for _, sig := range []syscall.Signal{syscall.SIGCHLD, syscall.SIGHUP, syscall.SIGINT, syscall.SIGUSR1} {
+		if mask&(1<<sig) != 0 {
+			t.Errorf("%v in signal mask %#x", sig, mask)
+		}
+	}
+	if got := signalMask(map[uint32]string{9: "SIGKILL", 64: "out of range"}); got != 1<<9 {
+		t.Errorf("signalMask ignoring out-of-range = %#x, want %#x", got, 1<<9)
+	}
+}
+
+func TestSignalName(t *testing.T) {
+	for sig, want := range map[uint32]string{9: "SIGKILL", 6: "SIGABRT", 10: "SIG10"} {
+		if got := signalName(sig); got != want {
+			t.Errorf("signalName(%d) = %q, want %q", sig, got, want)
+		}
+	}
+}
+
+func TestSetSignalMask_PrebuiltObject(t *testing.T) {
+	spec, err := loadBpf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := setSignalMask(spec); err != nil {
+		t.Fatal(err)
+	}
+	var got uint64
+	if err := spec.Variables[constants.BPFVarSignalMask].Get(&got); err != nil {
+		t.Fatal(err)
+	}
+	if want := signalMask(constants.TracedSignals); got != want {
+		t.Errorf("signal_mask = %#x, want %#x", got, want)
+	}
+}
+
+// FuzzDecode feeds the decoder records of any length: it must reject
+// those shorter than struct signal_event, and decode the rest as binary.Read
+// does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, rawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decoder.Decode(data)
+		if (err == nil) != (len(data) >= rawEventSize) {
+			t.Fatalf("Decode(%d bytes) error = %v", len(data), err)
+		}
+		if err == nil {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("Decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
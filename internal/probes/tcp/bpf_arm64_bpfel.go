@@ -0,0 +1,197 @@
+// Code generated by bpf2go; DO NOT EDIT.
+//go:build arm64
+
+package tcp
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"structs"
+
+	"github.com/cilium/ebpf"
+)
+
+type bpfConnVal struct {
+	_           structs.HostLayout
+	StartNs     uint64
+	Saddr       uint32
+	Daddr       uint32
+	Sport       uint16
+	Dport       uint16
+	Uid         uint32
+	Pid         uint32
+	Established uint8
+	Comm        [16]int8
+	_           [3]byte
+}
+
+type bpfTcpEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Uid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Pad       uint32
+	LatencyNs uint64
+	Timestamp uint64
+	Comm      [16]int8
+	Failed    uint8
+	Role      uint8
+	Pad2      [2]uint8
+	SkErr     uint32
+}
+
+// loadBpf returns the embedded CollectionSpec for bpf.
+func loadBpf() (*ebpf.CollectionSpec, error) {
+	reader := bytes.NewReader(_BpfBytes)
+	spec, err := ebpf.LoadCollectionSpecFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("can't load bpf: %w", err)
+	}
+
+	return spec, err
+}
+
+// loadBpfObjects loads bpf and converts it into a struct.
+//
+// The following types are suitable as obj argument:
+//
+//	*bpfObjects
+//	*bpfPrograms
+//	*bpfMaps
+//
+// See ebpf.CollectionSpec.LoadAndAssign documentation for details.
+func loadBpfObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
+	spec, err := loadBpf()
+	if err != nil {
+		return err
+	}
+
+	return spec.LoadAndAssign(obj, opts)
+}
+
+// bpfSpecs contains maps and programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfSpecs struct {
+	bpfProgramSpecs
+	bpfMapSpecs
+	bpfVariableSpecs
+}
+
+// bpfProgramSpecs contains programs before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfProgramSpecs struct {
+	FentryTcpClose             *ebpf.ProgramSpec `ebpf:"fentry_tcp_close"`
+	FentryTcpConnect           *ebpf.ProgramSpec `ebpf:"fentry_tcp_connect"`
+	KprobeTcpClose             *ebpf.ProgramSpec `ebpf:"kprobe_tcp_close"`
+	KprobeTcpConnect           *ebpf.ProgramSpec `ebpf:"kprobe_tcp_connect"`
+	KretprobeInetCskAccept     *ebpf.ProgramSpec `ebpf:"kretprobe_inet_csk_accept"`
+	TracepointInetSockSetState *ebpf.ProgramSpec `ebpf:"tracepoint_inet_sock_set_state"`
+}
+
+// bpfMapSpecs contains maps before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfMapSpecs struct {
+	AcceptStart  *ebpf.MapSpec `ebpf:"accept_start"`
+	ConnStart    *ebpf.MapSpec `ebpf:"conn_start"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+	TcpEvents    *ebpf.MapSpec `ebpf:"tcp_events"`
+}
+
+// bpfVariableSpecs contains global variables before they are loaded into the kernel.
+//
+// It can be passed ebpf.CollectionSpec.Assign.
+type bpfVariableSpecs struct {
+	TrackState     *ebpf.VariableSpec `ebpf:"track_state"`
+	UnusedTcpEvent *ebpf.VariableSpec `ebpf:"unused_tcp_event"`
+}
+
+// bpfObjects contains all objects after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfObjects struct {
+	bpfPrograms
+	bpfMaps
+	bpfVariables
+}
+
+func (o *bpfObjects) Close() error {
+	return _BpfClose(
+		&o.bpfPrograms,
+		&o.bpfMaps,
+	)
+}
+
+// bpfMaps contains all maps after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfMaps struct {
+	AcceptStart  *ebpf.Map `ebpf:"accept_start"`
+	ConnStart    *ebpf.Map `ebpf:"conn_start"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+	TcpEvents    *ebpf.Map `ebpf:"tcp_events"`
+}
+
+func (m *bpfMaps) Close() error {
+	return _BpfClose(
+		m.AcceptStart,
+		m.ConnStart,
+		m.ExcludedPids,
+		m.RingbufLost,
+		m.TcpEvents,
+	)
+}
+
+// bpfVariables contains all global variables after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfVariables struct {
+	TrackState     *ebpf.Variable `ebpf:"track_state"`
+	UnusedTcpEvent *ebpf.Variable `ebpf:"unused_tcp_event"`
+}
+
+// bpfPrograms contains all programs after they have been loaded into the kernel.
+//
+// It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
+type bpfPrograms struct {
+	FentryTcpClose             *ebpf.Program `ebpf:"fentry_tcp_close"`
+	FentryTcpConnect           *ebpf.Program `ebpf:"fentry_tcp_connect"`
+	KprobeTcpClose             *ebpf.Program `ebpf:"kprobe_tcp_close"`
+	KprobeTcpConnect           *ebpf.Program `ebpf:"kprobe_tcp_connect"`
+	KretprobeInetCskAccept     *ebpf.Program `ebpf:"kretprobe_inet_csk_accept"`
+	TracepointInetSockSetState *ebpf.Program `ebpf:"tracepoint_inet_sock_set_state"`
+}
+
+func (p *bpfPrograms) Close() error {
+	return _BpfClose(
+		p.FentryTcpClose,
+		p.FentryTcpConnect,
+		p.KprobeTcpClose,
+		p.KprobeTcpConnect,
+		p.KretprobeInetCskAccept,
+		p.TracepointInetSockSetState,
+	)
+}
+
+func _BpfClose(closers ...io.Closer) error {
+	for _, closer := range closers {
+		if err := closer.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Do not access this directly.
+//
+//go:embed bpf_arm64_bpfel.o
+var _BpfBytes []byte
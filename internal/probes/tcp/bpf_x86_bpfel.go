@@ -13,21 +13,36 @@ import (
 	"github.com/cilium/ebpf"
 )
 
-type bpfConnKey struct {
-	_       structs.HostLayout
-	Pid     uint32
-	_       [4]byte
-	SockPtr uint64
-}
-
 type bpfConnVal struct {
-	_       structs.HostLayout
-	StartNs uint64
-	Saddr   uint32
-	Daddr   uint32
-	Sport   uint16
-	Dport   uint16
-	Uid     uint32
+	_           structs.HostLayout
+	StartNs     uint64
+	Saddr       uint32
+	Daddr       uint32
+	Sport       uint16
+	Dport       uint16
+	Uid         uint32
+	Pid         uint32
+	Established uint8
+	Comm        [16]int8
+	_           [3]byte
+}
+
+type bpfTcpEvent struct {
+	_         structs.HostLayout
+	Pid       uint32
+	Uid       uint32
+	Saddr     uint32
+	Daddr     uint32
+	Sport     uint16
+	Dport     uint16
+	Pad       uint32
+	LatencyNs uint64
+	Timestamp uint64
+	Comm      [16]int8
+	Failed    uint8
+	Role      uint8
+	Pad2      [2]uint8
+	SkErr     uint32
 }
 
 // loadBpf returns the embedded CollectionSpec for bpf.
@@ -72,22 +87,31 @@ type bpfSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfProgramSpecs struct {
-	KprobeTcpClose   *ebpf.ProgramSpec `ebpf:"kprobe_tcp_close"`
-	KprobeTcpConnect *ebpf.ProgramSpec `ebpf:"kprobe_tcp_connect"`
+	FentryTcpClose             *ebpf.ProgramSpec `ebpf:"fentry_tcp_close"`
+	FentryTcpConnect           *ebpf.ProgramSpec `ebpf:"fentry_tcp_connect"`
+	KprobeTcpClose             *ebpf.ProgramSpec `ebpf:"kprobe_tcp_close"`
+	KprobeTcpConnect           *ebpf.ProgramSpec `ebpf:"kprobe_tcp_connect"`
+	KretprobeInetCskAccept     *ebpf.ProgramSpec `ebpf:"kretprobe_inet_csk_accept"`
+	TracepointInetSockSetState *ebpf.ProgramSpec `ebpf:"tracepoint_inet_sock_set_state"`
 }
 
 // bpfMapSpecs contains maps before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfMapSpecs struct {
-	ConnStart *ebpf.MapSpec `ebpf:"conn_start"`
-	TcpEvents *ebpf.MapSpec `ebpf:"tcp_events"`
+	AcceptStart  *ebpf.MapSpec `ebpf:"accept_start"`
+	ConnStart    *ebpf.MapSpec `ebpf:"conn_start"`
+	ExcludedPids *ebpf.MapSpec `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.MapSpec `ebpf:"ringbuf_lost"`
+	TcpEvents    *ebpf.MapSpec `ebpf:"tcp_events"`
 }
 
 // bpfVariableSpecs contains global variables before they are loaded into the kernel.
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type bpfVariableSpecs struct {
+	TrackState     *ebpf.VariableSpec `ebpf:"track_state"`
+	UnusedTcpEvent *ebpf.VariableSpec `ebpf:"unused_tcp_event"`
 }
 
 // bpfObjects contains all objects after they have been loaded into the kernel.
@@ -110,13 +134,19 @@ func (o *bpfObjects) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfMaps struct {
-	ConnStart *ebpf.Map `ebpf:"conn_start"`
-	TcpEvents *ebpf.Map `ebpf:"tcp_events"`
+	AcceptStart  *ebpf.Map `ebpf:"accept_start"`
+	ConnStart    *ebpf.Map `ebpf:"conn_start"`
+	ExcludedPids *ebpf.Map `ebpf:"excluded_pids"`
+	RingbufLost  *ebpf.Map `ebpf:"ringbuf_lost"`
+	TcpEvents    *ebpf.Map `ebpf:"tcp_events"`
 }
 
 func (m *bpfMaps) Close() error {
 	return _BpfClose(
+		m.AcceptStart,
 		m.ConnStart,
+		m.ExcludedPids,
+		m.RingbufLost,
 		m.TcpEvents,
 	)
 }
@@ -125,20 +155,30 @@ func (m *bpfMaps) Close() error {
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfVariables struct {
+	TrackState     *ebpf.Variable `ebpf:"track_state"`
+	UnusedTcpEvent *ebpf.Variable `ebpf:"unused_tcp_event"`
 }
 
 // bpfPrograms contains all programs after they have been loaded into the kernel.
 //
 // It can be passed to loadBpfObjects or ebpf.CollectionSpec.LoadAndAssign.
 type bpfPrograms struct {
-	KprobeTcpClose   *ebpf.Program `ebpf:"kprobe_tcp_close"`
-	KprobeTcpConnect *ebpf.Program `ebpf:"kprobe_tcp_connect"`
+	FentryTcpClose             *ebpf.Program `ebpf:"fentry_tcp_close"`
+	FentryTcpConnect           *ebpf.Program `ebpf:"fentry_tcp_connect"`
+	KprobeTcpClose             *ebpf.Program `ebpf:"kprobe_tcp_close"`
+	KprobeTcpConnect           *ebpf.Program `ebpf:"kprobe_tcp_connect"`
+	KretprobeInetCskAccept     *ebpf.Program `ebpf:"kretprobe_inet_csk_accept"`
+	TracepointInetSockSetState *ebpf.Program `ebpf:"tracepoint_inet_sock_set_state"`
 }
 
 func (p *bpfPrograms) Close() error {
 	return _BpfClose(
+		p.FentryTcpClose,
+		p.FentryTcpConnect,
 		p.KprobeTcpClose,
 		p.KprobeTcpConnect,
+		p.KretprobeInetCskAccept,
+		p.TracepointInetSockSetState,
 	)
 }
 
@@ -2,13 +2,13 @@
 package tcp
 
 import (
-	"bytes"
 	"context"
-	"encoding/binary"
-	"errors"
 	"fmt"
-	"time"
+	"strings"
+	"syscall"
+	"unsafe"
 
+	"github.com/cilium/ebpf"
 	"github.com/cilium/ebpf/link"
 	"github.com/cilium/ebpf/ringbuf"
 	"go.uber.org/zap"
@@ -16,10 +16,11 @@ import (
 	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
 	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
 	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
 )
 
-// rawEvent is the BPF-side event struct (byte-identical to C definition).
+// rawEvent mirrors struct tcp_event in bpf/tcp_tracer.c.
 type rawEvent struct {
 	PID       uint32
 	UID       uint32
@@ -27,9 +28,59 @@ type rawEvent struct {
 	DAddr     uint32
 	SPort     uint16
 	DPort     uint16
+	Pad1      uint32
 	LatencyNs uint64
 	Timestamp uint64
 	Comm      [constants.CommSize]byte
+	Failed    uint8 // the handshake failed; LatencyNs is not a latency
+	Role      uint8 // roleServer: LatencyNs is time in the accept queue
+	Pad2      [2]byte
+	SkErr     uint32 // errno of a failed connect, 0 if the app closed it
+}
+
+// Values of rawEvent.Role.
+const (
+	roleClient = 0
+	roleServer = 1
+)
+
+// decoder decodes struct tcp_event. Objects generated before the
+// tracer reported connect failures emit only the fields before Failed,
+// which decode as successes.
+var decoder = bpfutil.NewDecoder[rawEvent]()
+
+// rawEventSize is sizeof(struct tcp_event), which bpf/tcp_tracer.c asserts.
+// The assignment below fails the build if rawEvent is any other size.
+const rawEventSize = 64
+
+var _ [rawEventSize]byte = [unsafe.Sizeof(rawEvent{})]byte{}
+
+// minRawEventSize is the record size of objects generated before the
+// tracer reported connect failures: the fields before Failed. The
+// assignment below fails the build if Failed moves.
+const minRawEventSize = 56
+
+var _ [minRawEventSize]byte = [unsafe.Offsetof(rawEvent{}.Failed)]byte{}
+
+// decode parses one ring buffer record, zero-padding records from older
+// objects and rejecting any shorter than those.
+func decode(sample []byte) (rawEvent, error) {
+	return decoder.DecodeMin(sample, minRawEventSize)
+}
+
+// connectError classifies a failed connect by the socket's errno.
+func connectError(errno uint32) string {
+	switch syscall.Errno(errno) {
+	case 0:
+		return constants.ConnectErrorAborted
+	case syscall.ECONNREFUSED:
+		return constants.ConnectErrorRefused
+	case syscall.ETIMEDOUT:
+		return constants.ConnectErrorTimeout
+	case syscall.EHOSTUNREACH, syscall.ENETUNREACH:
+		return constants.ConnectErrorUnreachable
+	}
+	return constants.ConnectErrorOther
 }
 
 // Module implements probe.Module for TCP connection latency monitoring.
@@ -38,13 +89,16 @@ type Module struct {
 	logger *zap.Logger
 
 	objs   bpfObjects
+	bpf    *bpfutil.Resources
 	links  []link.Link
-	reader *ringbuf.Reader
+	mode   string // constants.AttachMode*
+	reader probe.RecordReader
+	comms  *bpfutil.Interner
 }
 
 // New creates a new TCP module instance (Factory constructor).
 func New() *Module {
-	return &Module{}
+	return &Module{comms: bpfutil.NewInterner()}
 }
 
 func (m *Module) Name() string { return constants.ModuleTCP }
@@ -53,79 +107,131 @@ func (m *Module) Init(_ context.Context, deps probe.Dependencies) error {
 	m.deps = deps
 	m.logger = deps.Logger
 
-	if err := loadBpfObjects(&m.objs, nil); err != nil {
+	spec, err := loadBpf()
+	if err != nil {
+		return fmt.Errorf("loading BPF spec: %w", err)
+	}
+	trackState, err := setTrackState(spec, deps.Kernel, m.logger)
+	if err != nil {
+		return fmt.Errorf("setting %s: %w", constants.BPFVarTrackState, err)
+	}
+	pins := deps.Pins.Open(m.Name(), spec)
+	m.bpf, err = pins.LoadObjectsOptional(m.logger, spec, &m.objs, deps.Kernel.Fentry(),
+		constants.BPFProgFentryTCPConnect, constants.BPFProgFentryTCPClose)
+	if err != nil {
 		return fmt.Errorf("loading BPF objects: %w", err)
 	}
 
-	kpConnect, err := link.Kprobe("tcp_connect", m.objs.KprobeTcpConnect, nil)
+	m.mode, m.links, err = bpfutil.AttachFirst(m.logger,
+		bpfutil.Attachment{Mode: constants.AttachModeFentry, Hooks: []bpfutil.Hook{
+			m.bpf.Tracing(constants.BPFProgFentryTCPConnect),
+			m.bpf.Tracing(constants.BPFProgFentryTCPClose),
+		}},
+		bpfutil.Attachment{Mode: constants.AttachModeKprobe, Hooks: []bpfutil.Hook{
+			m.bpf.Kprobe(constants.KprobeTCPConnect, m.objs.KprobeTcpConnect),
+			m.bpf.Kprobe(constants.KprobeTCPClose, m.objs.KprobeTcpClose),
+		}},
+	)
 	if err != nil {
 		m.Stop(context.Background())
-		return fmt.Errorf("attaching tcp_connect kprobe: %w", err)
+		return err
 	}
-	m.links = append(m.links, kpConnect)
+	m.logger.Info("TCP hooks attached", zap.String("mode", m.mode))
 
-	kpClose, err := link.Kprobe("tcp_close", m.objs.KprobeTcpClose, nil)
-	if err != nil {
-		m.Stop(context.Background())
-		return fmt.Errorf("attaching tcp_close kprobe: %w", err)
+	if trackState {
+		tp, err := m.bpf.Tracepoint(constants.TracepointGroupSock, constants.TracepointInetSockSetState,
+			m.bpf.Programs[constants.BPFProgTracepointInetSockSetState])()
+		if err != nil {
+			m.Stop(context.Background())
+			return err
+		}
+		m.links = append(m.links, tp)
+
+		if prog := m.bpf.Programs[constants.BPFProgKretprobeInetCskAccept]; prog != nil {
+			kp, err := m.bpf.Kretprobe(constants.KprobeInetCskAccept, prog)()
+			if err != nil {
+				m.Stop(context.Background())
+				return err
+			}
+			m.links = append(m.links, kp)
+		}
 	}
-	m.links = append(m.links, kpClose)
 
-	m.reader, err = ringbuf.NewReader(m.objs.TcpEvents)
+	reader, err := ringbuf.NewReader(m.objs.TcpEvents)
 	if err != nil {
 		m.Stop(context.Background())
 		return fmt.Errorf("creating ring buffer reader: %w", err)
 	}
+	m.reader = reader
 
 	return nil
 }
 
 func (m *Module) Start(ctx context.Context) error {
 	m.logger.Info("TCP module consumer started")
-	for {
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
+	return bpfutil.ReadLoop(ctx, m.reader, m.deps.Config.Drain(), m.logger, m.handle)
+}
 
-		record, err := m.reader.Read()
-		if err != nil {
-			if errors.Is(err, ringbuf.ErrClosed) {
-				return nil
-			}
-			m.logger.Warn("Reading TCP event", zap.Error(err))
-			continue
-		}
+// handle publishes the event in one ring buffer record.
+func (m *Module) handle(record ringbuf.Record) {
+	if !m.deps.Sampler.Keep() {
+		return
+	}
+	raw, err := decode(record.RawSample)
+	if err != nil {
+		m.logger.Warn("Parsing TCP event", zap.Error(err))
+		return
+	}
 
-		var raw rawEvent
-		if err := binary.Read(bytes.NewReader(record.RawSample), binary.LittleEndian, &raw); err != nil {
-			m.logger.Warn("Parsing TCP event", zap.Error(err))
-			continue
-		}
+	// Servers are filtered by the port they listen on.
+	port := raw.DPort
+	if raw.Role == roleServer {
+		port = raw.SPort
+	}
+	comm := m.comms.CString(raw.Comm[:])
+	if !m.deps.Filter.PID(raw.PID) || !m.deps.Filter.Comm(comm) || !m.deps.Filter.Port(port) {
+		return
+	}
+	if raw.Failed == 0 && !m.deps.Filter.Latency(raw.LatencyNs) {
+		return
+	}
 
-		e := event.Acquire()
-		e.Type = event.TypeTCP
-		e.Timestamp = time.Now()
-		e.PID = raw.PID
-		e.UID = raw.UID
-		e.Comm = bpfutil.CommString(raw.Comm)
-		e.Node = m.deps.NodeName
-
-		if m.deps.Metadata != nil {
-			if meta, found := m.deps.Metadata.Lookup(raw.PID); found {
-				e.Namespace = meta.Namespace
-				e.Pod = meta.PodName
-			}
-		}
+	e := event.Acquire()
+	e.Type = event.TypeTCP
+	e.Timestamp = bpfutil.WallTime(raw.Timestamp)
+	e.KernelTime = raw.Timestamp
+	e.PID = raw.PID
+	e.UID = raw.UID
+	e.Comm = comm
+	e.Node = m.deps.NodeName
+
+	m.deps.Enrich(e, raw.PID)
 
-		e.SetLabel(constants.KeySrc, fmt.Sprintf("%s:%d", bpfutil.FormatIPv4(raw.SAddr), raw.SPort))
-		e.SetLabel(constants.KeyDst, fmt.Sprintf("%s:%d", bpfutil.FormatIPv4(raw.DAddr), raw.DPort))
+	dst := bpfutil.FormatAddr(raw.DAddr, raw.DPort)
+	e.SetLabel(constants.KeySrc, bpfutil.FormatAddr(raw.SAddr, raw.SPort))
+	e.SetLabel(constants.KeyDst, dst)
+	if m.deps.Metadata != nil {
+		dstIP := dst[:strings.LastIndexByte(dst, ':')]
+		if svc, found := m.deps.Metadata.LookupService(dstIP); found {
+			e.SetLabel(constants.KeyDstService, svc.String())
+		}
+	}
+	switch {
+	case raw.Role == roleServer:
+		e.SetLabel(constants.KeyRole, constants.RoleServer)
+		e.SetNumeric(constants.KeyAcceptLatencySec, float64(raw.LatencyNs)/constants.NsPerSecond)
+	case raw.Failed != 0:
+		e.SetLabel(constants.KeyRole, constants.RoleClient)
+		e.SetLabel(constants.KeyOutcome, constants.OutcomeFailure)
+		e.SetLabel(constants.KeyError, connectError(raw.SkErr))
+	default:
+		e.SetLabel(constants.KeyRole, constants.RoleClient)
+		e.SetLabel(constants.KeyOutcome, constants.OutcomeSuccess)
 		e.SetNumeric(constants.KeyLatencySec, float64(raw.LatencyNs)/constants.NsPerSecond)
 		e.SetNumeric(constants.KeyLatencyNs, float64(raw.LatencyNs))
-
-		m.deps.EventBus.Publish(e)
 	}
+
+	m.deps.EventBus.Publish(e)
 }
 
 func (m *Module) Stop(_ context.Context) error {
@@ -136,5 +242,40 @@ func (m *Module) Stop(_ context.Context) error {
 		l.Close()
 	}
 	m.objs.Close()
+	m.bpf.Close()
+	m.reader, m.links = nil, nil
 	return nil
 }
+
+// setTrackState enables connect failure reporting in spec when the kernel
+// has the inet_sock_set_state tracepoint and the object has its program,
+// reporting whether it did.
+func setTrackState(spec *ebpf.CollectionSpec, k *kernelfeat.Features, logger *zap.Logger) (bool, error) {
+	v, ok := spec.Variables[constants.BPFVarTrackState]
+	if !ok || spec.Programs[constants.BPFProgTracepointInetSockSetState] == nil {
+		logger.Info("BPF object does not track TCP handshakes — connect failures will not be reported")
+		return false, nil
+	}
+	if !k.Tracepoint(constants.TracepointGroupSock, constants.TracepointInetSockSetState) {
+		logger.Warn("inet_sock_set_state tracepoint unavailable — connect failures will not be reported")
+		return false, nil
+	}
+	return true, v.Set(uint8(1))
+}
+
+// DroppedCount returns the events the BPF program dropped because the
+// ring buffer was full.
+func (m *Module) DroppedCount() (uint64, error) {
+	return m.bpf.DroppedCount()
+}
+
+// AttachMode returns how the module's programs are attached: fentry or
+// kprobe.
+func (m *Module) AttachMode() string {
+	return m.mode
+}
+
+// BPFResources returns the module's loaded programs and maps.
+func (m *Module) BPFResources() *bpfutil.Resources {
+	return m.bpf
+}
@@ -1,10 +1,24 @@
 package tcp
 
 import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"syscall"
 	"testing"
+	"time"
+
+	"github.com/cilium/ebpf/ringbuf"
+	"go.uber.org/zap"
 
 	"github.com/sureshkrishnan-v/kubePulse/internal/bpfutil"
+	"github.com/sureshkrishnan-v/kubePulse/internal/config"
 	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+	"github.com/sureshkrishnan-v/kubePulse/internal/metadata"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe"
+	"github.com/sureshkrishnan-v/kubePulse/internal/probe/probetest"
 )
 
 func TestNew(t *testing.T) {
@@ -41,3 +55,236 @@ func TestCommString(t *testing.T) {
 		t.Errorf("CommString = %q, want %q", got, "curl")
 	}
 }
+
+// TestRawEventSize pins the decode struct to sizeof(struct tcp_event), which
+// bpf/tcp_tracer.c asserts at compile time.
+func TestRawEventSize(t *testing.T) {
+	want, err := bpfutil.CEventSize("../../../bpf/tcp_tracer.c", "tcp_event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := binary.Size(rawEvent{}); got != want || rawEventSize != want {
+		t.Errorf("binary.Size(rawEvent{}) = %d, rawEventSize = %d, want %d", got, rawEventSize, want)
+	}
+	// bpfTcpEvent is generated from the embedded object's BTF, so this fails
+	// when the object was not regenerated after a layout change.
+	if got := binary.Size(bpfTcpEvent{}); got != want {
+		t.Errorf("embedded object's tcp_event is %d bytes, want %d: run make generate", got, want)
+	}
+}
+
+func TestConnectError(t *testing.T) {
+	tests := []struct {
+		errno syscall.Errno
+		want  string
+	}{
+		{syscall.ECONNREFUSED, constants.ConnectErrorRefused},
+		{syscall.ETIMEDOUT, constants.ConnectErrorTimeout},
+		{syscall.EHOSTUNREACH, constants.ConnectErrorUnreachable},
+		{syscall.ENETUNREACH, constants.ConnectErrorUnreachable},
+		{0, constants.ConnectErrorAborted},
+		{syscall.EPROTO, constants.ConnectErrorOther},
+	}
+	for _, tt := range tests {
+		if got := connectError(uint32(tt.errno)); got != tt.want {
+			t.Errorf("connectError(%v) = %q, want %q", tt.errno, got, tt.want)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var buf bytes.Buffer
+	in := rawEvent{PID: 9, DPort: 5432, LatencyNs: 3e9, Failed: 1, SkErr: uint32(syscall.ETIMEDOUT)}
+	if err := binary.Write(&buf, binary.LittleEndian, in); err != nil {
+		t.Fatal(err)
+	}
+	full := buf.Bytes()
+
+	raw := mustDecode(t, full)
+	if raw != in {
+		t.Errorf("decode = %+v, want %+v", raw, in)
+	}
+
+	// Objects built before connect failures were reported end after Comm.
+	raw = mustDecode(t, full[:56])
+	if raw.PID != 9 || raw.LatencyNs != 3e9 || raw.Failed != 0 {
+		t.Errorf("decode(short) = %+v, want a success", raw)
+	}
+}
+
+func TestDecode_ServerRole(t *testing.T) {
+	var buf bytes.Buffer
+	in := rawEvent{PID: 7, SPort: 8080, DPort: 51234, LatencyNs: 2e6, Role: roleServer}
+	if err := binary.Write(&buf, binary.LittleEndian, in); err != nil {
+		t.Fatal(err)
+	}
+	raw := mustDecode(t, buf.Bytes())
+	if raw.Role != roleServer || raw.Failed != 0 || raw.SPort != 8080 {
+		t.Errorf("decode = %+v, want a server event on port 8080", raw)
+	}
+
+	// Older objects never set role: every event is a client connect.
+	raw = mustDecode(t, buf.Bytes()[:56])
+	if raw.Role != roleClient {
+		t.Errorf("decode(short).Role = %d, want client", raw.Role)
+	}
+}
+
+func TestSetTrackState_PrebuiltObject(t *testing.T) {
+	spec, err := loadBpf()
+	if err != nil {
+		t.Fatal(err)
+	}
+	kernel := kernelfeat.Source{}.Detect() // nothing detectable: hooks assumed present
+	track, err := setTrackState(spec, kernel, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := spec.Variables[constants.BPFVarTrackState]; !ok && track {
+		t.Error("tracking enabled for an object without track_state")
+	}
+}
+
+func mustDecode(t *testing.T, sample []byte) rawEvent {
+	t.Helper()
+	raw, err := decode(sample)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// FuzzDecode feeds decode records of any length: it must reject those
+// shorter than the oldest layout, accept the rest, and decode a full one
+// as binary.Read does. It never panics.
+func FuzzDecode(f *testing.F) {
+	f.Add(make([]byte, rawEventSize))
+	f.Add(make([]byte, rawEventSize+16))
+	f.Add(make([]byte, minRawEventSize))
+	f.Add(make([]byte, minRawEventSize-1))
+	f.Add([]byte{})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		raw, err := decode(data)
+		if (err == nil) != (len(data) >= minRawEventSize) {
+			t.Fatalf("decode(%d bytes) error = %v", len(data), err)
+		}
+		if len(data) >= rawEventSize {
+			var want rawEvent
+			binary.Read(bytes.NewReader(data), binary.LittleEndian, &want)
+			if raw != want {
+				t.Fatalf("decode = %+v, binary.Read = %+v", raw, want)
+			}
+		}
+	})
+}
+
+// replayReader is a ring buffer that holds sample n times, then closes.
+type replayReader struct {
+	sample []byte
+	n      int
+}
+
+func (r *replayReader) Read() (ringbuf.Record, error) {
+	if r.n == 0 {
+		return ringbuf.Record{}, ringbuf.ErrClosed
+	}
+	r.n--
+	return ringbuf.Record{RawSample: r.sample}, nil
+}
+
+func (*replayReader) SetDeadline(time.Time) {}
+
+func (*replayReader) Flush() error { return nil }
+
+// BenchmarkPipeline measures one event from the ring buffer to a bus
+// subscriber: decoding, filtering, enrichment and publishing.
+func BenchmarkPipeline(b *testing.B) {
+	var buf bytes.Buffer
+	in := rawEvent{PID: 9, SAddr: 0x0100000a, DAddr: 0x0200000a, SPort: 51234, DPort: 5432, LatencyNs: 3e6}
+	copy(in.Comm[:], "postgres")
+	if err := binary.Write(&buf, binary.LittleEndian, in); err != nil {
+		b.Fatal(err)
+	}
+
+	filter, err := probe.NewFilter(constants.ModuleTCP, config.FilterConfig{})
+	if err != nil {
+		b.Fatal(err)
+	}
+	bus := event.NewBus(constants.DefaultEventBusBuffer, nil)
+	events := bus.Subscribe("bench")
+	done := make(chan struct{})
+	go func() {
+		for e := range events {
+			e.Done()
+		}
+		close(done)
+	}()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, nil, "node-a", probe.NewSampler(1), filter, nil, nil)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	bpfutil.ReadLoop(context.Background(), &replayReader{buf.Bytes(), b.N}, 0, zap.NewNop(), m.handle)
+	b.StopTimer()
+	bus.Close()
+	<-done
+}
+
+func TestStart(t *testing.T) {
+	filter, err := probe.NewFilter(constants.ModuleTCP, config.FilterConfig{ExcludeComm: []string{"^kubelet$"}, DenyPorts: []uint16{22}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bus := event.NewBus(16, nil)
+	events := bus.Subscribe("test")
+	meta := metadata.NewCache(metadata.DefaultCacheConfig())
+	meta.UpdateServiceIPs(metadata.ServiceRef{Namespace: "prod", Name: "db"}, []string{"10.0.0.2"})
+	reader := probetest.NewReader()
+	m := New()
+	m.logger = zap.NewNop()
+	m.deps = probe.NewDependencies(zap.NewNop(), &config.ModuleConfig{}, bus, meta, "node-a", probe.NewSampler(1), filter, nil, nil)
+	m.reader = reader
+	probetest.Start(t, m.Start)
+
+	connect := rawEvent{PID: 9, SAddr: 0x0100000a, DAddr: 0x0200000a, SPort: 51234, DPort: 5432, LatencyNs: 3e6}
+	copy(connect.Comm[:], "psql")
+	kubelet, ssh, refused := connect, connect, connect
+	copy(kubelet.Comm[:], "kubelet")
+	ssh.DPort = 22
+	refused.Failed, refused.SkErr = 1, uint32(syscall.ECONNREFUSED)
+	for _, raw := range []rawEvent{kubelet, ssh, connect} {
+		reader.Send(probetest.Sample(t, raw))
+	}
+	reader.Send([]byte{1, 2, 3}) // too short to decode: skipped
+	reader.Send(probetest.Sample(t, refused))
+
+	e := probetest.Next(t, events)
+	if e.Type != event.TypeTCP || e.Comm != "psql" || e.PID != 9 || e.Node != "node-a" {
+		t.Errorf("event = %+v, want psql's connect on node-a", e)
+	}
+	for key, want := range map[string]string{
+		constants.KeySrc:        "10.0.0.1:51234",
+		constants.KeyDst:        "10.0.0.2:5432",
+		constants.KeyDstService: "prod/db",
+		constants.KeyRole:       constants.RoleClient,
+		constants.KeyOutcome:    constants.OutcomeSuccess,
+	} {
+		if got := e.Label(key); got != want {
+			t.Errorf("label %s = %q, want %q", key, got, want)
+		}
+	}
+	if got := e.NumericVal(constants.KeyLatencySec); got != 0.003 {
+		t.Errorf("latency = %v, want 0.003", got)
+	}
+	e.Done()
+
+	e = probetest.Next(t, events)
+	if e.Label(constants.KeyOutcome) != constants.OutcomeFailure || e.Label(constants.KeyError) != constants.ConnectErrorRefused {
+		t.Errorf("labels = %v, want a refused connect", e.Labels)
+	}
+	if _, ok := e.Numeric[constants.KeyLatencySec]; ok {
+		t.Error("failed connect has a latency")
+	}
+	e.Done()
+}
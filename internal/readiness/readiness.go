@@ -0,0 +1,103 @@
+// Package readiness tracks the lifecycle state of each agent module and
+// decides whether the agent is ready: at least a minimum number of modules
+// must have completed Init and begun Start. The runtime updates a Tracker
+// as modules move between states; the metrics server's /readyz serves it.
+package readiness
+
+import (
+	"sync"
+)
+
+// State is where a module is in its lifecycle.
+type State string
+
+const (
+	// Initializing: Init has not returned yet.
+	Initializing State = "initializing"
+	// Running: Init succeeded and Start is reading the ring buffer.
+	Running State = "running"
+	// Restarting: Start failed; the module is being re-initialized.
+	Restarting State = "restarting"
+	// Failed: Init failed, or the module failed past its restart budget.
+	Failed State = "failed"
+	// Disabled: turned off in config or via the admin endpoint, or
+	// unsupported by the kernel.
+	Disabled State = "disabled"
+	// Stopped: the agent is shutting down and has stopped the module.
+	Stopped State = "stopped"
+)
+
+// States lists every State.
+var States = []State{Initializing, Running, Restarting, Failed, Disabled, Stopped}
+
+// Report is the /readyz body.
+type Report struct {
+	Status  string           `json:"status"` // "ready" or "not ready"
+	Running int              `json:"running"`
+	Minimum int              `json:"min_ready_modules"`
+	Modules map[string]State `json:"modules"`
+}
+
+// Tracker holds each module's State. It is safe for concurrent use.
+type Tracker struct {
+	min int
+
+	mu      sync.Mutex
+	modules map[string]State
+}
+
+// New returns a Tracker that is ready while at least minRunning modules,
+// and at least one, are Running.
+func New(minRunning int) *Tracker {
+	return &Tracker{min: max(minRunning, 1), modules: make(map[string]State)}
+}
+
+// Set records module's state.
+func (t *Tracker) Set(module string, s State) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.modules[module] = s
+}
+
+// Get returns module's state, or "" if it was never set.
+func (t *Tracker) Get(module string) State {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.modules[module]
+}
+
+// Ready reports whether enough modules are Running.
+func (t *Tracker) Ready() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.running() >= t.min
+}
+
+// Report returns a snapshot of every module's state.
+func (t *Tracker) Report() Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r := Report{
+		Status:  "not ready",
+		Running: t.running(),
+		Minimum: t.min,
+		Modules: make(map[string]State, len(t.modules)),
+	}
+	for name, s := range t.modules {
+		r.Modules[name] = s
+	}
+	if r.Running >= t.min {
+		r.Status = "ready"
+	}
+	return r
+}
+
+func (t *Tracker) running() int {
+	n := 0
+	for _, s := range t.modules {
+		if s == Running {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,80 @@
+package readiness
+
+import (
+	"testing"
+)
+
+func TestTracker_Transitions(t *testing.T) {
+	tr := New(1)
+	steps := []struct {
+		module string
+		state  State
+		ready  bool
+	}{
+		{"tcp", Initializing, false},
+		{"dns", Disabled, false},
+		{"oom", Initializing, false},
+		{"oom", Failed, false}, // init failed
+		{"tcp", Running, true}, // Init done, Start begun
+		{"tcp", Restarting, false},
+		{"tcp", Running, true},
+		{"tcp", Failed, false}, // gave up after max restarts
+		{"tcp", Stopped, false},
+	}
+	for i, s := range steps {
+		tr.Set(s.module, s.state)
+		if got := tr.Ready(); got != s.ready {
+			t.Errorf("step %d (%s → %s): Ready = %v, want %v", i, s.module, s.state, got, s.ready)
+		}
+	}
+}
+
+func TestTracker_DegradesWhenLastModuleDies(t *testing.T) {
+	tr := New(1)
+	tr.Set("tcp", Running)
+	tr.Set("dns", Running)
+
+	tr.Set("tcp", Failed)
+	if !tr.Ready() {
+		t.Error("not ready with dns still running")
+	}
+	tr.Set("dns", Failed)
+	if tr.Ready() {
+		t.Error("ready after every module failed")
+	}
+}
+
+func TestTracker_Minimum(t *testing.T) {
+	tr := New(2)
+	tr.Set("tcp", Running)
+	if tr.Ready() {
+		t.Error("ready with 1 of 2 required modules running")
+	}
+	tr.Set("dns", Running)
+	if !tr.Ready() {
+		t.Error("not ready with 2 of 2 required modules running")
+	}
+
+	if New(0).min != 1 {
+		t.Error("New(0) does not require a running module")
+	}
+}
+
+func TestTracker_Report(t *testing.T) {
+	tr := New(1)
+	tr.Set("tcp", Running)
+	tr.Set("oom", Disabled)
+
+	r := tr.Report()
+	if r.Status != "ready" || r.Running != 1 || r.Minimum != 1 {
+		t.Errorf("Report = %+v, want ready with 1 of 1 running", r)
+	}
+	if r.Modules["tcp"] != Running || r.Modules["oom"] != Disabled {
+		t.Errorf("Modules = %v", r.Modules)
+	}
+
+	r.Modules["tcp"] = Failed // a snapshot; must not write through
+	if tr.Get("tcp") != Running {
+		t.Error("editing the Report changed the Tracker")
+	}
+}
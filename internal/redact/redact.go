@@ -0,0 +1,142 @@
+// Package redact drops or hashes event labels that may hold personal or
+// sensitive data, such as DNS query names, exec filenames and file paths,
+// before any exporter sees the event.
+//
+// A hashed value is the salted SHA-256 of the original, truncated. Agents
+// sharing a salt hash a value the same way, so hashed labels still group
+// and join across a cluster; without the salt, the storage operator cannot
+// confirm a guess at the original.
+package redact
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// Actions: what happens to a label.
+const (
+	ActionKeep = "keep" // left as is; the default for unlisted labels
+	ActionDrop = "drop" // removed from the event
+	ActionHash = "hash" // replaced by its salted hash
+)
+
+// Config holds redaction settings.
+type Config struct {
+	Labels   map[string]string `yaml:"labels"`    // label key → keep, drop or hash
+	Salt     string            `yaml:"salt"`      // for hash; set this or SaltFile
+	SaltFile string            `yaml:"salt_file"` // file holding the salt, e.g. a mounted Secret
+}
+
+// DefaultConfig returns defaults that keep every label.
+func DefaultConfig() Config {
+	return Config{}
+}
+
+// Enabled reports whether c drops or hashes any label.
+func (c Config) Enabled() bool {
+	for _, action := range c.Labels {
+		if action != ActionKeep {
+			return true
+		}
+	}
+	return false
+}
+
+// Action returns what c does to the label key.
+func (c Config) Action(key string) string {
+	if action, ok := c.Labels[key]; ok {
+		return action
+	}
+	return ActionKeep
+}
+
+// Validate checks c without reading SaltFile.
+func (c Config) Validate() error {
+	var errs []string
+	hashes := false
+	for _, key := range slices.Sorted(maps.Keys(c.Labels)) {
+		switch c.Labels[key] {
+		case ActionKeep, ActionDrop:
+		case ActionHash:
+			hashes = true
+		default:
+			errs = append(errs, fmt.Sprintf("labels.%s must be %s, %s or %s, got %q",
+				key, ActionKeep, ActionDrop, ActionHash, c.Labels[key]))
+		}
+	}
+	if c.Salt != "" && c.SaltFile != "" {
+		errs = append(errs, "set salt or salt_file, not both")
+	}
+	if hashes && c.Salt == "" && c.SaltFile == "" {
+		errs = append(errs, "salt or salt_file is required to hash labels")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Redactor applies a Config to events. Safe for concurrent use.
+type Redactor struct {
+	drop []string
+	hash []string
+	salt []byte
+}
+
+// New creates a Redactor, reading the salt from cfg.SaltFile if set. It
+// fails if cfg does not pass Validate or the salt file is unreadable or
+// empty.
+func New(cfg Config) (*Redactor, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	r := &Redactor{salt: []byte(cfg.Salt)}
+	if cfg.SaltFile != "" {
+		data, err := os.ReadFile(cfg.SaltFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading salt: %w", err)
+		}
+		r.salt = []byte(strings.TrimSpace(string(data)))
+		if len(r.salt) == 0 {
+			return nil, fmt.Errorf("salt file %s is empty", cfg.SaltFile)
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(cfg.Labels)) {
+		switch cfg.Labels[key] {
+		case ActionDrop:
+			r.drop = append(r.drop, key)
+		case ActionHash:
+			r.hash = append(r.hash, key)
+		}
+	}
+	return r, nil
+}
+
+// Apply drops and hashes e's labels in place.
+func (r *Redactor) Apply(e *event.Event) {
+	for _, key := range r.drop {
+		delete(e.Labels, key)
+	}
+	for _, key := range r.hash {
+		if v, ok := e.Labels[key]; ok {
+			e.Labels[key] = r.Hash(v)
+		}
+	}
+}
+
+// Hash returns the salted hash of value, hex-encoded.
+func (r *Redactor) Hash(value string) string {
+	h := sha256.New()
+	h.Write(r.salt)
+	h.Write([]byte(value))
+	return hex.EncodeToString(h.Sum(nil)[:constants.RedactHashBytes])
+}
@@ -0,0 +1,131 @@
+package redact
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+func dnsEvent() *event.Event {
+	e := event.Acquire()
+	e.Type = event.TypeDNS
+	e.SetLabel(constants.KeyQName, "payroll.internal.example.com")
+	e.SetLabel(constants.KeyDomain, "example.com")
+	e.SetLabel(constants.KeyQType, "A")
+	return e
+}
+
+func TestApply(t *testing.T) {
+	r, err := New(Config{
+		Labels: map[string]string{
+			constants.KeyQName:  ActionHash,
+			constants.KeyDomain: ActionDrop,
+			constants.KeyQType:  ActionKeep,
+			constants.KeyPath:   ActionHash, // absent from DNS events
+		},
+		Salt: "cluster-a",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := dnsEvent()
+	defer e.Release()
+	r.Apply(e)
+
+	if _, ok := e.Labels[constants.KeyDomain]; ok {
+		t.Errorf("dropped label still present: %q", e.Labels[constants.KeyDomain])
+	}
+	if _, ok := e.Labels[constants.KeyPath]; ok {
+		t.Error("hashing an absent label added it")
+	}
+	if got := e.Label(constants.KeyQType); got != "A" {
+		t.Errorf("kept label = %q, want A", got)
+	}
+	qname := e.Label(constants.KeyQName)
+	if qname == "payroll.internal.example.com" || len(qname) != 2*constants.RedactHashBytes {
+		t.Errorf("hashed qname = %q, want %d hex characters", qname, 2*constants.RedactHashBytes)
+	}
+	if len(e.Labels) != 2 {
+		t.Errorf("labels = %v, want qname and qtype only", e.Labels)
+	}
+}
+
+func TestHash_StableWithinSalt(t *testing.T) {
+	a1, _ := New(Config{Salt: "cluster-a"})
+	a2, _ := New(Config{Salt: "cluster-a"})
+	b, _ := New(Config{Salt: "cluster-b"})
+
+	v := "/var/lib/secrets/token"
+	if a1.Hash(v) != a2.Hash(v) {
+		t.Error("same salt hashed a value differently")
+	}
+	if a1.Hash(v) == b.Hash(v) {
+		t.Error("different salts hashed a value the same")
+	}
+	if a1.Hash(v) == a1.Hash(v+"x") {
+		t.Error("different values hashed the same")
+	}
+}
+
+func TestNew_SaltFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "salt")
+	if err := os.WriteFile(path, []byte("cluster-a\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fromFile, err := New(Config{Labels: map[string]string{constants.KeyQName: ActionHash}, SaltFile: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	inline, _ := New(Config{Salt: "cluster-a"})
+	if fromFile.Hash("x") != inline.Hash("x") {
+		t.Error("salt file hashed differently from the same salt inline")
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, []byte(" \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(Config{SaltFile: empty}); err == nil {
+		t.Error("New accepted an empty salt file")
+	}
+	if _, err := New(Config{SaltFile: filepath.Join(dir, "missing")}); err == nil {
+		t.Error("New accepted a missing salt file")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := map[string]struct {
+		cfg   Config
+		valid bool
+	}{
+		"default":         {DefaultConfig(), true},
+		"drop":            {Config{Labels: map[string]string{"qname": ActionDrop}}, true},
+		"hash with salt":  {Config{Labels: map[string]string{"qname": ActionHash}, Salt: "s"}, true},
+		"hash with file":  {Config{Labels: map[string]string{"qname": ActionHash}, SaltFile: "/etc/salt"}, true},
+		"hash no salt":    {Config{Labels: map[string]string{"qname": ActionHash}}, false},
+		"unknown action":  {Config{Labels: map[string]string{"qname": "mask"}}, false},
+		"salt and file":   {Config{Salt: "s", SaltFile: "/etc/salt"}, false},
+		"keep needs none": {Config{Labels: map[string]string{"qname": ActionKeep}}, true},
+	}
+	for name, tt := range tests {
+		if err := tt.cfg.Validate(); (err == nil) != tt.valid {
+			t.Errorf("%s: Validate() = %v, want valid %v", name, err, tt.valid)
+		}
+	}
+}
+
+func TestEnabled(t *testing.T) {
+	if DefaultConfig().Enabled() {
+		t.Error("default config is enabled")
+	}
+	if (Config{Labels: map[string]string{"qname": ActionKeep}}).Enabled() {
+		t.Error("keep-only config is enabled")
+	}
+	if !(Config{Labels: map[string]string{"qname": ActionDrop}}).Enabled() {
+		t.Error("drop config is not enabled")
+	}
+}
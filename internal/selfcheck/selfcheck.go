@@ -0,0 +1,311 @@
+// Package selfcheck implements `kubepulse check`, which verifies that a
+// node can run the agent: capabilities, the memlock rlimit, kernel version
+// and BTF, ring buffer support, the hooks the modules attach to, the BPF
+// verifier, and the Kubernetes API.
+//
+// Each check is a function of its inputs so it can be tested without the
+// running kernel; Run feeds them the real system.
+package selfcheck
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/rlimit"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/caps"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+)
+
+// Status is a check's outcome. Only Fail makes `kubepulse check` exit
+// non-zero; Warn means the agent runs with something degraded.
+type Status int
+
+const (
+	Pass Status = iota
+	Warn
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "PASS"
+	case Warn:
+		return "WARN"
+	default:
+		return "FAIL"
+	}
+}
+
+// Result is one row of the check table.
+type Result struct {
+	Check  string
+	Status Status
+	Detail string
+}
+
+// AgentKprobes are the kernel functions the modules attach kprobes (or
+// fentry programs) to.
+var AgentKprobes = []string{
+	constants.KprobeTCPConnect,
+	constants.KprobeTCPClose,
+	constants.KprobeUDPSendmsg,
+	constants.KprobeVFSRead,
+	constants.KprobeVFSWrite,
+}
+
+// Capabilities checks that set, caps.Effective in Run, can load and
+// attach the agent's probes: as root, or with CAP_BPF and CAP_PERFMON.
+func Capabilities(set caps.Set, err error) Result {
+	r := Result{Check: "capabilities"}
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+	if err := caps.Check(set); err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+	r.Detail = "CAP_BPF and CAP_PERFMON"
+	if set.Has(unix.CAP_SYS_ADMIN) {
+		r.Detail = "CAP_SYS_ADMIN"
+	}
+	return r
+}
+
+// Memlock checks that remove, rlimit.RemoveMemlock in Run, lifts the
+// memlock rlimit BPF maps are charged against on kernels before 5.11.
+func Memlock(remove func() error) Result {
+	r := Result{Check: "memlock rlimit"}
+	if err := remove(); err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+	r.Detail = "unlimited or not needed"
+	return r
+}
+
+// KernelVersion checks that the kernel is at least
+// constants.MinKernelMajor.MinKernelMinor.
+func KernelVersion(k *kernelfeat.Features) Result {
+	r := Result{Check: "kernel version"}
+	switch {
+	case k.Version == (kernelfeat.Version{}):
+		r.Status, r.Detail = Warn, fmt.Sprintf("cannot parse release %q", k.Release)
+	case !k.Version.AtLeast(constants.MinKernelMajor, constants.MinKernelMinor):
+		r.Status, r.Detail = Fail, fmt.Sprintf("%s; Linux %d.%d or later is required",
+			k.Release, constants.MinKernelMajor, constants.MinKernelMinor)
+	default:
+		r.Detail = k.Release
+	}
+	return r
+}
+
+// BTF checks for kernel BTF, which the CO-RE probes are relocated against.
+func BTF(k *kernelfeat.Features) Result {
+	r := Result{Check: "kernel BTF"}
+	if !k.BTF {
+		r.Status, r.Detail = Fail, "not found; probes cannot be relocated for this kernel"
+		return r
+	}
+	r.Detail = "available"
+	if k.Fentry() {
+		r.Detail += ", fentry supported"
+	}
+	return r
+}
+
+// Ringbuf checks support for BPF ring buffer maps; probe is
+// features.HaveMapType(ebpf.RingBuf) in Run.
+func Ringbuf(probe func() error) Result {
+	r := Result{Check: "ring buffer"}
+	if err := probe(); err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+	r.Detail = "supported"
+	return r
+}
+
+// Tracepoints checks each tracepoint and field in kernelfeat.AgentHooks.
+// A missing one warns: its module is skipped, or the field left out.
+func Tracepoints(k *kernelfeat.Features) []Result {
+	detail := "present"
+	if k.Tracefs == "" && !k.BTF {
+		detail = "assumed present; neither tracefs nor BTF to check"
+	}
+	results := make([]Result, 0, len(kernelfeat.AgentHooks))
+	for _, h := range kernelfeat.AgentHooks {
+		r := Result{Check: h.String(), Detail: detail}
+		if !k.Has(h) {
+			r.Status, r.Detail = Warn, "missing; the module using it is skipped"
+			if h.Field != "" {
+				r.Detail = "missing; the field is not reported"
+			}
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Kprobes checks that each kernel function in names is among symbols, as
+// read by Kallsyms; symErr is the error reading them, if any.
+func Kprobes(symbols map[string]bool, symErr error, names []string) []Result {
+	if symErr != nil {
+		return []Result{{Check: "kprobe symbols", Status: Warn, Detail: symErr.Error()}}
+	}
+	results := make([]Result, 0, len(names))
+	for _, name := range names {
+		r := Result{Check: "kprobe:" + name, Detail: "present"}
+		if !symbols[name] {
+			r.Status, r.Detail = Fail, "not in kernel symbols; its module cannot attach"
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+// Kallsyms reads the function names in a /proc/kallsyms listing, whose
+// lines are "address type name [module]".
+func Kallsyms(r io.Reader) (map[string]bool, error) {
+	symbols := make(map[string]bool)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		if t := fields[1]; t == "t" || t == "T" {
+			symbols[fields[2]] = true
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return symbols, nil
+}
+
+// Verifier loads the collection returned by spec into the kernel and
+// unloads it again, exercising the BPF syscall, the verifier and CO-RE relocation.
+func Verifier(spec func() (*ebpf.CollectionSpec, error)) Result {
+	r := Result{Check: "BPF verifier"}
+	s, err := spec()
+	if err != nil {
+		r.Status, r.Detail = Fail, fmt.Sprintf("loading spec: %v", err)
+		return r
+	}
+	coll, err := ebpf.NewCollection(s)
+	if err != nil {
+		r.Status, r.Detail = Fail, err.Error()
+		return r
+	}
+	coll.Close()
+	r.Detail = fmt.Sprintf("loaded and unloaded %d programs", len(s.Programs))
+	return r
+}
+
+// Kubernetes checks that ping, metadata.Ping in Run, reaches the API
+// server. Failure warns: the agent runs without pod labels.
+func Kubernetes(ctx context.Context, ping func(context.Context) (string, error)) Result {
+	r := Result{Check: "kubernetes API"}
+	ctx, cancel := context.WithTimeout(ctx, constants.SelfCheckK8sTimeout)
+	defer cancel()
+	version, err := ping(ctx)
+	if err != nil {
+		r.Status, r.Detail = Warn, fmt.Sprintf("unreachable, pod labels will be empty: %v", err)
+		return r
+	}
+	r.Detail = "reachable, " + version
+	return r
+}
+
+// System is what Run checks; tests substitute fakes.
+type System struct {
+	Capabilities  func() (caps.Set, error)
+	RemoveMemlock func() error
+	Kernel        func() *kernelfeat.Features
+	Ringbuf       func() error
+	Kallsyms      func() (map[string]bool, error)
+	Spec          func() (*ebpf.CollectionSpec, error)
+	Ping          func(context.Context) (string, error)
+}
+
+// DefaultSystem returns the System of the running node. spec is the probe
+// the verifier check loads, and ping reaches the Kubernetes API.
+func DefaultSystem(spec func() (*ebpf.CollectionSpec, error), ping func(context.Context) (string, error)) System {
+	return System{
+		Capabilities:  caps.Effective,
+		RemoveMemlock: rlimit.RemoveMemlock,
+		Kernel:        kernelfeat.Detect,
+		Ringbuf:       func() error { return features.HaveMapType(ebpf.RingBuf) },
+		Kallsyms:      readKallsyms,
+		Spec:          spec,
+		Ping:          ping,
+	}
+}
+
+func readKallsyms() (map[string]bool, error) {
+	f, err := os.Open(constants.KallsymsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Kallsyms(f)
+}
+
+// Run performs every check in table order. The memlock check runs before
+// the checks that load BPF objects, since it is what lets them load.
+func (s System) Run(ctx context.Context) []Result {
+	k := s.Kernel()
+	results := []Result{
+		Capabilities(s.Capabilities()),
+		Memlock(s.RemoveMemlock),
+		KernelVersion(k),
+		BTF(k),
+		Ringbuf(s.Ringbuf),
+	}
+	results = append(results, Tracepoints(k)...)
+
+	kprobes := AgentKprobes
+	if !k.Tracepoint(constants.TracepointGroupSkb, constants.TracepointKfreeSkb) {
+		// The drop module falls back to a kprobe.
+		kprobes = append(kprobes[:len(kprobes):len(kprobes)], constants.KprobeKfreeSkb)
+	}
+	symbols, err := s.Kallsyms()
+	results = append(results, Kprobes(symbols, err, kprobes)...)
+
+	return append(results,
+		Verifier(s.Spec),
+		Kubernetes(ctx, s.Ping),
+	)
+}
+
+// Print writes results as a table.
+func Print(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CHECK\tSTATUS\tDETAIL")
+	for _, r := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Check, r.Status, r.Detail)
+	}
+	return tw.Flush()
+}
+
+// Failed reports whether any result is a hard failure.
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,258 @@
+package selfcheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/btf"
+	"golang.org/x/sys/unix"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/caps"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/kernelfeat"
+)
+
+func kernel(release string, withBTF bool) *kernelfeat.Features {
+	src := kernelfeat.Source{
+		Release:   func() (string, error) { return release, nil },
+		KernelBTF: func() (*btf.Spec, error) { return nil, errors.New("no BTF") },
+	}
+	if withBTF {
+		src.KernelBTF = minimalBTF
+	}
+	return src.Detect()
+}
+
+// minimalBTF is kernel BTF holding a single int, so no tracepoints.
+func minimalBTF() (*btf.Spec, error) {
+	b, err := btf.NewBuilder([]btf.Type{&btf.Int{Name: "int", Size: 4, Encoding: btf.Signed}})
+	if err != nil {
+		return nil, err
+	}
+	raw, err := b.Marshal(nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return btf.LoadSpecFromReader(bytes.NewReader(raw))
+}
+
+func TestCapabilities(t *testing.T) {
+	bpf := caps.Set(1<<unix.CAP_BPF | 1<<unix.CAP_PERFMON)
+	if r := Capabilities(bpf, nil); r.Status != Pass {
+		t.Errorf("Capabilities(CAP_BPF, CAP_PERFMON) = %+v, want pass", r)
+	}
+	if r := Capabilities(1<<unix.CAP_SYS_ADMIN, nil); r.Status != Pass || r.Detail != "CAP_SYS_ADMIN" {
+		t.Errorf("Capabilities(CAP_SYS_ADMIN) = %+v, want pass", r)
+	}
+	if r := Capabilities(1<<unix.CAP_BPF, nil); r.Status != Fail || !strings.Contains(r.Detail, "CAP_PERFMON") {
+		t.Errorf("Capabilities(CAP_BPF) = %+v, want fail naming CAP_PERFMON", r)
+	}
+	if r := Capabilities(0, errors.New("no /proc")); r.Status != Fail {
+		t.Errorf("Capabilities(unreadable) = %+v, want fail", r)
+	}
+}
+
+func TestMemlock(t *testing.T) {
+	if r := Memlock(func() error { return nil }); r.Status != Pass {
+		t.Errorf("Memlock = %+v, want pass", r)
+	}
+	if r := Memlock(func() error { return os.ErrPermission }); r.Status != Fail {
+		t.Errorf("Memlock = %+v, want fail", r)
+	}
+}
+
+func TestKernelVersion(t *testing.T) {
+	for release, want := range map[string]Status{
+		"6.1.0-18-amd64": Pass,
+		"5.8.0":          Pass,
+		"5.4.0-150":      Fail,
+		"weird":          Warn,
+	} {
+		if r := KernelVersion(kernel(release, false)); r.Status != want {
+			t.Errorf("KernelVersion(%s) = %+v, want %v", release, r, want)
+		}
+	}
+}
+
+func TestBTF(t *testing.T) {
+	if r := BTF(kernel("6.1.0", true)); r.Status != Pass || !strings.Contains(r.Detail, "fentry") {
+		t.Errorf("BTF with BTF = %+v, want pass with fentry", r)
+	}
+	if r := BTF(kernel("6.1.0", false)); r.Status != Fail {
+		t.Errorf("BTF without BTF = %+v, want fail", r)
+	}
+}
+
+func TestRingbuf(t *testing.T) {
+	if r := Ringbuf(func() error { return nil }); r.Status != Pass {
+		t.Errorf("Ringbuf = %+v, want pass", r)
+	}
+	if r := Ringbuf(func() error { return ebpf.ErrNotSupported }); r.Status != Fail {
+		t.Errorf("Ringbuf = %+v, want fail", r)
+	}
+}
+
+func TestTracepoints(t *testing.T) {
+	// Without tracefs or BTF, tracepoints are assumed present and the drop
+	// reason field is ruled out by the version table.
+	results := Tracepoints(kernel("5.10.0", false))
+	if len(results) != len(kernelfeat.AgentHooks) {
+		t.Fatalf("got %d results, want %d", len(results), len(kernelfeat.AgentHooks))
+	}
+	for _, r := range results {
+		want := Pass
+		if strings.HasPrefix(r.Check, "field:") {
+			want = Warn
+		}
+		if r.Status != want {
+			t.Errorf("%s = %+v, want %v", r.Check, r, want)
+		}
+	}
+}
+
+func TestKallsyms(t *testing.T) {
+	const listing = "ffffffff81000000 T _stext\n" +
+		"0000000000000000 T tcp_connect\n" +
+		"0000000000000000 t tcp_close\n" +
+		"0000000000000000 D tcp_hashinfo\n" +
+		"0000000000000000 t vfs_read [some_module]\n" +
+		"garbage\n"
+	symbols, err := Kallsyms(strings.NewReader(listing))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]bool{"tcp_connect": true, "tcp_close": true, "vfs_read": true, "tcp_hashinfo": false} {
+		if symbols[name] != want {
+			t.Errorf("symbols[%s] = %v, want %v", name, symbols[name], want)
+		}
+	}
+}
+
+func TestKprobes(t *testing.T) {
+	symbols := map[string]bool{constants.KprobeTCPConnect: true}
+	results := Kprobes(symbols, nil, []string{constants.KprobeTCPConnect, constants.KprobeTCPClose})
+	if len(results) != 2 || results[0].Status != Pass || results[1].Status != Fail {
+		t.Errorf("Kprobes = %+v, want pass then fail", results)
+	}
+	results = Kprobes(nil, os.ErrPermission, AgentKprobes)
+	if len(results) != 1 || results[0].Status != Warn {
+		t.Errorf("Kprobes with unreadable kallsyms = %+v, want one warning", results)
+	}
+}
+
+func tinySpec() (*ebpf.CollectionSpec, error) {
+	return &ebpf.CollectionSpec{Programs: map[string]*ebpf.ProgramSpec{
+		"tiny": {
+			Name: "tiny", Type: ebpf.SocketFilter, License: "GPL",
+			Instructions: asm.Instructions{asm.Mov.Imm(asm.R0, 0), asm.Return()},
+		},
+	}}, nil
+}
+
+func TestVerifier(t *testing.T) {
+	rejected := func() (*ebpf.CollectionSpec, error) {
+		spec, _ := tinySpec()
+		// Exits without setting R0.
+		spec.Programs["tiny"].Instructions = asm.Instructions{asm.Return()}
+		return spec, nil
+	}
+	if r := Verifier(rejected); r.Status != Fail {
+		t.Errorf("Verifier(rejected) = %+v, want fail", r)
+	}
+	if r := Verifier(func() (*ebpf.CollectionSpec, error) { return nil, errors.New("bad ELF") }); r.Status != Fail {
+		t.Errorf("Verifier(unloadable) = %+v, want fail", r)
+	}
+
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	if r := Verifier(tinySpec); r.Status != Pass {
+		t.Errorf("Verifier(tiny) = %+v, want pass", r)
+	}
+}
+
+func TestKubernetes(t *testing.T) {
+	ok := func(ctx context.Context) (string, error) {
+		if _, set := ctx.Deadline(); !set {
+			t.Error("ping has no deadline")
+		}
+		return "v1.30.2", nil
+	}
+	if r := Kubernetes(context.Background(), ok); r.Status != Pass || !strings.Contains(r.Detail, "v1.30.2") {
+		t.Errorf("Kubernetes = %+v, want pass with version", r)
+	}
+	down := func(context.Context) (string, error) { return "", errors.New("connection refused") }
+	if r := Kubernetes(context.Background(), down); r.Status != Warn {
+		t.Errorf("Kubernetes = %+v, want warn", r)
+	}
+}
+
+func fakeSystem() System {
+	return System{
+		Capabilities:  func() (caps.Set, error) { return 1 << unix.CAP_SYS_ADMIN, nil },
+		RemoveMemlock: func() error { return nil },
+		Kernel:        func() *kernelfeat.Features { return kernel("6.1.0", true) },
+		Ringbuf:       func() error { return nil },
+		Kallsyms: func() (map[string]bool, error) {
+			symbols := map[string]bool{constants.KprobeKfreeSkb: true}
+			for _, name := range AgentKprobes {
+				symbols[name] = true
+			}
+			return symbols, nil
+		},
+		Spec: tinySpec,
+		Ping: func(context.Context) (string, error) { return "", errors.New("no cluster") },
+	}
+}
+
+func TestSystem_Run(t *testing.T) {
+	if os.Geteuid() != 0 {
+		t.Skip("loading BPF programs requires root")
+	}
+	results := fakeSystem().Run(context.Background())
+	if Failed(results) {
+		t.Errorf("Run failed: %+v", results)
+	}
+	// minimalBTF has no kfree_skb tracepoint, so the drop module's kprobe
+	// fallback is checked too.
+	var fallback bool
+	for _, r := range results {
+		fallback = fallback || r.Check == "kprobe:"+constants.KprobeKfreeSkb
+	}
+	if !fallback {
+		t.Error("kfree_skb kprobe fallback not checked")
+	}
+	if last := results[len(results)-1]; last.Check != "kubernetes API" || last.Status != Warn {
+		t.Errorf("last result = %+v, want kubernetes API warning", last)
+	}
+
+	sys := fakeSystem()
+	sys.Capabilities = func() (caps.Set, error) { return 0, nil }
+	if !Failed(sys.Run(context.Background())) {
+		t.Error("Run without capabilities did not fail")
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var buf bytes.Buffer
+	err := Print(&buf, []Result{
+		{Check: "capabilities", Status: Pass, Detail: "CAP_SYS_ADMIN"},
+		{Check: "kernel BTF", Status: Fail, Detail: "not found"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "CHECK") {
+		t.Fatalf("table = %q", buf.String())
+	}
+	if f := strings.Fields(lines[2]); len(f) < 3 || f[2] != "FAIL" {
+		t.Errorf("row = %q, want kernel BTF FAIL", lines[2])
+	}
+}
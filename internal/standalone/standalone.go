@@ -0,0 +1,129 @@
+// Package standalone runs the KubePulse backend inside the agent process,
+// for small clusters and laptops: events go from the EventBus straight to
+// an embedded SQLite store, and the API server reads from it. No NATS,
+// ClickHouse or Redis is needed; without Redis the API has no response
+// cache, saved views or live stream.
+package standalone
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/api"
+	"github.com/sureshkrishnan-v/kubePulse/internal/cache"
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+	"github.com/sureshkrishnan-v/kubePulse/internal/export"
+	"github.com/sureshkrishnan-v/kubePulse/internal/storage"
+)
+
+// Config holds standalone mode settings.
+type Config struct {
+	Storage            storage.SQLiteConfig `yaml:"storage"`
+	export.StoreConfig `yaml:",inline"`     // batching of writes to the store
+
+	// API is the API server's config, as in the api section of
+	// cmd/api's file. Tokens come from API_TOKENS, never the file.
+	API api.Config `yaml:"api"`
+}
+
+// DefaultConfig returns defaults keeping a day of events.
+func DefaultConfig() Config {
+	return Config{
+		Storage:     storage.DefaultSQLiteConfig(),
+		StoreConfig: export.DefaultStoreConfig(),
+		API:         api.DefaultConfig(),
+	}
+}
+
+// Validate checks c without opening the store or reading TLS files.
+func (c Config) Validate() error {
+	var errs []string
+	if err := c.Storage.Validate(); err != nil {
+		errs = append(errs, "storage."+err.Error())
+	}
+	if c.BatchSize < 1 {
+		errs = append(errs, "batch_size must be >= 1")
+	}
+	if c.FlushInterval <= 0 {
+		errs = append(errs, "flush_interval must be > 0")
+	}
+	if c.API.Addr == "" {
+		errs = append(errs, "api.addr is required")
+	}
+	if err := c.API.Validate(); err != nil {
+		errs = append(errs, "api."+err.Error())
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Backend is the embedded store, its writer and the API server. It is
+// registered with the agent runtime as an exporter, so it starts and
+// stops with the rest of the agent.
+type Backend struct {
+	store  *storage.SQLite
+	sink   *export.StoreExporter
+	server *api.Server
+	logger *zap.Logger
+
+	sinkDone chan struct{} // closed when sink.Start returns
+}
+
+// New opens the store and builds the API server over it. The sink
+// subscribes to bus when Start is called.
+func New(cfg Config, bus *event.Bus, logger *zap.Logger) (*Backend, error) {
+	store, err := storage.NewSQLite(cfg.Storage, logger)
+	if err != nil {
+		return nil, err
+	}
+	return &Backend{
+		store:    store,
+		sink:     export.NewStoreExporter(cfg.StoreConfig, store, bus, logger),
+		server:   api.NewServer(cfg.API, store, cache.Disabled{}, logger),
+		logger:   logger,
+		sinkDone: make(chan struct{}),
+	}, nil
+}
+
+func (b *Backend) Name() string { return constants.ExporterStandalone }
+
+// Start stores events, enforces retention and serves the API until ctx is
+// cancelled or the API server fails.
+func (b *Backend) Start(ctx context.Context) error {
+	go func() {
+		defer close(b.sinkDone)
+		if err := b.sink.Start(ctx); err != nil {
+			b.logger.Error("Store exporter failed", zap.Error(err))
+		}
+	}()
+	go b.store.RunRetention(ctx)
+
+	served := make(chan error, 1)
+	go func() { served <- b.server.Start() }()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-served:
+		return err
+	}
+}
+
+// Stop shuts the API server down, waits for the sink to store the events
+// still on the bus (the runtime closes the bus first), then closes the
+// store.
+func (b *Backend) Stop(ctx context.Context) error {
+	err := b.server.Stop()
+	select {
+	case <-b.sinkDone:
+	case <-ctx.Done():
+		b.logger.Warn("Store exporter still draining at shutdown timeout")
+	}
+	b.sink.Stop(ctx)
+	return errors.Join(err, b.store.Close())
+}
@@ -0,0 +1,155 @@
+package standalone
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+	"github.com/sureshkrishnan-v/kubePulse/internal/event"
+)
+
+// freeAddr returns a loopback address nothing is listening on.
+func freeAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().String()
+}
+
+type eventsPage struct {
+	Events []struct {
+		Type   string            `json:"type"`
+		Pod    string            `json:"pod"`
+		Labels map[string]string `json:"labels"`
+	} `json:"events"`
+}
+
+// getEvents fetches the events page at addr; ok is false until the server
+// is up.
+func getEvents(t *testing.T, addr, query string) (page eventsPage, ok bool) {
+	t.Helper()
+	resp, err := http.Get("http://" + addr + "/api/v1/events?" + query)
+	if err != nil {
+		return page, false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /api/v1/events?%s = %d", query, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	return page, true
+}
+
+func TestBackend_EventsReachTheAPI(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Storage.Path = filepath.Join(t.TempDir(), "events.db")
+	cfg.FlushInterval = 10 * time.Millisecond
+	cfg.API.Addr = freeAddr(t)
+
+	bus := event.NewBus(64, nil)
+	b, err := New(cfg, bus, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- b.Start(ctx) }()
+	for {
+		if _, ok := bus.Stats().QueueDepth[constants.ExporterStore]; ok {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	for i := range 3 {
+		bus.Publish(&event.Event{
+			Type:      event.TypeTCP,
+			Timestamp: time.Now(),
+			PID:       uint32(100 + i),
+			Namespace: "prod",
+			Pod:       "web-0",
+			Labels:    map[string]string{constants.KeyDst: "10.0.0.1:443"},
+		})
+	}
+	bus.Publish(&event.Event{Type: event.TypeDNS, Timestamp: time.Now(), Pod: "web-1"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		page, ok := getEvents(t, cfg.API.Addr, "type=tcp")
+		if ok && len(page.Events) == 3 {
+			if e := page.Events[0]; e.Pod != "web-0" || e.Labels[constants.KeyDst] != "10.0.0.1:443" {
+				t.Errorf("event = %+v", e)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("tcp events never served: %+v", page.Events)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Events published during shutdown are stored before the store closes.
+	cancel()
+	bus.Publish(&event.Event{Type: event.TypeOOM, Timestamp: time.Now()})
+	bus.Close()
+	<-done
+	stopCtx, stop := context.WithTimeout(context.Background(), 5*time.Second)
+	defer stop()
+	if err := b.Stop(stopCtx); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg.API.Addr = freeAddr(t)
+	b, err = New(cfg, event.NewBus(64, nil), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.server.Stop(); b.store.Close() })
+	go b.server.Start()
+	deadline = time.Now().Add(5 * time.Second)
+	for {
+		page, ok := getEvents(t, cfg.API.Addr, "")
+		if ok {
+			if len(page.Events) != 5 {
+				t.Errorf("%d events after restart, want 5", len(page.Events))
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("API never came up after restart")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	if err := DefaultConfig().Validate(); err != nil {
+		t.Errorf("defaults: %v", err)
+	}
+	cfg := DefaultConfig()
+	cfg.Storage.Path = ""
+	cfg.BatchSize = 0
+	cfg.API.Addr = ""
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("accepted an invalid config")
+	}
+	for _, want := range []string{"storage.path", "batch_size", "api.addr"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %s", err, want)
+		}
+	}
+}
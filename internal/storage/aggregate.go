@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"cmp"
+	"maps"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// The stores without a SQL engine of their own for quantiles (Memory and
+// SQLite) select the matching rows and aggregate them here, so both
+// answer SeriesByType, TypeStats, TopPods and Topology identically.
+// Memory counts EventRate here too.
+
+// aggregate accumulates one group of an aggregate query.
+type aggregate struct {
+	rows      uint64              // count()
+	ids       map[uint64]struct{} // uniqExact(event_id)
+	latencies []float64
+}
+
+func (a *aggregate) add(r EventRow) {
+	if a.ids == nil {
+		a.ids = make(map[uint64]struct{})
+	}
+	a.rows++
+	a.ids[r.EventID] = struct{}{}
+	a.latencies = append(a.latencies, r.LatencySec)
+}
+
+func (a *aggregate) distinct() uint64 { return uint64(len(a.ids)) }
+
+// avg returns the mean latency, 0 for no events.
+func (a *aggregate) avg() float64 {
+	if len(a.latencies) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range a.latencies {
+		sum += v
+	}
+	return sum / float64(len(a.latencies))
+}
+
+// p99 returns the exact 99th percentile latency, interpolated between
+// ranks.
+func (a *aggregate) p99() float64 { return a.quantile(0.99) }
+
+// quantile returns the exact q quantile latency, interpolated between
+// ranks, 0 for no events.
+func (a *aggregate) quantile(q float64) float64 {
+	if len(a.latencies) == 0 {
+		return 0
+	}
+	s := slices.Sorted(slices.Values(a.latencies))
+	pos := q * float64(len(s)-1)
+	lo := int(math.Floor(pos))
+	hi := min(lo+1, len(s)-1)
+	return s[lo] + (s[hi]-s[lo])*(pos-float64(lo))
+}
+
+// series aggregates rows, all of q.Type and in q's window, into per-minute
+// points.
+func series(rows []EventRow, q SeriesQuery) ([]SeriesPoint, error) {
+	groupOf := func(EventRow) string { return "" }
+	if q.GroupBy != "" {
+		col, ok := rowGroupColumns[q.GroupBy]
+		if !ok {
+			return nil, errUnknownGroup(q.GroupBy)
+		}
+		// Keep the q.Groups largest groups by row count.
+		volume := map[string]int{}
+		for _, r := range rows {
+			volume[col(r)]++
+		}
+		ranked := slices.SortedFunc(maps.Keys(volume), func(a, b string) int {
+			return cmp.Or(cmp.Compare(volume[b], volume[a]), cmp.Compare(a, b))
+		})
+		top := map[string]bool{}
+		for _, g := range ranked[:min(q.Groups, len(ranked))] {
+			top[g] = true
+		}
+		groupOf = func(r EventRow) string {
+			if g := col(r); top[g] {
+				return g
+			}
+			return constants.APIGroupOther
+		}
+	}
+
+	type key struct {
+		group  string
+		minute time.Time
+	}
+	aggs := map[key]*aggregate{}
+	for _, r := range rows {
+		k := key{groupOf(r), r.Timestamp.Truncate(time.Minute)}
+		if aggs[k] == nil {
+			aggs[k] = &aggregate{}
+		}
+		aggs[k].add(r)
+	}
+	points := make([]SeriesPoint, 0, len(aggs))
+	for k, a := range aggs {
+		points = append(points, SeriesPoint{
+			Group: k.group, Minute: k.minute, Count: a.distinct(), AvgLatency: a.avg(), P99Latency: a.p99(),
+		})
+	}
+	slices.SortFunc(points, func(a, b SeriesPoint) int {
+		return cmp.Or(cmp.Compare(a.Group, b.Group), a.Minute.Compare(b.Minute))
+	})
+	return points, nil
+}
+
+// typeStats summarizes rows, all matching the query, per type.
+func typeStats(rows []EventRow) []TypeStat {
+	aggs := map[string]*aggregate{}
+	for _, r := range rows {
+		if aggs[r.Type] == nil {
+			aggs[r.Type] = &aggregate{}
+		}
+		aggs[r.Type].add(r)
+	}
+	stats := make([]TypeStat, 0, len(aggs))
+	for t, a := range aggs {
+		stats = append(stats, TypeStat{Type: t, Count: a.distinct(), P50Latency: a.quantile(0.5), P99Latency: a.p99()})
+	}
+	slices.SortFunc(stats, func(a, b TypeStat) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Type, b.Type))
+	})
+	return stats
+}
+
+// rateBucket returns the start of the step-wide bucket holding t,
+// counted from the Unix epoch as toStartOfInterval does (time.Truncate
+// counts from year 1).
+func rateBucket(t time.Time, step time.Duration) time.Time {
+	ms, stepMs := t.UnixMilli(), step.Milliseconds()
+	return time.UnixMilli(ms - ms%stepMs).UTC()
+}
+
+// rate counts rows, all in the query's window, per type and bucket.
+func rate(rows []EventRow, step time.Duration) []RatePoint {
+	type key struct {
+		typ    string
+		bucket time.Time
+	}
+	aggs := map[key]*aggregate{}
+	for _, r := range rows {
+		k := key{r.Type, rateBucket(r.Timestamp, step)}
+		if aggs[k] == nil {
+			aggs[k] = &aggregate{}
+		}
+		aggs[k].add(r)
+	}
+	points := make([]RatePoint, 0, len(aggs))
+	for k, a := range aggs {
+		points = append(points, RatePoint{Type: k.typ, Bucket: k.bucket, Count: a.distinct()})
+	}
+	slices.SortFunc(points, func(a, b RatePoint) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), a.Bucket.Compare(b.Bucket))
+	})
+	return points
+}
+
+// rowGroupColumns is the EventRow counterpart of groupByColumns.
+var rowGroupColumns = map[string]func(EventRow) string{
+	GroupNamespace: func(r EventRow) string { return r.Namespace },
+	GroupPod:       func(r EventRow) string { return r.Pod },
+	GroupNode:      func(r EventRow) string { return r.Node },
+}
+
+// topPods ranks the pods of rows, all with a pod and in the query's
+// window, keeping k per type.
+func topPods(rows []EventRow, k int) []PodCount {
+	type key struct{ typ, ns, pod string }
+	aggs := map[key]*aggregate{}
+	for _, r := range rows {
+		k := key{r.Type, r.Namespace, r.Pod}
+		if aggs[k] == nil {
+			aggs[k] = &aggregate{}
+		}
+		aggs[k].add(r)
+	}
+	pods := make([]PodCount, 0, len(aggs))
+	for k, a := range aggs {
+		pods = append(pods, PodCount{Type: k.typ, Namespace: k.ns, Pod: k.pod, Count: a.rows, P99Latency: a.p99()})
+	}
+	slices.SortFunc(pods, func(a, b PodCount) int {
+		return cmp.Or(cmp.Compare(a.Type, b.Type), cmp.Compare(b.Count, a.Count),
+			cmp.Compare(a.Namespace, b.Namespace), cmp.Compare(a.Pod, b.Pod))
+	})
+
+	out := pods[:0]
+	perType := map[string]int{}
+	for _, p := range pods {
+		if perType[p.Type] < k {
+			perType[p.Type]++
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// topology aggregates rows, all TCP connects with a namespace and in the
+// query's window, into at most limit edges.
+func topology(rows []EventRow, limit int) []Edge {
+	type key struct{ ns, pod, svc string }
+	aggs := map[key]*aggregate{}
+	for _, r := range rows {
+		svc := r.Labels[constants.KeyDstService]
+		if svc == "" {
+			svc = constants.APIExternalService
+		}
+		k := key{r.Namespace, r.Pod, svc}
+		if aggs[k] == nil {
+			aggs[k] = &aggregate{}
+		}
+		aggs[k].add(r)
+	}
+	edges := make([]Edge, 0, len(aggs))
+	for k, a := range aggs {
+		edges = append(edges, Edge{SrcNamespace: k.ns, SrcPod: k.pod, DstService: k.svc, Count: a.rows, P99Latency: a.p99()})
+	}
+	slices.SortFunc(edges, func(a, b Edge) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.SrcNamespace, b.SrcNamespace),
+			cmp.Compare(a.SrcPod, b.SrcPod), cmp.Compare(a.DstService, b.DstService))
+	})
+	return edges[:min(limit, len(edges))]
+}
@@ -1,10 +1,13 @@
-// Package storage provides ClickHouse batch insert client for KubePulse.
-// Optimized for 1M inserts/sec via async batch writes.
+// Package storage is KubePulse's event store: the Store interface, its
+// ClickHouse implementation (batch inserts optimized for 1M rows/sec), an
+// embedded SQLite one for standalone mode and an in-memory one for tests.
 package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
@@ -16,22 +19,64 @@ import (
 
 // ClickHouseConfig holds connection settings.
 type ClickHouseConfig struct {
-	DSN      string `yaml:"dsn"`
-	MaxConns int    `yaml:"max_conns"`
+	DSN         string        `yaml:"dsn"`
+	MaxConns    int           `yaml:"max_conns"`
+	AutoMigrate bool          `yaml:"auto_migrate"` // create the database on connect; callers then run Migrate
+	Retention   time.Duration `yaml:"retention"`    // events TTL; 0 keeps data forever
+
+	// QueryTimeout bounds each read query, including reading its rows;
+	// 0 disables it. Queries slower than SlowQueryThreshold are logged
+	// with their parameters; 0 disables that.
+	QueryTimeout       time.Duration `yaml:"query_timeout"`
+	SlowQueryThreshold time.Duration `yaml:"slow_query_threshold"`
 }
 
 // DefaultClickHouseConfig returns lean defaults.
 func DefaultClickHouseConfig() ClickHouseConfig {
 	return ClickHouseConfig{
-		DSN:      constants.ClickHouseDefaultDSN,
-		MaxConns: constants.ClickHouseMaxConns,
+		DSN:         constants.ClickHouseDefaultDSN,
+		MaxConns:    constants.ClickHouseMaxConns,
+		AutoMigrate: true,
+		Retention:   constants.ClickHouseDefaultRetention,
+
+		QueryTimeout:       constants.ClickHouseQueryTimeout,
+		SlowQueryThreshold: constants.ClickHouseSlowQuery,
+	}
+}
+
+// Validate checks cfg without connecting.
+func (c ClickHouseConfig) Validate() error {
+	if _, err := clickhouse.ParseDSN(c.DSN); err != nil {
+		return fmt.Errorf("parse DSN: %w", err)
+	}
+	if c.Retention < 0 {
+		return fmt.Errorf("retention must be >= 0, got %s", c.Retention)
 	}
+	if c.QueryTimeout < 0 {
+		return fmt.Errorf("query_timeout must be >= 0, got %s", c.QueryTimeout)
+	}
+	if c.SlowQueryThreshold < 0 {
+		return fmt.Errorf("slow_query_threshold must be >= 0, got %s", c.SlowQueryThreshold)
+	}
+	return nil
+}
+
+// RedactedDSN returns the DSN with any password masked, for display.
+func (c ClickHouseConfig) RedactedDSN() string {
+	u, err := url.Parse(c.DSN)
+	if err != nil {
+		return "<unparsable>"
+	}
+	return u.Redacted()
 }
 
 // ClickHouse is the batch-insert client.
 type ClickHouse struct {
-	conn   driver.Conn
-	logger *zap.Logger
+	conn         driver.Conn
+	retention    time.Duration
+	queryTimeout time.Duration
+	slowQuery    time.Duration
+	logger       *zap.Logger
 }
 
 // NewClickHouse creates and pings a ClickHouse connection.
@@ -44,23 +89,37 @@ func NewClickHouse(cfg ClickHouseConfig, logger *zap.Logger) (*ClickHouse, error
 	opts.MaxIdleConns = cfg.MaxConns
 	opts.ConnMaxLifetime = 10 * time.Minute
 
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if cfg.AutoMigrate {
+		if err := ensureDatabase(ctx, opts); err != nil {
+			return nil, err
+		}
+	}
+
 	conn, err := clickhouse.Open(opts)
 	if err != nil {
 		return nil, fmt.Errorf("open clickhouse: %w", err)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
 	if err := conn.Ping(ctx); err != nil {
 		return nil, fmt.Errorf("ping clickhouse: %w", err)
 	}
 
-	logger.Info("ClickHouse connected", zap.String("dsn", cfg.DSN))
-	return &ClickHouse{conn: conn, logger: logger}, nil
+	logger.Info("ClickHouse connected", zap.String("dsn", cfg.RedactedDSN()))
+	return &ClickHouse{
+		conn:         conn,
+		retention:    cfg.Retention,
+		queryTimeout: cfg.QueryTimeout,
+		slowQuery:    cfg.SlowQueryThreshold,
+		logger:       logger,
+	}, nil
 }
 
 // EventRow is one row for batch insert.
 type EventRow struct {
+	EventID   uint64 // agent-assigned; identical on every redelivery
 	Timestamp time.Time
 	Type      string
 	PID       uint32
@@ -71,6 +130,30 @@ type EventRow struct {
 	Pod       string
 	Labels    map[string]string
 	Numerics  map[string]float64
+
+	// Typed copies of high-value numerics; see TypedNumerics.
+	LatencySec float64
+	Bytes      float64
+	Value      float64
+}
+
+// valueKeys maps an event type to the numeric stored in the generic value
+// column. Keep in sync with the value DEFAULT in 003_typed_numeric_columns.sql.
+var valueKeys = map[string]string{
+	constants.ModuleTCP:    constants.KeyLatencySec,
+	constants.ModuleFileIO: constants.KeyBytes,
+	constants.ModuleOOM:    constants.KeyTotalVMKB,
+}
+
+// TypedNumerics derives the typed numeric columns from an event's
+// numerics map. Missing keys yield 0, matching ClickHouse Map semantics.
+func TypedNumerics(eventType string, numerics map[string]float64) (latencySec, bytes, value float64) {
+	latencySec = numerics[constants.KeyLatencySec]
+	bytes = numerics[constants.KeyBytes]
+	if key, ok := valueKeys[eventType]; ok {
+		value = numerics[key]
+	}
+	return latencySec, bytes, value
 }
 
 // InsertBatch inserts a batch of events into ClickHouse.
@@ -81,13 +164,14 @@ func (ch *ClickHouse) InsertBatch(ctx context.Context, rows []EventRow) error {
 	}
 
 	batch, err := ch.conn.PrepareBatch(ctx,
-		"INSERT INTO kubepulse.events (timestamp, event_type, pid, uid, comm, node, namespace, pod, labels, numerics)")
+		"INSERT INTO kubepulse.events (event_id, timestamp, event_type, pid, uid, comm, node, namespace, pod, labels, numerics, latency_sec, bytes, value)")
 	if err != nil {
 		return fmt.Errorf("prepare batch: %w", err)
 	}
 
 	for _, r := range rows {
 		if err := batch.Append(
+			r.EventID,
 			r.Timestamp,
 			r.Type,
 			r.PID,
@@ -98,8 +182,11 @@ func (ch *ClickHouse) InsertBatch(ctx context.Context, rows []EventRow) error {
 			r.Pod,
 			r.Labels,
 			r.Numerics,
+			r.LatencySec,
+			r.Bytes,
+			r.Value,
 		); err != nil {
-			return fmt.Errorf("append row: %w", err)
+			return fmt.Errorf("append row: %w: %w", ErrInvalidRow, err)
 		}
 	}
 
@@ -111,17 +198,45 @@ func (ch *ClickHouse) InsertBatch(ctx context.Context, rows []EventRow) error {
 	return nil
 }
 
-// Close closes the ClickHouse connection.
-func (ch *ClickHouse) Close() error {
-	return ch.conn.Close()
+// ErrInvalidRow is wrapped by InsertBatch when a row is rejected
+// client-side (e.g. a value that can't be encoded for its column).
+var ErrInvalidRow = errors.New("invalid row")
+
+// transientExceptionCodes are ClickHouse server errors caused by load or
+// availability rather than by the data itself; retrying may succeed.
+var transientExceptionCodes = map[int32]bool{
+	159: true, // TIMEOUT_EXCEEDED
+	164: true, // READONLY
+	202: true, // TOO_MANY_SIMULTANEOUS_QUERIES
+	241: true, // MEMORY_LIMIT_EXCEEDED
+	252: true, // TOO_MANY_PARTS
 }
 
-// Query executes a query and returns rows. Used by the API layer.
-func (ch *ClickHouse) Query(ctx context.Context, query string, args ...any) (driver.Rows, error) {
-	return ch.conn.Query(ctx, query, args...)
+// IsDataError reports whether an InsertBatch error was caused by the rows
+// themselves, so retrying the same rows will fail again. Connection and
+// load-related failures return false.
+func IsDataError(err error) bool {
+	if errors.Is(err, ErrInvalidRow) {
+		return true
+	}
+	var ex *clickhouse.Exception
+	if errors.As(err, &ex) {
+		return !transientExceptionCodes[ex.Code]
+	}
+	return false
 }
 
-// QueryRow executes a query returning a single row.
-func (ch *ClickHouse) QueryRow(ctx context.Context, query string, args ...any) driver.Row {
-	return ch.conn.QueryRow(ctx, query, args...)
+// Retention returns the configured events TTL (0 = unlimited).
+func (ch *ClickHouse) Retention() time.Duration {
+	return ch.retention
+}
+
+// Ping checks that ClickHouse is reachable.
+func (ch *ClickHouse) Ping(ctx context.Context) error {
+	return ch.conn.Ping(ctx)
+}
+
+// Close closes the ClickHouse connection.
+func (ch *ClickHouse) Close() error {
+	return ch.conn.Close()
 }
@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+)
+
+func TestTypedNumerics(t *testing.T) {
+	tests := []struct {
+		typ                 string
+		numerics            map[string]float64
+		latency, bytes, val float64
+	}{
+		{"tcp", map[string]float64{"latency_sec": 0.25, "latency_ns": 2.5e8}, 0.25, 0, 0.25},
+		{"fileio", map[string]float64{"latency_sec": 0.01, "bytes": 4096}, 0.01, 4096, 4096},
+		{"oom", map[string]float64{"total_vm_kb": 1024, "oom_score_adj": 0}, 0, 0, 1024},
+		{"dns", nil, 0, 0, 0},
+	}
+	for _, tt := range tests {
+		l, b, v := TypedNumerics(tt.typ, tt.numerics)
+		if l != tt.latency || b != tt.bytes || v != tt.val {
+			t.Errorf("TypedNumerics(%s) = (%v, %v, %v), want (%v, %v, %v)",
+				tt.typ, l, b, v, tt.latency, tt.bytes, tt.val)
+		}
+	}
+}
+
+func TestIsDataError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("append row: %w: bad", ErrInvalidRow), true},
+		{fmt.Errorf("send batch: %w", &clickhouse.Exception{Code: 53}), true},   // TYPE_MISMATCH
+		{fmt.Errorf("send batch: %w", &clickhouse.Exception{Code: 252}), false}, // TOO_MANY_PARTS
+		{fmt.Errorf("prepare batch: connection refused"), false},
+	}
+	for _, tt := range tests {
+		if got := IsDataError(tt.err); got != tt.want {
+			t.Errorf("IsDataError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package storage
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Memory is a Store that keeps events in process memory, for tests and
+// trying the API without ClickHouse. Every query scans all events, there
+// is no rollup (Window.Rollup is ignored) and nothing expires. Results
+// match ClickHouse's except that quantiles are exact.
+type Memory struct {
+	mu   sync.RWMutex
+	rows []EventRow
+}
+
+// NewMemory returns an empty Memory store.
+func NewMemory() *Memory {
+	return &Memory{}
+}
+
+// InsertBatch implements Store. Timestamps are stored at millisecond
+// precision in UTC, like the events table; label and numeric maps are
+// shared with the caller.
+func (m *Memory) InsertBatch(_ context.Context, rows []EventRow) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range rows {
+		r.Timestamp = r.Timestamp.UTC().Truncate(time.Millisecond)
+		m.rows = append(m.rows, r)
+	}
+	return nil
+}
+
+// match reports whether row i passes q's filters, bounds and cursor.
+func (m *Memory) match(i int, q EventQuery) bool {
+	r := m.rows[i]
+	for _, eq := range []struct{ want, got string }{
+		{q.Type, r.Type}, {q.Namespace, r.Namespace}, {q.Pod, r.Pod}, {q.Node, r.Node}, {q.Comm, r.Comm},
+		{q.Enrichment, r.Labels[constants.KeyEnrichment]},
+	} {
+		if eq.want != "" && eq.want != eq.got {
+			return false
+		}
+	}
+	if q.PID != nil && *q.PID != r.PID {
+		return false
+	}
+	if q.MinLatency != nil && r.LatencySec < *q.MinLatency {
+		return false
+	}
+	for k, v := range q.Labels {
+		if r.Labels[k] != v {
+			return false
+		}
+	}
+	if !inScope(r.Namespace, q.Namespaces) || !inWindow(r.Timestamp, q.Since, q.Until) {
+		return false
+	}
+	if q.After != nil {
+		if c := r.Timestamp.Compare(q.After.Timestamp); c > 0 || (c == 0 && r.EventID >= q.After.RowKey) {
+			return false
+		}
+	}
+	return true
+}
+
+// inWindow reports whether t is in [since, until); zero bounds are open.
+func inWindow(t, since, until time.Time) bool {
+	return (since.IsZero() || !t.Before(since)) && (until.IsZero() || t.Before(until))
+}
+
+// inScope reports whether namespace is in namespaces; nil holds every
+// namespace.
+func inScope(namespace string, namespaces []string) bool {
+	return namespaces == nil || slices.Contains(namespaces, namespace)
+}
+
+// ListEvents implements Store.
+func (m *Memory) ListEvents(_ context.Context, q EventQuery) (EventRows, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var events []Event
+	for i, r := range m.rows {
+		if m.match(i, q) {
+			events = append(events, Event{
+				Timestamp: r.Timestamp, Type: r.Type, PID: r.PID, Comm: r.Comm, Node: r.Node,
+				Namespace: r.Namespace, Pod: r.Pod, Labels: r.Labels, Numerics: r.Numerics,
+				RowKey: r.EventID,
+			})
+		}
+	}
+	slices.SortFunc(events, func(a, b Event) int {
+		if c := b.Timestamp.Compare(a.Timestamp); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.RowKey, a.RowKey)
+	})
+	events = events[min(q.Offset, len(events)):]
+	events = events[:min(q.Limit, len(events))]
+	return &memEventRows{events: events}, nil
+}
+
+// memEventRows iterates over a ListEvents result.
+type memEventRows struct {
+	events []Event
+	i      int
+}
+
+func (r *memEventRows) Next() bool {
+	r.i++
+	return r.i <= len(r.events)
+}
+
+func (r *memEventRows) Event() (Event, error) { return r.events[r.i-1], nil }
+func (r *memEventRows) Err() error            { return nil }
+func (r *memEventRows) Close() error          { return nil }
+
+// EventKeys implements Store.
+func (m *Memory) EventKeys(_ context.Context, q EventQuery) (labels, numerics []string, err error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q.After = nil
+	ls, ns := map[string]bool{}, map[string]bool{}
+	for i, r := range m.rows {
+		if m.match(i, q) {
+			for k := range r.Labels {
+				ls[k] = true
+			}
+			for k := range r.Numerics {
+				ns[k] = true
+			}
+		}
+	}
+	return slices.Sorted(maps.Keys(ls)), slices.Sorted(maps.Keys(ns)), nil
+}
+
+// TypeStats implements Store.
+func (m *Memory) TypeStats(_ context.Context, q EventQuery) ([]TypeStat, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q.After = nil
+	var rows []EventRow
+	for i, r := range m.rows {
+		if m.match(i, q) {
+			rows = append(rows, r)
+		}
+	}
+	return typeStats(rows), nil
+}
+
+// LabelValues implements Store.
+func (m *Memory) LabelValues(_ context.Context, q EventQuery, key string, limit int) ([]LabelCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	q.After = nil
+	ids := map[string]map[uint64]struct{}{}
+	for i, r := range m.rows {
+		v := r.Labels[key]
+		if v == "" || !m.match(i, q) {
+			continue
+		}
+		if ids[v] == nil {
+			ids[v] = map[uint64]struct{}{}
+		}
+		ids[v][r.EventID] = struct{}{}
+	}
+	values := make([]LabelCount, 0, len(ids))
+	for v, set := range ids {
+		values = append(values, LabelCount{Value: v, Count: uint64(len(set))})
+	}
+	slices.SortFunc(values, func(a, b LabelCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Value, b.Value))
+	})
+	return values[:min(limit, len(values))], nil
+}
+
+// EventTypes implements Store.
+func (m *Memory) EventTypes(_ context.Context, namespaces []string) ([]TypeCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := map[string]uint64{}
+	for _, r := range m.rows {
+		if inScope(r.Namespace, namespaces) {
+			counts[r.Type]++
+		}
+	}
+	types := make([]TypeCount, 0, len(counts))
+	for t, n := range counts {
+		types = append(types, TypeCount{Type: t, Count: n})
+	}
+	slices.SortFunc(types, func(a, b TypeCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Type, b.Type))
+	})
+	return types, nil
+}
+
+// Overview implements Store.
+func (m *Memory) Overview(_ context.Context, w Window) (Overview, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var all aggregate
+	byType := map[string]*aggregate{}
+	for _, r := range m.rows {
+		if !inScope(r.Namespace, w.Namespaces) || !inWindow(r.Timestamp, w.Since, w.Until) {
+			continue
+		}
+		all.add(r)
+		if byType[r.Type] == nil {
+			byType[r.Type] = &aggregate{}
+		}
+		byType[r.Type].add(r)
+	}
+	count := func(t string) uint64 {
+		if a := byType[t]; a != nil {
+			return a.distinct()
+		}
+		return 0
+	}
+	return Overview{
+		Total:      all.distinct(),
+		TCP:        count(constants.ModuleTCP),
+		DNS:        count(constants.ModuleDNS),
+		OOM:        count(constants.ModuleOOM),
+		Drop:       count(constants.ModuleDrop),
+		AvgLatency: all.avg(),
+	}, nil
+}
+
+// SeriesByType implements Store.
+func (m *Memory) SeriesByType(_ context.Context, q SeriesQuery) ([]SeriesPoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []EventRow
+	for _, r := range m.rows {
+		if r.Type == q.Type && inScope(r.Namespace, q.Namespaces) && inWindow(r.Timestamp, q.Since, q.Until) {
+			rows = append(rows, r)
+		}
+	}
+	return series(rows, q)
+}
+
+// EventRate implements Store.
+func (m *Memory) EventRate(_ context.Context, q RateQuery) ([]RatePoint, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []EventRow
+	for _, r := range m.rows {
+		if inScope(r.Namespace, q.Namespaces) && inWindow(r.Timestamp, q.Since, q.Until) {
+			rows = append(rows, r)
+		}
+	}
+	return rate(rows, q.Step), nil
+}
+
+// TopPods implements Store.
+func (m *Memory) TopPods(_ context.Context, q TopQuery) ([]PodCount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []EventRow
+	for _, r := range m.rows {
+		if r.Pod != "" && (q.Type == "" || r.Type == q.Type) && inScope(r.Namespace, q.Namespaces) &&
+			inWindow(r.Timestamp, q.Since, q.Until) {
+			rows = append(rows, r)
+		}
+	}
+	return topPods(rows, q.K), nil
+}
+
+// Topology implements Store.
+func (m *Memory) Topology(_ context.Context, w Window, limit int) ([]Edge, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var rows []EventRow
+	for _, r := range m.rows {
+		if r.Type == constants.ModuleTCP && r.Namespace != "" && inScope(r.Namespace, w.Namespaces) &&
+			inWindow(r.Timestamp, w.Since, w.Until) {
+			rows = append(rows, r)
+		}
+	}
+	return topology(rows, limit), nil
+}
+
+// Retention implements Store: Memory keeps events forever.
+func (m *Memory) Retention() time.Duration { return 0 }
+
+// Ping implements Store.
+func (m *Memory) Ping(context.Context) error { return nil }
+
+// Close implements Store.
+func (m *Memory) Close() error { return nil }
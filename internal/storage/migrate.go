@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Schema migrations live in migrations/NNN_name.sql and are applied in
+// version order. ClickHouse has no transactional DDL or advisory locks, so
+// concurrent-start safety comes from idempotency: every statement must be
+// safe to re-run (CREATE ... IF NOT EXISTS, ADD COLUMN IF NOT EXISTS, ...).
+// Two processes racing both apply the same statements and both record the
+// version; the ReplacingMergeTree migrations table collapses the duplicate.
+//
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS ` + constants.ClickHouseDatabase + `.migrations (
+    version     UInt32,
+    name        String,
+    applied_at  DateTime DEFAULT now()
+) ENGINE = ReplacingMergeTree()
+ORDER BY version`
+
+// migration is one versioned schema change.
+type migration struct {
+	Version    uint32
+	Name       string
+	Statements []string
+}
+
+// appliedVersion is a row of the migrations table.
+type appliedVersion struct {
+	Version uint32 `ch:"version"`
+}
+
+// schemaConn is the subset of driver.Conn used by migrate.
+type schemaConn interface {
+	Exec(ctx context.Context, query string, args ...any) error
+	Select(ctx context.Context, dest any, query string, args ...any) error
+}
+
+// Migrate brings the schema up to the latest embedded migration, then
+// reconciles the events TTL with the configured retention.
+// Safe to call on every startup and from several processes at once.
+func (ch *ClickHouse) Migrate(ctx context.Context) error {
+	ms, err := loadMigrations(migrationFiles)
+	if err != nil {
+		return err
+	}
+	if err := migrate(ctx, ch.conn, ms, ch.logger); err != nil {
+		return err
+	}
+	return applyRetention(ctx, ch.conn, ch.retention, ch.logger)
+}
+
+func migrate(ctx context.Context, conn schemaConn, ms []migration, logger *zap.Logger) error {
+	if err := conn.Exec(ctx, createMigrationsTable); err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+
+	var rows []appliedVersion
+	if err := conn.Select(ctx, &rows,
+		"SELECT DISTINCT version FROM "+constants.ClickHouseDatabase+".migrations"); err != nil {
+		return fmt.Errorf("read applied migrations: %w", err)
+	}
+	applied := make(map[uint32]bool, len(rows))
+	for _, r := range rows {
+		applied[r.Version] = true
+	}
+
+	for _, m := range ms {
+		if applied[m.Version] {
+			continue
+		}
+		for i, stmt := range m.Statements {
+			if err := conn.Exec(ctx, stmt); err != nil {
+				return fmt.Errorf("migration %03d_%s statement %d: %w", m.Version, m.Name, i+1, err)
+			}
+		}
+		if err := conn.Exec(ctx,
+			"INSERT INTO "+constants.ClickHouseDatabase+".migrations (version, name) VALUES (?, ?)",
+			m.Version, m.Name); err != nil {
+			return fmt.Errorf("record migration %03d_%s: %w", m.Version, m.Name, err)
+		}
+		logger.Info("Applied ClickHouse migration",
+			zap.Uint32("version", m.Version), zap.String("name", m.Name))
+	}
+	return nil
+}
+
+// tableEngine is a row of system.tables.
+type tableEngine struct {
+	EngineFull string `ch:"engine_full"`
+}
+
+// applyRetention ALTERs the events TTL when the configured retention
+// differs from what the table currently has. retention 0 removes the TTL.
+// Racing processes issue the same ALTER, which is harmless.
+func applyRetention(ctx context.Context, conn schemaConn, retention time.Duration, logger *zap.Logger) error {
+	if retention < 0 {
+		return fmt.Errorf("retention must be >= 0, got %s", retention)
+	}
+
+	var rows []tableEngine
+	if err := conn.Select(ctx, &rows,
+		"SELECT engine_full FROM system.tables WHERE database = ? AND name = 'events'",
+		constants.ClickHouseDatabase); err != nil {
+		return fmt.Errorf("read events table engine: %w", err)
+	}
+	if len(rows) == 0 {
+		return fmt.Errorf("table %s.events does not exist", constants.ClickHouseDatabase)
+	}
+
+	current := parseTTL(rows[0].EngineFull)
+	want := retentionTTL(retention)
+	if current == want {
+		return nil
+	}
+
+	stmt := "ALTER TABLE " + constants.ClickHouseDatabase + ".events REMOVE TTL"
+	if want != "" {
+		stmt = "ALTER TABLE " + constants.ClickHouseDatabase + ".events MODIFY TTL " + want
+	}
+	if err := conn.Exec(ctx, stmt); err != nil {
+		return fmt.Errorf("update events TTL: %w", err)
+	}
+	logger.Info("Updated events retention",
+		zap.String("from", current), zap.String("to", want))
+	return nil
+}
+
+// retentionTTL renders the events TTL expression for d in the normalized
+// form ClickHouse reports in system.tables.engine_full, so an unchanged
+// retention compares equal. Returns "" for no TTL.
+func retentionTTL(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d <= 0:
+		return ""
+	case d%day == 0:
+		return fmt.Sprintf("toDateTime(timestamp) + toIntervalDay(%d)", d/day)
+	case d%time.Hour == 0:
+		return fmt.Sprintf("toDateTime(timestamp) + toIntervalHour(%d)", d/time.Hour)
+	default:
+		return fmt.Sprintf("toDateTime(timestamp) + toIntervalSecond(%d)", d/time.Second)
+	}
+}
+
+// parseTTL extracts the table TTL expression from an engine_full string.
+func parseTTL(engineFull string) string {
+	_, ttl, ok := strings.Cut(engineFull, " TTL ")
+	if !ok {
+		return ""
+	}
+	ttl, _, _ = strings.Cut(ttl, " SETTINGS ")
+	return strings.TrimSpace(ttl)
+}
+
+// loadMigrations reads NNN_name.sql files from fsys, sorted by version.
+func loadMigrations(fsys fs.FS) ([]migration, error) {
+	files, err := fs.Glob(fsys, "migrations/*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	ms := make([]migration, 0, len(files))
+	seen := make(map[uint32]string, len(files))
+	for _, f := range files {
+		base := strings.TrimSuffix(path.Base(f), ".sql")
+		num, name, ok := strings.Cut(base, "_")
+		v, err := strconv.ParseUint(num, 10, 32)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("migration %s: name must be NNN_description.sql", f)
+		}
+		if prev, dup := seen[uint32(v)]; dup {
+			return nil, fmt.Errorf("migration %s: version %d already used by %s", f, v, prev)
+		}
+		seen[uint32(v)] = f
+
+		data, err := fs.ReadFile(fsys, f)
+		if err != nil {
+			return nil, err
+		}
+		ms = append(ms, migration{
+			Version:    uint32(v),
+			Name:       name,
+			Statements: splitStatements(string(data)),
+		})
+	}
+
+	sort.Slice(ms, func(i, j int) bool { return ms[i].Version < ms[j].Version })
+	return ms, nil
+}
+
+// splitStatements splits a migration file on statement-terminating
+// semicolons, dropping "--" comment lines. The native protocol executes
+// one statement per Exec.
+func splitStatements(sql string) []string {
+	var b strings.Builder
+	for _, line := range strings.Split(sql, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "--") {
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+
+	var stmts []string
+	for _, s := range strings.Split(b.String(), ";") {
+		if s = strings.TrimSpace(s); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// ensureDatabase creates the KubePulse database over a connection to the
+// server's default database — the main connection can't be opened until
+// its database exists.
+func ensureDatabase(ctx context.Context, opts *clickhouse.Options) error {
+	bootstrap := *opts
+	bootstrap.Auth.Database = "default"
+
+	conn, err := clickhouse.Open(&bootstrap)
+	if err != nil {
+		return fmt.Errorf("open bootstrap connection: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Exec(ctx, "CREATE DATABASE IF NOT EXISTS "+constants.ClickHouseDatabase); err != nil {
+		return fmt.Errorf("create database: %w", err)
+	}
+	return nil
+}
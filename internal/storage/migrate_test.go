@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// fakeSchema is an in-memory schemaConn: it records executed DDL and
+// tracks rows inserted into the migrations table.
+type fakeSchema struct {
+	mu      sync.Mutex
+	execs   []string
+	applied []uint32 // may hold duplicates, like an unmerged ReplacingMergeTree
+	failOn  string
+
+	engineFull string // system.tables row for kubepulse.events; "" = missing
+}
+
+func (f *fakeSchema) Exec(_ context.Context, query string, args ...any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failOn != "" && strings.Contains(query, f.failOn) {
+		return errors.New("exec failed")
+	}
+	f.execs = append(f.execs, query)
+	if strings.HasPrefix(query, "INSERT INTO kubepulse.migrations") {
+		f.applied = append(f.applied, args[0].(uint32))
+	}
+	return nil
+}
+
+func (f *fakeSchema) Select(_ context.Context, dest any, _ string, _ ...any) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if tables, ok := dest.(*[]tableEngine); ok {
+		if f.engineFull != "" {
+			*tables = append(*tables, tableEngine{EngineFull: f.engineFull})
+		}
+		return nil
+	}
+	rows := dest.(*[]appliedVersion)
+	seen := map[uint32]bool{}
+	for _, v := range f.applied {
+		if !seen[v] {
+			seen[v] = true
+			*rows = append(*rows, appliedVersion{Version: v})
+		}
+	}
+	return nil
+}
+
+func (f *fakeSchema) count(prefix string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := 0
+	for _, q := range f.execs {
+		if strings.HasPrefix(q, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+var testMigrations = fstest.MapFS{
+	"migrations/002_add_col.sql":   {Data: []byte("ALTER TABLE kubepulse.t ADD COLUMN IF NOT EXISTS c UInt8;")},
+	"migrations/001_create_t.sql":  {Data: []byte("-- table t\nCREATE TABLE IF NOT EXISTS kubepulse.t (a UInt8) ENGINE = Memory;\n")},
+	"migrations/010_create_mv.sql": {Data: []byte("CREATE VIEW IF NOT EXISTS kubepulse.v AS SELECT 1;\nCREATE VIEW IF NOT EXISTS kubepulse.w AS SELECT 2;")},
+}
+
+func TestLoadMigrations_Ordered(t *testing.T) {
+	ms, err := loadMigrations(testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []uint32
+	for _, m := range ms {
+		got = append(got, m.Version)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 10 {
+		t.Fatalf("versions = %v, want [1 2 10]", got)
+	}
+	if ms[0].Name != "create_t" || len(ms[2].Statements) != 2 {
+		t.Errorf("unexpected parse: %+v", ms)
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"duplicate version": {
+			"migrations/001_a.sql": {Data: []byte("SELECT 1")},
+			"migrations/001_b.sql": {Data: []byte("SELECT 1")},
+		},
+		"bad name": {
+			"migrations/first.sql": {Data: []byte("SELECT 1")},
+		},
+	} {
+		if _, err := loadMigrations(fsys); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestLoadMigrations_Embedded(t *testing.T) {
+	ms, err := loadMigrations(migrationFiles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) == 0 || ms[0].Version != 1 {
+		t.Fatalf("embedded migrations = %+v, want version 1 first", ms)
+	}
+	if !strings.Contains(ms[0].Statements[0], "CREATE TABLE IF NOT EXISTS kubepulse.events") {
+		t.Errorf("first statement = %q, want events table DDL", ms[0].Statements[0])
+	}
+	for _, m := range ms {
+		for _, stmt := range m.Statements {
+			if !strings.Contains(stmt, "IF NOT EXISTS") && !strings.Contains(stmt, "IF EXISTS") &&
+				!strings.Contains(stmt, " MATERIALIZE ") {
+				t.Errorf("migration %d statement is not idempotent: %q", m.Version, stmt)
+			}
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("-- header; with semicolon\nCREATE A;\n\n  -- note\nCREATE B\n;\n")
+	if len(got) != 2 || got[0] != "CREATE A" || got[1] != "CREATE B" {
+		t.Errorf("splitStatements = %q", got)
+	}
+}
+
+func TestMigrate_AppliesOnceAndIsIdempotent(t *testing.T) {
+	ms, _ := loadMigrations(testMigrations)
+	db := &fakeSchema{}
+
+	if err := migrate(context.Background(), db, ms, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.applied) != 3 {
+		t.Fatalf("recorded versions = %v, want 3", db.applied)
+	}
+	if n := db.count("CREATE VIEW"); n != 2 {
+		t.Errorf("CREATE VIEW execs = %d, want 2", n)
+	}
+
+	// Second start: nothing left to apply.
+	if err := migrate(context.Background(), db, ms, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.applied) != 3 || db.count("CREATE VIEW") != 2 {
+		t.Errorf("re-run applied migrations again: applied=%v", db.applied)
+	}
+}
+
+func TestMigrate_FailureStopsBeforeRecording(t *testing.T) {
+	ms, _ := loadMigrations(testMigrations)
+	db := &fakeSchema{failOn: "ADD COLUMN"}
+
+	if err := migrate(context.Background(), db, ms, zap.NewNop()); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(db.applied) != 1 || db.applied[0] != 1 {
+		t.Fatalf("recorded versions = %v, want only [1]", db.applied)
+	}
+
+	// Fixed on the next start: resumes from version 2.
+	db.failOn = ""
+	if err := migrate(context.Background(), db, ms, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if n := db.count("CREATE TABLE IF NOT EXISTS kubepulse.t"); n != 1 {
+		t.Errorf("version 1 re-applied: %d execs", n)
+	}
+}
+
+func TestMigrate_ConcurrentStarts(t *testing.T) {
+	ms, _ := loadMigrations(testMigrations)
+	db := &fakeSchema{}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 2)
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- migrate(context.Background(), db, ms, zap.NewNop())
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Racing processes may both apply a version; the read side must see
+	// each version once and a later start must do nothing.
+	var rows []appliedVersion
+	db.Select(context.Background(), &rows, "")
+	if len(rows) != 3 {
+		t.Fatalf("distinct applied versions = %d, want 3", len(rows))
+	}
+	before := len(db.applied)
+	if err := migrate(context.Background(), db, ms, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	if len(db.applied) != before {
+		t.Errorf("third start recorded more versions")
+	}
+}
+
+// engine_full as ClickHouse reports it for migrations/001_create_events.sql.
+const events001Engine = "MergeTree PARTITION BY toDate(timestamp) ORDER BY (event_type, namespace, timestamp) " +
+	"TTL toDateTime(timestamp) + toIntervalDay(7) SETTINGS index_granularity = 8192, min_bytes_for_wide_part = 0"
+
+func TestRetentionTTL(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, ""},
+		{168 * time.Hour, "toDateTime(timestamp) + toIntervalDay(7)"},
+		{36 * time.Hour, "toDateTime(timestamp) + toIntervalHour(36)"},
+		{90 * time.Minute, "toDateTime(timestamp) + toIntervalSecond(5400)"},
+	}
+	for _, tt := range tests {
+		if got := retentionTTL(tt.in); got != tt.want {
+			t.Errorf("retentionTTL(%s) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseTTL(t *testing.T) {
+	if got := parseTTL(events001Engine); got != "toDateTime(timestamp) + toIntervalDay(7)" {
+		t.Errorf("parseTTL = %q", got)
+	}
+	if got := parseTTL("MergeTree ORDER BY x SETTINGS index_granularity = 8192"); got != "" {
+		t.Errorf("parseTTL without TTL = %q, want empty", got)
+	}
+}
+
+func TestApplyRetention(t *testing.T) {
+	tests := []struct {
+		name      string
+		retention time.Duration
+		want      string // expected ALTER, "" for none
+	}{
+		{"unchanged default", constants.ClickHouseDefaultRetention, ""},
+		{"shortened", 48 * time.Hour, "ALTER TABLE kubepulse.events MODIFY TTL toDateTime(timestamp) + toIntervalDay(2)"},
+		{"hours", 12 * time.Hour, "ALTER TABLE kubepulse.events MODIFY TTL toDateTime(timestamp) + toIntervalHour(12)"},
+		{"disabled", 0, "ALTER TABLE kubepulse.events REMOVE TTL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeSchema{engineFull: events001Engine}
+			if err := applyRetention(context.Background(), db, tt.retention, zap.NewNop()); err != nil {
+				t.Fatal(err)
+			}
+			var alters []string
+			for _, q := range db.execs {
+				if strings.HasPrefix(q, "ALTER") {
+					alters = append(alters, q)
+				}
+			}
+			switch {
+			case tt.want == "" && len(alters) != 0:
+				t.Errorf("unexpected ALTER: %q", alters)
+			case tt.want != "" && (len(alters) != 1 || alters[0] != tt.want):
+				t.Errorf("ALTERs = %q, want [%q]", alters, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyRetention_Errors(t *testing.T) {
+	if err := applyRetention(context.Background(), &fakeSchema{}, time.Hour, zap.NewNop()); err == nil {
+		t.Error("expected error when events table is missing")
+	}
+	db := &fakeSchema{engineFull: events001Engine}
+	if err := applyRetention(context.Background(), db, -time.Hour, zap.NewNop()); err == nil {
+		t.Error("expected error for negative retention")
+	}
+}
@@ -0,0 +1,30 @@
+-- Per-minute rollup of kubepulse.events for long-window API queries.
+-- AggregatingMergeTree keeps partial aggregate states, so averages and
+-- quantiles stay correct across merges (unlike summing precomputed values).
+-- Only events inserted after this migration are rolled up.
+CREATE TABLE IF NOT EXISTS kubepulse.events_1m (
+    minute          DateTime                                            CODEC(DoubleDelta, LZ4),
+    event_type      LowCardinality(String)                              CODEC(LZ4),
+    namespace       LowCardinality(String)                              CODEC(LZ4),
+    cnt             SimpleAggregateFunction(sum, UInt64),
+    latency_avg     AggregateFunction(avg, Float64),
+    latency_q       AggregateFunction(quantiles(0.5, 0.95, 0.99), Float64)
+) ENGINE = AggregatingMergeTree()
+PARTITION BY toDate(minute)
+ORDER BY (event_type, namespace, minute)
+TTL minute + INTERVAL 30 DAY;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS kubepulse.events_1m_mv TO kubepulse.events_1m
+AS SELECT
+    toStartOfMinute(timestamp) AS minute,
+    event_type,
+    namespace,
+    count() AS cnt,
+    avgState(numerics['latency_sec']) AS latency_avg,
+    quantilesState(0.5, 0.95, 0.99)(numerics['latency_sec']) AS latency_q
+FROM kubepulse.events
+GROUP BY minute, event_type, namespace;
+
+-- events_1m replaces the 001 view, which summed averages and quantiles
+-- across merges. Dropping it stops double-writing every insert.
+DROP VIEW IF EXISTS kubepulse.events_per_minute_mv;
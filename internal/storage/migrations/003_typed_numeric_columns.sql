@@ -0,0 +1,28 @@
+-- Promote high-value numerics out of the numerics Map into typed Float64
+-- columns so filters and aggregates avoid Map scans and can use min/max
+-- skip indexes. The Map keeps every value for compatibility.
+--
+-- The DEFAULT expressions backfill rows written before this migration and
+-- mirror storage.TypedNumerics, which the consumer uses for new rows.
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS latency_sec Float64 DEFAULT numerics['latency_sec'] CODEC(Gorilla, LZ4) AFTER numerics;
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS bytes Float64 DEFAULT numerics['bytes'] CODEC(Gorilla, LZ4) AFTER latency_sec;
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS value Float64 DEFAULT multiIf(
+        event_type = 'tcp',    numerics['latency_sec'],
+        event_type = 'fileio', numerics['bytes'],
+        event_type = 'oom',    numerics['total_vm_kb'],
+        0) CODEC(Gorilla, LZ4) AFTER bytes;
+
+ALTER TABLE kubepulse.events
+    ADD INDEX IF NOT EXISTS idx_latency_sec latency_sec TYPE minmax GRANULARITY 4;
+ALTER TABLE kubepulse.events
+    ADD INDEX IF NOT EXISTS idx_value value TYPE minmax GRANULARITY 4;
+
+-- Backfill existing parts. Re-running rewrites the same values.
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN latency_sec;
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN bytes;
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN value;
+ALTER TABLE kubepulse.events MATERIALIZE INDEX idx_latency_sec;
+ALTER TABLE kubepulse.events MATERIALIZE INDEX idx_value;
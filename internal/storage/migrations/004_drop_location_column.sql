@@ -0,0 +1,11 @@
+-- Promote the kernel function a packet was dropped in (labels['location'],
+-- resolved by the agent from /proc/kallsyms) to a column, so drops can be
+-- grouped by location without a Map scan. Empty for other event types.
+--
+-- The DEFAULT fills the column from labels on insert and backfills rows
+-- written before this migration.
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS location LowCardinality(String) DEFAULT labels['location'] CODEC(LZ4) AFTER value;
+
+-- Backfill existing parts. Re-running rewrites the same values.
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN location;
@@ -0,0 +1,15 @@
+-- Promote the packet endpoints ("ip:port", or "ip" without TCP/UDP ports)
+-- to columns, so drops and connections can be filtered by address without a
+-- Map scan. Filled for drop events with IPv4/IPv6 packets and for TCP
+-- events; empty otherwise.
+--
+-- The DEFAULT fills the columns from labels on insert and backfills rows
+-- written before this migration.
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS src String DEFAULT labels['src'] CODEC(LZ4) AFTER location;
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS dst String DEFAULT labels['dst'] CODEC(LZ4) AFTER src;
+
+-- Backfill existing parts. Re-running rewrites the same values.
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN src;
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN dst;
@@ -0,0 +1,11 @@
+-- Promote the DNS question type (labels['qtype']: A, AAAA, SRV, PTR or
+-- other) to a column, so queries can be grouped by type without a Map scan.
+-- Empty for other event types.
+--
+-- The DEFAULT fills the column from labels on insert and backfills rows
+-- written before this migration.
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS qtype LowCardinality(String) DEFAULT labels['qtype'] CODEC(LZ4) AFTER dst;
+
+-- Backfill existing parts. Re-running rewrites the same values.
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN qtype;
@@ -0,0 +1,14 @@
+-- Store the agent-assigned event ID, identical on every redelivery of an
+-- event, so duplicates written by the at-least-once consumer can be counted
+-- once with uniqExact(event_id).
+--
+-- The consumer always supplies the ID. The DEFAULT only backfills rows
+-- written before this migration, with a hash of their contents: duplicate
+-- rows share every column and therefore the ID.
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS event_id UInt64 DEFAULT cityHash64(
+        timestamp, event_type, pid, uid, comm, node, namespace, pod,
+        toString(labels), toString(numerics)) CODEC(LZ4) FIRST;
+
+-- Backfill existing parts. Re-running rewrites the same values.
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN event_id;
@@ -0,0 +1,12 @@
+-- Promote how each event's PID resolved to a pod (labels['enrichment']: ok,
+-- miss, host or error) to a column, so events missing a pod can be told
+-- apart and counted without a Map scan. Empty for events written by agents
+-- that don't set it.
+--
+-- The DEFAULT fills the column from labels on insert and backfills rows
+-- written before this migration.
+ALTER TABLE kubepulse.events
+    ADD COLUMN IF NOT EXISTS enrichment LowCardinality(String) DEFAULT labels['enrichment'] CODEC(LZ4) AFTER qtype;
+
+-- Backfill existing parts. Re-running rewrites the same values.
+ALTER TABLE kubepulse.events MATERIALIZE COLUMN enrichment;
@@ -0,0 +1,550 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// eventsColumns is the events SELECT list; chEventRows reads it. The
+// event ID is the row key that breaks ties between rows sharing a
+// timestamp: it is unique per event, and duplicates of one event
+// (at-least-once delivery) share it.
+const eventsColumns = "timestamp, event_type, pid, comm, node, namespace, pod, labels, numerics, event_id"
+
+// eventsQuery returns the parameterized events SELECT for q. Filter
+// values are always bound as arguments, never spliced into the SQL.
+// Rows are ordered newest first by (timestamp, event_id), the cursor key.
+func eventsQuery(q EventQuery) (string, []any) {
+	where, args := eventsWhere(q)
+	query := "SELECT " + eventsColumns + " FROM kubepulse.events" + where +
+		" ORDER BY timestamp DESC, event_id DESC LIMIT ? OFFSET ?"
+	return query, append(args, q.Limit, q.Offset)
+}
+
+// eventKeysQuery returns the query for every label and numeric key
+// matching q. The cursor is dropped: keys from rows before it only mean
+// empty columns.
+func eventKeysQuery(q EventQuery) (string, []any) {
+	q.After = nil
+	where, args := eventsWhere(q)
+	query := "SELECT arraySort(groupUniqArrayArray(mapKeys(labels))), " +
+		"arraySort(groupUniqArrayArray(mapKeys(numerics))) FROM kubepulse.events" + where
+	return query, args
+}
+
+// typeStatsQuery returns the per-type summary of the events matching q.
+// Returns (event_type, cnt, latency) with latency the [p50, p99] pair.
+func typeStatsQuery(q EventQuery) (string, []any) {
+	q.After = nil
+	where, args := eventsWhere(q)
+	query := "SELECT event_type, uniqExact(event_id) AS cnt, quantiles(0.5, 0.99)(latency_sec) AS latency " +
+		"FROM kubepulse.events" + where + " GROUP BY event_type ORDER BY cnt DESC, event_type"
+	return query, args
+}
+
+// labelValuesQuery returns the query counting the events matching q per
+// value of label key, at most limit values.
+func labelValuesQuery(q EventQuery, key string, limit int) (string, []any) {
+	q.After = nil
+	where, args := eventsWhere(q)
+	query := "SELECT labels[?] AS value, uniqExact(event_id) AS cnt FROM kubepulse.events" + where +
+		" AND labels[?] != '' GROUP BY value ORDER BY cnt DESC, value LIMIT ?"
+	return query, append(append([]any{key}, args...), key, limit)
+}
+
+// eventsWhere returns the WHERE clause and its args for q's filters, time
+// bounds and cursor. Paging is left to the caller.
+func eventsWhere(q EventQuery) (string, []any) {
+	query := " WHERE 1=1"
+	args := make([]any, 0)
+
+	for _, eq := range []struct{ col, v string }{
+		{"event_type", q.Type}, {"namespace", q.Namespace}, {"pod", q.Pod}, {"node", q.Node}, {"comm", q.Comm},
+		{"enrichment", q.Enrichment},
+	} {
+		if eq.v != "" {
+			query += " AND " + eq.col + " = ?"
+			args = append(args, eq.v)
+		}
+	}
+	if q.PID != nil {
+		query += " AND pid = ?"
+		args = append(args, *q.PID)
+	}
+	if q.MinLatency != nil {
+		query += " AND latency_sec >= ?"
+		args = append(args, *q.MinLatency)
+	}
+	for _, k := range slices.Sorted(maps.Keys(q.Labels)) {
+		query += " AND labels[?] = ?"
+		args = append(args, k, q.Labels[k])
+	}
+	scope, scopeArgs := namespaceScope(q.Namespaces)
+	query += scope
+	args = append(args, scopeArgs...)
+	if !q.Since.IsZero() {
+		query += " AND timestamp >= ?"
+		args = append(args, q.Since)
+	}
+	if !q.Until.IsZero() {
+		query += " AND timestamp < ?"
+		args = append(args, q.Until)
+	}
+	if q.After != nil {
+		// The plain timestamp bound lets the primary key prune parts.
+		query += " AND timestamp <= ? AND (timestamp, event_id) < (?, ?)"
+		args = append(args, q.After.Timestamp, q.After.Timestamp, q.After.RowKey)
+	}
+	return query, args
+}
+
+// namespaceScope returns the condition limiting a query to namespaces,
+// to append to its WHERE clause, and its args: nothing for nil, and a
+// condition no row meets for an empty scope.
+func namespaceScope(namespaces []string) (string, []any) {
+	switch {
+	case namespaces == nil:
+		return "", nil
+	case len(namespaces) == 0:
+		return " AND 0", nil
+	}
+	return " AND has(?, namespace)", []any{namespaces}
+}
+
+// The aggregate queries below take the condition namespaceScope returns
+// as scope; its args follow their time bounds.
+
+// metricsByTypeQuery returns the per-minute series query; bound args are
+// event_type, since, until. Both variants return
+// (minute, cnt, avg_latency, p99_latency).
+//
+// Raw counts are distinct event IDs, so a batch the consumer wrote twice
+// counts once. The rollup counts rows as they are inserted; the consumer's
+// deduplication keeps redeliveries out of it.
+func metricsByTypeQuery(rollup bool, scope string) string {
+	if rollup {
+		return `
+		SELECT
+			minute,
+			sum(cnt) AS cnt,
+			avgMerge(latency_avg) AS avg_latency,
+			quantilesMerge(0.5, 0.95, 0.99)(latency_q)[3] AS p99_latency
+		FROM kubepulse.events_1m
+		WHERE event_type = ? AND minute >= toStartOfMinute(?) AND minute < ?` + scope + `
+		GROUP BY minute
+		ORDER BY minute
+	`
+	}
+	return `
+		SELECT
+			toStartOfMinute(timestamp) AS minute,
+			uniqExact(event_id) AS cnt,
+			avg(latency_sec) AS avg_latency,
+			quantile(0.99)(latency_sec) AS p99_latency
+		FROM kubepulse.events
+		WHERE event_type = ? AND timestamp >= ? AND timestamp < ?` + scope + `
+		GROUP BY minute
+		ORDER BY minute
+	`
+}
+
+// overviewQuery returns the dashboard summary query; bound args are
+// since, until. Both variants return (total, tcp, dns, oom, drop, avg_latency),
+// with raw counts of distinct event IDs as in metricsByTypeQuery.
+func overviewQuery(rollup bool, scope string) string {
+	if rollup {
+		return `
+		SELECT
+			sum(cnt) AS total_events,
+			sumIf(cnt, event_type = 'tcp') AS tcp_events,
+			sumIf(cnt, event_type = 'dns') AS dns_events,
+			sumIf(cnt, event_type = 'oom') AS oom_events,
+			sumIf(cnt, event_type = 'drop') AS drop_events,
+			avgMerge(latency_avg) AS avg_latency
+		FROM kubepulse.events_1m
+		WHERE minute >= toStartOfMinute(?) AND minute < ?` + scope + `
+	`
+	}
+	return `
+		SELECT
+			uniqExact(event_id) AS total_events,
+			uniqExactIf(event_id, event_type = 'tcp') AS tcp_events,
+			uniqExactIf(event_id, event_type = 'dns') AS dns_events,
+			uniqExactIf(event_id, event_type = 'oom') AS oom_events,
+			uniqExactIf(event_id, event_type = 'drop') AS drop_events,
+			avg(latency_sec) AS avg_latency
+		FROM kubepulse.events
+		WHERE timestamp >= ? AND timestamp < ?` + scope + `
+	`
+}
+
+// groupByColumns maps SeriesQuery.GroupBy to its events column. Only
+// these names are ever spliced into SQL.
+var groupByColumns = map[string]string{
+	GroupNamespace: "namespace",
+	GroupPod:       "pod",
+	GroupNode:      "node",
+}
+
+func errUnknownGroup(groupBy string) error {
+	return fmt.Errorf("cannot group by %q", groupBy)
+}
+
+// metricsByGroupQuery returns the per-minute series query split by col,
+// keeping the top groups by volume and folding the rest into
+// constants.APIGroupOther. Bound args are event_type, since, until, scope,
+// max groups, then event_type, since, until, scope again. Both variants return
+// (grp, minute, cnt, avg_latency, p99_latency). col must come from
+// groupByColumns; rollup requires col == "namespace".
+func metricsByGroupQuery(rollup bool, col, scope string) string {
+	if rollup {
+		return `
+		SELECT
+			if(namespace IN (
+				SELECT namespace FROM kubepulse.events_1m
+				WHERE event_type = ? AND minute >= toStartOfMinute(?) AND minute < ?` + scope + `
+				GROUP BY namespace ORDER BY sum(cnt) DESC LIMIT ?
+			), namespace, '` + constants.APIGroupOther + `') AS grp,
+			minute,
+			sum(cnt) AS cnt,
+			avgMerge(latency_avg) AS avg_latency,
+			quantilesMerge(0.5, 0.95, 0.99)(latency_q)[3] AS p99_latency
+		FROM kubepulse.events_1m
+		WHERE event_type = ? AND minute >= toStartOfMinute(?) AND minute < ?` + scope + `
+		GROUP BY grp, minute
+		ORDER BY grp, minute
+	`
+	}
+	return `
+		SELECT
+			if(` + col + ` IN (
+				SELECT ` + col + ` FROM kubepulse.events
+				WHERE event_type = ? AND timestamp >= ? AND timestamp < ?` + scope + `
+				GROUP BY ` + col + ` ORDER BY count() DESC LIMIT ?
+			), ` + col + `, '` + constants.APIGroupOther + `') AS grp,
+			toStartOfMinute(timestamp) AS minute,
+			uniqExact(event_id) AS cnt,
+			avg(latency_sec) AS avg_latency,
+			quantile(0.99)(latency_sec) AS p99_latency
+		FROM kubepulse.events
+		WHERE event_type = ? AND timestamp >= ? AND timestamp < ?` + scope + `
+		GROUP BY grp, minute
+		ORDER BY grp, minute
+	`
+}
+
+// rateQuery returns the per-type bucketed count query; bound args are
+// the step in seconds, since, until. Both variants return
+// (event_type, bucket, cnt), with raw counts of distinct event IDs as in
+// metricsByTypeQuery. The rollup variant needs a whole-minute step.
+func rateQuery(rollup bool, scope string) string {
+	if rollup {
+		return `
+		SELECT
+			event_type,
+			toStartOfInterval(minute, INTERVAL ? SECOND) AS bucket,
+			sum(cnt) AS cnt
+		FROM kubepulse.events_1m
+		WHERE minute >= toStartOfMinute(?) AND minute < ?` + scope + `
+		GROUP BY event_type, bucket
+		ORDER BY event_type, bucket
+	`
+	}
+	return `
+		SELECT
+			event_type,
+			toStartOfInterval(timestamp, INTERVAL ? SECOND) AS bucket,
+			uniqExact(event_id) AS cnt
+		FROM kubepulse.events
+		WHERE timestamp >= ? AND timestamp < ?` + scope + `
+		GROUP BY event_type, bucket
+		ORDER BY event_type, bucket
+	`
+}
+
+// topQuery ranks pods by event count for one type; bound args are
+// event_type, since, until, scope, k.
+func topQuery(scope string) string {
+	return `
+		SELECT
+			namespace,
+			pod,
+			count() AS cnt,
+			quantile(0.99)(latency_sec) AS p99_latency
+		FROM kubepulse.events
+		WHERE event_type = ? AND pod != '' AND timestamp >= ? AND timestamp < ?` + scope + `
+		GROUP BY namespace, pod
+		ORDER BY cnt DESC
+		LIMIT ?
+	`
+}
+
+// topByTypeQuery is topQuery for every type at once; bound args are
+// since, until, scope, k. Returns (event_type, namespace, pod, cnt, p99_latency).
+func topByTypeQuery(scope string) string {
+	return `
+		SELECT
+			event_type,
+			namespace,
+			pod,
+			count() AS cnt,
+			quantile(0.99)(latency_sec) AS p99_latency
+		FROM kubepulse.events
+		WHERE pod != '' AND timestamp >= ? AND timestamp < ?` + scope + `
+		GROUP BY event_type, namespace, pod
+		ORDER BY event_type, cnt DESC
+		LIMIT ? BY event_type
+	`
+}
+
+// topologyQuery aggregates TCP connects into src pod → dst Service edges.
+// The agent stamps dst_service from its Service index; anything it
+// couldn't resolve is grouped as external. Bound args are the external
+// name, since, until, scope, limit. Labels aren't in the rollup, so this
+// always reads raw events.
+func topologyQuery(scope string) string {
+	return `
+		SELECT
+			namespace AS src_namespace,
+			pod AS src_pod,
+			if(labels['` + constants.KeyDstService + `'] = '', ?, labels['` + constants.KeyDstService + `']) AS dst_service,
+			count() AS cnt,
+			quantile(0.99)(latency_sec) AS p99_latency
+		FROM kubepulse.events
+		WHERE event_type = '` + constants.ModuleTCP + `'
+			AND namespace != ''
+			AND timestamp >= ? AND timestamp < ?` + scope + `
+		GROUP BY src_namespace, src_pod, dst_service
+		ORDER BY cnt DESC
+		LIMIT ?
+	`
+}
+
+// chEventRows reads eventsColumns rows.
+type chEventRows struct {
+	driver.Rows
+}
+
+func (r chEventRows) Event() (Event, error) {
+	var e Event
+	err := r.Scan(&e.Timestamp, &e.Type, &e.PID, &e.Comm, &e.Node, &e.Namespace, &e.Pod,
+		&e.Labels, &e.Numerics, &e.RowKey)
+	return e, err
+}
+
+// ListEvents implements Store.
+func (ch *ClickHouse) ListEvents(ctx context.Context, q EventQuery) (EventRows, error) {
+	query, args := eventsQuery(q)
+	rows, err := ch.query(ctx, "events", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return chEventRows{rows}, nil
+}
+
+// EventKeys implements Store.
+func (ch *ClickHouse) EventKeys(ctx context.Context, q EventQuery) (labels, numerics []string, err error) {
+	query, args := eventKeysQuery(q)
+	err = ch.scanRow(ctx, "event_keys", query, args, &labels, &numerics)
+	return labels, numerics, err
+}
+
+// TypeStats implements Store.
+func (ch *ClickHouse) TypeStats(ctx context.Context, q EventQuery) ([]TypeStat, error) {
+	query, args := typeStatsQuery(q)
+	rows, err := ch.query(ctx, "type_stats", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]TypeStat, 0)
+	for rows.Next() {
+		var t TypeStat
+		var latency []float64
+		if err := rows.Scan(&t.Type, &t.Count, &latency); err != nil || len(latency) != 2 {
+			continue
+		}
+		t.P50Latency, t.P99Latency = latency[0], latency[1]
+		stats = append(stats, t)
+	}
+	return stats, rows.Err()
+}
+
+// LabelValues implements Store.
+func (ch *ClickHouse) LabelValues(ctx context.Context, q EventQuery, key string, limit int) ([]LabelCount, error) {
+	query, args := labelValuesQuery(q, key, limit)
+	rows, err := ch.query(ctx, "label_values", query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]LabelCount, 0)
+	for rows.Next() {
+		var v LabelCount
+		if err := rows.Scan(&v.Value, &v.Count); err != nil {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// EventTypes implements Store.
+func (ch *ClickHouse) EventTypes(ctx context.Context, namespaces []string) ([]TypeCount, error) {
+	scope, args := namespaceScope(namespaces)
+	rows, err := ch.query(ctx, "event_types",
+		"SELECT event_type, count() AS cnt FROM kubepulse.events WHERE 1=1"+scope+
+			" GROUP BY event_type ORDER BY cnt DESC", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := make([]TypeCount, 0)
+	for rows.Next() {
+		var t TypeCount
+		if err := rows.Scan(&t.Type, &t.Count); err != nil {
+			continue
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+// Overview implements Store.
+func (ch *ClickHouse) Overview(ctx context.Context, w Window) (Overview, error) {
+	var o Overview
+	scope, scopeArgs := namespaceScope(w.Namespaces)
+	err := ch.scanRow(ctx, "overview", overviewQuery(w.Rollup, scope), append([]any{w.Since, w.Until}, scopeArgs...),
+		&o.Total, &o.TCP, &o.DNS, &o.OOM, &o.Drop, &o.AvgLatency)
+	return o, err
+}
+
+// SeriesByType implements Store.
+func (ch *ClickHouse) SeriesByType(ctx context.Context, q SeriesQuery) ([]SeriesPoint, error) {
+	var (
+		rows driver.Rows
+		err  error
+	)
+	scope, scopeArgs := namespaceScope(q.Namespaces)
+	bounds := append([]any{q.Type, q.Since, q.Until}, scopeArgs...)
+	if q.GroupBy == "" {
+		rows, err = ch.query(ctx, "series", metricsByTypeQuery(q.Rollup, scope), bounds...)
+	} else {
+		col, ok := groupByColumns[q.GroupBy]
+		if !ok {
+			return nil, errUnknownGroup(q.GroupBy)
+		}
+		if q.Rollup && q.GroupBy != GroupNamespace {
+			return nil, fmt.Errorf("the rollup cannot group by %q", q.GroupBy)
+		}
+		args := append(append(slices.Clip(bounds), q.Groups), bounds...)
+		rows, err = ch.query(ctx, "series_by_group", metricsByGroupQuery(q.Rollup, col, scope), args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]SeriesPoint, 0)
+	for rows.Next() {
+		var p SeriesPoint
+		dest := []any{&p.Minute, &p.Count, &p.AvgLatency, &p.P99Latency}
+		if q.GroupBy != "" {
+			dest = append([]any{&p.Group}, dest...)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// EventRate implements Store.
+func (ch *ClickHouse) EventRate(ctx context.Context, q RateQuery) ([]RatePoint, error) {
+	if q.Rollup && q.Step%time.Minute != 0 {
+		return nil, fmt.Errorf("the rollup cannot count %s buckets", q.Step)
+	}
+	scope, scopeArgs := namespaceScope(q.Namespaces)
+	args := append([]any{int64(q.Step / time.Second), q.Since, q.Until}, scopeArgs...)
+	rows, err := ch.query(ctx, "event_rate", rateQuery(q.Rollup, scope), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make([]RatePoint, 0)
+	for rows.Next() {
+		var p RatePoint
+		if err := rows.Scan(&p.Type, &p.Bucket, &p.Count); err != nil {
+			continue
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// TopPods implements Store.
+func (ch *ClickHouse) TopPods(ctx context.Context, q TopQuery) ([]PodCount, error) {
+	var (
+		rows driver.Rows
+		err  error
+	)
+	scope, scopeArgs := namespaceScope(q.Namespaces)
+	if q.Type == "" {
+		args := append(append([]any{q.Since, q.Until}, scopeArgs...), q.K)
+		rows, err = ch.query(ctx, "top_by_type", topByTypeQuery(scope), args...)
+	} else {
+		args := append(append([]any{q.Type, q.Since, q.Until}, scopeArgs...), q.K)
+		rows, err = ch.query(ctx, "top", topQuery(scope), args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pods := make([]PodCount, 0, q.K)
+	for rows.Next() {
+		p := PodCount{Type: q.Type}
+		dest := []any{&p.Namespace, &p.Pod, &p.Count, &p.P99Latency}
+		if q.Type == "" {
+			dest = append([]any{&p.Type}, dest...)
+		}
+		if err := rows.Scan(dest...); err != nil {
+			continue
+		}
+		pods = append(pods, p)
+	}
+	return pods, rows.Err()
+}
+
+// Topology implements Store.
+func (ch *ClickHouse) Topology(ctx context.Context, w Window, limit int) ([]Edge, error) {
+	scope, scopeArgs := namespaceScope(w.Namespaces)
+	args := append(append([]any{constants.APIExternalService, w.Since, w.Until}, scopeArgs...), limit)
+	rows, err := ch.query(ctx, "topology", topologyQuery(scope), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	edges := make([]Edge, 0)
+	for rows.Next() {
+		var e Edge
+		if err := rows.Scan(&e.SrcNamespace, &e.SrcPod, &e.DstService, &e.Count, &e.P99Latency); err != nil {
+			continue
+		}
+		edges = append(edges, e)
+	}
+	return edges, rows.Err()
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsQuery_CursorIsBound(t *testing.T) {
+	after := &Cursor{Timestamp: time.UnixMilli(1767225600123).UTC(), RowKey: 42}
+	query, args := eventsQuery(EventQuery{Type: "tcp", After: after, Limit: 10})
+
+	if !strings.Contains(query, "(timestamp, event_id) < (?, ?)") {
+		t.Errorf("query missing cursor predicate: %s", query)
+	}
+	if !strings.Contains(query, "ORDER BY timestamp DESC, event_id DESC") {
+		t.Errorf("query not ordered by cursor key: %s", query)
+	}
+	want := []any{"tcp", after.Timestamp, after.Timestamp, uint64(42), 10, 0}
+	if len(args) != len(want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("arg %d = %v, want %v", i, args[i], want[i])
+		}
+	}
+	if strings.Contains(query, "42") {
+		t.Errorf("cursor value spliced into SQL: %s", query)
+	}
+}
+
+func TestEventsQuery_FiltersAreBound(t *testing.T) {
+	pid, minLatency := uint32(4242), 0.25
+	since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	until := since.Add(time.Hour)
+	q := EventQuery{
+		Type:       "tcp",
+		Pod:        "api-7f9c'; DROP TABLE kubepulse.events; --",
+		Node:       "node-a",
+		Comm:       "curl",
+		PID:        &pid,
+		MinLatency: &minLatency,
+		Labels:     map[string]string{"dst": "10.0.0.1", "app": "web"},
+		Since:      since,
+		Until:      until,
+		Limit:      100,
+	}
+	query, args := eventsQuery(q)
+
+	for _, clause := range []string{
+		"event_type = ?", "pod = ?", "node = ?", "comm = ?", "pid = ?",
+		"latency_sec >= ?", "labels[?] = ?", "timestamp >= ? AND timestamp < ?",
+	} {
+		if !strings.Contains(query, clause) {
+			t.Errorf("query missing %q: %s", clause, query)
+		}
+	}
+	for _, v := range []string{"api-7f9c", "DROP", "node-a", "curl", "4242", "0.25", "10.0.0.1", "web", "dst"} {
+		if strings.Contains(query, v) {
+			t.Errorf("value %q concatenated into SQL: %s", v, query)
+		}
+	}
+
+	// Labels are bound in key order so the SQL is deterministic.
+	want := []any{"tcp", q.Pod, "node-a", "curl", uint32(4242), 0.25, "app", "web", "dst", "10.0.0.1", since, until, 100, 0}
+	if len(args) != len(want) {
+		t.Fatalf("args = %v, want %v", args, want)
+	}
+	for i := range want {
+		if args[i] != want[i] {
+			t.Errorf("arg %d = %#v, want %#v", i, args[i], want[i])
+		}
+	}
+}
+
+func TestEventsQuery_EnrichmentUsesColumn(t *testing.T) {
+	query, args := eventsQuery(EventQuery{Enrichment: "miss", Limit: 10})
+	if !strings.Contains(query, "enrichment = ?") || strings.Contains(query, "labels[") {
+		t.Errorf("query = %s, want the enrichment column", query)
+	}
+	if fmt.Sprint(args) != "[miss 10 0]" {
+		t.Errorf("args = %v", args)
+	}
+}
+
+func TestEventKeysQuery_DropsCursor(t *testing.T) {
+	q := EventQuery{Type: "tcp", After: &Cursor{Timestamp: time.Now(), RowKey: 1}, Limit: 10, Offset: 5}
+	query, args := eventKeysQuery(q)
+	if strings.Contains(query, "(timestamp, event_id)") || strings.Contains(query, "LIMIT") {
+		t.Errorf("keys query = %s", query)
+	}
+	if len(args) != 1 || args[0] != "tcp" {
+		t.Errorf("args = %v, want only the type", args)
+	}
+}
+
+func TestLabelValuesQuery_KeyIsBound(t *testing.T) {
+	key := "domain'] OR 1=1 --"
+	q := EventQuery{Pod: "web-0", After: &Cursor{Timestamp: time.Now(), RowKey: 1}}
+	query, args := labelValuesQuery(q, key, 20)
+	if strings.Contains(query, "domain") || strings.Contains(query, "(timestamp, event_id)") {
+		t.Errorf("query = %s", query)
+	}
+	if fmt.Sprint(args) != fmt.Sprint([]any{key, "web-0", key, 20}) {
+		t.Errorf("args = %v, want the key around the filters, then the limit", args)
+	}
+}
+
+func TestNamespaceScope_IsBound(t *testing.T) {
+	namespaces := []string{"payments", "checkout'; DROP TABLE kubepulse.events; --"}
+	query, args := eventsQuery(EventQuery{Type: "tcp", Namespaces: namespaces, Limit: 10})
+	if !strings.Contains(query, "has(?, namespace)") || strings.Contains(query, "payments") {
+		t.Errorf("query = %s", query)
+	}
+	if len(args) != 4 || fmt.Sprint(args[1]) != fmt.Sprint(namespaces) {
+		t.Errorf("args = %v, want the namespaces bound after the type", args)
+	}
+
+	if scope, args := namespaceScope(nil); scope != "" || args != nil {
+		t.Errorf("nil scope = %q %v, want no condition", scope, args)
+	}
+	if scope, args := namespaceScope([]string{}); scope != " AND 0" || args != nil {
+		t.Errorf("empty scope = %q %v, want a condition no row meets", scope, args)
+	}
+	scope, _ := namespaceScope(namespaces)
+	for name, query := range map[string]string{
+		"overview":  overviewQuery(true, scope),
+		"series":    metricsByTypeQuery(false, scope),
+		"rate":      rateQuery(true, scope),
+		"top":       topQuery(scope),
+		"top types": topByTypeQuery(scope),
+		"topology":  topologyQuery(scope),
+	} {
+		if !strings.Contains(query, "has(?, namespace)") {
+			t.Errorf("%s query not scoped: %s", name, query)
+		}
+	}
+	if n := strings.Count(metricsByGroupQuery(true, "namespace", scope), "has(?, namespace)"); n != 2 {
+		t.Errorf("grouped series query scoped %d times, want the ranking and the series", n)
+	}
+}
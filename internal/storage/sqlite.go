@@ -0,0 +1,548 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	_ "modernc.org/sqlite" // registers the "sqlite" driver
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// SQLiteConfig holds the embedded store's settings (standalone mode).
+type SQLiteConfig struct {
+	Path      string        `yaml:"path"`      // database file, created if missing
+	Retention time.Duration `yaml:"retention"` // events older than this are deleted; 0 keeps data forever
+}
+
+// DefaultSQLiteConfig returns defaults keeping a day of events.
+func DefaultSQLiteConfig() SQLiteConfig {
+	return SQLiteConfig{
+		Path:      constants.SQLiteDefaultPath,
+		Retention: constants.SQLiteDefaultRetention,
+	}
+}
+
+// Validate checks cfg without opening the database.
+func (c SQLiteConfig) Validate() error {
+	var errs []string
+	if c.Path == "" {
+		errs = append(errs, "path is required")
+	}
+	if c.Retention < 0 {
+		errs = append(errs, fmt.Sprintf("retention must be >= 0, got %s", c.Retention))
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// sqliteSchema creates the events table. Timestamps are Unix
+// milliseconds, labels and numerics JSON objects, and the uint64 IDs are
+// stored as their int64 bit patterns.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS events (
+	event_id    INTEGER NOT NULL,
+	ts          INTEGER NOT NULL,
+	event_type  TEXT    NOT NULL,
+	pid         INTEGER NOT NULL,
+	uid         INTEGER NOT NULL,
+	comm        TEXT    NOT NULL,
+	node        TEXT    NOT NULL,
+	namespace   TEXT    NOT NULL,
+	pod         TEXT    NOT NULL,
+	labels      TEXT    NOT NULL,
+	numerics    TEXT    NOT NULL,
+	latency_sec REAL    NOT NULL,
+	bytes       REAL    NOT NULL,
+	value       REAL    NOT NULL
+);
+CREATE INDEX IF NOT EXISTS events_ts ON events (ts, event_id);
+CREATE INDEX IF NOT EXISTS events_type_ts ON events (event_type, ts);
+`
+
+// SQLite is a Store in a single SQLite file, for standalone mode: small
+// clusters and laptops that run no ClickHouse. There is no rollup
+// (Window.Rollup is ignored); aggregates are computed in Go over the
+// matching rows, as Memory does, so quantiles are exact but long windows
+// are slow. RunRetention deletes expired events.
+type SQLite struct {
+	db        *sql.DB
+	retention time.Duration
+	logger    *zap.Logger
+}
+
+// NewSQLite opens (creating if needed) the database at cfg.Path and its
+// schema.
+func NewSQLite(cfg SQLiteConfig, logger *zap.Logger) (*SQLite, error) {
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0o750); err != nil {
+		return nil, err
+	}
+	// WAL lets the API read while the agent writes; immediate
+	// transactions take the write lock up front, so concurrent writers
+	// wait out busy_timeout instead of failing to upgrade.
+	dsn := "file:" + cfg.Path + "?" + url.Values{
+		"_pragma": {
+			fmt.Sprintf("busy_timeout(%d)", constants.SQLiteBusyTimeout.Milliseconds()),
+			"journal_mode(WAL)",
+			"synchronous(NORMAL)",
+		},
+		"_txlock": {"immediate"},
+	}.Encode()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open sqlite: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := dropRowKey(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("migrate sqlite schema: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create sqlite schema: %w", err)
+	}
+
+	logger.Info("SQLite store opened", zap.String("path", cfg.Path), zap.Duration("retention", cfg.Retention))
+	return &SQLite{db: db, retention: cfg.Retention, logger: logger}, nil
+}
+
+// dropRowKey removes the row_key column of databases created before
+// pagination tied on event_id, with the index that covered it;
+// sqliteSchema then indexes event_id instead. It does nothing to newer
+// databases or an empty file.
+func dropRowKey(ctx context.Context, db *sql.DB) error {
+	var n int
+	err := db.QueryRowContext(ctx, `SELECT count(*) FROM pragma_table_info('events') WHERE name = 'row_key'`).Scan(&n)
+	if err != nil || n == 0 {
+		return err
+	}
+	_, err = db.ExecContext(ctx, `DROP INDEX IF EXISTS events_ts; ALTER TABLE events DROP COLUMN row_key;`)
+	return err
+}
+
+// InsertBatch implements Store, in one transaction.
+func (s *SQLite) InsertBatch(ctx context.Context, rows []EventRow) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO events
+		(event_id, ts, event_type, pid, uid, comm, node, namespace, pod, labels, numerics, latency_sec, bytes, value)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	for _, r := range rows {
+		r.Timestamp = r.Timestamp.UTC().Truncate(time.Millisecond)
+		labels, err := jsonObject(r.Labels)
+		if err != nil {
+			return fmt.Errorf("encode labels: %w: %w", ErrInvalidRow, err)
+		}
+		numerics, err := jsonObject(r.Numerics)
+		if err != nil {
+			return fmt.Errorf("encode numerics: %w: %w", ErrInvalidRow, err)
+		}
+		if _, err := stmt.ExecContext(ctx,
+			int64(r.EventID), r.Timestamp.UnixMilli(), r.Type, r.PID, r.UID, r.Comm, r.Node, r.Namespace, r.Pod,
+			labels, numerics, r.LatencySec, r.Bytes, r.Value,
+		); err != nil {
+			return fmt.Errorf("insert row: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	s.logger.Debug("Batch inserted", zap.Int("rows", len(rows)))
+	return nil
+}
+
+// jsonObject encodes m, "{}" for nil.
+func jsonObject[V any](m map[string]V) (string, error) {
+	if m == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// sqliteWhere builds the WHERE clause for q's filters, bounds and cursor,
+// mirroring eventsWhere.
+func sqliteWhere(q EventQuery) (string, []any) {
+	where, args := sqliteConditions(q)
+	if len(where) == 0 {
+		return "", nil
+	}
+	return " WHERE " + strings.Join(where, " AND "), args
+}
+
+// sqliteConditions returns sqliteWhere's conditions, to be joined with
+// AND.
+func sqliteConditions(q EventQuery) ([]string, []any) {
+	var where []string
+	var args []any
+	for _, eq := range []struct{ col, v string }{
+		{"event_type", q.Type}, {"namespace", q.Namespace}, {"pod", q.Pod}, {"node", q.Node}, {"comm", q.Comm},
+		{"json_extract(labels, '$." + constants.KeyEnrichment + "')", q.Enrichment},
+	} {
+		if eq.v != "" {
+			where = append(where, eq.col+" = ?")
+			args = append(args, eq.v)
+		}
+	}
+	if q.PID != nil {
+		where = append(where, "pid = ?")
+		args = append(args, *q.PID)
+	}
+	if q.MinLatency != nil {
+		where = append(where, "latency_sec >= ?")
+		args = append(args, *q.MinLatency)
+	}
+	for _, k := range slices.Sorted(maps.Keys(q.Labels)) {
+		where = append(where, "EXISTS (SELECT 1 FROM json_each(events.labels) WHERE key = ? AND value = ?)")
+		args = append(args, k, q.Labels[k])
+	}
+	w, wargs := sqliteWindow(q.Since, q.Until, q.Namespaces)
+	where = append(where, w...)
+	args = append(args, wargs...)
+	if q.After != nil {
+		where = append(where, "(ts, event_id) < (?, ?)")
+		args = append(args, q.After.Timestamp.UnixMilli(), int64(q.After.RowKey))
+	}
+	return where, args
+}
+
+// sqliteWindow returns the conditions for ts in [since, until), zero
+// bounds being open, and for namespace in namespaces unless it is nil.
+func sqliteWindow(since, until time.Time, namespaces []string) ([]string, []any) {
+	var where []string
+	var args []any
+	if !since.IsZero() {
+		where = append(where, "ts >= ?")
+		args = append(args, since.UnixMilli())
+	}
+	if !until.IsZero() {
+		where = append(where, "ts < ?")
+		args = append(args, until.UnixMilli())
+	}
+	switch {
+	case namespaces == nil:
+	case len(namespaces) == 0:
+		where = append(where, "0")
+	default:
+		where = append(where, "namespace IN (?"+strings.Repeat(", ?", len(namespaces)-1)+")")
+		for _, ns := range namespaces {
+			args = append(args, ns)
+		}
+	}
+	return where, args
+}
+
+// ListEvents implements Store.
+func (s *SQLite) ListEvents(ctx context.Context, q EventQuery) (EventRows, error) {
+	where, args := sqliteWhere(q)
+	rows, err := s.db.QueryContext(ctx, `SELECT ts, event_type, pid, comm, node, namespace, pod, labels, numerics, event_id
+		FROM events`+where+` ORDER BY ts DESC, event_id DESC LIMIT ? OFFSET ?`,
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteEventRows{rows}, nil
+}
+
+// sqliteEventRows decodes ListEvents rows.
+type sqliteEventRows struct {
+	*sql.Rows
+}
+
+func (r *sqliteEventRows) Event() (Event, error) {
+	var e Event
+	var ts, id int64
+	var labels, numerics string
+	if err := r.Scan(&ts, &e.Type, &e.PID, &e.Comm, &e.Node, &e.Namespace, &e.Pod, &labels, &numerics, &id); err != nil {
+		return Event{}, err
+	}
+	if err := json.Unmarshal([]byte(labels), &e.Labels); err != nil {
+		return Event{}, fmt.Errorf("decode labels: %w", err)
+	}
+	if err := json.Unmarshal([]byte(numerics), &e.Numerics); err != nil {
+		return Event{}, fmt.Errorf("decode numerics: %w", err)
+	}
+	e.Timestamp = time.UnixMilli(ts).UTC()
+	e.RowKey = uint64(id)
+	return e, nil
+}
+
+// EventKeys implements Store.
+func (s *SQLite) EventKeys(ctx context.Context, q EventQuery) (labels, numerics []string, err error) {
+	q.After = nil
+	where, args := sqliteWhere(q)
+	keys := func(col string) ([]string, error) {
+		rows, err := s.db.QueryContext(ctx, `SELECT DISTINCT j.key FROM events, json_each(events.`+col+`) AS j`+
+			where+` ORDER BY j.key`, args...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var out []string
+		for rows.Next() {
+			var k string
+			if err := rows.Scan(&k); err != nil {
+				return nil, err
+			}
+			out = append(out, k)
+		}
+		return out, rows.Err()
+	}
+	if labels, err = keys("labels"); err != nil {
+		return nil, nil, err
+	}
+	if numerics, err = keys("numerics"); err != nil {
+		return nil, nil, err
+	}
+	return labels, numerics, nil
+}
+
+// TypeStats implements Store.
+func (s *SQLite) TypeStats(ctx context.Context, q EventQuery) ([]TypeStat, error) {
+	q.After = nil
+	where, args := sqliteConditions(q)
+	rows, err := s.aggregateRows(ctx, where, args)
+	if err != nil {
+		return nil, err
+	}
+	return typeStats(rows), nil
+}
+
+// LabelValues implements Store.
+func (s *SQLite) LabelValues(ctx context.Context, q EventQuery, key string, limit int) ([]LabelCount, error) {
+	q.After = nil
+	where, args := sqliteConditions(q)
+	where = append(where, "j.key = ?", "j.value != ''")
+	args = append(args, key)
+	rows, err := s.db.QueryContext(ctx, `SELECT j.value, count(DISTINCT event_id) AS n
+		FROM events, json_each(events.labels) AS j WHERE `+strings.Join(where, " AND ")+`
+		GROUP BY j.value ORDER BY n DESC, j.value LIMIT ?`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var values []LabelCount
+	for rows.Next() {
+		var v LabelCount
+		if err := rows.Scan(&v.Value, &v.Count); err != nil {
+			continue
+		}
+		values = append(values, v)
+	}
+	return values, rows.Err()
+}
+
+// EventTypes implements Store.
+func (s *SQLite) EventTypes(ctx context.Context, namespaces []string) ([]TypeCount, error) {
+	query := `SELECT event_type, count(*) AS n FROM events`
+	where, args := sqliteWindow(time.Time{}, time.Time{}, namespaces)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, query+` GROUP BY event_type ORDER BY n DESC, event_type`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var types []TypeCount
+	for rows.Next() {
+		var t TypeCount
+		if err := rows.Scan(&t.Type, &t.Count); err != nil {
+			continue
+		}
+		types = append(types, t)
+	}
+	return types, rows.Err()
+}
+
+// Overview implements Store.
+func (s *SQLite) Overview(ctx context.Context, w Window) (Overview, error) {
+	where, args := sqliteWindow(w.Since, w.Until, w.Namespaces)
+	query := `SELECT
+		count(DISTINCT event_id),
+		count(DISTINCT CASE WHEN event_type = ? THEN event_id END),
+		count(DISTINCT CASE WHEN event_type = ? THEN event_id END),
+		count(DISTINCT CASE WHEN event_type = ? THEN event_id END),
+		count(DISTINCT CASE WHEN event_type = ? THEN event_id END),
+		coalesce(avg(latency_sec), 0)
+		FROM events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	args = append([]any{constants.ModuleTCP, constants.ModuleDNS, constants.ModuleOOM, constants.ModuleDrop}, args...)
+
+	var o Overview
+	err := s.db.QueryRowContext(ctx, query, args...).Scan(&o.Total, &o.TCP, &o.DNS, &o.OOM, &o.Drop, &o.AvgLatency)
+	return o, err
+}
+
+// aggregateRows selects the rows aggregate queries need: the EventRow
+// fields series, topPods and topology read.
+func (s *SQLite) aggregateRows(ctx context.Context, where []string, args []any) ([]EventRow, error) {
+	query := `SELECT event_id, ts, event_type, node, namespace, pod, latency_sec,
+		coalesce(json_extract(labels, '$.` + constants.KeyDstService + `'), '')
+		FROM events`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []EventRow
+	for rows.Next() {
+		var r EventRow
+		var id, ts int64
+		var dstService string
+		if err := rows.Scan(&id, &ts, &r.Type, &r.Node, &r.Namespace, &r.Pod, &r.LatencySec, &dstService); err != nil {
+			continue
+		}
+		r.EventID = uint64(id)
+		r.Timestamp = time.UnixMilli(ts).UTC()
+		if dstService != "" {
+			r.Labels = map[string]string{constants.KeyDstService: dstService}
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// SeriesByType implements Store.
+func (s *SQLite) SeriesByType(ctx context.Context, q SeriesQuery) ([]SeriesPoint, error) {
+	if _, ok := rowGroupColumns[q.GroupBy]; q.GroupBy != "" && !ok {
+		return nil, errUnknownGroup(q.GroupBy)
+	}
+	where, args := sqliteWindow(q.Since, q.Until, q.Namespaces)
+	rows, err := s.aggregateRows(ctx, append(where, "event_type = ?"), append(args, q.Type))
+	if err != nil {
+		return nil, err
+	}
+	return series(rows, q)
+}
+
+// EventRate implements Store. Unlike the other aggregates it needs no
+// quantiles, so SQLite buckets and counts the rows itself.
+func (s *SQLite) EventRate(ctx context.Context, q RateQuery) ([]RatePoint, error) {
+	step := q.Step.Milliseconds()
+	query := `SELECT event_type, ts - ts % ? AS bucket, count(DISTINCT event_id) FROM events`
+	where, args := sqliteWindow(q.Since, q.Until, q.Namespaces)
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	rows, err := s.db.QueryContext(ctx, query+` GROUP BY event_type, bucket ORDER BY event_type, bucket`,
+		append([]any{step}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []RatePoint
+	for rows.Next() {
+		var p RatePoint
+		var bucket int64
+		if err := rows.Scan(&p.Type, &bucket, &p.Count); err != nil {
+			continue
+		}
+		p.Bucket = time.UnixMilli(bucket).UTC()
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// TopPods implements Store.
+func (s *SQLite) TopPods(ctx context.Context, q TopQuery) ([]PodCount, error) {
+	where, args := sqliteWindow(q.Since, q.Until, q.Namespaces)
+	where = append(where, "pod != ''")
+	if q.Type != "" {
+		where = append(where, "event_type = ?")
+		args = append(args, q.Type)
+	}
+	rows, err := s.aggregateRows(ctx, where, args)
+	if err != nil {
+		return nil, err
+	}
+	return topPods(rows, q.K), nil
+}
+
+// Topology implements Store.
+func (s *SQLite) Topology(ctx context.Context, w Window, limit int) ([]Edge, error) {
+	where, args := sqliteWindow(w.Since, w.Until, w.Namespaces)
+	rows, err := s.aggregateRows(ctx, append(where, "event_type = ?", "namespace != ''"), append(args, constants.ModuleTCP))
+	if err != nil {
+		return nil, err
+	}
+	return topology(rows, limit), nil
+}
+
+// DeleteBefore deletes the events older than t and returns how many.
+func (s *SQLite) DeleteBefore(ctx context.Context, t time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM events WHERE ts < ?`, t.UnixMilli())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// RunRetention deletes events older than the retention every
+// constants.SQLiteRetentionInterval until ctx is cancelled. It returns at
+// once when retention is unlimited.
+func (s *SQLite) RunRetention(ctx context.Context) {
+	if s.retention == 0 {
+		return
+	}
+	ticker := time.NewTicker(constants.SQLiteRetentionInterval)
+	defer ticker.Stop()
+	for {
+		n, err := s.DeleteBefore(ctx, time.Now().Add(-s.retention))
+		switch {
+		case err != nil && ctx.Err() == nil:
+			s.logger.Warn("Deleting expired events failed", zap.Error(err))
+		case n > 0:
+			s.logger.Debug("Deleted expired events", zap.Int64("rows", n))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Retention implements Store.
+func (s *SQLite) Retention() time.Duration { return s.retention }
+
+// Ping implements Store.
+func (s *SQLite) Ping(ctx context.Context) error { return s.db.PingContext(ctx) }
+
+// Close implements Store.
+func (s *SQLite) Close() error { return s.db.Close() }
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// openTestSQLite opens a fresh SQLite store in a temporary directory,
+// closed on cleanup.
+func openTestSQLite(t *testing.T, retention time.Duration) *SQLite {
+	t.Helper()
+	cfg := SQLiteConfig{Path: filepath.Join(t.TempDir(), "events.db"), Retention: retention}
+	s, err := NewSQLite(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLite_ReopenKeepsEvents(t *testing.T) {
+	cfg := SQLiteConfig{Path: filepath.Join(t.TempDir(), "nested", "events.db")}
+	s, err := NewSQLite(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	row := EventRow{
+		EventID:   1 << 63, // high bit: stored as a negative int64
+		Timestamp: time.Now(),
+		Type:      constants.ModuleTCP,
+		Labels:    map[string]string{constants.KeyDst: "10.0.0.1:443"},
+	}
+	if err := s.InsertBatch(context.Background(), []EventRow{row}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	if s, err = NewSQLite(cfg, zap.NewNop()); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	events := listAll(t, s, EventQuery{Limit: 10})
+	if len(events) != 1 || events[0].Labels[constants.KeyDst] != "10.0.0.1:443" || events[0].Numerics == nil {
+		t.Fatalf("after reopen: %+v", events)
+	}
+	o, err := s.Overview(context.Background(), Window{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o.Total != 1 || o.TCP != 1 {
+		t.Errorf("overview = %+v", o)
+	}
+}
+
+func TestSQLite_Retention(t *testing.T) {
+	s := openTestSQLite(t, time.Hour)
+	now := time.Now()
+	rows := []EventRow{
+		{EventID: 1, Timestamp: now.Add(-2 * time.Hour), Type: constants.ModuleDNS},
+		{EventID: 2, Timestamp: now.Add(-90 * time.Minute), Type: constants.ModuleDNS},
+		{EventID: 3, Timestamp: now.Add(-time.Minute), Type: constants.ModuleDNS},
+	}
+	if err := s.InsertBatch(context.Background(), rows); err != nil {
+		t.Fatal(err)
+	}
+
+	// RunRetention deletes once on start, then every interval.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.RunRetention(ctx)
+		close(done)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(listAll(t, s, EventQuery{Limit: 10})) != 1 {
+		if time.Now().After(deadline) {
+			t.Fatal("expired events never deleted")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if got := s.Retention(); got != time.Hour {
+		t.Errorf("Retention() = %s", got)
+	}
+	n, err := s.DeleteBefore(context.Background(), now)
+	if err != nil || n != 1 {
+		t.Errorf("DeleteBefore = %d, %v; want 1", n, err)
+	}
+}
+
+func TestSQLiteConfig_Validate(t *testing.T) {
+	if err := DefaultSQLiteConfig().Validate(); err != nil {
+		t.Errorf("defaults: %v", err)
+	}
+	if err := (SQLiteConfig{Retention: -time.Hour}).Validate(); err == nil {
+		t.Error("accepted an empty path and negative retention")
+	}
+}
+
+func TestSQLite_DropsRowKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.db")
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The schema before pagination tied on event_id.
+	_, err = db.Exec(`CREATE TABLE events (
+		event_id INTEGER NOT NULL, ts INTEGER NOT NULL, event_type TEXT NOT NULL, pid INTEGER NOT NULL,
+		uid INTEGER NOT NULL, comm TEXT NOT NULL, node TEXT NOT NULL, namespace TEXT NOT NULL,
+		pod TEXT NOT NULL, labels TEXT NOT NULL, numerics TEXT NOT NULL, latency_sec REAL NOT NULL,
+		bytes REAL NOT NULL, value REAL NOT NULL, row_key INTEGER NOT NULL);
+	CREATE INDEX events_ts ON events (ts, row_key);
+	INSERT INTO events VALUES (1, 1000, 'tcp', 1, 0, 'curl', 'node-a', '', '', '{}', '{}', 0, 0, 0, 99);`)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := NewSQLite(SQLiteConfig{Path: path}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.InsertBatch(context.Background(), []EventRow{{EventID: 2, Timestamp: time.UnixMilli(1000), Type: constants.ModuleTCP}}); err != nil {
+		t.Fatal(err)
+	}
+	events := listAll(t, s, EventQuery{Limit: 10})
+	if len(events) != 2 || events[0].RowKey != 2 || events[1].RowKey != 1 {
+		t.Errorf("events = %+v, want both, keyed by event ID", events)
+	}
+}
@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// Store is the event store: the consumer writes batches to it and the API
+// reads from it through typed queries, so neither builds SQL itself.
+// *ClickHouse is the production implementation, *SQLite the embedded one
+// of standalone mode, and *Memory keeps events in process for tests.
+type Store interface {
+	InsertBatch(ctx context.Context, rows []EventRow) error
+
+	// ListEvents returns the events matching q, newest first by
+	// (Timestamp, RowKey). The caller must close the result.
+	ListEvents(ctx context.Context, q EventQuery) (EventRows, error)
+	// EventKeys returns the sorted label and numeric keys present on the
+	// events matching q. q.After, Limit and Offset are ignored.
+	EventKeys(ctx context.Context, q EventQuery) (labels, numerics []string, err error)
+	// TypeStats counts the events matching q per type, with their median
+	// and p99 latency, most frequent first. q.After, Limit and Offset are
+	// ignored.
+	TypeStats(ctx context.Context, q EventQuery) ([]TypeStat, error)
+	// LabelValues counts the events matching q per value of label key,
+	// most frequent first, at most limit values. Events without the label
+	// are skipped; q.After, Limit and Offset are ignored.
+	LabelValues(ctx context.Context, q EventQuery, key string, limit int) ([]LabelCount, error)
+	// EventTypes counts stored events per type, most frequent first,
+	// counting only events in namespaces unless it is nil.
+	EventTypes(ctx context.Context, namespaces []string) ([]TypeCount, error)
+
+	// Overview summarizes the events in w.
+	Overview(ctx context.Context, w Window) (Overview, error)
+	// SeriesByType returns per-minute points for one event type, ordered
+	// by group, then minute.
+	SeriesByType(ctx context.Context, q SeriesQuery) ([]SeriesPoint, error)
+	// EventRate counts events per type in q.Step buckets, ordered by type,
+	// then bucket. Empty buckets are left out.
+	EventRate(ctx context.Context, q RateQuery) ([]RatePoint, error)
+	// TopPods ranks pods by event count, highest first.
+	TopPods(ctx context.Context, q TopQuery) ([]PodCount, error)
+	// Topology aggregates TCP connects into pod → Service edges in w,
+	// largest first, at most limit of them.
+	Topology(ctx context.Context, w Window, limit int) ([]Edge, error)
+
+	Retention() time.Duration // events TTL; 0 = unlimited
+	Ping(ctx context.Context) error
+	Close() error
+}
+
+var (
+	_ Store = (*ClickHouse)(nil)
+	_ Store = (*SQLite)(nil)
+	_ Store = (*Memory)(nil)
+)
+
+// Event is one stored event as read back.
+type Event struct {
+	Timestamp time.Time
+	Type      string
+	PID       uint32
+	Comm      string
+	Node      string
+	Namespace string
+	Pod       string
+	Labels    map[string]string
+	Numerics  map[string]float64
+
+	// RowKey breaks ties between events sharing a timestamp: it is the
+	// event ID, so duplicates of one event (at-least-once delivery) share
+	// a key.
+	RowKey uint64
+}
+
+// Cursor is the position of the last event of a page.
+type Cursor struct {
+	Timestamp time.Time
+	RowKey    uint64
+}
+
+// EventQuery selects events. Empty strings, nil pointers and zero times
+// don't filter.
+type EventQuery struct {
+	Type       string
+	Namespace  string
+	Pod        string
+	Node       string
+	Comm       string
+	PID        *uint32
+	MinLatency *float64          // seconds
+	Enrichment string            // the enrichment label: ok, miss, host or error
+	Labels     map[string]string // exact match on every key
+
+	// Namespaces, unless nil, limits the query to events in these
+	// namespaces: the scope of the API token asking. Empty matches
+	// nothing.
+	Namespaces []string
+
+	Since time.Time // inclusive
+	Until time.Time // exclusive
+	After *Cursor   // only events strictly older than this position
+
+	Limit  int
+	Offset int
+}
+
+// EventRows iterates over ListEvents results.
+type EventRows interface {
+	Next() bool
+	// Event decodes the current row. An error skips that row only;
+	// iteration may continue.
+	Event() (Event, error)
+	// Err reports an error that ended iteration early.
+	Err() error
+	Close() error
+}
+
+// TypeCount is the number of stored events of one type.
+type TypeCount struct {
+	Type  string
+	Count uint64
+}
+
+// TypeStat summarizes the events of one type matching a query. Counts are
+// of distinct event IDs.
+type TypeStat struct {
+	Type       string
+	Count      uint64
+	P50Latency float64
+	P99Latency float64
+}
+
+// LabelCount is the number of events carrying one label value, counting
+// distinct event IDs.
+type LabelCount struct {
+	Value string
+	Count uint64
+}
+
+// Window is the [Since, Until) range of an aggregate query. Rollup asks
+// for the per-minute rollup instead of raw events; a store without one
+// ignores it. Namespaces limits the query as in EventQuery.
+type Window struct {
+	Since      time.Time
+	Until      time.Time
+	Rollup     bool
+	Namespaces []string
+}
+
+// Overview is the dashboard summary of a window. Counts are of distinct
+// event IDs, so redelivered events count once.
+type Overview struct {
+	Total      uint64
+	TCP        uint64
+	DNS        uint64
+	OOM        uint64
+	Drop       uint64
+	AvgLatency float64
+}
+
+// Dimensions SeriesQuery.GroupBy accepts.
+const (
+	GroupNamespace = "namespace"
+	GroupPod       = "pod"
+	GroupNode      = "node"
+)
+
+// SeriesQuery selects a per-minute series of one event type. With
+// GroupBy set, the Groups largest groups get a series each and the rest
+// are folded into constants.APIGroupOther. The rollup can only group by
+// namespace.
+type SeriesQuery struct {
+	Type    string
+	GroupBy string
+	Groups  int
+	Window
+}
+
+// SeriesPoint is one minute of a series. Counts are of distinct event
+// IDs.
+type SeriesPoint struct {
+	Group      string // "" without GroupBy
+	Minute     time.Time
+	Count      uint64
+	AvgLatency float64
+	P99Latency float64
+}
+
+// RateQuery selects per-type event counts in Step-wide buckets. Buckets
+// start at multiples of Step since the Unix epoch, so the first and last
+// may extend past the window; only events inside it are counted. Step is
+// a whole number of seconds, and the rollup can only serve whole minutes.
+type RateQuery struct {
+	Step time.Duration
+	Window
+}
+
+// RatePoint is one bucket of one event type. Counts are of distinct
+// event IDs.
+type RatePoint struct {
+	Type   string
+	Bucket time.Time
+	Count  uint64
+}
+
+// TopQuery selects the K pods with the most events of Type in
+// [Since, Until), or the K per type when Type is empty. Events without a
+// pod are skipped. Pods aren't in the rollup, so raw events are read.
+// Namespaces limits the query as in EventQuery.
+type TopQuery struct {
+	Type       string
+	Since      time.Time
+	Until      time.Time
+	K          int
+	Namespaces []string
+}
+
+// PodCount is one ranked pod.
+type PodCount struct {
+	Type       string
+	Namespace  string
+	Pod        string
+	Count      uint64
+	P99Latency float64
+}
+
+// Edge is one src pod → dst Service edge. Connects the agent couldn't
+// resolve to a Service go to constants.APIExternalService.
+type Edge struct {
+	SrcNamespace string
+	SrcPod       string
+	DstService   string
+	Count        uint64
+	P99Latency   float64
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.uber.org/zap"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// exceptionTimeoutExceeded is ClickHouse's TIMEOUT_EXCEEDED, raised when a
+// query runs past max_execution_time.
+const exceptionTimeoutExceeded = 159
+
+var (
+	queryTimeouts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricClickHouseQueryTimeouts,
+		Help: "ClickHouse read queries cancelled by the query timeout, by query.",
+	}, []string{constants.LabelQuery})
+	slowQueries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: constants.MetricClickHouseSlowQueries,
+		Help: "ClickHouse read queries slower than the slow query threshold, by query.",
+	}, []string{constants.LabelQuery})
+)
+
+// IsTimeout reports whether a query failed because it ran out of time:
+// its context deadline passed, or ClickHouse stopped it at
+// max_execution_time.
+func IsTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) {
+		return true
+	}
+	var ex *clickhouse.Exception
+	return errors.As(err, &ex) && ex.Code == exceptionTimeoutExceeded
+}
+
+// begin starts the read query name under the query timeout. The driver
+// passes the deadline on to ClickHouse as max_execution_time, so the
+// server stops the query too, rather than only the client giving up on
+// it. The returned end func must be called with the query's error once
+// its rows are read: it releases the timeout and logs a slow or timed
+// out query with args.
+func (ch *ClickHouse) begin(ctx context.Context, name string, args []any) (context.Context, func(error) error) {
+	cancel := context.CancelFunc(func() {})
+	if ch.queryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, ch.queryTimeout)
+	}
+	start := time.Now()
+	return ctx, func(err error) error {
+		cancel()
+		elapsed := time.Since(start)
+		switch {
+		case IsTimeout(err):
+			queryTimeouts.WithLabelValues(name).Inc()
+			ch.logger.Warn("ClickHouse query timed out",
+				zap.String("query", name), zap.Duration("timeout", ch.queryTimeout),
+				zap.Any("args", args), zap.Error(err))
+		case ch.slowQuery > 0 && elapsed >= ch.slowQuery:
+			slowQueries.WithLabelValues(name).Inc()
+			ch.logger.Warn("Slow ClickHouse query",
+				zap.String("query", name), zap.Duration("duration", elapsed), zap.Any("args", args))
+		}
+		return err
+	}
+}
+
+// query runs the read query name under the query timeout; see begin.
+// The timeout covers reading the rows, and ends when they are closed.
+func (ch *ClickHouse) query(ctx context.Context, name, query string, args ...any) (driver.Rows, error) {
+	ctx, end := ch.begin(ctx, name, args)
+	rows, err := ch.conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, end(err)
+	}
+	return &timedRows{Rows: rows, end: end}, nil
+}
+
+// scanRow runs the single-row read query name under the query timeout
+// and scans its row into dest.
+func (ch *ClickHouse) scanRow(ctx context.Context, name, query string, args []any, dest ...any) error {
+	ctx, end := ch.begin(ctx, name, args)
+	return end(ch.conn.QueryRow(ctx, query, args...).Scan(dest...))
+}
+
+// timedRows ends its query when closed.
+type timedRows struct {
+	driver.Rows
+	end    func(error) error
+	closed bool
+}
+
+func (r *timedRows) Close() error {
+	err := r.Rows.Close()
+	if !r.closed {
+		r.closed = true
+		r.end(errors.Join(r.Rows.Err(), err))
+	}
+	return err
+}
+
+// poolCollector exports a ClickHouse client's connection pool usage, read
+// when scraped.
+type poolCollector struct {
+	conn driver.Conn
+}
+
+var (
+	poolInUseDesc = prometheus.NewDesc(constants.MetricClickHousePoolInUse,
+		"ClickHouse connections running a query or insert.", nil, nil)
+	poolIdleDesc = prometheus.NewDesc(constants.MetricClickHousePoolIdle,
+		"Open ClickHouse connections waiting in the pool.", nil, nil)
+	poolMaxDesc = prometheus.NewDesc(constants.MetricClickHousePoolMax,
+		"ClickHouse connections the pool may open (max_conns).", nil, nil)
+)
+
+// PoolCollector returns a collector exporting ch's connection pool usage:
+// connections in use against max_conns shows when queries wait for one.
+func (ch *ClickHouse) PoolCollector() prometheus.Collector {
+	return poolCollector{conn: ch.conn}
+}
+
+func (c poolCollector) Describe(descs chan<- *prometheus.Desc) {
+	descs <- poolInUseDesc
+	descs <- poolIdleDesc
+	descs <- poolMaxDesc
+}
+
+func (c poolCollector) Collect(metrics chan<- prometheus.Metric) {
+	s := c.conn.Stats()
+	metrics <- prometheus.MustNewConstMetric(poolInUseDesc, prometheus.GaugeValue, float64(s.Open-s.Idle))
+	metrics <- prometheus.MustNewConstMetric(poolIdleDesc, prometheus.GaugeValue, float64(s.Idle))
+	metrics <- prometheus.MustNewConstMetric(poolMaxDesc, prometheus.GaugeValue, float64(s.MaxOpenConns))
+}
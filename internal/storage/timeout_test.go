@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeConn is a driver.Conn whose queries take delay, or until their
+// context is done, and return no rows. Other methods are unimplemented.
+type fakeConn struct {
+	driver.Conn
+	delay time.Duration
+	stats driver.Stats
+}
+
+func (c fakeConn) Query(ctx context.Context, _ string, _ ...any) (driver.Rows, error) {
+	select {
+	case <-time.After(c.delay):
+		return emptyRows{}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (c fakeConn) Stats() driver.Stats { return c.stats }
+
+type emptyRows struct{ driver.Rows }
+
+func (emptyRows) Next() bool   { return false }
+func (emptyRows) Err() error   { return nil }
+func (emptyRows) Close() error { return nil }
+
+func TestClickHouse_QueryTimeout(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	ch := &ClickHouse{conn: fakeConn{delay: time.Hour}, queryTimeout: 20 * time.Millisecond, logger: zap.New(core)}
+	before := testutil.ToFloat64(queryTimeouts.WithLabelValues("topology"))
+
+	start := time.Now()
+	_, err := ch.Topology(context.Background(), Window{}, 10)
+	if !IsTimeout(err) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("query returned after %s", elapsed)
+	}
+	if got := testutil.ToFloat64(queryTimeouts.WithLabelValues("topology")) - before; got != 1 {
+		t.Errorf("timeouts counted = %v, want 1", got)
+	}
+	entries := logs.FilterMessage("ClickHouse query timed out").All()
+	if len(entries) != 1 || entries[0].ContextMap()["query"] != "topology" {
+		t.Errorf("logged %v, want the timed out query", logs.All())
+	}
+}
+
+func TestClickHouse_SlowQueryLogged(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	ch := &ClickHouse{
+		conn:         fakeConn{delay: 20 * time.Millisecond},
+		queryTimeout: time.Minute,
+		slowQuery:    10 * time.Millisecond,
+		logger:       zap.New(core),
+	}
+	rows, err := ch.ListEvents(context.Background(), EventQuery{Type: "tcp", Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 0 {
+		t.Errorf("logged before the rows were read: %v", logs.All())
+	}
+	rows.Close()
+	rows.Close()
+
+	entries := logs.FilterMessage("Slow ClickHouse query").All()
+	if len(entries) != 1 {
+		t.Fatalf("logged %v, want one slow query", logs.All())
+	}
+	fields := entries[0].ContextMap()
+	if fields["query"] != "events" || !strings.Contains(fmt.Sprint(fields["args"]), "tcp") {
+		t.Errorf("slow query fields = %v, want the query name and its args", fields)
+	}
+
+	// Under the threshold nothing is logged.
+	ch.conn = fakeConn{}
+	if _, err := ch.EventTypes(context.Background(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if logs.Len() != 1 {
+		t.Errorf("logged %v, want only the slow query", logs.All())
+	}
+}
+
+func TestIsTimeout(t *testing.T) {
+	for _, tt := range []struct {
+		err  error
+		want bool
+	}{
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("read: %w", context.DeadlineExceeded), true},
+		{&clickhouse.Exception{Code: 159}, true},
+		{&clickhouse.Exception{Code: 241}, false},
+		{context.Canceled, false},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	} {
+		if got := IsTimeout(tt.err); got != tt.want {
+			t.Errorf("IsTimeout(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPoolCollector(t *testing.T) {
+	ch := &ClickHouse{conn: fakeConn{stats: driver.Stats{MaxOpenConns: 4, Open: 3, Idle: 1}}}
+	want := `
+# HELP kubepulse_clickhouse_pool_conns_idle Open ClickHouse connections waiting in the pool.
+# TYPE kubepulse_clickhouse_pool_conns_idle gauge
+kubepulse_clickhouse_pool_conns_idle 1
+# HELP kubepulse_clickhouse_pool_conns_in_use ClickHouse connections running a query or insert.
+# TYPE kubepulse_clickhouse_pool_conns_in_use gauge
+kubepulse_clickhouse_pool_conns_in_use 2
+# HELP kubepulse_clickhouse_pool_max_conns ClickHouse connections the pool may open (max_conns).
+# TYPE kubepulse_clickhouse_pool_max_conns gauge
+kubepulse_clickhouse_pool_max_conns 4
+`
+	if err := testutil.CollectAndCompare(ch.PoolCollector(), strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
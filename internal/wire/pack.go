@@ -0,0 +1,84 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/sureshkrishnan-v/kubePulse/internal/constants"
+)
+
+// Content encodings for packed messages, carried in the
+// constants.NATSHeaderContentEncoding header. A message without the header
+// holds a single uncompressed event.
+const (
+	EncodingZstd   = "zstd"
+	EncodingSnappy = "snappy"
+)
+
+// ErrUnsupportedEncoding is returned by DecodePack for a content encoding
+// this build does not know.
+var ErrUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// SupportedEncoding reports whether enc can pack events.
+func SupportedEncoding(enc string) bool {
+	return enc == EncodingZstd || enc == EncodingSnappy
+}
+
+// The zstd coders are safe for concurrent EncodeAll/DecodeAll and costly to
+// create, so each process makes one of each on first use.
+var (
+	zstdEncoder = sync.OnceValue(func() *zstd.Encoder {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedFastest))
+		return enc
+	})
+	zstdDecoder = sync.OnceValue(func() *zstd.Decoder {
+		dec, _ := zstd.NewReader(nil,
+			zstd.WithDecoderConcurrency(0),
+			zstd.WithDecoderMaxMemory(constants.NATSPackMaxBytes))
+		return dec
+	})
+)
+
+// EncodePack joins encoded events (see Encode) one per line and compresses
+// them with enc.
+func EncodePack(enc string, events [][]byte) ([]byte, error) {
+	plain := bytes.Join(events, []byte{'\n'})
+	switch enc {
+	case EncodingZstd:
+		return zstdEncoder().EncodeAll(plain, nil), nil
+	case EncodingSnappy:
+		return s2.EncodeSnappy(nil, plain), nil
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, enc)
+	}
+}
+
+// DecodePack decompresses a pack written by EncodePack and returns its
+// events, each to be passed to Decode.
+func DecodePack(enc string, data []byte) ([][]byte, error) {
+	var plain []byte
+	var err error
+	switch enc {
+	case EncodingZstd:
+		plain, err = zstdDecoder().DecodeAll(data, nil)
+	case EncodingSnappy:
+		var n int
+		if n, err = s2.DecodedLen(data); err == nil && n > constants.NATSPackMaxBytes {
+			err = fmt.Errorf("pack decodes to %d bytes, over the %d byte limit", n, constants.NATSPackMaxBytes)
+		}
+		if err == nil {
+			plain, err = s2.Decode(nil, data)
+		}
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnsupportedEncoding, enc)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Split(plain, []byte{'\n'}), nil
+}
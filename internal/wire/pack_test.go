@@ -0,0 +1,113 @@
+package wire
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// sampleEvents returns n encoded events shaped like a busy node's TCP
+// stream: a few pods, repeated comms and label keys.
+func sampleEvents(tb testing.TB, n int) [][]byte {
+	tb.Helper()
+	events := make([][]byte, n)
+	for i := range events {
+		data, err := Encode(Event{
+			ID:        uint64(i) * 2654435761,
+			Type:      "tcp",
+			Timestamp: 1700000000000 + int64(i),
+			PID:       uint32(1000 + i%50),
+			Comm:      []string{"nginx", "envoy", "java"}[i%3],
+			Node:      "ip-10-0-12-34.ec2.internal",
+			Namespace: "prod",
+			Pod:       fmt.Sprintf("checkout-7d9f8b6c5-%05d", i%20),
+			Labels:    map[string]string{"dst": fmt.Sprintf("10.0.%d.%d:443", i%4, i%250), "role": "client", "outcome": "ok"},
+			Numerics:  map[string]float64{"latency_sec": float64(i%1000) / 1e5},
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		events[i] = data
+	}
+	return events
+}
+
+func TestPack_RoundTrip(t *testing.T) {
+	events := sampleEvents(t, 100)
+	plain := len(bytes.Join(events, []byte{'\n'}))
+	for _, enc := range []string{EncodingZstd, EncodingSnappy} {
+		packed, err := EncodePack(enc, events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(packed) >= plain/2 {
+			t.Errorf("%s: pack is %d bytes for %d plain, want under half", enc, len(packed), plain)
+		}
+		got, err := DecodePack(enc, packed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != len(events) {
+			t.Fatalf("%s: %d events, want %d", enc, len(got), len(events))
+		}
+		for i := range got {
+			if !bytes.Equal(got[i], events[i]) {
+				t.Fatalf("%s: event %d = %s, want %s", enc, i, got[i], events[i])
+			}
+			if _, err := Decode(got[i]); err != nil {
+				t.Fatalf("%s: event %d: %v", enc, i, err)
+			}
+		}
+	}
+}
+
+func TestPack_UnsupportedEncoding(t *testing.T) {
+	if _, err := EncodePack("gzip", sampleEvents(t, 1)); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("EncodePack err = %v, want ErrUnsupportedEncoding", err)
+	}
+	if _, err := DecodePack("br", []byte("x")); !errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("DecodePack err = %v, want ErrUnsupportedEncoding", err)
+	}
+	if _, err := DecodePack(EncodingZstd, []byte("not zstd")); err == nil || errors.Is(err, ErrUnsupportedEncoding) {
+		t.Errorf("DecodePack of corrupt data err = %v, want a decode error", err)
+	}
+}
+
+// BenchmarkEncodePack reports throughput in uncompressed bytes and the
+// packed size relative to the same events sent uncompressed.
+//
+//	go test ./internal/wire -run '^$' -bench Pack
+func BenchmarkEncodePack(b *testing.B) {
+	events := sampleEvents(b, 100)
+	plain := len(bytes.Join(events, []byte{'\n'}))
+	for _, enc := range []string{EncodingZstd, EncodingSnappy} {
+		b.Run(enc, func(b *testing.B) {
+			b.SetBytes(int64(plain))
+			var packed []byte
+			for b.Loop() {
+				packed, _ = EncodePack(enc, events)
+			}
+			b.ReportMetric(float64(len(packed))/float64(plain), "size_ratio")
+		})
+	}
+}
+
+func BenchmarkDecodePack(b *testing.B) {
+	events := sampleEvents(b, 100)
+	plain := len(bytes.Join(events, []byte{'\n'}))
+	for _, enc := range []string{EncodingZstd, EncodingSnappy} {
+		packed, err := EncodePack(enc, events)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(enc, func(b *testing.B) {
+			b.SetBytes(int64(plain))
+			for b.Loop() {
+				if _, err := DecodePack(enc, packed); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+// Package wire defines the JSON event format the agent publishes to NATS
+// and the consumer reads. Both sides import it, so the format has one
+// definition; any change to its shape must bump Version.
+package wire
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Version is the wire format version the agent writes.
+const Version = 1
+
+// ErrUnsupportedVersion is returned by Decode for an event written in a
+// version this build does not know, typically by a newer agent during a
+// rolling upgrade.
+var ErrUnsupportedVersion = errors.New("unsupported wire version")
+
+// Event is one event on the wire (flat, compact).
+type Event struct {
+	V         int                `json:"v"`            // format version; 0 predates versioning
+	ID        uint64             `json:"id,omitempty"` // agent-assigned, identical on redelivery
+	Type      string             `json:"type"`
+	Timestamp int64              `json:"ts"` // Unix milliseconds
+	PID       uint32             `json:"pid"`
+	UID       uint32             `json:"uid"`
+	Comm      string             `json:"comm"`
+	Node      string             `json:"node"`
+	Namespace string             `json:"ns"`
+	Pod       string             `json:"pod"`
+	Labels    map[string]string  `json:"l,omitempty"`
+	Numerics  map[string]float64 `json:"n,omitempty"`
+}
+
+// Supported reports whether this build can decode version v. Version 0,
+// written by agents that predate the field, has the same shape as 1.
+func Supported(v int) bool {
+	return v == 0 || v == Version
+}
+
+// Encode marshals e as the current Version.
+func Encode(e Event) ([]byte, error) {
+	e.V = Version
+	return json.Marshal(e)
+}
+
+// Decode unmarshals one event, rejecting versions this build does not
+// support with ErrUnsupportedVersion.
+func Decode(data []byte) (Event, error) {
+	var e Event
+	if err := json.Unmarshal(data, &e); err != nil {
+		// A newer version may have changed a field's type; report the
+		// version rather than the mismatch when it is readable.
+		var hdr struct {
+			V int `json:"v"`
+		}
+		if json.Unmarshal(data, &hdr) == nil && !Supported(hdr.V) {
+			return Event{}, fmt.Errorf("%w %d", ErrUnsupportedVersion, hdr.V)
+		}
+		return Event{}, err
+	}
+	if !Supported(e.V) {
+		return Event{}, fmt.Errorf("%w %d", ErrUnsupportedVersion, e.V)
+	}
+	return e, nil
+}
+
+// Live is an event as the consumer publishes it to the Redis live channel
+// for /ws/events. Its fields match the API's /events records, so the
+// dashboard renders both the same way.
+type Live struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Type      string             `json:"type"`
+	PID       uint32             `json:"pid"`
+	Comm      string             `json:"comm"`
+	Node      string             `json:"node"`
+	Namespace string             `json:"namespace"`
+	Pod       string             `json:"pod"`
+	Labels    map[string]string  `json:"labels"`
+	Numerics  map[string]float64 `json:"numerics"`
+}
+
+// Live returns e as published to the live channel.
+func (e Event) Live() Live {
+	return Live{
+		Timestamp: time.UnixMilli(e.Timestamp).UTC(),
+		Type:      e.Type,
+		PID:       e.PID,
+		Comm:      e.Comm,
+		Node:      e.Node,
+		Namespace: e.Namespace,
+		Pod:       e.Pod,
+		Labels:    e.Labels,
+		Numerics:  e.Numerics,
+	}
+}
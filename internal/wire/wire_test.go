@@ -0,0 +1,68 @@
+package wire
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeDecode_RoundTrip(t *testing.T) {
+	in := Event{
+		ID:        42,
+		Type:      "tcp",
+		Timestamp: 1700000000123,
+		PID:       1234,
+		UID:       1000,
+		Comm:      "curl",
+		Node:      "node-a",
+		Namespace: "prod",
+		Pod:       "web-0",
+		Labels:    map[string]string{"dst": "10.0.0.1:443"},
+		Numerics:  map[string]float64{"latency_sec": 0.012},
+	}
+	data, err := Encode(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.V = Version
+	if !reflect.DeepEqual(out, in) {
+		t.Errorf("round trip = %+v, want %+v", out, in)
+	}
+}
+
+// TestDecode_Unversioned decodes a payload exactly as agents that predate
+// the version field wrote it.
+func TestDecode_Unversioned(t *testing.T) {
+	data := []byte(`{"type":"dns","ts":1700000000000,"pid":7,"uid":0,"comm":"coredns","node":"n","ns":"kube-system","pod":"coredns-0","l":{"qname":"example.com"}}`)
+	e, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.V != 0 || e.ID != 0 || e.Type != "dns" || e.Labels["qname"] != "example.com" {
+		t.Errorf("Decode = %+v", e)
+	}
+}
+
+func TestDecode_UnsupportedVersion(t *testing.T) {
+	for name, data := range map[string]string{
+		"same shape":    `{"v":2,"type":"tcp","ts":1,"pid":1}`,
+		"changed shape": `{"v":2,"type":"tcp","ts":"2024-01-01T00:00:00Z","pid":1}`,
+	} {
+		if _, err := Decode([]byte(data)); !errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("%s: err = %v, want ErrUnsupportedVersion", name, err)
+		}
+	}
+}
+
+func TestDecode_Malformed(t *testing.T) {
+	for _, data := range []string{`{not json`, `{"v":1,"ts":"soon"}`} {
+		_, err := Decode([]byte(data))
+		if err == nil || errors.Is(err, ErrUnsupportedVersion) {
+			t.Errorf("Decode(%s) err = %v, want a decode error", data, err)
+		}
+	}
+}
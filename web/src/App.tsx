@@ -4,7 +4,7 @@ import {
   AreaChart, Area, XAxis, YAxis, Tooltip, ResponsiveContainer, CartesianGrid,
 } from 'recharts';
 import { fetchOverview, fetchEvents, fetchMetrics, fetchTopology } from './api';
-import type { Overview, Event, MetricPoint, TopologyItem } from './api';
+import type { Overview, Event, MetricPoint, TopologyEdge } from './api';
 import { usePolling } from './hooks';
 import './index.css';
 
@@ -106,6 +106,7 @@ function EventsTable() {
     fileio: 'bg-cyan-500/20 text-cyan-400',
     retransmit: 'bg-orange-500/20 text-orange-400',
     rst: 'bg-rose-500/20 text-rose-400',
+    signals: 'bg-fuchsia-500/20 text-fuchsia-400',
   };
 
   return (
@@ -119,7 +120,7 @@ function EventsTable() {
             className="bg-[var(--bg-card)] border border-[var(--border)] rounded-lg px-3 py-1.5 text-sm text-[var(--text-primary)] focus:outline-none focus:border-[var(--accent)]"
           >
             <option value="">All Types</option>
-            {['tcp', 'dns', 'oom', 'drop', 'exec', 'fileio', 'retransmit', 'rst'].map(t => (
+            {['tcp', 'dns', 'oom', 'drop', 'exec', 'fileio', 'retransmit', 'rst', 'signals'].map(t => (
               <option key={t} value={t}>{t.toUpperCase()}</option>
             ))}
           </select>
@@ -187,10 +188,10 @@ function EventsTable() {
 function TopologyView() {
   const fetcher = useCallback(() => fetchTopology(), []);
   const { data } = usePolling(fetcher, 15000);
-  const items: TopologyItem[] = data?.topology ?? [];
+  const edges: TopologyEdge[] = data?.edges ?? [];
 
-  const grouped = items.reduce<Record<string, TopologyItem[]>>((acc, item) => {
-    (acc[item.namespace] = acc[item.namespace] || []).push(item);
+  const grouped = edges.reduce<Record<string, TopologyEdge[]>>((acc, edge) => {
+    (acc[edge.src_namespace] = acc[edge.src_namespace] || []).push(edge);
     return acc;
   }, {});
 
@@ -201,24 +202,28 @@ function TopologyView() {
         <p className="text-[var(--text-muted)] text-sm">No topology data yet</p>
       ) : (
         <div className="grid grid-cols-1 md:grid-cols-2 lg:grid-cols-3 gap-3">
-          {Object.entries(grouped).map(([ns, pods]) => (
+          {Object.entries(grouped).map(([ns, nsEdges]) => (
             <div key={ns} className="bg-[var(--bg-card)] rounded-lg p-3 border border-[var(--border)]">
               <div className="flex items-center gap-2 mb-2">
                 <div className="w-2 h-2 rounded-full bg-indigo-500" />
                 <span className="text-sm font-medium">{ns}</span>
                 <span className="text-xs text-[var(--text-muted)] ml-auto">
-                  {pods.reduce((s, p) => s + p.count, 0).toLocaleString()} events
+                  {nsEdges.reduce((s, e) => s + e.count, 0).toLocaleString()} connects
                 </span>
               </div>
               <div className="space-y-1">
-                {pods.slice(0, 5).map((p, i) => (
-                  <div key={i} className="flex items-center justify-between text-xs">
-                    <span className="truncate max-w-[60%] text-[var(--text-muted)]">{p.pod}</span>
-                    <span className="font-mono">{p.count.toLocaleString()}</span>
+                {nsEdges.slice(0, 5).map((e, i) => (
+                  <div key={i} className="flex items-center justify-between text-xs gap-2">
+                    <span className="truncate max-w-[60%] text-[var(--text-muted)]">
+                      {e.src_pod} → {e.dst_service}
+                    </span>
+                    <span className="font-mono">
+                      {e.count.toLocaleString()} · p99 {(e.p99_latency * 1000).toFixed(1)}ms
+                    </span>
                   </div>
                 ))}
-                {pods.length > 5 && (
-                  <p className="text-xs text-[var(--text-muted)]">+{pods.length - 5} more</p>
+                {nsEdges.length > 5 && (
+                  <p className="text-xs text-[var(--text-muted)]">+{nsEdges.length - 5} more</p>
                 )}
               </div>
             </div>
@@ -299,7 +304,7 @@ export default function App() {
         {tab === 'metrics' && (
           <div className="space-y-6">
             <div className="flex gap-2">
-              {['tcp', 'dns', 'oom', 'drop', 'exec', 'fileio', 'retransmit', 'rst'].map(t => (
+              {['tcp', 'dns', 'oom', 'drop', 'exec', 'fileio', 'retransmit', 'rst', 'signals'].map(t => (
                 <button
                   key={t}
                   onClick={() => setMetricType(t)}
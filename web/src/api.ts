@@ -20,6 +20,15 @@ export interface Overview {
     drop_events: number;
     avg_latency_sec: number;
     window: string;
+    retention_sec: number; // events TTL; 0 = unlimited
+    top_offenders: Record<string, TopOffender[]>; // event type → noisiest pods
+}
+
+export interface TopOffender {
+    namespace: string;
+    pod: string;
+    count: number;
+    p99_latency?: number; // tcp and fileio only
 }
 
 export interface MetricPoint {
@@ -29,11 +38,12 @@ export interface MetricPoint {
     p99_latency: number;
 }
 
-export interface TopologyItem {
-    namespace: string;
-    pod: string;
-    node: string;
+export interface TopologyEdge {
+    src_namespace: string;
+    src_pod: string;
+    dst_service: string; // "namespace/name" or "external"
     count: number;
+    p99_latency: number;
 }
 
 export async function fetchOverview(): Promise<Overview> {
@@ -43,14 +53,16 @@ export async function fetchOverview(): Promise<Overview> {
 
 export async function fetchEvents(params: {
     limit?: number;
-    offset?: number;
+    offset?: number; // deprecated: use cursor
+    cursor?: string;
     type?: string;
     namespace?: string;
     since?: string;
-}): Promise<{ events: Event[]; limit: number; offset: number }> {
+}): Promise<{ events: Event[]; limit: number; offset: number; next_cursor: string | null }> {
     const q = new URLSearchParams();
     if (params.limit) q.set('limit', String(params.limit));
-    if (params.offset) q.set('offset', String(params.offset));
+    if (params.cursor) q.set('cursor', params.cursor);
+    else if (params.offset) q.set('offset', String(params.offset));
     if (params.type) q.set('type', params.type);
     if (params.namespace) q.set('namespace', params.namespace);
     if (params.since) q.set('since', params.since);
@@ -68,14 +80,39 @@ export async function fetchMetrics(type: string, window = '1h'): Promise<{ serie
     return r.json();
 }
 
-export async function fetchTopology(): Promise<{ topology: TopologyItem[] }> {
-    const r = await fetch(`${API}/topology`);
+export interface GroupSeries {
+    key: string; // group value, or "other" for the folded remainder
+    points: MetricPoint[];
+}
+
+export async function fetchGroupedMetrics(
+    type: string, groupBy: 'namespace' | 'pod' | 'node', window = '1h', groups = 10,
+): Promise<{ type: string; group_by: string; series: GroupSeries[] }> {
+    const r = await fetch(`${API}/metrics/${type}?window=${window}&group_by=${groupBy}&groups=${groups}`);
+    return r.json();
+}
+
+export async function fetchTopology(window = '1h'): Promise<{ window: string; edges: TopologyEdge[] }> {
+    const r = await fetch(`${API}/topology?window=${window}`);
+    return r.json();
+}
+
+export async function fetchTop(
+    type: string, k = 10, window = '1h',
+): Promise<{ type: string; k: number; window: string; top: TopOffender[] }> {
+    const r = await fetch(`${API}/top?type=${type}&k=${k}&window=${window}`);
     return r.json();
 }
 
-export function connectWebSocket(onMessage: (event: Event) => void): WebSocket {
+export function connectWebSocket(
+    onMessage: (event: Event) => void,
+    filters: { type?: string; namespace?: string; pod?: string } = {},
+): WebSocket {
     const proto = window.location.protocol === 'https:' ? 'wss:' : 'ws:';
-    const ws = new WebSocket(`${proto}//${window.location.host}/ws/events`);
+    const params = new URLSearchParams();
+    for (const [k, v] of Object.entries(filters)) if (v) params.set(k, v);
+    const qs = params.toString();
+    const ws = new WebSocket(`${proto}//${window.location.host}/ws/events${qs ? `?${qs}` : ''}`);
     ws.onmessage = (e) => {
         try {
             onMessage(JSON.parse(e.data));